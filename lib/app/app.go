@@ -19,6 +19,7 @@ package app
 
 import (
 	"context"
+	"crypto"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -264,6 +265,9 @@ type InstallerRequest struct {
 	CACert string `json:"ca_cert,omitempty"`
 	// EncryptionKey is encryption key to encrypt installer packages with
 	EncryptionKey string `json:"encryption_key,omitempty"`
+	// SigningKey is an optional key to sign installer packages with.
+	// It is never sent over the wire so only local services can sign installers
+	SigningKey crypto.Signer `json:"-"`
 }
 
 // Check validates this request
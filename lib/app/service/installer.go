@@ -35,6 +35,7 @@ import (
 	"github.com/gravitational/gravity/lib/pack/encryptedpack"
 	"github.com/gravitational/gravity/lib/pack/localpack"
 	"github.com/gravitational/gravity/lib/schema"
+	"github.com/gravitational/gravity/lib/signature"
 	"github.com/gravitational/gravity/lib/storage"
 	"github.com/gravitational/gravity/lib/storage/keyval"
 	fileutils "github.com/gravitational/gravity/lib/utils"
@@ -133,6 +134,9 @@ func (r *applications) GetAppInstaller(req appservice.InstallerRequest) (install
 		return nil, trace.Wrap(err)
 	}
 
+	// signature covers the package data as stored in the installer
+	// so keep a reference to the raw package service
+	storedPackages := localPackages
 	if req.EncryptionKey != "" {
 		localPackages = encryptedpack.New(localPackages, req.EncryptionKey)
 	}
@@ -176,6 +180,18 @@ func (r *applications) GetAppInstaller(req appservice.InstallerRequest) (install
 		return nil, trace.Wrap(err)
 	}
 
+	if req.SigningKey != nil {
+		var sig *signature.Signature
+		sig, err = signature.Sign(storedPackages, app.Package, req.SigningKey)
+		if err != nil {
+			return nil, trace.Wrap(err, "failed to sign installer")
+		}
+		err = signature.Write(tempDir, *sig)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+	}
+
 	reader, writer := io.Pipe()
 	go func() {
 		uploadScript, err := renderUploadScript(*app)
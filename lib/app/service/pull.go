@@ -18,6 +18,7 @@ package service
 
 import (
 	"context"
+	"io"
 	"io/ioutil"
 	"sync"
 	"time"
@@ -26,6 +27,7 @@ import (
 	"github.com/gravitational/gravity/lib/defaults"
 	"github.com/gravitational/gravity/lib/loc"
 	"github.com/gravitational/gravity/lib/pack"
+	"github.com/gravitational/gravity/lib/pack/delta"
 	"github.com/gravitational/gravity/lib/run"
 	"github.com/gravitational/gravity/lib/schema"
 	"github.com/gravitational/gravity/lib/utils"
@@ -180,6 +182,16 @@ func pullPackage(req PackagePullRequest) (*pack.PackageEnvelope, error) {
 
 	req.Infof("Pulling package %v.", req.Package)
 
+	if !req.MetadataOnly {
+		env, err = pullPackageDelta(req)
+		if err != nil {
+			req.WithError(err).Warnf("Failed to pull package %v as delta, will pull the whole package.", req.Package)
+		}
+		if env != nil {
+			return env, nil
+		}
+	}
+
 	reader := ioutil.NopCloser(utils.NopReader())
 	if req.MetadataOnly {
 		env, err = req.SrcPack.ReadPackageEnvelope(req.Package)
@@ -227,6 +239,68 @@ func pullPackage(req PackagePullRequest) (*pack.PackageEnvelope, error) {
 	return env, nil
 }
 
+// pullPackageDelta pulls the package as a delta against the previous version
+// of the package the destination package service already has.
+// Deltas are used when either of the package services is remote and supports them.
+// Returns nil envelope if the package cannot be pulled as a delta
+func pullPackageDelta(req PackagePullRequest) (*pack.PackageEnvelope, error) {
+	src, srcOK := req.SrcPack.(pack.DeltaPackageService)
+	dst, dstOK := req.DstPack.(pack.DeltaPackageService)
+	if !srcOK && !dstOK {
+		return nil, nil
+	}
+	base, err := pack.FindDeltaBase(req.DstPack, req.Package)
+	if err != nil {
+		if trace.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, trace.Wrap(err)
+	}
+	var sig *delta.Signature
+	if srcOK {
+		sig, err = pack.ReadPackageSignature(req.DstPack, *base)
+	} else {
+		sig, err = dst.ReadPackageSignature(*base)
+	}
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var env *pack.PackageEnvelope
+	var reader io.ReadCloser
+	if srcOK {
+		env, reader, err = src.ReadPackageDelta(req.Package, *sig)
+	} else {
+		env, reader, err = pack.ReadPackageDelta(req.SrcPack, req.Package, *sig)
+	}
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	defer reader.Close()
+
+	req.Infof("Pulling package %v as delta against %v.", req.Package, base)
+	labels := make(map[string]string)
+	for label, value := range env.RuntimeLabels {
+		labels[label] = value
+	}
+	for label, value := range req.Labels {
+		labels[label] = value
+	}
+	if srcOK {
+		env, err = pack.CreatePackageFromDelta(req.DstPack, env.Locator, *base,
+			reader, req.Upsert, pack.WithLabels(labels))
+	} else {
+		env, err = dst.CreatePackageFromDelta(env.Locator, *base,
+			reader, req.Upsert, pack.WithLabels(labels))
+	}
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if req.Progress != nil {
+		req.Progress.Report(env.SizeBytes, env.SizeBytes)
+	}
+	return env, nil
+}
+
 // PullApp pulls the application specified with app, along with all its dependencies
 // and base application, from the "source" application service and replicates it in
 // the "destination" application service
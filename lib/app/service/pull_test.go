@@ -18,6 +18,8 @@ package service
 
 import (
 	"bytes"
+	"io"
+	"io/ioutil"
 	"path/filepath"
 	"time"
 
@@ -29,6 +31,7 @@ import (
 	"github.com/gravitational/gravity/lib/helm"
 	"github.com/gravitational/gravity/lib/loc"
 	"github.com/gravitational/gravity/lib/pack"
+	"github.com/gravitational/gravity/lib/pack/delta"
 	"github.com/gravitational/gravity/lib/pack/localpack"
 	"github.com/gravitational/gravity/lib/storage"
 	"github.com/gravitational/gravity/lib/storage/keyval"
@@ -85,6 +88,54 @@ func (s *PullerSuite) TestPullPackage(c *C) {
 	c.Assert(trace.IsAlreadyExists(err), Equals, true)
 }
 
+func (s *PullerSuite) TestPullPackageDelta(c *C) {
+	base := loc.MustParseLocator("example.com/package:0.0.1")
+	update := loc.MustParseLocator("example.com/package:0.0.2")
+	baseData := bytes.Repeat([]byte("base package data "), 64*1024)
+	updateData := append(append([]byte{}, baseData...), "update"...)
+	for _, packages := range []pack.PackageService{s.srcPack, s.dstPack} {
+		_, err := packages.CreatePackage(base, bytes.NewReader(baseData))
+		c.Assert(err, IsNil)
+	}
+	_, err := s.srcPack.CreatePackage(update, bytes.NewReader(updateData),
+		pack.WithLabels(map[string]string{"purpose": "test"}))
+	c.Assert(err, IsNil)
+
+	// download from and upload to a package service that supports deltas
+	for _, tc := range []struct {
+		comment string
+		src     pack.PackageService
+		dst     pack.PackageService
+	}{
+		{comment: "download", src: &deltaPackages{PackageService: s.srcPack}, dst: s.dstPack},
+		{comment: "upload", src: s.srcPack, dst: &deltaPackages{PackageService: s.dstPack}},
+	} {
+		comment := Commentf(tc.comment)
+		env, err := PullPackage(PackagePullRequest{
+			SrcPack: tc.src,
+			DstPack: tc.dst,
+			Package: update,
+			Upsert:  true,
+		})
+		c.Assert(err, IsNil, comment)
+		c.Assert(env.Locator, Equals, update, comment)
+		c.Assert(env.RuntimeLabels, DeepEquals, map[string]string{"purpose": "test"}, comment)
+
+		_, reader, err := s.dstPack.ReadPackage(update)
+		c.Assert(err, IsNil, comment)
+		data, err := ioutil.ReadAll(reader)
+		reader.Close()
+		c.Assert(err, IsNil, comment)
+		c.Assert(bytes.Equal(data, updateData), Equals, true, comment)
+
+		deltas := tc.src
+		if _, ok := deltas.(*deltaPackages); !ok {
+			deltas = tc.dst
+		}
+		c.Assert(deltas.(*deltaPackages).transfers, Equals, 1, comment)
+	}
+}
+
 func (s *PullerSuite) TestPullApp(c *C) {
 	s.pullApp(c, 0)
 }
@@ -205,3 +256,23 @@ type packagesByName []loc.Locator
 func (r packagesByName) Len() int           { return len(r) }
 func (r packagesByName) Swap(i, j int)      { r[i], r[j] = r[j], r[i] }
 func (r packagesByName) Less(i, j int) bool { return r[i].String() < r[j].String() }
+
+// deltaPackages is a package service that counts the transferred deltas
+type deltaPackages struct {
+	pack.PackageService
+	transfers int
+}
+
+func (r *deltaPackages) ReadPackageSignature(loc loc.Locator) (*delta.Signature, error) {
+	return pack.ReadPackageSignature(r.PackageService, loc)
+}
+
+func (r *deltaPackages) ReadPackageDelta(loc loc.Locator, base delta.Signature) (*pack.PackageEnvelope, io.ReadCloser, error) {
+	r.transfers++
+	return pack.ReadPackageDelta(r.PackageService, loc, base)
+}
+
+func (r *deltaPackages) CreatePackageFromDelta(loc loc.Locator, base loc.Locator, data io.Reader, upsert bool, options ...pack.PackageOption) (*pack.PackageEnvelope, error) {
+	r.transfers++
+	return pack.CreatePackageFromDelta(r.PackageService, loc, base, data, upsert, options...)
+}
@@ -0,0 +1,136 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package audit implements the audit log of changes made to the cluster.
+//
+// Every mutating call made through the cluster operator API on behalf of
+// a user is recorded as an audit event with the user, the verb, the changed
+// resource, a diff of the resource state and the result. Events are kept in
+// the cluster backend for the retention period and can optionally be sent
+// to the configured log forwarders.
+package audit
+
+import (
+	"os"
+	"time"
+
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/storage"
+
+	"github.com/gravitational/trace"
+	"github.com/jonboulle/clockwork"
+	"github.com/sirupsen/logrus"
+)
+
+// Emitter records audit events
+type Emitter interface {
+	// Emit records the specified audit event
+	Emit(storage.AuditEvent)
+}
+
+// Config defines the audit log configuration
+type Config struct {
+	// Backend is the cluster backend audit events are kept in
+	Backend storage.Backend
+	// Retention is how long audit events are kept
+	Retention time.Duration
+	// Forwarders returns the log forwarders audit events are sent to.
+	// Events are not forwarded if unset
+	Forwarders func() ([]storage.LogForwarder, error)
+	// Hostname is the hostname of forwarded messages
+	Hostname string
+	// Clock is used to tell time
+	Clock clockwork.Clock
+	// FieldLogger is used for logging
+	logrus.FieldLogger
+}
+
+// CheckAndSetDefaults validates the configuration and sets defaults
+func (c *Config) CheckAndSetDefaults() error {
+	if c.Backend == nil {
+		return trace.BadParameter("missing Backend")
+	}
+	if c.Retention < 0 {
+		return trace.BadParameter("retention cannot be negative")
+	}
+	if c.Retention == 0 {
+		c.Retention = defaults.AuditEventRetention
+	}
+	if c.Hostname == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return trace.ConvertSystemError(err)
+		}
+		c.Hostname = hostname
+	}
+	if c.Clock == nil {
+		c.Clock = clockwork.NewRealClock()
+	}
+	if c.FieldLogger == nil {
+		c.FieldLogger = logrus.WithField(trace.Component, "audit")
+	}
+	return nil
+}
+
+// New returns a new audit log
+func New(config Config) (*Log, error) {
+	if err := config.CheckAndSetDefaults(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &Log{Config: config}, nil
+}
+
+// Log persists audit events in the cluster backend and sends
+// them to log forwarders
+type Log struct {
+	// Config is the audit log configuration
+	Config
+}
+
+// Emit records the specified audit event.
+//
+// Failures to record the event are logged and do not fail the audited
+// call, since the change has already been made by the time it is recorded
+func (l *Log) Emit(event storage.AuditEvent) {
+	if event.Time.IsZero() {
+		event.Time = l.Clock.Now().UTC()
+	}
+	event.Expires = event.Time.Add(l.Retention)
+	out, err := l.Backend.CreateAuditEvent(event)
+	if err != nil {
+		l.Warnf("Failed to record %v: %v.", event, trace.DebugReport(err))
+		return
+	}
+	l.Info(out.String())
+	if l.Forwarders != nil {
+		go l.forward(*out)
+	}
+}
+
+// forward sends the audit event to all configured log forwarders
+func (l *Log) forward(event storage.AuditEvent) {
+	forwarders, err := l.Forwarders()
+	if err != nil {
+		l.Warnf("Failed to query log forwarders: %v.", trace.DebugReport(err))
+		return
+	}
+	for _, forwarder := range forwarders {
+		err := send(forwarder, l.Hostname, event)
+		if err != nil {
+			l.Warnf("Failed to forward %v to %v: %v.", event, forwarder.GetName(), err)
+		}
+	}
+}
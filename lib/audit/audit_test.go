@@ -0,0 +1,145 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package audit
+
+import (
+	"encoding/json"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/storage/keyval"
+
+	teleservices "github.com/gravitational/teleport/lib/services"
+	"github.com/jonboulle/clockwork"
+	check "gopkg.in/check.v1"
+)
+
+func TestAudit(t *testing.T) { check.TestingT(t) }
+
+type AuditSuite struct {
+	clock   clockwork.FakeClock
+	backend storage.Backend
+}
+
+var _ = check.Suite(&AuditSuite{})
+
+func (s *AuditSuite) SetUpTest(c *check.C) {
+	s.clock = clockwork.NewFakeClockAt(time.Date(2018, time.June, 15, 10, 30, 0, 0, time.UTC))
+	var err error
+	s.backend, err = keyval.NewBolt(keyval.BoltConfig{
+		Clock: s.clock,
+		Path:  filepath.Join(c.MkDir(), "bolt.db"),
+	})
+	c.Assert(err, check.IsNil)
+}
+
+func (s *AuditSuite) TearDownTest(c *check.C) {
+	if s.backend != nil {
+		s.backend.Close()
+	}
+}
+
+func (s *AuditSuite) TestRecordsAndForwardsEvents(c *check.C) {
+	listener, err := net.ListenPacket("udp", "127.0.0.1:0")
+	c.Assert(err, check.IsNil)
+	defer listener.Close()
+
+	log, err := New(Config{
+		Backend:   s.backend,
+		Retention: time.Hour,
+		Hostname:  "node-1",
+		Clock:     s.clock,
+		Forwarders: func() ([]storage.LogForwarder, error) {
+			return []storage.LogForwarder{
+				storage.NewLogForwarder("audit", listener.LocalAddr().String(), "udp"),
+			}, nil
+		},
+	})
+	c.Assert(err, check.IsNil)
+
+	log.Emit(storage.AuditEvent{
+		User:   "alice@example.com",
+		Verb:   teleservices.VerbDelete,
+		Kind:   teleservices.KindUser,
+		Name:   "bob@example.com",
+		Result: storage.AuditResultSuccess,
+	})
+
+	events, err := s.backend.GetAuditEvents(storage.AuditEventFilter{})
+	c.Assert(err, check.IsNil)
+	c.Assert(events, check.HasLen, 1)
+	c.Assert(events[0].Time.Equal(s.clock.Now()), check.Equals, true)
+	c.Assert(events[0].Expires.Equal(s.clock.Now().Add(time.Hour)), check.Equals, true)
+
+	buf := make([]byte, 4096)
+	c.Assert(listener.SetReadDeadline(time.Now().Add(5*time.Second)), check.IsNil)
+	n, _, err := listener.ReadFrom(buf)
+	c.Assert(err, check.IsNil)
+	message := string(buf[:n])
+	c.Assert(strings.HasPrefix(message, "<109>1 2018-06-15T10:30:00Z node-1 gravity-audit "), check.Equals, true,
+		check.Commentf(message))
+
+	var forwarded storage.AuditEvent
+	c.Assert(json.Unmarshal([]byte(message[strings.Index(message, "{"):]), &forwarded), check.IsNil)
+	c.Assert(forwarded.ID, check.Equals, events[0].ID)
+	c.Assert(forwarded.User, check.Equals, "alice@example.com")
+}
+
+func (s *AuditSuite) TestFormatsMessageWithToken(c *check.C) {
+	message, err := formatMessage("node-1", `to"ken`, storage.AuditEvent{
+		Time: s.clock.Now(),
+		Kind: storage.KindSMTPConfig,
+	})
+	c.Assert(err, check.IsNil)
+	c.Assert(strings.Contains(string(message), ` smtp [auth token="to\"ken"] {`), check.Equals, true,
+		check.Commentf(string(message)))
+}
+
+func (s *AuditSuite) TestSelectsSeverity(c *check.C) {
+	c.Assert(selectsSeverity(nil, severity), check.Equals, true)
+	c.Assert(selectsSeverity(&storage.LogForwarderSelector{Severity: "info"}, severity), check.Equals, true)
+	c.Assert(selectsSeverity(&storage.LogForwarderSelector{Severity: "notice"}, severity), check.Equals, true)
+	c.Assert(selectsSeverity(&storage.LogForwarderSelector{Severity: "warning"}, severity), check.Equals, false)
+}
+
+func (s *AuditSuite) TestDiffRedactsSecrets(c *check.C) {
+	type spec struct {
+		Host         string `json:"host"`
+		Password     string `json:"password"`
+		ClientSecret string `json:"client_secret,omitempty"`
+	}
+
+	diff, err := Diff(nil, spec{Host: "a", Password: "secret"})
+	c.Assert(err, check.IsNil)
+	c.Assert(diff, check.Equals, "+host: a\n+password: <redacted>")
+
+	diff, err = Diff(spec{Host: "a", Password: "secret"}, spec{Host: "b", Password: "secret"})
+	c.Assert(err, check.IsNil)
+	c.Assert(diff, check.Equals, "-host: a\n+host: b\n password: <redacted>")
+
+	diff, err = Diff(spec{Host: "a", ClientSecret: "secret"}, nil)
+	c.Assert(err, check.IsNil)
+	c.Assert(diff, check.Equals, "-client_secret: <redacted>\n-host: a\n-password: \"\"")
+
+	diff, err = Diff(spec{Host: "a"}, spec{Host: "a"})
+	c.Assert(err, check.IsNil)
+	c.Assert(diff, check.Equals, "")
+}
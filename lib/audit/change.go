@@ -0,0 +1,93 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package audit
+
+import (
+	"github.com/gravitational/gravity/lib/storage"
+
+	teleservices "github.com/gravitational/teleport/lib/services"
+	"github.com/gravitational/trace"
+	log "github.com/sirupsen/logrus"
+)
+
+// NewChange returns a new audited change of the resource with the specified
+// kind and name made with the specified verb
+func NewChange(verb, kind, name, cluster string) *Change {
+	return &Change{verb: verb, kind: kind, name: name, cluster: cluster}
+}
+
+// Change describes an audited change
+type Change struct {
+	verb    string
+	kind    string
+	name    string
+	cluster string
+	// before is the resource state before the change
+	before interface{}
+	// hasBefore is whether the state before the change is known
+	hasBefore bool
+}
+
+// CaptureBefore records the resource state before the change.
+// A resource that is not found has the nil state
+func (c *Change) CaptureBefore(get func() (interface{}, error)) {
+	before, err := get()
+	if err != nil {
+		if !trace.IsNotFound(err) {
+			log.Debugf("Failed to query state of %v %v: %v.", c.kind, c.name, err)
+			return
+		}
+		before = nil
+	}
+	c.before = before
+	c.hasBefore = true
+}
+
+// Event returns the audit event for the change made by the specified user
+// with the specified result and the resource state after the change
+func (c *Change) Event(user string, after interface{}, err error) storage.AuditEvent {
+	event := storage.AuditEvent{
+		User:        user,
+		Verb:        c.verb,
+		Kind:        c.kind,
+		Name:        c.name,
+		ClusterName: c.cluster,
+		Result:      storage.AuditResultSuccess,
+	}
+	if c.verb == VerbUpsert {
+		event.Verb = teleservices.VerbUpdate
+		if c.hasBefore && c.before == nil {
+			event.Verb = teleservices.VerbCreate
+		}
+	}
+	if c.hasBefore || after != nil {
+		diff, errDiff := Diff(c.before, after)
+		if errDiff != nil {
+			log.Debugf("Failed to compute diff for %v %v: %v.", c.kind, c.name, errDiff)
+		}
+		event.Diff = diff
+	}
+	if err != nil {
+		event.Result = storage.AuditResultFailure
+		event.Error = trace.UserMessage(err)
+	}
+	return event
+}
+
+// VerbUpsert denotes a change that either creates or updates a resource.
+// It is recorded as one or the other depending on whether the resource existed
+const VerbUpsert = "upsert"
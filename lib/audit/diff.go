@@ -0,0 +1,140 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package audit
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/gravitational/gravity/lib/utils"
+
+	"github.com/ghodss/yaml"
+	"github.com/gravitational/trace"
+	"github.com/kylelemons/godebug/diff"
+)
+
+// Diff returns the line diff between the YAML representations of the
+// resource state before and after a change, with secrets redacted.
+//
+// A nil before state denotes a newly created resource and
+// a nil after state denotes a removed resource
+func Diff(before, after interface{}) (string, error) {
+	beforeText, err := format(before)
+	if err != nil {
+		return "", trace.Wrap(err)
+	}
+	afterText, err := format(after)
+	if err != nil {
+		return "", trace.Wrap(err)
+	}
+	switch {
+	case beforeText == afterText:
+		return "", nil
+	case beforeText == "":
+		return prefixLines(afterText, "+"), nil
+	case afterText == "":
+		return prefixLines(beforeText, "-"), nil
+	}
+	return diff.Diff(beforeText, afterText), nil
+}
+
+// prefixLines prepends each line of the text with the prefix
+func prefixLines(text, prefix string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = prefix + line
+	}
+	return strings.Join(lines, "\n")
+}
+
+// format returns the YAML representation of the value with secrets redacted
+func format(value interface{}) (string, error) {
+	if value == nil {
+		return "", nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "", trace.Wrap(err)
+	}
+	var object interface{}
+	if err := json.Unmarshal(data, &object); err != nil {
+		return "", trace.Wrap(err)
+	}
+	if object == nil {
+		return "", nil
+	}
+	data, err = yaml.Marshal(redact(object))
+	if err != nil {
+		return "", trace.Wrap(err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// redact replaces the values of secret fields in the decoded JSON object
+func redact(object interface{}) interface{} {
+	return redactFields(object, "")
+}
+
+// redactFields replaces the values of secret fields in the decoded JSON object
+// found under the field with the specified name
+func redactFields(object interface{}, parent string) interface{} {
+	switch value := object.(type) {
+	case map[string]interface{}:
+		for key, field := range value {
+			if isSecret(parent, key) && field != nil && field != "" {
+				value[key] = redacted
+			} else {
+				value[key] = redactFields(field, key)
+			}
+		}
+	case []interface{}:
+		for i := range value {
+			value[i] = redactFields(value[i], parent)
+		}
+	}
+	return object
+}
+
+// isSecret returns true if the field found under the specified parent field
+// holds a credential.
+//
+// Besides the fields named after keys, secrets, tokens, passwords and
+// one-time password seeds,
+// the URLs and headers of alert target sinks are secret as well since
+// they embed the credentials of the receiving service
+func isSecret(parent, field string) bool {
+	field = strings.ToLower(field)
+	for _, word := range secretWords {
+		if strings.Contains(field, word) {
+			return true
+		}
+	}
+	if !utils.StringInSlice(sinkFields, strings.ToLower(parent)) {
+		return false
+	}
+	return field == "url" || field == "headers"
+}
+
+var (
+	// secretWords lists the words that denote secret fields
+	secretWords = []string{"key", "secret", "token", "password", "otp"}
+	// sinkFields lists the fields with the alert target sinks
+	sinkFields = []string{"webhook", "slack", "pagerduty"}
+)
+
+// redacted replaces the values of secret fields
+const redacted = "<redacted>"
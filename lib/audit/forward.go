@@ -0,0 +1,162 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package audit
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/utils"
+
+	"github.com/gravitational/trace"
+)
+
+// send delivers the audit event to the log forwarder as an RFC 5424
+// syslog message with the JSON-encoded event as the message body
+func send(forwarder storage.LogForwarder, hostname string, event storage.AuditEvent) error {
+	if !selectsSeverity(forwarder.GetSelector(), severity) {
+		return nil
+	}
+	message, err := formatMessage(hostname, forwarder.GetToken(), event)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	conn, err := dial(forwarder)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	defer conn.Close()
+	if err := conn.SetDeadline(time.Now().Add(defaults.DialTimeout)); err != nil {
+		return trace.Wrap(err)
+	}
+	if protocol(forwarder) == "tcp" {
+		// stream transports use octet counting framing (RFC 6587)
+		message = append([]byte(fmt.Sprintf("%v ", len(message))), message...)
+	}
+	_, err = conn.Write(message)
+	return trace.Wrap(err)
+}
+
+// formatMessage formats the audit event as an RFC 5424 syslog message
+func formatMessage(hostname, token string, event storage.AuditEvent) ([]byte, error) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	structuredData := "-"
+	if token != "" {
+		structuredData = fmt.Sprintf(`[auth token="%v"]`, escapeParam(token))
+	}
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "<%v>1 %v %v %v %v %v %v ",
+		facility*8+severity,
+		event.Time.UTC().Format(time.RFC3339Nano),
+		hostname,
+		defaults.AuditTag,
+		os.Getpid(),
+		event.Kind,
+		structuredData)
+	buf.Write(data)
+	return buf.Bytes(), nil
+}
+
+// escapeParam escapes the structured data parameter value as per RFC 5424
+func escapeParam(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`).Replace(value)
+}
+
+// selectsSeverity returns true if the selector allows messages
+// with the specified syslog severity
+func selectsSeverity(selector *storage.LogForwarderSelector, severity int) bool {
+	if selector == nil || selector.Severity == "" {
+		return true
+	}
+	for i, name := range storage.LogSeverities {
+		if name == selector.Severity {
+			return severity <= i
+		}
+	}
+	return true
+}
+
+// dial connects to the log forwarder
+func dial(forwarder storage.LogForwarder) (net.Conn, error) {
+	config := forwarder.GetTLS()
+	if config == nil {
+		conn, err := net.DialTimeout(protocol(forwarder), forwarder.GetAddress(), defaults.DialTimeout)
+		if err != nil {
+			return nil, trace.ConvertSystemError(err)
+		}
+		return conn, nil
+	}
+	tlsConfig, err := clientTLSConfig(*config, forwarder.GetAddress())
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	conn, err := tls.DialWithDialer(&net.Dialer{Timeout: defaults.DialTimeout},
+		"tcp", forwarder.GetAddress(), tlsConfig)
+	if err != nil {
+		return nil, trace.ConvertSystemError(err)
+	}
+	return conn, nil
+}
+
+// clientTLSConfig returns the client TLS configuration for the log forwarder
+func clientTLSConfig(config storage.LogForwarderTLS, address string) (*tls.Config, error) {
+	tlsConfig := &tls.Config{ServerName: config.ServerName}
+	if tlsConfig.ServerName == "" {
+		tlsConfig.ServerName = utils.ExtractHost(address)
+	}
+	if config.CACert != "" {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM([]byte(config.CACert)) {
+			return nil, trace.BadParameter("failed to parse CA certificate")
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if config.ClientCert != "" {
+		cert, err := tls.X509KeyPair([]byte(config.ClientCert), []byte(config.ClientKey))
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
+
+func protocol(forwarder storage.LogForwarder) string {
+	if forwarder.GetProtocol() == "" {
+		return "tcp"
+	}
+	return forwarder.GetProtocol()
+}
+
+const (
+	// facility is the syslog facility of audit messages, "log audit"
+	facility = 13
+	// severity is the syslog severity of audit messages, "notice"
+	severity = 5
+)
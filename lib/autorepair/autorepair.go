@@ -0,0 +1,567 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package autorepair implements the controller that replaces failed nodes.
+//
+// The controller runs on the active gravity-site master and watches node
+// health reported by the planet agents and the Kubernetes node conditions.
+// A node that stays failed longer than the grace period is cordoned,
+// drained and removed from the cluster with a forced shrink operation.
+// If a cloud provisioner is configured, a replacement node with the same
+// profile is then provisioned with an expand operation.
+//
+// Master nodes are never repaired automatically since removing them
+// changes the etcd membership and might cost the etcd quorum.
+// The controller state is kept in a ConfigMap, so the repairs in progress
+// are picked up by the controller on the next active master.
+package autorepair
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gravitational/gravity/lib/constants"
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/kubernetes"
+	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/status"
+	"github.com/gravitational/gravity/lib/storage"
+
+	"github.com/gravitational/rigging"
+	"github.com/gravitational/trace"
+	"github.com/jonboulle/clockwork"
+	"github.com/sirupsen/logrus"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubeclient "k8s.io/client-go/kubernetes"
+)
+
+// Config defines the auto-repair controller configuration
+type Config struct {
+	// Operator is the cluster operator service
+	Operator ops.Operator
+	// Client is the Kubernetes client used to query node conditions
+	// and drain failed nodes
+	Client *kubeclient.Clientset
+	// GracePeriod is how long a node has to stay failed before it is repaired
+	GracePeriod time.Duration
+	// MaxNodes is the maximum number of nodes being repaired at once
+	MaxNodes int
+	// Provisioner is the provisioner used to replace removed nodes.
+	// If unset, removed nodes are not replaced
+	Provisioner string
+	// Interval is the node health check interval
+	Interval time.Duration
+	// Clock is used to tell time
+	Clock clockwork.Clock
+	// FieldLogger is used for logging
+	logrus.FieldLogger
+}
+
+// CheckAndSetDefaults validates the configuration and sets defaults
+func (c *Config) CheckAndSetDefaults() error {
+	if c.Operator == nil {
+		return trace.BadParameter("missing Operator")
+	}
+	if c.Client == nil {
+		return trace.BadParameter("missing Client")
+	}
+	if c.GracePeriod == 0 {
+		c.GracePeriod = defaults.AutoRepairGracePeriod
+	}
+	if c.MaxNodes == 0 {
+		c.MaxNodes = defaults.AutoRepairMaxNodes
+	}
+	if c.MaxNodes < 0 {
+		return trace.BadParameter("MaxNodes can't be negative")
+	}
+	if c.Interval == 0 {
+		c.Interval = defaults.AutoRepairCheckInterval
+	}
+	if c.Clock == nil {
+		c.Clock = clockwork.NewRealClock()
+	}
+	if c.FieldLogger == nil {
+		c.FieldLogger = logrus.WithField(trace.Component, "autorepair")
+	}
+	return nil
+}
+
+// New returns a new auto-repair controller
+func New(config Config) (*Controller, error) {
+	if err := config.CheckAndSetDefaults(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	c := &Controller{
+		Config:  config,
+		failed:  make(map[string]time.Time),
+		repairs: make(map[string]*repair),
+		store:   &configMapStore{client: config.Client},
+	}
+	c.getFailedNodes = c.checkNodes
+	c.drainNode = c.drain
+	return c, nil
+}
+
+// Controller repairs failed cluster nodes
+type Controller struct {
+	// Config is the controller configuration
+	Config
+	// failed maps hostnames of failed nodes to the time
+	// they were first seen failed
+	failed map[string]time.Time
+	// repairs maps hostnames of nodes being repaired to the repair state
+	repairs map[string]*repair
+	// getFailedNodes returns failed nodes of the cluster mapped to the failure reason
+	getFailedNodes func(ctx context.Context, cluster ops.Site) (map[string]string, error)
+	// drainNode cordons and drains the specified node
+	drainNode func(ctx context.Context, server storage.Server) error
+	// store persists the controller state
+	store stateStore
+	// loaded is set once the persisted state has been loaded
+	loaded bool
+	// saved is the last persisted state
+	saved []byte
+}
+
+// repair is the state of a node repair
+type repair struct {
+	// Server is the node being repaired
+	Server storage.Server `json:"server"`
+	// Shrink is the operation removing the node
+	Shrink ops.SiteOperationKey `json:"shrink"`
+	// Expand is the operation adding the replacement node
+	Expand *ops.SiteOperationKey `json:"expand,omitempty"`
+}
+
+// state is the persisted controller state
+type state struct {
+	// Failed maps hostnames of failed nodes to the time
+	// they were first seen failed
+	Failed map[string]time.Time `json:"failed"`
+	// Repairs maps hostnames of nodes being repaired to the repair state
+	Repairs map[string]*repair `json:"repairs"`
+}
+
+// Run checks node health periodically and repairs failed nodes
+// until the context is canceled
+func (c *Controller) Run(ctx context.Context) error {
+	c.Infof("Starting node auto-repair, grace period %v, at most %v node(s) at once.",
+		c.GracePeriod, c.MaxNodes)
+	for {
+		select {
+		case <-c.Clock.After(c.Interval):
+			if err := c.check(ctx); err != nil {
+				c.Errorf("Failed to check nodes: %v.", trace.DebugReport(err))
+			}
+		case <-ctx.Done():
+			c.Info("Stopping node auto-repair.")
+			return nil
+		}
+	}
+}
+
+// check restores the state persisted by the controller on the previously
+// active master, repairs the nodes and persists the new state
+func (c *Controller) check(ctx context.Context) error {
+	if err := c.load(); err != nil {
+		return trace.Wrap(err, "failed to load repair state")
+	}
+	err := c.repairNodes(ctx)
+	if errSave := c.save(); errSave != nil {
+		return trace.NewAggregate(err, trace.Wrap(errSave, "failed to save repair state"))
+	}
+	return trace.Wrap(err)
+}
+
+// repairNodes updates the state of ongoing repairs and starts repairs
+// of the nodes that have been failed longer than the grace period
+func (c *Controller) repairNodes(ctx context.Context) error {
+	cluster, err := c.Operator.GetLocalSite()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if err := c.updateRepairs(*cluster); err != nil {
+		return trace.Wrap(err)
+	}
+
+	failed, err := c.getFailedNodes(ctx, *cluster)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	now := c.Clock.Now()
+	for hostname := range c.failed {
+		if _, ok := failed[hostname]; !ok {
+			c.Infof("Node %v has recovered.", hostname)
+			delete(c.failed, hostname)
+		}
+	}
+	for hostname, reason := range failed {
+		if _, ok := c.failed[hostname]; !ok {
+			c.Warnf("Node %v has failed: %v.", hostname, reason)
+			if server := findServer(*cluster, hostname); server != nil && server.IsMaster() {
+				c.Warnf("Node %v is a master and will not be repaired automatically.", hostname)
+			}
+			c.failed[hostname] = now
+		}
+	}
+
+	// nodes can only be removed by one operation at a time and not while
+	// the cluster is being updated or is undergoing another operation
+	switch cluster.State {
+	case ops.SiteStateActive, ops.SiteStateDegraded:
+	default:
+		c.Debugf("Cluster is %v, repairs are paused.", cluster.State)
+		return nil
+	}
+	if len(c.repairs) >= c.MaxNodes {
+		c.Debugf("%v node(s) are being repaired, at most %v allowed.", len(c.repairs), c.MaxNodes)
+		return nil
+	}
+	for _, repair := range c.repairs {
+		if repair.Expand == nil {
+			// the previous node is still being removed
+			return nil
+		}
+	}
+	// too many failed nodes likely means that this node is partitioned
+	// from the rest of the cluster rather than all of them failing
+	if len(failed)*2 > len(cluster.ClusterState.Servers) {
+		c.Warnf("%v of %v nodes have failed, not repairing.", len(failed), len(cluster.ClusterState.Servers))
+		return nil
+	}
+
+	server := c.nextNode(*cluster, now)
+	if server == nil {
+		return nil
+	}
+	return trace.Wrap(c.repair(ctx, *cluster, *server))
+}
+
+// nextNode returns the regular node that has been failed longest and past
+// the grace period or nil if there is no node to repair
+func (c *Controller) nextNode(cluster ops.Site, now time.Time) *storage.Server {
+	var servers []storage.Server
+	for hostname, since := range c.failed {
+		if _, ok := c.repairs[hostname]; ok {
+			continue
+		}
+		if now.Sub(since) < c.GracePeriod {
+			continue
+		}
+		server := findServer(cluster, hostname)
+		if server == nil || server.IsMaster() {
+			continue
+		}
+		servers = append(servers, *server)
+	}
+	if len(servers) == 0 {
+		return nil
+	}
+	sort.Slice(servers, func(i, j int) bool {
+		return c.failed[servers[i].Hostname].Before(c.failed[servers[j].Hostname])
+	})
+	return &servers[0]
+}
+
+// findServer returns the cluster server with the specified hostname
+// or nil if there is no such server
+func findServer(cluster ops.Site, hostname string) *storage.Server {
+	for _, server := range cluster.ClusterState.Servers {
+		if server.Hostname == hostname {
+			return &server
+		}
+	}
+	return nil
+}
+
+// repair drains the specified node and starts the operation to remove it
+func (c *Controller) repair(ctx context.Context, cluster ops.Site, server storage.Server) error {
+	c.Infof("Repairing node %v that has failed since %v.", server.Hostname,
+		c.failed[server.Hostname].Format(time.RFC3339))
+	drainCtx, cancel := context.WithTimeout(ctx, defaults.AutoRepairDrainTimeout)
+	defer cancel()
+	// the node is removed even if it could not be drained
+	// since pods on a failed node might never terminate
+	if err := c.drainNode(drainCtx, server); err != nil {
+		c.Warnf("Failed to drain node %v: %v.", server.Hostname, trace.DebugReport(err))
+	}
+	key, err := c.Operator.CreateSiteShrinkOperation(ops.CreateSiteShrinkOperationRequest{
+		AccountID:  cluster.AccountID,
+		SiteDomain: cluster.Domain,
+		Servers:    []string{server.Hostname},
+		Force:      true,
+	})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	c.Infof("Started operation %v to remove node %v.", key.OperationID, server.Hostname)
+	c.repairs[server.Hostname] = &repair{
+		Server: server,
+		Shrink: *key,
+	}
+	return nil
+}
+
+// updateRepairs checks the operations of ongoing repairs, starts
+// the replacement of removed nodes and forgets the finished repairs
+func (c *Controller) updateRepairs(cluster ops.Site) error {
+	for hostname, repair := range c.repairs {
+		if repair.Expand != nil {
+			operation, err := c.Operator.GetSiteOperation(*repair.Expand)
+			if err != nil {
+				return trace.Wrap(err)
+			}
+			if !operation.IsFinished() {
+				continue
+			}
+			if operation.IsFailed() {
+				c.Warnf("Failed to replace node %v, see operation %v.", hostname, operation.ID)
+			} else {
+				c.Infof("Node %v has been replaced.", hostname)
+			}
+			delete(c.repairs, hostname)
+			continue
+		}
+		operation, err := c.Operator.GetSiteOperation(repair.Shrink)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		if !operation.IsFinished() {
+			continue
+		}
+		delete(c.failed, hostname)
+		if operation.IsFailed() {
+			c.Warnf("Failed to remove node %v, see operation %v.", hostname, operation.ID)
+			delete(c.repairs, hostname)
+			continue
+		}
+		c.Infof("Node %v has been removed.", hostname)
+		if c.Provisioner == "" {
+			delete(c.repairs, hostname)
+			continue
+		}
+		key, err := c.replace(cluster, repair.Server)
+		if err != nil {
+			c.Warnf("Failed to replace node %v: %v.", hostname, trace.DebugReport(err))
+			delete(c.repairs, hostname)
+			continue
+		}
+		repair.Expand = key
+	}
+	return nil
+}
+
+// replace starts the operation that provisions a node with the profile
+// and the instance type of the specified removed node
+func (c *Controller) replace(cluster ops.Site, server storage.Server) (*ops.SiteOperationKey, error) {
+	if server.InstanceType == "" {
+		return nil, trace.BadParameter("instance type of node %v is unknown", server.Hostname)
+	}
+	key, err := c.Operator.CreateSiteExpandOperation(ops.CreateSiteExpandOperationRequest{
+		AccountID:   cluster.AccountID,
+		SiteDomain:  cluster.Domain,
+		Provisioner: c.Provisioner,
+		Servers:     map[string]int{server.Role: 1},
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	// the provisioner needs the instance type of each profile
+	// like with expand operations started from the UI
+	err = c.Operator.UpdateExpandOperationState(*key, ops.OperationUpdateRequest{
+		Profiles: map[string]storage.ServerProfileRequest{
+			server.Role: {
+				InstanceType: server.InstanceType,
+				Count:        1,
+			},
+		},
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	err = c.Operator.SiteExpandOperationStart(*key)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	c.Infof("Started operation %v to replace node %v.", key.OperationID, server.Hostname)
+	return key, nil
+}
+
+// load restores the persisted controller state once
+func (c *Controller) load() error {
+	if c.loaded {
+		return nil
+	}
+	data, err := c.store.load()
+	if err != nil && !trace.IsNotFound(err) {
+		return trace.Wrap(err)
+	}
+	if len(data) != 0 {
+		var state state
+		if err := json.Unmarshal(data, &state); err != nil {
+			return trace.Wrap(err)
+		}
+		for hostname, since := range state.Failed {
+			c.failed[hostname] = since
+		}
+		for hostname, repair := range state.Repairs {
+			c.repairs[hostname] = repair
+		}
+		c.Infof("Restored repair state: %v failed node(s), %v repair(s) in progress.",
+			len(c.failed), len(c.repairs))
+	}
+	c.saved = data
+	c.loaded = true
+	return nil
+}
+
+// save persists the controller state if it has changed
+func (c *Controller) save() error {
+	if !c.loaded {
+		return nil
+	}
+	data, err := json.Marshal(state{Failed: c.failed, Repairs: c.repairs})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if bytes.Equal(data, c.saved) {
+		return nil
+	}
+	if err := c.store.save(data); err != nil {
+		return trace.Wrap(err)
+	}
+	c.saved = data
+	return nil
+}
+
+// stateStore persists the controller state
+type stateStore interface {
+	// load returns the persisted state
+	load() ([]byte, error)
+	// save persists the state
+	save(data []byte) error
+}
+
+// configMapStore keeps the controller state in a ConfigMap
+type configMapStore struct {
+	client kubeclient.Interface
+}
+
+func (s *configMapStore) load() ([]byte, error) {
+	configMap, err := s.client.CoreV1().ConfigMaps(defaults.KubeSystemNamespace).Get(
+		constants.AutoRepairConfigMap, metav1.GetOptions{})
+	if err != nil {
+		return nil, trace.Wrap(rigging.ConvertError(err))
+	}
+	return []byte(configMap.Data[stateKey]), nil
+}
+
+func (s *configMapStore) save(data []byte) error {
+	configMaps := s.client.CoreV1().ConfigMaps(defaults.KubeSystemNamespace)
+	configMap, err := configMaps.Get(constants.AutoRepairConfigMap, metav1.GetOptions{})
+	if err != nil {
+		err = rigging.ConvertError(err)
+		if !trace.IsNotFound(err) {
+			return trace.Wrap(err)
+		}
+		_, err = configMaps.Create(&v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      constants.AutoRepairConfigMap,
+				Namespace: defaults.KubeSystemNamespace,
+			},
+			Data: map[string]string{stateKey: string(data)},
+		})
+		return trace.Wrap(rigging.ConvertError(err))
+	}
+	if configMap.Data == nil {
+		configMap.Data = make(map[string]string)
+	}
+	configMap.Data[stateKey] = string(data)
+	_, err = configMaps.Update(configMap)
+	return trace.Wrap(rigging.ConvertError(err))
+}
+
+// stateKey is the ConfigMap key with the controller state
+const stateKey = "state"
+
+// checkNodes returns the cluster nodes that are either reported offline by
+// the planet agents or not ready by Kubernetes mapped to the failure reason
+func (c *Controller) checkNodes(ctx context.Context, cluster ops.Site) (map[string]string, error) {
+	agentStatus, err := status.FromPlanetAgent(ctx, cluster.ClusterState.Servers)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	nodes, err := c.Client.CoreV1().Nodes().List(metav1.ListOptions{})
+	if err != nil {
+		return nil, rigging.ConvertError(err)
+	}
+	return failedNodes(cluster.ClusterState.Servers, agentStatus.Nodes, nodes.Items), nil
+}
+
+// drain cordons and drains the Kubernetes node of the specified server
+func (c *Controller) drain(ctx context.Context, server storage.Server) error {
+	node, err := kubernetes.GetNode(c.Client, server)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return trace.Wrap(kubernetes.Drain(ctx, c.Client, node.Name))
+}
+
+// failedNodes returns the servers that are offline according to the planet agent
+// or have their Kubernetes node not ready, mapped to the failure reason
+func failedNodes(servers []storage.Server, agentNodes []status.ClusterServer, kubeNodes []v1.Node) map[string]string {
+	offline := make(map[string]bool)
+	for _, node := range agentNodes {
+		if node.Status == status.NodeOffline {
+			offline[node.AdvertiseIP] = true
+		}
+	}
+	ready := make(map[string]bool)
+	for _, node := range kubeNodes {
+		ready[node.Labels[defaults.KubernetesHostnameLabel]] = isNodeReady(node)
+	}
+	failed := make(map[string]string)
+	for _, server := range servers {
+		var reasons []string
+		if offline[server.AdvertiseIP] {
+			reasons = append(reasons, "planet agent is offline")
+		}
+		if isReady, ok := ready[server.KubeNodeID()]; !ok {
+			reasons = append(reasons, "Kubernetes node is missing")
+		} else if !isReady {
+			reasons = append(reasons, "Kubernetes node is not ready")
+		}
+		if len(reasons) != 0 {
+			failed[server.Hostname] = strings.Join(reasons, ", ")
+		}
+	}
+	return failed
+}
+
+// isNodeReady returns true if the node has the ready condition set
+func isNodeReady(node v1.Node) bool {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == v1.NodeReady {
+			return condition.Status == v1.ConditionTrue
+		}
+	}
+	return false
+}
@@ -0,0 +1,396 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package autorepair
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/schema"
+	"github.com/gravitational/gravity/lib/status"
+	"github.com/gravitational/gravity/lib/storage"
+
+	"github.com/gravitational/trace"
+	"github.com/jonboulle/clockwork"
+	"github.com/sirupsen/logrus"
+	check "gopkg.in/check.v1"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestAutoRepair(t *testing.T) { check.TestingT(t) }
+
+type AutoRepairSuite struct {
+	clock    clockwork.FakeClock
+	operator *mockOperator
+	store    *memoryStore
+	failed   map[string]string
+	drained  []string
+}
+
+var _ = check.Suite(&AutoRepairSuite{})
+
+func (s *AutoRepairSuite) SetUpTest(c *check.C) {
+	s.clock = clockwork.NewFakeClockAt(time.Date(2018, time.June, 15, 10, 30, 0, 0, time.UTC))
+	s.operator = newMockOperator(5)
+	s.store = &memoryStore{}
+	s.failed = make(map[string]string)
+	s.drained = nil
+}
+
+func (s *AutoRepairSuite) newController(provisioner string) *Controller {
+	c := &Controller{
+		Config: Config{
+			Operator:    s.operator,
+			GracePeriod: 10 * time.Minute,
+			MaxNodes:    1,
+			Provisioner: provisioner,
+			Clock:       s.clock,
+			FieldLogger: logrus.WithField(trace.Component, "test"),
+		},
+		failed:  make(map[string]time.Time),
+		repairs: make(map[string]*repair),
+		store:   s.store,
+	}
+	c.getFailedNodes = func(context.Context, ops.Site) (map[string]string, error) {
+		return s.failed, nil
+	}
+	c.drainNode = func(_ context.Context, server storage.Server) error {
+		s.drained = append(s.drained, server.Hostname)
+		return nil
+	}
+	return c
+}
+
+func (s *AutoRepairSuite) TestRepairsAfterGracePeriod(c *check.C) {
+	controller := s.newController("")
+	s.failed["node-2"] = "planet agent is offline"
+	c.Assert(controller.check(context.TODO()), check.IsNil)
+	c.Assert(s.operator.shrinks, check.HasLen, 0)
+
+	s.clock.Advance(5 * time.Minute)
+	c.Assert(controller.check(context.TODO()), check.IsNil)
+	c.Assert(s.operator.shrinks, check.HasLen, 0)
+
+	s.clock.Advance(5 * time.Minute)
+	c.Assert(controller.check(context.TODO()), check.IsNil)
+	c.Assert(s.drained, check.DeepEquals, []string{"node-2"})
+	c.Assert(s.operator.shrinks, check.DeepEquals, []ops.CreateSiteShrinkOperationRequest{{
+		AccountID:  "account",
+		SiteDomain: "example.com",
+		Servers:    []string{"node-2"},
+		Force:      true,
+	}})
+	c.Assert(s.operator.expands, check.HasLen, 0)
+}
+
+func (s *AutoRepairSuite) TestForgetsRecoveredNodes(c *check.C) {
+	controller := s.newController("")
+	s.failed["node-2"] = "planet agent is offline"
+	c.Assert(controller.check(context.TODO()), check.IsNil)
+
+	s.clock.Advance(5 * time.Minute)
+	delete(s.failed, "node-2")
+	c.Assert(controller.check(context.TODO()), check.IsNil)
+
+	s.clock.Advance(5 * time.Minute)
+	s.failed["node-2"] = "planet agent is offline"
+	c.Assert(controller.check(context.TODO()), check.IsNil)
+	c.Assert(s.operator.shrinks, check.HasLen, 0)
+}
+
+func (s *AutoRepairSuite) TestRepairsOneNodeAtATime(c *check.C) {
+	controller := s.newController("")
+	s.failed["node-2"] = "planet agent is offline"
+	c.Assert(controller.check(context.TODO()), check.IsNil)
+	s.clock.Advance(time.Minute)
+	s.failed["node-3"] = "Kubernetes node is not ready"
+	c.Assert(controller.check(context.TODO()), check.IsNil)
+	s.clock.Advance(10 * time.Minute)
+	c.Assert(controller.check(context.TODO()), check.IsNil)
+	c.Assert(s.operator.shrinkServers(), check.DeepEquals, []string{"node-2"})
+
+	// the second node waits until the first one has been removed
+	s.clock.Advance(time.Minute)
+	c.Assert(controller.check(context.TODO()), check.IsNil)
+	c.Assert(s.operator.shrinkServers(), check.DeepEquals, []string{"node-2"})
+
+	s.operator.complete(ops.OperationStateCompleted)
+	delete(s.failed, "node-2")
+	c.Assert(controller.check(context.TODO()), check.IsNil)
+	c.Assert(s.operator.shrinkServers(), check.DeepEquals, []string{"node-2", "node-3"})
+}
+
+func (s *AutoRepairSuite) TestPausesWhenMostNodesFail(c *check.C) {
+	controller := s.newController("")
+	for _, hostname := range []string{"node-1", "node-2", "node-3"} {
+		s.failed[hostname] = "planet agent is offline"
+	}
+	c.Assert(controller.check(context.TODO()), check.IsNil)
+	s.clock.Advance(time.Hour)
+	c.Assert(controller.check(context.TODO()), check.IsNil)
+	c.Assert(s.operator.shrinks, check.HasLen, 0)
+}
+
+func (s *AutoRepairSuite) TestReplacesRemovedNodes(c *check.C) {
+	controller := s.newController(schema.ProvisionerAWSTerraform)
+	s.failed["node-2"] = "planet agent is offline"
+	c.Assert(controller.check(context.TODO()), check.IsNil)
+	s.clock.Advance(10 * time.Minute)
+	c.Assert(controller.check(context.TODO()), check.IsNil)
+	c.Assert(s.operator.shrinks, check.HasLen, 1)
+
+	s.operator.complete(ops.OperationStateCompleted)
+	delete(s.failed, "node-2")
+	c.Assert(controller.check(context.TODO()), check.IsNil)
+	c.Assert(s.operator.expands, check.DeepEquals, []ops.CreateSiteExpandOperationRequest{{
+		AccountID:   "account",
+		SiteDomain:  "example.com",
+		Provisioner: schema.ProvisionerAWSTerraform,
+		Servers:     map[string]int{"worker": 1},
+	}})
+	c.Assert(s.operator.updates, check.DeepEquals, map[string]ops.OperationUpdateRequest{
+		"operation-2": {
+			Profiles: map[string]storage.ServerProfileRequest{
+				"worker": {InstanceType: "m4.xlarge", Count: 1},
+			},
+		},
+	})
+	c.Assert(s.operator.started, check.DeepEquals, []string{"operation-2"})
+	c.Assert(controller.repairs, check.HasLen, 1)
+
+	s.operator.complete(ops.OperationStateCompleted)
+	c.Assert(controller.check(context.TODO()), check.IsNil)
+	c.Assert(controller.repairs, check.HasLen, 0)
+}
+
+func (s *AutoRepairSuite) TestDoesNotReplaceNodesOfUnknownInstanceType(c *check.C) {
+	s.operator.cluster.ClusterState.Servers[1].InstanceType = ""
+	controller := s.newController(schema.ProvisionerAWSTerraform)
+	s.failed["node-2"] = "planet agent is offline"
+	c.Assert(controller.check(context.TODO()), check.IsNil)
+	s.clock.Advance(10 * time.Minute)
+	c.Assert(controller.check(context.TODO()), check.IsNil)
+	c.Assert(s.operator.shrinks, check.HasLen, 1)
+
+	s.operator.complete(ops.OperationStateCompleted)
+	delete(s.failed, "node-2")
+	c.Assert(controller.check(context.TODO()), check.IsNil)
+	c.Assert(s.operator.expands, check.HasLen, 0)
+	c.Assert(controller.repairs, check.HasLen, 0)
+}
+
+func (s *AutoRepairSuite) TestDoesNotRepairMasters(c *check.C) {
+	controller := s.newController("")
+	s.failed["node-1"] = "planet agent is offline"
+	c.Assert(controller.check(context.TODO()), check.IsNil)
+	s.clock.Advance(time.Hour)
+	c.Assert(controller.check(context.TODO()), check.IsNil)
+	c.Assert(s.operator.shrinks, check.HasLen, 0)
+
+	// regular nodes are still repaired
+	s.failed["node-2"] = "planet agent is offline"
+	c.Assert(controller.check(context.TODO()), check.IsNil)
+	s.clock.Advance(10 * time.Minute)
+	c.Assert(controller.check(context.TODO()), check.IsNil)
+	c.Assert(s.operator.shrinkServers(), check.DeepEquals, []string{"node-2"})
+}
+
+func (s *AutoRepairSuite) TestResumesRepairOnAnotherMaster(c *check.C) {
+	controller := s.newController(schema.ProvisionerAWSTerraform)
+	s.failed["node-2"] = "planet agent is offline"
+	c.Assert(controller.check(context.TODO()), check.IsNil)
+	s.clock.Advance(10 * time.Minute)
+	c.Assert(controller.check(context.TODO()), check.IsNil)
+	c.Assert(s.operator.shrinks, check.HasLen, 1)
+
+	// the controller on the new active master picks up the repair
+	s.operator.complete(ops.OperationStateCompleted)
+	delete(s.failed, "node-2")
+	controller = s.newController(schema.ProvisionerAWSTerraform)
+	c.Assert(controller.check(context.TODO()), check.IsNil)
+	c.Assert(s.operator.started, check.DeepEquals, []string{"operation-2"})
+	c.Assert(s.operator.shrinks, check.HasLen, 1)
+
+	controller = s.newController(schema.ProvisionerAWSTerraform)
+	s.operator.complete(ops.OperationStateCompleted)
+	c.Assert(controller.check(context.TODO()), check.IsNil)
+	c.Assert(controller.repairs, check.HasLen, 0)
+	c.Assert(s.operator.expands, check.HasLen, 1)
+}
+
+func (s *AutoRepairSuite) TestFindsFailedNodes(c *check.C) {
+	servers := []storage.Server{
+		{Hostname: "node-1", AdvertiseIP: "10.0.0.1"},
+		{Hostname: "node-2", AdvertiseIP: "10.0.0.2"},
+		{Hostname: "node-3", AdvertiseIP: "10.0.0.3"},
+		{Hostname: "node-4", AdvertiseIP: "10.0.0.4"},
+	}
+	agentNodes := []status.ClusterServer{
+		{AdvertiseIP: "10.0.0.1", Status: status.NodeHealthy},
+		{AdvertiseIP: "10.0.0.2", Status: status.NodeOffline},
+		{AdvertiseIP: "10.0.0.3", Status: status.NodeDegraded},
+		{AdvertiseIP: "10.0.0.4", Status: status.NodeHealthy},
+	}
+	kubeNodes := []v1.Node{
+		newNode("10.0.0.1", v1.ConditionTrue),
+		newNode("10.0.0.2", v1.ConditionUnknown),
+		newNode("10.0.0.3", v1.ConditionFalse),
+	}
+	c.Assert(failedNodes(servers, agentNodes, kubeNodes), check.DeepEquals, map[string]string{
+		"node-2": "planet agent is offline, Kubernetes node is not ready",
+		"node-3": "Kubernetes node is not ready",
+		"node-4": "Kubernetes node is missing",
+	})
+}
+
+func newNode(name string, ready v1.ConditionStatus) v1.Node {
+	return v1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: map[string]string{defaults.KubernetesHostnameLabel: name},
+		},
+		Status: v1.NodeStatus{
+			Conditions: []v1.NodeCondition{{Type: v1.NodeReady, Status: ready}},
+		},
+	}
+}
+
+func newMockOperator(nodes int) *mockOperator {
+	cluster := ops.Site{
+		AccountID: "account",
+		Domain:    "example.com",
+		State:     ops.SiteStateActive,
+	}
+	for i := 1; i <= nodes; i++ {
+		server := storage.Server{
+			Hostname:     fmt.Sprintf("node-%v", i),
+			AdvertiseIP:  fmt.Sprintf("10.0.0.%v", i),
+			Role:         "worker",
+			ClusterRole:  string(schema.ServiceRoleNode),
+			InstanceType: "m4.xlarge",
+		}
+		if i == 1 {
+			server.Role = "master"
+			server.ClusterRole = string(schema.ServiceRoleMaster)
+		}
+		cluster.ClusterState.Servers = append(cluster.ClusterState.Servers, server)
+	}
+	return &mockOperator{
+		cluster:    cluster,
+		operations: make(map[string]*ops.SiteOperation),
+		updates:    make(map[string]ops.OperationUpdateRequest),
+	}
+}
+
+type mockOperator struct {
+	ops.Operator
+	cluster    ops.Site
+	operations map[string]*ops.SiteOperation
+	shrinks    []ops.CreateSiteShrinkOperationRequest
+	expands    []ops.CreateSiteExpandOperationRequest
+	updates    map[string]ops.OperationUpdateRequest
+	started    []string
+}
+
+func (o *mockOperator) GetLocalSite() (*ops.Site, error) {
+	cluster := o.cluster
+	return &cluster, nil
+}
+
+func (o *mockOperator) CreateSiteShrinkOperation(req ops.CreateSiteShrinkOperationRequest) (*ops.SiteOperationKey, error) {
+	o.shrinks = append(o.shrinks, req)
+	return o.createOperation(ops.OperationShrink, ops.SiteStateShrinking), nil
+}
+
+func (o *mockOperator) CreateSiteExpandOperation(req ops.CreateSiteExpandOperationRequest) (*ops.SiteOperationKey, error) {
+	o.expands = append(o.expands, req)
+	return o.createOperation(ops.OperationExpand, ops.SiteStateExpanding), nil
+}
+
+func (o *mockOperator) UpdateExpandOperationState(key ops.SiteOperationKey, req ops.OperationUpdateRequest) error {
+	o.updates[key.OperationID] = req
+	return nil
+}
+
+func (o *mockOperator) SiteExpandOperationStart(key ops.SiteOperationKey) error {
+	if _, ok := o.updates[key.OperationID]; !ok {
+		return trace.BadParameter("operation %v has no server profiles", key.OperationID)
+	}
+	o.started = append(o.started, key.OperationID)
+	return nil
+}
+
+func (o *mockOperator) GetSiteOperation(key ops.SiteOperationKey) (*ops.SiteOperation, error) {
+	operation, ok := o.operations[key.OperationID]
+	if !ok {
+		return nil, trace.NotFound("operation %v not found", key.OperationID)
+	}
+	return operation, nil
+}
+
+func (o *mockOperator) createOperation(operationType, clusterState string) *ops.SiteOperationKey {
+	operation := &ops.SiteOperation{
+		ID:         fmt.Sprintf("operation-%v", len(o.operations)+1),
+		AccountID:  o.cluster.AccountID,
+		SiteDomain: o.cluster.Domain,
+		Type:       operationType,
+		State:      "in_progress",
+	}
+	o.operations[operation.ID] = operation
+	o.cluster.State = clusterState
+	key := operation.Key()
+	return &key
+}
+
+// complete finishes all operations in progress with the specified state
+func (o *mockOperator) complete(state string) {
+	for _, operation := range o.operations {
+		if !operation.IsFinished() {
+			operation.State = state
+		}
+	}
+	o.cluster.State = ops.SiteStateActive
+}
+
+func (o *mockOperator) shrinkServers() (servers []string) {
+	for _, shrink := range o.shrinks {
+		servers = append(servers, shrink.Servers...)
+	}
+	return servers
+}
+
+// memoryStore keeps the controller state in memory
+type memoryStore struct {
+	data []byte
+}
+
+func (s *memoryStore) load() ([]byte, error) {
+	if s.data == nil {
+		return nil, trace.NotFound("no state")
+	}
+	return s.data, nil
+}
+
+func (s *memoryStore) save(data []byte) error {
+	s.data = data
+	return nil
+}
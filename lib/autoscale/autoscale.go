@@ -0,0 +1,204 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+Package autoscale integrates the cluster with external systems that
+scale cluster nodes up and down.
+
+Each provider implements the Autoscaler interface:
+
+* aws receives lifecycle events of the auto scaling group from SQS
+  and publishes the cluster discovery information to SSM parameter store
+* gce watches the managed instance group and publishes the cluster
+  discovery information to the project metadata
+* webhook receives scaling events posted by an external system over HTTP
+  and returns the cluster discovery information in response
+
+Providers share the same paths to join and remove nodes: new nodes join
+the cluster with the published join token and the nodes removed by the
+provider are removed from the cluster with a forced shrink operation.
+*/
+package autoscale
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gravitational/gravity/lib/constants"
+	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/storage"
+
+	"github.com/gravitational/trace"
+	log "github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Autoscaler applies scaling events of an autoscaling provider to the cluster
+type Autoscaler interface {
+	// Run processes scaling events and publishes the cluster discovery
+	// information until the context is cancelled
+	Run(ctx context.Context, operator ops.Operator) error
+}
+
+// Operator is a subset of the operator service used to remove nodes
+type Operator interface {
+	// GetLocalSite returns the local cluster
+	GetLocalSite() (*ops.Site, error)
+	// CreateSiteShrinkOperation starts the operation to remove nodes
+	CreateSiteShrinkOperation(ops.CreateSiteShrinkOperationRequest) (*ops.SiteOperationKey, error)
+}
+
+// Node identifies a node by any of the attributes the provider is aware of
+type Node struct {
+	// InstanceID is the cloud instance ID of the node
+	InstanceID string `json:"instance_id,omitempty"`
+	// Hostname is the hostname of the node
+	Hostname string `json:"hostname,omitempty"`
+	// AdvertiseIP is the IP address the node is advertised on in the cluster
+	AdvertiseIP string `json:"advertise_ip,omitempty"`
+}
+
+// Check makes sure the node is identified by at least one attribute
+func (n Node) Check() error {
+	if n.InstanceID == "" && n.Hostname == "" && n.AdvertiseIP == "" {
+		return trace.BadParameter("node should be identified by instance ID, hostname or advertise IP")
+	}
+	return nil
+}
+
+// String returns a textual representation of the node
+func (n Node) String() string {
+	return fmt.Sprintf("node(instance=%v, hostname=%v, ip=%v)", n.InstanceID, n.Hostname, n.AdvertiseIP)
+}
+
+// Matches returns true if the server is the node
+func (n Node) Matches(server storage.Server) bool {
+	switch {
+	case n.InstanceID != "":
+		return n.InstanceID == server.InstanceID
+	case n.Hostname != "":
+		return n.Hostname == server.Hostname
+	default:
+		return n.AdvertiseIP == server.AdvertiseIP
+	}
+}
+
+// FindServer returns the cluster server that is the specified node
+func FindServer(cluster ops.Site, node Node) (*storage.Server, error) {
+	for _, server := range cluster.ClusterState.Servers {
+		if node.Matches(server) {
+			return &server, nil
+		}
+	}
+	return nil, trace.NotFound("%v is not a member of the cluster", node)
+}
+
+// RemoveNode starts the operation to remove the node that has been
+// removed by the autoscaling provider.
+//
+// The node is removed in forced mode as the instance is usually
+// offline by the time the provider reports its removal
+func RemoveNode(operator Operator, node Node) (*ops.SiteOperationKey, error) {
+	if err := node.Check(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	cluster, err := operator.GetLocalSite()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	server, err := FindServer(*cluster, node)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	key, err := operator.CreateSiteShrinkOperation(
+		ops.CreateSiteShrinkOperationRequest{
+			AccountID:   cluster.AccountID,
+			SiteDomain:  cluster.Domain,
+			Servers:     []string{server.Hostname},
+			Force:       true,
+			NodeRemoved: true,
+		})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	log.Infof("Initiated shrink operation %v for node %v.", key.OperationID, server.Hostname)
+	return key, nil
+}
+
+// Discovery is the information new nodes use to join the cluster
+type Discovery struct {
+	// ServiceURL is the address of the cluster controller
+	ServiceURL string `json:"service_url"`
+	// Token is the cluster join token
+	Token string `json:"token"`
+}
+
+// GetDiscovery returns the cluster discovery information
+func GetDiscovery(operator ops.Operator, client kubernetes.Interface) (*Discovery, error) {
+	cluster, err := operator.GetLocalSite()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	token, err := operator.GetExpandToken(cluster.Key())
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	serviceURL, err := GetServiceURL(client)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &Discovery{
+		ServiceURL: serviceURL,
+		Token:      token.Token,
+	}, nil
+}
+
+// GetServiceURL returns the address of the cluster controller load balancer
+func GetServiceURL(client kubernetes.Interface) (string, error) {
+	service, err := client.CoreV1().Services(constants.KubeSystemNamespace).Get(constants.GravityServiceName, v1.GetOptions{})
+	if err != nil {
+		return "", trace.Wrap(err)
+	}
+	var port int32
+	for _, p := range service.Spec.Ports {
+		if p.Name == constants.GravityServicePortName {
+			port = p.Port
+			break
+		}
+	}
+	if port == 0 {
+		return "", trace.NotFound("no port %q found for service %q", constants.GravityServicePortName, constants.GravityServiceName)
+	}
+	for _, ingress := range service.Status.LoadBalancer.Ingress {
+		if ingress.Hostname != "" {
+			return fmt.Sprintf("https://%v:%v", ingress.Hostname, port), nil
+		}
+		if ingress.IP != "" {
+			return fmt.Sprintf("https://%v:%v", ingress.IP, port), nil
+		}
+	}
+	return "", trace.NotFound("ingress load balancer not found for %v", constants.GravityServiceName)
+}
+
+const (
+	// ProviderAWS is the autoscaler for AWS auto scaling groups
+	ProviderAWS = "aws"
+	// ProviderGCE is the autoscaler for GCE managed instance groups
+	ProviderGCE = "gce"
+	// ProviderWebhook is the autoscaler driven by an external system over HTTP
+	ProviderWebhook = "webhook"
+)
@@ -21,6 +21,7 @@ import (
 	"fmt"
 
 	gaws "github.com/gravitational/gravity/lib/cloudprovider/aws"
+	"github.com/gravitational/gravity/lib/ops"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
@@ -123,6 +124,20 @@ func New(cfg Config) (*Autoscaler, error) {
 	return a, nil
 }
 
+// Run receives the lifecycle events of the auto scaling group and publishes
+// the cluster discovery information until the context is cancelled
+func (a *Autoscaler) Run(ctx context.Context, operator ops.Operator) error {
+	queueURL, err := a.GetQueueURL(ctx)
+	if err != nil {
+		return trace.Wrap(err, "failed to get autoscale queue URL")
+	}
+	// publish discovery information about this cluster
+	go a.PublishDiscovery(ctx, operator)
+	// receive and process events from SQS notification service
+	a.ProcessEvents(ctx, queueURL, operator)
+	return nil
+}
+
 // DeleteEvent deletes SQS message associated with event
 func (a *Autoscaler) DeleteEvent(ctx context.Context, event HookEvent) error {
 	a.Debugf("DeleteEvent(%v)", event.Type)
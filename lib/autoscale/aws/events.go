@@ -21,7 +21,7 @@ import (
 	"encoding/json"
 	"regexp"
 
-	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/autoscale"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/sqs"
@@ -61,7 +61,7 @@ func (a *Autoscaler) GetQueueURL(ctx context.Context) (string, error) {
 
 // ProcessEvents listens for events on SQS queue that are sent by the auto scaling
 // group lifecycle hooks.
-func (a *Autoscaler) ProcessEvents(ctx context.Context, queueURL string, operator Operator) {
+func (a *Autoscaler) ProcessEvents(ctx context.Context, queueURL string, operator autoscale.Operator) {
 	for {
 		out, err := a.Queue.ReceiveMessageWithContext(ctx, &sqs.ReceiveMessageInput{
 			QueueUrl:            aws.String(queueURL),
@@ -94,7 +94,7 @@ func (a *Autoscaler) ProcessEvents(ctx context.Context, queueURL string, operato
 	}
 }
 
-func (a *Autoscaler) processEvent(ctx context.Context, operator Operator, event HookEvent) error {
+func (a *Autoscaler) processEvent(ctx context.Context, operator autoscale.Operator, event HookEvent) error {
 	a.Debugf("got event: %v", event)
 	switch event.Type {
 	case InstanceLaunching:
@@ -121,31 +121,9 @@ func (a *Autoscaler) processEvent(ctx context.Context, operator Operator, event
 	return nil
 }
 
-func (a *Autoscaler) removeInstance(ctx context.Context, operator Operator, event HookEvent) error {
-	cluster, err := operator.GetLocalSite()
-	if err != nil {
-		return trace.Wrap(err)
-	}
-
-	server, err := ops.FindServerByInstanceID(cluster, event.InstanceID)
-	if err != nil {
-		return trace.Wrap(err)
-	}
-
-	_, err = operator.CreateSiteShrinkOperation(
-		ops.CreateSiteShrinkOperationRequest{
-			AccountID:   cluster.AccountID,
-			SiteDomain:  cluster.Domain,
-			Servers:     []string{server.Hostname},
-			Force:       true,
-			NodeRemoved: true,
-		})
-	if err != nil {
-		return trace.Wrap(err)
-	}
-
-	a.Debugf("initiated shrink operation for node %v", server.Hostname)
-	return nil
+func (a *Autoscaler) removeInstance(ctx context.Context, operator autoscale.Operator, event HookEvent) error {
+	_, err := autoscale.RemoveNode(operator, autoscale.Node{InstanceID: event.InstanceID})
+	return trace.Wrap(err)
 }
 
 func mustMarshalHook(e HookEvent) string {
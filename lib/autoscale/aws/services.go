@@ -18,7 +18,6 @@ package aws
 
 import (
 	gaws "github.com/gravitational/gravity/lib/cloudprovider/aws"
-	"github.com/gravitational/gravity/lib/ops"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/request"
@@ -45,10 +44,4 @@ type EC2 interface {
 	ModifyInstanceAttributeWithContext(aws.Context, *ec2.ModifyInstanceAttributeInput, ...request.Option) (*ec2.ModifyInstanceAttributeOutput, error)
 }
 
-// Operator is a simplified operator interface to mock in tests
-type Operator interface {
-	GetLocalSite() (*ops.Site, error)
-	CreateSiteShrinkOperation(ops.CreateSiteShrinkOperationRequest) (*ops.SiteOperationKey, error)
-}
-
 type NewLocalInstance func() (*gaws.Instance, error)
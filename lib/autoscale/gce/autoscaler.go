@@ -0,0 +1,315 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gce
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gravitational/gravity/lib/autoscale"
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/ops"
+
+	"cloud.google.com/go/compute/metadata"
+	"github.com/gravitational/trace"
+	log "github.com/sirupsen/logrus"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Config is the GCE autoscaler configuration
+type Config struct {
+	// ClusterName is the name of the cluster
+	ClusterName string
+	// Project is the project of the managed instance group.
+	// Defaults to the project of this instance
+	Project string
+	// Zone is the zone of the managed instance group.
+	// Defaults to the zone of this instance
+	Zone string
+	// InstanceGroup is the name of the managed instance group
+	InstanceGroup string
+	// Client is the kubernetes client
+	Client kubernetes.Interface
+	// Compute is the Compute Engine API client
+	Compute Compute
+	// Secrets is the Secret Manager API client
+	Secrets Secrets
+	// Interval is how often the instance group is checked for removed instances
+	Interval time.Duration
+}
+
+// CheckAndSetDefaults checks and sets default values
+func (cfg *Config) CheckAndSetDefaults() (err error) {
+	if cfg.ClusterName == "" {
+		return trace.BadParameter("missing parameter ClusterName")
+	}
+	if cfg.InstanceGroup == "" {
+		return trace.BadParameter("missing parameter InstanceGroup")
+	}
+	if cfg.Project == "" {
+		cfg.Project, err = metadata.ProjectID()
+		if err != nil {
+			return trace.Wrap(err, "failed to determine project")
+		}
+	}
+	if cfg.Zone == "" {
+		cfg.Zone, err = metadata.Zone()
+		if err != nil {
+			return trace.Wrap(err, "failed to determine zone")
+		}
+	}
+	if cfg.Compute == nil {
+		cfg.Compute = NewCompute()
+	}
+	if cfg.Secrets == nil {
+		cfg.Secrets = NewSecrets()
+	}
+	if cfg.Interval == 0 {
+		cfg.Interval = defaults.InstanceGroupSyncInterval
+	}
+	return nil
+}
+
+// Autoscaler integrates the cluster with a GCE managed instance group
+type Autoscaler struct {
+	// Config is the autoscaler configuration
+	Config
+	*log.Entry
+	// published is the discovery information that has been published
+	published autoscale.Discovery
+	// members persists the instances observed in the instance group
+	members memberStore
+}
+
+// New returns a new GCE autoscaler
+func New(config Config) (*Autoscaler, error) {
+	if err := config.CheckAndSetDefaults(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &Autoscaler{
+		Config: config,
+		Entry: log.WithFields(log.Fields{
+			trace.Component: "autoscale:gce",
+			"group":         config.InstanceGroup,
+		}),
+		members: &configMapStore{client: config.Client},
+	}, nil
+}
+
+// Run removes the nodes of the instances deleted from the managed instance
+// group and publishes the cluster discovery information until the context
+// is cancelled
+func (a *Autoscaler) Run(ctx context.Context, operator ops.Operator) error {
+	group, err := a.Compute.GetInstanceGroup(ctx, a.Project, a.Zone, a.InstanceGroup)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	go a.PublishDiscovery(ctx, operator)
+	ticker := time.NewTicker(a.Interval)
+	defer ticker.Stop()
+	for {
+		err := a.removeDeletedInstances(ctx, operator, *group)
+		if err != nil {
+			a.Warnf("Failed to sync instance group: %v.", trace.DebugReport(err))
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// removeDeletedInstances starts the operation to remove a node
+// whose instance has been observed in the instance group, but is no longer
+// a member of the group.
+//
+// The observed instances are persisted so nodes of the instances deleted
+// while no master was watching the group are removed as well. Instances
+// that have never been members of the group are never removed.
+//
+// Nodes are removed one at a time as shrink operations cannot run concurrently
+func (a *Autoscaler) removeDeletedInstances(ctx context.Context, operator autoscale.Operator, group InstanceGroup) error {
+	instances, err := a.Compute.ListManagedInstances(ctx, a.Project, a.Zone, group.Name)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	observed, err := a.members.load()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	cluster, err := operator.GetLocalSite()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	members := make(map[string]bool)
+	changed := false
+	for _, instance := range instances {
+		if !observed[instance.Name()] {
+			observed[instance.Name()] = true
+			changed = true
+		}
+		if !instance.IsRemoved() {
+			members[instance.Name()] = true
+		}
+	}
+	servers := make(map[string]bool)
+	for _, server := range cluster.ClusterState.Servers {
+		servers[instanceName(server.Hostname)] = true
+	}
+	// forget the instances that have left both the group and the cluster
+	for name := range observed {
+		if !members[name] && !servers[name] {
+			delete(observed, name)
+			changed = true
+		}
+	}
+	if changed {
+		if err := a.members.save(observed); err != nil {
+			return trace.Wrap(err)
+		}
+	}
+	for _, server := range cluster.ClusterState.Servers {
+		name := instanceName(server.Hostname)
+		if !observed[name] || members[name] {
+			continue
+		}
+		a.Infof("Instance %v has been removed from the group.", name)
+		_, err := autoscale.RemoveNode(operator, autoscale.Node{Hostname: server.Hostname})
+		return trace.Wrap(err)
+	}
+	return nil
+}
+
+// PublishDiscovery periodically publishes the cluster discovery information
+func (a *Autoscaler) PublishDiscovery(ctx context.Context, operator ops.Operator) {
+	err := a.syncDiscovery(ctx, operator, true)
+	if err != nil {
+		a.Errorf("Failed to publish discovery: %v.", trace.DebugReport(err))
+	}
+	publishTicker := time.NewTicker(defaults.DiscoveryPublishInterval)
+	defer publishTicker.Stop()
+	resyncTicker := time.NewTicker(defaults.DiscoveryResyncInterval)
+	defer resyncTicker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-publishTicker.C:
+			err = a.syncDiscovery(ctx, operator, false)
+		case <-resyncTicker.C:
+			err = a.syncDiscovery(ctx, operator, true)
+		}
+		if err != nil {
+			a.Errorf("Failed to publish discovery: %v.", trace.DebugReport(err))
+		}
+	}
+}
+
+// syncDiscovery publishes the cluster discovery information if it has
+// changed since it was last published or if forced
+func (a *Autoscaler) syncDiscovery(ctx context.Context, operator ops.Operator, force bool) error {
+	discovery, err := autoscale.GetDiscovery(operator, a.Client)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if *discovery == a.published && !force {
+		return nil
+	}
+	if err := a.publish(ctx, *discovery); err != nil {
+		return trace.Wrap(err)
+	}
+	a.Debugf("Published discovery for %v.", discovery.ServiceURL)
+	a.published = *discovery
+	return nil
+}
+
+// publish stores the join token in Secret Manager and the service URL
+// in the project metadata.
+//
+// The token is kept out of the project metadata as it is readable
+// by all instances of the project
+func (a *Autoscaler) publish(ctx context.Context, discovery autoscale.Discovery) error {
+	token, err := a.Secrets.GetSecret(ctx, a.Project, TokenSecret(a.ClusterName))
+	if err != nil && !trace.IsNotFound(err) {
+		return trace.Wrap(err)
+	}
+	if string(token) != discovery.Token {
+		err = a.Secrets.PutSecret(ctx, a.Project, TokenSecret(a.ClusterName), []byte(discovery.Token))
+		if err != nil {
+			return trace.Wrap(err)
+		}
+	}
+	metadata, err := a.Compute.GetProjectMetadata(ctx, a.Project)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if value, ok := metadata.Get(ServiceURLKey(a.ClusterName)); ok && value == discovery.ServiceURL {
+		return nil
+	}
+	metadata.Set(ServiceURLKey(a.ClusterName), discovery.ServiceURL)
+	return trace.Wrap(a.Compute.SetProjectMetadata(ctx, a.Project, *metadata))
+}
+
+// GetDiscovery returns the cluster discovery information published
+// for the project of this instance
+func GetDiscovery(ctx context.Context, clusterName string) (*autoscale.Discovery, error) {
+	project, err := metadata.ProjectID()
+	if err != nil {
+		return nil, trace.Wrap(err, "failed to determine project")
+	}
+	token, err := NewSecrets().GetSecret(ctx, project, TokenSecret(clusterName))
+	if err != nil {
+		return nil, trace.Wrap(err, "failed to read join token")
+	}
+	serviceURL, err := metadata.ProjectAttributeValue(ServiceURLKey(clusterName))
+	if err != nil {
+		return nil, trace.Wrap(err, "failed to read service URL")
+	}
+	return &autoscale.Discovery{
+		ServiceURL: serviceURL,
+		Token:      string(token),
+	}, nil
+}
+
+// TokenSecret returns the Secret Manager ID of the cluster join token
+func TokenSecret(clusterName string) string {
+	return fmt.Sprintf("telekube-%v-token", safeName(clusterName))
+}
+
+// ServiceURLKey returns the project metadata key of the cluster service URL
+func ServiceURLKey(clusterName string) string {
+	return fmt.Sprintf("telekube-%v-service", safeName(clusterName))
+}
+
+// safeName replaces the characters not allowed in metadata keys
+// and secret IDs
+func safeName(clusterName string) string {
+	return unsafeKeyChars.ReplaceAllString(clusterName, "-")
+}
+
+// instanceName returns the instance name from the node hostname
+// which might be fully qualified
+func instanceName(hostname string) string {
+	return strings.SplitN(hostname, ".", 2)[0]
+}
+
+// unsafeKeyChars matches the characters not allowed in metadata keys
+var unsafeKeyChars = regexp.MustCompile("[^a-zA-Z0-9_-]")
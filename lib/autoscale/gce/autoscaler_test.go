@@ -0,0 +1,229 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gce
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gravitational/gravity/lib/autoscale"
+	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/storage"
+
+	"github.com/gravitational/trace"
+	"gopkg.in/check.v1"
+)
+
+func TestGCE(t *testing.T) { check.TestingT(t) }
+
+type AutoscalerSuite struct{}
+
+var _ = check.Suite(&AutoscalerSuite{})
+
+func (s *AutoscalerSuite) TestRemovesDeletedInstances(c *check.C) {
+	group := InstanceGroup{Name: "nodes"}
+	compute := &mockCompute{
+		instances: []ManagedInstance{
+			{Instance: instanceURL("node-abcd")},
+			{Instance: instanceURL("node-efgh"), CurrentAction: ActionDeleting},
+		},
+	}
+	a, err := New(Config{
+		ClusterName:   "example.com",
+		Project:       "project",
+		Zone:          "us-central1-a",
+		InstanceGroup: group.Name,
+		Compute:       compute,
+		Secrets:       &mockSecrets{},
+	})
+	c.Assert(err, check.IsNil)
+	a.members = &memoryStore{}
+	operator := &mockOperator{site: ops.Site{
+		AccountID: "account",
+		Domain:    "example.com",
+		ClusterState: storage.ClusterState{
+			Servers: storage.Servers{
+				{Hostname: "master"},
+				{Hostname: "node-abcd.c.project.internal"},
+				{Hostname: "node-efgh.c.project.internal"},
+			},
+		},
+	}}
+
+	err = a.removeDeletedInstances(context.TODO(), operator, group)
+	c.Assert(err, check.IsNil)
+	c.Assert(operator.shrinks, check.DeepEquals, []ops.CreateSiteShrinkOperationRequest{{
+		AccountID:   "account",
+		SiteDomain:  "example.com",
+		Servers:     []string{"node-efgh.c.project.internal"},
+		Force:       true,
+		NodeRemoved: true,
+	}})
+
+	// instances deleted from the group since the last check are removed
+	operator.shrinks = nil
+	compute.instances = nil
+	operator.site.ClusterState.Servers = storage.Servers{
+		{Hostname: "master"},
+		{Hostname: "node-abcd.c.project.internal"},
+		{Hostname: "node-ijkl.c.project.internal"},
+	}
+	err = a.removeDeletedInstances(context.TODO(), operator, group)
+	c.Assert(err, check.IsNil)
+	c.Assert(operator.shrinks, check.HasLen, 1)
+	c.Assert(operator.shrinks[0].Servers, check.DeepEquals, []string{"node-abcd.c.project.internal"})
+
+	// nodes that have never been members of the group are never removed,
+	// even if their names look like the names of the group instances
+	operator.shrinks = nil
+	operator.site.ClusterState.Servers = storage.Servers{
+		{Hostname: "master"},
+		{Hostname: "node-ijkl.c.project.internal"},
+	}
+	err = a.removeDeletedInstances(context.TODO(), operator, group)
+	c.Assert(err, check.IsNil)
+	c.Assert(operator.shrinks, check.HasLen, 0)
+	observed, err := a.members.load()
+	c.Assert(err, check.IsNil)
+	c.Assert(observed, check.HasLen, 0)
+}
+
+func (s *AutoscalerSuite) TestPublishesTokenToSecretManager(c *check.C) {
+	compute := &mockCompute{
+		metadata: Metadata{Items: []MetadataItem{{Key: "ssh-keys", Value: "keys"}}},
+	}
+	secrets := &mockSecrets{}
+	a, err := New(Config{
+		ClusterName:   "example.com",
+		Project:       "project",
+		Zone:          "us-central1-a",
+		InstanceGroup: "nodes",
+		Compute:       compute,
+		Secrets:       secrets,
+	})
+	c.Assert(err, check.IsNil)
+	discovery := autoscale.Discovery{ServiceURL: "10.0.0.1:3009", Token: "token"}
+
+	err = a.publish(context.TODO(), discovery)
+	c.Assert(err, check.IsNil)
+	err = a.publish(context.TODO(), discovery)
+	c.Assert(err, check.IsNil)
+	c.Assert(secrets.versions, check.DeepEquals, map[string][]string{
+		"telekube-example-com-token": {"token"},
+	})
+	c.Assert(compute.metadata.Items, check.DeepEquals, []MetadataItem{
+		{Key: "ssh-keys", Value: "keys"},
+		{Key: "telekube-example-com-service", Value: "10.0.0.1:3009"},
+	})
+	c.Assert(compute.updates, check.Equals, 1)
+}
+
+func (s *AutoscalerSuite) TestMetadata(c *check.C) {
+	c.Assert(TokenSecret("example.com"), check.Equals, "telekube-example-com-token")
+	c.Assert(ServiceURLKey("example.com"), check.Equals, "telekube-example-com-service")
+
+	metadata := Metadata{Items: []MetadataItem{{Key: "ssh-keys", Value: "keys"}}}
+	metadata.Set("token", "secret")
+	metadata.Set("token", "updated")
+	value, ok := metadata.Get("token")
+	c.Assert(ok, check.Equals, true)
+	c.Assert(value, check.Equals, "updated")
+	c.Assert(metadata.Items, check.HasLen, 2)
+}
+
+func instanceURL(name string) string {
+	return "https://www.googleapis.com/compute/v1/projects/project/zones/us-central1-a/instances/" + name
+}
+
+type mockCompute struct {
+	Compute
+	instances []ManagedInstance
+	metadata  Metadata
+	updates   int
+}
+
+func (m *mockCompute) ListManagedInstances(ctx context.Context, project, zone, name string) ([]ManagedInstance, error) {
+	return m.instances, nil
+}
+
+func (m *mockCompute) GetProjectMetadata(ctx context.Context, project string) (*Metadata, error) {
+	metadata := Metadata{Items: append([]MetadataItem(nil), m.metadata.Items...)}
+	return &metadata, nil
+}
+
+func (m *mockCompute) SetProjectMetadata(ctx context.Context, project string, metadata Metadata) error {
+	m.metadata = metadata
+	m.updates++
+	return nil
+}
+
+// mockSecrets keeps all versions of the secrets in memory
+type mockSecrets struct {
+	versions map[string][]string
+}
+
+func (m *mockSecrets) GetSecret(ctx context.Context, project, name string) ([]byte, error) {
+	versions := m.versions[name]
+	if len(versions) == 0 {
+		return nil, trace.NotFound("secret %v not found", name)
+	}
+	return []byte(versions[len(versions)-1]), nil
+}
+
+func (m *mockSecrets) PutSecret(ctx context.Context, project, name string, data []byte) error {
+	if m.versions == nil {
+		m.versions = make(map[string][]string)
+	}
+	m.versions[name] = append(m.versions[name], string(data))
+	return nil
+}
+
+// memoryStore keeps the observed instances in memory
+type memoryStore struct {
+	members map[string]bool
+}
+
+func (s *memoryStore) load() (map[string]bool, error) {
+	members := make(map[string]bool, len(s.members))
+	for name := range s.members {
+		members[name] = true
+	}
+	return members, nil
+}
+
+func (s *memoryStore) save(members map[string]bool) error {
+	s.members = members
+	return nil
+}
+
+type mockOperator struct {
+	site    ops.Site
+	shrinks []ops.CreateSiteShrinkOperationRequest
+}
+
+func (o *mockOperator) GetLocalSite() (*ops.Site, error) {
+	return &o.site, nil
+}
+
+func (o *mockOperator) CreateSiteShrinkOperation(req ops.CreateSiteShrinkOperationRequest) (*ops.SiteOperationKey, error) {
+	o.shrinks = append(o.shrinks, req)
+	return &ops.SiteOperationKey{
+		AccountID:   o.site.AccountID,
+		SiteDomain:  o.site.Domain,
+		OperationID: "operation",
+	}, nil
+}
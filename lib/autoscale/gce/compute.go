@@ -0,0 +1,318 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gce
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/gravitational/gravity/lib/defaults"
+
+	"cloud.google.com/go/compute/metadata"
+	"github.com/gravitational/trace"
+)
+
+// Compute is a subset of the Compute Engine API used by the autoscaler
+type Compute interface {
+	// GetInstanceGroup returns the managed instance group
+	GetInstanceGroup(ctx context.Context, project, zone, name string) (*InstanceGroup, error)
+	// ListManagedInstances returns the instances of the managed instance group
+	ListManagedInstances(ctx context.Context, project, zone, name string) ([]ManagedInstance, error)
+	// GetProjectMetadata returns the metadata shared by all instances of the project
+	GetProjectMetadata(ctx context.Context, project string) (*Metadata, error)
+	// SetProjectMetadata replaces the metadata shared by all instances of the project
+	SetProjectMetadata(ctx context.Context, project string, metadata Metadata) error
+}
+
+// Secrets is a subset of the Secret Manager API used to share
+// the cluster join token with the group instances
+type Secrets interface {
+	// GetSecret returns the data of the latest version of the secret
+	GetSecret(ctx context.Context, project, name string) ([]byte, error)
+	// PutSecret adds a new version of the secret creating the secret
+	// if it does not exist
+	PutSecret(ctx context.Context, project, name string, data []byte) error
+}
+
+// InstanceGroup is a managed instance group
+type InstanceGroup struct {
+	// Name is the name of the instance group
+	Name string `json:"name"`
+}
+
+// ManagedInstance is an instance of the managed instance group
+type ManagedInstance struct {
+	// Instance is the URL of the instance
+	Instance string `json:"instance"`
+	// CurrentAction is the action the group is performing on the instance
+	CurrentAction string `json:"currentAction"`
+}
+
+// Name returns the name of the instance
+func (i ManagedInstance) Name() string {
+	return path.Base(i.Instance)
+}
+
+// IsRemoved returns true if the instance is being removed from the group
+func (i ManagedInstance) IsRemoved() bool {
+	return i.CurrentAction == ActionDeleting || i.CurrentAction == ActionAbandoning
+}
+
+// Metadata is the project metadata
+type Metadata struct {
+	// Fingerprint identifies the metadata version and guards
+	// against concurrent updates
+	Fingerprint string `json:"fingerprint"`
+	// Items is the list of metadata entries
+	Items []MetadataItem `json:"items"`
+}
+
+// Get returns the value of the specified key
+func (m Metadata) Get(key string) (string, bool) {
+	for _, item := range m.Items {
+		if item.Key == key {
+			return item.Value, true
+		}
+	}
+	return "", false
+}
+
+// Set sets the value of the specified key
+func (m *Metadata) Set(key, value string) {
+	for i, item := range m.Items {
+		if item.Key == key {
+			m.Items[i].Value = value
+			return
+		}
+	}
+	m.Items = append(m.Items, MetadataItem{Key: key, Value: value})
+}
+
+// MetadataItem is a single metadata entry
+type MetadataItem struct {
+	// Key is the metadata key
+	Key string `json:"key"`
+	// Value is the metadata value
+	Value string `json:"value"`
+}
+
+// NewCompute returns a new Compute Engine API client that authenticates
+// with the service account of this instance
+func NewCompute() Compute {
+	return newClient()
+}
+
+// NewSecrets returns a new Secret Manager API client that authenticates
+// with the service account of this instance
+func NewSecrets() Secrets {
+	return newClient()
+}
+
+func newClient() *apiClient {
+	return &apiClient{
+		client: &http.Client{Timeout: defaults.CloudAPITimeout},
+	}
+}
+
+// apiClient is the client of the Google Cloud APIs
+type apiClient struct {
+	client *http.Client
+	mu     sync.Mutex
+	// token is the cached access token of the instance service account
+	token   string
+	expires time.Time
+}
+
+// GetInstanceGroup returns the managed instance group
+func (c *apiClient) GetInstanceGroup(ctx context.Context, project, zone, name string) (*InstanceGroup, error) {
+	var group InstanceGroup
+	err := c.do(ctx, http.MethodGet, c.endpoint(computeURL, "projects", project, "zones", zone, "instanceGroupManagers", name), nil, &group)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &group, nil
+}
+
+// ListManagedInstances returns the instances of the managed instance group
+func (c *apiClient) ListManagedInstances(ctx context.Context, project, zone, name string) ([]ManagedInstance, error) {
+	endpoint := c.endpoint(computeURL, "projects", project, "zones", zone, "instanceGroupManagers", name, "listManagedInstances")
+	var instances []ManagedInstance
+	var pageToken string
+	for {
+		var resp struct {
+			ManagedInstances []ManagedInstance `json:"managedInstances"`
+			NextPageToken    string            `json:"nextPageToken"`
+		}
+		pageEndpoint := endpoint
+		if pageToken != "" {
+			pageEndpoint = fmt.Sprintf("%v?pageToken=%v", endpoint, url.QueryEscape(pageToken))
+		}
+		if err := c.do(ctx, http.MethodPost, pageEndpoint, nil, &resp); err != nil {
+			return nil, trace.Wrap(err)
+		}
+		instances = append(instances, resp.ManagedInstances...)
+		if resp.NextPageToken == "" {
+			return instances, nil
+		}
+		pageToken = resp.NextPageToken
+	}
+}
+
+// GetProjectMetadata returns the metadata shared by all instances of the project
+func (c *apiClient) GetProjectMetadata(ctx context.Context, project string) (*Metadata, error) {
+	var resp struct {
+		CommonInstanceMetadata Metadata `json:"commonInstanceMetadata"`
+	}
+	if err := c.do(ctx, http.MethodGet, c.endpoint(computeURL, "projects", project), nil, &resp); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &resp.CommonInstanceMetadata, nil
+}
+
+// SetProjectMetadata replaces the metadata shared by all instances of the project
+func (c *apiClient) SetProjectMetadata(ctx context.Context, project string, metadata Metadata) error {
+	return trace.Wrap(c.do(ctx, http.MethodPost, c.endpoint(computeURL, "projects", project, "setCommonInstanceMetadata"), metadata, nil))
+}
+
+// GetSecret returns the data of the latest version of the secret
+func (c *apiClient) GetSecret(ctx context.Context, project, name string) ([]byte, error) {
+	var resp struct {
+		Payload secretPayload `json:"payload"`
+	}
+	err := c.do(ctx, http.MethodGet, c.endpoint(secretManagerURL, "projects", project, "secrets", name, "versions", "latest:access"), nil, &resp)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return resp.Payload.Data, nil
+}
+
+// PutSecret adds a new version of the secret creating the secret
+// if it does not exist
+func (c *apiClient) PutSecret(ctx context.Context, project, name string, data []byte) error {
+	secret := map[string]interface{}{
+		"replication": map[string]interface{}{"automatic": map[string]interface{}{}},
+	}
+	endpoint := fmt.Sprintf("%v?secretId=%v", c.endpoint(secretManagerURL, "projects", project, "secrets"), url.QueryEscape(name))
+	err := c.do(ctx, http.MethodPost, endpoint, secret, nil)
+	if err != nil && !trace.IsAlreadyExists(err) {
+		return trace.Wrap(err)
+	}
+	version := map[string]interface{}{"payload": secretPayload{Data: data}}
+	return trace.Wrap(c.do(ctx, http.MethodPost, c.endpoint(secretManagerURL, "projects", project, "secrets", name+":addVersion"), version, nil))
+}
+
+// secretPayload is the data of a secret version
+type secretPayload struct {
+	// Data is the secret data, base64-encoded in JSON
+	Data []byte `json:"data"`
+}
+
+func (c *apiClient) endpoint(baseURL string, parts ...string) string {
+	for i, part := range parts {
+		parts[i] = url.PathEscape(part)
+	}
+	return baseURL + path.Join(parts...)
+}
+
+func (c *apiClient) do(ctx context.Context, method, endpoint string, in, out interface{}) error {
+	token, err := c.getToken()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	body := &bytes.Buffer{}
+	if in != nil {
+		if err := json.NewEncoder(body).Encode(in); err != nil {
+			return trace.Wrap(err)
+		}
+	}
+	req, err := http.NewRequest(method, endpoint, body)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return trace.NotFound("%v %v: %s", method, endpoint, data)
+	case resp.StatusCode == http.StatusConflict:
+		return trace.AlreadyExists("%v %v: %s", method, endpoint, data)
+	case resp.StatusCode == http.StatusPreconditionFailed:
+		return trace.CompareFailed("%v %v: %s", method, endpoint, data)
+	case resp.StatusCode >= http.StatusBadRequest:
+		return trace.BadParameter("%v %v: %v %s", method, endpoint, resp.Status, data)
+	}
+	if out == nil {
+		return nil
+	}
+	return trace.Wrap(json.Unmarshal(data, out))
+}
+
+// getToken returns the access token of the instance service account
+func (c *apiClient) getToken() (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.token != "" && time.Now().Before(c.expires) {
+		return c.token, nil
+	}
+	data, err := metadata.Get("instance/service-accounts/default/token")
+	if err != nil {
+		return "", trace.Wrap(err, "failed to retrieve service account token")
+	}
+	var token struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.Unmarshal([]byte(data), &token); err != nil {
+		return "", trace.Wrap(err)
+	}
+	c.token = token.AccessToken
+	// renew the token a minute before it expires
+	c.expires = time.Now().Add(time.Duration(token.ExpiresIn)*time.Second - time.Minute)
+	return c.token, nil
+}
+
+const (
+	// computeURL is the base URL of the Compute Engine API
+	computeURL = "https://www.googleapis.com/compute/v1/"
+	// secretManagerURL is the base URL of the Secret Manager API
+	secretManagerURL = "https://secretmanager.googleapis.com/v1/"
+
+	// ActionDeleting is the action of the managed instance group
+	// on the instance that is being deleted
+	ActionDeleting = "DELETING"
+	// ActionAbandoning is the action of the managed instance group
+	// on the instance that is being removed from the group
+	ActionAbandoning = "ABANDONING"
+)
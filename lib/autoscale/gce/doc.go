@@ -0,0 +1,31 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/* package gce implements autoscaling integration for GCE managed instance groups
+
+* Autoscaler runs on the active master node
+* Autoscaler publishes the Gravity load balancer service address to the project
+  metadata and the join token to Secret Manager. The token is kept out of the
+  project metadata as it is readable by all instances of the project.
+* Instances started up as a part of the managed instance group discover
+  the cluster by reading the project metadata and the token secret
+  (see gravity autojoin).
+* Autoscaler periodically lists the instances of the managed instance group,
+  records them in a ConfigMap and removes the nodes of the recorded instances
+  that have been deleted from the group from the cluster in forced mode
+
+*/
+package gce
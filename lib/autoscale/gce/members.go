@@ -0,0 +1,106 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gce
+
+import (
+	"encoding/json"
+	"sort"
+
+	"github.com/gravitational/gravity/lib/constants"
+	"github.com/gravitational/gravity/lib/defaults"
+
+	"github.com/gravitational/rigging"
+	"github.com/gravitational/trace"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// memberStore persists the names of the instances observed
+// in the managed instance group
+type memberStore interface {
+	// load returns the persisted instance names
+	load() (map[string]bool, error)
+	// save persists the instance names
+	save(members map[string]bool) error
+}
+
+// configMapStore keeps the instance names in a ConfigMap
+// so they survive the failover of the active master
+type configMapStore struct {
+	client kubernetes.Interface
+}
+
+func (s *configMapStore) load() (map[string]bool, error) {
+	configMap, err := s.client.CoreV1().ConfigMaps(defaults.KubeSystemNamespace).Get(
+		constants.AutoscaleGCEConfigMap, metav1.GetOptions{})
+	if err != nil {
+		err = rigging.ConvertError(err)
+		if trace.IsNotFound(err) {
+			return make(map[string]bool), nil
+		}
+		return nil, trace.Wrap(err)
+	}
+	var names []string
+	if data := configMap.Data[membersKey]; data != "" {
+		if err := json.Unmarshal([]byte(data), &names); err != nil {
+			return nil, trace.Wrap(err)
+		}
+	}
+	members := make(map[string]bool, len(names))
+	for _, name := range names {
+		members[name] = true
+	}
+	return members, nil
+}
+
+func (s *configMapStore) save(members map[string]bool) error {
+	names := make([]string, 0, len(members))
+	for name := range members {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	data, err := json.Marshal(names)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	configMaps := s.client.CoreV1().ConfigMaps(defaults.KubeSystemNamespace)
+	configMap, err := configMaps.Get(constants.AutoscaleGCEConfigMap, metav1.GetOptions{})
+	if err != nil {
+		err = rigging.ConvertError(err)
+		if !trace.IsNotFound(err) {
+			return trace.Wrap(err)
+		}
+		_, err = configMaps.Create(&v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      constants.AutoscaleGCEConfigMap,
+				Namespace: defaults.KubeSystemNamespace,
+			},
+			Data: map[string]string{membersKey: string(data)},
+		})
+		return trace.Wrap(rigging.ConvertError(err))
+	}
+	if configMap.Data == nil {
+		configMap.Data = make(map[string]string)
+	}
+	configMap.Data[membersKey] = string(data)
+	_, err = configMaps.Update(configMap)
+	return trace.Wrap(rigging.ConvertError(err))
+}
+
+// membersKey is the ConfigMap key with the instance names
+const membersKey = "members"
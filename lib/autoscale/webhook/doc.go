@@ -0,0 +1,36 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/* package webhook implements autoscaling driven by an external system over HTTP
+
+* Autoscaler runs on the active master node
+* An external system, for example an on-premise virtualization platform,
+  posts scaling events to the autoscaler authenticating with a Gravity
+  API key or token
+* In response to a scale up event the autoscaler returns the cluster service
+  URL and the join token that the new instance uses to join the cluster
+* In response to a scale down event the autoscaler removes the node
+  from the cluster in forced mode
+
+Events are posted as JSON to /autoscale/v1/events:
+
+  {"type": "scale_up"}
+  {"type": "scale_down", "node": {"hostname": "node-1"}}
+
+The node is identified by any of instance_id, hostname or advertise_ip.
+
+*/
+package webhook
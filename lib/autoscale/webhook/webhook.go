@@ -0,0 +1,239 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gravitational/gravity/lib/autoscale"
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/httplib"
+	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/users"
+
+	"github.com/gravitational/roundtrip"
+	teleservices "github.com/gravitational/teleport/lib/services"
+	"github.com/gravitational/trace"
+	"github.com/julienschmidt/httprouter"
+	log "github.com/sirupsen/logrus"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Config is the webhook autoscaler configuration
+type Config struct {
+	// Users authenticates the webhook requests
+	Users users.Identity
+	// Client is the kubernetes client
+	Client kubernetes.Interface
+	// Timeout is how long a posted event waits to be processed
+	Timeout time.Duration
+}
+
+// CheckAndSetDefaults checks and sets default values
+func (cfg *Config) CheckAndSetDefaults() error {
+	if cfg.Users == nil {
+		return trace.BadParameter("missing parameter Users")
+	}
+	if cfg.Client == nil {
+		return trace.BadParameter("missing parameter Client")
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = defaults.AutoscaleEventTimeout
+	}
+	return nil
+}
+
+// Event is a scaling event posted by an external system
+type Event struct {
+	// Type is the event type
+	Type string `json:"type"`
+	// Node identifies the removed node for scale down events
+	Node autoscale.Node `json:"node"`
+}
+
+// Check validates the event
+func (e Event) Check() error {
+	switch e.Type {
+	case EventScaleUp:
+		return nil
+	case EventScaleDown:
+		return trace.Wrap(e.Node.Check())
+	default:
+		return trace.BadParameter("unsupported event type %q, supported are %q and %q",
+			e.Type, EventScaleUp, EventScaleDown)
+	}
+}
+
+// Response is the result of processing an event
+type Response struct {
+	// Discovery is the information the new node uses to join the cluster,
+	// returned for scale up events
+	Discovery *autoscale.Discovery `json:"discovery,omitempty"`
+	// Operation is the operation that removes the node,
+	// returned for scale down events
+	Operation *ops.SiteOperationKey `json:"operation,omitempty"`
+}
+
+// Autoscaler receives scaling events posted by an external system
+// and applies them to the cluster
+type Autoscaler struct {
+	// Config is the autoscaler configuration
+	Config
+	httprouter.Router
+	*log.Entry
+	eventsC chan request
+}
+
+// New returns a new webhook autoscaler
+func New(config Config) (*Autoscaler, error) {
+	if err := config.CheckAndSetDefaults(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	a := &Autoscaler{
+		Config:  config,
+		Entry:   log.WithField(trace.Component, "autoscale:webhook"),
+		eventsC: make(chan request),
+	}
+	a.POST("/autoscale/v1/events", a.needsAuth(a.postEvent))
+	return a, nil
+}
+
+// Run processes the posted events until the context is cancelled.
+//
+// Events are processed one at a time as the operations they start
+// cannot run concurrently
+func (a *Autoscaler) Run(ctx context.Context, operator ops.Operator) error {
+	for {
+		select {
+		case req := <-a.eventsC:
+			resp, err := a.processEvent(ops.OperatorWithACL(operator, a.Users, req.user, req.checker), req.event)
+			req.resultC <- result{response: resp, err: err}
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+func (a *Autoscaler) processEvent(operator ops.Operator, event Event) (*Response, error) {
+	a.Infof("Received %v event for %v.", event.Type, event.Node)
+	switch event.Type {
+	case EventScaleUp:
+		discovery, err := autoscale.GetDiscovery(operator, a.Client)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		return &Response{Discovery: discovery}, nil
+	case EventScaleDown:
+		key, err := autoscale.RemoveNode(operator, event.Node)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		return &Response{Operation: key}, nil
+	}
+	return nil, trace.BadParameter("unsupported event type %q", event.Type)
+}
+
+/* postEvent submits the scaling event for processing
+
+     POST /autoscale/v1/events
+
+   Input: Event
+
+   Success Response: Response
+*/
+func (a *Autoscaler) postEvent(w http.ResponseWriter, r *http.Request, p httprouter.Params, user storage.User, checker teleservices.AccessChecker) error {
+	var event Event
+	if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+		return trace.BadParameter("failed to decode event: %v", err)
+	}
+	if err := event.Check(); err != nil {
+		return trace.Wrap(err)
+	}
+	resp, err := a.submit(r.Context(), request{event: event, user: user, checker: checker})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	roundtrip.ReplyJSON(w, http.StatusOK, resp)
+	return nil
+}
+
+// submit queues the event for processing and waits for the result
+func (a *Autoscaler) submit(ctx context.Context, req request) (*Response, error) {
+	ctx, cancel := context.WithTimeout(ctx, a.Timeout)
+	defer cancel()
+	req.resultC = make(chan result, 1)
+	select {
+	case a.eventsC <- req:
+	case <-ctx.Done():
+		return nil, trace.LimitExceeded("timed out waiting for the event to be accepted, " +
+			"make sure the autoscaler is running on the active cluster controller")
+	}
+	select {
+	case result := <-req.resultC:
+		return result.response, trace.Wrap(result.err)
+	case <-ctx.Done():
+		return nil, trace.LimitExceeded("timed out waiting for the event to be processed")
+	}
+}
+
+func (a *Autoscaler) needsAuth(fn authHandler) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+		authCreds, err := httplib.ParseAuthHeaders(r)
+		if err != nil {
+			trace.WriteError(w, err)
+			return
+		}
+		user, checker, err := a.Users.AuthenticateUser(*authCreds)
+		if err != nil {
+			a.Infof("Authentication error: %v.", err)
+			// we hide the error from the remote user to avoid giving any hints
+			trace.WriteError(w, trace.AccessDenied("bad username or password"))
+			return
+		}
+		if err := fn(w, r, p, user, checker); err != nil {
+			a.Warnf("Failed to process event: %v.", trace.DebugReport(err))
+			trace.WriteError(w, err)
+		}
+	}
+}
+
+type authHandler func(http.ResponseWriter, *http.Request, httprouter.Params, storage.User, teleservices.AccessChecker) error
+
+// request is an event submitted for processing on behalf of the user
+type request struct {
+	event   Event
+	user    storage.User
+	checker teleservices.AccessChecker
+	resultC chan result
+}
+
+type result struct {
+	response *Response
+	err      error
+}
+
+const (
+	// EventScaleUp is the event posted when a new instance is being added.
+	// The response contains the information the instance uses to join the cluster
+	EventScaleUp = "scale_up"
+	// EventScaleDown is the event posted when an instance has been removed
+	EventScaleDown = "scale_down"
+)
@@ -0,0 +1,106 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gravitational/gravity/lib/autoscale"
+	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/storage"
+
+	"github.com/gravitational/trace"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/check.v1"
+)
+
+func TestWebhook(t *testing.T) { check.TestingT(t) }
+
+type WebhookSuite struct{}
+
+var _ = check.Suite(&WebhookSuite{})
+
+func (s *WebhookSuite) TestValidatesEvents(c *check.C) {
+	c.Assert(Event{Type: EventScaleUp}.Check(), check.IsNil)
+	c.Assert(Event{Type: EventScaleDown, Node: autoscale.Node{AdvertiseIP: "10.0.0.2"}}.Check(), check.IsNil)
+	c.Assert(trace.IsBadParameter(Event{Type: EventScaleDown}.Check()), check.Equals, true)
+	c.Assert(trace.IsBadParameter(Event{Type: "reboot"}.Check()), check.Equals, true)
+}
+
+func (s *WebhookSuite) TestRemovesNode(c *check.C) {
+	operator := &mockOperator{site: ops.Site{
+		AccountID: "account",
+		Domain:    "example.com",
+		ClusterState: storage.ClusterState{
+			Servers: storage.Servers{
+				{Hostname: "node-1", AdvertiseIP: "10.0.0.1"},
+				{Hostname: "node-2", AdvertiseIP: "10.0.0.2"},
+			},
+		},
+	}}
+	a := &Autoscaler{Entry: log.WithField(trace.Component, "test")}
+
+	resp, err := a.processEvent(operator, Event{
+		Type: EventScaleDown,
+		Node: autoscale.Node{AdvertiseIP: "10.0.0.2"},
+	})
+	c.Assert(err, check.IsNil)
+	c.Assert(resp.Operation.OperationID, check.Equals, "operation")
+	c.Assert(operator.shrinks, check.DeepEquals, []ops.CreateSiteShrinkOperationRequest{{
+		AccountID:   "account",
+		SiteDomain:  "example.com",
+		Servers:     []string{"node-2"},
+		Force:       true,
+		NodeRemoved: true,
+	}})
+
+	_, err = a.processEvent(operator, Event{
+		Type: EventScaleDown,
+		Node: autoscale.Node{Hostname: "node-3"},
+	})
+	c.Assert(trace.IsNotFound(err), check.Equals, true)
+}
+
+func (s *WebhookSuite) TestTimesOutWithoutRunningAutoscaler(c *check.C) {
+	a := &Autoscaler{
+		Config:  Config{Timeout: 10 * time.Millisecond},
+		eventsC: make(chan request),
+	}
+	_, err := a.submit(context.TODO(), request{event: Event{Type: EventScaleUp}})
+	c.Assert(trace.IsLimitExceeded(err), check.Equals, true)
+}
+
+type mockOperator struct {
+	ops.Operator
+	site    ops.Site
+	shrinks []ops.CreateSiteShrinkOperationRequest
+}
+
+func (o *mockOperator) GetLocalSite() (*ops.Site, error) {
+	return &o.site, nil
+}
+
+func (o *mockOperator) CreateSiteShrinkOperation(req ops.CreateSiteShrinkOperationRequest) (*ops.SiteOperationKey, error) {
+	o.shrinks = append(o.shrinks, req)
+	return &ops.SiteOperationKey{
+		AccountID:   o.site.AccountID,
+		SiteDomain:  o.site.Domain,
+		OperationID: "operation",
+	}, nil
+}
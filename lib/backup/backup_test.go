@@ -0,0 +1,275 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backup
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/gravitational/gravity/lib/app"
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/storage/keyval"
+
+	teleservices "github.com/gravitational/teleport/lib/services"
+	"github.com/gravitational/trace"
+	"github.com/jonboulle/clockwork"
+	check "gopkg.in/check.v1"
+)
+
+func TestBackup(t *testing.T) { check.TestingT(t) }
+
+type BackupSuite struct {
+	dir     string
+	clock   clockwork.FakeClock
+	backend storage.Backend
+}
+
+var _ = check.Suite(&BackupSuite{})
+
+func (s *BackupSuite) SetUpTest(c *check.C) {
+	s.dir = c.MkDir()
+	// Friday
+	s.clock = clockwork.NewFakeClockAt(time.Date(2018, time.June, 15, 10, 30, 0, 0, time.UTC))
+	var err error
+	s.backend, err = keyval.NewBolt(keyval.BoltConfig{
+		Clock: s.clock,
+		Path:  filepath.Join(s.dir, "bolt.db"),
+	})
+	c.Assert(err, check.IsNil)
+}
+
+func (s *BackupSuite) TearDownTest(c *check.C) {
+	if s.backend != nil {
+		s.backend.Close()
+	}
+}
+
+func (s *BackupSuite) TestPrunesByCount(c *check.C) {
+	dest := newMemDestination(s.clock.Now(), 5)
+	removed, err := Prune(context.TODO(), dest, storage.BackupRetention{Count: 2}, s.clock.Now())
+	c.Assert(err, check.IsNil)
+	c.Assert(removed, check.DeepEquals, []string{"backup-2", "backup-1", "backup-0"})
+	c.Assert(dest.names(), check.DeepEquals, []string{"backup-3", "backup-4"})
+}
+
+func (s *BackupSuite) TestPrunesByAge(c *check.C) {
+	dest := newMemDestination(s.clock.Now(), 5)
+	removed, err := Prune(context.TODO(), dest, storage.BackupRetention{
+		MaxAge: teleservices.NewDuration(60 * time.Hour),
+	}, s.clock.Now())
+	c.Assert(err, check.IsNil)
+	c.Assert(removed, check.DeepEquals, []string{"backup-1", "backup-0"})
+	c.Assert(dest.names(), check.DeepEquals, []string{"backup-2", "backup-3", "backup-4"})
+}
+
+func (s *BackupSuite) TestDirDestination(c *check.C) {
+	dest := &dirDestination{dir: filepath.Join(s.dir, "nightly"), schedule: "nightly"}
+	backups, err := dest.List(context.TODO())
+	c.Assert(err, check.IsNil)
+	c.Assert(backups, check.HasLen, 0)
+
+	name, err := dest.Upload(context.TODO(), s.clock.Now(), bytes.NewBufferString("backup data"))
+	c.Assert(err, check.IsNil)
+	c.Assert(name, check.Equals, "nightly-20180615103000.tar.gz")
+	data, err := ioutil.ReadFile(filepath.Join(s.dir, "nightly", name))
+	c.Assert(err, check.IsNil)
+	c.Assert(string(data), check.Equals, "backup data")
+
+	backups, err = dest.List(context.TODO())
+	c.Assert(err, check.IsNil)
+	c.Assert(backups, check.HasLen, 1)
+	c.Assert(backups[0].Name, check.Equals, name)
+
+	c.Assert(dest.Delete(context.TODO(), name), check.IsNil)
+	backups, err = dest.List(context.TODO())
+	c.Assert(err, check.IsNil)
+	c.Assert(backups, check.HasLen, 0)
+}
+
+func (s *BackupSuite) TestSchedulerRunsDueBackups(c *check.C) {
+	schedule := storage.NewBackupSchedule("nightly", storage.BackupScheduleSpecV2{
+		Schedule: "0 2 * * *",
+		Destination: storage.BackupDestination{
+			NFS: &storage.BackupNFSDestination{Path: defaults.ClusterBackupsDir},
+		},
+		Retention: storage.BackupRetention{Count: 1},
+	})
+	c.Assert(s.backend.UpsertBackupSchedule(schedule), check.IsNil)
+
+	scheduler, err := NewScheduler(Config{
+		Backend:     s.backend,
+		Operator:    &testOperator{},
+		Apps:        testApps{},
+		AdvertiseIP: "192.168.1.1",
+		StagingDir:  filepath.Join(s.dir, "staging"),
+		Clock:       s.clock,
+	})
+	c.Assert(err, check.IsNil)
+	destDir := filepath.Join(s.dir, "backups")
+	scheduler.newDestination = func(schedule storage.BackupSchedule, clusterName string) (Destination, error) {
+		return &dirDestination{dir: destDir, schedule: schedule.GetName()}, nil
+	}
+	var hookErr error
+	scheduler.runHook = func(ctx context.Context, cluster ops.Site, schedule storage.BackupSchedule, dir string) error {
+		if hookErr != nil {
+			return hookErr
+		}
+		return ioutil.WriteFile(filepath.Join(dir, "backup.sql"), []byte("data"), defaults.SharedReadMask)
+	}
+
+	// the schedule starts when it is first seen
+	c.Assert(scheduler.check(context.TODO()), check.IsNil)
+	s.assertStatus(c, storage.BackupScheduleStatus{})
+
+	s.clock.Advance(16 * time.Hour)
+	c.Assert(scheduler.check(context.TODO()), check.IsNil)
+	s.assertStatus(c, storage.BackupScheduleStatus{
+		LastRun:     s.clock.Now(),
+		LastSuccess: s.clock.Now(),
+		LastBackup:  "nightly-20180616023000.tar.gz",
+	})
+	s.assertBackups(c, destDir, "nightly-20180616023000.tar.gz")
+	staged, err := ioutil.ReadDir(filepath.Join(s.dir, "staging"))
+	c.Assert(err, check.IsNil)
+	c.Assert(staged, check.HasLen, 0, check.Commentf("expected staging directory to be cleaned up"))
+
+	// not due until the next day
+	s.clock.Advance(time.Hour)
+	c.Assert(scheduler.check(context.TODO()), check.IsNil)
+	s.assertBackups(c, destDir, "nightly-20180616023000.tar.gz")
+
+	// the previous backup is retained on failure
+	hookErr = trace.BadParameter("database is not available")
+	s.clock.Advance(23 * time.Hour)
+	c.Assert(scheduler.check(context.TODO()), check.IsNil)
+	s.assertStatus(c, storage.BackupScheduleStatus{
+		LastRun:     s.clock.Now(),
+		LastSuccess: s.clock.Now().Add(-24 * time.Hour),
+		LastFailure: s.clock.Now(),
+		LastError:   "database is not available",
+		LastBackup:  "nightly-20180616023000.tar.gz",
+	})
+	s.assertBackups(c, destDir, "nightly-20180616023000.tar.gz")
+
+	// old backups are removed according to the retention policy
+	hookErr = nil
+	s.clock.Advance(24 * time.Hour)
+	c.Assert(scheduler.check(context.TODO()), check.IsNil)
+	s.assertBackups(c, destDir, "nightly-20180618023000.tar.gz")
+
+	// a single backup runs for all backups missed while the scheduler was down
+	s.clock.Advance(3 * 24 * time.Hour)
+	c.Assert(scheduler.check(context.TODO()), check.IsNil)
+	missedRun := s.clock.Now()
+	s.clock.Advance(time.Minute)
+	c.Assert(scheduler.check(context.TODO()), check.IsNil)
+	s.assertStatus(c, storage.BackupScheduleStatus{
+		LastRun:     missedRun,
+		LastSuccess: missedRun,
+		LastFailure: missedRun.Add(-4 * 24 * time.Hour),
+		LastBackup:  "nightly-20180621023000.tar.gz",
+	})
+	s.assertBackups(c, destDir, "nightly-20180621023000.tar.gz")
+}
+
+func (s *BackupSuite) assertStatus(c *check.C, expected storage.BackupScheduleStatus) {
+	schedule, err := s.backend.GetBackupSchedule("nightly")
+	c.Assert(err, check.IsNil)
+	status := schedule.GetStatus()
+	c.Assert(status.LastRun.Equal(expected.LastRun), check.Equals, true,
+		check.Commentf("last run %v, expected %v", status.LastRun, expected.LastRun))
+	c.Assert(status.LastSuccess.Equal(expected.LastSuccess), check.Equals, true,
+		check.Commentf("last success %v, expected %v", status.LastSuccess, expected.LastSuccess))
+	c.Assert(status.LastFailure.Equal(expected.LastFailure), check.Equals, true,
+		check.Commentf("last failure %v, expected %v", status.LastFailure, expected.LastFailure))
+	c.Assert(status.LastError, check.Equals, expected.LastError)
+	c.Assert(status.LastBackup, check.Equals, expected.LastBackup)
+}
+
+func (s *BackupSuite) assertBackups(c *check.C, dir string, expected ...string) {
+	files, err := ioutil.ReadDir(dir)
+	c.Assert(err, check.IsNil)
+	var names []string
+	for _, file := range files {
+		names = append(names, file.Name())
+	}
+	c.Assert(names, check.DeepEquals, expected)
+}
+
+// newMemDestination returns a destination with the specified number of
+// backups created a day apart with the last one created at now
+func newMemDestination(now time.Time, count int) *memDestination {
+	dest := &memDestination{backups: make(map[string]time.Time)}
+	for i := 0; i < count; i++ {
+		dest.backups[fmt.Sprintf("backup-%v", i)] = now.Add(-time.Duration(count-i-1) * 24 * time.Hour)
+	}
+	return dest
+}
+
+type memDestination struct {
+	backups map[string]time.Time
+}
+
+func (d *memDestination) Upload(ctx context.Context, created time.Time, archive io.Reader) (string, error) {
+	name := archiveName("test", created)
+	d.backups[name] = created
+	return name, nil
+}
+
+func (d *memDestination) List(ctx context.Context) (backups []Backup, err error) {
+	for name, created := range d.backups {
+		backups = append(backups, Backup{Name: name, Created: created})
+	}
+	return backups, nil
+}
+
+func (d *memDestination) Delete(ctx context.Context, name string) error {
+	if _, ok := d.backups[name]; !ok {
+		return trace.NotFound("backup %v not found", name)
+	}
+	delete(d.backups, name)
+	return nil
+}
+
+func (d *memDestination) names() (names []string) {
+	for name := range d.backups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+type testOperator struct {
+	ops.Operator
+}
+
+func (o *testOperator) GetLocalSite() (*ops.Site, error) {
+	return &ops.Site{Domain: "example.com"}, nil
+}
+
+type testApps struct {
+	app.Applications
+}
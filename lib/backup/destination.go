@@ -0,0 +1,386 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backup
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/loc"
+	"github.com/gravitational/gravity/lib/pack"
+	"github.com/gravitational/gravity/lib/pack/webpack"
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/utils"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/gravitational/trace"
+)
+
+// Destination is a location scheduled backups are shipped to
+type Destination interface {
+	// Upload stores the backup archive created at the specified time
+	// and returns the name of the stored backup
+	Upload(ctx context.Context, created time.Time, archive io.Reader) (name string, err error)
+	// List returns the backups stored in the destination
+	List(ctx context.Context) ([]Backup, error)
+	// Delete removes the backup specified with name
+	Delete(ctx context.Context, name string) error
+}
+
+// Backup describes a stored backup
+type Backup struct {
+	// Name is the backup name unique within its destination
+	Name string
+	// Created is the backup creation time
+	Created time.Time
+}
+
+// NewDestination returns the destination for backups of the specified
+// schedule. Backups of each schedule are kept separately so retention
+// of one schedule does not affect backups of the others
+func NewDestination(schedule storage.BackupSchedule, clusterName string) (Destination, error) {
+	dest := schedule.GetDestination()
+	switch {
+	case dest.S3 != nil:
+		return newS3Destination(*dest.S3, schedule.GetName())
+	case dest.NFS != nil:
+		return &dirDestination{
+			dir:      filepath.Join(dest.NFS.Path, schedule.GetName()),
+			schedule: schedule.GetName(),
+		}, nil
+	case dest.Cluster != nil:
+		return newClusterDestination(*dest.Cluster, schedule.GetName(), clusterName)
+	}
+	return nil, trace.BadParameter("backup schedule %q has no destination", schedule.GetName())
+}
+
+// Prune removes backups that exceed the specified retention policy
+// and returns the names of removed backups
+func Prune(ctx context.Context, dest Destination, retention storage.BackupRetention, now time.Time) (removed []string, err error) {
+	backups, err := dest.List(ctx)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	// most recent first
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].Created.After(backups[j].Created)
+	})
+	for i, backup := range backups {
+		expired := retention.MaxAge.Duration != 0 && now.Sub(backup.Created) > retention.MaxAge.Duration
+		excess := retention.Count != 0 && i >= retention.Count
+		if !expired && !excess {
+			continue
+		}
+		if err := dest.Delete(ctx, backup.Name); err != nil && !trace.IsNotFound(err) {
+			return removed, trace.Wrap(err)
+		}
+		removed = append(removed, backup.Name)
+	}
+	return removed, nil
+}
+
+// archiveName returns the name of the backup archive for the specified schedule
+func archiveName(schedule string, created time.Time) string {
+	return fmt.Sprintf("%v-%v%v", schedule, created.UTC().Format(timestampFormat), archiveExt)
+}
+
+// archiveCreated returns the creation time encoded in the specified archive name
+func archiveCreated(name string) (created time.Time, ok bool) {
+	name = strings.TrimSuffix(name, archiveExt)
+	if len(name) < len(timestampFormat) {
+		return time.Time{}, false
+	}
+	created, err := time.Parse(timestampFormat, name[len(name)-len(timestampFormat):])
+	if err != nil {
+		return time.Time{}, false
+	}
+	return created, true
+}
+
+func newS3Destination(config storage.BackupS3Destination, schedule string) (*s3Destination, error) {
+	awsConfig := &aws.Config{
+		Region: aws.String(config.Region),
+	}
+	if config.Region == "" {
+		awsConfig.Region = aws.String(defaults.AWSRegion)
+	}
+	if config.AccessKeyID != "" {
+		awsConfig.Credentials = credentials.NewStaticCredentials(
+			config.AccessKeyID, config.SecretAccessKey, "")
+	}
+	if config.Endpoint != "" {
+		// S3-compatible stores commonly do not support
+		// virtual-hosted style bucket addressing
+		awsConfig.Endpoint = aws.String(config.Endpoint)
+		awsConfig.S3ForcePathStyle = aws.Bool(true)
+	}
+	session, err := session.NewSession(awsConfig)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	client := s3.New(session)
+	return &s3Destination{
+		client:   client,
+		uploader: s3manager.NewUploaderWithClient(client),
+		bucket:   config.Bucket,
+		prefix:   path.Join(config.Prefix, schedule),
+		schedule: schedule,
+	}, nil
+}
+
+// s3Destination stores backups in an S3-compatible bucket
+type s3Destination struct {
+	client   *s3.S3
+	uploader *s3manager.Uploader
+	bucket   string
+	// prefix is the key prefix backups of the schedule are stored under
+	prefix   string
+	schedule string
+}
+
+// Upload stores the backup archive in the bucket
+func (d *s3Destination) Upload(ctx context.Context, created time.Time, archive io.Reader) (string, error) {
+	name := archiveName(d.schedule, created)
+	_, err := d.uploader.UploadWithContext(ctx, &s3manager.UploadInput{
+		Bucket: aws.String(d.bucket),
+		Key:    aws.String(path.Join(d.prefix, name)),
+		Body:   archive,
+	})
+	if err != nil {
+		return "", trace.Wrap(utils.ConvertS3Error(err))
+	}
+	return name, nil
+}
+
+// List returns the backups stored in the bucket
+func (d *s3Destination) List(ctx context.Context) (backups []Backup, err error) {
+	err = d.client.ListObjectsV2PagesWithContext(ctx, &s3.ListObjectsV2Input{
+		Bucket: aws.String(d.bucket),
+		Prefix: aws.String(d.prefix + "/"),
+	}, func(page *s3.ListObjectsV2Output, _ bool) bool {
+		for _, object := range page.Contents {
+			name := path.Base(aws.StringValue(object.Key))
+			if !isArchive(name) {
+				continue
+			}
+			backups = append(backups, Backup{
+				Name:    name,
+				Created: aws.TimeValue(object.LastModified),
+			})
+		}
+		return true
+	})
+	if err != nil {
+		return nil, trace.Wrap(utils.ConvertS3Error(err))
+	}
+	return backups, nil
+}
+
+// Delete removes the backup from the bucket
+func (d *s3Destination) Delete(ctx context.Context, name string) error {
+	_, err := d.client.DeleteObjectWithContext(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(d.bucket),
+		Key:    aws.String(path.Join(d.prefix, name)),
+	})
+	return trace.Wrap(utils.ConvertS3Error(err))
+}
+
+// String returns a textual representation of the destination
+func (d *s3Destination) String() string {
+	return fmt.Sprintf("s3://%v/%v", d.bucket, d.prefix)
+}
+
+// dirDestination stores backups in a local directory, such as an NFS mount
+type dirDestination struct {
+	dir      string
+	schedule string
+}
+
+// Upload stores the backup archive in the directory.
+// The archive is written to a temporary file first so partially written
+// backups are never listed
+func (d *dirDestination) Upload(ctx context.Context, created time.Time, archive io.Reader) (string, error) {
+	if err := os.MkdirAll(d.dir, defaults.SharedDirMask); err != nil {
+		return "", trace.ConvertSystemError(err)
+	}
+	name := archiveName(d.schedule, created)
+	f, err := ioutil.TempFile(d.dir, ".tmp-"+name)
+	if err != nil {
+		return "", trace.ConvertSystemError(err)
+	}
+	defer os.Remove(f.Name())
+	_, err = io.Copy(f, archive)
+	if err == nil {
+		err = f.Sync()
+	}
+	if errClose := f.Close(); err == nil {
+		err = errClose
+	}
+	if err != nil {
+		return "", trace.ConvertSystemError(err)
+	}
+	if err := os.Rename(f.Name(), filepath.Join(d.dir, name)); err != nil {
+		return "", trace.ConvertSystemError(err)
+	}
+	return name, nil
+}
+
+// List returns the backups stored in the directory
+func (d *dirDestination) List(ctx context.Context) ([]Backup, error) {
+	files, err := ioutil.ReadDir(d.dir)
+	if err != nil {
+		err = trace.ConvertSystemError(err)
+		if trace.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, trace.Wrap(err)
+	}
+	var backups []Backup
+	for _, file := range files {
+		if file.IsDir() || !isArchive(file.Name()) || strings.HasPrefix(file.Name(), ".") {
+			continue
+		}
+		created, ok := archiveCreated(file.Name())
+		if !ok {
+			created = file.ModTime()
+		}
+		backups = append(backups, Backup{
+			Name:    file.Name(),
+			Created: created,
+		})
+	}
+	return backups, nil
+}
+
+// Delete removes the backup from the directory
+func (d *dirDestination) Delete(ctx context.Context, name string) error {
+	return trace.ConvertSystemError(os.Remove(filepath.Join(d.dir, filepath.Base(name))))
+}
+
+// String returns a textual representation of the destination
+func (d *dirDestination) String() string {
+	return d.dir
+}
+
+func newClusterDestination(config storage.BackupClusterDestination, schedule, clusterName string) (*clusterDestination, error) {
+	packages, err := webpack.NewBearerClient(config.Address, config.Token)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	repository := config.Repository
+	if repository == "" {
+		repository = clusterName
+	}
+	return &clusterDestination{
+		packages:   packages,
+		address:    config.Address,
+		repository: repository,
+		name:       fmt.Sprintf("%v-%v", backupPackagePrefix, schedule),
+	}, nil
+}
+
+// clusterDestination stores backups as packages in the package
+// service of another cluster, such as an Ops Center
+type clusterDestination struct {
+	packages pack.PackageService
+	address  string
+	// repository is the package repository backups are stored in
+	repository string
+	// name is the name of the backup packages
+	name string
+}
+
+// Upload stores the backup archive as a package.
+// Package versions are derived from the backup creation time so they
+// sort in the order backups were taken
+func (d *clusterDestination) Upload(ctx context.Context, created time.Time, archive io.Reader) (string, error) {
+	locator := loc.Locator{
+		Repository: d.repository,
+		Name:       d.name,
+		Version:    fmt.Sprintf("0.0.%v", created.UTC().Unix()),
+	}
+	if err := d.packages.UpsertRepository(d.repository, time.Time{}); err != nil {
+		return "", trace.Wrap(err)
+	}
+	_, err := d.packages.CreatePackage(locator, archive)
+	if err != nil {
+		return "", trace.Wrap(err)
+	}
+	return locator.String(), nil
+}
+
+// List returns the backup packages of the schedule
+func (d *clusterDestination) List(ctx context.Context) ([]Backup, error) {
+	packages, err := d.packages.GetPackages(d.repository)
+	if err != nil {
+		if trace.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, trace.Wrap(err)
+	}
+	var backups []Backup
+	for _, envelope := range packages {
+		if envelope.Locator.Name != d.name {
+			continue
+		}
+		backups = append(backups, Backup{
+			Name:    envelope.Locator.String(),
+			Created: envelope.Created,
+		})
+	}
+	return backups, nil
+}
+
+// Delete removes the backup package
+func (d *clusterDestination) Delete(ctx context.Context, name string) error {
+	locator, err := loc.ParseLocator(name)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return trace.Wrap(d.packages.DeletePackage(*locator))
+}
+
+// String returns a textual representation of the destination
+func (d *clusterDestination) String() string {
+	return fmt.Sprintf("%v/%v", d.address, d.repository)
+}
+
+func isArchive(name string) bool {
+	return strings.HasSuffix(name, archiveExt)
+}
+
+const (
+	// archiveExt is the extension of backup archives
+	archiveExt = ".tar.gz"
+	// timestampFormat is the format of the backup creation time in archive names
+	timestampFormat = "20060102150405"
+	// backupPackagePrefix is the name prefix of packages with backups
+	backupPackagePrefix = "backup"
+)
@@ -0,0 +1,312 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package backup implements scheduled cluster backups.
+//
+// The scheduler runs on the active gravity-site master, executes the
+// application backup hook according to the cron schedules of backup
+// schedule resources, ships the resulting archives to the configured
+// destination and removes backups that exceed the retention policy.
+package backup
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/gravitational/gravity/lib/app"
+	"github.com/gravitational/gravity/lib/app/hooks"
+	"github.com/gravitational/gravity/lib/cron"
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/schema"
+	"github.com/gravitational/gravity/lib/storage"
+
+	dockerarchive "github.com/docker/docker/pkg/archive"
+	teleutils "github.com/gravitational/teleport/lib/utils"
+	"github.com/gravitational/trace"
+	"github.com/jonboulle/clockwork"
+	"github.com/sirupsen/logrus"
+	"k8s.io/api/core/v1"
+)
+
+// Config defines the backup scheduler configuration
+type Config struct {
+	// Backend is the cluster backend with backup schedules
+	Backend storage.Backend
+	// Operator is the cluster operator service
+	Operator ops.Operator
+	// Apps is the cluster application service used to run backup hooks
+	Apps app.Applications
+	// AdvertiseIP is the advertise IP of the node the scheduler runs on.
+	// Backup hooks are scheduled on this node so the scheduler can access
+	// the backup data
+	AdvertiseIP string
+	// StagingDir is the directory backup hooks write backup data into
+	// before it is shipped to the destination
+	StagingDir string
+	// Clock is used to tell time
+	Clock clockwork.Clock
+	// FieldLogger is used for logging
+	logrus.FieldLogger
+}
+
+// CheckAndSetDefaults validates the configuration and sets defaults
+func (c *Config) CheckAndSetDefaults() error {
+	if c.Backend == nil {
+		return trace.BadParameter("missing Backend")
+	}
+	if c.Operator == nil {
+		return trace.BadParameter("missing Operator")
+	}
+	if c.Apps == nil {
+		return trace.BadParameter("missing Apps")
+	}
+	if c.AdvertiseIP == "" {
+		return trace.BadParameter("missing AdvertiseIP")
+	}
+	if c.StagingDir == "" {
+		c.StagingDir = defaults.ScheduledBackupDir
+	}
+	if c.Clock == nil {
+		c.Clock = clockwork.NewRealClock()
+	}
+	if c.FieldLogger == nil {
+		c.FieldLogger = logrus.WithField(trace.Component, "backup")
+	}
+	return nil
+}
+
+// NewScheduler returns a new backup scheduler
+func NewScheduler(config Config) (*Scheduler, error) {
+	if err := config.CheckAndSetDefaults(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	s := &Scheduler{
+		Config: config,
+		seen:   make(map[string]time.Time),
+	}
+	s.runHook = s.runBackupHook
+	s.newDestination = NewDestination
+	return s, nil
+}
+
+// Scheduler runs scheduled backups
+type Scheduler struct {
+	// Config is the scheduler configuration
+	Config
+	// seen maps names of schedules that have never run to the time
+	// they were first seen which is used as the start of their schedule
+	seen map[string]time.Time
+	// runHook runs the backup hook that writes backup data into the specified directory
+	runHook func(ctx context.Context, cluster ops.Site, schedule storage.BackupSchedule, dir string) error
+	// newDestination returns the destination for backups of the specified schedule
+	newDestination func(schedule storage.BackupSchedule, clusterName string) (Destination, error)
+}
+
+// Run checks backup schedules periodically and runs the backups
+// that are due until the context is canceled
+func (s *Scheduler) Run(ctx context.Context) error {
+	s.Info("Starting backup scheduler.")
+	for {
+		select {
+		case <-s.Clock.After(defaults.BackupScheduleCheckInterval):
+			if err := s.check(ctx); err != nil {
+				s.Errorf("Failed to check backup schedules: %v.", trace.DebugReport(err))
+			}
+		case <-ctx.Done():
+			s.Info("Stopping backup scheduler.")
+			return nil
+		}
+	}
+}
+
+// check runs the backups that are due
+func (s *Scheduler) check(ctx context.Context) error {
+	schedules, err := s.Backend.GetBackupSchedules()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	now := s.Clock.Now().UTC()
+	names := make(map[string]struct{}, len(schedules))
+	for _, schedule := range schedules {
+		names[schedule.GetName()] = struct{}{}
+		due, err := s.isDue(schedule, now)
+		if err != nil {
+			s.Warnf("Invalid backup schedule %v: %v.", schedule.GetName(), err)
+			continue
+		}
+		if !due {
+			continue
+		}
+		if err := s.runAndRecord(ctx, schedule); err != nil {
+			s.Errorf("Failed to update status of backup schedule %v: %v.",
+				schedule.GetName(), trace.DebugReport(err))
+		}
+	}
+	for name := range s.seen {
+		if _, ok := names[name]; !ok {
+			delete(s.seen, name)
+		}
+	}
+	return nil
+}
+
+// isDue returns true if the backup of the specified schedule should run.
+// If any backups have been missed while no scheduler was running, a single
+// backup runs in their place and the schedule resumes from that time
+func (s *Scheduler) isDue(schedule storage.BackupSchedule, now time.Time) (bool, error) {
+	cronSchedule, err := cron.Parse(schedule.GetSchedule())
+	if err != nil {
+		return false, trace.Wrap(err)
+	}
+	lastRun := schedule.GetStatus().LastRun
+	if lastRun.IsZero() {
+		seen, ok := s.seen[schedule.GetName()]
+		if !ok {
+			s.seen[schedule.GetName()] = now
+			return false, nil
+		}
+		lastRun = seen
+	}
+	next := cronSchedule.Next(lastRun)
+	return !next.IsZero() && !next.After(now), nil
+}
+
+// runAndRecord runs the backup and records the outcome in the schedule status
+func (s *Scheduler) runAndRecord(ctx context.Context, schedule storage.BackupSchedule) error {
+	logger := s.WithField("schedule", schedule.GetName())
+	status := schedule.GetStatus()
+	status.LastRun = s.Clock.Now().UTC()
+	if err := s.updateStatus(schedule.GetName(), status); err != nil {
+		return trace.Wrap(err)
+	}
+	delete(s.seen, schedule.GetName())
+
+	logger.Info("Starting scheduled backup.")
+	name, err := s.backup(ctx, schedule, status.LastRun)
+	if err != nil {
+		logger.Errorf("Scheduled backup failed: %v.", trace.DebugReport(err))
+		status.LastFailure = s.Clock.Now().UTC()
+		status.LastError = trace.UserMessage(err)
+	} else {
+		logger.Infof("Scheduled backup %v completed.", name)
+		status.LastSuccess = s.Clock.Now().UTC()
+		status.LastBackup = name
+		status.LastError = ""
+	}
+	return trace.Wrap(s.updateStatus(schedule.GetName(), status))
+}
+
+// updateStatus updates the status of the latest version of the schedule
+// which could have been changed while the backup was running
+func (s *Scheduler) updateStatus(name string, status storage.BackupScheduleStatus) error {
+	schedule, err := s.Backend.GetBackupSchedule(name)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	schedule.SetStatus(status)
+	return trace.Wrap(s.Backend.UpsertBackupSchedule(schedule))
+}
+
+// backup runs the backup hook, ships the backup to the schedule destination,
+// applies the retention policy and returns the name of the new backup
+func (s *Scheduler) backup(ctx context.Context, schedule storage.BackupSchedule, created time.Time) (string, error) {
+	cluster, err := s.Operator.GetLocalSite()
+	if err != nil {
+		return "", trace.Wrap(err)
+	}
+	dest, err := s.newDestination(schedule, cluster.Domain)
+	if err != nil {
+		return "", trace.Wrap(err)
+	}
+
+	id, err := teleutils.CryptoRandomHex(3)
+	if err != nil {
+		return "", trace.Wrap(err)
+	}
+	dir := filepath.Join(s.StagingDir, id)
+	if err := os.MkdirAll(dir, defaults.SharedDirMask); err != nil {
+		return "", trace.ConvertSystemError(err)
+	}
+	defer func() {
+		if err := os.RemoveAll(dir); err != nil {
+			s.Warnf("Failed to remove backup directory %v: %v.", dir, err)
+		}
+	}()
+
+	if err := s.runHook(ctx, *cluster, schedule, dir); err != nil {
+		return "", trace.Wrap(err)
+	}
+
+	archive, err := dockerarchive.Tar(dir, dockerarchive.Gzip)
+	if err != nil {
+		return "", trace.Wrap(err, "failed to compress the backup directory %v", dir)
+	}
+	defer archive.Close()
+	name, err := dest.Upload(ctx, created, archive)
+	if err != nil {
+		return "", trace.Wrap(err, "failed to upload backup to %v", dest)
+	}
+
+	removed, err := Prune(ctx, dest, schedule.GetRetention(), s.Clock.Now())
+	if err != nil {
+		// the backup itself has succeeded
+		s.Warnf("Failed to remove old backups from %v: %v.", dest, trace.DebugReport(err))
+	}
+	for _, name := range removed {
+		s.Infof("Removed backup %v.", name)
+	}
+	return name, nil
+}
+
+// runBackupHook runs the application backup hook on the local node
+// with the specified directory mounted as the backup volume
+func (s *Scheduler) runBackupHook(ctx context.Context, cluster ops.Site, schedule storage.BackupSchedule, dir string) error {
+	server, err := cluster.ClusterState.FindServerByIP(s.AdvertiseIP)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	req := app.HookRunRequest{
+		Application: cluster.App.Package,
+		Hook:        schema.HookBackup,
+		Timeout:     schedule.GetTimeout(),
+		Volumes: []v1.Volume{{
+			Name: hooks.VolumeBackup,
+			VolumeSource: v1.VolumeSource{
+				HostPath: &v1.HostPathVolumeSource{
+					Path: dir,
+				},
+			},
+		}},
+		VolumeMounts: []v1.VolumeMount{{
+			Name:      hooks.VolumeBackup,
+			MountPath: hooks.ContainerBackupDir,
+		}},
+		NodeSelector: map[string]string{
+			defaults.KubernetesHostnameLabel: server.KubeNodeID(),
+		},
+	}
+	ref, out, err := app.RunAppHook(ctx, s.Apps, req)
+	s.WithField("schedule", schedule.GetName()).Debugf("Backup hook output: %s.", out)
+	if ref != nil {
+		if err := s.Apps.DeleteAppHookJob(ctx, *ref); err != nil {
+			s.Warnf("Failed to delete hook %v: %v.", ref, trace.DebugReport(err))
+		}
+	}
+	return trace.Wrap(err)
+}
@@ -17,8 +17,11 @@ limitations under the License.
 package blob
 
 import (
+	"context"
 	"io"
 	"time"
+
+	"github.com/gravitational/gravity/lib/storage"
 )
 
 // Envelope specifies the metadata about BLOB - it's SHA512 hash and size
@@ -54,3 +57,44 @@ type Objects interface {
 	// GetBLOBEnvelope returns BLOB envelope
 	GetBLOBEnvelope(hash string) (*Envelope, error)
 }
+
+// Checker is implemented by BLOB storages that can verify
+// the integrity of the stored BLOBs
+type Checker interface {
+	// Check verifies the BLOBs against their hashes. If repair is set,
+	// corrupted BLOBs are set aside and fetched again, and BLOBs stored
+	// on too few peers are replicated
+	Check(ctx context.Context, repair bool) (*storage.BLOBIntegrity, error)
+}
+
+// Quarantiner is implemented by BLOB storages that can set
+// corrupted BLOBs aside for inspection instead of deleting them
+type Quarantiner interface {
+	// QuarantineBLOB removes the BLOB specified with hash from the storage
+	// and keeps its data aside
+	QuarantineBLOB(hash string) error
+}
+
+// Pruner is implemented by BLOB storages that share data between BLOBs
+type Pruner interface {
+	// PruneBLOBs removes the data no longer referenced by any BLOB.
+	// If dryRun is set, the data is only counted
+	PruneBLOBs(ctx context.Context, dryRun bool) (*PruneResult, error)
+}
+
+// PruneResult describes the data removed from the BLOB storage
+type PruneResult struct {
+	// Removed is the number of removed data items
+	Removed int
+	// ReclaimedBytes is the size of the removed data
+	ReclaimedBytes int64
+}
+
+const (
+	// BackendFS stores BLOBs on the local filesystem and replicates
+	// them between the cluster peers
+	BackendFS = "fs"
+	// BackendS3 stores BLOBs in an S3-compatible bucket shared
+	// by all cluster peers
+	BackendS3 = "s3"
+)
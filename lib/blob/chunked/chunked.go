@@ -0,0 +1,890 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package chunked implements BLOB storage on the local filesystem that splits
+// BLOBs into content-defined chunks and stores each distinct chunk once.
+//
+// Different versions of the same package share most of their data, so a node
+// or an Ops Center that keeps many versions of a package only stores the chunks
+// that differ between the versions. The chunk boundaries are the same as
+// the ones used for package deltas.
+//
+// The storage is laid out as follows:
+//
+//	<path>/chunks/<prefix>/<sha256>           - chunk data
+//	<path>/index/<prefix>/<sha512>            - list of chunks of a BLOB
+//	<path>/refs/<prefix>/<sha256>/<sha512>    - reference to a chunk from a BLOB
+//	<path>/blobs/<prefix>/<sha512>            - BLOBs stored as whole files
+//
+// BLOBs stored as whole files by the fs storage are still served and
+// can be converted into chunks with Convert.
+//
+// Each BLOB records a reference to each of its chunks, so deleting a BLOB
+// only looks at its own chunks and removes those left without references.
+// The storage is shared between processes, e.g. the cluster controller
+// and gravity gc, so the chunks are only removed under an exclusive
+// file lock while BLOBs are written and read under a shared one.
+// Open readers hold the shared lock until they are closed.
+package chunked
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/gravitational/gravity/lib/blob"
+	"github.com/gravitational/gravity/lib/blob/fs"
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/pack/delta"
+
+	teleutils "github.com/gravitational/teleport/lib/utils"
+	"github.com/gravitational/trace"
+	"github.com/sirupsen/logrus"
+)
+
+// Config defines the chunked BLOB storage configuration
+type Config struct {
+	// Path is the storage directory
+	Path string
+	// FieldLogger is used for logging
+	logrus.FieldLogger
+}
+
+// CheckAndSetDefaults validates the configuration and sets defaults
+func (c *Config) CheckAndSetDefaults() error {
+	if c.Path == "" {
+		return trace.BadParameter("missing Path parameter")
+	}
+	if c.FieldLogger == nil {
+		c.FieldLogger = logrus.WithField(trace.Component, "blob:chunked")
+	}
+	return nil
+}
+
+// New returns a new chunked BLOB storage in the specified directory
+func New(config Config) (blob.Objects, error) {
+	return newObjects(config)
+}
+
+// Open returns the BLOB storage in the specified directory:
+// the chunked storage if the directory has been set up for chunks
+// and the storage of BLOBs as whole files otherwise
+func Open(path string) (blob.Objects, error) {
+	if IsChunked(path) {
+		return New(Config{Path: path})
+	}
+	return fs.New(path)
+}
+
+// IsChunked returns true if the BLOB storage in the specified
+// directory keeps BLOBs in chunks
+func IsChunked(path string) bool {
+	fi, err := os.Stat(filepath.Join(path, chunksDir))
+	return err == nil && fi.IsDir()
+}
+
+// Usage describes the disk usage of the chunked storage
+type Usage struct {
+	// BLOBs is the number of BLOBs stored in chunks
+	BLOBs int
+	// SizeBytes is the total size of the BLOBs stored in chunks
+	SizeBytes int64
+	// Chunks is the number of stored chunks
+	Chunks int
+	// StoredBytes is the total size of the stored chunks
+	StoredBytes int64
+}
+
+// ConvertResult describes the outcome of the conversion
+type ConvertResult struct {
+	// Converted is the number of BLOBs converted into chunks
+	Converted int
+	// Usage is the disk usage after the conversion
+	Usage Usage
+}
+
+// Convert converts the BLOBs stored as whole files
+// in the specified directory into chunks
+func Convert(ctx context.Context, config Config) (*ConvertResult, error) {
+	o, err := newObjects(config)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	defer o.Close()
+	hashes, err := o.legacy.GetBLOBs()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var result ConvertResult
+	for _, hash := range hashes {
+		if err := ctx.Err(); err != nil {
+			return nil, trace.Wrap(err)
+		}
+		converted, err := o.convert(hash)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		if converted {
+			result.Converted++
+		}
+	}
+	usage, err := o.usage()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	result.Usage = *usage
+	return &result, nil
+}
+
+// GetUsage returns the disk usage of the chunked storage
+// in the specified directory
+func GetUsage(config Config) (*Usage, error) {
+	o, err := newObjects(config)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	defer o.Close()
+	return o.usage()
+}
+
+func newObjects(config Config) (*objects, error) {
+	if err := config.CheckAndSetDefaults(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	legacy, err := fs.New(config.Path)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	o := &objects{Config: config, legacy: legacy}
+	for _, dir := range []string{o.chunksDir(), o.indexDir(), o.tempDir()} {
+		if err := os.MkdirAll(dir, defaults.SharedDirMask); err != nil {
+			return nil, trace.ConvertSystemError(err)
+		}
+	}
+	if err := o.initReferences(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return o, nil
+}
+
+type objects struct {
+	Config
+	// legacy is the storage of BLOBs as whole files
+	legacy blob.Objects
+}
+
+// Close closes the storage
+func (o *objects) Close() error {
+	return o.legacy.Close()
+}
+
+// GetBLOBs returns a list of BLOBs in the storage
+func (o *objects) GetBLOBs() ([]string, error) {
+	hashes, err := o.legacy.GetBLOBs()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	seen := make(map[string]struct{}, len(hashes))
+	for _, hash := range hashes {
+		seen[hash] = struct{}{}
+	}
+	err = walkFiles(o.indexDir(), func(path string, fi os.FileInfo) error {
+		if _, ok := seen[fi.Name()]; !ok {
+			hashes = append(hashes, fi.Name())
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	sort.Strings(hashes)
+	return hashes, nil
+}
+
+// WriteBLOB splits the data into chunks and writes
+// the chunks not yet in the storage
+func (o *objects) WriteBLOB(data io.Reader) (*blob.Envelope, error) {
+	unlock, err := o.lock(false)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	defer unlock()
+	hasher := sha512.New()
+	var idx index
+	err = delta.Split(io.TeeReader(data, hasher), func(data []byte) error {
+		hash := fmt.Sprintf("%x", sha256.Sum256(data))
+		if err := o.writeChunk(hash, data); err != nil {
+			return trace.Wrap(err)
+		}
+		idx.Chunks = append(idx.Chunks, chunk{Hash: hash, SizeBytes: int64(len(data))})
+		idx.SizeBytes += int64(len(data))
+		return nil
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	hash := fmt.Sprintf("%x", hasher.Sum(nil)[:sha512.Size/2])
+	// the references are recorded before the index so the chunks of
+	// an indexed BLOB are always referenced
+	for _, chunk := range idx.Chunks {
+		if err := o.addReference(chunk.Hash, hash); err != nil {
+			return nil, trace.Wrap(err)
+		}
+	}
+	if err := o.writeIndex(hash, idx); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	// the chunks supersede the copy of the BLOB stored as a whole file
+	if err := o.legacy.DeleteBLOB(hash); err != nil && !os.IsNotExist(trace.Unwrap(err)) {
+		o.WithError(err).Warnf("Failed to remove BLOB %v stored as file.", hash)
+	}
+	return o.GetBLOBEnvelope(hash)
+}
+
+// GetBLOBEnvelope returns the envelope of the BLOB identified by hash
+func (o *objects) GetBLOBEnvelope(hash string) (*blob.Envelope, error) {
+	fi, err := os.Stat(o.indexPath(hash))
+	if os.IsNotExist(err) {
+		return o.legacy.GetBLOBEnvelope(hash)
+	}
+	if err != nil {
+		return nil, trace.ConvertSystemError(err)
+	}
+	idx, err := o.readIndex(hash)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &blob.Envelope{
+		SizeBytes: idx.SizeBytes,
+		SHA512:    hash,
+		Modified:  fi.ModTime().UTC(),
+	}, nil
+}
+
+// OpenBLOB opens the BLOB identified by hash and returns reader.
+// The reader holds the shared lock until it is closed so the chunks
+// cannot be removed while they are being read
+func (o *objects) OpenBLOB(hash string) (blob.ReadSeekCloser, error) {
+	unlock, err := o.lock(false)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	idx, err := o.readIndex(hash)
+	if err != nil {
+		unlock()
+		if trace.IsNotFound(err) {
+			return o.legacy.OpenBLOB(hash)
+		}
+		return nil, trace.Wrap(err)
+	}
+	return newReader(o, *idx, unlock), nil
+}
+
+// DeleteBLOB deletes the BLOB identified by hash and
+// the chunks no other BLOB refers to
+func (o *objects) DeleteBLOB(hash string) error {
+	unlock, err := o.lock(true)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	defer unlock()
+	idx, err := o.readIndex(hash)
+	if trace.IsNotFound(err) {
+		return o.legacy.DeleteBLOB(hash)
+	}
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if err := os.Remove(o.indexPath(hash)); err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	removed, err := o.release(hash, idx.Chunks)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	o.WithField("blob", hash).Debugf("Removed %v of %v chunks.", removed, len(idx.Chunks))
+	if err := o.legacy.DeleteBLOB(hash); err != nil && !os.IsNotExist(trace.Unwrap(err)) {
+		return trace.Wrap(err)
+	}
+	return nil
+}
+
+// PruneBLOBs removes the chunks no BLOB refers to, e.g. the chunks
+// written by the writes that have been interrupted
+func (o *objects) PruneBLOBs(ctx context.Context, dryRun bool) (*blob.PruneResult, error) {
+	unlock, err := o.lock(true)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	defer unlock()
+	refs, err := o.references(ctx, dryRun)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var result blob.PruneResult
+	err = walkFiles(o.chunksDir(), func(path string, fi os.FileInfo) error {
+		if err := ctx.Err(); err != nil {
+			return trace.Wrap(err)
+		}
+		if refs[fi.Name()] {
+			return nil
+		}
+		result.Removed++
+		result.ReclaimedBytes += fi.Size()
+		if dryRun {
+			return nil
+		}
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return trace.ConvertSystemError(err)
+		}
+		err := os.Remove(o.chunkRefsDir(fi.Name()))
+		if err != nil && !os.IsNotExist(err) {
+			return trace.ConvertSystemError(err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &result, nil
+}
+
+// QuarantineBLOB removes the BLOB identified by hash from the storage.
+// The chunks of the BLOB that do not match their hashes are moved to
+// the quarantine directory so that the BLOB can be written anew
+func (o *objects) QuarantineBLOB(hash string) error {
+	unlock, err := o.lock(true)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	defer unlock()
+	idx, err := o.readIndex(hash)
+	if trace.IsNotFound(err) {
+		quarantiner, ok := o.legacy.(blob.Quarantiner)
+		if !ok {
+			return trace.NotImplemented("storage does not support quarantine")
+		}
+		return quarantiner.QuarantineBLOB(hash)
+	}
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if err := os.MkdirAll(o.quarantineDir(), defaults.SharedDirMask); err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	for _, chunk := range idx.Chunks {
+		ok, err := o.verifyChunk(chunk)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		if ok {
+			continue
+		}
+		o.WithField("blob", hash).Warnf("Chunk %v is corrupted.", chunk.Hash)
+		err = os.Rename(o.chunkPath(chunk.Hash), filepath.Join(o.quarantineDir(), chunk.Hash))
+		if err != nil && !os.IsNotExist(err) {
+			return trace.ConvertSystemError(err)
+		}
+	}
+	err = os.Rename(o.indexPath(hash), filepath.Join(o.quarantineDir(), hash+".index"))
+	if err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	_, err = o.release(hash, idx.Chunks)
+	return trace.Wrap(err)
+}
+
+// convert converts the BLOB stored as a whole file into chunks.
+// Returns false if the BLOB is corrupted and has not been converted
+func (o *objects) convert(hash string) (converted bool, err error) {
+	f, err := o.legacy.OpenBLOB(hash)
+	if err != nil {
+		return false, trace.Wrap(err)
+	}
+	defer f.Close()
+	envelope, err := o.WriteBLOB(f)
+	if err != nil {
+		return false, trace.Wrap(err)
+	}
+	if envelope.SHA512 == hash {
+		o.WithField("blob", hash).Debug("Converted BLOB into chunks.")
+		return true, nil
+	}
+	// the data does not match the hash, leave the BLOB as is
+	// for the integrity check to discover
+	o.WithField("blob", hash).Warn("BLOB is corrupted, will not convert.")
+	return false, trace.Wrap(o.DeleteBLOB(envelope.SHA512))
+}
+
+func (o *objects) usage() (*Usage, error) {
+	var usage Usage
+	err := walkFiles(o.indexDir(), func(path string, fi os.FileInfo) error {
+		idx, err := o.readIndex(fi.Name())
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		usage.BLOBs++
+		usage.SizeBytes += idx.SizeBytes
+		return nil
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	err = walkFiles(o.chunksDir(), func(path string, fi os.FileInfo) error {
+		usage.Chunks++
+		usage.StoredBytes += fi.Size()
+		return nil
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &usage, nil
+}
+
+// addReference records the reference to the chunk from the BLOB
+func (o *objects) addReference(chunkHash, blobHash string) error {
+	return trace.Wrap(touch(o.refPath(chunkHash, blobHash)))
+}
+
+// release removes the references from the BLOB to the specified chunks
+// and the chunks left without references.
+// Must be called under the exclusive lock
+func (o *objects) release(blobHash string, chunks []chunk) (removed int, err error) {
+	for _, chunk := range chunks {
+		path := o.refPath(chunk.Hash, blobHash)
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return removed, trace.ConvertSystemError(err)
+		}
+		// the references directory can only be removed once empty
+		err := os.Remove(filepath.Dir(path))
+		if os.IsExist(err) {
+			// referenced by other BLOBs
+			continue
+		}
+		if err != nil && !os.IsNotExist(err) {
+			return removed, trace.ConvertSystemError(err)
+		}
+		err = os.Remove(o.chunkPath(chunk.Hash))
+		if err != nil && !os.IsNotExist(err) {
+			return removed, trace.ConvertSystemError(err)
+		}
+		if err == nil {
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+// references returns the set of chunks referenced by the stored BLOBs.
+// The references left by the BLOBs that have no index, e.g. because
+// the write has been interrupted, are removed unless dryRun is set
+func (o *objects) references(ctx context.Context, dryRun bool) (map[string]bool, error) {
+	refs := make(map[string]bool)
+	err := walkFiles(o.refsDir(), func(path string, fi os.FileInfo) error {
+		if err := ctx.Err(); err != nil {
+			return trace.Wrap(err)
+		}
+		chunkHash := filepath.Base(filepath.Dir(path))
+		_, err := os.Stat(o.indexPath(fi.Name()))
+		if err == nil {
+			refs[chunkHash] = true
+			return nil
+		}
+		if !os.IsNotExist(err) {
+			return trace.ConvertSystemError(err)
+		}
+		if dryRun {
+			return nil
+		}
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return trace.ConvertSystemError(err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return refs, nil
+}
+
+// initReferences records the chunk references of the BLOBs written
+// before the storage has started to keep them
+func (o *objects) initReferences() error {
+	if _, err := os.Stat(o.refsDir()); err == nil {
+		return nil
+	}
+	unlock, err := o.lock(true)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	defer unlock()
+	if _, err := os.Stat(o.refsDir()); err == nil {
+		return nil
+	}
+	// the references are recorded in a temporary directory
+	// so the storage never has a partial set of references
+	dir, err := ioutil.TempDir(o.tempDir(), "refs")
+	if err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	defer os.RemoveAll(dir)
+	var count int
+	err = walkFiles(o.indexDir(), func(path string, fi os.FileInfo) error {
+		idx, err := o.readIndex(fi.Name())
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		for _, chunk := range idx.Chunks {
+			path := filepath.Join(dir, chunk.Hash[0:3], chunk.Hash, fi.Name())
+			if err := touch(path); err != nil {
+				return trace.Wrap(err)
+			}
+		}
+		count++
+		return nil
+	})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if err := os.Rename(dir, o.refsDir()); err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	if count != 0 {
+		o.Infof("Recorded chunk references of %v BLOBs.", count)
+	}
+	return nil
+}
+
+func (o *objects) verifyChunk(chunk chunk) (ok bool, err error) {
+	f, err := os.Open(o.chunkPath(chunk.Hash))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, trace.ConvertSystemError(err)
+	}
+	defer f.Close()
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, f); err != nil {
+		return false, trace.ConvertSystemError(err)
+	}
+	return fmt.Sprintf("%x", hasher.Sum(nil)) == chunk.Hash, nil
+}
+
+func (o *objects) writeChunk(hash string, data []byte) error {
+	path := o.chunkPath(hash)
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	}
+	return trace.Wrap(o.writeFile(path, data))
+}
+
+func (o *objects) writeIndex(hash string, idx index) error {
+	data, err := json.Marshal(idx)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return trace.Wrap(o.writeFile(o.indexPath(hash), data))
+}
+
+func (o *objects) readIndex(hash string) (*index, error) {
+	data, err := ioutil.ReadFile(o.indexPath(hash))
+	if err != nil {
+		return nil, trace.ConvertSystemError(err)
+	}
+	var idx index
+	if err := json.Unmarshal(data, &idx); err != nil {
+		return nil, trace.Wrap(err, "invalid index of BLOB %v", hash)
+	}
+	return &idx, nil
+}
+
+// writeFile writes the file via a temporary file
+// so that the file is either complete or missing
+func (o *objects) writeFile(path string, data []byte) error {
+	f, err := ioutil.TempFile(o.tempDir(), "chunk")
+	if err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	_, err = f.Write(data)
+	if errClose := f.Close(); err == nil {
+		err = errClose
+	}
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(path), defaults.SharedDirMask)
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return trace.ConvertSystemError(err)
+	}
+	return nil
+}
+
+// lock acquires the storage lock shared between processes.
+// Chunks are written under the shared lock and removed under the exclusive one
+func (o *objects) lock(exclusive bool) (unlock func(), err error) {
+	f, err := os.OpenFile(filepath.Join(o.Path, lockFile), os.O_CREATE|os.O_RDWR, defaults.SharedReadMask)
+	if err != nil {
+		return nil, trace.ConvertSystemError(err)
+	}
+	if exclusive {
+		err = teleutils.FSWriteLock(f)
+	} else {
+		err = teleutils.FSReadLock(f)
+	}
+	if err != nil {
+		f.Close()
+		return nil, trace.Wrap(err)
+	}
+	return func() {
+		if err := teleutils.FSUnlock(f); err != nil {
+			o.WithError(err).Warn("Failed to release lock.")
+		}
+		f.Close()
+	}, nil
+}
+
+func (o *objects) chunksDir() string {
+	return filepath.Join(o.Path, chunksDir)
+}
+
+func (o *objects) indexDir() string {
+	return filepath.Join(o.Path, indexDir)
+}
+
+func (o *objects) refsDir() string {
+	return filepath.Join(o.Path, refsDir)
+}
+
+func (o *objects) tempDir() string {
+	return filepath.Join(o.Path, "tmp")
+}
+
+// quarantineDir keeps the chunks that failed the integrity check
+func (o *objects) quarantineDir() string {
+	return filepath.Join(o.Path, "quarantine")
+}
+
+// chunkPath returns the path to the chunk file. The chunks
+// are spread over 4096 directories by the hash prefix
+func (o *objects) chunkPath(hash string) string {
+	return filepath.Join(o.chunksDir(), hash[0:3], hash)
+}
+
+func (o *objects) indexPath(hash string) string {
+	return filepath.Join(o.indexDir(), hash[0:3], hash)
+}
+
+// chunkRefsDir returns the directory with the references to the chunk
+func (o *objects) chunkRefsDir(hash string) string {
+	return filepath.Join(o.refsDir(), hash[0:3], hash)
+}
+
+// refPath returns the path to the reference to the chunk from the BLOB
+func (o *objects) refPath(chunkHash, blobHash string) string {
+	return filepath.Join(o.chunkRefsDir(chunkHash), blobHash)
+}
+
+// index lists the chunks of a BLOB
+type index struct {
+	// SizeBytes is the BLOB size
+	SizeBytes int64 `json:"size_bytes"`
+	// Chunks lists the chunks of the BLOB in order
+	Chunks []chunk `json:"chunks"`
+}
+
+type chunk struct {
+	// Hash is the SHA256 hash of the chunk
+	Hash string `json:"hash"`
+	// SizeBytes is the chunk size
+	SizeBytes int64 `json:"size_bytes"`
+}
+
+// reader reads the BLOB from its chunks
+type reader struct {
+	o *objects
+	// unlock releases the shared lock held by the reader
+	unlock func()
+	chunks []chunk
+	// offsets lists the offsets of the chunks in the BLOB
+	offsets []int64
+	size    int64
+	offset  int64
+
+	// mu guards the open chunk for concurrent ReadAt calls
+	mu      sync.Mutex
+	file    *os.File
+	current int
+}
+
+func newReader(o *objects, idx index, unlock func()) *reader {
+	offsets := make([]int64, len(idx.Chunks))
+	var offset int64
+	for i, chunk := range idx.Chunks {
+		offsets[i] = offset
+		offset += chunk.SizeBytes
+	}
+	return &reader{
+		o:       o,
+		unlock:  unlock,
+		chunks:  idx.Chunks,
+		offsets: offsets,
+		size:    idx.SizeBytes,
+		current: -1,
+	}
+}
+
+// Read reads the BLOB data at the current offset
+func (r *reader) Read(p []byte) (int, error) {
+	n, err := r.readChunkAt(p, r.offset)
+	r.offset += int64(n)
+	return n, err
+}
+
+// ReadAt reads the BLOB data at the specified offset
+func (r *reader) ReadAt(p []byte, offset int64) (int, error) {
+	var read int
+	for read < len(p) {
+		n, err := r.readChunkAt(p[read:], offset+int64(read))
+		read += n
+		if err != nil {
+			return read, err
+		}
+	}
+	return read, nil
+}
+
+// Seek sets the offset for the next Read
+func (r *reader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += r.offset
+	case io.SeekEnd:
+		offset += r.size
+	default:
+		return 0, trace.BadParameter("invalid whence %v", whence)
+	}
+	if offset < 0 {
+		return 0, trace.BadParameter("negative offset %v", offset)
+	}
+	r.offset = offset
+	return offset, nil
+}
+
+// Close closes the reader and releases the lock
+func (r *reader) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.unlock != nil {
+		defer r.unlock()
+		r.unlock = nil
+	}
+	if r.file == nil {
+		return nil
+	}
+	err := r.file.Close()
+	r.file = nil
+	return trace.ConvertSystemError(err)
+}
+
+// readChunkAt reads the data at the specified offset
+// from the chunk the offset falls into
+func (r *reader) readChunkAt(p []byte, offset int64) (int, error) {
+	if offset >= r.size {
+		return 0, io.EOF
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+	i := sort.Search(len(r.offsets), func(i int) bool { return r.offsets[i] > offset }) - 1
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if i != r.current {
+		if r.file != nil {
+			r.file.Close()
+			r.file = nil
+		}
+		f, err := os.Open(r.o.chunkPath(r.chunks[i].Hash))
+		if err != nil {
+			return 0, trace.ConvertSystemError(err)
+		}
+		r.file, r.current = f, i
+	}
+	chunkOffset := offset - r.offsets[i]
+	if remaining := r.chunks[i].SizeBytes - chunkOffset; int64(len(p)) > remaining {
+		p = p[:remaining]
+	}
+	n, err := r.file.ReadAt(p, chunkOffset)
+	if err == io.EOF && n < len(p) {
+		return n, trace.BadParameter("chunk %v is truncated", r.chunks[i].Hash)
+	}
+	if err != nil && err != io.EOF {
+		return n, trace.ConvertSystemError(err)
+	}
+	return n, nil
+}
+
+// touch creates an empty file at the specified path
+func touch(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), defaults.SharedDirMask); err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY, defaults.SharedReadMask)
+	if err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	return trace.ConvertSystemError(f.Close())
+}
+
+// walkFiles invokes fn for each file in the directory tree
+func walkFiles(dir string, fn func(path string, fi os.FileInfo) error) error {
+	err := filepath.Walk(dir, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return trace.ConvertSystemError(err)
+		}
+		if fi.IsDir() {
+			return nil
+		}
+		return fn(path, fi)
+	})
+	return trace.Wrap(err)
+}
+
+const (
+	// chunksDir is the directory with chunks
+	chunksDir = "chunks"
+	// indexDir is the directory with the indexes of BLOBs
+	indexDir = "index"
+	// refsDir is the directory with the references to chunks
+	refsDir = "refs"
+	// lockFile is the file locked by the processes using the storage
+	lockFile = "chunks.lock"
+)
@@ -0,0 +1,271 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package chunked
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gravitational/gravity/lib/blob"
+	"github.com/gravitational/gravity/lib/blob/fs"
+	"github.com/gravitational/gravity/lib/blob/suite"
+
+	"github.com/gravitational/trace"
+	. "gopkg.in/check.v1"
+)
+
+func TestChunked(t *testing.T) { TestingT(t) }
+
+type ChunkedSuite struct {
+	suite   suite.BLOBSuite
+	dir     string
+	objects blob.Objects
+}
+
+var _ = Suite(&ChunkedSuite{})
+
+func (s *ChunkedSuite) SetUpTest(c *C) {
+	s.dir = c.MkDir()
+	var err error
+	s.objects, err = New(Config{Path: s.dir})
+	c.Assert(err, IsNil)
+	s.suite.Objects = s.objects
+}
+
+func (s *ChunkedSuite) TestBLOB(c *C) {
+	s.suite.BLOB(c)
+}
+
+func (s *ChunkedSuite) TestBLOBSeek(c *C) {
+	s.suite.BLOBSeek(c)
+}
+
+func (s *ChunkedSuite) TestBLOBWriteTwice(c *C) {
+	s.suite.BLOBWriteTwice(c)
+}
+
+func (s *ChunkedSuite) TestBLOBList(c *C) {
+	s.suite.BLOBList(c)
+}
+
+func (s *ChunkedSuite) TestDeduplicatesChunks(c *C) {
+	base := randomData(1, 2*1024*1024)
+	update := append(append([]byte{}, base[:1024*1024]...), "update"...)
+	update = append(update, base[1024*1024:]...)
+
+	baseEnvelope := s.writeBLOB(c, base)
+	updateEnvelope := s.writeBLOB(c, update)
+	s.assertBLOB(c, baseEnvelope.SHA512, base)
+	s.assertBLOB(c, updateEnvelope.SHA512, update)
+
+	usage, err := GetUsage(Config{Path: s.dir})
+	c.Assert(err, IsNil)
+	c.Assert(usage.BLOBs, Equals, 2)
+	c.Assert(usage.SizeBytes, Equals, int64(len(base)+len(update)))
+	// only the chunks around the change are stored twice
+	c.Assert(usage.StoredBytes < int64(len(base))*3/2, Equals, true,
+		Commentf("stored %v bytes", usage.StoredBytes))
+
+	// the shared chunks are kept while referenced
+	c.Assert(s.objects.DeleteBLOB(baseEnvelope.SHA512), IsNil)
+	s.assertBLOB(c, updateEnvelope.SHA512, update)
+	_, err = s.objects.OpenBLOB(baseEnvelope.SHA512)
+	c.Assert(trace.IsNotFound(err), Equals, true, Commentf("%v", err))
+
+	c.Assert(s.objects.DeleteBLOB(updateEnvelope.SHA512), IsNil)
+	usage, err = GetUsage(Config{Path: s.dir})
+	c.Assert(err, IsNil)
+	c.Assert(*usage, DeepEquals, Usage{})
+}
+
+func (s *ChunkedSuite) TestReadAt(c *C) {
+	data := randomData(1, 1024*1024)
+	envelope := s.writeBLOB(c, data)
+	f, err := s.objects.OpenBLOB(envelope.SHA512)
+	c.Assert(err, IsNil)
+	defer f.Close()
+	readerAt, ok := f.(io.ReaderAt)
+	c.Assert(ok, Equals, true)
+	for _, offset := range []int64{0, 100, 500 * 1024, int64(len(data)) - 10} {
+		buf := make([]byte, 300*1024)
+		n, err := readerAt.ReadAt(buf, offset)
+		end := offset + int64(len(buf))
+		if end > int64(len(data)) {
+			end = int64(len(data))
+			c.Assert(err, Equals, io.EOF)
+		} else {
+			c.Assert(err, IsNil)
+		}
+		c.Assert(bytes.Equal(buf[:n], data[offset:end]), Equals, true)
+	}
+}
+
+func (s *ChunkedSuite) TestPrunesUnreferencedChunks(c *C) {
+	data := randomData(1, 512*1024)
+	envelope := s.writeBLOB(c, data)
+	// simulate an interrupted write
+	objects := s.objects.(*objects)
+	c.Assert(objects.writeChunk("abcdef", []byte("orphaned chunk")), IsNil)
+	c.Assert(objects.addReference("abcdef", "fedcba"), IsNil)
+
+	pruner := s.objects.(blob.Pruner)
+	result, err := pruner.PruneBLOBs(context.TODO(), true)
+	c.Assert(err, IsNil)
+	c.Assert(*result, DeepEquals, blob.PruneResult{Removed: 1, ReclaimedBytes: int64(len("orphaned chunk"))})
+	_, err = os.Stat(objects.chunkPath("abcdef"))
+	c.Assert(err, IsNil)
+
+	result, err = pruner.PruneBLOBs(context.TODO(), false)
+	c.Assert(err, IsNil)
+	c.Assert(result.Removed, Equals, 1)
+	_, err = os.Stat(objects.chunkPath("abcdef"))
+	c.Assert(os.IsNotExist(err), Equals, true)
+	s.assertBLOB(c, envelope.SHA512, data)
+}
+
+func (s *ChunkedSuite) TestReaderKeepsChunks(c *C) {
+	data := randomData(1, 512*1024)
+	envelope := s.writeBLOB(c, data)
+	f, err := s.objects.OpenBLOB(envelope.SHA512)
+	c.Assert(err, IsNil)
+
+	deleted := make(chan error, 1)
+	go func() {
+		deleted <- s.objects.DeleteBLOB(envelope.SHA512)
+	}()
+	select {
+	case err := <-deleted:
+		c.Fatalf("BLOB deleted while being read: %v.", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+	out, err := ioutil.ReadAll(f)
+	c.Assert(err, IsNil)
+	c.Assert(bytes.Equal(out, data), Equals, true)
+	c.Assert(f.Close(), IsNil)
+
+	select {
+	case err := <-deleted:
+		c.Assert(err, IsNil)
+	case <-time.After(5 * time.Second):
+		c.Fatal("timeout waiting for BLOB to be deleted")
+	}
+	usage, err := GetUsage(Config{Path: s.dir})
+	c.Assert(err, IsNil)
+	c.Assert(*usage, DeepEquals, Usage{})
+}
+
+func (s *ChunkedSuite) TestRecordsReferencesOfExistingBLOBs(c *C) {
+	base := randomData(1, 2*1024*1024)
+	update := append(append([]byte{}, base[:1024*1024]...), "update"...)
+	update = append(update, base[1024*1024:]...)
+	baseEnvelope := s.writeBLOB(c, base)
+	updateEnvelope := s.writeBLOB(c, update)
+
+	// the storage written before the references have been introduced
+	c.Assert(os.RemoveAll(filepath.Join(s.dir, refsDir)), IsNil)
+	objects, err := New(Config{Path: s.dir})
+	c.Assert(err, IsNil)
+	s.objects = objects
+
+	c.Assert(s.objects.DeleteBLOB(baseEnvelope.SHA512), IsNil)
+	s.assertBLOB(c, updateEnvelope.SHA512, update)
+	c.Assert(s.objects.DeleteBLOB(updateEnvelope.SHA512), IsNil)
+	usage, err := GetUsage(Config{Path: s.dir})
+	c.Assert(err, IsNil)
+	c.Assert(*usage, DeepEquals, Usage{})
+}
+
+func (s *ChunkedSuite) TestConvertsBLOBs(c *C) {
+	dir := c.MkDir()
+	legacy, err := fs.New(dir)
+	c.Assert(err, IsNil)
+	data := randomData(1, 512*1024)
+	envelope, err := legacy.WriteBLOB(bytes.NewReader(data))
+	c.Assert(err, IsNil)
+	c.Assert(IsChunked(dir), Equals, false)
+
+	// the BLOBs stored as files are served by the chunked storage
+	objects, err := Open(dir)
+	c.Assert(err, IsNil)
+	c.Assert(objects, FitsTypeOf, legacy)
+	objects, err = New(Config{Path: dir})
+	c.Assert(err, IsNil)
+	s.objects = objects
+	s.assertBLOB(c, envelope.SHA512, data)
+
+	result, err := Convert(context.TODO(), Config{Path: dir})
+	c.Assert(err, IsNil)
+	c.Assert(result.Converted, Equals, 1)
+	c.Assert(result.Usage.SizeBytes, Equals, int64(len(data)))
+	c.Assert(IsChunked(dir), Equals, true)
+	hashes, err := legacy.GetBLOBs()
+	c.Assert(err, IsNil)
+	c.Assert(hashes, HasLen, 0)
+	s.assertBLOB(c, envelope.SHA512, data)
+	hashes, err = objects.GetBLOBs()
+	c.Assert(err, IsNil)
+	c.Assert(hashes, DeepEquals, []string{envelope.SHA512})
+}
+
+func (s *ChunkedSuite) TestQuarantinesCorruptedChunks(c *C) {
+	data := randomData(1, 512*1024)
+	envelope := s.writeBLOB(c, data)
+	objects := s.objects.(*objects)
+	idx, err := objects.readIndex(envelope.SHA512)
+	c.Assert(err, IsNil)
+	corrupted := objects.chunkPath(idx.Chunks[0].Hash)
+	c.Assert(ioutil.WriteFile(corrupted, []byte("corrupted"), 0644), IsNil)
+
+	c.Assert(objects.QuarantineBLOB(envelope.SHA512), IsNil)
+	_, err = os.Stat(filepath.Join(objects.quarantineDir(), idx.Chunks[0].Hash))
+	c.Assert(err, IsNil)
+	_, err = objects.OpenBLOB(envelope.SHA512)
+	c.Assert(trace.IsNotFound(err), Equals, true, Commentf("%v", err))
+
+	// the BLOB can be written anew
+	s.writeBLOB(c, data)
+	s.assertBLOB(c, envelope.SHA512, data)
+}
+
+func (s *ChunkedSuite) writeBLOB(c *C, data []byte) *blob.Envelope {
+	envelope, err := s.objects.WriteBLOB(bytes.NewReader(data))
+	c.Assert(err, IsNil)
+	c.Assert(envelope.SizeBytes, Equals, int64(len(data)))
+	return envelope
+}
+
+func (s *ChunkedSuite) assertBLOB(c *C, hash string, expected []byte) {
+	f, err := s.objects.OpenBLOB(hash)
+	c.Assert(err, IsNil)
+	defer f.Close()
+	data, err := ioutil.ReadAll(f)
+	c.Assert(err, IsNil)
+	c.Assert(bytes.Equal(data, expected), Equals, true)
+}
+
+func randomData(seed int64, size int) []byte {
+	data := make([]byte, size)
+	rand.New(rand.NewSource(seed)).Read(data)
+	return data
+}
@@ -19,6 +19,7 @@ package cluster
 import (
 	"io"
 	"sort"
+	"sync"
 	"time"
 
 	"github.com/gravitational/gravity/lib/blob"
@@ -63,6 +64,9 @@ type Config struct {
 	// GracePeriod is a period for GC not to delete undetected files
 	// to prevent accidental deletion. Defaults to 1 hour
 	GracePeriod time.Duration
+	// ScrubPeriod is the period between integrity checks of the objects
+	// stored on this peer. Defaults to defaults.BLOBScrubPeriod
+	ScrubPeriod time.Duration
 }
 
 // New returns cluster BLOB storage that takes care of replication
@@ -99,6 +103,9 @@ func New(config Config) (blob.Objects, error) {
 	if config.GracePeriod == 0 {
 		config.GracePeriod = defaults.GracePeriod
 	}
+	if config.ScrubPeriod == 0 {
+		config.ScrubPeriod = defaults.BLOBScrubPeriod
+	}
 
 	close, cancelFn := context.WithCancel(context.TODO())
 
@@ -108,11 +115,18 @@ func New(config Config) (blob.Objects, error) {
 		"addr":          config.AdvertiseAddr,
 	})
 
-	c := &cluster{Config: config, close: close, cancelFn: cancelFn, Entry: entry}
+	c := &cluster{
+		Config:         config,
+		close:          close,
+		cancelFn:       cancelFn,
+		Entry:          entry,
+		missingObjects: make(map[string]time.Time),
+	}
 	if !c.TestMode {
 		go c.periodically("heartbeat", c.heartbeat)
 		go c.periodically("purgeDeleted", c.purgeDeletedObjects)
 		go c.periodically("fetchNew", c.fetchNewObjects)
+		go c.scrub()
 	}
 
 	return c, nil
@@ -123,6 +137,15 @@ type cluster struct {
 	Config
 	close    context.Context
 	cancelFn context.CancelFunc
+	// missingObjects maps objects that have not been replicated
+	// to this peer yet to the time they have been discovered
+	missingObjects map[string]time.Time
+	// checkMutex serializes integrity checks
+	checkMutex sync.Mutex
+	// mu guards integrity
+	mu sync.Mutex
+	// integrity is the outcome of the last integrity check
+	integrity *storage.BLOBIntegrity
 }
 
 func (c *cluster) Close() error {
@@ -169,6 +192,7 @@ func (c *cluster) localPeer() storage.Peer {
 		ID:            c.ID,
 		AdvertiseAddr: c.AdvertiseAddr,
 		LastHeartbeat: c.Clock.Now().UTC(),
+		Integrity:     c.getIntegrity(),
 	}
 }
 
@@ -228,17 +252,51 @@ func (c *cluster) fetchNewObjects() error {
 			missingObjects = append(missingObjects, hash)
 		}
 	}
+	c.trackMissingObjects(missingObjects)
 	for _, hash := range missingObjects {
 		c.Infof("Found missing object %v.", hash)
 		err = c.fetchObject(hash)
 		if err != nil {
+			fetchRequests.WithLabelValues("failure").Inc()
 			c.Warningf("Failed to fetch object(%v) %v.", hash, trace.DebugReport(err))
 			return trace.Wrap(err)
 		}
+		fetchRequests.WithLabelValues("success").Inc()
+		delete(c.missingObjects, hash)
+		c.reportReplicationLag()
 	}
 	return nil
 }
 
+// trackMissingObjects records the objects that are missing on this peer.
+// Only invoked from the replication loop
+func (c *cluster) trackMissingObjects(missing []string) {
+	now := c.Clock.Now()
+	discovered := make(map[string]time.Time, len(missing))
+	for _, hash := range missing {
+		if t, ok := c.missingObjects[hash]; ok {
+			discovered[hash] = t
+		} else {
+			discovered[hash] = now
+		}
+	}
+	c.missingObjects = discovered
+	c.reportReplicationLag()
+}
+
+// reportReplicationLag updates the replication metrics
+func (c *cluster) reportReplicationLag() {
+	now := c.Clock.Now()
+	var lag time.Duration
+	for _, t := range c.missingObjects {
+		if now.Sub(t) > lag {
+			lag = now.Sub(t)
+		}
+	}
+	missingObjects.Set(float64(len(c.missingObjects)))
+	replicationLag.Set(lag.Seconds())
+}
+
 func (c *cluster) fetchObject(hash string) error {
 	peerIDs, err := c.Backend.GetObjectPeers(hash)
 	if err != nil {
@@ -392,6 +450,16 @@ func (c *cluster) DeleteBLOB(hash string) error {
 	return trace.Wrap(c.Backend.DeleteObject(hash))
 }
 
+// PruneBLOBs removes the data no longer referenced by any BLOB
+// from the local storage if it shares data between BLOBs
+func (c *cluster) PruneBLOBs(ctx context.Context, dryRun bool) (*blob.PruneResult, error) {
+	pruner, ok := c.Local.(blob.Pruner)
+	if !ok {
+		return nil, trace.NotImplemented("local storage does not share data between BLOBs")
+	}
+	return pruner.PruneBLOBs(ctx, dryRun)
+}
+
 // matchPeer finds matching id in the filter
 // if the filter is empty, we consider all ids to match the filter
 func matchPeer(ids []string, id string) bool {
@@ -18,6 +18,7 @@ package cluster
 
 import (
 	"bytes"
+	"context"
 	"crypto/tls"
 	"fmt"
 	"io/ioutil"
@@ -151,8 +152,11 @@ func (s *ClusterMultiPeers) SetUpTest(c *C) {
 		return peers[id], nil
 	}
 
+	dirs := make([]string, peersCount)
+
 	for i := 0; i < peersCount; i++ {
-		local, err := fs.New(c.MkDir())
+		dirs[i] = c.MkDir()
+		local, err := fs.New(dirs[i])
 		c.Assert(err, IsNil)
 		peers[i] = local
 		obj, err := New(Config{
@@ -178,6 +182,7 @@ func (s *ClusterMultiPeers) SetUpTest(c *C) {
 	s.clusterSuite.objects = objects
 	s.clusterSuite.clients = clients
 	s.clusterSuite.clock = fakeClock
+	s.clusterSuite.dirs = dirs
 }
 
 func (s *ClusterMultiPeers) TearDownTest(c *C) {
@@ -210,6 +215,22 @@ func (s *ClusterMultiPeers) TestCleanup(c *C) {
 	s.clusterSuite.Cleanup(c)
 }
 
+func (s *ClusterMultiPeers) TestRepairsCorruptedObjects(c *C) {
+	s.clusterSuite.RepairsCorruptedObjects(c)
+}
+
+func (s *ClusterMultiPeers) TestReplicatesUnderReplicatedObjects(c *C) {
+	s.clusterSuite.ReplicatesUnderReplicatedObjects(c)
+}
+
+func (s *ClusterMultiPeers) TestQuarantinesObjectsWithoutReplicas(c *C) {
+	s.clusterSuite.QuarantinesObjectsWithoutReplicas(c)
+}
+
+func (s *ClusterMultiPeers) TestDoesNotReplicateCorruptedObjects(c *C) {
+	s.clusterSuite.DoesNotReplicateCorruptedObjects(c)
+}
+
 type RPCSuite struct {
 	suite        suite.BLOBSuite
 	clusterSuite clusterSuite
@@ -252,8 +273,11 @@ func (s *RPCSuite) SetUpTest(c *C) {
 
 	fakeClock := clockwork.NewFakeClockAt(time.Now().UTC())
 
+	dirs := make([]string, peersCount)
+
 	for i := 0; i < 3; i++ {
-		local, err := fs.New(c.MkDir())
+		dirs[i] = c.MkDir()
+		local, err := fs.New(dirs[i])
 		c.Assert(err, IsNil)
 		peers[i] = local
 
@@ -310,6 +334,7 @@ func (s *RPCSuite) SetUpTest(c *C) {
 	s.clusterSuite.objects = objects
 	s.clusterSuite.clients = clients
 	s.clusterSuite.clock = fakeClock
+	s.clusterSuite.dirs = dirs
 
 	c.Assert(err, IsNil)
 
@@ -339,10 +364,27 @@ func (s *RPCSuite) TestCleanup(c *C) {
 	s.clusterSuite.Cleanup(c)
 }
 
+func (s *RPCSuite) TestRepairsCorruptedObjects(c *C) {
+	s.clusterSuite.RepairsCorruptedObjects(c)
+}
+
+func (s *RPCSuite) TestReplicatesUnderReplicatedObjects(c *C) {
+	s.clusterSuite.ReplicatesUnderReplicatedObjects(c)
+}
+
+func (s *RPCSuite) TestQuarantinesObjectsWithoutReplicas(c *C) {
+	s.clusterSuite.QuarantinesObjectsWithoutReplicas(c)
+}
+
+func (s *RPCSuite) TestDoesNotReplicateCorruptedObjects(c *C) {
+	s.clusterSuite.DoesNotReplicateCorruptedObjects(c)
+}
+
 type clusterSuite struct {
 	objects []*cluster
 	clients []blob.Objects
 	clock   clockwork.FakeClock
+	dirs    []string
 }
 
 func (s *clusterSuite) Replication(c *C) {
@@ -402,3 +444,134 @@ func (s *clusterSuite) Cleanup(c *C) {
 		c.Assert(trace.IsNotFound(err), Equals, true, Commentf("%#v", err))
 	}
 }
+
+func (s *clusterSuite) RepairsCorruptedObjects(c *C) {
+	peer := s.objects[0]
+
+	data := []byte("hello, there, cluster!")
+	envelope, err := s.clients[0].WriteBLOB(bytes.NewBuffer(data))
+	c.Assert(err, IsNil)
+
+	path := filepath.Join(s.dirs[0], "blobs", envelope.SHA512[:3], envelope.SHA512)
+	c.Assert(ioutil.WriteFile(path, []byte("bit rot"), 0644), IsNil)
+
+	integrity, err := peer.Check(context.TODO(), false)
+	c.Assert(err, IsNil)
+	c.Assert(integrity.Corrupted, DeepEquals, []string{envelope.SHA512})
+	c.Assert(integrity.Repaired, IsNil)
+	c.Assert(integrity.IsHealthy(), Equals, false)
+
+	integrity, err = peer.Check(context.TODO(), true)
+	c.Assert(err, IsNil)
+	c.Assert(integrity.Corrupted, DeepEquals, []string{envelope.SHA512})
+	c.Assert(integrity.Repaired, DeepEquals, []string{envelope.SHA512})
+	c.Assert(integrity.IsHealthy(), Equals, true)
+
+	f, err := peer.Local.OpenBLOB(envelope.SHA512)
+	c.Assert(err, IsNil)
+	defer f.Close()
+	out, err := ioutil.ReadAll(f)
+	c.Assert(err, IsNil)
+	c.Assert(string(out), Equals, string(data))
+
+	quarantined, err := ioutil.ReadFile(filepath.Join(s.dirs[0], "quarantine", envelope.SHA512))
+	c.Assert(err, IsNil)
+	c.Assert(string(quarantined), Equals, "bit rot")
+
+	// the results are published with the peer heartbeat
+	peers, err := peer.Backend.GetPeers()
+	c.Assert(err, IsNil)
+	for _, p := range peers {
+		if p.ID == peer.ID {
+			c.Assert(p.Integrity, DeepEquals, integrity)
+		}
+	}
+}
+
+func (s *clusterSuite) ReplicatesUnderReplicatedObjects(c *C) {
+	peer := s.objects[0]
+
+	data := []byte("hello, there, cluster!")
+	envelope, err := s.clients[0].WriteBLOB(bytes.NewBuffer(data))
+	c.Assert(err, IsNil)
+
+	ids, err := peer.Backend.GetObjectPeers(envelope.SHA512)
+	c.Assert(err, IsNil)
+	c.Assert(ids, DeepEquals, []string{"0", "1"})
+
+	// lose the second replica
+	c.Assert(peer.Backend.DeletePeer("1"), IsNil)
+
+	integrity, err := peer.Check(context.TODO(), false)
+	c.Assert(err, IsNil)
+	c.Assert(integrity.UnderReplicated, DeepEquals, []string{envelope.SHA512})
+
+	integrity, err = peer.Check(context.TODO(), true)
+	c.Assert(err, IsNil)
+	c.Assert(integrity.Replicated, DeepEquals, []string{envelope.SHA512})
+	c.Assert(integrity.UnderReplicated, IsNil)
+
+	ids, err = peer.Backend.GetObjectPeers(envelope.SHA512)
+	c.Assert(err, IsNil)
+	c.Assert(ids, DeepEquals, []string{"0", "1", "2"})
+
+	f, err := s.objects[2].Local.OpenBLOB(envelope.SHA512)
+	c.Assert(err, IsNil)
+	defer f.Close()
+	out, err := ioutil.ReadAll(f)
+	c.Assert(err, IsNil)
+	c.Assert(string(out), Equals, string(data))
+}
+
+func (s *clusterSuite) QuarantinesObjectsWithoutReplicas(c *C) {
+	peer := s.objects[0]
+
+	data := []byte("hello, there, cluster!")
+	envelope, err := s.clients[0].WriteBLOB(bytes.NewBuffer(data))
+	c.Assert(err, IsNil)
+
+	// the only other replica is lost, so the quarantined copy cannot be fetched again
+	c.Assert(s.objects[1].Local.DeleteBLOB(envelope.SHA512), IsNil)
+	path := filepath.Join(s.dirs[0], "blobs", envelope.SHA512[:3], envelope.SHA512)
+	c.Assert(ioutil.WriteFile(path, []byte("bit rot"), 0644), IsNil)
+
+	integrity, err := peer.Check(context.TODO(), true)
+	c.Assert(err, IsNil)
+	c.Assert(integrity.Corrupted, DeepEquals, []string{envelope.SHA512})
+	c.Assert(integrity.Repaired, IsNil)
+	c.Assert(integrity.Replicated, IsNil)
+	c.Assert(integrity.IsHealthy(), Equals, false)
+
+	_, err = peer.Local.OpenBLOB(envelope.SHA512)
+	c.Assert(trace.IsNotFound(err), Equals, true, Commentf("%#v", err))
+
+	// the peer no longer advertises the quarantined copy
+	ids, err := peer.Backend.GetObjectPeers(envelope.SHA512)
+	c.Assert(err, IsNil)
+	c.Assert(ids, DeepEquals, []string{"1"})
+
+	// and the corrupted copy has not been replicated
+	_, err = s.objects[2].Local.OpenBLOB(envelope.SHA512)
+	c.Assert(trace.IsNotFound(err), Equals, true, Commentf("%#v", err))
+}
+
+func (s *clusterSuite) DoesNotReplicateCorruptedObjects(c *C) {
+	peer := s.objects[0]
+
+	data := []byte("hello, there, cluster!")
+	envelope, err := s.clients[0].WriteBLOB(bytes.NewBuffer(data))
+	c.Assert(err, IsNil)
+
+	// lose the second replica and corrupt the first one
+	c.Assert(peer.Backend.DeletePeer("1"), IsNil)
+	path := filepath.Join(s.dirs[0], "blobs", envelope.SHA512[:3], envelope.SHA512)
+	c.Assert(ioutil.WriteFile(path, []byte("bit rot"), 0644), IsNil)
+
+	var integrity storage.BLOBIntegrity
+	c.Assert(peer.checkReplication(context.TODO(), true, &integrity), IsNil)
+	c.Assert(integrity.Replicated, IsNil)
+	c.Assert(integrity.UnderReplicated, DeepEquals, []string{envelope.SHA512})
+
+	_, err = s.objects[2].Local.OpenBLOB(envelope.SHA512)
+	c.Assert(trace.IsNotFound(err), Equals, true, Commentf("%#v", err))
+}
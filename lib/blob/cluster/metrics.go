@@ -0,0 +1,70 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	missingObjects = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "gravity_blob_missing_objects",
+			Help: "Number of objects that have not been replicated to this peer yet",
+		},
+	)
+	replicationLag = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "gravity_blob_replication_lag_seconds",
+			Help: "Time since the oldest object that has not been replicated to this peer was discovered",
+		},
+	)
+	fetchRequests = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "gravity_blob_fetch_requests_total",
+			Help: "Number of attempts to replicate objects from other peers",
+		},
+		[]string{"result"},
+	)
+	corruptedObjects = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "gravity_blob_corrupted_objects_total",
+			Help: "Number of objects on this peer found not to match their hash",
+		},
+	)
+	underReplicatedObjects = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "gravity_blob_under_replicated_objects",
+			Help: "Number of objects stored on fewer active peers than the write factor",
+		},
+	)
+	lastCheck = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "gravity_blob_last_integrity_check_timestamp_seconds",
+			Help: "Time of the last integrity check of the objects on this peer",
+		},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(missingObjects)
+	prometheus.MustRegister(replicationLag)
+	prometheus.MustRegister(fetchRequests)
+	prometheus.MustRegister(corruptedObjects)
+	prometheus.MustRegister(underReplicatedObjects)
+	prometheus.MustRegister(lastCheck)
+}
@@ -0,0 +1,293 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"context"
+	"crypto/sha512"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/gravitational/gravity/lib/blob"
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/utils"
+
+	"github.com/gravitational/trace"
+)
+
+// Check verifies the objects stored on this peer against their hashes
+// and finds the objects stored on fewer active peers than the write factor.
+//
+// If repair is set, corrupted objects are quarantined and fetched again from
+// other peers, and under-replicated objects held by this peer are replicated
+// to the active peers that do not have them
+func (c *cluster) Check(ctx context.Context, repair bool) (*storage.BLOBIntegrity, error) {
+	c.checkMutex.Lock()
+	defer c.checkMutex.Unlock()
+	integrity := &storage.BLOBIntegrity{Checked: c.Clock.Now().UTC()}
+	err := c.verifyObjects(ctx, repair, integrity)
+	if err == nil {
+		err = c.checkReplication(ctx, repair, integrity)
+	}
+	if err != nil {
+		integrity.Error = err.Error()
+	}
+	c.setIntegrity(*integrity)
+	if err := c.heartbeat(); err != nil {
+		c.Warnf("Failed to publish integrity check results: %v.", err)
+	}
+	return integrity, trace.Wrap(err)
+}
+
+// scrub periodically checks and repairs the objects stored on this peer
+func (c *cluster) scrub() {
+	ticker := time.NewTicker(c.ScrubPeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.close.Done():
+			return
+		case <-ticker.C:
+			integrity, err := c.Check(c.close, true)
+			if err != nil {
+				c.Errorf("Periodic integrity check failed: %v.", trace.DebugReport(err))
+				continue
+			}
+			c.Infof("Checked %v objects: %v corrupted, %v repaired, %v replicated, %v under-replicated.",
+				integrity.Objects, len(integrity.Corrupted), len(integrity.Repaired),
+				len(integrity.Replicated), len(integrity.UnderReplicated))
+		}
+	}
+}
+
+// verifyObjects rehashes the objects stored on this peer
+func (c *cluster) verifyObjects(ctx context.Context, repair bool, integrity *storage.BLOBIntegrity) error {
+	hashes, err := c.Local.GetBLOBs()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	for _, hash := range hashes {
+		if err := ctx.Err(); err != nil {
+			return trace.Wrap(err)
+		}
+		valid, err := c.verifyObject(hash)
+		if err != nil {
+			if trace.IsNotFound(err) {
+				// deleted since listed
+				continue
+			}
+			return trace.Wrap(err)
+		}
+		integrity.Objects++
+		if valid {
+			continue
+		}
+		c.Warnf("Object %v does not match its hash.", hash)
+		corruptedObjects.Inc()
+		integrity.Corrupted = append(integrity.Corrupted, hash)
+		if !repair {
+			continue
+		}
+		if err := c.quarantine(hash); err != nil {
+			c.Errorf("Failed to quarantine object %v: %v.", hash, trace.DebugReport(err))
+			continue
+		}
+		if err := c.fetchObject(hash); err != nil {
+			// the object will be fetched by the replication loop
+			// once a healthy replica becomes available
+			c.Warnf("Failed to fetch object %v after quarantine: %v.", hash, err)
+			continue
+		}
+		integrity.Repaired = append(integrity.Repaired, hash)
+	}
+	return nil
+}
+
+// verifyObject returns true if the contents of the local object match its hash
+func (c *cluster) verifyObject(hash string) (bool, error) {
+	f, err := c.Local.OpenBLOB(hash)
+	if err != nil {
+		return false, trace.Wrap(err)
+	}
+	defer f.Close()
+	hasher := sha512.New()
+	if _, err := io.Copy(hasher, f); err != nil {
+		return false, trace.Wrap(err)
+	}
+	return fmt.Sprintf("%x", hasher.Sum(nil)[:sha512.Size/2]) == hash, nil
+}
+
+// quarantine removes the corrupted object from the local storage
+// and this peer from the object peers so other peers neither fetch
+// the object from this peer nor count it as a replica
+func (c *cluster) quarantine(hash string) error {
+	var err error
+	if quarantiner, ok := c.Local.(blob.Quarantiner); ok {
+		err = quarantiner.QuarantineBLOB(hash)
+	} else {
+		err = c.Local.DeleteBLOB(hash)
+	}
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	err = c.Backend.DeleteObjectPeers(hash, []string{c.ID})
+	if err != nil && !trace.IsNotFound(err) {
+		return trace.Wrap(err)
+	}
+	return nil
+}
+
+// checkReplication finds the objects stored on fewer active peers than
+// the write factor and replicates those held by this peer
+func (c *cluster) checkReplication(ctx context.Context, repair bool, integrity *storage.BLOBIntegrity) error {
+	hashes, err := c.Backend.GetObjects()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	peers, err := c.getPeers(nil)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	required := c.WriteFactor
+	if len(peers) < required {
+		required = len(peers)
+	}
+	for _, hash := range hashes {
+		if err := ctx.Err(); err != nil {
+			return trace.Wrap(err)
+		}
+		ids, err := c.Backend.GetObjectPeers(hash)
+		if err != nil {
+			if trace.IsNotFound(err) {
+				continue
+			}
+			return trace.Wrap(err)
+		}
+		holders, targets := splitPeers(peers, ids)
+		if len(holders) >= required {
+			continue
+		}
+		if repair && c.isReplicator(holders) {
+			replicated := c.replicateObject(hash, targets, required-len(holders))
+			if replicated != 0 {
+				integrity.Replicated = append(integrity.Replicated, hash)
+			}
+			if len(holders)+replicated >= required {
+				continue
+			}
+		}
+		integrity.UnderReplicated = append(integrity.UnderReplicated, hash)
+	}
+	underReplicatedObjects.Set(float64(len(integrity.UnderReplicated)))
+	return nil
+}
+
+// isReplicator returns true if this peer is responsible for replicating an
+// object stored on the specified peers. To avoid redundant copies, only
+// the holder with the lowest ID replicates the object
+func (c *cluster) isReplicator(holders []storage.Peer) bool {
+	if len(holders) == 0 {
+		return false
+	}
+	ids := make([]string, 0, len(holders))
+	for _, p := range holders {
+		ids = append(ids, p.ID)
+	}
+	sort.Strings(ids)
+	return ids[0] == c.ID
+}
+
+// replicateObject copies the local object to up to count target peers
+// and returns the number of successful copies.
+// The object is only replicated if the local copy matches its hash
+func (c *cluster) replicateObject(hash string, targets []storage.Peer, count int) (replicated int) {
+	valid, err := c.verifyObject(hash)
+	if err != nil {
+		c.Warnf("Failed to verify object %v before replication: %v.", hash, err)
+		return 0
+	}
+	if !valid {
+		// the object is quarantined by the next integrity check
+		c.Warnf("Object %v does not match its hash, not replicating.", hash)
+		return 0
+	}
+	f, err := c.Local.OpenBLOB(hash)
+	if err != nil {
+		c.Warnf("Failed to open object %v for replication: %v.", hash, err)
+		return 0
+	}
+	defer f.Close()
+	for _, p := range targets {
+		if replicated >= count {
+			break
+		}
+		if err := c.copyObject(f, p); err != nil {
+			c.Warnf("Failed to replicate object %v to %v: %v.", hash, p, err)
+			continue
+		}
+		if err := c.Backend.UpsertObjectPeers(hash, []string{p.ID}, 0); err != nil {
+			c.Warnf("Failed to update object %v peers: %v.", hash, err)
+			continue
+		}
+		c.Infof("Replicated object %v to %v.", hash, p)
+		replicated++
+	}
+	return replicated
+}
+
+func (c *cluster) copyObject(f blob.ReadSeekCloser, p storage.Peer) error {
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return trace.Wrap(err)
+	}
+	peer, err := c.GetPeer(p)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	_, err = peer.WriteBLOB(f)
+	return trace.Wrap(err)
+}
+
+// setIntegrity records the outcome of the integrity check
+// to be published with the heartbeats of this peer
+func (c *cluster) setIntegrity(integrity storage.BLOBIntegrity) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.integrity = &integrity
+	lastCheck.Set(float64(integrity.Checked.Unix()))
+}
+
+// getIntegrity returns the outcome of the last integrity check
+func (c *cluster) getIntegrity() *storage.BLOBIntegrity {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.integrity
+}
+
+// splitPeers splits the active peers into those that hold
+// the object per its metadata and the rest
+func splitPeers(peers []storage.Peer, ids []string) (holders, others []storage.Peer) {
+	for _, p := range peers {
+		if utils.StringInSlice(ids, p.ID) {
+			holders = append(holders, p)
+		} else {
+			others = append(others, p)
+		}
+	}
+	return holders, others
+}
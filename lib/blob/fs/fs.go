@@ -57,6 +57,11 @@ func (o *objects) blobDir() string {
 	return filepath.Join(o.dir, "blobs")
 }
 
+// quarantineDir keeps the BLOBs that failed the integrity check
+func (o *objects) quarantineDir() string {
+	return filepath.Join(o.dir, "quarantine")
+}
+
 // hashDir helps us to organize the blobs in the folder -
 // instead of putting all blobs in one folder, we
 // will put them in 4096 folders, groping by first 3 strings
@@ -173,3 +178,17 @@ func (o *objects) DeleteBLOB(hash string) error {
 	}
 	return nil
 }
+
+// QuarantineBLOB moves the BLOB to the quarantine directory
+// so that it is no longer served from the storage
+func (o *objects) QuarantineBLOB(hash string) error {
+	if err := os.MkdirAll(o.quarantineDir(), defaults.SharedDirMask); err != nil {
+		return trace.Wrap(err)
+	}
+	err := os.Rename(filepath.Join(o.hashDir(hash), hash),
+		filepath.Join(o.quarantineDir(), hash))
+	if err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package s3
+
+import (
+	"context"
+
+	"github.com/gravitational/gravity/lib/blob"
+
+	"github.com/gravitational/trace"
+)
+
+// MigrateResult describes the outcome of the migration
+type MigrateResult struct {
+	// Migrated is the number of BLOBs copied to the bucket
+	Migrated int
+	// SizeBytes is the total size of the copied BLOBs
+	SizeBytes int64
+}
+
+// Migrate copies the BLOBs of the local storage that are missing
+// from the bucket storage
+func Migrate(ctx context.Context, local, bucket blob.Objects) (*MigrateResult, error) {
+	missing, err := Missing(local, bucket)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var result MigrateResult
+	for _, hash := range missing {
+		if err := ctx.Err(); err != nil {
+			return nil, trace.Wrap(err)
+		}
+		envelope, err := copyBLOB(local, bucket, hash)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		result.Migrated++
+		result.SizeBytes += envelope.SizeBytes
+	}
+	return &result, nil
+}
+
+// Missing returns the BLOBs of the local storage that are missing
+// from the bucket storage
+func Missing(local, bucket blob.Objects) ([]string, error) {
+	localHashes, err := local.GetBLOBs()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	bucketHashes, err := bucket.GetBLOBs()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	stored := make(map[string]bool, len(bucketHashes))
+	for _, hash := range bucketHashes {
+		stored[hash] = true
+	}
+	var missing []string
+	for _, hash := range localHashes {
+		if !stored[hash] {
+			missing = append(missing, hash)
+		}
+	}
+	return missing, nil
+}
+
+func copyBLOB(from, to blob.Objects, hash string) (*blob.Envelope, error) {
+	r, err := from.OpenBLOB(hash)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	defer r.Close()
+	envelope, err := to.WriteBLOB(r)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if envelope.SHA512 != hash {
+		// the copy is kept under its actual hash and never referenced
+		return nil, trace.BadParameter("BLOB %v is corrupted: its contents hash to %v, "+
+			"repair it with 'gravity system blob fsck --repair'", hash, envelope.SHA512)
+	}
+	return envelope, nil
+}
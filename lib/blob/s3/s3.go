@@ -0,0 +1,324 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package s3
+
+import (
+	"crypto/sha512"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+
+	"github.com/gravitational/gravity/lib/blob"
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/utils"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/gravitational/trace"
+	"github.com/sirupsen/logrus"
+)
+
+// Config is the S3-backed BLOB storage configuration
+type Config struct {
+	// Bucket is the S3 bucket name
+	Bucket string
+	// Prefix is the optional key prefix for BLOBs in the bucket
+	Prefix string
+	// Region is the S3 region.
+	// Defaults to defaults.AWSRegion
+	Region string
+	// Endpoint is the optional endpoint of an S3-compatible store, such as MinIO.
+	// AWS S3 is used if not set
+	Endpoint string
+	// Credentials are the optional credentials to access the bucket with.
+	// The default AWS credentials chain is used if not set
+	Credentials *credentials.Credentials
+	// ServerSideEncryption is the optional server-side encryption
+	// algorithm for stored BLOBs: AES256 or aws:kms
+	ServerSideEncryption string
+	// KMSKeyID is the optional ID of the KMS key used with aws:kms encryption
+	KMSKeyID string
+	// TempDir is the directory for BLOBs being hashed before the upload.
+	// Defaults to the system temporary directory
+	TempDir string
+	// FieldLogger is used for logging
+	logrus.FieldLogger
+	// S3 is optional S3 API client
+	S3 s3iface.S3API
+}
+
+// CheckAndSetDefaults validates config and sets defaults
+func (c *Config) CheckAndSetDefaults() error {
+	if c.Bucket == "" {
+		return trace.BadParameter("missing Bucket parameter")
+	}
+	switch c.ServerSideEncryption {
+	case "", s3.ServerSideEncryptionAes256, s3.ServerSideEncryptionAwsKms:
+	default:
+		return trace.BadParameter("unsupported server-side encryption %q, supported are: %v",
+			c.ServerSideEncryption, []string{s3.ServerSideEncryptionAes256, s3.ServerSideEncryptionAwsKms})
+	}
+	if c.KMSKeyID != "" && c.ServerSideEncryption != s3.ServerSideEncryptionAwsKms {
+		return trace.BadParameter("KMS key requires %q server-side encryption",
+			s3.ServerSideEncryptionAwsKms)
+	}
+	if c.Region == "" {
+		c.Region = defaults.AWSRegion
+	}
+	if c.TempDir == "" {
+		c.TempDir = os.TempDir()
+	}
+	if c.FieldLogger == nil {
+		c.FieldLogger = logrus.WithField(trace.Component, "blob:s3")
+	}
+	if c.S3 == nil {
+		config := &aws.Config{
+			Region:      aws.String(c.Region),
+			Credentials: c.Credentials,
+		}
+		if c.Endpoint != "" {
+			// S3-compatible stores commonly do not support
+			// virtual-hosted style bucket addressing
+			config.Endpoint = aws.String(c.Endpoint)
+			config.S3ForcePathStyle = aws.Bool(true)
+		}
+		session, err := session.NewSession(config)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		c.S3 = s3.New(session)
+	}
+	return nil
+}
+
+// New returns a new BLOB storage that keeps objects in an S3 bucket
+func New(config Config) (blob.Objects, error) {
+	if err := config.CheckAndSetDefaults(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if err := os.MkdirAll(config.TempDir, defaults.SharedDirMask); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &objects{
+		Config:   config,
+		uploader: s3manager.NewUploaderWithClient(config.S3),
+	}, nil
+}
+
+type objects struct {
+	// Config is the storage configuration
+	Config
+	// uploader is the S3 upload manager
+	uploader *s3manager.Uploader
+}
+
+// Close is a no-op as the storage does not hold any resources
+func (o *objects) Close() error {
+	return nil
+}
+
+// GetBLOBs returns a list of BLOBs in the storage
+func (o *objects) GetBLOBs() ([]string, error) {
+	var out []string
+	err := o.S3.ListObjectsV2Pages(&s3.ListObjectsV2Input{
+		Bucket: aws.String(o.Bucket),
+		Prefix: aws.String(o.keyPrefix()),
+	}, func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+		for _, object := range page.Contents {
+			out = append(out, path.Base(aws.StringValue(object.Key)))
+		}
+		return true
+	})
+	if err != nil {
+		return nil, trace.Wrap(utils.ConvertS3Error(err))
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+// WriteBLOB writes object to the storage, returns object envelope
+func (o *objects) WriteBLOB(data io.Reader) (*blob.Envelope, error) {
+	// the key of the object is its hash so the data is spooled
+	// to a temporary file while the hash is being computed
+	f, err := ioutil.TempFile(o.TempDir, "blob")
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	defer func() {
+		f.Close()
+		os.Remove(f.Name())
+	}()
+
+	hasher := sha512.New()
+	if _, err := io.Copy(io.MultiWriter(f, hasher), data); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	hash := fmt.Sprintf("%x", hasher.Sum(nil)[:sha512.Size/2])
+
+	envelope, err := o.GetBLOBEnvelope(hash)
+	if err == nil {
+		o.Debugf("BLOB %v already exists.", hash)
+		return envelope, nil
+	}
+	if !trace.IsNotFound(err) {
+		return nil, trace.Wrap(err)
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	input := &s3manager.UploadInput{
+		Bucket: aws.String(o.Bucket),
+		Key:    aws.String(o.key(hash)),
+		Body:   f,
+	}
+	if o.ServerSideEncryption != "" {
+		input.ServerSideEncryption = aws.String(o.ServerSideEncryption)
+	}
+	if o.KMSKeyID != "" {
+		input.SSEKMSKeyId = aws.String(o.KMSKeyID)
+	}
+	if _, err := o.uploader.Upload(input); err != nil {
+		return nil, trace.Wrap(utils.ConvertS3Error(err))
+	}
+	return o.GetBLOBEnvelope(hash)
+}
+
+// GetBLOBEnvelope returns file information identified by hash
+func (o *objects) GetBLOBEnvelope(hash string) (*blob.Envelope, error) {
+	object, err := o.S3.HeadObject(&s3.HeadObjectInput{
+		Bucket: aws.String(o.Bucket),
+		Key:    aws.String(o.key(hash)),
+	})
+	if err != nil {
+		return nil, trace.Wrap(utils.ConvertS3Error(err))
+	}
+	return &blob.Envelope{
+		SizeBytes: aws.Int64Value(object.ContentLength),
+		SHA512:    hash,
+		Modified:  aws.TimeValue(object.LastModified).UTC(),
+	}, nil
+}
+
+// OpenBLOB opens file identified by hash and returns reader
+func (o *objects) OpenBLOB(hash string) (blob.ReadSeekCloser, error) {
+	envelope, err := o.GetBLOBEnvelope(hash)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &reader{
+		objects: o,
+		key:     o.key(hash),
+		size:    envelope.SizeBytes,
+	}, nil
+}
+
+// DeleteBLOB deletes BLOB from the storage
+func (o *objects) DeleteBLOB(hash string) error {
+	// S3 does not fail deletes of missing objects
+	if _, err := o.GetBLOBEnvelope(hash); err != nil {
+		return trace.Wrap(err)
+	}
+	_, err := o.S3.DeleteObject(&s3.DeleteObjectInput{
+		Bucket: aws.String(o.Bucket),
+		Key:    aws.String(o.key(hash)),
+	})
+	return trace.Wrap(utils.ConvertS3Error(err))
+}
+
+// key returns the S3 key of the BLOB with the specified hash
+func (o *objects) key(hash string) string {
+	return path.Join(o.Prefix, hash)
+}
+
+// keyPrefix returns the prefix for listing the BLOBs in the bucket
+func (o *objects) keyPrefix() string {
+	if o.Prefix == "" {
+		return ""
+	}
+	return path.Clean(o.Prefix) + "/"
+}
+
+// reader reads an S3 object with ranged GET requests
+// so the object can be seeked without downloading it
+type reader struct {
+	objects *objects
+	key     string
+	size    int64
+	offset  int64
+	body    io.ReadCloser
+}
+
+// Read reads the object data from the current offset
+func (r *reader) Read(p []byte) (int, error) {
+	if r.offset >= r.size {
+		return 0, io.EOF
+	}
+	if r.body == nil {
+		object, err := r.objects.S3.GetObject(&s3.GetObjectInput{
+			Bucket: aws.String(r.objects.Bucket),
+			Key:    aws.String(r.key),
+			Range:  aws.String(fmt.Sprintf("bytes=%v-", r.offset)),
+		})
+		if err != nil {
+			return 0, trace.Wrap(utils.ConvertS3Error(err))
+		}
+		r.body = object.Body
+	}
+	n, err := r.body.Read(p)
+	r.offset += int64(n)
+	return n, err
+}
+
+// Seek sets the offset for the next Read
+func (r *reader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += r.offset
+	case io.SeekEnd:
+		offset += r.size
+	default:
+		return 0, trace.BadParameter("invalid whence: %v", whence)
+	}
+	if offset < 0 {
+		return 0, trace.BadParameter("negative offset: %v", offset)
+	}
+	if offset != r.offset {
+		// the next Read will request the data from the new offset
+		r.Close()
+		r.offset = offset
+	}
+	return offset, nil
+}
+
+// Close closes the response body of the pending request
+func (r *reader) Close() error {
+	if r.body == nil {
+		return nil
+	}
+	err := r.body.Close()
+	r.body = nil
+	return trace.Wrap(err)
+}
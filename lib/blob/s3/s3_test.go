@@ -0,0 +1,353 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package s3
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gravitational/gravity/lib/blob/fs"
+	"github.com/gravitational/gravity/lib/blob/suite"
+	"github.com/gravitational/gravity/lib/defaults"
+
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/gravitational/trace"
+	. "gopkg.in/check.v1"
+)
+
+func TestS3(t *testing.T) { TestingT(t) }
+
+type S3Suite struct {
+	suite  suite.BLOBSuite
+	server *fakeS3
+	http   *httptest.Server
+}
+
+var _ = Suite(&S3Suite{})
+
+func (s *S3Suite) SetUpTest(c *C) {
+	s.server = newFakeS3("blobs")
+	s.http = httptest.NewServer(s.server)
+	obj, err := New(Config{
+		Bucket:               "blobs",
+		Prefix:               "packages",
+		Endpoint:             s.http.URL,
+		Credentials:          credentials.NewStaticCredentials("access", "secret", ""),
+		ServerSideEncryption: s3.ServerSideEncryptionAes256,
+		TempDir:              c.MkDir(),
+	})
+	c.Assert(err, IsNil)
+	s.suite.Objects = obj
+}
+
+func (s *S3Suite) TearDownTest(c *C) {
+	s.http.Close()
+}
+
+func (s *S3Suite) TestBLOB(c *C) {
+	s.suite.BLOB(c)
+}
+
+func (s *S3Suite) TestBLOBSeek(c *C) {
+	s.suite.BLOBSeek(c)
+}
+
+func (s *S3Suite) TestBLOBWriteTwice(c *C) {
+	s.suite.BLOBWriteTwice(c)
+}
+
+func (s *S3Suite) TestBLOBList(c *C) {
+	s.suite.BLOBList(c)
+}
+
+func (s *S3Suite) TestStoresObjectsUnderPrefixWithEncryption(c *C) {
+	e, err := s.suite.Objects.WriteBLOB(strings.NewReader("hello, blob"))
+	c.Assert(err, IsNil)
+
+	object, ok := s.server.get("packages/" + e.SHA512)
+	c.Assert(ok, Equals, true)
+	c.Assert(string(object.data), Equals, "hello, blob")
+	c.Assert(object.encryption, Equals, s3.ServerSideEncryptionAes256)
+}
+
+func (s *S3Suite) TestSeeksWithinObject(c *C) {
+	e, err := s.suite.Objects.WriteBLOB(strings.NewReader("0123456789"))
+	c.Assert(err, IsNil)
+
+	r, err := s.suite.Objects.OpenBLOB(e.SHA512)
+	c.Assert(err, IsNil)
+	defer r.Close()
+
+	offset, err := r.Seek(-4, 2)
+	c.Assert(err, IsNil)
+	c.Assert(offset, Equals, int64(6))
+	out, err := ioutil.ReadAll(r)
+	c.Assert(err, IsNil)
+	c.Assert(string(out), Equals, "6789")
+}
+
+func (s *S3Suite) TestMigratesLocalBLOBs(c *C) {
+	local, err := fs.New(c.MkDir())
+	c.Assert(err, IsNil)
+	first, err := local.WriteBLOB(strings.NewReader("first"))
+	c.Assert(err, IsNil)
+	second, err := local.WriteBLOB(strings.NewReader("second"))
+	c.Assert(err, IsNil)
+	_, err = s.suite.Objects.WriteBLOB(strings.NewReader("first"))
+	c.Assert(err, IsNil)
+
+	missing, err := Missing(local, s.suite.Objects)
+	c.Assert(err, IsNil)
+	c.Assert(missing, DeepEquals, []string{second.SHA512})
+
+	result, err := Migrate(context.TODO(), local, s.suite.Objects)
+	c.Assert(err, IsNil)
+	c.Assert(*result, DeepEquals, MigrateResult{Migrated: 1, SizeBytes: int64(len("second"))})
+
+	missing, err = Missing(local, s.suite.Objects)
+	c.Assert(err, IsNil)
+	c.Assert(missing, HasLen, 0)
+	for _, hash := range []string{first.SHA512, second.SHA512} {
+		_, ok := s.server.get("packages/" + hash)
+		c.Assert(ok, Equals, true)
+	}
+}
+
+func (s *S3Suite) TestMissingBLOB(c *C) {
+	_, err := s.suite.Objects.GetBLOBEnvelope("missing")
+	c.Assert(trace.IsNotFound(err), Equals, true, Commentf("%v", err))
+	err = s.suite.Objects.DeleteBLOB("missing")
+	c.Assert(trace.IsNotFound(err), Equals, true, Commentf("%v", err))
+}
+
+func (s *S3Suite) TestValidatesConfig(c *C) {
+	for _, config := range []Config{
+		{},
+		{Bucket: "blobs", ServerSideEncryption: "rot13"},
+		{Bucket: "blobs", KMSKeyID: "key"},
+	} {
+		c.Assert(config.CheckAndSetDefaults(), NotNil, Commentf("%#v", config))
+	}
+}
+
+// MinioSuite runs the BLOB storage tests against an external
+// S3-compatible store specified with TEST_S3_CONFIG, e.g.:
+//
+//	{"endpoint": "http://127.0.0.1:9000", "bucket": "test",
+//	 "access_key_id": "minio", "secret_access_key": "minio123"}
+type MinioSuite struct {
+	suite  suite.BLOBSuite
+	config *testConfig
+}
+
+var _ = Suite(&MinioSuite{})
+
+type testConfig struct {
+	Endpoint        string `json:"endpoint"`
+	Bucket          string `json:"bucket"`
+	AccessKeyID     string `json:"access_key_id"`
+	SecretAccessKey string `json:"secret_access_key"`
+}
+
+func (s *MinioSuite) SetUpSuite(c *C) {
+	data := os.Getenv(defaults.TestS3Config)
+	if data == "" {
+		c.Skip(fmt.Sprintf("set %v to run S3-compatible store tests", defaults.TestS3Config))
+		return
+	}
+	s.config = &testConfig{}
+	c.Assert(json.Unmarshal([]byte(data), s.config), IsNil)
+}
+
+func (s *MinioSuite) SetUpTest(c *C) {
+	obj, err := New(Config{
+		Bucket:   s.config.Bucket,
+		Prefix:   fmt.Sprintf("test-%v", time.Now().UnixNano()),
+		Endpoint: s.config.Endpoint,
+		Credentials: credentials.NewStaticCredentials(
+			s.config.AccessKeyID, s.config.SecretAccessKey, ""),
+		TempDir: c.MkDir(),
+	})
+	c.Assert(err, IsNil)
+	s.suite.Objects = obj
+}
+
+func (s *MinioSuite) TearDownTest(c *C) {
+	hashes, err := s.suite.Objects.GetBLOBs()
+	c.Assert(err, IsNil)
+	for _, hash := range hashes {
+		c.Assert(s.suite.Objects.DeleteBLOB(hash), IsNil)
+	}
+}
+
+func (s *MinioSuite) TestBLOB(c *C) {
+	s.suite.BLOB(c)
+}
+
+func (s *MinioSuite) TestBLOBSeek(c *C) {
+	s.suite.BLOBSeek(c)
+}
+
+func (s *MinioSuite) TestBLOBWriteTwice(c *C) {
+	s.suite.BLOBWriteTwice(c)
+}
+
+func (s *MinioSuite) TestBLOBList(c *C) {
+	s.suite.BLOBList(c)
+}
+
+// fakeS3 is an in-memory stand-in for an S3-compatible store
+// that serves a single bucket with path-style addressing
+type fakeS3 struct {
+	sync.Mutex
+	bucket  string
+	objects map[string]fakeObject
+}
+
+type fakeObject struct {
+	data       []byte
+	modified   time.Time
+	encryption string
+}
+
+func newFakeS3(bucket string) *fakeS3 {
+	return &fakeS3{
+		bucket:  bucket,
+		objects: make(map[string]fakeObject),
+	}
+}
+
+func (f *fakeS3) get(key string) (fakeObject, bool) {
+	f.Lock()
+	defer f.Unlock()
+	object, ok := f.objects[key]
+	return object, ok
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.Lock()
+	defer f.Unlock()
+	parts := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/"), "/", 2)
+	if parts[0] != f.bucket {
+		writeError(w, http.StatusNotFound, s3.ErrCodeNoSuchBucket)
+		return
+	}
+	var key string
+	if len(parts) == 2 {
+		key = parts[1]
+	}
+	switch {
+	case r.Method == http.MethodGet && key == "":
+		f.list(w, r.URL.Query().Get("prefix"))
+	case r.Method == http.MethodPut:
+		data, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "InternalError")
+			return
+		}
+		f.objects[key] = fakeObject{
+			data:       data,
+			modified:   time.Now().UTC().Truncate(time.Second),
+			encryption: r.Header.Get("X-Amz-Server-Side-Encryption"),
+		}
+		w.Header().Set("ETag", `"etag"`)
+	case r.Method == http.MethodHead:
+		object, ok := f.objects[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(object.data)))
+		w.Header().Set("Last-Modified", object.modified.Format(http.TimeFormat))
+	case r.Method == http.MethodGet:
+		object, ok := f.objects[key]
+		if !ok {
+			writeError(w, http.StatusNotFound, s3.ErrCodeNoSuchKey)
+			return
+		}
+		var offset int
+		if rng := r.Header.Get("Range"); rng != "" {
+			offset, _ = strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(rng, "bytes="), "-"))
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes %v-%v/%v",
+				offset, len(object.data)-1, len(object.data)))
+			w.WriteHeader(http.StatusPartialContent)
+		}
+		w.Write(object.data[offset:])
+	case r.Method == http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "MethodNotAllowed")
+	}
+}
+
+func (f *fakeS3) list(w http.ResponseWriter, prefix string) {
+	result := listBucketResult{Name: f.bucket, Prefix: prefix}
+	for key, object := range f.objects {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		result.Contents = append(result.Contents, listObject{
+			Key:          key,
+			Size:         len(object.data),
+			LastModified: object.modified.Format(time.RFC3339),
+		})
+	}
+	sort.Slice(result.Contents, func(i, j int) bool {
+		return result.Contents[i].Key < result.Contents[j].Key
+	})
+	result.KeyCount = len(result.Contents)
+	var buf bytes.Buffer
+	xml.NewEncoder(&buf).Encode(result)
+	w.Header().Set("Content-Type", "application/xml")
+	w.Write(buf.Bytes())
+}
+
+type listBucketResult struct {
+	XMLName     xml.Name     `xml:"http://s3.amazonaws.com/doc/2006-03-01/ ListBucketResult"`
+	Name        string       `xml:"Name"`
+	Prefix      string       `xml:"Prefix"`
+	KeyCount    int          `xml:"KeyCount"`
+	IsTruncated bool         `xml:"IsTruncated"`
+	Contents    []listObject `xml:"Contents"`
+}
+
+type listObject struct {
+	Key          string `xml:"Key"`
+	Size         int    `xml:"Size"`
+	LastModified string `xml:"LastModified"`
+}
+
+func writeError(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, "<Error><Code>%v</Code><Message>%v</Message></Error>", code, code)
+}
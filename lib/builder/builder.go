@@ -18,6 +18,7 @@ package builder
 
 import (
 	"context"
+	"crypto"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -71,8 +72,13 @@ type Config struct {
 	Overwrite bool
 	// Repository represents the source package repository
 	Repository string
+	// Hub is the optional URL of the hub to download runtimes from,
+	// the default public hub is used if not set
+	Hub string
 	// SkipVersionCheck allows to skip tele/runtime compatibility check
 	SkipVersionCheck bool
+	// SigningKey is the optional key to sign the installer with
+	SigningKey crypto.Signer
 	// VendorReq combines vendoring options
 	VendorReq service.VendorRequest
 	// Generator is used to generate installer
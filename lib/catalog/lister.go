@@ -173,9 +173,10 @@ type hubLister struct {
 	hub hub.Hub
 }
 
-// NewLister returns a lister with S3 hub backend.
-func NewLister() (*hubLister, error) {
-	hub, err := hub.New(hub.Config{})
+// NewLister returns a lister with the hub backend at the specified URL.
+// The default public hub is used if the URL is empty.
+func NewLister(hubURL string, insecure bool) (*hubLister, error) {
+	hub, err := hub.NewFromURL(hubURL, insecure)
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
@@ -207,6 +207,9 @@ const (
 	// BlockingOperationEnvVar specifies whether to wait for operation to complete
 	BlockingOperationEnvVar = "GRAVITY_BLOCKING_OPERATION"
 
+	// TeleHubEnvVar names the environment variable that specifies the hub URL
+	TeleHubEnvVar = "TELE_HUB"
+
 	// DockerRegistry is a default name for private docker registry
 	DockerRegistry = "leader.telekube.local:5000"
 
@@ -543,6 +546,21 @@ const (
 	// MonitoringTypeAlertTarget specifies the value of the component label for monitoring alert targets
 	MonitoringTypeAlertTarget = "alert-target"
 
+	// AlertTargetSecretPrefix is the name prefix of secrets that store webhook,
+	// Slack and PagerDuty alert targets
+	AlertTargetSecretPrefix = "alert-target-"
+
+	// AlertNotifyTokenSecret specifies the name of the Secret with the token
+	// Kapacitor uses to relay alerts to the alert targets
+	AlertNotifyTokenSecret = "alert-notify-token"
+
+	// AlertNotifyTokenKey specifies the name of the key with the alert notify token
+	AlertNotifyTokenKey = "token"
+
+	// AlertRelayHandlerID is the ID of the Kapacitor topic handler that
+	// relays alerts to the alert targets
+	AlertRelayHandlerID = "gravity-alert-targets"
+
 	// MonitoringTypeAlert specifies the value of the component label for monitoring alerts
 	MonitoringTypeAlert = "alert"
 
@@ -555,6 +573,29 @@ const (
 	// AuthGatewayConfigMap is the name of config map with auth gateway configuration.
 	AuthGatewayConfigMap = "auth-gateway"
 
+	// HelmConfigMap is the name of the ConfigMap with the release driver
+	// used by gravity app commands
+	HelmConfigMap = "helm-configuration"
+
+	// AutoRepairConfigMap is the name of the ConfigMap with the state
+	// of the node auto-repair controller
+	AutoRepairConfigMap = "autorepair-state"
+
+	// OpsCenterConfigMap is the name of the ConfigMap with the Ops Center
+	// configuration that overrides the defaults of the cluster controller
+	OpsCenterConfigMap = "gravity-opscenter"
+
+	// S3AccessKeyIDKey is the key of the access key ID in the secret
+	// with the credentials of the S3 package storage
+	S3AccessKeyIDKey = "access_key_id"
+	// S3SecretAccessKeyKey is the key of the secret access key in the secret
+	// with the credentials of the S3 package storage
+	S3SecretAccessKeyKey = "secret_access_key"
+
+	// AutoscaleGCEConfigMap is the name of the ConfigMap with the instances
+	// observed in the GCE managed instance group
+	AutoscaleGCEConfigMap = "autoscale-gce-members"
+
 	// LVMSystemDir specifies the default location where lvm2 keeps state and configuration data
 	LVMSystemDir = "/etc/lvm"
 	// LVMSystemDirEnvvar defines the name of the environment variable that overrides the
@@ -0,0 +1,229 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package cron implements parsing of cron schedule expressions.
+//
+// Standard five-field expressions (minute, hour, day of month, month and
+// day of week) are supported along with the @yearly, @monthly, @weekly,
+// @daily, @hourly and "@every <duration>" descriptors.
+package cron
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gravitational/trace"
+)
+
+// Schedule describes a recurring schedule
+type Schedule interface {
+	// Next returns the first activation time strictly after the specified time
+	Next(time.Time) time.Time
+}
+
+// Parse parses the specified cron expression
+func Parse(spec string) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return nil, trace.BadParameter("empty schedule")
+	}
+	if strings.HasPrefix(spec, "@") {
+		return parseDescriptor(spec)
+	}
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, trace.BadParameter("expected 5 fields in schedule %q, got %v", spec, len(fields))
+	}
+	var s schedule
+	var err error
+	for i, f := range []struct {
+		bits *uint64
+		bounds
+	}{
+		{&s.minute, minutes},
+		{&s.hour, hours},
+		{&s.dom, daysOfMonth},
+		{&s.month, months},
+		{&s.dow, daysOfWeek},
+	} {
+		*f.bits, err = parseField(fields[i], f.bounds)
+		if err != nil {
+			return nil, trace.Wrap(err, "invalid schedule %q", spec)
+		}
+	}
+	// Sunday can be specified as either 0 or 7
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1
+	}
+	s.domRestricted = fields[2] != "*" && fields[2] != "?"
+	s.dowRestricted = fields[4] != "*" && fields[4] != "?"
+	return &s, nil
+}
+
+func parseDescriptor(spec string) (Schedule, error) {
+	switch spec {
+	case "@yearly", "@annually":
+		return Parse("0 0 1 1 *")
+	case "@monthly":
+		return Parse("0 0 1 * *")
+	case "@weekly":
+		return Parse("0 0 * * 0")
+	case "@daily", "@midnight":
+		return Parse("0 0 * * *")
+	case "@hourly":
+		return Parse("0 * * * *")
+	}
+	if strings.HasPrefix(spec, "@every ") {
+		interval, err := time.ParseDuration(strings.TrimSpace(strings.TrimPrefix(spec, "@every ")))
+		if err != nil {
+			return nil, trace.BadParameter("invalid interval in schedule %q: %v", spec, err)
+		}
+		if interval < time.Minute {
+			return nil, trace.BadParameter("interval in schedule %q should be at least a minute", spec)
+		}
+		return every(interval), nil
+	}
+	return nil, trace.BadParameter("unsupported schedule descriptor %q", spec)
+}
+
+// parseField parses a comma-separated list of values, ranges and steps
+// into a bit set
+func parseField(field string, b bounds) (bits uint64, err error) {
+	for _, expr := range strings.Split(field, ",") {
+		rangeExpr, step := expr, 1
+		if i := strings.Index(expr, "/"); i != -1 {
+			rangeExpr = expr[:i]
+			step, err = strconv.Atoi(expr[i+1:])
+			if err != nil || step <= 0 {
+				return 0, trace.BadParameter("invalid step in %q", expr)
+			}
+		}
+		var start, end int
+		switch {
+		case rangeExpr == "*" || rangeExpr == "?":
+			start, end = b.min, b.max
+		case strings.Contains(rangeExpr, "-"):
+			parts := strings.SplitN(rangeExpr, "-", 2)
+			if start, err = b.parse(parts[0]); err != nil {
+				return 0, trace.Wrap(err)
+			}
+			if end, err = b.parse(parts[1]); err != nil {
+				return 0, trace.Wrap(err)
+			}
+		default:
+			if start, err = b.parse(rangeExpr); err != nil {
+				return 0, trace.Wrap(err)
+			}
+			end = start
+			// a step applied to a single value means "starting at"
+			if step != 1 {
+				end = b.max
+			}
+		}
+		if start > end {
+			return 0, trace.BadParameter("invalid range %q", expr)
+		}
+		for i := start; i <= end; i += step {
+			bits |= 1 << uint(i)
+		}
+	}
+	return bits, nil
+}
+
+func (b bounds) parse(value string) (int, error) {
+	if n, ok := b.names[strings.ToLower(value)]; ok {
+		return n, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, trace.BadParameter("invalid value %q", value)
+	}
+	if n < b.min || n > b.max {
+		return 0, trace.BadParameter("value %v is out of range [%v-%v]", n, b.min, b.max)
+	}
+	return n, nil
+}
+
+// Next returns the first activation time strictly after the specified time
+func (s *schedule) Next(t time.Time) time.Time {
+	t = t.Add(time.Minute - time.Duration(t.Second())*time.Second - time.Duration(t.Nanosecond()))
+	// give up if there is no match within several years which can only
+	// happen for impossible dates like February, 30th
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.matchesDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// matchesDay returns true if the day of the specified time matches the schedule.
+// As with the standard cron, if both day of month and day of week are
+// restricted, the day matches if either of them does
+func (s *schedule) matchesDay(t time.Time) bool {
+	dom := s.dom&(1<<uint(t.Day())) != 0
+	dow := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domRestricted && s.dowRestricted {
+		return dom || dow
+	}
+	return dom && dow
+}
+
+// Next returns the first activation time strictly after the specified time
+func (e every) Next(t time.Time) time.Time {
+	return t.Add(time.Duration(e)).Truncate(time.Second)
+}
+
+type schedule struct {
+	minute, hour, dom, month, dow uint64
+	domRestricted, dowRestricted  bool
+}
+
+type every time.Duration
+
+type bounds struct {
+	min, max int
+	names    map[string]int
+}
+
+var (
+	minutes     = bounds{min: 0, max: 59}
+	hours       = bounds{min: 0, max: 23}
+	daysOfMonth = bounds{min: 1, max: 31}
+	months      = bounds{min: 1, max: 12, names: map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	daysOfWeek = bounds{min: 0, max: 7, names: map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
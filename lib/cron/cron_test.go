@@ -0,0 +1,80 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cron
+
+import (
+	"testing"
+	"time"
+
+	check "gopkg.in/check.v1"
+)
+
+func TestCron(t *testing.T) { check.TestingT(t) }
+
+type CronSuite struct{}
+
+var _ = check.Suite(&CronSuite{})
+
+func (s *CronSuite) TestNext(c *check.C) {
+	// Friday
+	now := time.Date(2018, time.June, 15, 10, 30, 15, 0, time.UTC)
+	testCases := []struct {
+		spec     string
+		expected time.Time
+	}{
+		{spec: "* * * * *", expected: time.Date(2018, time.June, 15, 10, 31, 0, 0, time.UTC)},
+		{spec: "*/15 * * * *", expected: time.Date(2018, time.June, 15, 10, 45, 0, 0, time.UTC)},
+		{spec: "0 2 * * *", expected: time.Date(2018, time.June, 16, 2, 0, 0, 0, time.UTC)},
+		{spec: "@daily", expected: time.Date(2018, time.June, 16, 0, 0, 0, 0, time.UTC)},
+		{spec: "@hourly", expected: time.Date(2018, time.June, 15, 11, 0, 0, 0, time.UTC)},
+		{spec: "30 1 * * sun", expected: time.Date(2018, time.June, 17, 1, 30, 0, 0, time.UTC)},
+		{spec: "30 1 * * 7", expected: time.Date(2018, time.June, 17, 1, 30, 0, 0, time.UTC)},
+		{spec: "0 0 1 jan,jul *", expected: time.Date(2018, time.July, 1, 0, 0, 0, 0, time.UTC)},
+		{spec: "0 9-17/4 * * mon-fri", expected: time.Date(2018, time.June, 15, 13, 0, 0, 0, time.UTC)},
+		// either day of month or day of week matches
+		{spec: "0 0 20 * mon", expected: time.Date(2018, time.June, 18, 0, 0, 0, 0, time.UTC)},
+		{spec: "0 0 29 2 *", expected: time.Date(2020, time.February, 29, 0, 0, 0, 0, time.UTC)},
+		{spec: "@every 90m", expected: time.Date(2018, time.June, 15, 12, 0, 15, 0, time.UTC)},
+		// impossible date
+		{spec: "0 0 30 2 *", expected: time.Time{}},
+	}
+	for _, tc := range testCases {
+		schedule, err := Parse(tc.spec)
+		c.Assert(err, check.IsNil, check.Commentf(tc.spec))
+		c.Assert(schedule.Next(now), check.DeepEquals, tc.expected, check.Commentf(tc.spec))
+	}
+}
+
+func (s *CronSuite) TestRejectsInvalidSchedules(c *check.C) {
+	for _, spec := range []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"* * * foo *",
+		"@often",
+		"@every 1s",
+	} {
+		_, err := Parse(spec)
+		c.Assert(err, check.NotNil, check.Commentf(spec))
+	}
+}
@@ -84,6 +84,10 @@ const (
 	// EtcdRetryInterval is the retry interval for some etcd commands
 	EtcdRetryInterval = 3 * time.Second
 
+	// StorageMigrationGracePeriod is the maximum time to wait for running processes
+	// to acknowledge each step of the etcd v3 storage migration
+	StorageMigrationGracePeriod = 30 * time.Second
+
 	// InstallApplicationTimeout is the max allowed time for k8s application to install
 	InstallApplicationTimeout = 90 * time.Minute // 1.5 hours
 
@@ -96,6 +100,10 @@ const (
 	// InstallSystemServiceTimeout specifies the maximum time to wait for system install service to complete
 	InstallSystemServiceTimeout = 5 * time.Minute
 
+	// HelmTimeout specifies the maximum time to wait for release resources
+	// and hooks when managing releases without Tiller
+	HelmTimeout = 5 * time.Minute
+
 	// LabelRetryAttempts specifies the maximum number of attempts to label a node
 	LabelRetryAttempts = 10
 
@@ -188,6 +196,38 @@ const (
 	// GravityUpdateDir specifies the directory used by the update process
 	GravityUpdateDir = "/var/lib/gravity/site/update"
 
+	// ScheduledBackupDir is the directory on master nodes where scheduled
+	// backup hooks store backup data before it is shipped to the destination
+	ScheduledBackupDir = "/var/lib/gravity/site/backup"
+
+	// ClusterBackupsDir is the directory on master nodes available to
+	// gravity-site for storing scheduled backups, such as an NFS mount
+	ClusterBackupsDir = "/var/lib/gravity/backups"
+
+	// BackupRetentionCount is the default number of scheduled backups to keep
+	BackupRetentionCount = 7
+
+	// BackupScheduleCheckInterval is how often backup schedules are checked
+	// for backups that are due
+	BackupScheduleCheckInterval = time.Minute
+
+	// AuditEventRetention is how long audit events are kept in the cluster state
+	AuditEventRetention = 90 * 24 * time.Hour
+
+	// AuditEventsSince is the default time window of audit events to display
+	AuditEventsSince = 24 * time.Hour
+
+	// AuditTag is the syslog tag of audit events sent to log forwarders
+	AuditTag = "gravity-audit"
+
+	// OperationEventsPollInterval is how often the operation event stream
+	// checks the operation state for new events
+	OperationEventsPollInterval = time.Second
+
+	// OperationEventsReconnectAttempts is how many times a client reconnects
+	// the interrupted operation event stream before giving up
+	OperationEventsReconnectAttempts = 10
+
 	// GravityRPCAgentPort defines which port RPC agent is listening on
 	GravityRPCAgentPort = 3012
 
@@ -352,6 +392,10 @@ const (
 	// TestK8s controls whether k8s tests are run
 	TestK8s = "TEST_K8S"
 
+	// TestS3Config is a JSON BLOB with the configuration of an S3-compatible
+	// store (such as MinIO) to run blob storage tests against
+	TestS3Config = "TEST_S3_CONFIG"
+
 	// LocalDir is the gravity subdirectory where local data is stored
 	LocalDir = "local"
 
@@ -512,6 +556,9 @@ const (
 
 	// LogForwardersConfigMap is the name of the config map that contains log forwarders configuration
 	LogForwardersConfigMap = "log-forwarders"
+	// LogForwardersSecret is the name of the secret that contains log forwarders
+	// client keys and authentication tokens
+	LogForwardersSecret = "log-forwarders"
 
 	// GrafanaServiceName is the name of Grafana service
 	GrafanaServiceName = "grafana"
@@ -527,6 +574,10 @@ const (
 	// InfluxDBAdminPassword is the InfluxDB admin user password
 	InfluxDBAdminPassword = "root"
 
+	// BLOBScrubPeriod is the default period between integrity
+	// checks of the objects stored on a package service peer
+	BLOBScrubPeriod = 24 * time.Hour
+
 	// WriteFactor is a default amount of acknowledged writes for object storage
 	// to be considered successfull
 	WriteFactor = 1
@@ -547,6 +598,13 @@ const (
 	// ManifestFileName is the name of the application manifest
 	ManifestFileName = "app.yaml"
 
+	// InstallerSignatureFile is the name of the installer signature file
+	// inside an installer tarball
+	InstallerSignatureFile = "signature.json"
+
+	// TrustedKeysDir is the directory with public keys trusted to sign installers
+	TrustedKeysDir = "/etc/gravity/trusted-keys"
+
 	// RegistryDir is the name of the layers directory inside an application tarball
 	RegistryDir = "registry"
 
@@ -908,6 +966,22 @@ const (
 	// SMTPPort defines the SMTP service port
 	SMTPPort = 465
 
+	// PagerDutyEventsURL is the PagerDuty Events API v2 endpoint
+	PagerDutyEventsURL = "https://events.pagerduty.com/v2/enqueue"
+
+	// AlertNotifyTimeout is the timeout for delivering a single alert notification
+	AlertNotifyTimeout = 10 * time.Second
+
+	// AlertRelaySyncInterval is how often Kapacitor alert topics are checked
+	// for the alert relay handler
+	AlertRelaySyncInterval = 1 * time.Minute
+
+	// AlertNotifyTokenBytes is the length of the alert notify token in bytes
+	AlertNotifyTokenBytes = 32
+
+	// KapacitorServiceURL is the address of the Kapacitor API in the cluster
+	KapacitorServiceURL = "http://kapacitor.monitoring.svc.cluster.local:9092"
+
 	// ServiceUser specifies the name of the user used as a service user in planet
 	// as well as for unprivileged (system) kubernetes resources.
 	ServiceUser = "planet"
@@ -1072,6 +1146,34 @@ var (
 	DiscoveryPublishInterval = 5 * time.Second
 	// DiscoveryResyncInterval specifies the frequency to force publish cluster discovery details
 	DiscoveryResyncInterval = 10 * time.Minute
+	// InstanceGroupSyncInterval specifies the frequency to check the managed instance group
+	// for removed instances
+	InstanceGroupSyncInterval = 30 * time.Second
+	// CloudAPITimeout specifies the timeout of requests to cloud provider APIs
+	CloudAPITimeout = 30 * time.Second
+	// AutoscaleEventTimeout specifies how long an autoscaling event posted to the webhook
+	// waits to be processed
+	AutoscaleEventTimeout = 1 * time.Minute
+
+	// AutoRepairGracePeriod is how long a node has to stay failed before
+	// it is repaired
+	AutoRepairGracePeriod = 15 * time.Minute
+	// AutoRepairCheckInterval specifies the frequency of node health checks
+	// of the auto-repair controller
+	AutoRepairCheckInterval = 1 * time.Minute
+	// AutoRepairMaxNodes is the maximum number of nodes repaired at once
+	AutoRepairMaxNodes = 1
+	// AutoRepairDrainTimeout is how long the auto-repair controller waits
+	// for a failed node to drain before removing it
+	AutoRepairDrainTimeout = 5 * time.Minute
+
+	// VerifyUpdateTimeout is how long the cluster is given to become
+	// healthy after a node or the whole cluster has been updated
+	VerifyUpdateTimeout = 5 * time.Minute
+
+	// RollbackTimeout is the maximum amount of time allowed to automatically
+	// roll back a failed operation plan
+	RollbackTimeout = 1 * time.Hour
 
 	// CACertificateExpiry is the validity period of self-signed CA generated
 	// for clusters during installation
@@ -185,6 +185,9 @@ func (e *fsmEngine) GetPlan() (*storage.OperationPlan, error) {
 // RunCommand executes the phase specified by params on the specified
 // server using the provided runner
 func (e *fsmEngine) RunCommand(ctx context.Context, runner fsm.RemoteRunner, node storage.Server, p fsm.Params) error {
+	if p.Rollback {
+		return trace.NotImplemented("remote rollback is not supported for expand operation")
+	}
 	args := []string{"join", "--phase", p.PhaseID, fmt.Sprintf("--force=%v", p.Force)}
 	if e.DebugMode {
 		args = append([]string{"--debug"}, args...)
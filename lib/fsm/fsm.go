@@ -20,6 +20,8 @@ import (
 	"context"
 	"fmt"
 	"path"
+	"sync"
+	"time"
 
 	"github.com/gravitational/gravity/lib/ops"
 	"github.com/gravitational/gravity/lib/storage"
@@ -75,6 +77,10 @@ type Params struct {
 	Force bool
 	// Progress is optional progress reporter
 	Progress utils.Progress
+	// Rollback specifies whether the phase is being rolled back.
+	// Engines use it to select the command to run for the phases
+	// rolled back on remote nodes
+	Rollback bool
 }
 
 // CheckAndSetDefaults makes sure all required parameters are set
@@ -99,6 +105,9 @@ type FSM struct {
 	preExecFn PhaseHookFn
 	// postExecFn is called after phase execution if set
 	postExecFn PhaseHookFn
+	// mu serializes access to the engine's plan when phases
+	// are executed concurrently
+	mu sync.RWMutex
 }
 
 // PhaseHookFn defines the phase hook function
@@ -114,6 +123,10 @@ type Config struct {
 	Insecure bool
 	// Logger allows to override default logger
 	Logger logrus.FieldLogger
+	// Parallelism specifies the maximum number of phases ExecutePlan
+	// is allowed to run concurrently.
+	// If unset or set to 1, the plan is executed sequentially in plan order
+	Parallelism int
 }
 
 // CheckAndSetDefaults makes sure the config is valid and sets some defaults
@@ -124,6 +137,9 @@ func (c *Config) CheckAndSetDefaults() error {
 	if c.Logger == nil {
 		c.Logger = logrus.WithField(trace.Component, "fsm")
 	}
+	if c.Parallelism < 0 {
+		return trace.BadParameter("parallelism cannot be negative: %v", c.Parallelism)
+	}
 	return nil
 }
 
@@ -139,12 +155,19 @@ func New(config Config) (*FSM, error) {
 	}, nil
 }
 
-// ExecutePlan iterates over all phases of the plan and executes them in order
+// ExecutePlan executes all phases of the plan.
+//
+// If the FSM is configured with parallelism greater than 1, the plan is treated
+// as a dependency graph and independent phases are executed concurrently
+// (see PhaseGraph for details), otherwise the phases are executed in order
 func (f *FSM) ExecutePlan(ctx context.Context, progress utils.Progress, force bool) error {
 	plan, err := f.GetPlan()
 	if err != nil {
 		return trace.Wrap(err)
 	}
+	if f.Parallelism > 1 {
+		return trace.Wrap(f.executePlanConcurrently(ctx, *plan, progress, force))
+	}
 	for _, phase := range plan.Phases {
 		f.Debugf("Executing phase %q.", phase.ID)
 		err := f.ExecutePhase(ctx, Params{
@@ -273,6 +296,22 @@ func (f *FSM) SetPostExec(fn PhaseHookFn) {
 	f.postExecFn = fn
 }
 
+// ChangePhaseState updates the phase state using the underlying engine.
+// State changes are serialized so engines do not observe concurrent updates
+// from phases executed in parallel
+func (f *FSM) ChangePhaseState(ctx context.Context, change StateChange) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return trace.Wrap(f.Engine.ChangePhaseState(ctx, change))
+}
+
+// GetPlan returns the up-to-date operation plan from the underlying engine
+func (f *FSM) GetPlan() (*storage.OperationPlan, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.Engine.GetPlan()
+}
+
 // Close releases all FSM resources
 func (f *FSM) Close() error {
 	return trace.Wrap(f.Runner.Close())
@@ -418,8 +457,10 @@ func (f *FSM) executeOnePhase(ctx context.Context, p Params, phase storage.Opera
 
 	executor.Infof("Executing phase: %v.", phase.ID)
 
+	started := time.Now()
 	err = executor.Execute(ctx)
 	if err != nil {
+		observePhase(plan.OperationType, phase.ID, storage.OperationPhaseStateFailed, started)
 		executor.Errorf("Phase execution failed: %v.", err)
 		if err := f.ChangePhaseState(ctx,
 			StateChange{
@@ -446,6 +487,7 @@ func (f *FSM) executeOnePhase(ctx context.Context, p Params, phase storage.Opera
 	if err != nil {
 		return trace.Wrap(err)
 	}
+	observePhase(plan.OperationType, phase.ID, storage.OperationPhaseStateCompleted, started)
 
 	return nil
 }
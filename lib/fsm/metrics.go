@@ -0,0 +1,45 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fsm
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var phaseLatencies = prometheus.NewHistogramVec(
+	prometheus.HistogramOpts{
+		Name: "gravity_operation_phase_duration_seconds",
+		Help: "Execution time of operation phases executed by this process",
+		// lowest bucket start of upper bound 0.1 sec with factor 2
+		// highest bucket start of 0.1 sec * 2^15 == 3276.8 sec
+		Buckets: prometheus.ExponentialBuckets(0.1, 2, 16),
+	},
+	[]string{"operation", "phase", "state"},
+)
+
+func init() {
+	prometheus.MustRegister(phaseLatencies)
+}
+
+// observePhase records the execution time of the phase that
+// has finished in the specified state
+func observePhase(operationType, phaseID, state string, started time.Time) {
+	phaseLatencies.WithLabelValues(operationType, phaseID, state).
+		Observe(time.Since(started).Seconds())
+}
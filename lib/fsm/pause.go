@@ -0,0 +1,65 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fsm
+
+import (
+	"fmt"
+
+	"github.com/gravitational/trace"
+)
+
+// Pause returns an error a phase uses to stop the plan execution until
+// the operation is resumed, for example, after the user has approved it.
+//
+// Unlike other errors, the pause does not mean the operation has failed
+// and should not trigger the rollback of the plan
+func Pause(format string, args ...interface{}) error {
+	return trace.Wrap(&PausedError{Message: fmt.Sprintf(format, args...)})
+}
+
+// PausedError is returned when the plan execution has been paused
+type PausedError struct {
+	// Message describes how to resume the operation
+	Message string
+}
+
+// Error returns the message describing the pause
+func (e *PausedError) Error() string {
+	return e.Message
+}
+
+// IsPaused returns true if the error indicates that the plan execution
+// has been paused.
+// The aggregate error is considered a pause only if all of its errors are,
+// so a failure of a concurrently executed phase is not mistaken for a pause
+func IsPaused(err error) bool {
+	switch e := trace.Unwrap(err).(type) {
+	case *PausedError:
+		return true
+	case trace.Aggregate:
+		if len(e.Errors()) == 0 {
+			return false
+		}
+		for _, err := range e.Errors() {
+			if !IsPaused(err) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
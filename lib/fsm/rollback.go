@@ -0,0 +1,108 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fsm
+
+import (
+	"context"
+
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/utils"
+
+	"github.com/gravitational/trace"
+)
+
+// RollbackPlan rolls back all phases of the plan that have been started
+// in reverse dependency order and returns the IDs of the phases that have
+// been rolled back.
+//
+// Phases that are bound to other nodes are rolled back on those nodes using
+// the engine's RunCommand with Params.Rollback set.
+// Rollback stops at the first phase that fails to roll back
+func (f *FSM) RollbackPlan(ctx context.Context, progress utils.Progress, force bool) (rolledBack []string, err error) {
+	plan, err := f.GetPlan()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	graph, err := NewPhaseGraph(*plan)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	phases := graph.Order()
+	for i := len(phases) - 1; i >= 0; i-- {
+		phase := phases[i]
+		if phase.IsUnstarted() || phase.IsRolledBack() {
+			continue
+		}
+		f.Debugf("Rolling back phase %q.", phase.ID)
+		err := f.rollbackLeafPhase(ctx, Params{
+			PhaseID:  phase.ID,
+			Progress: progress,
+			Force:    force,
+			Rollback: true,
+		}, phase)
+		if err != nil {
+			return rolledBack, trace.Wrap(err, "failed to roll back phase %q", phase.ID)
+		}
+		rolledBack = append(rolledBack, phase.ID)
+	}
+	return rolledBack, nil
+}
+
+// rollbackLeafPhase rolls back the specified phase either locally or
+// on the node the phase is bound to
+func (f *FSM) rollbackLeafPhase(ctx context.Context, p Params, phase storage.OperationPhase) error {
+	var execServer *storage.Server
+	if phase.Data != nil {
+		if phase.Data.ExecServer != nil {
+			execServer = phase.Data.ExecServer
+		} else {
+			execServer = phase.Data.Server
+		}
+	}
+
+	execWhere := CanRunLocally
+	if execServer != nil {
+		var err error
+		execWhere, err = canExecuteOnServer(ctx, *execServer, f.Runner, f.FieldLogger)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+	}
+
+	switch execWhere {
+	case CanRunLocally:
+		p.Progress.NextStep("Rolling back %q", phase.ID)
+		return trace.Wrap(f.rollbackPhase(ctx, p, phase))
+	case CanRunRemotely:
+		p.Progress.NextStep("Rolling back %q on remote node %v", phase.ID, execServer.Hostname)
+		err := f.RunCommand(ctx, f.Runner, *execServer, p)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		// Record the state locally as the cluster backend might not be
+		// available to synchronize the changes back
+		return trace.Wrap(f.ChangePhaseState(ctx, StateChange{
+			Phase: phase.ID,
+			State: storage.OperationPhaseStateRolledBack,
+		}))
+	case ShouldRunRemotely:
+		return trace.NotFound("no agent is running on node %v, please roll back phase %q locally on that node",
+			serverName(*execServer), phase.ID)
+	default:
+		return trace.BadParameter("unsupported execution location: %v", execWhere)
+	}
+}
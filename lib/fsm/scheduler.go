@@ -0,0 +1,255 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fsm
+
+import (
+	"context"
+	"strings"
+
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/utils"
+
+	"github.com/gravitational/trace"
+)
+
+// PhaseGraph is the dependency graph of the leaf (executable) phases of
+// an operation plan.
+//
+// A leaf phase depends on all phases listed in Requires of the phase itself
+// and of all its parent phases, where requiring a composite phase means
+// requiring all of its leaf phases.
+// Top-level phases always depend on the preceding top-level phase, and
+// subphases of a composite phase that is not marked as Parallel depend on
+// the preceding subphase, so the graph never relaxes the order in which
+// the phases would be executed sequentially.
+type PhaseGraph struct {
+	// Phases lists all leaf phases in plan order
+	Phases []storage.OperationPhase
+	// Requires maps the leaf phase ID to the set of leaf phase IDs
+	// it depends on
+	Requires map[string]map[string]struct{}
+}
+
+// NewPhaseGraph builds a dependency graph for the provided plan
+func NewPhaseGraph(plan storage.OperationPlan) (*PhaseGraph, error) {
+	g := &PhaseGraph{
+		Requires: make(map[string]map[string]struct{}),
+	}
+	leaves := make(map[string][]string)
+	for _, phase := range plan.Phases {
+		g.collectLeaves(phase, leaves)
+	}
+	for i, phase := range plan.Phases {
+		var implicit []string
+		if i > 0 {
+			implicit = []string{plan.Phases[i-1].ID}
+		}
+		g.addDependencies(phase, nil, implicit, leaves)
+	}
+	if err := g.checkCycles(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return g, nil
+}
+
+// collectLeaves records the list of leaf phase IDs for the specified phase
+// and all its subphases
+func (g *PhaseGraph) collectLeaves(phase storage.OperationPhase, leaves map[string][]string) []string {
+	if !phase.HasSubphases() {
+		g.Phases = append(g.Phases, phase)
+		leaves[phase.ID] = []string{phase.ID}
+		return leaves[phase.ID]
+	}
+	var result []string
+	for _, subphase := range phase.Phases {
+		result = append(result, g.collectLeaves(subphase, leaves)...)
+	}
+	leaves[phase.ID] = result
+	return result
+}
+
+// addDependencies records dependencies for all leaf phases of the specified phase.
+// inherited is the list of requirements accumulated from the parent phases
+// and implicit is the list of phases the phase depends on due to its position in the plan
+func (g *PhaseGraph) addDependencies(phase storage.OperationPhase, inherited, implicit []string, leaves map[string][]string) {
+	requires := append(append(append([]string{}, inherited...), phase.Requires...), implicit...)
+	if !phase.HasSubphases() {
+		deps := make(map[string]struct{})
+		for _, required := range requires {
+			for _, id := range leaves[required] {
+				// Skip requirements on the phase's own parents
+				if id != phase.ID {
+					deps[id] = struct{}{}
+				}
+			}
+		}
+		g.Requires[phase.ID] = deps
+		return
+	}
+	for i, subphase := range phase.Phases {
+		var implicit []string
+		if !phase.Parallel && i > 0 {
+			implicit = []string{phase.Phases[i-1].ID}
+		}
+		g.addDependencies(subphase, requires, implicit, leaves)
+	}
+}
+
+// checkCycles makes sure the graph does not have dependency cycles
+func (g *PhaseGraph) checkCycles() error {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[string]int)
+	var visit func(id string, path []string) error
+	visit = func(id string, path []string) error {
+		switch state[id] {
+		case visiting:
+			return trace.BadParameter("plan has a dependency cycle: %v",
+				strings.Join(append(path[:len(path):len(path)], id), " -> "))
+		case visited:
+			return nil
+		}
+		state[id] = visiting
+		for dep := range g.Requires[id] {
+			if err := visit(dep, append(path[:len(path):len(path)], id)); err != nil {
+				return trace.Wrap(err)
+			}
+		}
+		state[id] = visited
+		return nil
+	}
+	for _, phase := range g.Phases {
+		if err := visit(phase.ID, nil); err != nil {
+			return trace.Wrap(err)
+		}
+	}
+	return nil
+}
+
+// Order returns the leaf phases sorted so that each phase comes after all
+// the phases it depends on.
+// Phases are otherwise kept in plan order
+func (g *PhaseGraph) Order() []storage.OperationPhase {
+	visited := make(map[string]bool, len(g.Phases))
+	phases := make(map[string]storage.OperationPhase, len(g.Phases))
+	for _, phase := range g.Phases {
+		phases[phase.ID] = phase
+	}
+	result := make([]storage.OperationPhase, 0, len(g.Phases))
+	var visit func(phase storage.OperationPhase)
+	visit = func(phase storage.OperationPhase) {
+		if visited[phase.ID] {
+			return
+		}
+		visited[phase.ID] = true
+		// Visit dependencies in plan order to keep the result stable
+		for _, dep := range g.Phases {
+			if _, ok := g.Requires[phase.ID][dep.ID]; ok {
+				visit(phases[dep.ID])
+			}
+		}
+		result = append(result, phase)
+	}
+	for _, phase := range g.Phases {
+		visit(phase)
+	}
+	return result
+}
+
+// executePlanConcurrently executes the leaf phases of the plan in dependency order
+// running at most f.Parallelism phases at a time.
+//
+// Once a phase fails, no new phases are started but the phases that are already
+// running are allowed to complete.
+func (f *FSM) executePlanConcurrently(ctx context.Context, plan storage.OperationPlan, progress utils.Progress, force bool) error {
+	graph, err := NewPhaseGraph(plan)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	type result struct {
+		phaseID string
+		err     error
+	}
+	pending := make(map[string]map[string]struct{}, len(graph.Phases))
+	for id, deps := range graph.Requires {
+		pending[id] = make(map[string]struct{}, len(deps))
+		for dep := range deps {
+			pending[id][dep] = struct{}{}
+		}
+	}
+	resultsCh := make(chan result, len(graph.Phases))
+	var errors []error
+	running := 0
+	started := make(map[string]bool, len(graph.Phases))
+	doneCh := ctx.Done()
+	for {
+		if len(errors) == 0 {
+			// Start the phases with satisfied dependencies in plan order
+			for _, phase := range graph.Phases {
+				if running >= f.Parallelism {
+					break
+				}
+				if started[phase.ID] || len(pending[phase.ID]) != 0 {
+					continue
+				}
+				started[phase.ID] = true
+				running++
+				f.Debugf("Executing phase %q.", phase.ID)
+				go func(phaseID string) {
+					err := f.ExecutePhase(ctx, Params{
+						PhaseID:  phaseID,
+						Progress: progress,
+						Force:    force,
+					})
+					resultsCh <- result{phaseID: phaseID, err: err}
+				}(phase.ID)
+			}
+		}
+		if running == 0 {
+			break
+		}
+		select {
+		case r := <-resultsCh:
+			running--
+			if r.err != nil {
+				f.Warnf("Failed to execute phase %q: %v.", r.phaseID, trace.DebugReport(r.err))
+				errors = append(errors, trace.Wrap(r.err, "failed to execute phase %q", r.phaseID))
+				continue
+			}
+			for _, deps := range pending {
+				delete(deps, r.phaseID)
+			}
+		case <-doneCh:
+			// Phases observe the same context so wait for the running
+			// ones to exit before returning
+			doneCh = nil
+			if len(errors) == 0 {
+				errors = append(errors, trace.Wrap(ctx.Err()))
+			}
+		}
+	}
+	if len(errors) != 0 {
+		return trace.NewAggregate(errors...)
+	}
+	if len(started) != len(graph.Phases) {
+		return trace.BadParameter("some phases of the plan could not be scheduled")
+	}
+	return nil
+}
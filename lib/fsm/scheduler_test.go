@@ -0,0 +1,270 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fsm
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gravitational/gravity/lib/storage"
+
+	"github.com/gravitational/trace"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/check.v1"
+)
+
+func TestFSM(t *testing.T) { check.TestingT(t) }
+
+type SchedulerSuite struct{}
+
+var _ = check.Suite(&SchedulerSuite{})
+
+func (s *SchedulerSuite) TestPhaseGraph(c *check.C) {
+	plan := storage.OperationPlan{
+		Phases: []storage.OperationPhase{
+			{ID: "/init"},
+			{ID: "/checks"},
+			{ID: "/bootstrap", Phases: []storage.OperationPhase{
+				{ID: "/bootstrap/node-1"},
+				{ID: "/bootstrap/node-2"},
+			}, Parallel: true},
+			{ID: "/masters", Phases: []storage.OperationPhase{
+				{ID: "/masters/node-1", Phases: []storage.OperationPhase{
+					{ID: "/masters/node-1/teleport"},
+					{ID: "/masters/node-1/planet"},
+				}},
+				{ID: "/masters/node-2"},
+			}, Requires: []string{"/bootstrap"}, Parallel: true},
+			{ID: "/wait", Requires: []string{"/masters"}},
+		},
+	}
+
+	graph, err := NewPhaseGraph(plan)
+	c.Assert(err, check.IsNil)
+	c.Assert(graph.Requires, check.DeepEquals, map[string]map[string]struct{}{
+		"/init":                    {},
+		"/checks":                  {"/init": {}},
+		"/bootstrap/node-1":        {"/checks": {}},
+		"/bootstrap/node-2":        {"/checks": {}},
+		"/masters/node-1/teleport": {"/bootstrap/node-1": {}, "/bootstrap/node-2": {}},
+		"/masters/node-1/planet": {
+			"/bootstrap/node-1":        {},
+			"/bootstrap/node-2":        {},
+			"/masters/node-1/teleport": {},
+		},
+		"/masters/node-2": {"/bootstrap/node-1": {}, "/bootstrap/node-2": {}},
+		"/wait": {
+			"/masters/node-1/teleport": {},
+			"/masters/node-1/planet":   {},
+			"/masters/node-2":          {},
+		},
+	})
+
+	plan.Phases[0].Requires = []string{"/wait"}
+	_, err = NewPhaseGraph(plan)
+	c.Assert(err, check.NotNil)
+}
+
+func (s *SchedulerSuite) TestPhaseGraphKeepsSequentialOrder(c *check.C) {
+	plan := storage.OperationPlan{
+		Phases: []storage.OperationPhase{
+			{ID: "/phase1"},
+			{ID: "/phase2"},
+			// Explicit requirement does not relax the order of top-level phases
+			{ID: "/phase3", Requires: []string{"/phase1"}},
+		},
+	}
+
+	graph, err := NewPhaseGraph(plan)
+	c.Assert(err, check.IsNil)
+	c.Assert(graph.Requires["/phase3"], check.DeepEquals, map[string]struct{}{
+		"/phase1": {},
+		"/phase2": {},
+	})
+}
+
+func (s *SchedulerSuite) TestExecutePlanConcurrently(c *check.C) {
+	plan := storage.OperationPlan{
+		Phases: []storage.OperationPhase{
+			{ID: "/phase1"},
+			{ID: "/phase2", Phases: []storage.OperationPhase{
+				{ID: "/phase2/sub1"},
+				{ID: "/phase2/sub2"},
+				{ID: "/phase2/sub3"},
+			}, Parallel: true},
+			{ID: "/phase3", Requires: []string{"/phase2/sub1"}},
+		},
+	}
+	engine := newTestEngine(plan)
+	fsm, err := New(Config{Engine: engine, Parallelism: 2})
+	c.Assert(err, check.IsNil)
+
+	err = fsm.ExecutePlan(context.TODO(), nil, false)
+	c.Assert(err, check.IsNil)
+
+	c.Assert(IsCompleted(engine.resolvedPlan()), check.Equals, true)
+	c.Assert(engine.maxRunning, check.Equals, 2)
+	c.Assert(engine.executed[0], check.Equals, "/phase1")
+	c.Assert(engine.executed[len(engine.executed)-1], check.Equals, "/phase3")
+}
+
+func (s *SchedulerSuite) TestExecutePlanConcurrentlyStopsOnFailure(c *check.C) {
+	plan := storage.OperationPlan{
+		Phases: []storage.OperationPhase{
+			{ID: "/phase1"},
+			{ID: "/phase2"},
+		},
+	}
+	engine := newTestEngine(plan)
+	engine.failures = map[string]error{"/phase1": trace.BadParameter("failure")}
+	fsm, err := New(Config{Engine: engine, Parallelism: 2})
+	c.Assert(err, check.IsNil)
+
+	err = fsm.ExecutePlan(context.TODO(), nil, false)
+	c.Assert(err, check.NotNil)
+	c.Assert(engine.executed, check.DeepEquals, []string{"/phase1"})
+}
+
+func (s *SchedulerSuite) TestPausedPlan(c *check.C) {
+	plan := storage.OperationPlan{
+		Phases: []storage.OperationPhase{
+			{ID: "/phase1", Phases: []storage.OperationPhase{
+				{ID: "/phase1/sub1"},
+				{ID: "/phase1/sub2"},
+			}, Parallel: true},
+			{ID: "/phase2"},
+		},
+	}
+	engine := newTestEngine(plan)
+	engine.failures = map[string]error{"/phase1/sub1": Pause("waiting for approval")}
+	fsm, err := New(Config{Engine: engine, Parallelism: 2})
+	c.Assert(err, check.IsNil)
+
+	err = fsm.ExecutePlan(context.TODO(), nil, false)
+	c.Assert(IsPaused(err), check.Equals, true, check.Commentf("%v", err))
+
+	// Failure of a concurrently executed phase is not a pause
+	engine = newTestEngine(plan)
+	engine.failures = map[string]error{
+		"/phase1/sub1": Pause("waiting for approval"),
+		"/phase1/sub2": trace.BadParameter("failure"),
+	}
+	fsm, err = New(Config{Engine: engine, Parallelism: 2})
+	c.Assert(err, check.IsNil)
+
+	err = fsm.ExecutePlan(context.TODO(), nil, false)
+	c.Assert(err, check.NotNil)
+	c.Assert(IsPaused(err), check.Equals, false)
+}
+
+func newTestEngine(plan storage.OperationPlan) *testEngine {
+	return &testEngine{plan: plan}
+}
+
+// testEngine is an in-memory FSM engine that records the order
+// of executed phases and the maximum number of concurrently running phases
+type testEngine struct {
+	sync.Mutex
+	plan       storage.OperationPlan
+	changelog  storage.PlanChangelog
+	failures   map[string]error
+	executed   []string
+	running    int
+	maxRunning int
+}
+
+func (e *testEngine) GetExecutor(p ExecutorParams, remote Remote) (PhaseExecutor, error) {
+	return &testExecutor{
+		FieldLogger: logrus.WithField("phase", p.Phase.ID),
+		engine:      e,
+		phaseID:     p.Phase.ID,
+	}, nil
+}
+
+func (e *testEngine) ChangePhaseState(ctx context.Context, change StateChange) error {
+	e.Lock()
+	defer e.Unlock()
+	e.changelog = append(e.changelog, storage.PlanChange{
+		PhaseID:  change.Phase,
+		NewState: change.State,
+		// Keep the changes strictly ordered
+		Created: time.Unix(0, int64(len(e.changelog)+1)),
+	})
+	return nil
+}
+
+func (e *testEngine) GetPlan() (*storage.OperationPlan, error) {
+	return e.resolvedPlan(), nil
+}
+
+func (e *testEngine) RunCommand(context.Context, RemoteRunner, storage.Server, Params) error {
+	return trace.NotImplemented("not implemented")
+}
+
+func (e *testEngine) Complete(error) error {
+	return nil
+}
+
+func (e *testEngine) resolvedPlan() *storage.OperationPlan {
+	e.Lock()
+	defer e.Unlock()
+	return ResolvePlan(e.plan, e.changelog)
+}
+
+func (e *testEngine) start(phaseID string) {
+	e.Lock()
+	defer e.Unlock()
+	e.executed = append(e.executed, phaseID)
+	e.running++
+	if e.running > e.maxRunning {
+		e.maxRunning = e.running
+	}
+}
+
+func (e *testEngine) stop() {
+	e.Lock()
+	defer e.Unlock()
+	e.running--
+}
+
+type testExecutor struct {
+	logrus.FieldLogger
+	engine  *testEngine
+	phaseID string
+}
+
+func (p *testExecutor) PreCheck(context.Context) error {
+	return nil
+}
+
+func (p *testExecutor) PostCheck(context.Context) error {
+	return nil
+}
+
+func (p *testExecutor) Execute(context.Context) error {
+	p.engine.start(p.phaseID)
+	defer p.engine.stop()
+	// Give concurrently scheduled phases a chance to overlap
+	time.Sleep(10 * time.Millisecond)
+	return p.engine.failures[p.phaseID]
+}
+
+func (p *testExecutor) Rollback(context.Context) error {
+	return nil
+}
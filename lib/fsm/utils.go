@@ -79,6 +79,16 @@ func IsFailed(plan *storage.OperationPlan) bool {
 	return true
 }
 
+// IsRolledBack returns true if all phases of the provided plan are either rolled back or unstarted
+func IsRolledBack(plan *storage.OperationPlan) bool {
+	for _, phase := range FlattenPlan(plan) {
+		if !phase.IsRolledBack() && !phase.IsUnstarted() {
+			return false
+		}
+	}
+	return true
+}
+
 // FindPhase finds a phase with the specified id in the provided plan
 func FindPhase(plan *storage.OperationPlan, phaseID string) (*storage.OperationPhase, error) {
 	allPhases := FlattenPlan(plan)
@@ -38,24 +38,67 @@ import (
 )
 
 // Client is the Helm client.
-type Client struct {
-	client helm.Interface
-	tunnel *kube.Tunnel
+type Client interface {
+	// Install installs a Helm chart and returns release information.
+	Install(InstallParameters) (*Release, error)
+	// List returns list of releases matching provided parameters.
+	List(ListParameters) ([]Release, error)
+	// Get returns a single release with the specified name.
+	Get(name string) (*Release, error)
+	// Upgrade upgrades a release.
+	Upgrade(UpgradeParameters) (*Release, error)
+	// Rollback rolls back a release to the specified version.
+	Rollback(RollbackParameters) (*Release, error)
+	// Uninstall uninstalls a release with the provided name.
+	Uninstall(name string) (*Release, error)
+	// Revisions returns revision history for a release with the provided name.
+	Revisions(name string) ([]Release, error)
+	// Close closes the Helm client.
+	Close() error
 }
 
 // ClientConfig is the Helm client configuration.
 type ClientConfig struct {
 	// DNSAddress is an optional in-cluster DNS address.
 	DNSAddress string
+	// Driver is an optional release driver to use instead of the one
+	// selected for the cluster.
+	Driver string
 	// TODO Add Helm TLS flags.
 }
 
-// NewClient returns a new Helm client instance.
-func NewClient(conf ClientConfig) (*Client, error) {
-	kubeClient, kubeConfig, err := getKubeClient(conf.DNSAddress)
+// NewClient returns a new Helm client instance that uses the release
+// driver selected for the cluster.
+func NewClient(conf ClientConfig) (Client, error) {
+	kubeClient, kubeConfig, err := GetKubeClient(conf.DNSAddress)
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
+	driver := conf.Driver
+	if driver == "" {
+		driver, err = GetDriver(kubeClient)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+	}
+	switch driver {
+	case DriverTiller:
+		return newTillerClient(kubeClient, kubeConfig)
+	case DriverSecret:
+		return newSecretClient(kubeClient, kubeConfig), nil
+	}
+	return nil, trace.BadParameter("unsupported release driver %q", driver)
+}
+
+// tillerClient is the Helm client that manages releases through Tiller.
+type tillerClient struct {
+	client helm.Interface
+	tunnel *kube.Tunnel
+}
+
+// newTillerClient returns a new Helm client that talks to Tiller
+// through a port forward.
+func newTillerClient(kubeClient *kubernetes.Clientset, kubeConfig *rest.Config) (*tillerClient, error) {
 	tunnel, err := portforwarder.New("kube-system", kubeClient, kubeConfig)
 	if err != nil {
 		return nil, trace.Wrap(err)
@@ -63,7 +106,7 @@ func NewClient(conf ClientConfig) (*Client, error) {
 	options := []helm.Option{
 		helm.Host(fmt.Sprintf("127.0.0.1:%d", tunnel.Local)),
 	}
-	return &Client{
+	return &tillerClient{
 		client: helm.NewClient(options...),
 		tunnel: tunnel,
 	}, nil
@@ -84,7 +127,7 @@ type InstallParameters struct {
 }
 
 // Install installs a Helm chart and returns release information.
-func (c *Client) Install(p InstallParameters) (*Release, error) {
+func (c *tillerClient) Install(p InstallParameters) (*Release, error) {
 	rawVals, err := helmutils.Vals(p.Values, p.Set, nil, nil, "", "", "")
 	if err != nil {
 		return nil, trace.Wrap(err)
@@ -125,7 +168,7 @@ func (p ListParameters) Options() (options []helm.ReleaseListOption) {
 }
 
 // List returns list of releases matching provided parameters.
-func (c *Client) List(p ListParameters) ([]Release, error) {
+func (c *tillerClient) List(p ListParameters) ([]Release, error) {
 	response, err := c.client.ListReleases(p.Options()...) // TODO Paging.
 	if err != nil {
 		return nil, trace.Wrap(err)
@@ -140,7 +183,13 @@ func (c *Client) List(p ListParameters) ([]Release, error) {
 }
 
 // Get returns a single release with the specified name.
-func (c *Client) Get(name string) (*Release, error) {
+func (c *tillerClient) Get(name string) (*Release, error) {
+	return getRelease(c, name)
+}
+
+// getRelease returns a single release with the specified name
+// by filtering the list of releases.
+func getRelease(c Client, name string) (*Release, error) {
 	releases, err := c.List(ListParameters{Filter: name})
 	if err != nil {
 		return nil, trace.Wrap(err)
@@ -166,7 +215,7 @@ type UpgradeParameters struct {
 }
 
 // Upgrade upgrades a release.
-func (c *Client) Upgrade(p UpgradeParameters) (*Release, error) {
+func (c *tillerClient) Upgrade(p UpgradeParameters) (*Release, error) {
 	rawVals, err := helmutils.Vals(p.Values, p.Set, nil, nil, "", "", "")
 	if err != nil {
 		return nil, trace.Wrap(err)
@@ -193,7 +242,7 @@ type RollbackParameters struct {
 }
 
 // Rollback rolls back a release to the specified version.
-func (c *Client) Rollback(p RollbackParameters) (*Release, error) {
+func (c *tillerClient) Rollback(p RollbackParameters) (*Release, error) {
 	response, err := c.client.RollbackRelease(
 		p.Release,
 		helm.RollbackVersion(int32(p.Revision)))
@@ -204,7 +253,7 @@ func (c *Client) Rollback(p RollbackParameters) (*Release, error) {
 }
 
 // Uninstall uninstalls a release with the provided name.
-func (c *Client) Uninstall(name string) (*Release, error) {
+func (c *tillerClient) Uninstall(name string) (*Release, error) {
 	response, err := c.client.DeleteRelease(name)
 	if err != nil {
 		return nil, trace.Wrap(err)
@@ -213,7 +262,7 @@ func (c *Client) Uninstall(name string) (*Release, error) {
 }
 
 // Revisions returns revision history for a release with the provided name.
-func (c *Client) Revisions(name string) ([]Release, error) {
+func (c *tillerClient) Revisions(name string) ([]Release, error) {
 	response, err := c.client.ReleaseHistory(name,
 		helm.WithMaxHistory(maxHistory))
 	if err != nil {
@@ -229,18 +278,18 @@ func (c *Client) Revisions(name string) ([]Release, error) {
 }
 
 // Close closes the Helm client.
-func (c *Client) Close() error {
+func (c *tillerClient) Close() error {
 	c.tunnel.Close()
 	return nil
 }
 
-// getKubeClient returns a cluster's Kubernetes client and its config.
+// GetKubeClient returns a cluster's Kubernetes client and its config.
 //
 // When invoked inside a Gravity cluster, returns the cluster client. The
 // dnsAddress parameter specifies the address of in-cluster DNS.
 //
 // Otherwise, returns a client based on the default kubeconfig.
-func getKubeClient(dnsAddress string) (*kubernetes.Clientset, *rest.Config, error) {
+func GetKubeClient(dnsAddress string) (*kubernetes.Clientset, *rest.Config, error) {
 	err := httplib.InGravity(dnsAddress)
 	if err != nil {
 		logrus.Infof("Not in Gravity: %v.", err)
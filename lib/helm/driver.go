@@ -0,0 +1,102 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helm
+
+import (
+	"github.com/gravitational/gravity/lib/constants"
+	"github.com/gravitational/gravity/lib/defaults"
+
+	"github.com/gravitational/rigging"
+	"github.com/gravitational/trace"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// DriverTiller manages releases through Tiller which keeps them
+	// as ConfigMaps in the kube-system namespace.
+	DriverTiller = "tiller"
+	// DriverSecret manages releases without Tiller and keeps them
+	// as Secrets in the release namespace in the Helm 3 storage format.
+	DriverSecret = "secret"
+)
+
+// Drivers lists all supported release drivers.
+var Drivers = []string{DriverTiller, DriverSecret}
+
+// GetDriver returns the release driver selected for the cluster.
+//
+// Clusters that have not selected a driver use Tiller.
+func GetDriver(client kubernetes.Interface) (string, error) {
+	configMap, err := client.CoreV1().ConfigMaps(defaults.KubeSystemNamespace).Get(
+		constants.HelmConfigMap, metav1.GetOptions{})
+	if err != nil {
+		err = rigging.ConvertError(err)
+		if trace.IsNotFound(err) {
+			return DriverTiller, nil
+		}
+		return "", trace.Wrap(err)
+	}
+	driver := configMap.Data[driverKey]
+	if driver == "" {
+		return DriverTiller, nil
+	}
+	return driver, nil
+}
+
+// SetDriver selects the release driver for the cluster.
+func SetDriver(client kubernetes.Interface, driver string) error {
+	if err := checkDriver(driver); err != nil {
+		return trace.Wrap(err)
+	}
+	configMaps := client.CoreV1().ConfigMaps(defaults.KubeSystemNamespace)
+	configMap, err := configMaps.Get(constants.HelmConfigMap, metav1.GetOptions{})
+	if err != nil {
+		err = rigging.ConvertError(err)
+		if !trace.IsNotFound(err) {
+			return trace.Wrap(err)
+		}
+		_, err = configMaps.Create(&v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      constants.HelmConfigMap,
+				Namespace: defaults.KubeSystemNamespace,
+			},
+			Data: map[string]string{driverKey: driver},
+		})
+		return rigging.ConvertError(err)
+	}
+	if configMap.Data == nil {
+		configMap.Data = make(map[string]string)
+	}
+	configMap.Data[driverKey] = driver
+	_, err = configMaps.Update(configMap)
+	return rigging.ConvertError(err)
+}
+
+func checkDriver(driver string) error {
+	for _, supported := range Drivers {
+		if driver == supported {
+			return nil
+		}
+	}
+	return trace.BadParameter("unsupported release driver %q, supported drivers: %v",
+		driver, Drivers)
+}
+
+// driverKey is the key of the release driver in the Helm ConfigMap.
+const driverKey = "driver"
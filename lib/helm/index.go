@@ -49,18 +49,55 @@ func GenerateIndexFile(apps []app.Application) *repo.IndexFile {
 
 // generateChartMetadata generates chart metadata for the provided application.
 func generateChartMetadata(item app.Application) *chart.Metadata {
+	return GenerateChartMetadata(item.Manifest, item.PackageEnvelope.SizeBytes)
+}
+
+// GenerateChartMetadata generates chart metadata for the application image
+// with the provided manifest and size.
+func GenerateChartMetadata(manifest schema.Manifest, sizeBytes int64) *chart.Metadata {
 	return &chart.Metadata{
-		Name:        item.Manifest.Metadata.Name,
-		Version:     item.Manifest.Metadata.ResourceVersion,
-		Description: item.Manifest.Metadata.Description,
+		Name:        manifest.Metadata.Name,
+		Version:     manifest.Metadata.ResourceVersion,
+		Description: manifest.Metadata.Description,
 		Annotations: map[string]string{
-			constants.AnnotationKind: item.Manifest.ImageType(),
-			constants.AnnotationLogo: item.Manifest.Logo,
-			constants.AnnotationSize: fmt.Sprintf("%v", item.PackageEnvelope.SizeBytes),
+			constants.AnnotationKind: manifest.ImageType(),
+			constants.AnnotationLogo: manifest.Logo,
+			constants.AnnotationSize: fmt.Sprintf("%v", sizeBytes),
 		},
 	}
 }
 
+// MergeIndexFile merges entries of the index file src into the index file dst.
+//
+// If overwrite is true, entries of dst are replaced with entries of src
+// with the same name and version, otherwise they are kept intact.
+func MergeIndexFile(dst, src *repo.IndexFile, overwrite bool) {
+	if overwrite {
+		for name, versions := range src.Entries {
+			for _, version := range versions {
+				removeVersion(dst, name, version.Version)
+			}
+		}
+	}
+	dst.Merge(src)
+	dst.SortEntries()
+}
+
+// removeVersion removes the specified chart version from the index file.
+func removeVersion(indexFile *repo.IndexFile, name, version string) {
+	var versions repo.ChartVersions
+	for _, entry := range indexFile.Entries[name] {
+		if entry.Version != version {
+			versions = append(versions, entry)
+		}
+	}
+	if len(versions) == 0 {
+		delete(indexFile.Entries, name)
+		return
+	}
+	indexFile.Entries[name] = versions
+}
+
 // baseURL returns the base URL of S3 bucket for the specified image.
 func baseURL(name, version string) string {
 	return fmt.Sprintf("https://s3.amazonaws.com/%v/%v/app/%v/%v/linux/x86_64",
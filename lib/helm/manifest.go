@@ -0,0 +1,243 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helm
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/gravitational/trace"
+	"k8s.io/helm/pkg/chartutil"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+	"k8s.io/helm/pkg/releaseutil"
+	"k8s.io/helm/pkg/renderutil"
+)
+
+// renderedChart is a chart rendered for a release revision.
+type renderedChart struct {
+	// Manifest is the rendered chart without hooks.
+	Manifest string
+	// Hooks lists the rendered chart hooks.
+	Hooks []*recordHook
+	// Notes is the rendered NOTES.txt of the chart.
+	Notes string
+}
+
+// renderChart renders the chart with the provided values.
+func renderChart(ch *chart.Chart, rawVals []byte, options chartutil.ReleaseOptions) (*renderedChart, error) {
+	files, err := renderutil.Render(ch, &chart.Config{Raw: string(rawVals)},
+		renderutil.Options{ReleaseOptions: options})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var notes string
+	notesPath := path.Join(ch.GetMetadata().GetName(), "templates", notesFile)
+	for name, content := range files {
+		if name == notesPath {
+			notes = content
+		}
+	}
+	manifests, hooks, err := sortManifests(files)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var b strings.Builder
+	for _, m := range manifests {
+		fmt.Fprintf(&b, "---\n# Source: %v\n%v\n", m.path, m.content)
+	}
+	return &renderedChart{
+		Manifest: b.String(),
+		Hooks:    hooks,
+		Notes:    notes,
+	}, nil
+}
+
+// resource is a single resource of a rendered chart.
+type resource struct {
+	// path is the chart template the resource has been rendered from.
+	path string
+	// kind is the resource kind.
+	kind string
+	// content is the rendered resource.
+	content string
+}
+
+// sortManifests splits the rendered templates into resources and hooks.
+//
+// Resources are sorted in the order they are installed in, hooks are
+// sorted by weight.
+func sortManifests(files map[string]string) (manifests []resource, hooks []*recordHook, err error) {
+	paths := make([]string, 0, len(files))
+	for name := range files {
+		paths = append(paths, name)
+	}
+	sort.Strings(paths)
+	for _, filePath := range paths {
+		// Skip internal Helm files and files that begin with underscore
+		// which are not expected to output a Kubernetes spec.
+		base := path.Base(filePath)
+		if base == notesFile || strings.HasPrefix(base, "_") {
+			continue
+		}
+		docs := releaseutil.SplitManifests(files[filePath])
+		for i := 0; i < len(docs); i++ {
+			content := docs[fmt.Sprintf("manifest-%d", i)]
+			var head releaseutil.SimpleHead
+			if err := yaml.Unmarshal([]byte(content), &head); err != nil {
+				return nil, nil, trace.BadParameter("invalid manifest in %v: %v", filePath, err)
+			}
+			if head.Kind == "" {
+				// Templates may render to empty documents.
+				continue
+			}
+			if head.Metadata == nil || head.Metadata.Annotations[hookAnnotation] == "" {
+				manifests = append(manifests, resource{
+					path:    filePath,
+					kind:    head.Kind,
+					content: content,
+				})
+				continue
+			}
+			hook, err := newHook(filePath, content, head)
+			if err != nil {
+				return nil, nil, trace.Wrap(err)
+			}
+			hooks = append(hooks, hook)
+		}
+	}
+	sort.SliceStable(manifests, func(i, j int) bool {
+		return installOrder(manifests[i].kind) < installOrder(manifests[j].kind)
+	})
+	sort.SliceStable(hooks, func(i, j int) bool {
+		if hooks[i].Weight != hooks[j].Weight {
+			return hooks[i].Weight < hooks[j].Weight
+		}
+		return hooks[i].Name < hooks[j].Name
+	})
+	return manifests, hooks, nil
+}
+
+// newHook returns the hook defined by the annotations of the resource.
+func newHook(path, content string, head releaseutil.SimpleHead) (*recordHook, error) {
+	annotations := head.Metadata.Annotations
+	hook := &recordHook{
+		Name:     head.Metadata.Name,
+		Kind:     head.Kind,
+		Path:     path,
+		Manifest: content,
+	}
+	for _, event := range strings.Split(annotations[hookAnnotation], ",") {
+		hook.Events = append(hook.Events, strings.TrimSpace(event))
+	}
+	if weight := annotations[hookWeightAnnotation]; weight != "" {
+		value, err := strconv.Atoi(weight)
+		if err != nil {
+			return nil, trace.BadParameter("invalid weight %q of hook %v in %v",
+				weight, hook.Name, path)
+		}
+		hook.Weight = value
+	}
+	if policies := annotations[hookDeletePolicyAnnotation]; policies != "" {
+		for _, policy := range strings.Split(policies, ",") {
+			hook.DeletePolicies = append(hook.DeletePolicies, strings.TrimSpace(policy))
+		}
+	}
+	return hook, nil
+}
+
+// installOrder returns the position of the resource kind
+// in the order resources are installed in.
+func installOrder(kind string) int {
+	for i, k := range kindInstallOrder {
+		if k == kind {
+			return i
+		}
+	}
+	// Unknown kinds, e.g. custom resources, are installed last.
+	return len(kindInstallOrder)
+}
+
+// kindInstallOrder is the order Helm installs resources in.
+var kindInstallOrder = []string{
+	"Namespace",
+	"ResourceQuota",
+	"LimitRange",
+	"PodSecurityPolicy",
+	"PodDisruptionBudget",
+	"Secret",
+	"ConfigMap",
+	"StorageClass",
+	"PersistentVolume",
+	"PersistentVolumeClaim",
+	"ServiceAccount",
+	"CustomResourceDefinition",
+	"ClusterRole",
+	"ClusterRoleList",
+	"ClusterRoleBinding",
+	"ClusterRoleBindingList",
+	"Role",
+	"RoleList",
+	"RoleBinding",
+	"RoleBindingList",
+	"Service",
+	"DaemonSet",
+	"Pod",
+	"ReplicationController",
+	"ReplicaSet",
+	"Deployment",
+	"HorizontalPodAutoscaler",
+	"StatefulSet",
+	"Job",
+	"CronJob",
+	"Ingress",
+	"APIService",
+}
+
+const (
+	// notesFile is the chart template rendered into release notes.
+	notesFile = "NOTES.txt"
+
+	// hookAnnotation lists the events that trigger the hook.
+	hookAnnotation = "helm.sh/hook"
+	// hookWeightAnnotation orders hooks triggered by the same event.
+	hookWeightAnnotation = "helm.sh/hook-weight"
+	// hookDeletePolicyAnnotation specifies when the hook resource is deleted.
+	hookDeletePolicyAnnotation = "helm.sh/hook-delete-policy"
+)
+
+// Hook events.
+const (
+	hookPreInstall   = "pre-install"
+	hookPostInstall  = "post-install"
+	hookPreDelete    = "pre-delete"
+	hookPostDelete   = "post-delete"
+	hookPreUpgrade   = "pre-upgrade"
+	hookPostUpgrade  = "post-upgrade"
+	hookPreRollback  = "pre-rollback"
+	hookPostRollback = "post-rollback"
+)
+
+// Hook delete policies.
+const (
+	hookSucceeded          = "hook-succeeded"
+	hookFailed             = "hook-failed"
+	hookBeforeHookCreation = "before-hook-creation"
+)
@@ -0,0 +1,83 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helm
+
+import (
+	check "gopkg.in/check.v1"
+)
+
+type ManifestSuite struct{}
+
+var _ = check.Suite(&ManifestSuite{})
+
+func (s *ManifestSuite) TestSortsManifests(c *check.C) {
+	manifests, hooks, err := sortManifests(map[string]string{
+		"app/templates/NOTES.txt":    "Visit http://app",
+		"app/templates/_helpers.tpl": "",
+		"app/templates/empty.yaml":   "# disabled\n",
+		"app/templates/service.yaml": "kind: Service\nmetadata:\n  name: app\n",
+		"app/templates/resources.yaml": `kind: Deployment
+metadata:
+  name: app
+---
+kind: ConfigMap
+metadata:
+  name: app
+`,
+		"app/templates/hooks.yaml": `kind: Job
+metadata:
+  name: migrate
+  annotations:
+    helm.sh/hook: pre-install, pre-upgrade
+    helm.sh/hook-weight: "5"
+    helm.sh/hook-delete-policy: hook-succeeded
+---
+kind: Job
+metadata:
+  name: init
+  annotations:
+    helm.sh/hook: pre-install
+    helm.sh/hook-weight: "-1"
+`,
+	})
+	c.Assert(err, check.IsNil)
+	var kinds []string
+	for _, m := range manifests {
+		kinds = append(kinds, m.kind)
+	}
+	c.Assert(kinds, check.DeepEquals, []string{"ConfigMap", "Service", "Deployment"})
+	c.Assert(hooks, check.HasLen, 2)
+	c.Assert(hooks[0].Name, check.Equals, "init")
+	c.Assert(hooks[0].Weight, check.Equals, -1)
+	c.Assert(hooks[1].Name, check.Equals, "migrate")
+	c.Assert(hooks[1].Path, check.Equals, "app/templates/hooks.yaml")
+	c.Assert(hooks[1].Events, check.DeepEquals, []string{hookPreInstall, hookPreUpgrade})
+	c.Assert(hooks[1].DeletePolicies, check.DeepEquals, []string{hookSucceeded})
+}
+
+func (s *ManifestSuite) TestRejectsInvalidHookWeight(c *check.C) {
+	_, _, err := sortManifests(map[string]string{
+		"app/templates/hook.yaml": `kind: Job
+metadata:
+  name: migrate
+  annotations:
+    helm.sh/hook: post-install
+    helm.sh/hook-weight: heavy
+`,
+	})
+	c.Assert(err, check.NotNil)
+}
@@ -0,0 +1,321 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helm
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/gravitational/gravity/lib/defaults"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/gravitational/rigging"
+	"github.com/gravitational/trace"
+	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/helm/pkg/chartutil"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+	"k8s.io/helm/pkg/proto/hapi/release"
+	"k8s.io/helm/pkg/timeconv"
+)
+
+// MigrateConfig defines the configuration for migrating releases from Tiller.
+type MigrateConfig struct {
+	// Client is the Kubernetes client.
+	Client kubernetes.Interface
+	// Cleanup removes the Tiller releases once they have been converted.
+	Cleanup bool
+	// DryRun only reports the releases that would be converted.
+	DryRun bool
+	// FieldLogger is used for logging.
+	logrus.FieldLogger
+}
+
+// CheckAndSetDefaults validates the configuration and sets defaults.
+func (c *MigrateConfig) CheckAndSetDefaults() error {
+	if c.Client == nil {
+		return trace.BadParameter("missing Client parameter")
+	}
+	if c.FieldLogger == nil {
+		c.FieldLogger = logrus.WithField(trace.Component, "helm")
+	}
+	return nil
+}
+
+// MigrateResult describes the outcome of the release migration.
+type MigrateResult struct {
+	// Releases lists the latest revisions of the migrated releases.
+	Releases []Release
+	// Converted is the number of revisions converted.
+	Converted int
+	// Skipped is the number of revisions that had already been converted.
+	Skipped int
+}
+
+// MigrateReleases converts the releases kept by Tiller as ConfigMaps in
+// the kube-system namespace into Secrets in the release namespace and
+// selects the Secret release driver for the cluster.
+//
+// Revisions that have already been converted are skipped so the migration
+// can be safely repeated. The Tiller releases are only removed if requested.
+func MigrateReleases(config MigrateConfig) (*MigrateResult, error) {
+	if err := config.CheckAndSetDefaults(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	configMaps, err := config.Client.CoreV1().ConfigMaps(defaults.KubeSystemNamespace).List(
+		metav1.ListOptions{LabelSelector: tillerSelector})
+	if err != nil {
+		return nil, rigging.ConvertError(err)
+	}
+	storage := &secretStorage{client: config.Client}
+	latest := make(map[string]record)
+	var result MigrateResult
+	for _, configMap := range configMaps.Items {
+		rls, err := decodeTillerRelease(configMap.Data[releaseKey])
+		if err != nil {
+			return nil, trace.Wrap(err, "failed to decode release %v", configMap.Name)
+		}
+		rec, err := convertRelease(rls)
+		if err != nil {
+			return nil, trace.Wrap(err, "failed to convert release %v", configMap.Name)
+		}
+		if existing, ok := latest[rec.Name]; !ok || existing.Version < rec.Version {
+			latest[rec.Name] = *rec
+		}
+		logger := config.WithField("release", configMap.Name)
+		if config.DryRun {
+			result.Converted++
+			continue
+		}
+		err = storage.create(*rec)
+		if err != nil && !trace.IsAlreadyExists(err) {
+			return nil, trace.Wrap(err)
+		}
+		if err != nil {
+			logger.Info("Release has already been converted.")
+			result.Skipped++
+		} else {
+			logger.Info("Converted release.")
+			result.Converted++
+		}
+	}
+	for _, rec := range latest {
+		result.Releases = append(result.Releases, *(rec.toRelease()))
+	}
+	sort.Slice(result.Releases, func(i, j int) bool {
+		return result.Releases[i].Name < result.Releases[j].Name
+	})
+	if config.DryRun {
+		return &result, nil
+	}
+	if err := SetDriver(config.Client, DriverSecret); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if !config.Cleanup {
+		return &result, nil
+	}
+	for _, configMap := range configMaps.Items {
+		err := rigging.ConvertError(config.Client.CoreV1().ConfigMaps(defaults.KubeSystemNamespace).Delete(
+			configMap.Name, &metav1.DeleteOptions{}))
+		if err != nil && !trace.IsNotFound(err) {
+			return nil, trace.Wrap(err)
+		}
+	}
+	return &result, nil
+}
+
+// decodeTillerRelease decodes the release kept by Tiller:
+// base64-encoded gzipped protobuf message.
+func decodeTillerRelease(encoded string) (*release.Release, error) {
+	data, err := decodeCompressed([]byte(encoded))
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var rls release.Release
+	if err := proto.Unmarshal(data, &rls); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &rls, nil
+}
+
+// convertRelease converts the Helm 2 release revision into the Helm 3 format.
+func convertRelease(rls *release.Release) (*record, error) {
+	chart, err := convertChart(rls.GetChart())
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	config, err := chartutil.ReadValues([]byte(rls.GetConfig().GetRaw()))
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	rec := &record{
+		Name:      rls.GetName(),
+		Namespace: rls.GetNamespace(),
+		Chart:     chart,
+		Config:    config.AsMap(),
+		Manifest:  rls.GetManifest(),
+		Version:   int(rls.GetVersion()),
+		Info: &recordInfo{
+			Status:      convertStatus(rls.GetInfo().GetStatus().GetCode()),
+			Notes:       rls.GetInfo().GetStatus().GetNotes(),
+			Description: rls.GetInfo().GetDescription(),
+		},
+	}
+	info := rls.GetInfo()
+	if info.GetFirstDeployed() != nil {
+		rec.Info.FirstDeployed = timeconv.Time(info.GetFirstDeployed()).UTC()
+	}
+	if info.GetLastDeployed() != nil {
+		rec.Info.LastDeployed = timeconv.Time(info.GetLastDeployed()).UTC()
+	}
+	if info.GetDeleted() != nil {
+		rec.Info.Deleted = timeconv.Time(info.GetDeleted()).UTC()
+	}
+	for _, hook := range rls.GetHooks() {
+		converted := &recordHook{
+			Name:     hook.GetName(),
+			Kind:     hook.GetKind(),
+			Path:     hook.GetPath(),
+			Manifest: hook.GetManifest(),
+			Weight:   int(hook.GetWeight()),
+		}
+		for _, event := range hook.GetEvents() {
+			converted.Events = append(converted.Events, convertHookEvent(event))
+		}
+		for _, policy := range hook.GetDeletePolicies() {
+			converted.DeletePolicies = append(converted.DeletePolicies, convertHookDeletePolicy(policy))
+		}
+		if hook.GetLastRun() != nil {
+			lastRun := timeconv.Time(hook.GetLastRun()).UTC()
+			converted.LastRun = hookExecution{
+				StartedAt:   lastRun,
+				CompletedAt: lastRun,
+				Phase:       hookPhaseSucceeded,
+			}
+		}
+		rec.Hooks = append(rec.Hooks, converted)
+	}
+	return rec, nil
+}
+
+// convertChart converts the Helm 2 chart into the Helm 3 format.
+//
+// Subcharts are not kept since Helm 3 does not store them either.
+func convertChart(ch *chart.Chart) (*recordChart, error) {
+	values, err := chartutil.ReadValues([]byte(ch.GetValues().GetRaw()))
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	md := ch.GetMetadata()
+	metadata := &chartMetadata{
+		Name:        md.GetName(),
+		Home:        md.GetHome(),
+		Sources:     md.GetSources(),
+		Version:     md.GetVersion(),
+		Description: md.GetDescription(),
+		Keywords:    md.GetKeywords(),
+		Icon:        md.GetIcon(),
+		APIVersion:  md.GetApiVersion(),
+		Condition:   md.GetCondition(),
+		Tags:        md.GetTags(),
+		AppVersion:  md.GetAppVersion(),
+		Deprecated:  md.GetDeprecated(),
+		Annotations: md.GetAnnotations(),
+		KubeVersion: md.GetKubeVersion(),
+	}
+	if metadata.APIVersion == "" {
+		metadata.APIVersion = chartAPIVersion
+	}
+	for _, maintainer := range md.GetMaintainers() {
+		metadata.Maintainers = append(metadata.Maintainers, &chartMaintainer{
+			Name:  maintainer.GetName(),
+			Email: maintainer.GetEmail(),
+			URL:   maintainer.GetUrl(),
+		})
+	}
+	result := &recordChart{
+		Metadata: metadata,
+		Values:   values.AsMap(),
+	}
+	for _, template := range ch.GetTemplates() {
+		result.Templates = append(result.Templates, &chartFile{
+			Name: template.GetName(),
+			Data: template.GetData(),
+		})
+	}
+	for _, file := range ch.GetFiles() {
+		result.Files = append(result.Files, &chartFile{
+			Name: file.GetTypeUrl(),
+			Data: file.GetValue(),
+		})
+	}
+	return result, nil
+}
+
+// convertStatus converts the Helm 2 release status code.
+func convertStatus(code release.Status_Code) string {
+	switch code {
+	case release.Status_DEPLOYED:
+		return statusDeployed
+	case release.Status_DELETED:
+		return statusUninstalled
+	case release.Status_SUPERSEDED:
+		return statusSuperseded
+	case release.Status_FAILED:
+		return statusFailed
+	case release.Status_DELETING:
+		return statusUninstalling
+	case release.Status_PENDING_INSTALL:
+		return statusPendingInstall
+	case release.Status_PENDING_UPGRADE:
+		return statusPendingUpgrade
+	case release.Status_PENDING_ROLLBACK:
+		return statusPendingRollback
+	}
+	return statusUnknown
+}
+
+// convertHookEvent converts the Helm 2 hook event, e.g. PRE_INSTALL
+// becomes pre-install.
+func convertHookEvent(event release.Hook_Event) string {
+	switch event {
+	case release.Hook_RELEASE_TEST_SUCCESS:
+		return "test-success"
+	case release.Hook_RELEASE_TEST_FAILURE:
+		return "test-failure"
+	}
+	return strings.Replace(strings.ToLower(event.String()), "_", "-", -1)
+}
+
+// convertHookDeletePolicy converts the Helm 2 hook delete policy.
+func convertHookDeletePolicy(policy release.Hook_DeletePolicy) string {
+	switch policy {
+	case release.Hook_SUCCEEDED:
+		return hookSucceeded
+	case release.Hook_FAILED:
+		return hookFailed
+	}
+	return hookBeforeHookCreation
+}
+
+const (
+	// tillerSelector selects the ConfigMaps that keep Tiller releases.
+	tillerSelector = "OWNER=TILLER"
+	// chartAPIVersion is the API version of Helm 2 charts.
+	chartAPIVersion = "v1"
+)
@@ -0,0 +1,161 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helm
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"time"
+
+	"github.com/gravitational/gravity/lib/compare"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes/any"
+	check "gopkg.in/check.v1"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+	"k8s.io/helm/pkg/proto/hapi/release"
+	"k8s.io/helm/pkg/timeconv"
+)
+
+type MigrateSuite struct{}
+
+var _ = check.Suite(&MigrateSuite{})
+
+func (s *MigrateSuite) TestConvertsTillerRelease(c *check.C) {
+	deployed := time.Date(2019, time.March, 1, 10, 0, 0, 0, time.UTC)
+	rls := &release.Release{
+		Name:      "nginx",
+		Namespace: "web",
+		Version:   3,
+		Manifest:  "---\n# Source: nginx/templates/service.yaml\nkind: Service\n",
+		Chart: &chart.Chart{
+			Metadata: &chart.Metadata{
+				Name:        "nginx",
+				Version:     "1.2.0",
+				Maintainers: []*chart.Maintainer{{Name: "ops", Email: "ops@example.com"}},
+			},
+			Templates: []*chart.Template{{Name: "templates/service.yaml", Data: []byte("kind: Service")}},
+			Values:    &chart.Config{Raw: "replicas: 1\n"},
+			Files:     []*any.Any{{TypeUrl: "README.md", Value: []byte("nginx")}},
+		},
+		Config: &chart.Config{Raw: "replicas: 2\n"},
+		Info: &release.Info{
+			Status: &release.Status{
+				Code:  release.Status_SUPERSEDED,
+				Notes: "Visit http://nginx",
+			},
+			FirstDeployed: timeconv.Timestamp(deployed),
+			LastDeployed:  timeconv.Timestamp(deployed.Add(time.Hour)),
+			Description:   "Upgrade complete",
+		},
+		Hooks: []*release.Hook{{
+			Name:           "nginx-migrate",
+			Kind:           "Job",
+			Path:           "nginx/templates/migrate.yaml",
+			Manifest:       "kind: Job",
+			Events:         []release.Hook_Event{release.Hook_PRE_UPGRADE, release.Hook_RELEASE_TEST_SUCCESS},
+			DeletePolicies: []release.Hook_DeletePolicy{release.Hook_BEFORE_HOOK_CREATION},
+			Weight:         5,
+		}},
+	}
+	rec, err := decodeTillerRelease(encodeTillerRelease(c, rls))
+	c.Assert(err, check.IsNil)
+	converted, err := convertRelease(rec)
+	c.Assert(err, check.IsNil)
+	compare.DeepCompare(c, converted, &record{
+		Name:      "nginx",
+		Namespace: "web",
+		Version:   3,
+		Manifest:  "---\n# Source: nginx/templates/service.yaml\nkind: Service\n",
+		Chart: &recordChart{
+			Metadata: &chartMetadata{
+				Name:        "nginx",
+				Version:     "1.2.0",
+				APIVersion:  "v1",
+				Maintainers: []*chartMaintainer{{Name: "ops", Email: "ops@example.com"}},
+			},
+			Templates: []*chartFile{{Name: "templates/service.yaml", Data: []byte("kind: Service")}},
+			Values:    map[string]interface{}{"replicas": float64(1)},
+			Files:     []*chartFile{{Name: "README.md", Data: []byte("nginx")}},
+		},
+		Config: map[string]interface{}{"replicas": float64(2)},
+		Info: &recordInfo{
+			FirstDeployed: deployed,
+			LastDeployed:  deployed.Add(time.Hour),
+			Status:        statusSuperseded,
+			Notes:         "Visit http://nginx",
+			Description:   "Upgrade complete",
+		},
+		Hooks: []*recordHook{{
+			Name:           "nginx-migrate",
+			Kind:           "Job",
+			Path:           "nginx/templates/migrate.yaml",
+			Manifest:       "kind: Job",
+			Events:         []string{"pre-upgrade", "test-success"},
+			DeletePolicies: []string{"before-hook-creation"},
+			Weight:         5,
+		}},
+	})
+}
+
+func (s *MigrateSuite) TestStoresReleaseInSecret(c *check.C) {
+	rec := record{
+		Name:      "nginx",
+		Namespace: "web",
+		Version:   2,
+		Manifest:  "kind: Service",
+		Config:    map[string]interface{}{"replicas": float64(2)},
+		Info: &recordInfo{
+			LastDeployed: time.Date(2019, time.March, 1, 10, 0, 0, 0, time.UTC),
+			Status:       statusDeployed,
+		},
+	}
+	secret, err := newSecret(rec)
+	c.Assert(err, check.IsNil)
+	c.Assert(secret.Name, check.Equals, "sh.helm.release.v1.nginx.v2")
+	c.Assert(secret.Namespace, check.Equals, "web")
+	c.Assert(string(secret.Type), check.Equals, "helm.sh/release.v1")
+	c.Assert(secret.Labels, check.DeepEquals, map[string]string{
+		"name":    "nginx",
+		"owner":   "helm",
+		"status":  "deployed",
+		"version": "2",
+	})
+	decoded, err := decodeRecord(secret.Data[releaseKey])
+	c.Assert(err, check.IsNil)
+	compare.DeepCompare(c, decoded, &rec)
+	c.Assert(decoded.toRelease(), check.DeepEquals, &Release{
+		Name:      "nginx",
+		Namespace: "web",
+		Status:    statusDeployed,
+		Revision:  2,
+		Updated:   rec.Info.LastDeployed,
+	})
+}
+
+// encodeTillerRelease encodes the release the way Tiller does.
+func encodeTillerRelease(c *check.C, rls *release.Release) string {
+	data, err := proto.Marshal(rls)
+	c.Assert(err, check.IsNil)
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	_, err = w.Write(data)
+	c.Assert(err, check.IsNil)
+	c.Assert(w.Close(), check.IsNil)
+	return base64.StdEncoding.EncodeToString(buf.Bytes())
+}
@@ -0,0 +1,503 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helm
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/utils"
+	helmutils "github.com/gravitational/gravity/lib/utils/helm"
+
+	teleutils "github.com/gravitational/teleport/lib/utils"
+	"github.com/gravitational/trace"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/restmapper"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"k8s.io/helm/pkg/chartutil"
+	"k8s.io/helm/pkg/kube"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+	"k8s.io/helm/pkg/timeconv"
+)
+
+// secretClient is the Helm client that manages releases without Tiller.
+//
+// Releases are kept as Secrets in the release namespace in the Helm 3
+// storage format so they can be managed by Helm 3 as well.
+type secretClient struct {
+	logrus.FieldLogger
+	storage *secretStorage
+	kube    *kube.Client
+}
+
+// newSecretClient returns a new Helm client that manages releases
+// with the provided Kubernetes client.
+func newSecretClient(kubeClient kubernetes.Interface, kubeConfig *rest.Config) *secretClient {
+	logger := logrus.WithField(trace.Component, "helm")
+	client := kube.New(&restClientGetter{config: kubeConfig})
+	client.Log = logger.Debugf
+	return &secretClient{
+		FieldLogger: logger,
+		storage:     &secretStorage{client: kubeClient},
+		kube:        client,
+	}
+}
+
+// Install installs a Helm chart and returns release information.
+func (c *secretClient) Install(p InstallParameters) (*Release, error) {
+	rawVals, err := helmutils.Vals(p.Values, p.Set, nil, nil, "", "", "")
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	ch, err := chartutil.Load(p.Path)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	name := p.Name
+	if name == "" {
+		name, err = generateName(ch.GetMetadata().GetName())
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+	}
+	namespace := p.Namespace
+	if namespace == "" {
+		namespace = defaults.Namespace
+	}
+	version := 1
+	last, err := c.storage.last(name)
+	if err != nil && !trace.IsNotFound(err) {
+		return nil, trace.Wrap(err)
+	}
+	if last != nil {
+		if last.Info.Status != statusUninstalled {
+			return nil, trace.AlreadyExists("release %v already exists", name)
+		}
+		version = last.Version + 1
+	}
+	rec, err := newRecord(ch, rawVals, chartutil.ReleaseOptions{
+		Name:      name,
+		Namespace: namespace,
+		Revision:  version,
+		IsInstall: true,
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	rec.Info.Status = statusPendingInstall
+	rec.Info.Description = "Initial install underway"
+	if err := c.storage.create(*rec); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	err = c.deploy(rec, hookPreInstall, hookPostInstall, func() error {
+		return c.kube.Create(namespace, strings.NewReader(rec.Manifest),
+			timeoutSeconds, false)
+	})
+	if err != nil {
+		return nil, trace.Wrap(c.fail(rec, "Release %q failed", err))
+	}
+	rec.Info.Status = statusDeployed
+	rec.Info.Description = "Install complete"
+	if err := c.storage.update(*rec); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return rec.toRelease(), nil
+}
+
+// List returns list of releases matching provided parameters.
+func (c *secretClient) List(p ListParameters) ([]Release, error) {
+	var filter *regexp.Regexp
+	if p.Filter != "" {
+		var err error
+		filter, err = regexp.Compile(p.Filter)
+		if err != nil {
+			return nil, trace.BadParameter("invalid release filter %q: %v", p.Filter, err)
+		}
+	}
+	records, err := c.storage.list()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var releases []Release
+	for _, rec := range records {
+		if filter != nil && !filter.MatchString(rec.Name) {
+			continue
+		}
+		if !p.All && (rec.Info.Status == statusUninstalled || rec.Info.Status == statusSuperseded) {
+			continue
+		}
+		releases = append(releases, *(rec.toRelease()))
+	}
+	return releases, nil
+}
+
+// Get returns a single release with the specified name.
+func (c *secretClient) Get(name string) (*Release, error) {
+	return getRelease(c, name)
+}
+
+// Upgrade upgrades a release.
+func (c *secretClient) Upgrade(p UpgradeParameters) (*Release, error) {
+	rawVals, err := helmutils.Vals(p.Values, p.Set, nil, nil, "", "", "")
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	ch, err := chartutil.Load(p.Path)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	records, err := c.storage.history(p.Release)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	current := deployedRevision(records)
+	if current == nil {
+		return nil, trace.NotFound("release %v has no deployed revisions", p.Release)
+	}
+	rec, err := newRecord(ch, rawVals, chartutil.ReleaseOptions{
+		Name:      current.Name,
+		Namespace: current.Namespace,
+		Revision:  records[len(records)-1].Version + 1,
+		IsUpgrade: true,
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	rec.Info.FirstDeployed = current.Info.FirstDeployed
+	rec.Info.Status = statusPendingUpgrade
+	rec.Info.Description = "Preparing upgrade"
+	if err := c.storage.create(*rec); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	err = c.deploy(rec, hookPreUpgrade, hookPostUpgrade, func() error {
+		return c.kube.Update(rec.Namespace, strings.NewReader(current.Manifest),
+			strings.NewReader(rec.Manifest), false, false, timeoutSeconds, false)
+	})
+	if err != nil {
+		return nil, trace.Wrap(c.fail(rec, "Upgrade %q failed", err))
+	}
+	if err := c.supersede(*current); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	rec.Info.Status = statusDeployed
+	rec.Info.Description = "Upgrade complete"
+	if err := c.storage.update(*rec); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return rec.toRelease(), nil
+}
+
+// Rollback rolls back a release to the specified version.
+func (c *secretClient) Rollback(p RollbackParameters) (*Release, error) {
+	records, err := c.storage.history(p.Release)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	last := records[len(records)-1]
+	current := deployedRevision(records)
+	if current == nil {
+		current = &last
+	}
+	var target *record
+	for i := range records {
+		if records[i].Version == p.Revision {
+			target = &records[i]
+		}
+	}
+	if target == nil {
+		return nil, trace.NotFound("release %v has no revision %v", p.Release, p.Revision)
+	}
+	rec := record{
+		Name:      target.Name,
+		Namespace: target.Namespace,
+		Chart:     target.Chart,
+		Config:    target.Config,
+		Manifest:  target.Manifest,
+		Hooks:     target.Hooks,
+		Version:   last.Version + 1,
+		Info: &recordInfo{
+			FirstDeployed: current.Info.FirstDeployed,
+			LastDeployed:  time.Now().UTC(),
+			Status:        statusPendingRollback,
+			Notes:         target.Info.Notes,
+			Description:   fmt.Sprintf("Rollback to %v", target.Version),
+		},
+	}
+	if err := c.storage.create(rec); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	err = c.deploy(&rec, hookPreRollback, hookPostRollback, func() error {
+		return c.kube.Update(rec.Namespace, strings.NewReader(current.Manifest),
+			strings.NewReader(rec.Manifest), false, false, timeoutSeconds, false)
+	})
+	if err != nil {
+		return nil, trace.Wrap(c.fail(&rec, "Rollback %q failed", err))
+	}
+	if err := c.supersede(*current); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	rec.Info.Status = statusDeployed
+	if err := c.storage.update(rec); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return rec.toRelease(), nil
+}
+
+// Uninstall uninstalls a release with the provided name.
+//
+// As with Helm 3, the release history is removed as well.
+func (c *secretClient) Uninstall(name string) (*Release, error) {
+	records, err := c.storage.history(name)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	rec := records[len(records)-1]
+	if rec.Info.Status == statusUninstalled {
+		return nil, trace.NotFound("release %v not found", name)
+	}
+	rec.Info.Status = statusUninstalling
+	rec.Info.Deleted = time.Now().UTC()
+	if err := c.storage.update(rec); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	err = c.deploy(&rec, hookPreDelete, hookPostDelete, func() error {
+		return c.kube.Delete(rec.Namespace, strings.NewReader(rec.Manifest))
+	})
+	if err != nil {
+		return nil, trace.Wrap(c.fail(&rec, "Uninstallation of %q failed", err))
+	}
+	if err := c.storage.delete(records); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	rec.Info.Status = statusUninstalled
+	rec.Info.Description = "Uninstallation complete"
+	return rec.toRelease(), nil
+}
+
+// Revisions returns revision history for a release with the provided name.
+func (c *secretClient) Revisions(name string) ([]Release, error) {
+	records, err := c.storage.history(name)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if len(records) > maxHistory {
+		records = records[len(records)-maxHistory:]
+	}
+	releases := make([]Release, 0, len(records))
+	for _, rec := range records {
+		releases = append(releases, *(rec.toRelease()))
+	}
+	return releases, nil
+}
+
+// Close closes the Helm client.
+func (c *secretClient) Close() error {
+	return nil
+}
+
+// deployedRevision returns the latest deployed revision from the
+// release history or nil if there is none.
+func deployedRevision(records []record) *record {
+	for i := len(records) - 1; i >= 0; i-- {
+		if records[i].Info.Status == statusDeployed {
+			return &records[i]
+		}
+	}
+	return nil
+}
+
+// deploy executes the pre and post hooks of the specified events around fn.
+func (c *secretClient) deploy(rec *record, pre, post string, fn func() error) error {
+	if err := c.runHooks(rec, pre); err != nil {
+		return trace.Wrap(err)
+	}
+	if err := fn(); err != nil {
+		return trace.Wrap(err)
+	}
+	return trace.Wrap(c.runHooks(rec, post))
+}
+
+// runHooks executes the hooks of the revision triggered by the specified
+// event one by one in the order of their weight and waits for each hook
+// to complete.
+func (c *secretClient) runHooks(rec *record, event string) error {
+	for _, hook := range rec.Hooks {
+		if !utils.StringInSlice(hook.Events, event) {
+			continue
+		}
+		// Helm 3 deletes the previous hook resource unless the hook
+		// specifies other policies.
+		if len(hook.DeletePolicies) == 0 || utils.StringInSlice(hook.DeletePolicies, hookBeforeHookCreation) {
+			if err := c.deleteHook(rec.Namespace, hook); err != nil {
+				return trace.Wrap(err)
+			}
+		}
+		c.WithField("hook", hook.Path).Infof("Executing %v hook.", event)
+		hook.LastRun = hookExecution{StartedAt: time.Now().UTC(), Phase: hookPhaseRunning}
+		err := c.kube.Create(rec.Namespace, strings.NewReader(hook.Manifest), timeoutSeconds, false)
+		if err == nil {
+			err = c.kube.WatchUntilReady(rec.Namespace, strings.NewReader(hook.Manifest), timeoutSeconds, false)
+		}
+		hook.LastRun.CompletedAt = time.Now().UTC()
+		if err != nil {
+			hook.LastRun.Phase = hookPhaseFailed
+			if utils.StringInSlice(hook.DeletePolicies, hookFailed) {
+				if errDelete := c.deleteHook(rec.Namespace, hook); errDelete != nil {
+					c.WithError(errDelete).Warnf("Failed to delete hook %v.", hook.Path)
+				}
+			}
+			return trace.Wrap(err, "%v hook %v failed", event, hook.Path)
+		}
+		hook.LastRun.Phase = hookPhaseSucceeded
+		if utils.StringInSlice(hook.DeletePolicies, hookSucceeded) {
+			if err := c.deleteHook(rec.Namespace, hook); err != nil {
+				return trace.Wrap(err)
+			}
+		}
+	}
+	return nil
+}
+
+func (c *secretClient) deleteHook(namespace string, hook *recordHook) error {
+	err := c.kube.Delete(namespace, strings.NewReader(hook.Manifest))
+	if err != nil && !strings.Contains(err.Error(), "not found") {
+		return trace.Wrap(err, "failed to delete hook %v", hook.Path)
+	}
+	return nil
+}
+
+// supersede marks the revision as superseded by a newer one.
+func (c *secretClient) supersede(rec record) error {
+	rec.Info.Status = statusSuperseded
+	return trace.Wrap(c.storage.update(rec))
+}
+
+// fail marks the revision as failed and returns the original error.
+func (c *secretClient) fail(rec *record, format string, err error) error {
+	rec.Info.Status = statusFailed
+	rec.Info.Description = fmt.Sprintf(format+": %v", rec.Name, trace.UserMessage(err))
+	if errUpdate := c.storage.update(*rec); errUpdate != nil {
+		c.WithError(errUpdate).Warnf("Failed to update release %v.", rec.Name)
+	}
+	return trace.Wrap(err)
+}
+
+// newRecord renders the chart and returns a new revision of the release.
+func newRecord(ch *chart.Chart, rawVals []byte, options chartutil.ReleaseOptions) (*record, error) {
+	now := time.Now().UTC()
+	options.Time = timeconv.Timestamp(now)
+	rendered, err := renderChart(ch, rawVals, options)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	config, err := chartutil.ReadValues(rawVals)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	recordChart, err := convertChart(ch)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &record{
+		Name:      options.Name,
+		Namespace: options.Namespace,
+		Chart:     recordChart,
+		Config:    config.AsMap(),
+		Manifest:  rendered.Manifest,
+		Hooks:     rendered.Hooks,
+		Version:   options.Revision,
+		Info: &recordInfo{
+			FirstDeployed: now,
+			LastDeployed:  now,
+			Notes:         rendered.Notes,
+		},
+	}, nil
+}
+
+// generateName returns a random release name for the chart.
+func generateName(chartName string) (string, error) {
+	suffix, err := teleutils.CryptoRandomHex(4)
+	if err != nil {
+		return "", trace.Wrap(err)
+	}
+	return fmt.Sprintf("%v-%v", chartName, suffix), nil
+}
+
+// restClientGetter provides the Kubernetes client configuration
+// for the Helm Kubernetes client.
+type restClientGetter struct {
+	config *rest.Config
+}
+
+// ToRESTConfig returns the client configuration.
+func (g *restClientGetter) ToRESTConfig() (*rest.Config, error) {
+	return rest.CopyConfig(g.config), nil
+}
+
+// ToDiscoveryClient returns the discovery client.
+func (g *restClientGetter) ToDiscoveryClient() (discovery.CachedDiscoveryInterface, error) {
+	client, err := discovery.NewDiscoveryClientForConfig(rest.CopyConfig(g.config))
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return uncachedDiscovery{client}, nil
+}
+
+// ToRESTMapper returns the mapper of resource kinds to API resources.
+func (g *restClientGetter) ToRESTMapper() (meta.RESTMapper, error) {
+	client, err := g.ToDiscoveryClient()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	mapper := restmapper.NewDeferredDiscoveryRESTMapper(client)
+	return restmapper.NewShortcutExpander(mapper, client), nil
+}
+
+// ToRawKubeConfigLoader returns an empty kubeconfig loader since
+// the client configuration is not loaded from a kubeconfig.
+func (g *restClientGetter) ToRawKubeConfigLoader() clientcmd.ClientConfig {
+	return clientcmd.NewDefaultClientConfig(*clientcmdapi.NewConfig(), &clientcmd.ConfigOverrides{})
+}
+
+// uncachedDiscovery is the discovery client that always queries the API server.
+type uncachedDiscovery struct {
+	discovery.DiscoveryInterface
+}
+
+// Fresh returns true since the discovery information is never cached.
+func (uncachedDiscovery) Fresh() bool { return true }
+
+// Invalidate is a no-op since the discovery information is never cached.
+func (uncachedDiscovery) Invalidate() {}
+
+// Hook execution phases.
+const (
+	hookPhaseRunning   = "Running"
+	hookPhaseSucceeded = "Succeeded"
+	hookPhaseFailed    = "Failed"
+)
+
+// timeoutSeconds is how long to wait for resources and hooks in seconds.
+const timeoutSeconds = int64(defaults.HelmTimeout / time.Second)
@@ -0,0 +1,406 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helm
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gravitational/rigging"
+	"github.com/gravitational/trace"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+)
+
+// record is a release revision in the Helm 3 storage format.
+//
+// Only the fields Helm 3 needs to list, upgrade, roll back and
+// uninstall the release are kept.
+type record struct {
+	// Name is the release name.
+	Name string `json:"name,omitempty"`
+	// Info describes the state of the revision.
+	Info *recordInfo `json:"info,omitempty"`
+	// Chart is the chart the revision has been rendered from.
+	Chart *recordChart `json:"chart,omitempty"`
+	// Config is the set of values supplied by the user.
+	Config map[string]interface{} `json:"config,omitempty"`
+	// Manifest is the rendered chart without hooks.
+	Manifest string `json:"manifest,omitempty"`
+	// Hooks lists the rendered chart hooks.
+	Hooks []*recordHook `json:"hooks,omitempty"`
+	// Version is the revision number.
+	Version int `json:"version,omitempty"`
+	// Namespace is the release namespace.
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// recordInfo describes the state of a release revision.
+type recordInfo struct {
+	// FirstDeployed is when the release was first deployed.
+	FirstDeployed time.Time `json:"first_deployed,omitempty"`
+	// LastDeployed is when the revision was deployed.
+	LastDeployed time.Time `json:"last_deployed,omitempty"`
+	// Deleted is when the release was uninstalled.
+	Deleted time.Time `json:"deleted"`
+	// Description is a human-friendly description of the revision.
+	Description string `json:"description,omitempty"`
+	// Status is the revision status.
+	Status string `json:"status,omitempty"`
+	// Notes is the rendered NOTES.txt of the chart.
+	Notes string `json:"notes,omitempty"`
+}
+
+// recordChart is a chart in the Helm 3 storage format.
+type recordChart struct {
+	// Metadata is the contents of Chart.yaml.
+	Metadata *chartMetadata `json:"metadata"`
+	// Templates lists the chart templates.
+	Templates []*chartFile `json:"templates"`
+	// Values are the default chart values.
+	Values map[string]interface{} `json:"values"`
+	// Files lists the other chart files.
+	Files []*chartFile `json:"files"`
+}
+
+// chartMetadata is the contents of Chart.yaml.
+type chartMetadata struct {
+	Name        string             `json:"name,omitempty"`
+	Home        string             `json:"home,omitempty"`
+	Sources     []string           `json:"sources,omitempty"`
+	Version     string             `json:"version,omitempty"`
+	Description string             `json:"description,omitempty"`
+	Keywords    []string           `json:"keywords,omitempty"`
+	Maintainers []*chartMaintainer `json:"maintainers,omitempty"`
+	Icon        string             `json:"icon,omitempty"`
+	APIVersion  string             `json:"apiVersion,omitempty"`
+	Condition   string             `json:"condition,omitempty"`
+	Tags        string             `json:"tags,omitempty"`
+	AppVersion  string             `json:"appVersion,omitempty"`
+	Deprecated  bool               `json:"deprecated,omitempty"`
+	Annotations map[string]string  `json:"annotations,omitempty"`
+	KubeVersion string             `json:"kubeVersion,omitempty"`
+}
+
+// chartMaintainer describes a chart maintainer.
+type chartMaintainer struct {
+	Name  string `json:"name,omitempty"`
+	Email string `json:"email,omitempty"`
+	URL   string `json:"url,omitempty"`
+}
+
+// chartFile is a file of a chart.
+type chartFile struct {
+	// Name is the file path relative to the chart root.
+	Name string `json:"name"`
+	// Data is the file contents.
+	Data []byte `json:"data"`
+}
+
+// recordHook is a rendered chart hook.
+type recordHook struct {
+	// Name is the hook resource name.
+	Name string `json:"name,omitempty"`
+	// Kind is the hook resource kind.
+	Kind string `json:"kind,omitempty"`
+	// Path is the chart template the hook has been rendered from.
+	Path string `json:"path,omitempty"`
+	// Manifest is the rendered hook resource.
+	Manifest string `json:"manifest,omitempty"`
+	// Events lists the events that trigger the hook.
+	Events []string `json:"events,omitempty"`
+	// LastRun describes the last execution of the hook.
+	LastRun hookExecution `json:"last_run,omitempty"`
+	// Weight orders hooks triggered by the same event.
+	Weight int `json:"weight,omitempty"`
+	// DeletePolicies specifies when the hook resource is deleted.
+	DeletePolicies []string `json:"delete_policies,omitempty"`
+}
+
+// hookExecution describes an execution of a hook.
+type hookExecution struct {
+	// StartedAt is when the hook has been started.
+	StartedAt time.Time `json:"started_at,omitempty"`
+	// CompletedAt is when the hook has completed.
+	CompletedAt time.Time `json:"completed_at,omitempty"`
+	// Phase is the outcome of the execution.
+	Phase string `json:"phase"`
+}
+
+// toRelease converts the revision to Release.
+func (r record) toRelease() *Release {
+	var chart string
+	if r.Chart != nil && r.Chart.Metadata != nil {
+		chart = fmt.Sprintf("%s-%s", r.Chart.Metadata.Name, r.Chart.Metadata.Version)
+	}
+	release := &Release{
+		Name:      r.Name,
+		Chart:     chart,
+		Namespace: r.Namespace,
+		Revision:  r.Version,
+	}
+	if r.Info != nil {
+		release.Status = r.Info.Status
+		release.Updated = r.Info.LastDeployed
+		release.Description = r.Info.Description
+	}
+	return release
+}
+
+// encodeRecord encodes the revision the way Helm 3 does:
+// as base64-encoded gzipped JSON.
+func encodeRecord(r record) ([]byte, error) {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var buf bytes.Buffer
+	w, err := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if _, err := w.Write(data); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if err := w.Close(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	encoded := make([]byte, base64.StdEncoding.EncodedLen(buf.Len()))
+	base64.StdEncoding.Encode(encoded, buf.Bytes())
+	return encoded, nil
+}
+
+// decodeRecord decodes the revision encoded with encodeRecord.
+func decodeRecord(encoded []byte) (*record, error) {
+	data, err := decodeCompressed(encoded)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var r record
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &r, nil
+}
+
+// decodeCompressed decodes base64-encoded data that is gzipped by
+// both Helm 2 and Helm 3 unless compression did not pay off.
+func decodeCompressed(encoded []byte) ([]byte, error) {
+	data := make([]byte, base64.StdEncoding.DecodedLen(len(encoded)))
+	n, err := base64.StdEncoding.Decode(data, encoded)
+	if err != nil {
+		return nil, trace.BadParameter("invalid release encoding: %v", err)
+	}
+	data = data[:n]
+	if !bytes.HasPrefix(data, gzipMagic) {
+		return data, nil
+	}
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	defer r.Close()
+	data, err = ioutil.ReadAll(r)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return data, nil
+}
+
+// newSecret returns the Secret that stores the revision.
+func newSecret(r record) (*v1.Secret, error) {
+	data, err := encodeRecord(r)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      secretName(r.Name, r.Version),
+			Namespace: r.Namespace,
+			Labels: map[string]string{
+				labelName:    r.Name,
+				labelOwner:   ownerHelm,
+				labelStatus:  r.Info.Status,
+				labelVersion: strconv.Itoa(r.Version),
+			},
+		},
+		Type: secretType,
+		Data: map[string][]byte{releaseKey: data},
+	}, nil
+}
+
+// secretName returns the name of the Secret that stores the revision.
+func secretName(name string, version int) string {
+	return fmt.Sprintf("%v.%v.v%v", secretPrefix, name, version)
+}
+
+// secretStorage keeps releases as Secrets in the release namespace.
+type secretStorage struct {
+	client kubernetes.Interface
+}
+
+// history returns all revisions of the release with the specified name
+// ordered by revision number.
+func (s *secretStorage) history(name string) ([]record, error) {
+	records, err := s.query(map[string]string{
+		labelName:  name,
+		labelOwner: ownerHelm,
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if len(records) == 0 {
+		return nil, trace.NotFound("release %v not found", name)
+	}
+	for _, r := range records {
+		if r.Namespace != records[0].Namespace {
+			return nil, trace.BadParameter("release %v exists in namespaces %v and %v",
+				name, records[0].Namespace, r.Namespace)
+		}
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].Version < records[j].Version
+	})
+	return records, nil
+}
+
+// last returns the latest revision of the release with the specified name.
+func (s *secretStorage) last(name string) (*record, error) {
+	records, err := s.history(name)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &records[len(records)-1], nil
+}
+
+// list returns the latest revision of each release.
+func (s *secretStorage) list() ([]record, error) {
+	records, err := s.query(map[string]string{labelOwner: ownerHelm})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	latest := make(map[string]record)
+	for _, r := range records {
+		key := fmt.Sprintf("%v/%v", r.Namespace, r.Name)
+		if existing, ok := latest[key]; !ok || existing.Version < r.Version {
+			latest[key] = r
+		}
+	}
+	result := make([]record, 0, len(latest))
+	for _, r := range latest {
+		result = append(result, r)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result, nil
+}
+
+// create stores a new revision.
+func (s *secretStorage) create(r record) error {
+	secret, err := newSecret(r)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	_, err = s.client.CoreV1().Secrets(r.Namespace).Create(secret)
+	return rigging.ConvertError(err)
+}
+
+// update updates an existing revision.
+func (s *secretStorage) update(r record) error {
+	secret, err := newSecret(r)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	_, err = s.client.CoreV1().Secrets(r.Namespace).Update(secret)
+	return rigging.ConvertError(err)
+}
+
+// delete removes all revisions of the release.
+func (s *secretStorage) delete(records []record) error {
+	for _, r := range records {
+		err := rigging.ConvertError(s.client.CoreV1().Secrets(r.Namespace).Delete(
+			secretName(r.Name, r.Version), &metav1.DeleteOptions{}))
+		if err != nil && !trace.IsNotFound(err) {
+			return trace.Wrap(err)
+		}
+	}
+	return nil
+}
+
+func (s *secretStorage) query(selector map[string]string) ([]record, error) {
+	secrets, err := s.client.CoreV1().Secrets(metav1.NamespaceAll).List(metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(labels.Set(selector)).String(),
+	})
+	if err != nil {
+		return nil, rigging.ConvertError(err)
+	}
+	records := make([]record, 0, len(secrets.Items))
+	for _, secret := range secrets.Items {
+		if secret.Type != secretType {
+			continue
+		}
+		r, err := decodeRecord(secret.Data[releaseKey])
+		if err != nil {
+			return nil, trace.Wrap(err, "failed to decode release %v/%v",
+				secret.Namespace, secret.Name)
+		}
+		records = append(records, *r)
+	}
+	return records, nil
+}
+
+const (
+	// secretType is the type of Secrets that store releases.
+	secretType = "helm.sh/release.v1"
+	// secretPrefix is the name prefix of Secrets that store releases.
+	secretPrefix = "sh.helm.release.v1"
+	// releaseKey is the key of the encoded release in the Secret data.
+	releaseKey = "release"
+	// ownerHelm is the owner label value of releases managed by Helm 3.
+	ownerHelm = "helm"
+
+	labelName    = "name"
+	labelOwner   = "owner"
+	labelStatus  = "status"
+	labelVersion = "version"
+)
+
+// Helm 3 release statuses.
+const (
+	statusUnknown         = "unknown"
+	statusDeployed        = "deployed"
+	statusUninstalled     = "uninstalled"
+	statusSuperseded      = "superseded"
+	statusFailed          = "failed"
+	statusUninstalling    = "uninstalling"
+	statusPendingInstall  = "pending-install"
+	statusPendingUpgrade  = "pending-upgrade"
+	statusPendingRollback = "pending-rollback"
+)
+
+// gzipMagic is the header of gzipped data.
+var gzipMagic = []byte{0x1f, 0x8b, 0x08}
@@ -0,0 +1,189 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hub
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/gravitational/gravity/lib/defaults"
+
+	teleutils "github.com/gravitational/teleport/lib/utils"
+	"github.com/gravitational/trace"
+	"github.com/sirupsen/logrus"
+)
+
+// DirectoryConfig is the configuration of the hub stored in a local directory
+type DirectoryConfig struct {
+	// Dir is the hub root directory, the index file is expected at <Dir>/index.yaml
+	Dir string
+	// FieldLogger is used for logging
+	logrus.FieldLogger
+}
+
+// CheckAndSetDefaults validates config and sets defaults
+func (c *DirectoryConfig) CheckAndSetDefaults() error {
+	if c.Dir == "" {
+		return trace.BadParameter("missing parameter Dir")
+	}
+	if c.FieldLogger == nil {
+		c.FieldLogger = logrus.WithField(trace.Component, "dirhub")
+	}
+	return nil
+}
+
+// NewDirectory returns a new hub stored in a local directory
+func NewDirectory(config DirectoryConfig) (Hub, error) {
+	if err := config.CheckAndSetDefaults(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &remoteHub{
+		FieldLogger: config.FieldLogger,
+		backend:     directoryBackend(config.Dir),
+	}, nil
+}
+
+// directoryBackend provides access to the hub objects stored in a local directory
+type directoryBackend string
+
+// Get returns the contents of the object at the specified path
+func (b directoryBackend) Get(path string) (io.ReadCloser, error) {
+	f, err := os.Open(b.path(path))
+	if err != nil {
+		return nil, trace.ConvertSystemError(err)
+	}
+	return f, nil
+}
+
+// Download writes the contents of the object at the specified path into the provided file
+func (b directoryBackend) Download(f *os.File, path string) (int64, error) {
+	object, err := b.Get(path)
+	if err != nil {
+		return 0, trace.Wrap(err)
+	}
+	defer object.Close()
+	n, err := io.Copy(f, object)
+	if err != nil {
+		return n, trace.ConvertSystemError(err)
+	}
+	return n, nil
+}
+
+// Put replaces the object at the specified path with the provided data.
+// The data is written into a temporary file first which is then renamed
+// so the object is replaced atomically
+func (b directoryBackend) Put(path string, data io.Reader) error {
+	objectPath := b.path(path)
+	if err := os.MkdirAll(filepath.Dir(objectPath), defaults.SharedDirMask); err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	f, err := ioutil.TempFile(filepath.Dir(objectPath), "."+filepath.Base(objectPath))
+	if err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	defer os.Remove(f.Name())
+	_, err = io.Copy(f, data)
+	if err == nil {
+		err = f.Sync()
+	}
+	if errClose := f.Close(); err == nil {
+		err = errClose
+	}
+	if err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	if err := os.Chmod(f.Name(), defaults.SharedReadMask); err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	return trace.ConvertSystemError(os.Rename(f.Name(), objectPath))
+}
+
+// Read returns the contents of the object at the specified path.
+// The revision is the checksum of the contents
+func (b directoryBackend) Read(path string) (data []byte, revision string, err error) {
+	data, err = ioutil.ReadFile(b.path(path))
+	if err != nil {
+		return nil, "", trace.ConvertSystemError(err)
+	}
+	return data, checksum(data), nil
+}
+
+// PutIf replaces the object at the specified path with the provided data
+// if the object has not been modified since it was read at the specified revision.
+// Conditional updates are serialized with a file lock in the hub directory
+func (b directoryBackend) PutIf(path string, data []byte, revision string) error {
+	unlock, err := b.lock()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	defer unlock()
+	_, current, err := b.Read(path)
+	if err != nil && !trace.IsNotFound(err) {
+		return trace.Wrap(err)
+	}
+	if current != revision {
+		return trace.CompareFailed("%v has been modified concurrently", path)
+	}
+	return trace.Wrap(b.Put(path, bytes.NewReader(data)))
+}
+
+// lock acquires the exclusive lock of the hub directory
+func (b directoryBackend) lock() (unlock func(), err error) {
+	if err := os.MkdirAll(string(b), defaults.SharedDirMask); err != nil {
+		return nil, trace.ConvertSystemError(err)
+	}
+	f, err := os.OpenFile(b.path(lockFileName), os.O_CREATE|os.O_RDWR, defaults.SharedReadMask)
+	if err != nil {
+		return nil, trace.ConvertSystemError(err)
+	}
+	if err := teleutils.FSWriteLock(f); err != nil {
+		f.Close()
+		return nil, trace.Wrap(err)
+	}
+	return func() {
+		teleutils.FSUnlock(f)
+		f.Close()
+	}, nil
+}
+
+// Delete removes the object at the specified path
+func (b directoryBackend) Delete(path string) error {
+	return trace.ConvertSystemError(os.Remove(b.path(path)))
+}
+
+// String returns the hub directory
+func (b directoryBackend) String() string {
+	return string(b)
+}
+
+func (b directoryBackend) path(objectPath string) string {
+	return filepath.Join(string(b), filepath.FromSlash(objectPath))
+}
+
+// checksum returns the hex-encoded sha256 checksum of the provided data
+func checksum(data []byte) string {
+	return fmt.Sprintf("%x", sha256.Sum256(data))
+}
+
+// lockFileName is the name of the file that serializes conditional
+// updates of the hub stored in a local directory
+const lockFileName = ".lock"
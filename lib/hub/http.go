@@ -0,0 +1,194 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hub
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/gravitational/gravity/lib/httplib"
+
+	"github.com/gravitational/trace"
+	"github.com/sirupsen/logrus"
+)
+
+// HTTPConfig is the configuration of the hub served by a web server
+type HTTPConfig struct {
+	// URL is the base URL of the hub, the index file is expected at <URL>/index.yaml
+	URL string
+	// Client is the optional HTTP client
+	Client *http.Client
+	// FieldLogger is used for logging
+	logrus.FieldLogger
+}
+
+// CheckAndSetDefaults validates config and sets defaults
+func (c *HTTPConfig) CheckAndSetDefaults() error {
+	if c.URL == "" {
+		return trace.BadParameter("missing parameter URL")
+	}
+	if c.Client == nil {
+		c.Client = httplib.GetClient(false)
+	}
+	if c.FieldLogger == nil {
+		c.FieldLogger = logrus.WithField(trace.Component, "httphub")
+	}
+	return nil
+}
+
+// NewHTTP returns a new hub served by a web server
+func NewHTTP(config HTTPConfig) (Hub, error) {
+	if err := config.CheckAndSetDefaults(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	baseURL, err := url.Parse(strings.TrimSuffix(config.URL, "/") + "/")
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &remoteHub{
+		FieldLogger: config.FieldLogger,
+		backend: &httpBackend{
+			client:  config.Client,
+			baseURL: *baseURL,
+		},
+	}, nil
+}
+
+// httpBackend provides access to the hub objects served by a web server
+type httpBackend struct {
+	client  *http.Client
+	baseURL url.URL
+}
+
+// Get returns the contents of the object at the specified path
+func (b *httpBackend) Get(path string) (io.ReadCloser, error) {
+	resp, err := b.do(http.MethodGet, path, nil, nil)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return resp.Body, nil
+}
+
+// Download writes the contents of the object at the specified path into the provided file
+func (b *httpBackend) Download(f *os.File, path string) (int64, error) {
+	body, err := b.Get(path)
+	if err != nil {
+		return 0, trace.Wrap(err)
+	}
+	defer body.Close()
+	n, err := io.Copy(f, body)
+	if err != nil {
+		return n, trace.ConvertSystemError(err)
+	}
+	return n, nil
+}
+
+// Put uploads the provided data to the specified path with an HTTP PUT request.
+// The web server must support uploads, for example with WebDAV
+func (b *httpBackend) Put(path string, data io.Reader) error {
+	resp, err := b.do(http.MethodPut, path, data, nil)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return resp.Body.Close()
+}
+
+// Read returns the contents of the object at the specified path.
+// The revision is the entity tag returned by the web server.
+// Returns trace.NotImplemented if the web server does not return entity tags
+func (b *httpBackend) Read(path string) (data []byte, revision string, err error) {
+	resp, err := b.do(http.MethodGet, path, nil, nil)
+	if err != nil {
+		return nil, "", trace.Wrap(err)
+	}
+	defer resp.Body.Close()
+	data, err = ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", trace.ConvertSystemError(err)
+	}
+	revision = resp.Header.Get("ETag")
+	if revision == "" {
+		return nil, "", trace.NotImplemented("web server at %v does not return the ETag header for %v, "+
+			"publishing requires a web server that supports conditional requests", b, path)
+	}
+	return data, revision, nil
+}
+
+// PutIf uploads the provided data to the specified path with an HTTP PUT request
+// conditional on the entity tag of the object (If-Match) or, if the revision is empty,
+// on the absence of the object (If-None-Match).
+// The web server responds with 412 Precondition Failed if the condition does not hold
+func (b *httpBackend) PutIf(path string, data []byte, revision string) error {
+	header := make(http.Header)
+	if revision != "" {
+		header.Set("If-Match", revision)
+	} else {
+		header.Set("If-None-Match", "*")
+	}
+	resp, err := b.do(http.MethodPut, path, bytes.NewReader(data), header)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return resp.Body.Close()
+}
+
+// Delete removes the object at the specified path with an HTTP DELETE request
+func (b *httpBackend) Delete(path string) error {
+	resp, err := b.do(http.MethodDelete, path, nil, nil)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return resp.Body.Close()
+}
+
+// do sends the request with the specified method for the object at the provided path.
+// Non-successful responses are converted to errors
+func (b *httpBackend) do(method, path string, body io.Reader, header http.Header) (*http.Response, error) {
+	objectURL, err := b.baseURL.Parse(path)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	req, err := http.NewRequest(method, objectURL.String(), body)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return nil, trace.ConvertSystemError(err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		defer resp.Body.Close()
+		body, _ := ioutil.ReadAll(resp.Body)
+		return nil, trace.ReadError(resp.StatusCode, body)
+	}
+	return resp, nil
+}
+
+// String returns the hub URL without credentials
+func (b *httpBackend) String() string {
+	u := b.baseURL
+	u.User = nil
+	return u.String()
+}
@@ -22,13 +22,13 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"net/url"
 	"os"
-	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/gravitational/gravity/lib/constants"
-	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/httplib"
 	"github.com/gravitational/gravity/lib/loc"
 	"github.com/gravitational/gravity/lib/utils"
 
@@ -38,19 +38,13 @@ import (
 	"github.com/gravitational/trace"
 	"github.com/sirupsen/logrus"
 	"k8s.io/helm/pkg/repo"
-
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/credentials"
-	"github.com/aws/aws-sdk-go/aws/session"
-	"github.com/aws/aws-sdk-go/service/s3"
-	"github.com/aws/aws-sdk-go/service/s3/s3iface"
-	"github.com/aws/aws-sdk-go/service/s3/s3manager"
 )
 
 // Hub defines an interface for the hub that stores Telekube application installers
 //
-// The current hub implementation is backed by S3 and all application installers
-// are stored in the bucket of the following structure:
+// The hub can be backed by an S3 bucket, a web server or a local directory
+// (see NewFromURL) and all application installers are stored in the following
+// structure (shown for the default public hub S3 bucket):
 //
 // hub.gravitational.io
 // ∟ gravity
@@ -78,6 +72,8 @@ type Hub interface {
 	Get(loc.Locator) (io.ReadCloser, error)
 	// GetLatestVersion returns latest version of the specified application
 	GetLatestVersion(name string) (string, error)
+	// Push publishes the application installer to the hub
+	Push(PushRequest) error
 }
 
 // App represents a single application item in the hub
@@ -96,69 +92,92 @@ type App struct {
 	Type string `json:"type"`
 }
 
-// s3Hub is the S3-backed hub implementation
-type s3Hub struct {
-	// Config is the hub configuration
-	Config
-	// downloader is the S3 download manager
-	downloader *s3manager.Downloader
-}
-
-// Config is the S3-backed hub configuration
-type Config struct {
-	// Bucket is the S3 bucket name
-	Bucket string
-	// Prefix is the S3 path prefix
-	Prefix string
-	// Region is the S3 region
-	Region string
-	// FieldLogger is used for logging
-	logrus.FieldLogger
-	// S3 is optional S3 API client
-	S3 s3iface.S3API
-}
-
-// CheckAndSetDefaults validates config and sets defaults
-func (c *Config) CheckAndSetDefaults() error {
-	if c.Bucket == "" {
-		c.Bucket = defaults.HubBucket
-	}
-	if c.Prefix == "" {
-		c.Prefix = defaults.HubTelekubePrefix
-	}
-	if c.Region == "" {
-		c.Region = defaults.AWSRegion
+// NewFromURL returns a new hub for the specified URL. The URL scheme selects
+// the hub backend.
+//
+// s3://bucket/prefix selects an S3 or S3-compatible bucket. Optional region
+// and endpoint query parameters specify the bucket region and the endpoint
+// of an S3-compatible store, such as MinIO. Credentials are taken from the URL
+// user info if specified, or from the AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY
+// environment variables, otherwise the bucket is accessed anonymously.
+//
+// http:// and https:// select a web server that serves the hub index file and
+// installers, for example a Helm-style chart repository. Basic authentication
+// credentials can be provided as URL user info.
+//
+// file:// or a plain path selects a local directory.
+//
+// Publishing applications with Push requires the backend to support conditional
+// writes of the index file. S3 buckets must honor the If-Match and If-None-Match
+// headers of PUT requests (AWS S3 and MinIO do). Web servers must accept PUT and
+// DELETE requests, return the ETag header and honor If-Match and If-None-Match,
+// for example Apache httpd with mod_dav. Local directories are always supported.
+//
+// The default public hub is returned if the URL is empty
+func NewFromURL(hubURL string, insecure bool) (Hub, error) {
+	if hubURL == "" {
+		return New(Config{})
 	}
-	if c.FieldLogger == nil {
-		c.FieldLogger = logrus.WithField(trace.Component, "s3hub")
+	u, err := url.Parse(hubURL)
+	if err != nil {
+		return nil, trace.Wrap(err, "failed to parse hub URL %q", hubURL)
 	}
-	if c.S3 == nil {
-		session, err := session.NewSession(&aws.Config{
-			Region:      aws.String(c.Region),
-			Credentials: credentials.AnonymousCredentials,
-		})
+	switch u.Scheme {
+	case schemeS3:
+		config, err := parseS3URL(*u)
 		if err != nil {
-			return trace.Wrap(err)
+			return nil, trace.Wrap(err)
 		}
-		c.S3 = s3.New(session)
+		return New(*config)
+	case schemeHTTP, schemeHTTPS:
+		return NewHTTP(HTTPConfig{
+			URL:    hubURL,
+			Client: httplib.GetClient(insecure),
+		})
+	case schemeFile:
+		return NewDirectory(DirectoryConfig{Dir: u.Path})
+	case "":
+		return NewDirectory(DirectoryConfig{Dir: hubURL})
 	}
-	return nil
+	return nil, trace.BadParameter("unsupported hub URL scheme %q, supported are: %v",
+		u.Scheme, strings.Join([]string{schemeS3, schemeHTTP, schemeHTTPS, schemeFile}, ", "))
 }
 
-// New returns a new S3-backed hub instance
-func New(config Config) (*s3Hub, error) {
-	err := config.CheckAndSetDefaults()
-	if err != nil {
-		return nil, trace.Wrap(err)
-	}
-	return &s3Hub{
-		Config:     config,
-		downloader: s3manager.NewDownloaderWithClient(config.S3),
-	}, nil
+// backend provides access to the objects stored in the hub.
+// Object paths are relative to the hub root and use forward slashes
+type backend interface {
+	// Get returns the contents of the object at the specified path
+	Get(path string) (io.ReadCloser, error)
+	// Download writes the contents of the object at the specified path into
+	// the provided file and returns the number of bytes written
+	Download(f *os.File, path string) (int64, error)
+	// Put replaces the object at the specified path with the provided data.
+	// Readers observe either the old or the new contents of the object
+	Put(path string, data io.Reader) error
+	// Read returns the contents of the object at the specified path along with
+	// the revision of the contents to replace the object conditionally with PutIf
+	Read(path string) (data []byte, revision string, err error)
+	// PutIf replaces the object at the specified path with the provided data
+	// only if the object is still at the specified revision. Empty revision
+	// requires that the object does not exist.
+	// Returns trace.CompareFailed if the object has been modified concurrently
+	PutIf(path string, data []byte, revision string) error
+	// Delete removes the object at the specified path
+	Delete(path string) error
+	// String returns the description of the backend location
+	String() string
+}
+
+// remoteHub implements the hub on top of the provided backend
+type remoteHub struct {
+	// FieldLogger is used for logging
+	logrus.FieldLogger
+	// backend provides access to the hub objects
+	backend backend
 }
 
 // List returns a list of applications in the hub
-func (h *s3Hub) List(withPrereleases bool) (items []App, err error) {
+func (h *remoteHub) List(withPrereleases bool) (items []App, err error) {
 	indexFile, err := h.getIndexFile()
 	if err != nil {
 		return nil, trace.Wrap(err)
@@ -178,7 +197,7 @@ func (h *s3Hub) List(withPrereleases bool) (items []App, err error) {
 }
 
 // Downloads downloads the specified application installer into provided file
-func (h *s3Hub) Download(f *os.File, locator loc.Locator, progress utils.Progress) (err error) {
+func (h *remoteHub) Download(f *os.File, locator loc.Locator, progress utils.Progress) (err error) {
 	version := locator.Version
 	// in case the provided version is a special 'latest' or 'stable' label,
 	// we need to look into respective bucket to find out the actual version
@@ -193,15 +212,11 @@ func (h *s3Hub) Download(f *os.File, locator loc.Locator, progress utils.Progres
 	}
 	progress.NextStep(fmt.Sprintf("Downloading %v:%v", locator.Name, locator.Version))
 	h.Infof("Downloading: %v.", h.appPath(locator.Name, locator.Version))
-	n, err := h.downloader.Download(f, &s3.GetObjectInput{
-		Bucket: aws.String(h.Bucket),
-		Key:    aws.String(h.appPath(locator.Name, locator.Version)),
-	})
+	n, err := h.backend.Download(f, h.appPath(locator.Name, locator.Version))
 	if err != nil {
-		err := utils.ConvertS3Error(err)
 		if trace.IsNotFound(err) {
 			return trace.NotFound("application %v:%v not found in %v, use 'tele ls' to see available applications",
-				locator.Name, locator.Version, h.Bucket)
+				locator.Name, locator.Version, h.backend)
 		}
 		return trace.Wrap(err)
 	}
@@ -213,7 +228,7 @@ func (h *s3Hub) Download(f *os.File, locator loc.Locator, progress utils.Progres
 }
 
 // Get returns application installer tarball of the specified version
-func (h *s3Hub) Get(locator loc.Locator) (io.ReadCloser, error) {
+func (h *remoteHub) Get(locator loc.Locator) (io.ReadCloser, error) {
 	tarFile, err := ioutil.TempFile("", locator.Name)
 	if err != nil {
 		return nil, trace.Wrap(err)
@@ -231,20 +246,22 @@ func (h *s3Hub) Get(locator loc.Locator) (io.ReadCloser, error) {
 		readCloser.Close()
 		return nil, trace.Wrap(err)
 	}
+	// Backends that stream the installer advance the file offset
+	if _, err := tarFile.Seek(0, io.SeekStart); err != nil {
+		readCloser.Close()
+		return nil, trace.ConvertSystemError(err)
+	}
 	return readCloser, nil
 }
 
 // getIndexFile returns the hub's index file.
-func (h *s3Hub) getIndexFile() (*repo.IndexFile, error) {
-	object, err := h.S3.GetObject(&s3.GetObjectInput{
-		Bucket: aws.String(h.Bucket),
-		Key:    aws.String(filepath.Join(h.Prefix, indexFileName)),
-	})
+func (h *remoteHub) getIndexFile() (*repo.IndexFile, error) {
+	object, err := h.backend.Get(indexFileName)
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
-	defer object.Body.Close()
-	bytes, err := ioutil.ReadAll(object.Body)
+	defer object.Close()
+	bytes, err := ioutil.ReadAll(object)
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
@@ -257,27 +274,33 @@ func (h *s3Hub) getIndexFile() (*repo.IndexFile, error) {
 }
 
 // appsBucket returns sub-bucket where all applications are stored
-func (h *s3Hub) appsBucket() string {
-	return fmt.Sprintf("%v/app", h.Prefix)
+func (h *remoteHub) appsBucket() string {
+	return "app"
 }
 
 // appBucket returns sub-bucket where the specified application is stored
-func (h *s3Hub) appBucket(name, version string) string {
+func (h *remoteHub) appBucket(name, version string) string {
 	return fmt.Sprintf("%v/%v/%v/linux/x86_64", h.appsBucket(), name, version)
 }
 
+// channelPath returns path to the specified application in the sub-bucket
+// of the specified channel, such as latest or stable
+func (h *remoteHub) channelPath(channel, name, version string) string {
+	return fmt.Sprintf("%v/%v/%v/linux/x86_64/%v", h.appsBucket(), name, channel, makeFilename(name, version))
+}
+
 // appBucketPath returns path to the specified application in the hub
-func (h *s3Hub) appPath(name, version string) string {
+func (h *remoteHub) appPath(name, version string) string {
 	return fmt.Sprintf("%v/%v", h.appBucket(name, version), makeFilename(name, version))
 }
 
 // shaPath returns path to the checksum file of the specified application in the hub
-func (h *s3Hub) shaPath(name, version string) string {
+func (h *remoteHub) shaPath(name, version string) string {
 	return h.appPath(name, version) + ".sha256"
 }
 
 // GetLatestVersion returns the latest version of the specified application in the hub
-func (h *s3Hub) GetLatestVersion(name string) (string, error) {
+func (h *remoteHub) GetLatestVersion(name string) (string, error) {
 	indexFile, err := h.getIndexFile()
 	if err != nil {
 		return "", trace.Wrap(err)
@@ -286,24 +309,15 @@ func (h *s3Hub) GetLatestVersion(name string) (string, error) {
 	if len(versions) == 0 {
 		return "", trace.NotFound("image %q not found", name)
 	}
-	latestVersion, err := semver.NewVersion(versions[0].Version)
+	latestVersion, err := latestVersion(versions)
 	if err != nil {
 		return "", trace.Wrap(err)
 	}
-	for _, version := range versions {
-		nextVersion, err := semver.NewVersion(version.Version)
-		if err != nil {
-			return "", trace.Wrap(err)
-		}
-		if latestVersion.LessThan(*nextVersion) {
-			latestVersion = nextVersion
-		}
-	}
 	return latestVersion.String(), nil
 }
 
 // getStableVersion returns the stable version of the specified application in the hub
-func (h *s3Hub) getStableVersion(name string) (string, error) {
+func (h *remoteHub) getStableVersion(name string) (string, error) {
 	indexFile, err := h.getIndexFile()
 	if err != nil {
 		return "", trace.Wrap(err)
@@ -312,11 +326,37 @@ func (h *s3Hub) getStableVersion(name string) (string, error) {
 	if !ok || len(versions) == 0 {
 		return "", trace.NotFound("image %q not found", name)
 	}
-	var stableVersion *semver.Version
+	stableVersion, err := stableVersion(versions)
+	if err != nil {
+		return "", trace.Wrap(err)
+	}
+	if stableVersion == nil {
+		return "", trace.NotFound("no stable version of image %q found", name)
+	}
+	return stableVersion.String(), nil
+}
+
+// latestVersion returns the latest of the provided versions
+func latestVersion(versions repo.ChartVersions) (latestVersion *semver.Version, err error) {
 	for _, version := range versions {
 		nextVersion, err := semver.NewVersion(version.Version)
 		if err != nil {
-			return "", trace.Wrap(err)
+			return nil, trace.Wrap(err)
+		}
+		if latestVersion == nil || latestVersion.LessThan(*nextVersion) {
+			latestVersion = nextVersion
+		}
+	}
+	return latestVersion, nil
+}
+
+// stableVersion returns the latest of the provided versions that is not
+// a pre-release or nil, if there's no such version
+func stableVersion(versions repo.ChartVersions) (stableVersion *semver.Version, err error) {
+	for _, version := range versions {
+		nextVersion, err := semver.NewVersion(version.Version)
+		if err != nil {
+			return nil, trace.Wrap(err)
 		}
 		if nextVersion.PreRelease != "" {
 			continue
@@ -325,14 +365,11 @@ func (h *s3Hub) getStableVersion(name string) (string, error) {
 			stableVersion = nextVersion
 		}
 	}
-	if stableVersion == nil {
-		return "", trace.NotFound("no stable version of image %q found", name)
-	}
-	return stableVersion.String(), nil
+	return stableVersion, nil
 }
 
 // verifyChecksum verifies the checksum of the downloaded installer file
-func (h *s3Hub) verifyChecksum(name, version, path string) error {
+func (h *remoteHub) verifyChecksum(name, version, path string) error {
 	storedChecksum, err := h.getChecksum(name, version)
 	if err != nil {
 		return trace.Wrap(err)
@@ -356,19 +393,16 @@ func (h *s3Hub) verifyChecksum(name, version, path string) error {
 }
 
 // getChecksum fetches the sha256 checksum of the specified application from the hub
-func (h *s3Hub) getChecksum(name, version string) (string, error) {
-	object, err := h.S3.GetObject(&s3.GetObjectInput{
-		Bucket: aws.String(h.Bucket),
-		Key:    aws.String(h.shaPath(name, version)),
-	})
+func (h *remoteHub) getChecksum(name, version string) (string, error) {
+	object, err := h.backend.Get(h.shaPath(name, version))
 	if err != nil {
 		return "", trace.Wrap(err)
 	}
-	defer object.Body.Close()
+	defer object.Close()
 	// even though the file should only contain the checksum, read in only
 	// first 64 bytes (checksum block size) to be on a safe side
 	checksum := make([]byte, sha256.BlockSize)
-	n, err := bufio.NewReader(object.Body).Read(checksum)
+	n, err := bufio.NewReader(object).Read(checksum)
 	if err != nil {
 		return "", trace.Wrap(err)
 	}
@@ -388,4 +422,9 @@ func makeFilename(name, version string) string {
 const (
 	// indexFileName is the repository index file name.
 	indexFileName = "index.yaml"
+
+	schemeS3    = "s3"
+	schemeHTTP  = "http"
+	schemeHTTPS = "https"
+	schemeFile  = "file"
 )
@@ -17,7 +17,16 @@ limitations under the License.
 package hub
 
 import (
+	"fmt"
+	"io"
 	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -25,7 +34,9 @@ import (
 	"github.com/gravitational/gravity/lib/loc"
 	"github.com/gravitational/gravity/lib/testutils"
 
+	"github.com/gravitational/trace"
 	check "gopkg.in/check.v1"
+	"k8s.io/helm/pkg/proto/hapi/chart"
 )
 
 func TestHub(t *testing.T) { check.TestingT(t) }
@@ -50,6 +61,74 @@ func (s *HubSuite) SetUpSuite(c *check.C) {
 	s.hub = hub
 }
 
+type DirectoryHubSuite struct {
+	HubSuite
+}
+
+var _ = check.Suite(&DirectoryHubSuite{})
+
+func (s *DirectoryHubSuite) SetUpSuite(c *check.C) {
+	dir := c.MkDir()
+	writeHub(c, dir)
+	hub, err := NewFromURL("file://"+dir, false)
+	c.Assert(err, check.IsNil)
+	s.hub = hub
+}
+
+type HTTPHubSuite struct {
+	HubSuite
+	server *httptest.Server
+}
+
+var _ = check.Suite(&HTTPHubSuite{})
+
+func (s *HTTPHubSuite) SetUpSuite(c *check.C) {
+	dir := c.MkDir()
+	writeHub(c, dir)
+	s.server = httptest.NewServer(http.StripPrefix("/hub", http.FileServer(http.Dir(dir))))
+	hub, err := NewFromURL(s.server.URL+"/hub", false)
+	c.Assert(err, check.IsNil)
+	s.hub = hub
+}
+
+func (s *HTTPHubSuite) TearDownSuite(c *check.C) {
+	s.server.Close()
+}
+
+func (s *HTTPHubSuite) TestNotFound(c *check.C) {
+	_, err := s.hub.Get(loc.Locator{
+		Repository: defaults.SystemAccountOrg,
+		Name:       defaults.TelekubePackage,
+		Version:    "3.0.0",
+	})
+	c.Assert(trace.IsNotFound(err), check.Equals, true, check.Commentf("%v", err))
+}
+
+// TestRequiresEntityTagsToPush verifies that the web servers that do not support
+// conditional requests are rejected for publishing
+func (s *HTTPHubSuite) TestRequiresEntityTagsToPush(c *check.C) {
+	err := s.hub.Push(newPushRequest(c, app1))
+	c.Assert(trace.IsNotImplemented(err), check.Equals, true, check.Commentf("%v", err))
+}
+
+func (s *HubSuite) TestParsesS3URL(c *check.C) {
+	u, err := url.Parse("s3://key:secret@mirror/gravity/oss?region=us-west-2&endpoint=https://minio.example.com:9000")
+	c.Assert(err, check.IsNil)
+	config, err := parseS3URL(*u)
+	c.Assert(err, check.IsNil)
+	c.Assert(config.Bucket, check.Equals, "mirror")
+	c.Assert(config.Prefix, check.Equals, "gravity/oss")
+	c.Assert(config.Region, check.Equals, "us-west-2")
+	c.Assert(config.Endpoint, check.Equals, "https://minio.example.com:9000")
+	creds, err := config.Credentials.Get()
+	c.Assert(err, check.IsNil)
+	c.Assert(creds.AccessKeyID, check.Equals, "key")
+	c.Assert(creds.SecretAccessKey, check.Equals, "secret")
+
+	_, err = NewFromURL("ftp://mirror", false)
+	c.Assert(trace.IsBadParameter(err), check.Equals, true)
+}
+
 func (s *HubSuite) TestList(c *check.C) {
 	apps, err := s.hub.List(true)
 	c.Assert(err, check.IsNil)
@@ -99,6 +178,252 @@ func (s *HubSuite) TestDownloadLatest(c *check.C) {
 	c.Assert(bytes, check.DeepEquals, app2.Data)
 }
 
+type PushSuite struct {
+	dir string
+	hub Hub
+}
+
+var _ = check.Suite(&PushSuite{})
+
+func (s *PushSuite) SetUpTest(c *check.C) {
+	s.dir = c.MkDir()
+	hub, err := NewFromURL(s.dir, false)
+	c.Assert(err, check.IsNil)
+	s.hub = hub
+}
+
+func (s *PushSuite) TestPushesApplications(c *check.C) {
+	s.push(c, app1, false)
+	s.assertApp(c, loc.LatestVersion, app1)
+	s.assertApp(c, loc.StableVersion, app1)
+
+	s.push(c, app2, false)
+	s.assertApp(c, app1.Version, app1)
+	s.assertApp(c, loc.LatestVersion, app2)
+	s.assertApp(c, loc.StableVersion, app1)
+
+	apps, err := s.hub.List(true)
+	c.Assert(err, check.IsNil)
+	for i := range apps {
+		apps[i].Created = time.Time{}
+	}
+	// index file entries are sorted with the most recent version first
+	c.Assert(apps, check.DeepEquals, []App{toHubApp(app2), toHubApp(app1)})
+
+	checksum, err := ioutil.ReadFile(filepath.Join(s.dir, "app", app2.Name, app2.Version,
+		"linux", "x86_64", makeFilename(app2.Name, app2.Version)+".sha256"))
+	c.Assert(err, check.IsNil)
+	c.Assert(string(checksum), check.Equals, app2.Checksum)
+
+	// the previous latest version is removed from the channel
+	_, err = os.Stat(filepath.Join(s.dir, "app", app1.Name, channelLatest,
+		"linux", "x86_64", makeFilename(app1.Name, app1.Version)))
+	c.Assert(os.IsNotExist(err), check.Equals, true, check.Commentf("%v", err))
+}
+
+// TestRetriesConcurrentIndexUpdates verifies that a push does not lose
+// the application published concurrently by another push
+func (s *PushSuite) TestRetriesConcurrentIndexUpdates(c *check.C) {
+	hub := s.hub.(*remoteHub)
+	concurrent, err := NewFromURL(s.dir, false)
+	c.Assert(err, check.IsNil)
+	hub.backend = &racingBackend{
+		backend: hub.backend,
+		race: func() {
+			c.Assert(concurrent.Push(newPushRequest(c, app1)), check.IsNil)
+		},
+	}
+
+	s.push(c, app2, false)
+
+	apps, err := s.hub.List(true)
+	c.Assert(err, check.IsNil)
+	for i := range apps {
+		apps[i].Created = time.Time{}
+	}
+	c.Assert(apps, check.DeepEquals, []App{toHubApp(app2), toHubApp(app1)})
+	s.assertApp(c, loc.LatestVersion, app2)
+	s.assertApp(c, loc.StableVersion, app1)
+}
+
+// TestKeepsMostRecentVersionInChannel verifies that the channel only keeps
+// the most recent version when a newer version is published while
+// the channel is being updated
+func (s *PushSuite) TestKeepsMostRecentVersionInChannel(c *check.C) {
+	s.push(c, app1, false)
+	hub := s.hub.(*remoteHub)
+	concurrent, err := NewFromURL(s.dir, false)
+	c.Assert(err, check.IsNil)
+	hub.backend = &racingBackend{
+		backend: hub.backend,
+		path:    hub.channelPath(channelLatest, app2.Name, app2.Version),
+		race: func() {
+			c.Assert(concurrent.Push(newPushRequest(c, app3)), check.IsNil)
+		},
+	}
+
+	s.push(c, app2, false)
+
+	s.assertApp(c, loc.LatestVersion, app3)
+	s.assertApp(c, loc.StableVersion, app1)
+	files, err := ioutil.ReadDir(filepath.Join(s.dir, "app", app3.Name, channelLatest, "linux", "x86_64"))
+	c.Assert(err, check.IsNil)
+	var names []string
+	for _, file := range files {
+		names = append(names, file.Name())
+	}
+	filename := makeFilename(app3.Name, app3.Version)
+	c.Assert(names, check.DeepEquals, []string{filename, filename + ".sha256"})
+}
+
+// TestPushesToWebServer verifies publishing to a web server
+// that supports conditional requests
+func (s *PushSuite) TestPushesToWebServer(c *check.C) {
+	server := httptest.NewServer(newWebDAVServer())
+	defer server.Close()
+	hub, err := NewFromURL(server.URL, false)
+	c.Assert(err, check.IsNil)
+	s.hub = hub
+
+	s.push(c, app1, false)
+	s.push(c, app2, false)
+	s.assertApp(c, loc.LatestVersion, app2)
+	s.assertApp(c, loc.StableVersion, app1)
+
+	backend := hub.(*remoteHub).backend
+	_, revision, err := backend.Read(indexFileName)
+	c.Assert(err, check.IsNil)
+	err = backend.PutIf(indexFileName, []byte("{}"), revision+"stale")
+	c.Assert(trace.IsCompareFailed(err), check.Equals, true, check.Commentf("%v", err))
+	err = backend.PutIf(indexFileName, []byte("{}"), "")
+	c.Assert(trace.IsCompareFailed(err), check.Equals, true, check.Commentf("%v", err))
+}
+
+func (s *PushSuite) TestRefusesToOverwrite(c *check.C) {
+	s.push(c, app1, false)
+	err := s.hub.Push(newPushRequest(c, app1))
+	c.Assert(trace.IsAlreadyExists(err), check.Equals, true, check.Commentf("%v", err))
+
+	updated := app1
+	updated.Data = []byte("version 1 (updated)")
+	s.push(c, updated, true)
+	s.assertApp(c, app1.Version, updated)
+}
+
+func (s *PushSuite) push(c *check.C, app testutils.S3App, force bool) {
+	req := newPushRequest(c, app)
+	req.Force = force
+	c.Assert(s.hub.Push(req), check.IsNil)
+}
+
+func newPushRequest(c *check.C, app testutils.S3App) PushRequest {
+	path := filepath.Join(c.MkDir(), makeFilename(app.Name, app.Version))
+	c.Assert(ioutil.WriteFile(path, app.Data, defaults.SharedReadMask), check.IsNil)
+	return PushRequest{
+		Path: path,
+		Metadata: &chart.Metadata{
+			Name:    app.Name,
+			Version: app.Version,
+		},
+	}
+}
+
+func (s *PushSuite) assertApp(c *check.C, version string, app testutils.S3App) {
+	reader, err := s.hub.Get(loc.Locator{
+		Repository: defaults.SystemAccountOrg,
+		Name:       app.Name,
+		Version:    version,
+	})
+	c.Assert(err, check.IsNil)
+	defer reader.Close()
+	bytes, err := ioutil.ReadAll(reader)
+	c.Assert(err, check.IsNil)
+	c.Assert(bytes, check.DeepEquals, app.Data)
+}
+
+// racingBackend runs the race function before the first update of the object
+// at the specified path (the index file by default) to simulate a concurrent update
+type racingBackend struct {
+	backend
+	path string
+	race func()
+	once sync.Once
+}
+
+func (b *racingBackend) Put(path string, data io.Reader) error {
+	if path == b.path {
+		b.once.Do(b.race)
+	}
+	return b.backend.Put(path, data)
+}
+
+func (b *racingBackend) PutIf(path string, data []byte, revision string) error {
+	if path == b.path || (b.path == "" && path == indexFileName) {
+		b.once.Do(b.race)
+	}
+	return b.backend.PutIf(path, data, revision)
+}
+
+// webDAVServer is an in-memory web server that accepts uploads
+// and supports conditional requests
+type webDAVServer struct {
+	sync.Mutex
+	objects map[string][]byte
+}
+
+func newWebDAVServer() *webDAVServer {
+	return &webDAVServer{objects: make(map[string][]byte)}
+}
+
+func (s *webDAVServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.Lock()
+	defer s.Unlock()
+	data, exists := s.objects[r.URL.Path]
+	etag := fmt.Sprintf("%q", checksum(data))
+	switch r.Method {
+	case http.MethodGet:
+		if !exists {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		w.Write(data)
+	case http.MethodPut:
+		if match := r.Header.Get("If-Match"); match != "" && (!exists || match != etag) {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+		if r.Header.Get("If-None-Match") == "*" && exists {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+		var buf strings.Builder
+		io.Copy(&buf, r.Body)
+		s.objects[r.URL.Path] = []byte(buf.String())
+		w.WriteHeader(http.StatusCreated)
+	case http.MethodDelete:
+		if !exists {
+			http.NotFound(w, r)
+			return
+		}
+		delete(s.objects, r.URL.Path)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// writeHub writes the test hub contents into the specified directory
+func writeHub(c *check.C, dir string) {
+	s3 := testutils.NewS3()
+	s3.Add(c, app1, testutils.WithStable())
+	s3.Add(c, app2, testutils.WithLatest())
+	for key, object := range s3.Objects {
+		path := filepath.Join(dir, strings.TrimPrefix(key, defaults.HubTelekubePrefix+"/"))
+		c.Assert(os.MkdirAll(filepath.Dir(path), defaults.SharedDirMask), check.IsNil)
+		c.Assert(ioutil.WriteFile(path, object.Data, defaults.SharedReadMask), check.IsNil)
+	}
+}
+
 func toHubApp(s3App testutils.S3App) App {
 	return App{
 		Name:    s3App.Name,
@@ -121,4 +446,10 @@ var (
 		Data:     []byte("version 2 (latest)"),
 		Checksum: "5c99c4996ac2f6d7eb12420f908fc0897360de6011f458716f36e3f14898777e",
 	}
+	app3 = testutils.S3App{
+		Name:    defaults.TelekubePackage,
+		Version: "3.0.0-alpha.1",
+		Created: time.Now(),
+		Data:    []byte("version 3 (latest)"),
+	}
 )
@@ -0,0 +1,298 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hub
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/gravitational/gravity/lib/helm"
+	"github.com/gravitational/gravity/lib/utils"
+
+	"github.com/coreos/go-semver/semver"
+	"github.com/ghodss/yaml"
+	"github.com/gravitational/trace"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+	"k8s.io/helm/pkg/repo"
+)
+
+// PushRequest describes a request to publish an application installer
+type PushRequest struct {
+	// Path is the path to the installer tarball
+	Path string
+	// Metadata describes the application, see helm.GenerateChartMetadata
+	Metadata *chart.Metadata
+	// Force allows to overwrite an already published version
+	Force bool
+	// Progress is used to report the push progress
+	Progress utils.Progress
+}
+
+// CheckAndSetDefaults validates the request and sets defaults
+func (r *PushRequest) CheckAndSetDefaults() error {
+	if r.Path == "" {
+		return trace.BadParameter("missing parameter Path")
+	}
+	if r.Metadata == nil {
+		return trace.BadParameter("missing parameter Metadata")
+	}
+	if r.Metadata.Name == "" {
+		return trace.BadParameter("missing application name")
+	}
+	if _, err := semver.NewVersion(r.Metadata.Version); err != nil {
+		return trace.BadParameter("invalid application version %q: %v", r.Metadata.Version, err)
+	}
+	if r.Progress == nil {
+		r.Progress = utils.NewNopProgress()
+	}
+	return nil
+}
+
+// Push publishes the application installer to the hub.
+//
+// The installer and its checksum are uploaded first, then the application
+// is added to the index file and finally the latest and stable channels
+// are updated if the application becomes their most recent version.
+// Every object is replaced atomically, so readers never observe partially
+// published applications.
+//
+// The index file is updated with a conditional write and the update is retried
+// if another push modified the index file concurrently, so concurrent pushes
+// do not lose each other's entries. The channels follow the index file:
+// after the channel has been updated, the index file is read again and
+// the installer is removed from the channel if a newer version has been
+// published in the meantime
+func (h *remoteHub) Push(req PushRequest) error {
+	if err := req.CheckAndSetDefaults(); err != nil {
+		return trace.Wrap(err)
+	}
+	name, version := req.Metadata.Name, req.Metadata.Version
+	// Fail early before uploading the installer
+	indexFile, _, err := h.readIndexFile()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if err := checkPush(indexFile, req); err != nil {
+		return trace.Wrap(err)
+	}
+	checksum, err := fileChecksum(req.Path)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	req.Progress.NextStep(fmt.Sprintf("Uploading %v:%v", name, version))
+	if err := h.upload(req.Path, h.appPath(name, version)); err != nil {
+		return trace.Wrap(err)
+	}
+	if err := h.backend.Put(h.shaPath(name, version), strings.NewReader(checksum)); err != nil {
+		return trace.Wrap(err)
+	}
+
+	req.Progress.NextStep("Updating index file")
+	channels, newChannels, err := h.updateIndexFile(req, checksum)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	var updated []string
+	for _, channel := range []string{channelLatest, channelStable} {
+		if newChannels[channel] != version {
+			continue
+		}
+		req.Progress.NextStep(fmt.Sprintf("Updating %v channel", channel))
+		if err := h.updateChannel(channel, name, version, checksum, req.Path); err != nil {
+			return trace.Wrap(err)
+		}
+		if previous := channels[channel]; previous != "" && previous != version {
+			h.removeFromChannel(channel, name, previous)
+		}
+		updated = append(updated, channel)
+	}
+	if len(updated) != 0 {
+		if err := h.reconcileChannels(updated, name, version); err != nil {
+			return trace.Wrap(err)
+		}
+	}
+	h.Infof("Published %v:%v to %v.", name, version, h.backend)
+	return nil
+}
+
+// readIndexFile returns the hub's index file and its revision.
+// Returns an empty index file if the hub does not have one yet
+func (h *remoteHub) readIndexFile() (indexFile *repo.IndexFile, revision string, err error) {
+	data, revision, err := h.backend.Read(indexFileName)
+	if err != nil && !trace.IsNotFound(err) {
+		return nil, "", trace.Wrap(err)
+	}
+	indexFile = repo.NewIndexFile()
+	if err := yaml.Unmarshal(data, indexFile); err != nil {
+		return nil, "", trace.Wrap(err)
+	}
+	if indexFile.Entries == nil {
+		indexFile.Entries = make(map[string]repo.ChartVersions)
+	}
+	return indexFile, revision, nil
+}
+
+// updateIndexFile adds the application to the index file and returns
+// the versions in the channels before and after the update.
+// The update is retried if the index file has been modified concurrently
+func (h *remoteHub) updateIndexFile(req PushRequest, checksum string) (channels, newChannels map[string]string, err error) {
+	name, version := req.Metadata.Name, req.Metadata.Version
+	for attempt := 1; ; attempt++ {
+		indexFile, revision, err := h.readIndexFile()
+		if err != nil {
+			return nil, nil, trace.Wrap(err)
+		}
+		if err := checkPush(indexFile, req); err != nil {
+			return nil, nil, trace.Wrap(err)
+		}
+		channels, err := getChannels(indexFile.Entries[name])
+		if err != nil {
+			return nil, nil, trace.Wrap(err)
+		}
+		update := repo.NewIndexFile()
+		update.Add(req.Metadata, makeFilename(name, version), h.appBucket(name, version),
+			fmt.Sprintf("sha256:%v", checksum))
+		helm.MergeIndexFile(indexFile, update, true)
+		data, err := yaml.Marshal(indexFile)
+		if err != nil {
+			return nil, nil, trace.Wrap(err)
+		}
+		err = h.backend.PutIf(indexFileName, data, revision)
+		if err == nil {
+			newChannels, err := getChannels(indexFile.Entries[name])
+			if err != nil {
+				return nil, nil, trace.Wrap(err)
+			}
+			return channels, newChannels, nil
+		}
+		if !trace.IsCompareFailed(err) || attempt >= indexUpdateAttempts {
+			return nil, nil, trace.Wrap(err)
+		}
+		h.Infof("Index file has been modified concurrently, retrying (attempt %v).", attempt)
+	}
+}
+
+// reconcileChannels removes the specified application version from the provided
+// channels if a newer version has been published to the channel concurrently
+func (h *remoteHub) reconcileChannels(channels []string, name, version string) error {
+	indexFile, _, err := h.readIndexFile()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	current, err := getChannels(indexFile.Entries[name])
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	for _, channel := range channels {
+		if current[channel] != version {
+			h.Infof("Version %v has been published to the %v channel concurrently.", current[channel], channel)
+			h.removeFromChannel(channel, name, version)
+		}
+	}
+	return nil
+}
+
+// checkPush returns an error if the application is already published
+// in the specified index file and the request does not allow to overwrite it
+func checkPush(indexFile *repo.IndexFile, req PushRequest) error {
+	name, version := req.Metadata.Name, req.Metadata.Version
+	if indexFile.Has(name, version) && !req.Force {
+		return trace.AlreadyExists("application %v:%v is already published, "+
+			"provide --force flag to overwrite it", name, version)
+	}
+	return nil
+}
+
+// upload uploads the file at the specified local path to the hub
+func (h *remoteHub) upload(localPath, path string) error {
+	f, err := os.Open(localPath)
+	if err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	defer f.Close()
+	return trace.Wrap(h.backend.Put(path, f))
+}
+
+// updateChannel puts the application installer into the specified channel
+func (h *remoteHub) updateChannel(channel, name, version, checksum, localPath string) error {
+	path := h.channelPath(channel, name, version)
+	if err := h.upload(localPath, path); err != nil {
+		return trace.Wrap(err)
+	}
+	return trace.Wrap(h.backend.Put(path+".sha256", strings.NewReader(checksum)))
+}
+
+// removeFromChannel removes the previous application version from the
+// specified channel. Failures are logged and otherwise ignored since
+// the stale installer is no longer referenced
+func (h *remoteHub) removeFromChannel(channel, name, version string) {
+	path := h.channelPath(channel, name, version)
+	for _, path := range []string{path, path + ".sha256"} {
+		if err := h.backend.Delete(path); err != nil && !trace.IsNotFound(err) {
+			h.Warnf("Failed to remove %v: %v.", path, err)
+		}
+	}
+}
+
+// getChannels returns the latest and stable versions among the provided versions
+func getChannels(versions repo.ChartVersions) (map[string]string, error) {
+	channels := make(map[string]string)
+	latest, err := latestVersion(versions)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if latest != nil {
+		channels[channelLatest] = latest.String()
+	}
+	stable, err := stableVersion(versions)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if stable != nil {
+		channels[channelStable] = stable.String()
+	}
+	return channels, nil
+}
+
+// fileChecksum returns the hex-encoded sha256 checksum of the specified file
+func fileChecksum(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", trace.ConvertSystemError(err)
+	}
+	defer f.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return "", trace.ConvertSystemError(err)
+	}
+	return fmt.Sprintf("%x", hash.Sum(nil)), nil
+}
+
+const (
+	// indexUpdateAttempts is the maximum number of attempts to update
+	// the index file modified concurrently
+	indexUpdateAttempts = 10
+	// channelLatest is the channel with the latest application version
+	channelLatest = "latest"
+	// channelStable is the channel with the latest stable application version
+	channelStable = "stable"
+)
@@ -0,0 +1,236 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hub
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/utils"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/gravitational/trace"
+	"github.com/sirupsen/logrus"
+)
+
+// Config is the S3-backed hub configuration
+type Config struct {
+	// Bucket is the S3 bucket name
+	Bucket string
+	// Prefix is the S3 path prefix.
+	// Defaults to the Telekube prefix for the default bucket
+	Prefix string
+	// Region is the S3 region
+	Region string
+	// Endpoint is the optional endpoint of an S3-compatible store, such as MinIO.
+	// AWS S3 is used if not set
+	Endpoint string
+	// Credentials are the optional credentials to access the bucket with.
+	// The bucket is accessed anonymously if not set
+	Credentials *credentials.Credentials
+	// FieldLogger is used for logging
+	logrus.FieldLogger
+	// S3 is optional S3 API client
+	S3 s3iface.S3API
+}
+
+// CheckAndSetDefaults validates config and sets defaults
+func (c *Config) CheckAndSetDefaults() error {
+	if c.Bucket == "" {
+		c.Bucket = defaults.HubBucket
+	}
+	if c.Prefix == "" && c.Bucket == defaults.HubBucket {
+		c.Prefix = defaults.HubTelekubePrefix
+	}
+	if c.Region == "" {
+		c.Region = defaults.AWSRegion
+	}
+	if c.Credentials == nil {
+		c.Credentials = credentials.AnonymousCredentials
+	}
+	if c.FieldLogger == nil {
+		c.FieldLogger = logrus.WithField(trace.Component, "s3hub")
+	}
+	if c.S3 == nil {
+		config := &aws.Config{
+			Region:      aws.String(c.Region),
+			Credentials: c.Credentials,
+		}
+		if c.Endpoint != "" {
+			// S3-compatible stores commonly do not support
+			// virtual-hosted style bucket addressing
+			config.Endpoint = aws.String(c.Endpoint)
+			config.S3ForcePathStyle = aws.Bool(true)
+		}
+		session, err := session.NewSession(config)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		c.S3 = s3.New(session)
+	}
+	return nil
+}
+
+// New returns a new S3-backed hub instance
+func New(config Config) (Hub, error) {
+	err := config.CheckAndSetDefaults()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &remoteHub{
+		FieldLogger: config.FieldLogger,
+		backend: &s3Backend{
+			Config:     config,
+			downloader: s3manager.NewDownloaderWithClient(config.S3),
+			uploader:   s3manager.NewUploaderWithClient(config.S3),
+		},
+	}, nil
+}
+
+// s3Backend provides access to the hub objects stored in an S3 bucket
+type s3Backend struct {
+	// Config is the hub configuration
+	Config
+	// downloader is the S3 download manager
+	downloader *s3manager.Downloader
+	// uploader is the S3 upload manager
+	uploader *s3manager.Uploader
+}
+
+// Get returns the contents of the object at the specified path
+func (b *s3Backend) Get(path string) (io.ReadCloser, error) {
+	object, err := b.S3.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String(b.Bucket),
+		Key:    aws.String(b.key(path)),
+	})
+	if err != nil {
+		return nil, trace.Wrap(utils.ConvertS3Error(err))
+	}
+	return object.Body, nil
+}
+
+// Download writes the contents of the object at the specified path into the provided file
+func (b *s3Backend) Download(f *os.File, path string) (int64, error) {
+	n, err := b.downloader.Download(f, &s3.GetObjectInput{
+		Bucket: aws.String(b.Bucket),
+		Key:    aws.String(b.key(path)),
+	})
+	if err != nil {
+		return 0, trace.Wrap(utils.ConvertS3Error(err))
+	}
+	return n, nil
+}
+
+// Put replaces the object at the specified path with the provided data
+func (b *s3Backend) Put(path string, data io.Reader) error {
+	_, err := b.uploader.Upload(&s3manager.UploadInput{
+		Bucket: aws.String(b.Bucket),
+		Key:    aws.String(b.key(path)),
+		Body:   data,
+	})
+	return trace.Wrap(utils.ConvertS3Error(err))
+}
+
+// Read returns the contents of the object at the specified path.
+// The revision is the entity tag of the object
+func (b *s3Backend) Read(path string) (data []byte, revision string, err error) {
+	object, err := b.S3.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String(b.Bucket),
+		Key:    aws.String(b.key(path)),
+	})
+	if err != nil {
+		return nil, "", trace.Wrap(utils.ConvertS3Error(err))
+	}
+	defer object.Body.Close()
+	data, err = ioutil.ReadAll(object.Body)
+	if err != nil {
+		return nil, "", trace.Wrap(err)
+	}
+	return data, aws.StringValue(object.ETag), nil
+}
+
+// PutIf replaces the object at the specified path with the provided data
+// with a conditional write: the request carries the If-Match header with
+// the entity tag of the object or, if the revision is empty, If-None-Match.
+// The store must support conditional writes, as AWS S3 and MinIO do
+func (b *s3Backend) PutIf(path string, data []byte, revision string) error {
+	_, err := b.S3.PutObjectWithContext(context.TODO(), &s3.PutObjectInput{
+		Bucket: aws.String(b.Bucket),
+		Key:    aws.String(b.key(path)),
+		Body:   bytes.NewReader(data),
+	}, func(r *request.Request) {
+		if revision != "" {
+			r.HTTPRequest.Header.Set("If-Match", revision)
+		} else {
+			r.HTTPRequest.Header.Set("If-None-Match", "*")
+		}
+	})
+	return trace.Wrap(utils.ConvertS3Error(err))
+}
+
+// Delete removes the object at the specified path
+func (b *s3Backend) Delete(path string) error {
+	_, err := b.S3.DeleteObject(&s3.DeleteObjectInput{
+		Bucket: aws.String(b.Bucket),
+		Key:    aws.String(b.key(path)),
+	})
+	return trace.Wrap(utils.ConvertS3Error(err))
+}
+
+// String returns the bucket name
+func (b *s3Backend) String() string {
+	return b.Bucket
+}
+
+// key returns the S3 key of the object at the specified path
+func (b *s3Backend) key(objectPath string) string {
+	return path.Join(b.Prefix, objectPath)
+}
+
+// parseS3URL returns the S3 hub configuration from the provided URL
+// in the s3://[access-key:secret-key@]bucket/prefix?region=region&endpoint=endpoint format
+func parseS3URL(u url.URL) (*Config, error) {
+	if u.Host == "" {
+		return nil, trace.BadParameter("missing bucket name in S3 hub URL")
+	}
+	config := Config{
+		Bucket:   u.Host,
+		Prefix:   strings.Trim(u.Path, "/"),
+		Region:   u.Query().Get("region"),
+		Endpoint: u.Query().Get("endpoint"),
+	}
+	if u.User != nil {
+		secret, _ := u.User.Password()
+		config.Credentials = credentials.NewStaticCredentials(u.User.Username(), secret, "")
+	} else if _, err := credentials.NewEnvCredentials().Get(); err == nil {
+		config.Credentials = credentials.NewEnvCredentials()
+	}
+	return &config, nil
+}
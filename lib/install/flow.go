@@ -327,6 +327,7 @@ func (i *Installer) GetFSM() (*fsm.FSM, error) {
 		Insecure:           i.Insecure,
 		UserLogFile:        i.UserLogFile,
 		ReportProgress:     true,
+		Parallelism:        i.Parallelism,
 	})
 }
 
@@ -82,6 +82,8 @@ type FSMConfig struct {
 	ReportProgress bool
 	// DNSConfig specifies the DNS configuration to use
 	DNSConfig storage.DNSConfig
+	// Parallelism specifies the maximum number of phases to execute concurrently
+	Parallelism int
 }
 
 // Check validates install FSM config and sets some defaults
@@ -148,10 +150,11 @@ func NewFSM(config FSMConfig) (*fsm.FSM, error) {
 	}
 	runner := fsm.NewAgentRunner(config.Credentials)
 	fsm, err := fsm.New(fsm.Config{
-		Engine:   engine,
-		Runner:   runner,
-		Insecure: config.Insecure,
-		Logger:   logger,
+		Engine:      engine,
+		Runner:      runner,
+		Insecure:    config.Insecure,
+		Logger:      logger,
+		Parallelism: config.Parallelism,
 	})
 	if err != nil {
 		return nil, trace.Wrap(err)
@@ -249,6 +252,9 @@ func (f *fsmEngine) GetExecutor(p fsm.ExecutorParams, remote fsm.Remote) (fsm.Ph
 // RunCommand executes the phase specified by params on the specified server
 // using the provided runner
 func (f *fsmEngine) RunCommand(ctx context.Context, runner fsm.RemoteRunner, server storage.Server, p fsm.Params) error {
+	if p.Rollback {
+		return trace.NotImplemented("remote rollback is not supported for install operation")
+	}
 	args := []string{"install", "--phase", p.PhaseID, fmt.Sprintf("--force=%v", p.Force)}
 	if f.RemoteOpsURL != "" && f.RemoteOpsToken != "" {
 		args = append(args,
@@ -202,6 +202,9 @@ type Config struct {
 	LocalBackend storage.Backend
 	// Manual disables automatic phase execution when set to true
 	Manual bool
+	// Parallelism specifies the maximum number of install plan phases
+	// to execute concurrently
+	Parallelism int
 	// ServiceUser specifies the user to use as a service user in planet
 	// and for unprivileged kubernetes services
 	ServiceUser systeminfo.User
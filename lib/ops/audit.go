@@ -0,0 +1,562 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ops
+
+import (
+	"github.com/gravitational/gravity/lib/audit"
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/storage/clusterconfig"
+
+	teleservices "github.com/gravitational/teleport/lib/services"
+	"github.com/gravitational/trace"
+	log "github.com/sirupsen/logrus"
+)
+
+// OperatorWithAudit returns a new instance of the Operator interface
+// that records every change made on behalf of the specified user
+// in the audit log
+func OperatorWithAudit(operator Operator, emitter audit.Emitter, user storage.User) Operator {
+	return &operatorAudit{
+		Operator: operator,
+		emitter:  emitter,
+		username: user.GetName(),
+	}
+}
+
+// operatorAudit is a wrapper around any Operator service that
+// audits all mutating calls.
+//
+// Read-only calls are passed through to the underlying service
+type operatorAudit struct {
+	// Operator is the audited operator service
+	Operator
+	emitter  audit.Emitter
+	username string
+}
+
+func (o *operatorAudit) change(verb, kind, name, cluster string) *audit.Change {
+	return audit.NewChange(verb, kind, name, cluster)
+}
+
+// record emits the audit event for the change with the specified result
+// and the resource state after the change
+func (o *operatorAudit) record(c *audit.Change, after interface{}, err error) {
+	o.emitter.Emit(c.Event(o.username, after, err))
+}
+
+// recordOperation emits the audit event for the operation created
+// with the specified request
+func (o *operatorAudit) recordOperation(operationType, cluster string, key *SiteOperationKey, req interface{}, err error) {
+	name := operationType
+	if key != nil {
+		name = key.OperationID
+	}
+	c := o.change(teleservices.VerbCreate, storage.KindOperation, name, cluster)
+	o.record(c, req, err)
+}
+
+func (o *operatorAudit) CreateUser(req NewUserRequest) error {
+	c := o.change(teleservices.VerbCreate, teleservices.KindUser, req.Name, "")
+	err := o.Operator.CreateUser(req)
+	o.record(c, req, err)
+	return trace.Wrap(err)
+}
+
+func (o *operatorAudit) DeleteLocalUser(name string) error {
+	c := o.change(teleservices.VerbDelete, teleservices.KindUser, name, "")
+	err := o.Operator.DeleteLocalUser(name)
+	o.record(c, nil, err)
+	return trace.Wrap(err)
+}
+
+func (o *operatorAudit) ResetUserPassword(req ResetUserPasswordRequest) (string, error) {
+	c := o.change(teleservices.VerbUpdate, teleservices.KindUser, req.Email, req.SiteDomain)
+	password, err := o.Operator.ResetUserPassword(req)
+	o.record(c, nil, err)
+	return password, trace.Wrap(err)
+}
+
+func (o *operatorAudit) UpsertUser(key SiteKey, user teleservices.User) error {
+	c := o.change(audit.VerbUpsert, teleservices.KindUser, user.GetName(), key.SiteDomain)
+	c.CaptureBefore(func() (interface{}, error) {
+		return o.Operator.GetUser(key, user.GetName())
+	})
+	err := o.Operator.UpsertUser(key, user)
+	o.record(c, user, err)
+	return trace.Wrap(err)
+}
+
+func (o *operatorAudit) DeleteUser(key SiteKey, name string) error {
+	c := o.change(teleservices.VerbDelete, teleservices.KindUser, name, key.SiteDomain)
+	c.CaptureBefore(func() (interface{}, error) {
+		return o.Operator.GetUser(key, name)
+	})
+	err := o.Operator.DeleteUser(key, name)
+	o.record(c, nil, err)
+	return trace.Wrap(err)
+}
+
+func (o *operatorAudit) CreateAPIKey(req NewAPIKeyRequest) (*storage.APIKey, error) {
+	c := o.change(teleservices.VerbCreate, storage.KindToken, req.UserEmail, "")
+	key, err := o.Operator.CreateAPIKey(req)
+	o.record(c, nil, err)
+	return key, trace.Wrap(err)
+}
+
+func (o *operatorAudit) DeleteAPIKey(userEmail, token string) error {
+	c := o.change(teleservices.VerbDelete, storage.KindToken, userEmail, "")
+	err := o.Operator.DeleteAPIKey(userEmail, token)
+	o.record(c, nil, err)
+	return trace.Wrap(err)
+}
+
+func (o *operatorAudit) CreateInstallToken(req NewInstallTokenRequest) (*storage.InstallToken, error) {
+	c := o.change(teleservices.VerbCreate, storage.KindToken, req.UserEmail, "")
+	token, err := o.Operator.CreateInstallToken(req)
+	o.record(c, nil, err)
+	return token, trace.Wrap(err)
+}
+
+func (o *operatorAudit) CreateProvisioningToken(token storage.ProvisioningToken) error {
+	c := o.change(teleservices.VerbCreate, storage.KindToken, string(token.Type), token.SiteDomain)
+	err := o.Operator.CreateProvisioningToken(token)
+	o.record(c, nil, err)
+	return trace.Wrap(err)
+}
+
+func (o *operatorAudit) CreateSite(req NewSiteRequest) (*Site, error) {
+	c := o.change(teleservices.VerbCreate, storage.KindCluster, req.DomainName, req.DomainName)
+	site, err := o.Operator.CreateSite(req)
+	o.record(c, nil, err)
+	return site, trace.Wrap(err)
+}
+
+func (o *operatorAudit) DeleteSite(key SiteKey) error {
+	c := o.change(teleservices.VerbDelete, storage.KindCluster, key.SiteDomain, key.SiteDomain)
+	err := o.Operator.DeleteSite(key)
+	o.record(c, nil, err)
+	return trace.Wrap(err)
+}
+
+func (o *operatorAudit) CreateSiteInstallOperation(req CreateSiteInstallOperationRequest) (*SiteOperationKey, error) {
+	key, err := o.Operator.CreateSiteInstallOperation(req)
+	// the install request is not recorded since it contains provisioner credentials
+	o.recordOperation(OperationInstall, req.SiteDomain, key, nil, err)
+	return key, trace.Wrap(err)
+}
+
+func (o *operatorAudit) CreateSiteExpandOperation(req CreateSiteExpandOperationRequest) (*SiteOperationKey, error) {
+	key, err := o.Operator.CreateSiteExpandOperation(req)
+	o.recordOperation(OperationExpand, req.SiteDomain, key, req, err)
+	return key, trace.Wrap(err)
+}
+
+func (o *operatorAudit) CreateSiteShrinkOperation(req CreateSiteShrinkOperationRequest) (*SiteOperationKey, error) {
+	key, err := o.Operator.CreateSiteShrinkOperation(req)
+	o.recordOperation(OperationShrink, req.SiteDomain, key, req, err)
+	return key, trace.Wrap(err)
+}
+
+func (o *operatorAudit) ResumeShrink(siteKey SiteKey) (*SiteOperationKey, error) {
+	key, err := o.Operator.ResumeShrink(siteKey)
+	o.recordOperation(OperationShrink, siteKey.SiteDomain, key, nil, err)
+	return key, trace.Wrap(err)
+}
+
+func (o *operatorAudit) CreateSiteAppUpdateOperation(req CreateSiteAppUpdateOperationRequest) (*SiteOperationKey, error) {
+	key, err := o.Operator.CreateSiteAppUpdateOperation(req)
+	o.recordOperation(OperationUpdate, req.SiteDomain, key, req, err)
+	return key, trace.Wrap(err)
+}
+
+func (o *operatorAudit) CreateSiteUninstallOperation(req CreateSiteUninstallOperationRequest) (*SiteOperationKey, error) {
+	key, err := o.Operator.CreateSiteUninstallOperation(req)
+	o.recordOperation(OperationUninstall, req.SiteDomain, key, req, err)
+	return key, trace.Wrap(err)
+}
+
+func (o *operatorAudit) CreateClusterGarbageCollectOperation(req CreateClusterGarbageCollectOperationRequest) (*SiteOperationKey, error) {
+	key, err := o.Operator.CreateClusterGarbageCollectOperation(req)
+	o.recordOperation(OperationGarbageCollect, req.ClusterName, key, req, err)
+	return key, trace.Wrap(err)
+}
+
+func (o *operatorAudit) CreateUpdateEnvarsOperation(req CreateUpdateEnvarsOperationRequest) (*SiteOperationKey, error) {
+	key, err := o.Operator.CreateUpdateEnvarsOperation(req)
+	o.recordOperation(OperationUpdateRuntimeEnviron, req.ClusterKey.SiteDomain, key, req.Env, err)
+	return key, trace.Wrap(err)
+}
+
+func (o *operatorAudit) CreateUpdateConfigOperation(req CreateUpdateConfigOperationRequest) (*SiteOperationKey, error) {
+	key, err := o.Operator.CreateUpdateConfigOperation(req)
+	o.recordOperation(OperationUpdateConfig, req.ClusterKey.SiteDomain, key, clusterConfigState(req.Config), err)
+	return key, trace.Wrap(err)
+}
+
+func (o *operatorAudit) SetOperationState(key SiteOperationKey, req SetOperationStateRequest) error {
+	c := o.change(teleservices.VerbUpdate, storage.KindOperation, key.OperationID, key.SiteDomain)
+	c.CaptureBefore(func() (interface{}, error) {
+		operation, err := o.Operator.GetSiteOperation(key)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		return operationState{State: operation.State}, nil
+	})
+	err := o.Operator.SetOperationState(key, req)
+	o.record(c, operationState{State: req.State}, err)
+	return trace.Wrap(err)
+}
+
+func (o *operatorAudit) DeleteSiteOperation(key SiteOperationKey) error {
+	c := o.change(teleservices.VerbDelete, storage.KindOperation, key.OperationID, key.SiteDomain)
+	err := o.Operator.DeleteSiteOperation(key)
+	o.record(c, nil, err)
+	return trace.Wrap(err)
+}
+
+func (o *operatorAudit) UpdateClusterConfiguration(req UpdateClusterConfigRequest) error {
+	c := o.change(teleservices.VerbUpdate, storage.KindClusterConfiguration, "", req.ClusterKey.SiteDomain)
+	c.CaptureBefore(func() (interface{}, error) {
+		return o.Operator.GetClusterConfiguration(req.ClusterKey)
+	})
+	err := o.Operator.UpdateClusterConfiguration(req)
+	o.record(c, clusterConfigState(req.Config), err)
+	return trace.Wrap(err)
+}
+
+func (o *operatorAudit) UpdateClusterCertificate(req UpdateCertificateRequest) (*ClusterCertificate, error) {
+	key := SiteKey{AccountID: req.AccountID, SiteDomain: req.SiteDomain}
+	c := o.change(teleservices.VerbUpdate, storage.KindTLSKeyPair, "", req.SiteDomain)
+	c.CaptureBefore(func() (interface{}, error) {
+		cert, err := o.Operator.GetClusterCertificate(key, false)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		return certificateState{Certificate: string(cert.Certificate)}, nil
+	})
+	cert, err := o.Operator.UpdateClusterCertificate(req)
+	o.record(c, certificateState{Certificate: string(req.Certificate)}, err)
+	return cert, trace.Wrap(err)
+}
+
+func (o *operatorAudit) DeleteClusterCertificate(key SiteKey) error {
+	c := o.change(teleservices.VerbDelete, storage.KindTLSKeyPair, "", key.SiteDomain)
+	err := o.Operator.DeleteClusterCertificate(key)
+	o.record(c, nil, err)
+	return trace.Wrap(err)
+}
+
+func (o *operatorAudit) UpsertClusterAuthPreference(key SiteKey, auth teleservices.AuthPreference) error {
+	c := o.change(audit.VerbUpsert, teleservices.KindClusterAuthPreference, "", key.SiteDomain)
+	c.CaptureBefore(func() (interface{}, error) {
+		return o.Operator.GetClusterAuthPreference(key)
+	})
+	err := o.Operator.UpsertClusterAuthPreference(key, auth)
+	o.record(c, auth, err)
+	return trace.Wrap(err)
+}
+
+func (o *operatorAudit) UpsertGithubConnector(key SiteKey, connector teleservices.GithubConnector) error {
+	c := o.change(audit.VerbUpsert, teleservices.KindGithubConnector, connector.GetName(), key.SiteDomain)
+	c.CaptureBefore(func() (interface{}, error) {
+		return o.Operator.GetGithubConnector(key, connector.GetName(), false)
+	})
+	err := o.Operator.UpsertGithubConnector(key, connector)
+	o.record(c, connector, err)
+	return trace.Wrap(err)
+}
+
+func (o *operatorAudit) DeleteGithubConnector(key SiteKey, name string) error {
+	c := o.change(teleservices.VerbDelete, teleservices.KindGithubConnector, name, key.SiteDomain)
+	c.CaptureBefore(func() (interface{}, error) {
+		return o.Operator.GetGithubConnector(key, name, false)
+	})
+	err := o.Operator.DeleteGithubConnector(key, name)
+	o.record(c, nil, err)
+	return trace.Wrap(err)
+}
+
+func (o *operatorAudit) UpsertOIDCConnector(key SiteKey, connector teleservices.OIDCConnector) error {
+	c := o.change(audit.VerbUpsert, teleservices.KindOIDCConnector, connector.GetName(), key.SiteDomain)
+	c.CaptureBefore(func() (interface{}, error) {
+		return o.Operator.GetOIDCConnector(key, connector.GetName(), false)
+	})
+	err := o.Operator.UpsertOIDCConnector(key, connector)
+	o.record(c, connector, err)
+	return trace.Wrap(err)
+}
+
+func (o *operatorAudit) DeleteOIDCConnector(key SiteKey, name string) error {
+	c := o.change(teleservices.VerbDelete, teleservices.KindOIDCConnector, name, key.SiteDomain)
+	c.CaptureBefore(func() (interface{}, error) {
+		return o.Operator.GetOIDCConnector(key, name, false)
+	})
+	err := o.Operator.DeleteOIDCConnector(key, name)
+	o.record(c, nil, err)
+	return trace.Wrap(err)
+}
+
+func (o *operatorAudit) UpsertSAMLConnector(key SiteKey, connector teleservices.SAMLConnector) error {
+	c := o.change(audit.VerbUpsert, teleservices.KindSAMLConnector, connector.GetName(), key.SiteDomain)
+	c.CaptureBefore(func() (interface{}, error) {
+		return o.Operator.GetSAMLConnector(key, connector.GetName(), false)
+	})
+	err := o.Operator.UpsertSAMLConnector(key, connector)
+	o.record(c, connector, err)
+	return trace.Wrap(err)
+}
+
+func (o *operatorAudit) DeleteSAMLConnector(key SiteKey, name string) error {
+	c := o.change(teleservices.VerbDelete, teleservices.KindSAMLConnector, name, key.SiteDomain)
+	c.CaptureBefore(func() (interface{}, error) {
+		return o.Operator.GetSAMLConnector(key, name, false)
+	})
+	err := o.Operator.DeleteSAMLConnector(key, name)
+	o.record(c, nil, err)
+	return trace.Wrap(err)
+}
+
+func (o *operatorAudit) UpsertRole(key SiteKey, role teleservices.Role) error {
+	c := o.change(audit.VerbUpsert, teleservices.KindRole, role.GetName(), key.SiteDomain)
+	c.CaptureBefore(func() (interface{}, error) {
+		return o.Operator.GetRole(key, role.GetName())
+	})
+	err := o.Operator.UpsertRole(key, role)
+	o.record(c, role, err)
+	return trace.Wrap(err)
+}
+
+func (o *operatorAudit) DeleteRole(key SiteKey, name string) error {
+	c := o.change(teleservices.VerbDelete, teleservices.KindRole, name, key.SiteDomain)
+	c.CaptureBefore(func() (interface{}, error) {
+		return o.Operator.GetRole(key, name)
+	})
+	err := o.Operator.DeleteRole(key, name)
+	o.record(c, nil, err)
+	return trace.Wrap(err)
+}
+
+func (o *operatorAudit) UpsertTrustedCluster(key SiteKey, cluster teleservices.TrustedCluster) error {
+	c := o.change(audit.VerbUpsert, teleservices.KindTrustedCluster, cluster.GetName(), key.SiteDomain)
+	c.CaptureBefore(func() (interface{}, error) {
+		return o.Operator.GetTrustedCluster(key, cluster.GetName())
+	})
+	err := o.Operator.UpsertTrustedCluster(key, cluster)
+	o.record(c, cluster, err)
+	return trace.Wrap(err)
+}
+
+func (o *operatorAudit) DeleteTrustedCluster(key SiteKey, name string) error {
+	c := o.change(teleservices.VerbDelete, teleservices.KindTrustedCluster, name, key.SiteDomain)
+	c.CaptureBefore(func() (interface{}, error) {
+		return o.Operator.GetTrustedCluster(key, name)
+	})
+	err := o.Operator.DeleteTrustedCluster(key, name)
+	o.record(c, nil, err)
+	return trace.Wrap(err)
+}
+
+func (o *operatorAudit) UpsertAuthGateway(key SiteKey, gw storage.AuthGateway) error {
+	c := o.change(audit.VerbUpsert, storage.KindAuthGateway, gw.GetName(), key.SiteDomain)
+	c.CaptureBefore(func() (interface{}, error) {
+		return o.Operator.GetAuthGateway(key)
+	})
+	err := o.Operator.UpsertAuthGateway(key, gw)
+	o.record(c, gw, err)
+	return trace.Wrap(err)
+}
+
+func (o *operatorAudit) CreateLogForwarder(key SiteKey, forwarder storage.LogForwarder) error {
+	c := o.change(teleservices.VerbCreate, storage.KindLogForwarder, forwarder.GetName(), key.SiteDomain)
+	err := o.Operator.CreateLogForwarder(key, forwarder)
+	o.record(c, forwarder, err)
+	return trace.Wrap(err)
+}
+
+func (o *operatorAudit) UpdateLogForwarder(key SiteKey, forwarder storage.LogForwarder) error {
+	c := o.change(teleservices.VerbUpdate, storage.KindLogForwarder, forwarder.GetName(), key.SiteDomain)
+	c.CaptureBefore(func() (interface{}, error) {
+		return o.getLogForwarder(key, forwarder.GetName())
+	})
+	err := o.Operator.UpdateLogForwarder(key, forwarder)
+	o.record(c, forwarder, err)
+	return trace.Wrap(err)
+}
+
+func (o *operatorAudit) DeleteLogForwarder(key SiteKey, name string) error {
+	c := o.change(teleservices.VerbDelete, storage.KindLogForwarder, name, key.SiteDomain)
+	c.CaptureBefore(func() (interface{}, error) {
+		return o.getLogForwarder(key, name)
+	})
+	err := o.Operator.DeleteLogForwarder(key, name)
+	o.record(c, nil, err)
+	return trace.Wrap(err)
+}
+
+func (o *operatorAudit) getLogForwarder(key SiteKey, name string) (storage.LogForwarder, error) {
+	forwarders, err := o.Operator.GetLogForwarders(key, false)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	for _, forwarder := range forwarders {
+		if forwarder.GetName() == name {
+			return forwarder, nil
+		}
+	}
+	return nil, trace.NotFound("log forwarder %q not found", name)
+}
+
+func (o *operatorAudit) UpdateSMTPConfig(key SiteKey, config storage.SMTPConfig) error {
+	c := o.change(audit.VerbUpsert, storage.KindSMTPConfig, config.GetName(), key.SiteDomain)
+	c.CaptureBefore(func() (interface{}, error) {
+		return o.Operator.GetSMTPConfig(key, false)
+	})
+	err := o.Operator.UpdateSMTPConfig(key, config)
+	o.record(c, config, err)
+	return trace.Wrap(err)
+}
+
+func (o *operatorAudit) DeleteSMTPConfig(key SiteKey) error {
+	c := o.change(teleservices.VerbDelete, storage.KindSMTPConfig, "", key.SiteDomain)
+	c.CaptureBefore(func() (interface{}, error) {
+		return o.Operator.GetSMTPConfig(key, false)
+	})
+	err := o.Operator.DeleteSMTPConfig(key)
+	o.record(c, nil, err)
+	return trace.Wrap(err)
+}
+
+func (o *operatorAudit) UpdateAlert(key SiteKey, alert storage.Alert) error {
+	c := o.change(audit.VerbUpsert, storage.KindAlert, alert.GetName(), key.SiteDomain)
+	c.CaptureBefore(func() (interface{}, error) {
+		return o.getAlert(key, alert.GetName())
+	})
+	err := o.Operator.UpdateAlert(key, alert)
+	o.record(c, alert, err)
+	return trace.Wrap(err)
+}
+
+func (o *operatorAudit) DeleteAlert(key SiteKey, name string) error {
+	c := o.change(teleservices.VerbDelete, storage.KindAlert, name, key.SiteDomain)
+	c.CaptureBefore(func() (interface{}, error) {
+		return o.getAlert(key, name)
+	})
+	err := o.Operator.DeleteAlert(key, name)
+	o.record(c, nil, err)
+	return trace.Wrap(err)
+}
+
+func (o *operatorAudit) getAlert(key SiteKey, name string) (storage.Alert, error) {
+	alerts, err := o.Operator.GetAlerts(key)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	for _, alert := range alerts {
+		if alert.GetName() == name {
+			return alert, nil
+		}
+	}
+	return nil, trace.NotFound("alert %q not found", name)
+}
+
+func (o *operatorAudit) UpdateAlertTarget(key SiteKey, target storage.AlertTarget) error {
+	c := o.change(audit.VerbUpsert, storage.KindAlertTarget, target.GetName(), key.SiteDomain)
+	c.CaptureBefore(func() (interface{}, error) {
+		return o.getAlertTarget(key, target.GetName())
+	})
+	err := o.Operator.UpdateAlertTarget(key, target)
+	o.record(c, target, err)
+	return trace.Wrap(err)
+}
+
+func (o *operatorAudit) DeleteAlertTarget(key SiteKey, name string) error {
+	c := o.change(teleservices.VerbDelete, storage.KindAlertTarget, name, key.SiteDomain)
+	c.CaptureBefore(func() (interface{}, error) {
+		return o.getAlertTarget(key, name)
+	})
+	err := o.Operator.DeleteAlertTarget(key, name)
+	o.record(c, nil, err)
+	return trace.Wrap(err)
+}
+
+func (o *operatorAudit) getAlertTarget(key SiteKey, name string) (storage.AlertTarget, error) {
+	targets, err := o.Operator.GetAlertTargets(key, false)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	for _, target := range targets {
+		if target.GetName() == name {
+			return target, nil
+		}
+	}
+	return nil, trace.NotFound("alert target %q not found", name)
+}
+
+func (o *operatorAudit) UpsertBackupSchedule(key SiteKey, schedule storage.BackupSchedule) error {
+	c := o.change(audit.VerbUpsert, storage.KindBackupSchedule, schedule.GetName(), key.SiteDomain)
+	c.CaptureBefore(func() (interface{}, error) {
+		return o.getBackupSchedule(key, schedule.GetName())
+	})
+	err := o.Operator.UpsertBackupSchedule(key, schedule)
+	o.record(c, schedule, err)
+	return trace.Wrap(err)
+}
+
+func (o *operatorAudit) DeleteBackupSchedule(key SiteKey, name string) error {
+	c := o.change(teleservices.VerbDelete, storage.KindBackupSchedule, name, key.SiteDomain)
+	c.CaptureBefore(func() (interface{}, error) {
+		return o.getBackupSchedule(key, name)
+	})
+	err := o.Operator.DeleteBackupSchedule(key, name)
+	o.record(c, nil, err)
+	return trace.Wrap(err)
+}
+
+func (o *operatorAudit) getBackupSchedule(key SiteKey, name string) (storage.BackupSchedule, error) {
+	schedules, err := o.Operator.GetBackupSchedules(key, false)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	for _, schedule := range schedules {
+		if schedule.GetName() == name {
+			return schedule, nil
+		}
+	}
+	return nil, trace.NotFound("backup schedule %q not found", name)
+}
+
+// clusterConfigState returns the cluster configuration resource
+// from its serialized form for diffing
+func clusterConfigState(data []byte) interface{} {
+	config, err := clusterconfig.Unmarshal(data)
+	if err != nil {
+		log.Debugf("Failed to unmarshal cluster configuration: %v.", err)
+		return nil
+	}
+	return config
+}
+
+// operationState is the audited state of an operation
+type operationState struct {
+	State string `json:"state"`
+}
+
+// certificateState is the audited state of the cluster certificate.
+// The private key is never recorded
+type certificateState struct {
+	Certificate string `json:"certificate"`
+}
@@ -115,6 +115,9 @@ const (
 	// operation "update" and its states
 	OperationUpdate                = "operation_update"
 	OperationStateUpdateInProgress = "update_in_progress"
+	// OperationStateUpdateAwaitingApproval is the state of the update operation
+	// paused until the update of the remaining nodes has been approved
+	OperationStateUpdateAwaitingApproval = "update_awaiting_approval"
 
 	// operation "shrink" and its states
 	OperationShrink                = "operation_shrink"
@@ -0,0 +1,143 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ops
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gravitational/gravity/lib/storage"
+
+	"github.com/gravitational/trace"
+)
+
+// OperationEventStream is a stream of operation events
+type OperationEventStream interface {
+	// Recv returns the next event from the stream.
+	// It returns io.EOF once the operation has completed and all
+	// its events have been delivered
+	Recv() (*OperationEvent, error)
+	// Close stops the stream and releases its resources
+	Close() error
+}
+
+// OperationEvent is a single event of an operation
+type OperationEvent struct {
+	// Type is the event type
+	Type string `json:"type"`
+	// Offset is the stream offset after this event.
+	// The stream can be resumed from this offset
+	Offset EventOffset `json:"offset"`
+	// Progress is the operation progress entry, set for progress events
+	Progress *ProgressEntry `json:"progress,omitempty"`
+	// Change is the phase state change, set for phase events
+	Change *storage.PlanChange `json:"change,omitempty"`
+	// Log is the operation log line, set for log events
+	Log string `json:"log,omitempty"`
+	// Error is the error message, set for error events
+	Error string `json:"error,omitempty"`
+}
+
+// IsCompleted returns true if this event marks the completion of the operation
+func (e OperationEvent) IsCompleted() bool {
+	return e.Type == OperationEventProgress && e.Progress != nil && e.Progress.IsCompleted()
+}
+
+// String returns a textual representation of this event
+func (e OperationEvent) String() string {
+	switch e.Type {
+	case OperationEventProgress:
+		if e.Progress != nil {
+			return fmt.Sprintf("progress(%v%%, state=%v, message=%v)",
+				e.Progress.Completion, e.Progress.State, e.Progress.Message)
+		}
+	case OperationEventPhase:
+		if e.Change != nil {
+			return fmt.Sprintf("phase(%v, state=%v)", e.Change.PhaseID, e.Change.NewState)
+		}
+	case OperationEventLog:
+		return fmt.Sprintf("log(%v)", e.Log)
+	case OperationEventError:
+		return fmt.Sprintf("error(%v)", e.Error)
+	}
+	return fmt.Sprintf("event(%v)", e.Type)
+}
+
+// EventOffset defines the position in the operation event stream.
+//
+// Each source of events maintains a separate position: progress entries
+// are tracked by creation time, phase state changes by their number
+// in the plan changelog and logs by the byte offset in the operation log
+type EventOffset struct {
+	// Progress is the creation time of the last delivered progress entry
+	// in nanoseconds since Unix epoch
+	Progress int64 `json:"progress"`
+	// Changes is the number of delivered phase state changes
+	Changes int `json:"changes"`
+	// Logs is the byte offset in the operation log
+	Logs int64 `json:"logs"`
+}
+
+// String returns the offset in the format accepted by ParseEventOffset
+func (o EventOffset) String() string {
+	return fmt.Sprintf("%v.%v.%v", o.Progress, o.Changes, o.Logs)
+}
+
+// IsZero returns true if this offset points to the start of the stream
+func (o EventOffset) IsZero() bool {
+	return o == EventOffset{}
+}
+
+// ParseEventOffset parses the event offset from the specified string.
+// An empty string denotes the start of the stream
+func ParseEventOffset(s string) (*EventOffset, error) {
+	if s == "" {
+		return &EventOffset{}, nil
+	}
+	parts := strings.Split(s, ".")
+	if len(parts) != 3 {
+		return nil, trace.BadParameter("invalid event offset %q, expected <progress>.<changes>.<logs>", s)
+	}
+	progress, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return nil, trace.BadParameter("invalid event offset %q: %v", s, err)
+	}
+	changes, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return nil, trace.BadParameter("invalid event offset %q: %v", s, err)
+	}
+	logs, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		return nil, trace.BadParameter("invalid event offset %q: %v", s, err)
+	}
+	if progress < 0 || changes < 0 || logs < 0 {
+		return nil, trace.BadParameter("invalid event offset %q: negative position", s)
+	}
+	return &EventOffset{Progress: progress, Changes: changes, Logs: logs}, nil
+}
+
+const (
+	// OperationEventProgress is the event with a new operation progress entry
+	OperationEventProgress = "progress"
+	// OperationEventPhase is the event with an operation phase state change
+	OperationEventPhase = "phase"
+	// OperationEventLog is the event with an operation log line
+	OperationEventLog = "log"
+	// OperationEventError is the event sent when the stream has failed
+	OperationEventError = "error"
+)
@@ -0,0 +1,259 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package monitoring
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/storage"
+
+	"github.com/gravitational/trace"
+)
+
+// AlertEvent is a single alert event in the format
+// of the Kapacitor HTTP POST alert handler
+type AlertEvent struct {
+	// ID is the alert ID
+	ID string `json:"id"`
+	// Message is the alert message
+	Message string `json:"message"`
+	// Details is the optional alert details
+	Details string `json:"details,omitempty"`
+	// Time is the time the alert was triggered
+	Time time.Time `json:"time"`
+	// Duration is the time the alert has been in the non-OK state
+	Duration time.Duration `json:"duration"`
+	// Level is the alert level: OK, INFO, WARNING or CRITICAL
+	Level string `json:"level"`
+	// PreviousLevel is the previous alert level
+	PreviousLevel string `json:"previousLevel,omitempty"`
+}
+
+// Notifier delivers alert events to a single alert target
+type Notifier interface {
+	// Notify sends the specified alert event to the target
+	Notify(context.Context, AlertEvent) error
+}
+
+// NewNotifier returns a new notifier for the specified alert target.
+// clusterName identifies the source of alerts for targets that require one.
+//
+// Email alert targets are delivered by Kapacitor directly and have no notifier
+func NewNotifier(target storage.AlertTarget, clusterName string, client *http.Client) (Notifier, error) {
+	if client == nil {
+		client = &http.Client{Timeout: defaults.AlertNotifyTimeout}
+	}
+	switch target.GetType() {
+	case storage.AlertTargetWebhook:
+		return &webhookNotifier{WebhookAlertTarget: *target.GetWebhook(), client: client}, nil
+	case storage.AlertTargetSlack:
+		return &slackNotifier{SlackAlertTarget: *target.GetSlack(), client: client}, nil
+	case storage.AlertTargetPagerDuty:
+		return &pagerDutyNotifier{
+			PagerDutyAlertTarget: *target.GetPagerDuty(),
+			source:               clusterName,
+			client:               client,
+		}, nil
+	}
+	return nil, trace.BadParameter("%v alert targets are delivered by Kapacitor",
+		target.GetType())
+}
+
+// Notify sends the alert event to all webhook, Slack and PagerDuty targets
+// from the provided list.
+// Delivery is attempted for every target and all errors are collected
+func Notify(ctx context.Context, targets []storage.AlertTarget, clusterName string, event AlertEvent) error {
+	var errors []error
+	for _, target := range targets {
+		if target.GetType() == storage.AlertTargetEmail {
+			continue
+		}
+		notifier, err := NewNotifier(target, clusterName, nil)
+		if err != nil {
+			errors = append(errors, err)
+			continue
+		}
+		if err := notifier.Notify(ctx, event); err != nil {
+			errors = append(errors, trace.Wrap(err, "failed to notify alert target %q",
+				target.GetName()))
+		}
+	}
+	return trace.NewAggregate(errors...)
+}
+
+// Sign returns the hex-encoded HMAC-SHA256 signature of the payload
+// computed with the specified secret
+func Sign(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+type webhookNotifier struct {
+	storage.WebhookAlertTarget
+	client *http.Client
+}
+
+// Notify posts the alert event as JSON to the webhook
+func (r *webhookNotifier) Notify(ctx context.Context, event AlertEvent) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	headers := make(map[string]string, len(r.Headers)+1)
+	for name, value := range r.Headers {
+		headers[name] = value
+	}
+	if r.HMACSecret != "" {
+		headers[SignatureHeader] = fmt.Sprintf("sha256=%v", Sign(r.HMACSecret, payload))
+	}
+	return trace.Wrap(postJSON(ctx, r.client, r.URL, payload, headers))
+}
+
+type slackNotifier struct {
+	storage.SlackAlertTarget
+	client *http.Client
+}
+
+// Notify posts the alert event as a message to the Slack incoming webhook
+func (r *slackNotifier) Notify(ctx context.Context, event AlertEvent) error {
+	text := fmt.Sprintf("[%v] %v", event.Level, event.Message)
+	if event.Details != "" {
+		text = fmt.Sprintf("%v\n%v", text, event.Details)
+	}
+	payload, err := json.Marshal(slackMessage{
+		Text:     text,
+		Channel:  r.Channel,
+		Username: r.Username,
+	})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return trace.Wrap(postJSON(ctx, r.client, r.URL, payload, nil))
+}
+
+type pagerDutyNotifier struct {
+	storage.PagerDutyAlertTarget
+	source string
+	client *http.Client
+}
+
+// Notify triggers or resolves a PagerDuty incident for the alert event.
+// The alert ID is used as the deduplication key so the incident is resolved
+// once the alert returns to OK level
+func (r *pagerDutyNotifier) Notify(ctx context.Context, event AlertEvent) error {
+	pdEvent := pagerDutyEvent{
+		RoutingKey:  r.RoutingKey,
+		EventAction: pagerDutyActionTrigger,
+		DedupKey:    event.ID,
+	}
+	if strings.ToUpper(event.Level) == AlertLevelOK {
+		pdEvent.EventAction = pagerDutyActionResolve
+	} else {
+		pdEvent.Payload = &pagerDutyPayload{
+			Summary:   event.Message,
+			Source:    r.source,
+			Severity:  pagerDutySeverity(event.Level),
+			Timestamp: event.Time,
+		}
+	}
+	payload, err := json.Marshal(pdEvent)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return trace.Wrap(postJSON(ctx, r.client, r.URL, payload, nil))
+}
+
+func pagerDutySeverity(level string) string {
+	switch strings.ToUpper(level) {
+	case AlertLevelCritical:
+		return "critical"
+	case AlertLevelWarning:
+		return "warning"
+	default:
+		return "info"
+	}
+}
+
+func postJSON(ctx context.Context, client *http.Client, url string, payload []byte, headers map[string]string) error {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return trace.ReadError(resp.StatusCode, body)
+	}
+	return nil
+}
+
+type slackMessage struct {
+	Text     string `json:"text"`
+	Channel  string `json:"channel,omitempty"`
+	Username string `json:"username,omitempty"`
+}
+
+type pagerDutyEvent struct {
+	RoutingKey  string            `json:"routing_key"`
+	EventAction string            `json:"event_action"`
+	DedupKey    string            `json:"dedup_key,omitempty"`
+	Payload     *pagerDutyPayload `json:"payload,omitempty"`
+}
+
+type pagerDutyPayload struct {
+	Summary   string    `json:"summary"`
+	Source    string    `json:"source"`
+	Severity  string    `json:"severity"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+const (
+	// SignatureHeader is the HTTP header with the HMAC signature
+	// of webhook alert target payloads
+	SignatureHeader = "X-Gravity-Signature"
+
+	// AlertLevelOK is the level of recovered alerts
+	AlertLevelOK = "OK"
+	// AlertLevelWarning is the level of warning alerts
+	AlertLevelWarning = "WARNING"
+	// AlertLevelCritical is the level of critical alerts
+	AlertLevelCritical = "CRITICAL"
+
+	pagerDutyActionTrigger = "trigger"
+	pagerDutyActionResolve = "resolve"
+)
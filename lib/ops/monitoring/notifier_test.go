@@ -0,0 +1,161 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package monitoring
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gravitational/gravity/lib/storage"
+
+	. "gopkg.in/check.v1"
+)
+
+func TestMonitoring(t *testing.T) { TestingT(t) }
+
+type NotifierSuite struct{}
+
+var _ = Suite(&NotifierSuite{})
+
+func (s *NotifierSuite) TestWebhookSignsPayload(c *C) {
+	var request capturedRequest
+	server := httptest.NewServer(request.handler(c))
+	defer server.Close()
+
+	target := storage.NewAlertTarget("hook", storage.AlertTargetSpecV2{
+		Webhook: &storage.WebhookAlertTarget{
+			URL:        server.URL,
+			Headers:    map[string]string{"X-Custom": "value"},
+			HMACSecret: "secret",
+		},
+	})
+	c.Assert(target.CheckAndSetDefaults(), IsNil)
+
+	err := Notify(context.TODO(), []storage.AlertTarget{target}, "example.com", testEvent)
+	c.Assert(err, IsNil)
+
+	var event AlertEvent
+	c.Assert(json.Unmarshal(request.body, &event), IsNil)
+	c.Assert(event.ID, Equals, testEvent.ID)
+	c.Assert(request.header.Get("X-Custom"), Equals, "value")
+	c.Assert(request.header.Get(SignatureHeader), Equals,
+		fmt.Sprintf("sha256=%v", Sign("secret", request.body)))
+}
+
+func (s *NotifierSuite) TestSlackMessage(c *C) {
+	var request capturedRequest
+	server := httptest.NewServer(request.handler(c))
+	defer server.Close()
+
+	target := storage.NewAlertTarget("slack", storage.AlertTargetSpecV2{
+		Slack: &storage.SlackAlertTarget{URL: server.URL, Channel: "#alerts"},
+	})
+	c.Assert(target.CheckAndSetDefaults(), IsNil)
+
+	err := Notify(context.TODO(), []storage.AlertTarget{target}, "example.com", testEvent)
+	c.Assert(err, IsNil)
+
+	var message slackMessage
+	c.Assert(json.Unmarshal(request.body, &message), IsNil)
+	c.Assert(message, DeepEquals, slackMessage{
+		Text:    "[CRITICAL] node is down",
+		Channel: "#alerts",
+	})
+}
+
+func (s *NotifierSuite) TestPagerDutyTriggersAndResolves(c *C) {
+	var request capturedRequest
+	server := httptest.NewServer(request.handler(c))
+	defer server.Close()
+
+	target := storage.NewAlertTarget("pd", storage.AlertTargetSpecV2{
+		PagerDuty: &storage.PagerDutyAlertTarget{RoutingKey: "key", URL: server.URL},
+	})
+	c.Assert(target.CheckAndSetDefaults(), IsNil)
+
+	err := Notify(context.TODO(), []storage.AlertTarget{target}, "example.com", testEvent)
+	c.Assert(err, IsNil)
+	var event pagerDutyEvent
+	c.Assert(json.Unmarshal(request.body, &event), IsNil)
+	c.Assert(event, DeepEquals, pagerDutyEvent{
+		RoutingKey:  "key",
+		EventAction: pagerDutyActionTrigger,
+		DedupKey:    testEvent.ID,
+		Payload: &pagerDutyPayload{
+			Summary:   testEvent.Message,
+			Source:    "example.com",
+			Severity:  "critical",
+			Timestamp: testEvent.Time,
+		},
+	})
+
+	resolved := testEvent
+	resolved.Level = AlertLevelOK
+	err = Notify(context.TODO(), []storage.AlertTarget{target}, "example.com", resolved)
+	c.Assert(err, IsNil)
+	event = pagerDutyEvent{}
+	c.Assert(json.Unmarshal(request.body, &event), IsNil)
+	c.Assert(event, DeepEquals, pagerDutyEvent{
+		RoutingKey:  "key",
+		EventAction: pagerDutyActionResolve,
+		DedupKey:    testEvent.ID,
+	})
+}
+
+func (s *NotifierSuite) TestReportsFailedTargets(c *C) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	targets := []storage.AlertTarget{
+		storage.NewAlertTarget("", storage.AlertTargetSpecV2{Email: "admin@example.com"}),
+		storage.NewAlertTarget("hook", storage.AlertTargetSpecV2{
+			Webhook: &storage.WebhookAlertTarget{URL: server.URL},
+		}),
+	}
+	err := Notify(context.TODO(), targets, "example.com", testEvent)
+	c.Assert(err, NotNil)
+	c.Assert(err, ErrorMatches, `(?s).*failed to notify alert target "hook".*`)
+}
+
+type capturedRequest struct {
+	header http.Header
+	body   []byte
+}
+
+func (r *capturedRequest) handler(c *C) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, err := ioutil.ReadAll(req.Body)
+		c.Assert(err, IsNil)
+		r.header = req.Header
+		r.body = body
+	})
+}
+
+var testEvent = AlertEvent{
+	ID:      "node-down",
+	Message: "node is down",
+	Time:    time.Date(2019, time.January, 1, 0, 0, 0, 0, time.UTC),
+	Level:   AlertLevelCritical,
+}
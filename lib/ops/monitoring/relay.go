@@ -0,0 +1,319 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package monitoring
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gravitational/gravity/lib/constants"
+	"github.com/gravitational/gravity/lib/defaults"
+
+	"github.com/gravitational/rigging"
+	teleutils "github.com/gravitational/teleport/lib/utils"
+	"github.com/gravitational/trace"
+	"github.com/sirupsen/logrus"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+)
+
+// NewRelay returns a new relay of Kapacitor alerts to the alert targets
+func NewRelay(config RelayConfig) (*Relay, error) {
+	if err := config.checkAndSetDefaults(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &Relay{RelayConfig: config}, nil
+}
+
+// RelayConfig defines the alert relay configuration
+type RelayConfig struct {
+	// KapacitorURL is the address of the Kapacitor API
+	KapacitorURL string
+	// NotifyURL is the cluster API endpoint Kapacitor posts alert events to
+	NotifyURL string
+	// Tokens manages the token Kapacitor authenticates with
+	Tokens NotifyTokens
+	// Client is the HTTP client for the Kapacitor API
+	Client *http.Client
+	// FieldLogger is used for logging
+	logrus.FieldLogger
+}
+
+// Relay makes Kapacitor deliver alerts to the webhook, Slack and PagerDuty
+// alert targets.
+//
+// Kapacitor publishes every alert event to a topic, including the alerts
+// that do not specify one explicitly. Relay attaches a post handler to each
+// topic which sends the events to the cluster API notify endpoint
+// authenticated with the alert notify token.
+// The cluster API then delivers the events to the configured alert targets.
+type Relay struct {
+	RelayConfig
+}
+
+// Run periodically attaches the relay handler to the Kapacitor alert topics
+// until the context is cancelled.
+// New topics are created by Kapacitor as alerts are added
+func (r *Relay) Run(ctx context.Context) error {
+	ticker := time.NewTicker(defaults.AlertRelaySyncInterval)
+	defer ticker.Stop()
+	for {
+		if err := r.Sync(ctx); err != nil {
+			r.Warnf("Failed to configure Kapacitor alert handlers: %v.", trace.DebugReport(err))
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// Sync attaches the relay handler to all Kapacitor alert topics
+func (r *Relay) Sync(ctx context.Context) error {
+	token, err := r.Tokens.Init()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	topics, err := r.getTopics(ctx)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	handler := kapacitorHandler{
+		ID:   constants.AlertRelayHandlerID,
+		Kind: kapacitorHandlerPost,
+		Options: kapacitorPostOptions{
+			URL: r.NotifyURL,
+			Headers: map[string]string{
+				"Authorization": fmt.Sprintf("Bearer %v", token),
+			},
+		},
+	}
+	var errors []error
+	for _, topic := range topics {
+		if err := r.upsertHandler(ctx, topic, handler); err != nil {
+			errors = append(errors, trace.Wrap(err, "failed to configure topic %q", topic))
+		}
+	}
+	return trace.NewAggregate(errors...)
+}
+
+func (r *Relay) getTopics(ctx context.Context) (topics []string, err error) {
+	var response kapacitorTopics
+	err = r.do(ctx, http.MethodGet, r.endpoint("alerts", "topics"), nil, &response)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	for _, topic := range response.Topics {
+		topics = append(topics, topic.ID)
+	}
+	return topics, nil
+}
+
+// upsertHandler replaces the relay handler of the specified topic
+// or creates one if the topic does not have it yet
+func (r *Relay) upsertHandler(ctx context.Context, topic string, handler kapacitorHandler) error {
+	err := r.do(ctx, http.MethodPut, r.endpoint("alerts", "topics", topic, "handlers", handler.ID), handler, nil)
+	if !trace.IsNotFound(err) {
+		return trace.Wrap(err)
+	}
+	r.Infof("Attach alert relay to topic %q.", topic)
+	return trace.Wrap(r.do(ctx, http.MethodPost, r.endpoint("alerts", "topics", topic, "handlers"), handler, nil))
+}
+
+func (r *Relay) endpoint(params ...string) string {
+	for i, param := range params {
+		params[i] = url.PathEscape(param)
+	}
+	return fmt.Sprintf("%v/kapacitor/v1/%v", strings.TrimSuffix(r.KapacitorURL, "/"), strings.Join(params, "/"))
+}
+
+func (r *Relay) do(ctx context.Context, method, url string, in, out interface{}) error {
+	var body []byte
+	if in != nil {
+		var err error
+		if body, err = json.Marshal(in); err != nil {
+			return trace.Wrap(err)
+		}
+	}
+	req, err := http.NewRequest(method, url, bytes.NewReader(body))
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := r.Client.Do(req)
+	if err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	defer resp.Body.Close()
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return trace.ReadError(resp.StatusCode, respBody)
+	}
+	if out == nil {
+		return nil
+	}
+	return trace.Wrap(json.Unmarshal(respBody, out))
+}
+
+func (r *RelayConfig) checkAndSetDefaults() error {
+	if r.NotifyURL == "" {
+		return trace.BadParameter("missing NotifyURL")
+	}
+	if r.Tokens == nil {
+		return trace.BadParameter("missing Tokens")
+	}
+	if r.KapacitorURL == "" {
+		r.KapacitorURL = defaults.KapacitorServiceURL
+	}
+	if r.Client == nil {
+		r.Client = &http.Client{Timeout: defaults.AlertNotifyTimeout}
+	}
+	if r.FieldLogger == nil {
+		r.FieldLogger = logrus.WithField(trace.Component, "alert-relay")
+	}
+	return nil
+}
+
+// ReceiveEvent reads the alert event posted by Kapacitor.
+// The request is authenticated with the alert notify token
+func ReceiveEvent(r *http.Request, tokens NotifyTokens) (*AlertEvent, error) {
+	if tokens == nil {
+		return nil, trace.AccessDenied("alert relay is not enabled")
+	}
+	token, err := tokens.Get()
+	if err != nil {
+		if trace.IsNotFound(err) {
+			return nil, trace.AccessDenied("alert relay is not configured")
+		}
+		return nil, trace.Wrap(err)
+	}
+	const prefix = "Bearer "
+	header := r.Header.Get("Authorization")
+	if !strings.HasPrefix(header, prefix) ||
+		subtle.ConstantTimeCompare([]byte(strings.TrimPrefix(header, prefix)), []byte(token)) != 1 {
+		return nil, trace.AccessDenied("invalid alert notify token")
+	}
+	var event AlertEvent
+	if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+		return nil, trace.BadParameter("invalid alert event: %v", err)
+	}
+	return &event, nil
+}
+
+// NotifyTokens manages the token that authenticates the alert events
+// posted by Kapacitor to the cluster API.
+//
+// The token only permits delivering alerts to the configured alert targets
+type NotifyTokens interface {
+	// Get returns the current token
+	Get() (string, error)
+	// Init returns the current token generating a new one if necessary
+	Init() (string, error)
+}
+
+// NewNotifyTokens returns the notify token storage backed by a Kubernetes
+// secret managed with the specified client
+func NewNotifyTokens(client corev1.SecretInterface) NotifyTokens {
+	return &secretTokens{client: client}
+}
+
+type secretTokens struct {
+	client corev1.SecretInterface
+}
+
+// Get returns the token from the secret
+func (r *secretTokens) Get() (string, error) {
+	secret, err := r.client.Get(constants.AlertNotifyTokenSecret, metav1.GetOptions{})
+	if err != nil {
+		return "", trace.Wrap(rigging.ConvertError(err))
+	}
+	token := string(secret.Data[constants.AlertNotifyTokenKey])
+	if token == "" {
+		return "", trace.NotFound("alert notify token is empty")
+	}
+	return token, nil
+}
+
+// Init returns the token from the secret creating the secret if necessary
+func (r *secretTokens) Init() (string, error) {
+	token, err := r.Get()
+	if err == nil {
+		return token, nil
+	}
+	if !trace.IsNotFound(err) {
+		return "", trace.Wrap(err)
+	}
+	token, err = teleutils.CryptoRandomHex(defaults.AlertNotifyTokenBytes)
+	if err != nil {
+		return "", trace.Wrap(err)
+	}
+	_, err = r.client.Create(&v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: constants.AlertNotifyTokenSecret,
+		},
+		Data: map[string][]byte{
+			constants.AlertNotifyTokenKey: []byte(token),
+		},
+		Type: v1.SecretTypeOpaque,
+	})
+	err = rigging.ConvertError(err)
+	if trace.IsAlreadyExists(err) {
+		// Lost the race to another process
+		return r.Get()
+	}
+	if err != nil {
+		return "", trace.Wrap(err)
+	}
+	return token, nil
+}
+
+type kapacitorTopics struct {
+	Topics []kapacitorTopic `json:"topics"`
+}
+
+type kapacitorTopic struct {
+	ID string `json:"id"`
+}
+
+type kapacitorHandler struct {
+	ID      string               `json:"id"`
+	Kind    string               `json:"kind"`
+	Options kapacitorPostOptions `json:"options"`
+}
+
+type kapacitorPostOptions struct {
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+// kapacitorHandlerPost is the kind of Kapacitor handlers that
+// post alert events as JSON to an HTTP endpoint
+const kapacitorHandlerPost = "post"
@@ -0,0 +1,196 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package monitoring
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+
+	"github.com/gravitational/gravity/lib/constants"
+	"github.com/gravitational/gravity/lib/storage"
+
+	"github.com/gravitational/trace"
+	. "gopkg.in/check.v1"
+)
+
+type RelaySuite struct{}
+
+var _ = Suite(&RelaySuite{})
+
+// TestRelaysAlerts verifies the alert path from Kapacitor to the alert target:
+// the relay attaches the handler to Kapacitor topics, Kapacitor posts an alert
+// event to the notify endpoint with the configured headers and the event
+// is delivered to the webhook alert target
+func (s *RelaySuite) TestRelaysAlerts(c *C) {
+	var request capturedRequest
+	target := httptest.NewServer(request.handler(c))
+	defer target.Close()
+	targets := []storage.AlertTarget{
+		storage.NewAlertTarget("hook", storage.AlertTargetSpecV2{
+			Webhook: &storage.WebhookAlertTarget{URL: target.URL},
+		}),
+	}
+
+	tokens := &memoryTokens{}
+	notify := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		event, err := ReceiveEvent(r, tokens)
+		if err == nil {
+			err = Notify(r.Context(), targets, "example.com", *event)
+		}
+		if err != nil {
+			trace.WriteError(w, err)
+		}
+	}))
+	defer notify.Close()
+
+	kapacitor := newFakeKapacitor("main:cpu:alert2", "main:disk:alert3")
+	kapacitorServer := httptest.NewServer(kapacitor)
+	defer kapacitorServer.Close()
+
+	relay, err := NewRelay(RelayConfig{
+		KapacitorURL: kapacitorServer.URL,
+		NotifyURL:    notify.URL,
+		Tokens:       tokens,
+	})
+	c.Assert(err, IsNil)
+	c.Assert(relay.Sync(context.TODO()), IsNil)
+	// Sync is idempotent
+	c.Assert(relay.Sync(context.TODO()), IsNil)
+
+	c.Assert(kapacitor.handlers, HasLen, 2)
+	handler := kapacitor.handlers["main:cpu:alert2"]
+	c.Assert(handler.ID, Equals, constants.AlertRelayHandlerID)
+	c.Assert(handler.Kind, Equals, kapacitorHandlerPost)
+	c.Assert(handler.Options.URL, Equals, notify.URL)
+
+	c.Assert(kapacitor.fire(handler, testEvent), IsNil)
+	var event AlertEvent
+	c.Assert(json.Unmarshal(request.body, &event), IsNil)
+	c.Assert(event.ID, Equals, testEvent.ID)
+	c.Assert(event.Level, Equals, testEvent.Level)
+}
+
+func (s *RelaySuite) TestRejectsInvalidToken(c *C) {
+	tokens := &memoryTokens{}
+	_, err := tokens.Init()
+	c.Assert(err, IsNil)
+
+	for _, header := range []string{"", "Bearer invalid", "invalid"} {
+		req := httptest.NewRequest(http.MethodPost, "/notify", strings.NewReader("{}"))
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+		_, err = ReceiveEvent(req, tokens)
+		c.Assert(trace.IsAccessDenied(err), Equals, true, Commentf("header %q: %v", header, err))
+	}
+
+	_, err = ReceiveEvent(httptest.NewRequest(http.MethodPost, "/notify", nil), nil)
+	c.Assert(trace.IsAccessDenied(err), Equals, true)
+}
+
+// fakeKapacitor implements the subset of the Kapacitor alert topics API
+type fakeKapacitor struct {
+	sync.Mutex
+	topics   []string
+	handlers map[string]kapacitorHandler
+}
+
+func newFakeKapacitor(topics ...string) *fakeKapacitor {
+	return &fakeKapacitor{
+		topics:   topics,
+		handlers: make(map[string]kapacitorHandler),
+	}
+}
+
+func (k *fakeKapacitor) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	k.Lock()
+	defer k.Unlock()
+	path := strings.Split(strings.TrimPrefix(r.URL.Path, "/kapacitor/v1/alerts/topics"), "/")
+	switch {
+	case r.Method == http.MethodGet && len(path) == 1:
+		var response kapacitorTopics
+		for _, topic := range k.topics {
+			response.Topics = append(response.Topics, kapacitorTopic{ID: topic})
+		}
+		json.NewEncoder(w).Encode(response)
+	case r.Method == http.MethodPost && len(path) == 3 && path[2] == "handlers":
+		var handler kapacitorHandler
+		json.NewDecoder(r.Body).Decode(&handler)
+		if _, ok := k.handlers[path[1]]; ok {
+			http.Error(w, "handler already exists", http.StatusConflict)
+			return
+		}
+		k.handlers[path[1]] = handler
+	case r.Method == http.MethodPut && len(path) == 4:
+		var handler kapacitorHandler
+		json.NewDecoder(r.Body).Decode(&handler)
+		if _, ok := k.handlers[path[1]]; !ok {
+			http.Error(w, "handler does not exist", http.StatusNotFound)
+			return
+		}
+		k.handlers[path[1]] = handler
+	default:
+		http.Error(w, "not found", http.StatusNotFound)
+	}
+}
+
+// fire posts the alert event the way the Kapacitor post handler does
+func (k *fakeKapacitor) fire(handler kapacitorHandler, event AlertEvent) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	req, err := http.NewRequest(http.MethodPost, handler.Options.URL, bytes.NewReader(payload))
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	for name, value := range handler.Options.Headers {
+		req.Header.Set(name, value)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return trace.BadParameter("unexpected status: %v", resp.Status)
+	}
+	return nil
+}
+
+type memoryTokens struct {
+	token string
+}
+
+func (r *memoryTokens) Get() (string, error) {
+	if r.token == "" {
+		return "", trace.NotFound("no token")
+	}
+	return r.token, nil
+}
+
+func (r *memoryTokens) Init() (string, error) {
+	if r.token == "" {
+		r.token = "secret-token"
+	}
+	return r.token, nil
+}
@@ -17,6 +17,7 @@ limitations under the License.
 package ops
 
 import (
+	"context"
 	"crypto/x509"
 	"encoding/pem"
 	"io"
@@ -152,6 +153,27 @@ func (o *OperatorACL) authPreferenceActions(actions ...string) error {
 	return nil
 }
 
+// roleActions checks access to the specified actions on the "role" resource
+func (o *OperatorACL) roleActions(actions ...string) error {
+	for _, action := range actions {
+		if err := o.Action(teleservices.KindRole, action); err != nil {
+			return trace.Wrap(err)
+		}
+	}
+	return nil
+}
+
+// trustedClusterActions checks access to the specified actions on the
+// "trusted cluster" resource
+func (o *OperatorACL) trustedClusterActions(actions ...string) error {
+	for _, action := range actions {
+		if err := o.Action(teleservices.KindTrustedCluster, action); err != nil {
+			return trace.Wrap(err)
+		}
+	}
+	return nil
+}
+
 // AuthConnectorActions checks access to the specified actions on the "auth
 // connector" resource
 //
@@ -519,6 +541,13 @@ func (o *OperatorACL) GetSiteOperationLogs(key SiteOperationKey) (io.ReadCloser,
 	return o.operator.GetSiteOperationLogs(key)
 }
 
+func (o *OperatorACL) GetOperationEvents(ctx context.Context, key SiteOperationKey, offset EventOffset) (OperationEventStream, error) {
+	if err := o.ClusterAction(key.SiteDomain, storage.KindCluster, teleservices.VerbRead); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return o.operator.GetOperationEvents(ctx, key, offset)
+}
+
 func (o *OperatorACL) CreateLogEntry(key SiteOperationKey, entry LogEntry) error {
 	if err := o.ClusterAction(key.SiteDomain, storage.KindCluster, teleservices.VerbUpdate); err != nil {
 		return trace.Wrap(err)
@@ -570,11 +599,11 @@ func (o *OperatorACL) GetSiteOperationCrashReport(key SiteOperationKey) (io.Read
 	return o.operator.GetSiteOperationCrashReport(key)
 }
 
-func (o *OperatorACL) GetSiteReport(key SiteKey) (io.ReadCloser, error) {
-	if err := o.ClusterAction(key.SiteDomain, storage.KindCluster, teleservices.VerbRead); err != nil {
+func (o *OperatorACL) GetSiteReport(req GetSiteReportRequest) (io.ReadCloser, error) {
+	if err := o.ClusterAction(req.SiteDomain, storage.KindCluster, teleservices.VerbRead); err != nil {
 		return nil, trace.Wrap(err)
 	}
-	return o.operator.GetSiteReport(key)
+	return o.operator.GetSiteReport(req)
 }
 
 func (o *OperatorACL) ValidateDomainName(domainName string) error {
@@ -685,11 +714,16 @@ func (o *OperatorACL) ConfigureNode(req ConfigureNodeRequest) error {
 }
 
 // GetLogForwarders returns a list of configured log forwarders
-func (o *OperatorACL) GetLogForwarders(key SiteKey) ([]storage.LogForwarder, error) {
+func (o *OperatorACL) GetLogForwarders(key SiteKey, withSecrets bool) ([]storage.LogForwarder, error) {
 	if err := o.ClusterAction(key.SiteDomain, storage.KindLogForwarder, teleservices.VerbList); err != nil {
 		return nil, trace.Wrap(err)
 	}
-	return o.operator.GetLogForwarders(key)
+	if withSecrets {
+		if err := o.ClusterAction(key.SiteDomain, storage.KindLogForwarder, teleservices.VerbUpdate); err != nil {
+			return nil, trace.Wrap(err)
+		}
+	}
+	return o.operator.GetLogForwarders(key, withSecrets)
 }
 
 // CreateLogForwarder creates a new log forwarder
@@ -730,11 +764,16 @@ func (o *OperatorACL) UpdateRetentionPolicy(req UpdateRetentionPolicyRequest) er
 	return o.operator.UpdateRetentionPolicy(req)
 }
 
-func (o *OperatorACL) GetSMTPConfig(key SiteKey) (storage.SMTPConfig, error) {
+func (o *OperatorACL) GetSMTPConfig(key SiteKey, withSecrets bool) (storage.SMTPConfig, error) {
 	if err := o.ClusterAction(key.SiteDomain, storage.KindSMTPConfig, teleservices.VerbRead); err != nil {
 		return nil, trace.Wrap(err)
 	}
-	return o.operator.GetSMTPConfig(key)
+	if withSecrets {
+		if err := o.ClusterAction(key.SiteDomain, storage.KindSMTPConfig, teleservices.VerbUpdate); err != nil {
+			return nil, trace.Wrap(err)
+		}
+	}
+	return o.operator.GetSMTPConfig(key, withSecrets)
 }
 
 func (o *OperatorACL) UpdateSMTPConfig(key SiteKey, config storage.SMTPConfig) error {
@@ -772,11 +811,16 @@ func (o *OperatorACL) DeleteAlert(key SiteKey, name string) error {
 	return o.operator.DeleteAlert(key, name)
 }
 
-func (o *OperatorACL) GetAlertTargets(key SiteKey) ([]storage.AlertTarget, error) {
+func (o *OperatorACL) GetAlertTargets(key SiteKey, withSecrets bool) ([]storage.AlertTarget, error) {
 	if err := o.ClusterAction(key.SiteDomain, storage.KindAlertTarget, teleservices.VerbList); err != nil {
 		return nil, trace.Wrap(err)
 	}
-	return o.operator.GetAlertTargets(key)
+	if withSecrets {
+		if err := o.ClusterAction(key.SiteDomain, storage.KindAlertTarget, teleservices.VerbUpdate); err != nil {
+			return nil, trace.Wrap(err)
+		}
+	}
+	return o.operator.GetAlertTargets(key, withSecrets)
 }
 
 func (o *OperatorACL) UpdateAlertTarget(key SiteKey, target storage.AlertTarget) error {
@@ -786,11 +830,11 @@ func (o *OperatorACL) UpdateAlertTarget(key SiteKey, target storage.AlertTarget)
 	return o.operator.UpdateAlertTarget(key, target)
 }
 
-func (o *OperatorACL) DeleteAlertTarget(key SiteKey) error {
+func (o *OperatorACL) DeleteAlertTarget(key SiteKey, name string) error {
 	if err := o.ClusterAction(key.SiteDomain, storage.KindAlertTarget, teleservices.VerbUpdate); err != nil {
 		return trace.Wrap(err)
 	}
-	return o.operator.DeleteAlertTarget(key)
+	return o.operator.DeleteAlertTarget(key, name)
 }
 
 // GetClusterEnvironmentVariables retrieves the cluster runtime environment variables
@@ -817,6 +861,64 @@ func (o *OperatorACL) UpdateClusterConfiguration(req UpdateClusterConfigRequest)
 	return o.operator.UpdateClusterConfiguration(req)
 }
 
+// GetBackupSchedules returns the list of cluster backup schedules
+func (o *OperatorACL) GetBackupSchedules(key SiteKey, withSecrets bool) ([]storage.BackupSchedule, error) {
+	if err := o.ClusterAction(key.SiteDomain, storage.KindBackupSchedule, teleservices.VerbList); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if withSecrets {
+		if err := o.ClusterAction(key.SiteDomain, storage.KindBackupSchedule, teleservices.VerbRead); err != nil {
+			return nil, trace.Wrap(err)
+		}
+	}
+	return o.operator.GetBackupSchedules(key, withSecrets)
+}
+
+// UpsertBackupSchedule creates or updates the specified backup schedule
+func (o *OperatorACL) UpsertBackupSchedule(key SiteKey, schedule storage.BackupSchedule) error {
+	if err := o.ClusterAction(key.SiteDomain, storage.KindBackupSchedule, teleservices.VerbUpdate); err != nil {
+		return trace.Wrap(err)
+	}
+	return o.operator.UpsertBackupSchedule(key, schedule)
+}
+
+// DeleteBackupSchedule deletes the backup schedule specified with name
+func (o *OperatorACL) DeleteBackupSchedule(key SiteKey, name string) error {
+	if err := o.ClusterAction(key.SiteDomain, storage.KindBackupSchedule, teleservices.VerbDelete); err != nil {
+		return trace.Wrap(err)
+	}
+	return o.operator.DeleteBackupSchedule(key, name)
+}
+
+// GetAuditEvents returns the cluster audit events that match the filter
+func (o *OperatorACL) GetAuditEvents(key SiteKey, filter storage.AuditEventFilter) ([]storage.AuditEvent, error) {
+	if err := o.ClusterAction(key.SiteDomain, storage.KindAuditEvent, teleservices.VerbList); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return o.operator.GetAuditEvents(key, filter)
+}
+
+// GetBLOBStatus returns the outcome of the last integrity check
+// on each package storage peer
+func (o *OperatorACL) GetBLOBStatus(key SiteKey) (*BLOBStatus, error) {
+	if err := o.ClusterAction(key.SiteDomain, storage.KindCluster, teleservices.VerbRead); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return o.operator.GetBLOBStatus(key)
+}
+
+// CheckBLOBs verifies the integrity of the packages stored on the serving peer
+func (o *OperatorACL) CheckBLOBs(req CheckBLOBsRequest) (*storage.BLOBIntegrity, error) {
+	verb := teleservices.VerbRead
+	if req.Repair {
+		verb = teleservices.VerbUpdate
+	}
+	if err := o.ClusterAction(req.ClusterKey.SiteDomain, storage.KindCluster, verb); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return o.operator.CheckBLOBs(req)
+}
+
 func (o *OperatorACL) GetApplicationEndpoints(key SiteKey) ([]Endpoint, error) {
 	if err := o.ClusterAction(key.SiteDomain, storage.KindCluster, teleservices.VerbRead); err != nil {
 		return nil, trace.Wrap(err)
@@ -1013,6 +1115,116 @@ func (o *OperatorACL) DeleteGithubConnector(key SiteKey, name string) error {
 	return o.operator.DeleteGithubConnector(key, name)
 }
 
+// UpsertOIDCConnector creates or updates an OIDC connector
+func (o *OperatorACL) UpsertOIDCConnector(key SiteKey, connector teleservices.OIDCConnector) error {
+	if err := o.AuthConnectorActions(teleservices.KindOIDCConnector, teleservices.VerbCreate, teleservices.VerbUpdate); err != nil {
+		return trace.Wrap(err)
+	}
+	return o.operator.UpsertOIDCConnector(key, connector)
+}
+
+// GetOIDCConnector returns an OIDC connector by name
+//
+// Returned connector exclude client secret unless withSecrets is true.
+func (o *OperatorACL) GetOIDCConnector(key SiteKey, name string, withSecrets bool) (teleservices.OIDCConnector, error) {
+	if err := o.AuthConnectorActions(teleservices.KindOIDCConnector, teleservices.VerbRead); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return o.operator.GetOIDCConnector(key, name, withSecrets)
+}
+
+// DeleteOIDCConnector deletes an OIDC connector by name
+func (o *OperatorACL) DeleteOIDCConnector(key SiteKey, name string) error {
+	if err := o.AuthConnectorActions(teleservices.KindOIDCConnector, teleservices.VerbDelete); err != nil {
+		return trace.Wrap(err)
+	}
+	return o.operator.DeleteOIDCConnector(key, name)
+}
+
+// UpsertSAMLConnector creates or updates a SAML connector
+func (o *OperatorACL) UpsertSAMLConnector(key SiteKey, connector teleservices.SAMLConnector) error {
+	if err := o.AuthConnectorActions(teleservices.KindSAMLConnector, teleservices.VerbCreate, teleservices.VerbUpdate); err != nil {
+		return trace.Wrap(err)
+	}
+	return o.operator.UpsertSAMLConnector(key, connector)
+}
+
+// GetSAMLConnector returns a SAML connector by name
+//
+// Returned connector exclude signing key unless withSecrets is true.
+func (o *OperatorACL) GetSAMLConnector(key SiteKey, name string, withSecrets bool) (teleservices.SAMLConnector, error) {
+	if err := o.AuthConnectorActions(teleservices.KindSAMLConnector, teleservices.VerbRead); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return o.operator.GetSAMLConnector(key, name, withSecrets)
+}
+
+// DeleteSAMLConnector deletes a SAML connector by name
+func (o *OperatorACL) DeleteSAMLConnector(key SiteKey, name string) error {
+	if err := o.AuthConnectorActions(teleservices.KindSAMLConnector, teleservices.VerbDelete); err != nil {
+		return trace.Wrap(err)
+	}
+	return o.operator.DeleteSAMLConnector(key, name)
+}
+
+// UpsertRole creates or updates a role
+func (o *OperatorACL) UpsertRole(key SiteKey, role teleservices.Role) error {
+	if role.GetMetadata().Labels[constants.SystemLabel] == constants.True {
+		return trace.AccessDenied("modifying roles with %v label is prohibited", constants.SystemLabel)
+	}
+	if err := o.roleActions(teleservices.VerbCreate, teleservices.VerbUpdate); err != nil {
+		return trace.Wrap(err)
+	}
+	return o.operator.UpsertRole(key, role)
+}
+
+// GetRole returns a role by name
+func (o *OperatorACL) GetRole(key SiteKey, name string) (teleservices.Role, error) {
+	if err := o.roleActions(teleservices.VerbRead); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return o.operator.GetRole(key, name)
+}
+
+// DeleteRole deletes a role by name
+func (o *OperatorACL) DeleteRole(key SiteKey, name string) error {
+	if err := o.roleActions(teleservices.VerbDelete); err != nil {
+		return trace.Wrap(err)
+	}
+	role, err := o.operator.GetRole(key, name)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if role.GetMetadata().Labels[constants.SystemLabel] == constants.True {
+		return trace.AccessDenied("deleting roles with %v label is prohibited", constants.SystemLabel)
+	}
+	return o.operator.DeleteRole(key, name)
+}
+
+// UpsertTrustedCluster creates or updates a trusted cluster
+func (o *OperatorACL) UpsertTrustedCluster(key SiteKey, cluster teleservices.TrustedCluster) error {
+	if err := o.trustedClusterActions(teleservices.VerbCreate, teleservices.VerbUpdate); err != nil {
+		return trace.Wrap(err)
+	}
+	return o.operator.UpsertTrustedCluster(key, cluster)
+}
+
+// GetTrustedCluster returns a trusted cluster by name
+func (o *OperatorACL) GetTrustedCluster(key SiteKey, name string) (teleservices.TrustedCluster, error) {
+	if err := o.trustedClusterActions(teleservices.VerbRead); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return o.operator.GetTrustedCluster(key, name)
+}
+
+// DeleteTrustedCluster deletes a trusted cluster by name
+func (o *OperatorACL) DeleteTrustedCluster(key SiteKey, name string) error {
+	if err := o.trustedClusterActions(teleservices.VerbDelete); err != nil {
+		return trace.Wrap(err)
+	}
+	return o.operator.DeleteTrustedCluster(key, name)
+}
+
 // UpsertAuthGateway updates auth gateway configuration.
 func (o *OperatorACL) UpsertAuthGateway(key SiteKey, gw storage.AuthGateway) error {
 	if err := o.ClusterAction(key.SiteDomain, storage.KindCluster, teleservices.VerbUpdate); err != nil {
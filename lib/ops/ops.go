@@ -127,6 +127,9 @@ type Operator interface {
 	Identity
 	RuntimeEnvironment
 	ClusterConfiguration
+	Backups
+	Audit
+	BLOBs
 }
 
 // Accounts represents a collection of accounts in the portal
@@ -301,7 +304,7 @@ type Sites interface {
 	CompleteFinalInstallStep(CompleteFinalInstallStepRequest) error
 
 	// GetSiteReport returns a tarball that contains all debugging information gathered for the site
-	GetSiteReport(SiteKey) (io.ReadCloser, error)
+	GetSiteReport(GetSiteReportRequest) (io.ReadCloser, error)
 
 	// SignTLSKey signs X509 Public Key with X509 certificate authority of this site
 	SignTLSKey(TLSSignRequest) (*TLSSignResponse, error)
@@ -443,6 +446,26 @@ type ActivateSiteRequest struct {
 	StartApp bool `json:"start_app"`
 }
 
+// GetSiteReportRequest is a request to collect the cluster diagnostics report
+type GetSiteReportRequest struct {
+	// AccountID is the ID of the account the cluster belongs to
+	AccountID string `json:"account_id"`
+	// SiteDomain is the name of the cluster
+	SiteDomain string `json:"site_domain"`
+	// Redact specifies whether sensitive data should be redacted from the report
+	Redact bool `json:"redact"`
+	// RedactPatterns lists additional regular expressions of data to redact
+	RedactPatterns []string `json:"redact_patterns,omitempty"`
+}
+
+// SiteKey returns a cluster key from this request
+func (r GetSiteReportRequest) SiteKey() SiteKey {
+	return SiteKey{
+		AccountID:  r.AccountID,
+		SiteDomain: r.SiteDomain,
+	}
+}
+
 // CompleteFinalInstallStepRequest is a request to mark site final install step as completed
 type CompleteFinalInstallStepRequest struct {
 	// AccountID is the ID of the account the site belongs to
@@ -497,6 +520,59 @@ type ClusterConfiguration interface {
 	UpdateClusterConfiguration(UpdateClusterConfigRequest) error
 }
 
+// Backups manages scheduled cluster backups
+type Backups interface {
+	// GetBackupSchedules returns the list of cluster backup schedules.
+	// Destination credentials are only returned if withSecrets is true
+	GetBackupSchedules(key SiteKey, withSecrets bool) ([]storage.BackupSchedule, error)
+	// UpsertBackupSchedule creates or updates the specified backup schedule
+	UpsertBackupSchedule(SiteKey, storage.BackupSchedule) error
+	// DeleteBackupSchedule deletes the backup schedule specified with name
+	DeleteBackupSchedule(key SiteKey, name string) error
+}
+
+// BLOBs provides access to the integrity of the cluster package storage
+type BLOBs interface {
+	// GetBLOBStatus returns the outcome of the last integrity check
+	// on each package storage peer
+	GetBLOBStatus(SiteKey) (*BLOBStatus, error)
+	// CheckBLOBs verifies the integrity of the packages
+	// stored on the serving peer
+	CheckBLOBs(CheckBLOBsRequest) (*storage.BLOBIntegrity, error)
+}
+
+// BLOBStatus describes the integrity of the cluster package storage
+type BLOBStatus struct {
+	// Peers lists the package storage peers with
+	// the outcome of their last integrity check
+	Peers []storage.Peer `json:"peers"`
+}
+
+// IsHealthy returns true if no peer has reported unrepaired problems
+func (r BLOBStatus) IsHealthy() bool {
+	for _, peer := range r.Peers {
+		if peer.Integrity != nil && !peer.Integrity.IsHealthy() {
+			return false
+		}
+	}
+	return true
+}
+
+// CheckBLOBsRequest is a request to verify the integrity of the package storage
+type CheckBLOBsRequest struct {
+	// ClusterKey identifies the cluster
+	ClusterKey SiteKey `json:"cluster_key"`
+	// Repair sets aside and fetches again the corrupted packages
+	// and replicates the packages stored on too few peers
+	Repair bool `json:"repair"`
+}
+
+// Audit provides access to the audit log of cluster changes
+type Audit interface {
+	// GetAuditEvents returns the cluster audit events that match the filter
+	GetAuditEvents(SiteKey, storage.AuditEventFilter) ([]storage.AuditEvent, error)
+}
+
 // ClusterCertificate represents the cluster certificate
 type ClusterCertificate struct {
 	// Certificate is the cluster certificate
@@ -634,6 +710,14 @@ type Operations interface {
 	// related to this operation periodically
 	GetSiteOperationLogs(SiteOperationKey) (io.ReadCloser, error)
 
+	// GetOperationEvents returns a stream of progress entries, phase state
+	// changes and log lines of the specified operation starting after
+	// the provided offset.
+	//
+	// The stream ends once the operation has completed and all its events
+	// have been delivered
+	GetOperationEvents(context.Context, SiteOperationKey, EventOffset) (OperationEventStream, error)
+
 	// CreateLogEntry appends the provided log entry to the operation's log file
 	CreateLogEntry(SiteOperationKey, LogEntry) error
 
@@ -1159,6 +1243,12 @@ type CreateSiteAppUpdateOperationRequest struct {
 	App string `json:"package"`
 	// StartAgents specifies whether the operation will automatically start the update agents
 	StartAgents bool `json:"start_agents"`
+	// Strategy optionally specifies how the nodes are rolled during the update
+	Strategy *storage.UpdateStrategy `json:"strategy,omitempty"`
+	// AutoRollback specifies whether the update is rolled back automatically on failure
+	AutoRollback bool `json:"auto_rollback,omitempty"`
+	// Parallelism specifies the maximum number of plan phases to execute concurrently
+	Parallelism int `json:"parallelism,omitempty"`
 }
 
 // Check validates this request
@@ -1657,8 +1747,9 @@ type SetOperationStateRequest struct {
 
 // LogForwarders defines the interface to manage log forwarders
 type LogForwarders interface {
-	// GetLogForwarders retrieves the list of active log forwarders
-	GetLogForwarders(key SiteKey) ([]storage.LogForwarder, error)
+	// GetLogForwarders retrieves the list of active log forwarders.
+	// Client keys and authentication tokens are only returned if withSecrets is true
+	GetLogForwarders(key SiteKey, withSecrets bool) ([]storage.LogForwarder, error)
 	// CreateLogForwarder creates a new log forwarder
 	CreateLogForwarder(key SiteKey, forwarder storage.LogForwarder) error
 	// UpsertLogForwarder updates an existing log forwarder
@@ -1669,8 +1760,9 @@ type LogForwarders interface {
 
 // SMTP defines the interface to manage cluster SMTP configuration
 type SMTP interface {
-	// GetSMTPConfig returns the cluster SMTP configuration
-	GetSMTPConfig(SiteKey) (storage.SMTPConfig, error)
+	// GetSMTPConfig returns the cluster SMTP configuration.
+	// The password is only returned if withSecrets is true
+	GetSMTPConfig(key SiteKey, withSecrets bool) (storage.SMTPConfig, error)
 	// UpdateSMTPConfig updates the cluster SMTP configuration
 	UpdateSMTPConfig(SiteKey, storage.SMTPConfig) error
 	// DeleteSMTPConfig deletes the cluster STMP configuration
@@ -1689,12 +1781,13 @@ type Monitoring interface {
 	UpdateAlert(SiteKey, storage.Alert) error
 	// DeleteAlert deletes the monitoring alert specified with name
 	DeleteAlert(key SiteKey, name string) error
-	// GetAlertTargets returns the list of configured monitoring alert targets
-	GetAlertTargets(SiteKey) ([]storage.AlertTarget, error)
-	// UpdateAlertTarget updates cluster's alert target to the specified
+	// GetAlertTargets returns the list of configured monitoring alert targets.
+	// Delivery credentials are only returned if withSecrets is true
+	GetAlertTargets(key SiteKey, withSecrets bool) ([]storage.AlertTarget, error)
+	// UpdateAlertTarget creates or updates the specified cluster alert target
 	UpdateAlertTarget(SiteKey, storage.AlertTarget) error
-	// DeleteAlertTarget deletes the monitoring alert target
-	DeleteAlertTarget(SiteKey) error
+	// DeleteAlertTarget deletes the monitoring alert target specified with name
+	DeleteAlertTarget(key SiteKey, name string) error
 }
 
 // UpdateRetentionPolicyRequest is a request to update retention policy
@@ -1808,6 +1901,30 @@ type Identity interface {
 	GetGithubConnectors(key SiteKey, withSecrets bool) ([]teleservices.GithubConnector, error)
 	// DeleteGithubConnector deletes a Github connector by name
 	DeleteGithubConnector(key SiteKey, name string) error
+	// UpsertOIDCConnector creates or updates an OIDC connector
+	UpsertOIDCConnector(key SiteKey, conn teleservices.OIDCConnector) error
+	// GetOIDCConnector returns an OIDC connector by its name
+	GetOIDCConnector(key SiteKey, name string, withSecrets bool) (teleservices.OIDCConnector, error)
+	// DeleteOIDCConnector deletes an OIDC connector by name
+	DeleteOIDCConnector(key SiteKey, name string) error
+	// UpsertSAMLConnector creates or updates a SAML connector
+	UpsertSAMLConnector(key SiteKey, conn teleservices.SAMLConnector) error
+	// GetSAMLConnector returns a SAML connector by its name
+	GetSAMLConnector(key SiteKey, name string, withSecrets bool) (teleservices.SAMLConnector, error)
+	// DeleteSAMLConnector deletes a SAML connector by name
+	DeleteSAMLConnector(key SiteKey, name string) error
+	// UpsertRole creates or updates a role
+	UpsertRole(key SiteKey, role teleservices.Role) error
+	// GetRole returns a role by its name
+	GetRole(key SiteKey, name string) (teleservices.Role, error)
+	// DeleteRole deletes a role by name
+	DeleteRole(key SiteKey, name string) error
+	// UpsertTrustedCluster creates or updates a trusted cluster
+	UpsertTrustedCluster(key SiteKey, cluster teleservices.TrustedCluster) error
+	// GetTrustedCluster returns a trusted cluster by its name
+	GetTrustedCluster(key SiteKey, name string) (teleservices.TrustedCluster, error)
+	// DeleteTrustedCluster deletes a trusted cluster by name
+	DeleteTrustedCluster(key SiteKey, name string) error
 	// UpsertAuthGateway updates auth gateway configuration
 	UpsertAuthGateway(SiteKey, storage.AuthGateway) error
 	// GetAuthGateway returns auth gateway configuration
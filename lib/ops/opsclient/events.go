@@ -0,0 +1,78 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package opsclient
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/url"
+
+	"github.com/gravitational/gravity/lib/httplib"
+	"github.com/gravitational/gravity/lib/ops"
+
+	"github.com/gravitational/trace"
+)
+
+// GetOperationEvents returns a stream of events of the specified operation
+// starting after the provided offset
+func (c *Client) GetOperationEvents(ctx context.Context, key ops.SiteOperationKey, offset ops.EventOffset) (ops.OperationEventStream, error) {
+	endpoint := c.Endpoint("accounts", key.AccountID, "sites", key.SiteDomain, "operations", "common", key.OperationID, "events")
+	query := url.Values{"offset": []string{offset.String()}}
+	conn, err := httplib.SetupWebsocketClient(ctx, &c.Client, endpoint+"?"+query.Encode(), c.dialer)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &eventStream{
+		conn:    conn,
+		decoder: json.NewDecoder(conn),
+	}, nil
+}
+
+// eventStream receives operation events over a web socket connection
+type eventStream struct {
+	conn    io.ReadCloser
+	decoder *json.Decoder
+	// completed is whether the operation completion has been received
+	completed bool
+}
+
+// Recv returns the next event from the stream.
+// It returns io.EOF once the operation has completed and all
+// its events have been delivered
+func (s *eventStream) Recv() (*ops.OperationEvent, error) {
+	var event ops.OperationEvent
+	err := s.decoder.Decode(&event)
+	if err != nil {
+		if s.completed && err == io.EOF {
+			return nil, io.EOF
+		}
+		return nil, trace.ConnectionProblem(err, "operation event stream interrupted")
+	}
+	if event.Type == ops.OperationEventError {
+		return nil, trace.Errorf("operation event stream failed: %v", event.Error)
+	}
+	if event.IsCompleted() {
+		s.completed = true
+	}
+	return &event, nil
+}
+
+// Close closes the underlying connection
+func (s *eventStream) Close() error {
+	return s.conn.Close()
+}
@@ -675,8 +675,13 @@ func (c *Client) GetSiteOperationCrashReport(key ops.SiteOperationKey) (io.ReadC
 	return file.Body(), nil
 }
 
-func (c *Client) GetSiteReport(key ops.SiteKey) (io.ReadCloser, error) {
-	file, err := c.GetFile(c.Endpoint("accounts", key.AccountID, "sites", key.SiteDomain, "report"), url.Values{})
+func (c *Client) GetSiteReport(req ops.GetSiteReportRequest) (io.ReadCloser, error) {
+	query := url.Values{}
+	if req.Redact {
+		query.Set("redact", "true")
+		query["redact-pattern"] = req.RedactPatterns
+	}
+	file, err := c.GetFile(c.Endpoint("accounts", req.AccountID, "sites", req.SiteDomain, "report"), query)
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
@@ -916,9 +921,11 @@ func (c *Client) ConfigureNode(req ops.ConfigureNodeRequest) error {
 	return trace.BadParameter("this method is only supported by local operator")
 }
 
-// GetLogForwarders returns a list of configured log forwarders
-func (c *Client) GetLogForwarders(key ops.SiteKey) ([]storage.LogForwarder, error) {
-	out, err := c.Get(c.Endpoint("accounts", key.AccountID, "sites", key.SiteDomain, "logs", "forwarders"), url.Values{})
+// GetLogForwarders returns a list of configured log forwarders.
+// Client keys and authentication tokens are only returned if withSecrets is true
+func (c *Client) GetLogForwarders(key ops.SiteKey, withSecrets bool) ([]storage.LogForwarder, error) {
+	out, err := c.Get(c.Endpoint("accounts", key.AccountID, "sites", key.SiteDomain, "logs", "forwarders"),
+		url.Values{constants.WithSecretsParam: []string{fmt.Sprintf("%t", withSecrets)}})
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
@@ -1000,10 +1007,12 @@ func (c *Client) UpdateRetentionPolicy(req ops.UpdateRetentionPolicyRequest) err
 	return trace.Wrap(err)
 }
 
-// GetSMTPConfig returns the cluster SMTP configuration
-func (c *Client) GetSMTPConfig(key ops.SiteKey) (storage.SMTPConfig, error) {
+// GetSMTPConfig returns the cluster SMTP configuration.
+// The password is only returned if withSecrets is true
+func (c *Client) GetSMTPConfig(key ops.SiteKey, withSecrets bool) (storage.SMTPConfig, error) {
 	response, err := c.Get(c.Endpoint(
-		"accounts", key.AccountID, "sites", key.SiteDomain, "smtp"), url.Values{})
+		"accounts", key.AccountID, "sites", key.SiteDomain, "smtp"),
+		url.Values{constants.WithSecretsParam: []string{fmt.Sprintf("%t", withSecrets)}})
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
@@ -1081,10 +1090,12 @@ func (c *Client) DeleteAlert(key ops.SiteKey, name string) error {
 	return trace.Wrap(err)
 }
 
-// GetAlertTargets returns a list of monitoring alert targets for the cluster
-func (c *Client) GetAlertTargets(key ops.SiteKey) ([]storage.AlertTarget, error) {
+// GetAlertTargets returns a list of monitoring alert targets for the cluster.
+// Delivery credentials are only returned if withSecrets is true
+func (c *Client) GetAlertTargets(key ops.SiteKey, withSecrets bool) ([]storage.AlertTarget, error) {
 	response, err := c.Get(c.Endpoint(
-		"accounts", key.AccountID, "sites", key.SiteDomain, "monitoring", "alert-targets"), url.Values{})
+		"accounts", key.AccountID, "sites", key.SiteDomain, "monitoring", "alert-targets"),
+		url.Values{constants.WithSecretsParam: []string{fmt.Sprintf("%t", withSecrets)}})
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
@@ -1116,9 +1127,21 @@ func (c *Client) UpdateAlertTarget(key ops.SiteKey, target storage.AlertTarget)
 	return trace.Wrap(err)
 }
 
-// DeleteAlertTarget deletes the cluster monitoring alert target
-func (c *Client) DeleteAlertTarget(key ops.SiteKey) error {
-	_, err := c.Delete(c.Endpoint("accounts", key.AccountID, "sites", key.SiteDomain, "monitoring", "alert-targets"))
+// DeleteAlertTarget deletes the cluster monitoring alert target specified with name
+func (c *Client) DeleteAlertTarget(key ops.SiteKey, name string) error {
+	if name == "" {
+		_, err := c.Delete(c.Endpoint("accounts", key.AccountID, "sites", key.SiteDomain, "monitoring", "alert-targets"))
+		return trace.Wrap(err)
+	}
+	_, err := c.Delete(c.Endpoint("accounts", key.AccountID, "sites", key.SiteDomain, "monitoring", "alert-targets", name))
+	return trace.Wrap(err)
+}
+
+// NotifyAlertTargets delivers the alert event to the cluster webhook, Slack
+// and PagerDuty alert targets
+func (c *Client) NotifyAlertTargets(key ops.SiteKey, event monitoring.AlertEvent) error {
+	_, err := c.PostJSON(c.Endpoint("accounts", key.AccountID, "sites", key.SiteDomain, "monitoring", "alert-targets", "notify"),
+		event)
 	return trace.Wrap(err)
 }
 
@@ -1169,6 +1192,103 @@ func (c *Client) UpdateClusterConfiguration(req ops.UpdateClusterConfigRequest)
 	return nil
 }
 
+// GetBackupSchedules returns the list of cluster backup schedules.
+// Destination credentials are only returned if withSecrets is true
+func (c *Client) GetBackupSchedules(key ops.SiteKey, withSecrets bool) ([]storage.BackupSchedule, error) {
+	response, err := c.Get(c.Endpoint(
+		"accounts", key.AccountID, "sites", key.SiteDomain, "backups"),
+		url.Values{constants.WithSecretsParam: []string{fmt.Sprintf("%t", withSecrets)}})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+
+	var items []json.RawMessage
+	if err = json.Unmarshal(response.Bytes(), &items); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	schedules := make([]storage.BackupSchedule, len(items))
+	for i, item := range items {
+		schedule, err := storage.UnmarshalBackupSchedule(item)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		schedules[i] = schedule
+	}
+	return schedules, nil
+}
+
+// UpsertBackupSchedule creates or updates the specified backup schedule
+func (c *Client) UpsertBackupSchedule(key ops.SiteKey, schedule storage.BackupSchedule) error {
+	bytes, err := storage.MarshalBackupSchedule(schedule)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	_, err = c.PutJSON(c.Endpoint("accounts", key.AccountID, "sites", key.SiteDomain, "backups"),
+		&UpsertResourceRawReq{Resource: bytes})
+	return trace.Wrap(err)
+}
+
+// DeleteBackupSchedule deletes the backup schedule specified with name
+func (c *Client) DeleteBackupSchedule(key ops.SiteKey, name string) error {
+	_, err := c.Delete(c.Endpoint("accounts", key.AccountID, "sites", key.SiteDomain, "backups", name))
+	return trace.Wrap(err)
+}
+
+// GetAuditEvents returns the cluster audit events that match the filter
+func (c *Client) GetAuditEvents(key ops.SiteKey, filter storage.AuditEventFilter) ([]storage.AuditEvent, error) {
+	query := url.Values{}
+	if !filter.Since.IsZero() {
+		query.Set("since", filter.Since.Format(time.RFC3339))
+	}
+	if filter.User != "" {
+		query.Set("user", filter.User)
+	}
+	if filter.Kind != "" {
+		query.Set("kind", filter.Kind)
+	}
+	response, err := c.Get(c.Endpoint(
+		"accounts", key.AccountID, "sites", key.SiteDomain, "audit"), query)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+
+	var events []storage.AuditEvent
+	if err = json.Unmarshal(response.Bytes(), &events); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return events, nil
+}
+
+// GetBLOBStatus returns the outcome of the last integrity check
+// on each package storage peer
+func (c *Client) GetBLOBStatus(key ops.SiteKey) (*ops.BLOBStatus, error) {
+	response, err := c.Get(c.Endpoint(
+		"accounts", key.AccountID, "sites", key.SiteDomain, "blobs", "status"), url.Values{})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var status ops.BLOBStatus
+	if err = json.Unmarshal(response.Bytes(), &status); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &status, nil
+}
+
+// CheckBLOBs verifies the integrity of the packages stored on the serving peer
+func (c *Client) CheckBLOBs(req ops.CheckBLOBsRequest) (*storage.BLOBIntegrity, error) {
+	response, err := c.PostJSON(c.Endpoint(
+		"accounts", req.ClusterKey.AccountID, "sites", req.ClusterKey.SiteDomain, "blobs", "check"), req)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var integrity storage.BLOBIntegrity
+	if err = json.Unmarshal(response.Bytes(), &integrity); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &integrity, nil
+}
+
 func (c *Client) GetApplicationEndpoints(key ops.SiteKey) ([]ops.Endpoint, error) {
 	out, err := c.Get(c.Endpoint("accounts", key.AccountID, "sites", key.SiteDomain, "endpoints"), url.Values{})
 	if err != nil {
@@ -1467,6 +1587,148 @@ func (c *Client) DeleteGithubConnector(key ops.SiteKey, name string) error {
 	return trace.Wrap(err)
 }
 
+// UpsertOIDCConnector creates or updates an OIDC connector
+func (c *Client) UpsertOIDCConnector(key ops.SiteKey, connector teleservices.OIDCConnector) error {
+	data, err := teleservices.GetOIDCConnectorMarshaler().MarshalOIDCConnector(connector)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	_, err = c.PostJSON(c.Endpoint("accounts", key.AccountID, "sites", key.SiteDomain, "oidc", "connectors"),
+		&UpsertResourceRawReq{
+			Resource: data,
+		})
+	return trace.Wrap(err)
+}
+
+// GetOIDCConnector returns an OIDC connector by name
+//
+// Returned connector exclude client secret unless withSecrets is true.
+func (c *Client) GetOIDCConnector(key ops.SiteKey, name string, withSecrets bool) (teleservices.OIDCConnector, error) {
+	if name == "" {
+		return nil, trace.BadParameter("missing connector name")
+	}
+	out, err := c.Get(c.Endpoint("accounts", key.AccountID, "sites", key.SiteDomain, "oidc", "connectors", name),
+		url.Values{constants.WithSecretsParam: []string{fmt.Sprintf("%t", withSecrets)}})
+	if err != nil {
+		return nil, err
+	}
+	return teleservices.GetOIDCConnectorMarshaler().UnmarshalOIDCConnector(out.Bytes())
+}
+
+// DeleteOIDCConnector deletes an OIDC connector by name
+func (c *Client) DeleteOIDCConnector(key ops.SiteKey, name string) error {
+	if name == "" {
+		return trace.BadParameter("missing connector name")
+	}
+	_, err := c.Delete(c.Endpoint("accounts", key.AccountID, "sites", key.SiteDomain, "oidc", "connectors", name))
+	return trace.Wrap(err)
+}
+
+// UpsertSAMLConnector creates or updates a SAML connector
+func (c *Client) UpsertSAMLConnector(key ops.SiteKey, connector teleservices.SAMLConnector) error {
+	data, err := teleservices.GetSAMLConnectorMarshaler().MarshalSAMLConnector(connector)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	_, err = c.PostJSON(c.Endpoint("accounts", key.AccountID, "sites", key.SiteDomain, "saml", "connectors"),
+		&UpsertResourceRawReq{
+			Resource: data,
+		})
+	return trace.Wrap(err)
+}
+
+// GetSAMLConnector returns a SAML connector by name
+//
+// Returned connector exclude signing key unless withSecrets is true.
+func (c *Client) GetSAMLConnector(key ops.SiteKey, name string, withSecrets bool) (teleservices.SAMLConnector, error) {
+	if name == "" {
+		return nil, trace.BadParameter("missing connector name")
+	}
+	out, err := c.Get(c.Endpoint("accounts", key.AccountID, "sites", key.SiteDomain, "saml", "connectors", name),
+		url.Values{constants.WithSecretsParam: []string{fmt.Sprintf("%t", withSecrets)}})
+	if err != nil {
+		return nil, err
+	}
+	return teleservices.GetSAMLConnectorMarshaler().UnmarshalSAMLConnector(out.Bytes())
+}
+
+// DeleteSAMLConnector deletes a SAML connector by name
+func (c *Client) DeleteSAMLConnector(key ops.SiteKey, name string) error {
+	if name == "" {
+		return trace.BadParameter("missing connector name")
+	}
+	_, err := c.Delete(c.Endpoint("accounts", key.AccountID, "sites", key.SiteDomain, "saml", "connectors", name))
+	return trace.Wrap(err)
+}
+
+// UpsertRole creates or updates a role
+func (c *Client) UpsertRole(key ops.SiteKey, role teleservices.Role) error {
+	data, err := teleservices.GetRoleMarshaler().MarshalRole(role)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	_, err = c.PostJSON(c.Endpoint("accounts", key.AccountID, "sites", key.SiteDomain, "roles"),
+		&UpsertResourceRawReq{
+			Resource: data,
+		})
+	return trace.Wrap(err)
+}
+
+// GetRole returns a role by name
+func (c *Client) GetRole(key ops.SiteKey, name string) (teleservices.Role, error) {
+	if name == "" {
+		return nil, trace.BadParameter("missing role name")
+	}
+	out, err := c.Get(c.Endpoint("accounts", key.AccountID, "sites", key.SiteDomain, "roles", name), url.Values{})
+	if err != nil {
+		return nil, err
+	}
+	return teleservices.GetRoleMarshaler().UnmarshalRole(out.Bytes())
+}
+
+// DeleteRole deletes a role by name
+func (c *Client) DeleteRole(key ops.SiteKey, name string) error {
+	if name == "" {
+		return trace.BadParameter("missing role name")
+	}
+	_, err := c.Delete(c.Endpoint("accounts", key.AccountID, "sites", key.SiteDomain, "roles", name))
+	return trace.Wrap(err)
+}
+
+// UpsertTrustedCluster creates or updates a trusted cluster
+func (c *Client) UpsertTrustedCluster(key ops.SiteKey, cluster teleservices.TrustedCluster) error {
+	data, err := teleservices.GetTrustedClusterMarshaler().Marshal(cluster)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	_, err = c.PostJSON(c.Endpoint("accounts", key.AccountID, "sites", key.SiteDomain, "trustedclusters"),
+		&UpsertResourceRawReq{
+			Resource: data,
+		})
+	return trace.Wrap(err)
+}
+
+// GetTrustedCluster returns a trusted cluster by name
+func (c *Client) GetTrustedCluster(key ops.SiteKey, name string) (teleservices.TrustedCluster, error) {
+	if name == "" {
+		return nil, trace.BadParameter("missing trusted cluster name")
+	}
+	out, err := c.Get(c.Endpoint("accounts", key.AccountID, "sites", key.SiteDomain, "trustedclusters", name), url.Values{})
+	if err != nil {
+		return nil, err
+	}
+	return teleservices.GetTrustedClusterMarshaler().Unmarshal(out.Bytes())
+}
+
+// DeleteTrustedCluster deletes a trusted cluster by name
+func (c *Client) DeleteTrustedCluster(key ops.SiteKey, name string) error {
+	if name == "" {
+		return trace.BadParameter("missing trusted cluster name")
+	}
+	_, err := c.Delete(c.Endpoint("accounts", key.AccountID, "sites", key.SiteDomain, "trustedclusters", name))
+	return trace.Wrap(err)
+}
+
 // UpsertAuthGateway updates auth gateway configuration.
 func (c *Client) UpsertAuthGateway(key ops.SiteKey, gw storage.AuthGateway) error {
 	bytes, err := storage.MarshalAuthGateway(gw)
@@ -0,0 +1,60 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package opshandler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gravitational/gravity/lib/storage"
+
+	"github.com/gravitational/roundtrip"
+	"github.com/gravitational/trace"
+	"github.com/julienschmidt/httprouter"
+)
+
+/* getAuditEvents returns the cluster audit events
+
+     GET /portal/v1/accounts/:account_id/sites/:site_domain/audit?since=<time>&user=<user>&kind=<kind>
+
+   Success Response:
+
+     []storage.AuditEvent
+*/
+func (h *WebHandler) getAuditEvents(w http.ResponseWriter, r *http.Request, p httprouter.Params, context *HandlerContext) error {
+	query := r.URL.Query()
+	filter := storage.AuditEventFilter{
+		User: query.Get("user"),
+		Kind: query.Get("kind"),
+	}
+	if since := query.Get("since"); since != "" {
+		var err error
+		filter.Since, err = time.Parse(time.RFC3339, since)
+		if err != nil {
+			return trace.BadParameter("invalid since %q: %v", since, err)
+		}
+	}
+	events, err := context.Operator.GetAuditEvents(siteKey(p), filter)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if events == nil {
+		events = []storage.AuditEvent{}
+	}
+	roundtrip.ReplyJSON(w, http.StatusOK, events)
+	return nil
+}
@@ -0,0 +1,107 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package opshandler
+
+import (
+	"net/http"
+
+	"github.com/gravitational/gravity/lib/constants"
+	"github.com/gravitational/gravity/lib/ops/opsclient"
+	"github.com/gravitational/gravity/lib/storage"
+
+	"github.com/gravitational/roundtrip"
+	telehttplib "github.com/gravitational/teleport/lib/httplib"
+	"github.com/gravitational/trace"
+	"github.com/jonboulle/clockwork"
+	"github.com/julienschmidt/httprouter"
+)
+
+/* getBackupSchedules returns a list of backup schedules for the cluster
+
+     GET /portal/v1/accounts/:account_id/sites/:site_domain/backups?with_secrets=<true|false>
+
+   Destination credentials are only returned if with_secrets is set.
+
+   Success Response:
+
+     []storage.BackupSchedule
+*/
+func (h *WebHandler) getBackupSchedules(w http.ResponseWriter, r *http.Request, p httprouter.Params, context *HandlerContext) error {
+	withSecrets, _, err := telehttplib.ParseBool(r.URL.Query(), constants.WithSecretsParam)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	schedules, err := context.Operator.GetBackupSchedules(siteKey(p), withSecrets)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	roundtrip.ReplyJSON(w, http.StatusOK, schedules)
+	return nil
+}
+
+/* upsertBackupSchedule creates or updates the cluster backup schedule
+
+     PUT /portal/v1/accounts/:account_id/sites/:site_domain/backups
+
+   Success Response:
+
+     {
+       "message": "backup schedule updated"
+     }
+*/
+func (h *WebHandler) upsertBackupSchedule(w http.ResponseWriter, r *http.Request, p httprouter.Params, context *HandlerContext) error {
+	var req opsclient.UpsertResourceRawReq
+	if err := telehttplib.ReadJSON(r, &req); err != nil {
+		return trace.Wrap(err)
+	}
+
+	schedule, err := storage.UnmarshalBackupSchedule(req.Resource)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if req.TTL != 0 {
+		schedule.SetTTL(clockwork.NewRealClock(), req.TTL)
+	}
+
+	err = context.Operator.UpsertBackupSchedule(siteKey(p), schedule)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	roundtrip.ReplyJSON(w, http.StatusOK, statusOK("backup schedule updated"))
+	return nil
+}
+
+/* deleteBackupSchedule deletes the cluster backup schedule
+
+     DELETE /portal/v1/accounts/:account_id/sites/:site_domain/backups/:name
+
+   Success Response:
+
+     {
+       "message": "backup schedule deleted"
+     }
+*/
+func (h *WebHandler) deleteBackupSchedule(w http.ResponseWriter, r *http.Request, p httprouter.Params, context *HandlerContext) error {
+	err := context.Operator.DeleteBackupSchedule(siteKey(p), p.ByName("name"))
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	roundtrip.ReplyJSON(w, http.StatusOK, statusOK("backup schedule deleted"))
+	return nil
+}
@@ -0,0 +1,69 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package opshandler
+
+import (
+	"net/http"
+
+	"github.com/gravitational/gravity/lib/ops"
+
+	"github.com/gravitational/roundtrip"
+	telehttplib "github.com/gravitational/teleport/lib/httplib"
+	"github.com/gravitational/trace"
+	"github.com/julienschmidt/httprouter"
+)
+
+/* getBLOBStatus returns the outcome of the last integrity check on each package storage peer
+
+     GET /portal/v1/accounts/:account_id/sites/:site_domain/blobs/status
+
+   Success Response:
+
+     ops.BLOBStatus
+*/
+func (h *WebHandler) getBLOBStatus(w http.ResponseWriter, r *http.Request, p httprouter.Params, context *HandlerContext) error {
+	status, err := context.Operator.GetBLOBStatus(siteKey(p))
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	roundtrip.ReplyJSON(w, http.StatusOK, status)
+	return nil
+}
+
+/* checkBLOBs verifies the integrity of the packages stored on the serving peer
+
+     POST /portal/v1/accounts/:account_id/sites/:site_domain/blobs/check
+
+   Input: ops.CheckBLOBsRequest
+
+   Success Response:
+
+     storage.BLOBIntegrity
+*/
+func (h *WebHandler) checkBLOBs(w http.ResponseWriter, r *http.Request, p httprouter.Params, context *HandlerContext) error {
+	var req ops.CheckBLOBsRequest
+	if err := telehttplib.ReadJSON(r, &req); err != nil {
+		return trace.Wrap(err)
+	}
+	req.ClusterKey = siteKey(p)
+	integrity, err := context.Operator.CheckBLOBs(req)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	roundtrip.ReplyJSON(w, http.StatusOK, integrity)
+	return nil
+}
@@ -0,0 +1,175 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package opshandler
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/gravitational/gravity/lib/ops"
+
+	"github.com/gravitational/trace"
+	"github.com/julienschmidt/httprouter"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/net/websocket"
+)
+
+/* getOperationEvents streams progress entries, phase state changes and
+   log lines of the operation until the operation completes
+
+     GET /portal/v1/accounts/:account_id/sites/:site_domain/operations/common/:operation_id/events?offset=<offset>
+
+   The events are sent as JSON messages over a web socket connection
+   or as Server-Sent Events if the request is not a web socket upgrade.
+   The stream resumes after the offset taken from the offset query
+   parameter or the Last-Event-ID header.
+
+   Success Response:
+
+     ops.OperationEvent, ...
+*/
+func (h *WebHandler) getOperationEvents(w http.ResponseWriter, r *http.Request, p httprouter.Params, context *HandlerContext) error {
+	offset, err := OperationEventOffset(r)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	stream, err := context.Operator.GetOperationEvents(context.Context, siteOperationKey(p), *offset)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	defer stream.Close()
+	ServeOperationEvents(w, r, stream)
+	return nil
+}
+
+// OperationEventOffset returns the operation event stream offset
+// requested by the client
+func OperationEventOffset(r *http.Request) (*ops.EventOffset, error) {
+	offset := r.URL.Query().Get("offset")
+	if offset == "" {
+		// browsers resume Server-Sent Events with the ID of the last event
+		offset = r.Header.Get("Last-Event-ID")
+	}
+	return ops.ParseEventOffset(offset)
+}
+
+// ServeOperationEvents sends the events from the stream to the client
+// over a web socket connection if the request is a web socket upgrade
+// request or as Server-Sent Events otherwise
+func ServeOperationEvents(w http.ResponseWriter, r *http.Request, stream ops.OperationEventStream) {
+	if strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+		server := &websocket.Server{
+			Handler: func(conn *websocket.Conn) {
+				serveWebsocketEvents(conn, stream)
+			},
+		}
+		server.ServeHTTP(w, r)
+		return
+	}
+	serveEventSource(w, r, stream)
+}
+
+func serveWebsocketEvents(conn *websocket.Conn, stream ops.OperationEventStream) {
+	defer conn.Close()
+	go func() {
+		// the client does not send anything, so the read only
+		// returns once the connection has been closed
+		io.Copy(ioutil.Discard, conn)
+		stream.Close()
+	}()
+	for {
+		event, err := stream.Recv()
+		if err == io.EOF {
+			return
+		}
+		if err != nil {
+			event = errorEvent(err)
+		}
+		if errSend := websocket.JSON.Send(conn, event); errSend != nil {
+			log.Debugf("Failed to send operation event: %v.", errSend)
+			return
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func serveEventSource(w http.ResponseWriter, r *http.Request, stream ops.OperationEventStream) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		trace.WriteError(w, trace.BadParameter("streaming is not supported"))
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	doneC := make(chan struct{})
+	defer close(doneC)
+	go func() {
+		select {
+		case <-r.Context().Done():
+			// the client has gone away
+			stream.Close()
+		case <-doneC:
+		}
+	}()
+	for {
+		event, err := stream.Recv()
+		if err == io.EOF {
+			return
+		}
+		if err != nil {
+			event = errorEvent(err)
+		}
+		data, errMarshal := json.Marshal(event)
+		if errMarshal != nil {
+			log.Warnf("Failed to marshal operation event: %v.", errMarshal)
+			return
+		}
+		var buf bytes.Buffer
+		if err == nil {
+			// error events carry no offset and must not reset
+			// the position the client resumes from
+			fmt.Fprintf(&buf, "id: %v\n", event.Offset)
+		}
+		fmt.Fprintf(&buf, "event: %v\ndata: %s\n\n", event.Type, data)
+		_, errWrite := w.Write(buf.Bytes())
+		if errWrite != nil {
+			log.Debugf("Failed to send operation event: %v.", errWrite)
+			return
+		}
+		flusher.Flush()
+		if err != nil {
+			return
+		}
+	}
+}
+
+func errorEvent(err error) *ops.OperationEvent {
+	log.Debugf("Operation event stream failed: %v.", trace.DebugReport(err))
+	return &ops.OperationEvent{
+		Type:  ops.OperationEventError,
+		Error: trace.UserMessage(err),
+	}
+}
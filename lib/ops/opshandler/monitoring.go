@@ -20,7 +20,9 @@ import (
 	"encoding/json"
 	"net/http"
 
+	"github.com/gravitational/gravity/lib/constants"
 	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/ops/monitoring"
 	"github.com/gravitational/gravity/lib/ops/opsclient"
 	"github.com/gravitational/gravity/lib/storage"
 
@@ -143,14 +145,20 @@ func (h *WebHandler) deleteAlert(w http.ResponseWriter, r *http.Request, p httpr
 
 /* getAlertTargets returns a list of monitoring alert targets for the cluster
 
-     GET /portal/v1/accounts/:account_id/sites/:site_domain/monitoring/alert-targets
+     GET /portal/v1/accounts/:account_id/sites/:site_domain/monitoring/alert-targets?with_secrets=<true|false>
+
+   Delivery credentials are only returned if with_secrets is set.
 
    Success Response:
 
      []storage.AlertTarget
 */
 func (h *WebHandler) getAlertTargets(w http.ResponseWriter, r *http.Request, p httprouter.Params, context *HandlerContext) error {
-	targets, err := context.Operator.GetAlertTargets(siteKey(p))
+	withSecrets, _, err := telehttplib.ParseBool(r.URL.Query(), constants.WithSecretsParam)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	targets, err := context.Operator.GetAlertTargets(siteKey(p), withSecrets)
 	if err != nil {
 		return trace.Wrap(err)
 	}
@@ -193,7 +201,9 @@ func (h *WebHandler) updateAlertTarget(w http.ResponseWriter, r *http.Request, p
 
 /* deleteAlertTarget deletes cluster's monitoring alert target
 
-   DELETE /portal/v1/accounts/:account_id/sites/:site_domain/monitoring/alert-targets
+   DELETE /portal/v1/accounts/:account_id/sites/:site_domain/monitoring/alert-targets/:name
+
+   If name is omitted, the email alert target is deleted.
 
    Success Response:
 
@@ -202,7 +212,7 @@ func (h *WebHandler) updateAlertTarget(w http.ResponseWriter, r *http.Request, p
      }
 */
 func (h *WebHandler) deleteAlertTarget(w http.ResponseWriter, r *http.Request, p httprouter.Params, context *HandlerContext) error {
-	err := context.Operator.DeleteAlertTarget(siteKey(p))
+	err := context.Operator.DeleteAlertTarget(siteKey(p), p.ByName("name"))
 	if err != nil {
 		return trace.Wrap(err)
 	}
@@ -210,3 +220,43 @@ func (h *WebHandler) deleteAlertTarget(w http.ResponseWriter, r *http.Request, p
 	roundtrip.ReplyJSON(w, http.StatusOK, statusOK("alert target deleted"))
 	return nil
 }
+
+/* notifyAlertTargets delivers an alert event to the cluster's webhook, Slack
+   and PagerDuty alert targets.
+
+   The request body is compatible with the Kapacitor HTTP POST alert handler.
+   The endpoint is configured as a handler of Kapacitor alert topics by the
+   alert relay and is authenticated with the alert notify token instead
+   of user credentials.
+
+   POST /portal/v1/accounts/:account_id/sites/:site_domain/monitoring/alert-targets/notify
+
+   Authorization: Bearer <alert notify token>
+
+   Input: monitoring.AlertEvent
+
+   Success Response:
+
+     {
+       "message": "alert targets notified"
+     }
+*/
+func (h *WebHandler) notifyAlertTargets(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	if err := h.relayAlertEvent(r, p); err != nil {
+		trace.WriteError(w, err)
+		return
+	}
+	roundtrip.ReplyJSON(w, http.StatusOK, statusOK("alert targets notified"))
+}
+
+func (h *WebHandler) relayAlertEvent(r *http.Request, p httprouter.Params) error {
+	event, err := monitoring.ReceiveEvent(r, h.cfg.AlertNotifyTokens)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	targets, err := h.cfg.Operator.GetAlertTargets(siteKey(p), true)
+	if err != nil && !trace.IsNotFound(err) {
+		return trace.Wrap(err)
+	}
+	return trace.Wrap(monitoring.Notify(r.Context(), targets, p.ByName("site_domain"), *event))
+}
@@ -27,11 +27,13 @@ import (
 	"time"
 
 	"github.com/gravitational/gravity/lib/app"
+	"github.com/gravitational/gravity/lib/audit"
 	"github.com/gravitational/gravity/lib/constants"
 	"github.com/gravitational/gravity/lib/defaults"
 	"github.com/gravitational/gravity/lib/httplib"
 	"github.com/gravitational/gravity/lib/loc"
 	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/ops/monitoring"
 	"github.com/gravitational/gravity/lib/ops/opsclient"
 	"github.com/gravitational/gravity/lib/pack"
 	"github.com/gravitational/gravity/lib/schema"
@@ -66,6 +68,11 @@ type WebHandlerConfig struct {
 	Devmode bool
 	// PublicAdvertiseAddr is the process public advertise address
 	PublicAdvertiseAddr utils.NetAddr
+	// AuditLog records changes made through the handler, optional
+	AuditLog audit.Emitter
+	// AlertNotifyTokens authenticates alert events relayed by Kapacitor, optional.
+	// Alert events are rejected if unset
+	AlertNotifyTokens monitoring.NotifyTokens
 }
 
 type WebHandler struct {
@@ -201,6 +208,7 @@ func NewWebHandler(cfg WebHandlerConfig) (*WebHandler, error) {
 	h.GET("/portal/v1/accounts/:account_id/sites/:site_domain/operations/common/:operation_id", h.needsAuth(h.getSiteOperation))
 	h.DELETE("/portal/v1/accounts/:account_id/sites/:site_domain/operations/common/:operation_id", h.needsAuth(h.deleteOperation))
 	h.GET("/portal/v1/accounts/:account_id/sites/:site_domain/operations/common/:operation_id/logs", h.needsAuth(h.getSiteOperationLogs))
+	h.GET("/portal/v1/accounts/:account_id/sites/:site_domain/operations/common/:operation_id/events", h.needsAuth(h.getOperationEvents))
 	h.POST("/portal/v1/accounts/:account_id/sites/:site_domain/operations/common/:operation_id/logs/entry", h.needsAuth(h.createLogEntry))
 	h.POST("/portal/v1/accounts/:account_id/sites/:site_domain/operations/common/:operation_id/logs", h.needsAuth(h.streamOperationLogs))
 	h.GET("/portal/v1/accounts/:account_id/sites/:site_domain/operations/common/:operation_id/progress", h.needsAuth(h.getSiteOperationProgress))
@@ -233,6 +241,9 @@ func NewWebHandler(cfg WebHandlerConfig) (*WebHandler, error) {
 	h.GET("/portal/v1/accounts/:account_id/sites/:site_domain/monitoring/alert-targets", h.needsAuth(h.getAlertTargets))
 	h.PUT("/portal/v1/accounts/:account_id/sites/:site_domain/monitoring/alert-targets", h.needsAuth(h.updateAlertTarget))
 	h.DELETE("/portal/v1/accounts/:account_id/sites/:site_domain/monitoring/alert-targets", h.needsAuth(h.deleteAlertTarget))
+	h.DELETE("/portal/v1/accounts/:account_id/sites/:site_domain/monitoring/alert-targets/:name", h.needsAuth(h.deleteAlertTarget))
+	// alert events are posted by Kapacitor which authenticates with the alert notify token
+	h.POST("/portal/v1/accounts/:account_id/sites/:site_domain/monitoring/alert-targets/notify", h.notifyAlertTargets)
 
 	// environment variables
 	h.GET("/portal/v1/accounts/:account_id/sites/:site_domain/envars", h.needsAuth(h.getEnvironmentVariables))
@@ -243,6 +254,18 @@ func NewWebHandler(cfg WebHandlerConfig) (*WebHandler, error) {
 	h.PUT("/portal/v1/accounts/:account_id/sites/:site_domain/config", h.needsAuth(h.updateClusterConfig))
 	h.POST("/portal/v1/accounts/:account_id/sites/:site_domain/operations/config", h.needsAuth(h.createUpdateConfigOperation))
 
+	// scheduled backups
+	h.GET("/portal/v1/accounts/:account_id/sites/:site_domain/backups", h.needsAuth(h.getBackupSchedules))
+	h.PUT("/portal/v1/accounts/:account_id/sites/:site_domain/backups", h.needsAuth(h.upsertBackupSchedule))
+	h.DELETE("/portal/v1/accounts/:account_id/sites/:site_domain/backups/:name", h.needsAuth(h.deleteBackupSchedule))
+
+	// audit log
+	h.GET("/portal/v1/accounts/:account_id/sites/:site_domain/audit", h.needsAuth(h.getAuditEvents))
+
+	// package storage integrity
+	h.GET("/portal/v1/accounts/:account_id/sites/:site_domain/blobs/status", h.needsAuth(h.getBLOBStatus))
+	h.POST("/portal/v1/accounts/:account_id/sites/:site_domain/blobs/check", h.needsAuth(h.checkBLOBs))
+
 	// validation
 	h.POST("/portal/v1/accounts/:account_id/sites/:site_domain/validation/remoteaccess", h.needsAuth(h.validateRemoteAccess))
 
@@ -265,6 +288,32 @@ func NewWebHandler(cfg WebHandlerConfig) (*WebHandler, error) {
 	h.DELETE("/portal/v1/accounts/:account_id/sites/:site_domain/github/connectors/:id",
 		h.needsAuth(h.deleteGithubConnector))
 
+	// OIDC connector handlers
+	h.POST("/portal/v1/accounts/:account_id/sites/:site_domain/oidc/connectors",
+		h.needsAuth(h.upsertOIDCConnector))
+	h.GET("/portal/v1/accounts/:account_id/sites/:site_domain/oidc/connectors/:id",
+		h.needsAuth(h.getOIDCConnector))
+	h.DELETE("/portal/v1/accounts/:account_id/sites/:site_domain/oidc/connectors/:id",
+		h.needsAuth(h.deleteOIDCConnector))
+
+	// SAML connector handlers
+	h.POST("/portal/v1/accounts/:account_id/sites/:site_domain/saml/connectors",
+		h.needsAuth(h.upsertSAMLConnector))
+	h.GET("/portal/v1/accounts/:account_id/sites/:site_domain/saml/connectors/:id",
+		h.needsAuth(h.getSAMLConnector))
+	h.DELETE("/portal/v1/accounts/:account_id/sites/:site_domain/saml/connectors/:id",
+		h.needsAuth(h.deleteSAMLConnector))
+
+	// role handlers
+	h.POST("/portal/v1/accounts/:account_id/sites/:site_domain/roles", h.needsAuth(h.upsertRole))
+	h.GET("/portal/v1/accounts/:account_id/sites/:site_domain/roles/:name", h.needsAuth(h.getRole))
+	h.DELETE("/portal/v1/accounts/:account_id/sites/:site_domain/roles/:name", h.needsAuth(h.deleteRole))
+
+	// trusted cluster handlers
+	h.POST("/portal/v1/accounts/:account_id/sites/:site_domain/trustedclusters", h.needsAuth(h.upsertTrustedCluster))
+	h.GET("/portal/v1/accounts/:account_id/sites/:site_domain/trustedclusters/:name", h.needsAuth(h.getTrustedCluster))
+	h.DELETE("/portal/v1/accounts/:account_id/sites/:site_domain/trustedclusters/:name", h.needsAuth(h.deleteTrustedCluster))
+
 	// user handlers
 	h.POST("/portal/v1/accounts/:account_id/sites/:site_domain/users", h.needsAuth(h.upsertUser))
 	h.GET("/portal/v1/accounts/:account_id/sites/:site_domain/users/:name", h.needsAuth(h.getUser))
@@ -560,7 +609,11 @@ func (h *WebHandler) createUser(w http.ResponseWriter, r *http.Request, p httpro
 */
 func (h *WebHandler) deleteLocalUser(w http.ResponseWriter, r *http.Request, p httprouter.Params, ctx *HandlerContext) error {
 	name := p[0].Value
-	err := h.cfg.Users.DeleteUser(name)
+	identity := h.cfg.Users
+	if h.cfg.AuditLog != nil {
+		identity = users.IdentityWithAudit(identity, h.cfg.AuditLog, ctx.User)
+	}
+	err := identity.DeleteUser(name)
 	if err != nil {
 		return trace.Wrap(err)
 	}
@@ -929,10 +982,22 @@ func (h *WebHandler) activateSite(w http.ResponseWriter, r *http.Request, p http
 
 /* getSiteReport returns a tarball with collected information about the site
 
-   GET /portal/v1/accounts/:account_id/sites/:site_domain/report
+   GET /portal/v1/accounts/:account_id/sites/:site_domain/report?redact=<true|false>&redact-pattern=<regexp>
+
+   If redact is set, sensitive data is removed from the report. redact-pattern can be
+   specified multiple times to redact additional data.
 */
 func (h *WebHandler) getSiteReport(w http.ResponseWriter, r *http.Request, p httprouter.Params, context *HandlerContext) error {
-	report, err := context.Operator.GetSiteReport(siteKey(p))
+	redact, _, err := telehttplib.ParseBool(r.URL.Query(), "redact")
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	report, err := context.Operator.GetSiteReport(ops.GetSiteReportRequest{
+		AccountID:      p.ByName("account_id"),
+		SiteDomain:     p.ByName("site_domain"),
+		Redact:         redact,
+		RedactPatterns: r.URL.Query()["redact-pattern"],
+	})
 	if err != nil {
 		return trace.Wrap(err)
 	}
@@ -1922,14 +1987,20 @@ func (h *WebHandler) createClusterGarbageCollectOperation(w http.ResponseWriter,
 
 /* getLogForwarders returns a list of configured log forwarders
 
-   GET /portal/v1/accounts/:account_id/sites/:site_domain/logs/forwarders
+   GET /portal/v1/accounts/:account_id/sites/:site_domain/logs/forwarders?with_secrets=<true|false>
+
+   Client keys and authentication tokens are only returned if with_secrets is set.
 
 Success response:
 
    []storage.LogForwarder
 */
 func (h *WebHandler) getLogForwarders(w http.ResponseWriter, r *http.Request, p httprouter.Params, context *HandlerContext) error {
-	forwarders, err := context.Operator.GetLogForwarders(siteKey(p))
+	withSecrets, _, err := telehttplib.ParseBool(r.URL.Query(), constants.WithSecretsParam)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	forwarders, err := context.Operator.GetLogForwarders(siteKey(p), withSecrets)
 	if err != nil {
 		return trace.Wrap(err)
 	}
@@ -2008,14 +2079,20 @@ func (h *WebHandler) deleteLogForwarder(w http.ResponseWriter, r *http.Request,
 
 /* getSMTPConfig returns the cluster SMTP configuration
 
-     GET /portal/v1/accounts/:account_id/sites/:site_domain/smtp
+     GET /portal/v1/accounts/:account_id/sites/:site_domain/smtp?with_secrets=<true|false>
+
+   The password is only returned if with_secrets is set.
 
    Success Response:
 
      storage.SMTPConfig
 */
 func (h *WebHandler) getSMTPConfig(w http.ResponseWriter, r *http.Request, p httprouter.Params, context *HandlerContext) error {
-	config, err := context.Operator.GetSMTPConfig(siteKey(p))
+	withSecrets, _, err := telehttplib.ParseBool(r.URL.Query(), constants.WithSecretsParam)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	config, err := context.Operator.GetSMTPConfig(siteKey(p), withSecrets)
 	if err != nil {
 		return trace.Wrap(err)
 	}
@@ -2206,7 +2283,20 @@ func (s *WebHandler) wrap(fn func(w http.ResponseWriter, r *http.Request, p http
 }
 
 func (s *WebHandler) needsAuth(fn ServiceHandle) httprouter.Handle {
-	return NeedsAuth(s.cfg.Devmode, s.cfg.Backend, s.cfg.Operator, s.cfg.Authenticator, s.cfg.Users, fn)
+	return NeedsAuth(s.cfg.Devmode, s.cfg.Backend, s.cfg.Operator, s.cfg.Authenticator, s.cfg.Users, s.audited(fn))
+}
+
+// audited wraps the operator and the identity service of the handler context
+// to record the changes made by the authenticated user in the audit log
+func (s *WebHandler) audited(fn ServiceHandle) ServiceHandle {
+	if s.cfg.AuditLog == nil {
+		return fn
+	}
+	return func(w http.ResponseWriter, r *http.Request, p httprouter.Params, context *HandlerContext) error {
+		context.Operator = ops.OperatorWithAudit(context.Operator, s.cfg.AuditLog, context.User)
+		context.Identity = users.IdentityWithAudit(context.Identity, s.cfg.AuditLog, context.User)
+		return fn(w, r, p, context)
+	}
 }
 
 // GetHandlerContext authenticates the user that made the request and returns
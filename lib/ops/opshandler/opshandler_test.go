@@ -268,7 +268,7 @@ func (s *OpsHandlerSuite) TestLogForwarder(c *C) {
 }
 
 func (s *OpsHandlerSuite) checkForwarders(c *C, key ops.SiteKey, expected []storage.LogForwarder) {
-	forwarders, err := s.client.GetLogForwarders(key)
+	forwarders, err := s.client.GetLogForwarders(key, true)
 	c.Assert(err, IsNil)
 	c.Assert(len(forwarders), Equals, len(expected))
 	forwardersMap := make(map[string]storage.LogForwarder)
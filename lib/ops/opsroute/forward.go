@@ -17,6 +17,7 @@ limitations under the License.
 package opsroute
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"net/url"
@@ -357,6 +358,15 @@ func (r *Router) GetSiteOperationLogs(key ops.SiteOperationKey) (io.ReadCloser,
 	return client.GetSiteOperationLogs(key)
 }
 
+// GetOperationEvents returns a stream of events of the specified operation
+func (r *Router) GetOperationEvents(ctx context.Context, key ops.SiteOperationKey, offset ops.EventOffset) (ops.OperationEventStream, error) {
+	client, err := r.PickOperationClient(key.SiteDomain)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return client.GetOperationEvents(ctx, key, offset)
+}
+
 func (r *Router) CreateLogEntry(key ops.SiteOperationKey, entry ops.LogEntry) error {
 	client, err := r.PickOperationClient(key.SiteDomain)
 	if err != nil {
@@ -415,12 +425,12 @@ func (r *Router) GetSiteOperationCrashReport(key ops.SiteOperationKey) (io.ReadC
 	return client.GetSiteOperationCrashReport(key)
 }
 
-func (r *Router) GetSiteReport(key ops.SiteKey) (io.ReadCloser, error) {
-	client, err := r.PickClient(key.SiteDomain)
+func (r *Router) GetSiteReport(req ops.GetSiteReportRequest) (io.ReadCloser, error) {
+	client, err := r.PickClient(req.SiteDomain)
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
-	return client.GetSiteReport(key)
+	return client.GetSiteReport(req)
 }
 
 // ValidateServers runs pre-installation checks
@@ -531,12 +541,12 @@ func (r *Router) ConfigureNode(req ops.ConfigureNodeRequest) error {
 }
 
 // GetLogForwarders returns a list of configured log forwarders
-func (r *Router) GetLogForwarders(key ops.SiteKey) ([]storage.LogForwarder, error) {
+func (r *Router) GetLogForwarders(key ops.SiteKey, withSecrets bool) ([]storage.LogForwarder, error) {
 	client, err := r.RemoteClient(key.SiteDomain)
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
-	return client.GetLogForwarders(key)
+	return client.GetLogForwarders(key, withSecrets)
 }
 
 // CreateLogForwarder creates a new log forwarder
@@ -585,12 +595,12 @@ func (r *Router) UpdateRetentionPolicy(req ops.UpdateRetentionPolicyRequest) err
 }
 
 // GetSMTPConfig returns the cluster SMTP configuration
-func (r *Router) GetSMTPConfig(key ops.SiteKey) (storage.SMTPConfig, error) {
+func (r *Router) GetSMTPConfig(key ops.SiteKey, withSecrets bool) (storage.SMTPConfig, error) {
 	client, err := r.RemoteClient(key.SiteDomain)
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
-	return client.GetSMTPConfig(key)
+	return client.GetSMTPConfig(key, withSecrets)
 }
 
 // UpdateSMTPConfig updates the cluster SMTP configuration
@@ -639,12 +649,12 @@ func (r *Router) DeleteAlert(key ops.SiteKey, name string) error {
 }
 
 // GetAlertTargets returns a list of monitoring alert targets
-func (r *Router) GetAlertTargets(key ops.SiteKey) ([]storage.AlertTarget, error) {
+func (r *Router) GetAlertTargets(key ops.SiteKey, withSecrets bool) ([]storage.AlertTarget, error) {
 	client, err := r.RemoteClient(key.SiteDomain)
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
-	return client.GetAlertTargets(key)
+	return client.GetAlertTargets(key, withSecrets)
 }
 
 // UpdateAlertTarget updates the cluster monitoring alert target
@@ -656,13 +666,13 @@ func (r *Router) UpdateAlertTarget(key ops.SiteKey, target storage.AlertTarget)
 	return client.UpdateAlertTarget(key, target)
 }
 
-// DeleteAlertTarget deletes the cluster monitoring alert target
-func (r *Router) DeleteAlertTarget(key ops.SiteKey) error {
+// DeleteAlertTarget deletes the cluster monitoring alert target specified with name
+func (r *Router) DeleteAlertTarget(key ops.SiteKey, name string) error {
 	client, err := r.RemoteClient(key.SiteDomain)
 	if err != nil {
 		return trace.Wrap(err)
 	}
-	return client.DeleteAlertTarget(key)
+	return client.DeleteAlertTarget(key, name)
 }
 
 // GetClusterEnvironmentVariables retrieves the cluster runtime environment variables
@@ -692,6 +702,61 @@ func (r *Router) UpdateClusterConfiguration(req ops.UpdateClusterConfigRequest)
 	return client.UpdateClusterConfiguration(req)
 }
 
+// GetBackupSchedules returns the list of cluster backup schedules
+func (r *Router) GetBackupSchedules(key ops.SiteKey, withSecrets bool) ([]storage.BackupSchedule, error) {
+	client, err := r.RemoteClient(key.SiteDomain)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return client.GetBackupSchedules(key, withSecrets)
+}
+
+// UpsertBackupSchedule creates or updates the specified backup schedule
+func (r *Router) UpsertBackupSchedule(key ops.SiteKey, schedule storage.BackupSchedule) error {
+	client, err := r.RemoteClient(key.SiteDomain)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return client.UpsertBackupSchedule(key, schedule)
+}
+
+// DeleteBackupSchedule deletes the backup schedule specified with name
+func (r *Router) DeleteBackupSchedule(key ops.SiteKey, name string) error {
+	client, err := r.RemoteClient(key.SiteDomain)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return client.DeleteBackupSchedule(key, name)
+}
+
+// GetAuditEvents returns the cluster audit events that match the filter
+func (r *Router) GetAuditEvents(key ops.SiteKey, filter storage.AuditEventFilter) ([]storage.AuditEvent, error) {
+	client, err := r.RemoteClient(key.SiteDomain)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return client.GetAuditEvents(key, filter)
+}
+
+// GetBLOBStatus returns the outcome of the last integrity check
+// on each package storage peer
+func (r *Router) GetBLOBStatus(key ops.SiteKey) (*ops.BLOBStatus, error) {
+	client, err := r.RemoteClient(key.SiteDomain)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return client.GetBLOBStatus(key)
+}
+
+// CheckBLOBs verifies the integrity of the packages stored on the serving peer
+func (r *Router) CheckBLOBs(req ops.CheckBLOBsRequest) (*storage.BLOBIntegrity, error) {
+	client, err := r.RemoteClient(req.ClusterKey.SiteDomain)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return client.CheckBLOBs(req)
+}
+
 func (r *Router) GetApplicationEndpoints(key ops.SiteKey) ([]ops.Endpoint, error) {
 	client, err := r.RemoteClient(key.SiteDomain)
 	if err != nil {
@@ -861,6 +926,118 @@ func (r *Router) DeleteGithubConnector(key ops.SiteKey, name string) error {
 	return client.DeleteGithubConnector(key, name)
 }
 
+// UpsertOIDCConnector creates or updates an OIDC connector
+func (r *Router) UpsertOIDCConnector(key ops.SiteKey, connector teleservices.OIDCConnector) error {
+	client, err := r.PickClient(key.SiteDomain)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return client.UpsertOIDCConnector(key, connector)
+}
+
+// GetOIDCConnector returns an OIDC connector by name
+//
+// Returned connector exclude client secret unless withSecrets is true.
+func (r *Router) GetOIDCConnector(key ops.SiteKey, name string, withSecrets bool) (teleservices.OIDCConnector, error) {
+	client, err := r.PickClient(key.SiteDomain)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return client.GetOIDCConnector(key, name, withSecrets)
+}
+
+// DeleteOIDCConnector deletes an OIDC connector by name
+func (r *Router) DeleteOIDCConnector(key ops.SiteKey, name string) error {
+	client, err := r.PickClient(key.SiteDomain)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return client.DeleteOIDCConnector(key, name)
+}
+
+// UpsertSAMLConnector creates or updates a SAML connector
+func (r *Router) UpsertSAMLConnector(key ops.SiteKey, connector teleservices.SAMLConnector) error {
+	client, err := r.PickClient(key.SiteDomain)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return client.UpsertSAMLConnector(key, connector)
+}
+
+// GetSAMLConnector returns a SAML connector by name
+//
+// Returned connector exclude signing key unless withSecrets is true.
+func (r *Router) GetSAMLConnector(key ops.SiteKey, name string, withSecrets bool) (teleservices.SAMLConnector, error) {
+	client, err := r.PickClient(key.SiteDomain)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return client.GetSAMLConnector(key, name, withSecrets)
+}
+
+// DeleteSAMLConnector deletes a SAML connector by name
+func (r *Router) DeleteSAMLConnector(key ops.SiteKey, name string) error {
+	client, err := r.PickClient(key.SiteDomain)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return client.DeleteSAMLConnector(key, name)
+}
+
+// UpsertRole creates or updates a role
+func (r *Router) UpsertRole(key ops.SiteKey, role teleservices.Role) error {
+	client, err := r.PickClient(key.SiteDomain)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return client.UpsertRole(key, role)
+}
+
+// GetRole returns a role by name
+func (r *Router) GetRole(key ops.SiteKey, name string) (teleservices.Role, error) {
+	client, err := r.PickClient(key.SiteDomain)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return client.GetRole(key, name)
+}
+
+// DeleteRole deletes a role by name
+func (r *Router) DeleteRole(key ops.SiteKey, name string) error {
+	client, err := r.PickClient(key.SiteDomain)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return client.DeleteRole(key, name)
+}
+
+// UpsertTrustedCluster creates or updates a trusted cluster
+func (r *Router) UpsertTrustedCluster(key ops.SiteKey, cluster teleservices.TrustedCluster) error {
+	client, err := r.PickClient(key.SiteDomain)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return client.UpsertTrustedCluster(key, cluster)
+}
+
+// GetTrustedCluster returns a trusted cluster by name
+func (r *Router) GetTrustedCluster(key ops.SiteKey, name string) (teleservices.TrustedCluster, error) {
+	client, err := r.PickClient(key.SiteDomain)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return client.GetTrustedCluster(key, name)
+}
+
+// DeleteTrustedCluster deletes a trusted cluster by name
+func (r *Router) DeleteTrustedCluster(key ops.SiteKey, name string) error {
+	client, err := r.PickClient(key.SiteDomain)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return client.DeleteTrustedCluster(key, name)
+}
+
 // UpsertAuthGateway updates auth gateway configuration.
 func (r *Router) UpsertAuthGateway(key ops.SiteKey, gw storage.AuthGateway) error {
 	return r.Local.UpsertAuthGateway(key, gw)
@@ -0,0 +1,33 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package opsservice
+
+import (
+	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/storage"
+
+	"github.com/gravitational/trace"
+)
+
+// GetAuditEvents returns the cluster audit events that match the filter
+func (o *Operator) GetAuditEvents(key ops.SiteKey, filter storage.AuditEventFilter) ([]storage.AuditEvent, error) {
+	events, err := o.backend().GetAuditEvents(filter)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return events, nil
+}
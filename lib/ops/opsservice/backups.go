@@ -0,0 +1,62 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package opsservice
+
+import (
+	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/storage"
+
+	"github.com/gravitational/trace"
+)
+
+// GetBackupSchedules returns the list of cluster backup schedules.
+// The S3 secret access key and the cluster API token of the destination
+// are only returned if withSecrets is true
+func (o *Operator) GetBackupSchedules(key ops.SiteKey, withSecrets bool) ([]storage.BackupSchedule, error) {
+	schedules, err := o.backend().GetBackupSchedules()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if !withSecrets {
+		for i, schedule := range schedules {
+			schedules[i] = schedule.WithoutSecrets()
+		}
+	}
+	return schedules, nil
+}
+
+// UpsertBackupSchedule creates or updates the specified backup schedule.
+// The status of an existing schedule is preserved since it is maintained
+// by the backup scheduler
+func (o *Operator) UpsertBackupSchedule(key ops.SiteKey, schedule storage.BackupSchedule) error {
+	if err := schedule.CheckAndSetDefaults(); err != nil {
+		return trace.Wrap(err)
+	}
+	existing, err := o.backend().GetBackupSchedule(schedule.GetName())
+	if err != nil && !trace.IsNotFound(err) {
+		return trace.Wrap(err)
+	}
+	if existing != nil {
+		schedule.SetStatus(existing.GetStatus())
+	}
+	return trace.Wrap(o.backend().UpsertBackupSchedule(schedule))
+}
+
+// DeleteBackupSchedule deletes the backup schedule specified with name
+func (o *Operator) DeleteBackupSchedule(key ops.SiteKey, name string) error {
+	return trace.Wrap(o.backend().DeleteBackupSchedule(name))
+}
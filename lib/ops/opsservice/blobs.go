@@ -0,0 +1,49 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package opsservice
+
+import (
+	"context"
+
+	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/storage"
+
+	"github.com/gravitational/trace"
+)
+
+// GetBLOBStatus returns the outcome of the last integrity check
+// on each package storage peer
+func (o *Operator) GetBLOBStatus(key ops.SiteKey) (*ops.BLOBStatus, error) {
+	peers, err := o.backend().GetPeers()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &ops.BLOBStatus{Peers: peers}, nil
+}
+
+// CheckBLOBs verifies the integrity of the packages stored on the serving peer
+func (o *Operator) CheckBLOBs(req ops.CheckBLOBsRequest) (*storage.BLOBIntegrity, error) {
+	if o.cfg.BLOBs == nil {
+		return nil, trace.NotImplemented(
+			"package storage of this cluster does not support integrity checks")
+	}
+	integrity, err := o.cfg.BLOBs.Check(context.TODO(), req.Repair)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return integrity, nil
+}
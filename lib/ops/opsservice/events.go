@@ -0,0 +1,290 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package opsservice
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/storage"
+
+	"github.com/gravitational/trace"
+	"github.com/sirupsen/logrus"
+)
+
+// GetOperationEvents returns a stream of progress entries, phase state
+// changes and log lines of the specified operation starting after
+// the provided offset
+func (o *Operator) GetOperationEvents(ctx context.Context, key ops.SiteOperationKey, offset ops.EventOffset) (ops.OperationEventStream, error) {
+	site, err := o.openSite(key.SiteKey())
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	_, err = o.backend().GetSiteOperation(key.SiteDomain, key.OperationID)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	logPath := site.operationLogPath(key)
+	if len(o.cfg.InstallLogFiles) > 0 {
+		logPath = o.cfg.InstallLogFiles[0]
+	}
+	return newEventStream(ctx, eventStreamConfig{
+		Backend:     o.backend(),
+		Key:         key,
+		LogPath:     logPath,
+		Offset:      offset,
+		Interval:    defaults.OperationEventsPollInterval,
+		FieldLogger: o.WithField("operation", key.OperationID),
+	}), nil
+}
+
+// eventStreamConfig is the operation event stream configuration
+type eventStreamConfig struct {
+	// Backend is the cluster state backend
+	Backend storage.Backend
+	// Key identifies the operation
+	Key ops.SiteOperationKey
+	// LogPath is the path to the operation log file
+	LogPath string
+	// Offset is the stream offset to resume from
+	Offset ops.EventOffset
+	// Interval is how often the operation state is checked for new events
+	Interval time.Duration
+	// FieldLogger is used for logging
+	logrus.FieldLogger
+}
+
+// newEventStream starts a new operation event stream
+func newEventStream(ctx context.Context, config eventStreamConfig) *eventStream {
+	ctx, cancel := context.WithCancel(ctx)
+	stream := &eventStream{
+		eventStreamConfig: config,
+		offset:            config.Offset,
+		ctx:               ctx,
+		cancel:            cancel,
+		eventsC:           make(chan ops.OperationEvent),
+	}
+	go stream.run()
+	return stream
+}
+
+// eventStream collects events of an operation from the cluster state
+// and the operation log.
+//
+// The cluster state has no means to watch for changes so the stream
+// checks the operation state periodically and pushes new events
+// to the receiver
+type eventStream struct {
+	eventStreamConfig
+	// offset is the offset of the last collected event
+	offset ops.EventOffset
+	// sentCompleted is whether the final progress entry has been sent
+	sentCompleted bool
+	ctx           context.Context
+	cancel        context.CancelFunc
+	eventsC       chan ops.OperationEvent
+	// err is the reason the stream has stopped, set before eventsC is closed
+	err error
+}
+
+// Recv returns the next event from the stream.
+// It returns io.EOF once the operation has completed and all
+// its events have been delivered
+func (s *eventStream) Recv() (*ops.OperationEvent, error) {
+	event, ok := <-s.eventsC
+	if !ok {
+		return nil, s.err
+	}
+	return &event, nil
+}
+
+// Close stops the stream
+func (s *eventStream) Close() error {
+	s.cancel()
+	return nil
+}
+
+func (s *eventStream) run() {
+	defer close(s.eventsC)
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+	for {
+		completed, err := s.poll()
+		if err != nil {
+			s.Debugf("Operation event stream failed: %v.", trace.DebugReport(err))
+			s.err = trace.Wrap(err)
+			return
+		}
+		if completed {
+			s.err = io.EOF
+			return
+		}
+		select {
+		case <-ticker.C:
+		case <-s.ctx.Done():
+			s.err = trace.ConnectionProblem(s.ctx.Err(), "operation event stream closed")
+			return
+		}
+	}
+}
+
+// poll sends all events that have occurred since the last poll.
+// It returns true once the operation has completed and all its
+// events have been sent
+func (s *eventStream) poll() (completed bool, err error) {
+	if err := s.pollLogs(); err != nil {
+		return false, trace.Wrap(err)
+	}
+	if err := s.pollChanges(); err != nil {
+		return false, trace.Wrap(err)
+	}
+	progress, err := s.pollProgress()
+	if err != nil {
+		return false, trace.Wrap(err)
+	}
+	if progress == nil {
+		return false, nil
+	}
+	// collect the events that might have been recorded
+	// after the previous poll but before the operation completed
+	if err := s.pollLogs(); err != nil {
+		return false, trace.Wrap(err)
+	}
+	if err := s.pollChanges(); err != nil {
+		return false, trace.Wrap(err)
+	}
+	if progress.Created.UnixNano() > s.offset.Progress {
+		s.offset.Progress = progress.Created.UnixNano()
+	}
+	// the completed progress entry is always the last event of the stream,
+	// even if the stream has been resumed after it
+	if !s.sentCompleted {
+		if err := s.sendProgress(*progress); err != nil {
+			return false, trace.Wrap(err)
+		}
+		s.sentCompleted = true
+	}
+	return true, nil
+}
+
+// pollProgress sends the progress entries created since the last poll
+// in the order they were created. It returns the completed progress entry
+// once the operation has completed, without sending it
+func (s *eventStream) pollProgress() (completed *ops.ProgressEntry, err error) {
+	entries, err := s.Backend.GetProgressEntries(s.Key.SiteDomain, s.Key.OperationID)
+	if err != nil && !trace.IsNotFound(err) {
+		return nil, trace.Wrap(err)
+	}
+	since := s.offset.Progress
+	for _, entry := range entries {
+		progress := ops.ProgressEntry(entry)
+		if progress.IsCompleted() {
+			return &progress, nil
+		}
+		if progress.Created.UnixNano() <= since {
+			continue
+		}
+		s.offset.Progress = progress.Created.UnixNano()
+		if err := s.sendProgress(progress); err != nil {
+			return nil, trace.Wrap(err)
+		}
+	}
+	return nil, nil
+}
+
+func (s *eventStream) sendProgress(progress ops.ProgressEntry) error {
+	return s.send(ops.OperationEvent{
+		Type:     ops.OperationEventProgress,
+		Progress: &progress,
+	})
+}
+
+// pollChanges sends the phase state changes recorded since the last poll
+func (s *eventStream) pollChanges() error {
+	changelog, err := s.Backend.GetOperationPlanChangelog(s.Key.SiteDomain, s.Key.OperationID)
+	if err != nil && !trace.IsNotFound(err) {
+		return trace.Wrap(err)
+	}
+	sort.SliceStable(changelog, func(i, j int) bool {
+		return changelog[i].Created.Before(changelog[j].Created)
+	})
+	for i := s.offset.Changes; i < len(changelog); i++ {
+		s.offset.Changes = i + 1
+		change := changelog[i]
+		err := s.send(ops.OperationEvent{
+			Type:   ops.OperationEventPhase,
+			Change: &change,
+		})
+		if err != nil {
+			return trace.Wrap(err)
+		}
+	}
+	return nil
+}
+
+// pollLogs sends the complete lines appended to the operation log
+// since the last poll
+func (s *eventStream) pollLogs() error {
+	f, err := os.Open(s.LogPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return trace.ConvertSystemError(err)
+	}
+	defer f.Close()
+	if _, err := f.Seek(s.offset.Logs, io.SeekStart); err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	reader := bufio.NewReader(f)
+	for {
+		line, err := reader.ReadString('\n')
+		if err == io.EOF {
+			// an incomplete line is sent once it has been completed
+			return nil
+		}
+		if err != nil {
+			return trace.ConvertSystemError(err)
+		}
+		s.offset.Logs += int64(len(line))
+		err = s.send(ops.OperationEvent{
+			Type: ops.OperationEventLog,
+			Log:  strings.TrimSuffix(line, "\n"),
+		})
+		if err != nil {
+			return trace.Wrap(err)
+		}
+	}
+}
+
+// send pushes the event to the receiver tagging it with the current offset
+func (s *eventStream) send(event ops.OperationEvent) error {
+	event.Offset = s.offset
+	select {
+	case s.eventsC <- event:
+		return nil
+	case <-s.ctx.Done():
+		return trace.ConnectionProblem(s.ctx.Err(), "operation event stream closed")
+	}
+}
@@ -0,0 +1,189 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package opsservice
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/storage/keyval"
+
+	"github.com/sirupsen/logrus"
+	"gopkg.in/check.v1"
+)
+
+type EventsSuite struct {
+	backend storage.Backend
+	logPath string
+	key     ops.SiteOperationKey
+	created time.Time
+}
+
+var _ = check.Suite(&EventsSuite{})
+
+func (s *EventsSuite) SetUpTest(c *check.C) {
+	dir := c.MkDir()
+	var err error
+	s.backend, err = keyval.NewBolt(keyval.BoltConfig{Path: filepath.Join(dir, "bolt.db")})
+	c.Assert(err, check.IsNil)
+	s.created = time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+	_, err = s.backend.CreateSite(storage.Site{
+		AccountID: "account",
+		Domain:    "example.com",
+		Created:   s.created,
+	})
+	c.Assert(err, check.IsNil)
+	s.key = ops.SiteOperationKey{AccountID: "account", SiteDomain: "example.com", OperationID: "operation"}
+	s.logPath = filepath.Join(dir, "operation.log")
+}
+
+func (s *EventsSuite) TearDownTest(c *check.C) {
+	if s.backend != nil {
+		s.backend.Close()
+	}
+}
+
+func (s *EventsSuite) TestStreamsAndResumesEvents(c *check.C) {
+	c.Assert(ioutil.WriteFile(s.logPath, []byte("line1\nline2\npartial"), 0644), check.IsNil)
+	s.createChange(c, "/bootstrap", storage.OperationPhaseStateInProgress, 2)
+	s.createChange(c, "/init", storage.OperationPhaseStateCompleted, 1)
+	s.createProgress(c, 50, ops.ProgressStateInProgress, 3)
+
+	stream := s.newStream(ops.EventOffset{})
+	events := receive(c, stream, 5)
+	stream.Close()
+	c.Assert(events[0].Log, check.Equals, "line1")
+	c.Assert(events[1].Log, check.Equals, "line2")
+	c.Assert(events[1].Offset.Logs, check.Equals, int64(len("line1\nline2\n")))
+	c.Assert(events[2].Change.PhaseID, check.Equals, "/init")
+	c.Assert(events[3].Change.PhaseID, check.Equals, "/bootstrap")
+	c.Assert(events[3].Offset.Changes, check.Equals, 2)
+	c.Assert(events[4].Type, check.Equals, ops.OperationEventProgress)
+	c.Assert(events[4].Progress.Completion, check.Equals, 50)
+	c.Assert(events[4].IsCompleted(), check.Equals, false)
+
+	f, err := os.OpenFile(s.logPath, os.O_APPEND|os.O_WRONLY, 0644)
+	c.Assert(err, check.IsNil)
+	_, err = f.WriteString("\n")
+	c.Assert(err, check.IsNil)
+	c.Assert(f.Close(), check.IsNil)
+	s.createProgress(c, 100, ops.ProgressStateCompleted, 4)
+
+	offset := events[4].Offset
+	parsed, err := ops.ParseEventOffset(offset.String())
+	c.Assert(err, check.IsNil)
+	c.Assert(*parsed, check.Equals, offset)
+
+	stream = s.newStream(offset)
+	events = receive(c, stream, 2)
+	c.Assert(events[0].Log, check.Equals, "partial")
+	c.Assert(events[1].IsCompleted(), check.Equals, true)
+	_, err = stream.Recv()
+	c.Assert(err, check.Equals, io.EOF)
+
+	// the completed progress entry ends the stream resumed after it
+	stream = s.newStream(events[1].Offset)
+	events = receive(c, stream, 1)
+	c.Assert(events[0].IsCompleted(), check.Equals, true)
+	_, err = stream.Recv()
+	c.Assert(err, check.Equals, io.EOF)
+}
+
+func (s *EventsSuite) TestStreamsAllProgressEntries(c *check.C) {
+	s.createProgress(c, 10, ops.ProgressStateInProgress, 1)
+
+	stream := s.newStream(ops.EventOffset{})
+	events := receive(c, stream, 1)
+	stream.Close()
+	c.Assert(events[0].Progress.Completion, check.Equals, 10)
+
+	// entries created within a single poll interval are all sent
+	// in the order they were created
+	s.createProgress(c, 40, ops.ProgressStateInProgress, 4)
+	s.createProgress(c, 20, ops.ProgressStateInProgress, 2)
+	s.createProgress(c, 30, ops.ProgressStateInProgress, 3)
+	s.createProgress(c, 100, ops.ProgressStateCompleted, 5)
+
+	stream = s.newStream(events[0].Offset)
+	events = receive(c, stream, 4)
+	var completions []int
+	for _, event := range events {
+		completions = append(completions, event.Progress.Completion)
+	}
+	c.Assert(completions, check.DeepEquals, []int{20, 30, 40, 100})
+	c.Assert(events[3].IsCompleted(), check.Equals, true)
+	_, err := stream.Recv()
+	c.Assert(err, check.Equals, io.EOF)
+}
+
+func (s *EventsSuite) TestParsesEventOffset(c *check.C) {
+	offset, err := ops.ParseEventOffset("")
+	c.Assert(err, check.IsNil)
+	c.Assert(offset.IsZero(), check.Equals, true)
+	for _, invalid := range []string{"1.2", "a.b.c", "1.-2.3"} {
+		_, err := ops.ParseEventOffset(invalid)
+		c.Assert(err, check.NotNil, check.Commentf(invalid))
+	}
+}
+
+func (s *EventsSuite) newStream(offset ops.EventOffset) *eventStream {
+	return newEventStream(context.TODO(), eventStreamConfig{
+		Backend:     s.backend,
+		Key:         s.key,
+		LogPath:     s.logPath,
+		Offset:      offset,
+		Interval:    10 * time.Millisecond,
+		FieldLogger: logrus.StandardLogger(),
+	})
+}
+
+func (s *EventsSuite) createChange(c *check.C, phaseID, state string, minutes int) {
+	_, err := s.backend.CreateOperationPlanChange(storage.PlanChange{
+		ClusterName: s.key.SiteDomain,
+		OperationID: s.key.OperationID,
+		PhaseID:     phaseID,
+		NewState:    state,
+		Created:     s.created.Add(time.Duration(minutes) * time.Minute),
+	})
+	c.Assert(err, check.IsNil)
+}
+
+func (s *EventsSuite) createProgress(c *check.C, completion int, state string, minutes int) {
+	_, err := s.backend.CreateProgressEntry(storage.ProgressEntry{
+		SiteDomain:  s.key.SiteDomain,
+		OperationID: s.key.OperationID,
+		Completion:  completion,
+		State:       state,
+		Created:     s.created.Add(time.Duration(minutes) * time.Minute),
+	})
+	c.Assert(err, check.IsNil)
+}
+
+func receive(c *check.C, stream ops.OperationEventStream, count int) (events []ops.OperationEvent) {
+	for i := 0; i < count; i++ {
+		event, err := stream.Recv()
+		c.Assert(err, check.IsNil)
+		events = append(events, *event)
+	}
+	return events
+}
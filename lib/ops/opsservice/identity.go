@@ -18,8 +18,10 @@ package opsservice
 
 import (
 	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/storage"
 
 	teleservices "github.com/gravitational/teleport/lib/services"
+	"github.com/gravitational/trace"
 )
 
 // UpsertUser creates or updates a user
@@ -75,3 +77,68 @@ func (o *Operator) GetGithubConnectors(key ops.SiteKey, withSecrets bool) ([]tel
 func (o *Operator) DeleteGithubConnector(key ops.SiteKey, name string) error {
 	return o.cfg.Users.DeleteGithubConnector(name)
 }
+
+// UpsertOIDCConnector creates or updates an OIDC connector
+func (o *Operator) UpsertOIDCConnector(key ops.SiteKey, connector teleservices.OIDCConnector) error {
+	return o.cfg.Users.UpsertOIDCConnector(connector)
+}
+
+// GetOIDCConnector returns an OIDC connector by name
+//
+// Returned connector exclude client secret unless withSecrets is true.
+func (o *Operator) GetOIDCConnector(key ops.SiteKey, name string, withSecrets bool) (teleservices.OIDCConnector, error) {
+	return o.cfg.Users.GetOIDCConnector(name, withSecrets)
+}
+
+// DeleteOIDCConnector deletes an OIDC connector by name
+func (o *Operator) DeleteOIDCConnector(key ops.SiteKey, name string) error {
+	return o.cfg.Users.DeleteOIDCConnector(name)
+}
+
+// UpsertSAMLConnector creates or updates a SAML connector
+func (o *Operator) UpsertSAMLConnector(key ops.SiteKey, connector teleservices.SAMLConnector) error {
+	return o.cfg.Users.UpsertSAMLConnector(connector)
+}
+
+// GetSAMLConnector returns a SAML connector by name
+//
+// Returned connector exclude signing key unless withSecrets is true.
+func (o *Operator) GetSAMLConnector(key ops.SiteKey, name string, withSecrets bool) (teleservices.SAMLConnector, error) {
+	return o.cfg.Users.GetSAMLConnector(name, withSecrets)
+}
+
+// DeleteSAMLConnector deletes a SAML connector by name
+func (o *Operator) DeleteSAMLConnector(key ops.SiteKey, name string) error {
+	return o.cfg.Users.DeleteSAMLConnector(name)
+}
+
+// UpsertRole creates or updates a role
+func (o *Operator) UpsertRole(key ops.SiteKey, role teleservices.Role) error {
+	return o.cfg.Users.UpsertRole(role, storage.Forever)
+}
+
+// GetRole returns a role by name
+func (o *Operator) GetRole(key ops.SiteKey, name string) (teleservices.Role, error) {
+	return o.cfg.Users.GetRole(name)
+}
+
+// DeleteRole deletes a role by name
+func (o *Operator) DeleteRole(key ops.SiteKey, name string) error {
+	return o.cfg.Users.DeleteRole(name)
+}
+
+// UpsertTrustedCluster creates or updates a trusted cluster
+func (o *Operator) UpsertTrustedCluster(key ops.SiteKey, cluster teleservices.TrustedCluster) error {
+	_, err := o.cfg.Users.UpsertTrustedCluster(cluster)
+	return trace.Wrap(err)
+}
+
+// GetTrustedCluster returns a trusted cluster by name
+func (o *Operator) GetTrustedCluster(key ops.SiteKey, name string) (teleservices.TrustedCluster, error) {
+	return o.cfg.Users.GetTrustedCluster(name)
+}
+
+// DeleteTrustedCluster deletes a trusted cluster by name
+func (o *Operator) DeleteTrustedCluster(key ops.SiteKey, name string) error {
+	return o.cfg.Users.DeleteTrustedCluster(name)
+}
@@ -17,6 +17,8 @@ limitations under the License.
 package opsservice
 
 import (
+	"fmt"
+
 	"github.com/gravitational/gravity/lib/defaults"
 	"github.com/gravitational/gravity/lib/ops"
 	"github.com/gravitational/gravity/lib/storage"
@@ -24,12 +26,14 @@ import (
 
 	"github.com/gravitational/rigging"
 	"github.com/gravitational/trace"
+	"k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 )
 
-// GetLogForwarders returns a list of configured log forwarders
-func (o *Operator) GetLogForwarders(key ops.SiteKey) ([]storage.LogForwarder, error) {
+// GetLogForwarders returns a list of configured log forwarders.
+// Client keys and authentication tokens are only returned if withSecrets is true
+func (o *Operator) GetLogForwarders(key ops.SiteKey, withSecrets bool) ([]storage.LogForwarder, error) {
 	if o.cfg.LogForwarders == nil {
 		return nil, trace.BadParameter(
 			"this operator does not support log forwarders management")
@@ -40,6 +44,11 @@ func (o *Operator) GetLogForwarders(key ops.SiteKey) ([]storage.LogForwarder, er
 		return nil, trace.Wrap(err)
 	}
 
+	if !withSecrets {
+		for i, forwarder := range forwarders {
+			forwarders[i] = forwarder.WithoutSecrets()
+		}
+	}
 	return forwarders, nil
 }
 
@@ -77,6 +86,10 @@ func (o *Operator) CreateLogForwarder(key ops.SiteKey, forwarder storage.LogForw
 			"this operator does not support log forwarders management")
 	}
 
+	if err := forwarder.CheckAndSetDefaults(); err != nil {
+		return trace.Wrap(err)
+	}
+
 	err := o.cfg.LogForwarders.Create(forwarder)
 	if err != nil {
 		return trace.Wrap(err)
@@ -97,6 +110,10 @@ func (o *Operator) UpdateLogForwarder(key ops.SiteKey, forwarder storage.LogForw
 			"this operator does not support log forwarders management")
 	}
 
+	if err := forwarder.CheckAndSetDefaults(); err != nil {
+		return trace.Wrap(err)
+	}
+
 	err := o.cfg.LogForwarders.Update(forwarder)
 	if err != nil {
 		return trace.Wrap(err)
@@ -131,7 +148,10 @@ func (o *Operator) DeleteLogForwarder(key ops.SiteKey, name string) error {
 }
 
 // LogForwarderControl defines methods for managing log forwarders in Kubernetes,
-// mostly so implementation can be substituted in tests w/o Kubernetes
+// mostly so implementation can be substituted in tests w/o Kubernetes.
+//
+// Log forwarder client keys and authentication tokens are kept in a secret
+// separately from the rest of the configuration
 type LogForwardersControl interface {
 	// Get returns a list of log forwarders from config map
 	Get() ([]storage.LogForwarder, error)
@@ -164,13 +184,18 @@ func (c *logForwardersControl) Get() ([]storage.LogForwarder, error) {
 		return nil, rigging.ConvertError(err)
 	}
 
+	credentials, err := c.getCredentials()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+
 	var forwarders []storage.LogForwarder
 	for _, data := range configMap.Data {
 		forwarder, err := storage.GetLogForwarderMarshaler().Unmarshal([]byte(data))
 		if err != nil {
 			return nil, trace.Wrap(err)
 		}
-		forwarders = append(forwarders, forwarder)
+		forwarders = append(forwarders, mergeCredentials(forwarder, credentials))
 	}
 
 	return forwarders, nil
@@ -185,12 +210,17 @@ func (c *logForwardersControl) Replace(forwarders []storage.LogForwarder) error
 	}
 
 	configMap.Data = make(map[string]string)
+	credentials := make(map[string][]byte)
 	for _, forwarder := range forwarders {
+		forwarder, forwarderCredentials := splitCredentials(forwarder)
 		bytes, err := storage.GetLogForwarderMarshaler().Marshal(forwarder)
 		if err != nil {
 			return trace.Wrap(err)
 		}
 		configMap.Data[forwarder.GetName()] = string(bytes)
+		for key, value := range forwarderCredentials {
+			credentials[key] = value
+		}
 	}
 
 	_, err = c.client.Core().ConfigMaps(defaults.KubeSystemNamespace).Update(configMap)
@@ -198,7 +228,14 @@ func (c *logForwardersControl) Replace(forwarders []storage.LogForwarder) error
 		return rigging.ConvertError(err)
 	}
 
-	return nil
+	return trace.Wrap(c.updateCredentials(func(data map[string][]byte) {
+		for key := range data {
+			delete(data, key)
+		}
+		for key, value := range credentials {
+			data[key] = value
+		}
+	}))
 }
 
 // Create adds a new log forwarder to the forwarders config map
@@ -214,6 +251,7 @@ func (c *logForwardersControl) Create(forwarder storage.LogForwarder) error {
 		return trace.AlreadyExists("log forwarder %q already exists", forwarder.GetName())
 	}
 
+	forwarder, credentials := splitCredentials(forwarder)
 	bytes, err := storage.GetLogForwarderMarshaler().Marshal(forwarder)
 	if err != nil {
 		return trace.Wrap(err)
@@ -230,7 +268,12 @@ func (c *logForwardersControl) Create(forwarder storage.LogForwarder) error {
 		return rigging.ConvertError(err)
 	}
 
-	return nil
+	return trace.Wrap(c.updateCredentials(func(data map[string][]byte) {
+		deleteCredentials(data, forwarder.GetName())
+		for key, value := range credentials {
+			data[key] = value
+		}
+	}))
 }
 
 // Update updates an existing log forwarder in the k8s config map
@@ -246,6 +289,7 @@ func (c *logForwardersControl) Update(forwarder storage.LogForwarder) error {
 		return trace.NotFound("log forwarder %q not found", forwarder.GetName())
 	}
 
+	forwarder, credentials := splitCredentials(forwarder)
 	bytes, err := storage.GetLogForwarderMarshaler().Marshal(forwarder)
 	if err != nil {
 		return trace.Wrap(err)
@@ -262,7 +306,12 @@ func (c *logForwardersControl) Update(forwarder storage.LogForwarder) error {
 		return rigging.ConvertError(err)
 	}
 
-	return nil
+	return trace.Wrap(c.updateCredentials(func(data map[string][]byte) {
+		deleteCredentials(data, forwarder.GetName())
+		for key, value := range credentials {
+			data[key] = value
+		}
+	}))
 }
 
 // Delete deletes the specified log forwarder from the k8s config map
@@ -285,7 +334,9 @@ func (c *logForwardersControl) Delete(name string) error {
 		return rigging.ConvertError(err)
 	}
 
-	return nil
+	return trace.Wrap(c.updateCredentials(func(data map[string][]byte) {
+		deleteCredentials(data, name)
+	}))
 }
 
 // Reload forces log collector to reload forwarder configuration
@@ -301,3 +352,107 @@ func (c *logForwardersControl) Reload() error {
 
 	return nil
 }
+
+// getCredentials returns the contents of the log forwarders credentials secret
+func (c *logForwardersControl) getCredentials() (map[string][]byte, error) {
+	secret, err := c.client.Core().Secrets(defaults.KubeSystemNamespace).Get(
+		defaults.LogForwardersSecret, metav1.GetOptions{})
+	if err != nil {
+		err = rigging.ConvertError(err)
+		if trace.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, trace.Wrap(err)
+	}
+	return secret.Data, nil
+}
+
+// updateCredentials applies the update function to the contents of
+// the log forwarders credentials secret, creating the secret if necessary
+func (c *logForwardersControl) updateCredentials(update func(map[string][]byte)) error {
+	secrets := c.client.Core().Secrets(defaults.KubeSystemNamespace)
+	secret, err := secrets.Get(defaults.LogForwardersSecret, metav1.GetOptions{})
+	if err != nil {
+		err = rigging.ConvertError(err)
+		if !trace.IsNotFound(err) {
+			return trace.Wrap(err)
+		}
+		data := make(map[string][]byte)
+		update(data)
+		_, err = secrets.Create(&v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      defaults.LogForwardersSecret,
+				Namespace: defaults.KubeSystemNamespace,
+			},
+			Type: v1.SecretTypeOpaque,
+			Data: data,
+		})
+		return rigging.ConvertError(err)
+	}
+	if secret.Data == nil {
+		secret.Data = make(map[string][]byte)
+	}
+	update(secret.Data)
+	_, err = secrets.Update(secret)
+	return rigging.ConvertError(err)
+}
+
+// splitCredentials returns a copy of the log forwarder without the client key
+// and the authentication token, and the removed values keyed by their
+// credentials secret keys
+func splitCredentials(forwarder storage.LogForwarder) (storage.LogForwarder, map[string][]byte) {
+	credentials := make(map[string][]byte)
+	spec := storage.LogForwarderSpecV2{
+		Address:  forwarder.GetAddress(),
+		Protocol: forwarder.GetProtocol(),
+		Selector: forwarder.GetSelector(),
+	}
+	if tls := forwarder.GetTLS(); tls != nil {
+		tlsCopy := *tls
+		if tlsCopy.ClientKey != "" {
+			credentials[clientKeyKey(forwarder.GetName())] = []byte(tlsCopy.ClientKey)
+			tlsCopy.ClientKey = ""
+		}
+		spec.TLS = &tlsCopy
+	}
+	if token := forwarder.GetToken(); token != "" {
+		credentials[tokenKey(forwarder.GetName())] = []byte(token)
+	}
+	return storage.NewLogForwarderWithSpec(forwarder.GetName(), spec), credentials
+}
+
+// mergeCredentials returns the log forwarder with the client key and
+// the authentication token populated from the credentials secret data
+func mergeCredentials(forwarder storage.LogForwarder, credentials map[string][]byte) storage.LogForwarder {
+	clientKey, hasClientKey := credentials[clientKeyKey(forwarder.GetName())]
+	token, hasToken := credentials[tokenKey(forwarder.GetName())]
+	if !hasClientKey && !hasToken {
+		return forwarder
+	}
+	spec := storage.LogForwarderSpecV2{
+		Address:  forwarder.GetAddress(),
+		Protocol: forwarder.GetProtocol(),
+		Selector: forwarder.GetSelector(),
+		Token:    string(token),
+	}
+	if tls := forwarder.GetTLS(); tls != nil {
+		tlsCopy := *tls
+		tlsCopy.ClientKey = string(clientKey)
+		spec.TLS = &tlsCopy
+	}
+	return storage.NewLogForwarderWithSpec(forwarder.GetName(), spec)
+}
+
+// deleteCredentials removes credentials of the specified log forwarder
+func deleteCredentials(credentials map[string][]byte, name string) {
+	delete(credentials, clientKeyKey(name))
+	delete(credentials, tokenKey(name))
+}
+
+func clientKeyKey(name string) string {
+	return fmt.Sprintf("%v.key", name)
+}
+
+func tokenKey(name string) string {
+	return fmt.Sprintf("%v.token", name)
+}
@@ -0,0 +1,62 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package opsservice
+
+import (
+	"github.com/gravitational/gravity/lib/storage"
+
+	"gopkg.in/check.v1"
+)
+
+type LogForwardersSuite struct{}
+
+var _ = check.Suite(&LogForwardersSuite{})
+
+func (s *LogForwardersSuite) TestSplitsCredentials(c *check.C) {
+	forwarder := storage.NewLogForwarderWithSpec("siem", storage.LogForwarderSpecV2{
+		Address:  "siem.example.com:6514",
+		Protocol: "tcp",
+		TLS: &storage.LogForwarderTLS{
+			CACert:     "ca",
+			ClientCert: "cert",
+			ClientKey:  "key",
+		},
+		Token: "token",
+		Selector: &storage.LogForwarderSelector{
+			Namespaces: []string{"default"},
+			Severity:   "warning",
+		},
+	})
+
+	stripped, credentials := splitCredentials(forwarder)
+	c.Assert(credentials, check.DeepEquals, map[string][]byte{
+		"siem.key":   []byte("key"),
+		"siem.token": []byte("token"),
+	})
+	c.Assert(stripped.GetToken(), check.Equals, "")
+	c.Assert(stripped.GetTLS().ClientKey, check.Equals, "")
+	c.Assert(stripped.GetTLS().ClientCert, check.Equals, "cert")
+	c.Assert(forwarder.GetTLS().ClientKey, check.Equals, "key",
+		check.Commentf("original forwarder should not be modified"))
+
+	c.Assert(mergeCredentials(stripped, credentials), check.DeepEquals, forwarder)
+
+	plain := storage.NewLogForwarder("plain", "127.0.0.1:514", "udp")
+	stripped, credentials = splitCredentials(plain)
+	c.Assert(credentials, check.HasLen, 0)
+	c.Assert(mergeCredentials(stripped, credentials), check.DeepEquals, plain)
+}
@@ -0,0 +1,138 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package opsservice
+
+import (
+	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/storage"
+
+	"github.com/gravitational/trace"
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+)
+
+// NewMetricsCollector returns a Prometheus collector that exports
+// the number of operations and the durations of finished operations
+// by operation type and state.
+//
+// The metrics are computed from the operations in the specified backend
+// when scraped, so they survive process restarts and leader changes
+func NewMetricsCollector(backend storage.Backend) prometheus.Collector {
+	return &metricsCollector{backend: backend}
+}
+
+var (
+	operationsDesc = prometheus.NewDesc(
+		"gravity_operations",
+		"Number of cluster operations by type and state",
+		[]string{"type", "state"}, nil,
+	)
+	operationDurationsDesc = prometheus.NewDesc(
+		"gravity_operation_duration_seconds",
+		"Duration of finished cluster operations by type and state",
+		[]string{"type", "state"}, nil,
+	)
+	// operationBuckets are the buckets for operation durations:
+	// lowest bucket start of upper bound 10 sec with factor 2
+	// highest bucket start of 10 sec * 2^11 == 20480 sec
+	operationBuckets = prometheus.ExponentialBuckets(10, 2, 12)
+)
+
+type metricsCollector struct {
+	backend storage.Backend
+}
+
+// Describe implements prometheus.Collector
+func (c *metricsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- operationsDesc
+	ch <- operationDurationsDesc
+}
+
+// Collect implements prometheus.Collector
+func (c *metricsCollector) Collect(ch chan<- prometheus.Metric) {
+	operations, err := c.operations()
+	if err != nil {
+		log.Warnf("Failed to collect operation metrics: %v.", trace.DebugReport(err))
+		return
+	}
+	counts := make(map[operationLabels]int)
+	durations := make(map[operationLabels]*durationHistogram)
+	for _, operation := range operations {
+		labels := operationLabels{opType: operation.Type, state: operation.State}
+		counts[labels]++
+		if !(*ops.SiteOperation)(&operation).IsFinished() {
+			continue
+		}
+		if durations[labels] == nil {
+			durations[labels] = newDurationHistogram()
+		}
+		durations[labels].observe(operation.Updated.Sub(operation.Created).Seconds())
+	}
+	for labels, count := range counts {
+		ch <- prometheus.MustNewConstMetric(operationsDesc, prometheus.GaugeValue,
+			float64(count), labels.opType, labels.state)
+	}
+	for labels, h := range durations {
+		ch <- prometheus.MustNewConstHistogram(operationDurationsDesc,
+			h.count, h.sum, h.buckets, labels.opType, labels.state)
+	}
+}
+
+func (c *metricsCollector) operations() (operations []storage.SiteOperation, err error) {
+	clusters, err := c.backend.GetAllSites()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	for _, cluster := range clusters {
+		clusterOperations, err := c.backend.GetSiteOperations(cluster.Domain)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		operations = append(operations, clusterOperations...)
+	}
+	return operations, nil
+}
+
+type operationLabels struct {
+	opType string
+	state  string
+}
+
+// durationHistogram accumulates the cumulative histogram of operation durations
+type durationHistogram struct {
+	count   uint64
+	sum     float64
+	buckets map[float64]uint64
+}
+
+func newDurationHistogram() *durationHistogram {
+	buckets := make(map[float64]uint64, len(operationBuckets))
+	for _, bound := range operationBuckets {
+		buckets[bound] = 0
+	}
+	return &durationHistogram{buckets: buckets}
+}
+
+func (h *durationHistogram) observe(seconds float64) {
+	h.count++
+	h.sum += seconds
+	for bound := range h.buckets {
+		if seconds <= bound {
+			h.buckets[bound]++
+		}
+	}
+}
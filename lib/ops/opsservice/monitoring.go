@@ -140,8 +140,12 @@ func (o *Operator) DeleteAlert(key ops.SiteKey, name string) error {
 	return trace.Wrap(rigging.ConvertError(err))
 }
 
-// GetAlertTargets returns a list of configured monitoring alert targets
-func (o *Operator) GetAlertTargets(key ops.SiteKey) (targets []storage.AlertTarget, err error) {
+// GetAlertTargets returns a list of configured monitoring alert targets.
+//
+// The email alert target is kept in a ConfigMap consumed by the monitoring
+// application while webhook, Slack and PagerDuty targets are stored as secrets.
+// Delivery credentials are only returned if withSecrets is true
+func (o *Operator) GetAlertTargets(key ops.SiteKey, withSecrets bool) (targets []storage.AlertTarget, err error) {
 	client, err := o.GetKubeClient()
 	if err != nil {
 		return nil, trace.Wrap(err)
@@ -149,23 +153,62 @@ func (o *Operator) GetAlertTargets(key ops.SiteKey) (targets []storage.AlertTarg
 
 	data, err := getConfigMap(client.Core().ConfigMaps(defaults.MonitoringNamespace),
 		constants.AlertTargetConfigMap)
-	if err != nil {
-		if trace.IsNotFound(err) {
-			return nil, trace.NotFound("alert target not found")
-		}
+	if err != nil && !trace.IsNotFound(err) {
 		return nil, trace.Wrap(err)
 	}
+	if err == nil {
+		target, err := storage.UnmarshalAlertTarget([]byte(data))
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		targets = append(targets, target)
+	}
 
-	target, err := storage.UnmarshalAlertTarget([]byte(data))
+	labels := kubelabels.Set{
+		constants.MonitoringType: constants.MonitoringTypeAlertTarget,
+	}
+	secrets, err := client.Core().Secrets(defaults.MonitoringNamespace).List(metav1.ListOptions{
+		LabelSelector: labels.String(),
+	})
 	if err != nil {
-		return nil, trace.Wrap(err)
+		return nil, trace.Wrap(rigging.ConvertError(err))
+	}
+	var errors []error
+	for _, secret := range secrets.Items {
+		data, ok := secret.Data[constants.ResourceSpecKey]
+		if !ok {
+			continue
+		}
+		target, err := storage.UnmarshalAlertTarget(data)
+		if err != nil {
+			errors = append(errors, err)
+			continue
+		}
+		targets = append(targets, target)
+	}
+	if len(errors) != 0 {
+		return nil, trace.NewAggregate(errors...)
 	}
 
-	return []storage.AlertTarget{target}, nil
+	if len(targets) == 0 {
+		return nil, trace.NotFound("alert target not found")
+	}
+	if !withSecrets {
+		for i, target := range targets {
+			targets[i] = target.WithoutSecrets()
+		}
+	}
+	return targets, nil
 }
 
-// UpdateAlertTarget updates the cluster monitoring alert target
+// UpdateAlertTarget creates or updates the cluster monitoring alert target.
+//
+// There can be only one email alert target: updating it replaces the existing one
 func (o *Operator) UpdateAlertTarget(key ops.SiteKey, target storage.AlertTarget) error {
+	if err := target.CheckAndSetDefaults(); err != nil {
+		return trace.Wrap(err)
+	}
+
 	client, err := o.GetKubeClient()
 	if err != nil {
 		return trace.Wrap(err)
@@ -179,22 +222,44 @@ func (o *Operator) UpdateAlertTarget(key ops.SiteKey, target storage.AlertTarget
 	labels := map[string]string{
 		constants.MonitoringType: constants.MonitoringTypeAlertTarget,
 	}
-	return updateConfigMap(client.Core().ConfigMaps(defaults.MonitoringNamespace),
-		constants.AlertTargetConfigMap, defaults.MonitoringNamespace, string(data), labels)
+	if target.GetType() == storage.AlertTargetEmail {
+		return updateConfigMap(client.Core().ConfigMaps(defaults.MonitoringNamespace),
+			constants.AlertTargetConfigMap, defaults.MonitoringNamespace, string(data), labels)
+	}
+	return updateSecret(client.Core().Secrets(defaults.MonitoringNamespace),
+		alertTargetSecretName(target.GetName()), defaults.MonitoringNamespace, data, labels)
 }
 
-// DeleteAlertTarget deletes the cluster monitoring alert target
-func (o *Operator) DeleteAlertTarget(key ops.SiteKey) error {
+// DeleteAlertTarget deletes the cluster monitoring alert target specified with name.
+// If name is empty, the email alert target is deleted
+func (o *Operator) DeleteAlertTarget(key ops.SiteKey, name string) error {
 	client, err := o.GetKubeClient()
 	if err != nil {
 		return trace.Wrap(err)
 	}
 
-	err = rigging.ConvertError(client.Core().ConfigMaps(defaults.MonitoringNamespace).Delete(constants.AlertTargetConfigMap, nil))
-	if trace.IsNotFound(err) {
+	targets, err := o.GetAlertTargets(key, false)
+	if err != nil && !trace.IsNotFound(err) {
+		return trace.Wrap(err)
+	}
+	for _, target := range targets {
+		if target.GetType() == storage.AlertTargetEmail && (name == "" || target.GetName() == name) {
+			err = rigging.ConvertError(client.Core().ConfigMaps(defaults.MonitoringNamespace).Delete(constants.AlertTargetConfigMap, nil))
+			return trace.Wrap(err)
+		}
+		if name != "" && target.GetName() == name {
+			err = rigging.ConvertError(client.Core().Secrets(defaults.MonitoringNamespace).Delete(alertTargetSecretName(name), nil))
+			return trace.Wrap(err)
+		}
+	}
+	if name == "" {
 		return trace.NotFound("no alert targets found")
 	}
-	return trace.Wrap(err)
+	return trace.NotFound("alert target %q not found", name)
+}
+
+func alertTargetSecretName(name string) string {
+	return constants.AlertTargetSecretPrefix + name
 }
 
 func getConfigMap(client corev1.ConfigMapInterface, name string) (string, error) {
@@ -236,3 +301,30 @@ func updateConfigMap(client corev1.ConfigMapInterface, name, namespace, data str
 	_, err = client.Update(config)
 	return trace.Wrap(rigging.ConvertError(err))
 }
+
+func updateSecret(client corev1.SecretInterface, name, namespace string, data []byte, labels map[string]string) error {
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels:    labels,
+		},
+		Data: map[string][]byte{
+			constants.ResourceSpecKey: data,
+		},
+		Type: v1.SecretTypeOpaque,
+	}
+
+	_, err := client.Create(secret)
+	err = rigging.ConvertError(err)
+	if err == nil {
+		return nil
+	}
+
+	if !trace.IsAlreadyExists(err) {
+		return trace.Wrap(err)
+	}
+
+	_, err = client.Update(secret)
+	return trace.Wrap(rigging.ConvertError(err))
+}
@@ -63,10 +63,12 @@ func (s *site) getSiteOperationCrashReport(op ops.SiteOperation) (io.ReadCloser,
 	}
 
 	runner := s.agentRunner(ctx)
-	return s.getReport(runner, remoteServers, master)
+	return s.getReport(runner, remoteServers, master, nil)
 }
 
-func (s *site) getSiteReport() (io.ReadCloser, error) {
+// getSiteReport collects the cluster diagnostics report.
+// If redactor is not nil, sensitive data is removed from the report
+func (s *site) getSiteReport(redactor *report.Redactor) (io.ReadCloser, error) {
 	const noRetry = 1
 	servers, err := s.getTeleportServersWithTimeout(
 		nil,
@@ -93,23 +95,23 @@ func (s *site) getSiteReport() (io.ReadCloser, error) {
 		remoteServers = append(remoteServers, teleportServer)
 	}
 
-	return s.getReport(teleportRunner, remoteServers, master)
+	return s.getReport(teleportRunner, remoteServers, master, redactor)
 }
 
-func (s *site) getReport(runner remoteRunner, servers []remoteServer, master remoteServer) (io.ReadCloser, error) {
+func (s *site) getReport(runner remoteRunner, servers []remoteServer, master remoteServer, redactor *report.Redactor) (io.ReadCloser, error) {
 	dir, err := ioutil.TempDir("", "report")
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
 
-	err = runCollectors(*s, dir, runner)
+	err = runCollectors(*s, dir, runner, redactor)
 	if err != nil {
 		// Intermediate steps in diagnostics collection are not fatal
 		// to collect all possible pieces in best-effort
 		log.Errorf("failed to run cluster collectors: %v", trace.DebugReport(err))
 	}
 
-	collectOperationsLogs(*s, dir)
+	collectOperationsLogs(*s, dir, redactor)
 
 	if len(servers) > 0 {
 		// Use the first master server to collect kubernetes diagnostics
@@ -119,15 +121,23 @@ func (s *site) getReport(runner remoteRunner, servers []remoteServer, master rem
 			log.Warningf("no master servers, collecting kubernetes diagnostics from %v", server)
 		}
 		serverRunner := &serverRunner{server: server, runner: runner}
-		reportWriter := getReportWriterForServer(dir, server)
+		reportWriter := withRedactor(getReportWriterForServer(dir, server), redactor)
 		s.collectKubernetesInfo(reportWriter, serverRunner)
 
-		err = s.collectDebugInfoFromServers(dir, servers, runner)
+		err = s.collectDebugInfoFromServers(dir, servers, runner, redactor)
 		if err != nil {
 			log.Errorf("failed to collect diagnostics from some nodes: %v", trace.DebugReport(err))
 		}
 	}
 
+	if redactor != nil {
+		err = redactor.WriteManifest(report.NewFileWriter(dir))
+		if err != nil {
+			os.RemoveAll(dir)
+			return nil, trace.Wrap(err)
+		}
+	}
+
 	// use a pipe to avoid allocating a buffer
 	reader, writer := io.Pipe()
 	gzWriter := gzip.NewWriter(writer)
@@ -153,14 +163,14 @@ func (s *site) getReport(runner remoteRunner, servers []remoteServer, master rem
 //
 //   <server-name>-<resource>
 //
-func (s *site) collectDebugInfoFromServers(dir string, servers []remoteServer, runner remoteRunner) error {
+func (s *site) collectDebugInfoFromServers(dir string, servers []remoteServer, runner remoteRunner, redactor *report.Redactor) error {
 	err := s.executeOnServers(context.TODO(), servers, func(c context.Context, server remoteServer) error {
 		log.Debugf("collectDebugInfo for %v", server)
 		r := &serverRunner{
 			server: server,
 			runner: runner,
 		}
-		reportWriter := getReportWriterForServer(dir, server)
+		reportWriter := withRedactor(getReportWriterForServer(dir, server), redactor)
 		err := s.collectDebugInfo(reportWriter, r)
 		return trace.Wrap(err)
 	})
@@ -200,7 +210,7 @@ func (s *site) collectKubernetesInfo(reportWriter report.Writer, runner *serverR
 	return nil
 }
 
-func runCollectors(site site, dir string, runner remoteRunner) error {
+func runCollectors(site site, dir string, runner remoteRunner, redactor *report.Redactor) error {
 	storageSite, err := site.service.cfg.Backend.GetSite(site.domainName)
 	if err != nil {
 		return trace.Wrap(err)
@@ -210,7 +220,7 @@ func runCollectors(site site, dir string, runner remoteRunner) error {
 		collectSiteInfo(*storageSite),
 		collectDumpHook,
 	}
-	reportWriter := report.NewFileWriter(dir)
+	reportWriter := withRedactor(report.NewFileWriter(dir), redactor)
 
 	// collect information from all collectors
 	for _, collector := range collectors {
@@ -222,13 +232,13 @@ func runCollectors(site site, dir string, runner remoteRunner) error {
 	return nil
 }
 
-func collectOperationsLogs(site site, dir string) error {
+func collectOperationsLogs(site site, dir string, redactor *report.Redactor) error {
 	operations, err := site.service.GetSiteOperations(site.key)
 	if err != nil {
 		return trace.Wrap(err, "failed to get cluster operations")
 	}
 
-	reportWriter := report.NewFileWriter(dir)
+	reportWriter := withRedactor(report.NewFileWriter(dir), redactor)
 
 	for _, op := range operations {
 		operation := ops.SiteOperation(op)
@@ -304,6 +314,15 @@ func collectOperationLogs(site site, operation ops.SiteOperation, reportWriter r
 
 type collectorFn func(report.Writer, site) error
 
+// withRedactor returns the report writer that removes sensitive data
+// using the specified redactor, or the writer itself if redactor is nil
+func withRedactor(reportWriter report.Writer, redactor *report.Redactor) report.Writer {
+	if redactor == nil {
+		return reportWriter
+	}
+	return redactor.Writer(reportWriter)
+}
+
 func getReportWriterForServer(dir string, server remoteServer) report.Writer {
 	return func(name string) (io.WriteCloser, error) {
 		fileName := filepath.Join(dir, fmt.Sprintf("%s-%s", server.HostName(), name))
@@ -27,6 +27,7 @@ import (
 	"time"
 
 	appservice "github.com/gravitational/gravity/lib/app"
+	"github.com/gravitational/gravity/lib/blob"
 	"github.com/gravitational/gravity/lib/checks"
 	"github.com/gravitational/gravity/lib/clients"
 	"github.com/gravitational/gravity/lib/constants"
@@ -37,6 +38,7 @@ import (
 	"github.com/gravitational/gravity/lib/ops/monitoring"
 	"github.com/gravitational/gravity/lib/ops/opsclient"
 	"github.com/gravitational/gravity/lib/pack"
+	"github.com/gravitational/gravity/lib/report"
 	"github.com/gravitational/gravity/lib/schema"
 	"github.com/gravitational/gravity/lib/storage"
 	"github.com/gravitational/gravity/lib/users"
@@ -123,6 +125,10 @@ type Config struct {
 
 	// Client specifies an optional kubernetes client
 	Client *kubernetes.Clientset
+
+	// BLOBs optionally verifies the integrity of the packages
+	// stored on this peer
+	BLOBs blob.Checker
 }
 
 // Operator implements Operator interface
@@ -1061,17 +1067,24 @@ func (o *Operator) GetSiteOperationCrashReport(key ops.SiteOperationKey) (io.Rea
 	case ops.OperationInstall:
 		return site.getSiteOperationCrashReport(*op)
 	default:
-		return site.getSiteReport()
+		return site.getSiteReport(nil)
 	}
 }
 
-func (o *Operator) GetSiteReport(key ops.SiteKey) (io.ReadCloser, error) {
-	site, err := o.openSite(key)
+func (o *Operator) GetSiteReport(req ops.GetSiteReportRequest) (io.ReadCloser, error) {
+	site, err := o.openSite(req.SiteKey())
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
 
-	return site.getSiteReport()
+	var redactor *report.Redactor
+	if req.Redact {
+		redactor, err = report.NewRedactor(req.RedactPatterns)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+	}
+	return site.getSiteReport(redactor)
 }
 
 func (o *Operator) GetSiteOperationProgress(key ops.SiteOperationKey) (*ops.ProgressEntry, error) {
@@ -30,7 +30,7 @@ import (
 )
 
 // GetSMTPConfig returns the cluster SMTP configuration
-func (o *Operator) GetSMTPConfig(key ops.SiteKey) (storage.SMTPConfig, error) {
+func (o *Operator) GetSMTPConfig(key ops.SiteKey, withSecrets bool) (storage.SMTPConfig, error) {
 	client, err := o.GetKubeClient()
 	if err != nil {
 		return nil, trace.Wrap(err)
@@ -46,6 +46,9 @@ func (o *Operator) GetSMTPConfig(key ops.SiteKey) (storage.SMTPConfig, error) {
 		return nil, trace.Wrap(err)
 	}
 
+	if !withSecrets {
+		return config.WithoutSecrets(), nil
+	}
 	return config, nil
 }
 
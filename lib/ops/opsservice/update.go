@@ -331,6 +331,9 @@ func (s *site) createUpdateOperation(req ops.CreateSiteAppUpdateOperationRequest
 		Provisioner: installOperation.Provisioner,
 		Update: &storage.UpdateOperationState{
 			UpdatePackage: req.App,
+			Strategy:      req.Strategy,
+			AutoRollback:  req.AutoRollback,
+			Parallelism:   req.Parallelism,
 		},
 	}
 
@@ -402,6 +405,14 @@ func (s *site) validateUpdateOperationRequest(req ops.CreateSiteAppUpdateOperati
 	if err != nil {
 		return trace.Wrap(err)
 	}
+	if req.Strategy != nil {
+		if err := req.Strategy.Check(); err != nil {
+			return trace.Wrap(err)
+		}
+	}
+	if req.Parallelism < 0 {
+		return trace.BadParameter("parallelism cannot be negative: %v", req.Parallelism)
+	}
 	// the new package must exist in the Ops Center
 	newEnvelope, err := s.packages().ReadPackageEnvelope(*updatePackage)
 	if err != nil {
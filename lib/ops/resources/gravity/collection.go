@@ -282,6 +282,13 @@ func formatExpiry(t time.Time) string {
 	return t.Format(constants.HumanDateFormat)
 }
 
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Format(constants.HumanDateFormat)
+}
+
 type logForwardersCollection struct {
 	logForwarders []storage.LogForwarder
 }
@@ -301,12 +308,13 @@ func (c *logForwardersCollection) Resources() (resources []teleservices.UnknownR
 // WriteText serializes collection in human-friendly text format
 func (c *logForwardersCollection) WriteText(w io.Writer) error {
 	t := goterm.NewTable(0, 10, 5, ' ', 0)
-	common.PrintTableHeader(t, []string{"Name", "Address", "Protocol"})
+	common.PrintTableHeader(t, []string{"Name", "Address", "Protocol", "TLS"})
 	for _, forwarder := range c.logForwarders {
-		fmt.Fprintf(t, "%v\t%v\t%v\n",
+		fmt.Fprintf(t, "%v\t%v\t%v\t%v\n",
 			forwarder.GetName(),
 			forwarder.GetAddress(),
-			forwarder.GetProtocol())
+			forwarder.GetProtocol(),
+			forwarder.GetTLS() != nil)
 	}
 	_, err := io.WriteString(w, t.String())
 	return trace.Wrap(err)
@@ -475,9 +483,9 @@ type alertCollection []storage.Alert
 // WriteText serializes collection in human-friendly text format
 func (r alertTargetCollection) WriteText(w io.Writer) error {
 	t := goterm.NewTable(0, 10, 5, ' ', 0)
-	common.PrintTableHeader(t, []string{"Email"})
+	common.PrintTableHeader(t, []string{"Name", "Type", "Destination"})
 	for _, target := range r {
-		fmt.Fprintf(t, "%v\n", target.GetEmail())
+		fmt.Fprintf(t, "%v\t%v\t%v\n", target.GetName(), target.GetType(), formatAlertTarget(target))
 	}
 	_, err := io.WriteString(w, t.String())
 	return trace.Wrap(err)
@@ -514,6 +522,50 @@ func (c alertTargetCollection) Resources() (resources []teleservices.UnknownReso
 
 type alertTargetCollection []storage.AlertTarget
 
+// WriteText serializes collection in human-friendly text format
+func (r backupScheduleCollection) WriteText(w io.Writer) error {
+	t := goterm.NewTable(0, 10, 5, ' ', 0)
+	common.PrintTableHeader(t, []string{"Name", "Schedule", "Destination", "Last Success", "Last Failure"})
+	for _, schedule := range r {
+		status := schedule.GetStatus()
+		fmt.Fprintf(t, "%v\t%v\t%v\t%v\t%v\n", schedule.GetName(), schedule.GetSchedule(),
+			schedule.GetDestination(), formatTime(status.LastSuccess), formatTime(status.LastFailure))
+	}
+	_, err := io.WriteString(w, t.String())
+	return trace.Wrap(err)
+}
+
+// WriteJSON serializes collection into JSON format
+func (r backupScheduleCollection) WriteJSON(w io.Writer) error {
+	return utils.WriteJSON(r, w)
+}
+
+// WriteYAML serializes collection into YAML format
+func (r backupScheduleCollection) WriteYAML(w io.Writer) error {
+	return utils.WriteYAML(r, w)
+}
+
+func (r backupScheduleCollection) ToMarshal() interface{} {
+	if len(r) == 1 {
+		return r[0]
+	}
+	return r
+}
+
+// Resources returns the resources collection in the generic format
+func (r backupScheduleCollection) Resources() (resources []teleservices.UnknownResource, err error) {
+	for _, item := range r {
+		resource, err := utils.ToUnknownResource(item)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		resources = append(resources, *resource)
+	}
+	return resources, nil
+}
+
+type backupScheduleCollection []storage.BackupSchedule
+
 type authGatewayCollection struct {
 	item storage.AuthGateway
 }
@@ -693,3 +745,23 @@ func formatFeatureGates(features map[string]bool) string {
 	}
 	return strings.Join(result, ",")
 }
+
+// formatAlertTarget returns the destination of the alert target for display.
+// Slack webhook URLs and PagerDuty routing keys are credentials so they
+// are not displayed
+func formatAlertTarget(target storage.AlertTarget) string {
+	switch target.GetType() {
+	case storage.AlertTargetEmail:
+		return target.GetEmail()
+	case storage.AlertTargetWebhook:
+		return target.GetWebhook().URL
+	case storage.AlertTargetSlack:
+		if channel := target.GetSlack().Channel; channel != "" {
+			return channel
+		}
+		return "<default channel>"
+	case storage.AlertTargetPagerDuty:
+		return target.GetPagerDuty().URL
+	}
+	return ""
+}
@@ -79,6 +79,10 @@ func (r *Resources) Create(req resources.CreateRequest) error {
 	if err := req.Check(); err != nil {
 		return trace.Wrap(err)
 	}
+	if req.DryRun && !isClusterOperationResource(req.Resource.Kind) {
+		return trace.BadParameter("dry run is only supported for %v and %v resources",
+			storage.KindClusterConfiguration, storage.KindRuntimeEnvironment)
+	}
 	switch req.Resource.Kind {
 	case teleservices.KindGithubConnector:
 		conn, err := teleservices.GetGithubConnectorMarshaler().Unmarshal(req.Resource.Raw)
@@ -218,6 +222,16 @@ func (r *Resources) Create(req resources.CreateRequest) error {
 			return trace.Wrap(err)
 		}
 		r.Printf("Updated monitoring alert target %q\n", target.GetName())
+	case storage.KindBackupSchedule:
+		schedule, err := storage.UnmarshalBackupSchedule(req.Resource.Raw)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		err = r.Operator.UpsertBackupSchedule(r.cluster.Key(), schedule)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		r.Printf("Updated backup schedule %q\n", schedule.GetName())
 	case storage.KindAuthGateway:
 		gw, err := storage.UnmarshalAuthGateway(req.Resource.Raw)
 		if err != nil {
@@ -294,7 +308,7 @@ func (r *Resources) GetCollection(req resources.ListRequest) (resources.Collecti
 		}
 		return &tokenCollection{tokens: tokens}, nil
 	case storage.KindLogForwarder:
-		forwarders, err := r.Operator.GetLogForwarders(r.cluster.Key())
+		forwarders, err := r.Operator.GetLogForwarders(r.cluster.Key(), req.WithSecrets)
 		if err != nil {
 			return nil, trace.Wrap(err)
 		}
@@ -340,7 +354,7 @@ func (r *Resources) GetCollection(req resources.ListRequest) (resources.Collecti
 		}
 		return &authGatewayCollection{gw}, nil
 	case storage.KindSMTPConfig:
-		config, err := r.Operator.GetSMTPConfig(r.cluster.Key())
+		config, err := r.Operator.GetSMTPConfig(r.cluster.Key(), req.WithSecrets)
 		if err != nil {
 			return nil, trace.Wrap(err)
 		}
@@ -366,11 +380,33 @@ func (r *Resources) GetCollection(req resources.ListRequest) (resources.Collecti
 		}
 		return alertCollection(filtered), nil
 	case storage.KindAlertTarget:
-		alertTargets, err := r.Operator.GetAlertTargets(r.cluster.Key())
+		alertTargets, err := r.Operator.GetAlertTargets(r.cluster.Key(), req.WithSecrets)
 		if err != nil {
 			return nil, trace.Wrap(err)
 		}
-		return alertTargetCollection(alertTargets), nil
+		if req.Name == "" {
+			return alertTargetCollection(alertTargets), nil
+		}
+		for _, target := range alertTargets {
+			if target.GetName() == req.Name {
+				return alertTargetCollection{target}, nil
+			}
+		}
+		return nil, trace.NotFound("alert target %q is not found", req.Name)
+	case storage.KindBackupSchedule:
+		schedules, err := r.Operator.GetBackupSchedules(r.cluster.Key(), req.WithSecrets)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		if req.Name == "" {
+			return backupScheduleCollection(schedules), nil
+		}
+		for _, schedule := range schedules {
+			if schedule.GetName() == req.Name {
+				return backupScheduleCollection{schedule}, nil
+			}
+		}
+		return nil, trace.NotFound("backup schedule %q is not found", req.Name)
 	case storage.KindRuntimeEnvironment:
 		env, err := r.Operator.GetClusterEnvironmentVariables(r.cluster.Key())
 		if err != nil {
@@ -459,13 +495,25 @@ func (r *Resources) Remove(req resources.RemoveRequest) error {
 		}
 		r.Printf("Alert %q has been deleted\n", req.Name)
 	case storage.KindAlertTarget:
-		if err := r.Operator.DeleteAlertTarget(r.cluster.Key()); err != nil {
+		if err := r.Operator.DeleteAlertTarget(r.cluster.Key(), req.Name); err != nil {
+			if trace.IsNotFound(err) && req.Force {
+				return nil
+			}
+			return trace.Wrap(err)
+		}
+		if req.Name != "" {
+			r.Printf("Alert target %q has been deleted\n", req.Name)
+		} else {
+			r.Println("Alert target has been deleted")
+		}
+	case storage.KindBackupSchedule:
+		if err := r.Operator.DeleteBackupSchedule(r.cluster.Key(), req.Name); err != nil {
 			if trace.IsNotFound(err) && req.Force {
 				return nil
 			}
 			return trace.Wrap(err)
 		}
-		r.Println("Alert target has been deleted")
+		r.Printf("Backup schedule %q has been deleted\n", req.Name)
 	case storage.KindRuntimeEnvironment, storage.KindClusterConfiguration:
 		err := r.ClusterOperationHandler.RemoveResource(req)
 		return trace.Wrap(err)
@@ -478,6 +526,16 @@ func (r *Resources) Remove(req resources.RemoveRequest) error {
 	return nil
 }
 
+// isClusterOperationResource returns true if the resource of the specified kind
+// is managed with a cluster operation
+func isClusterOperationResource(kind string) bool {
+	switch kind {
+	case storage.KindRuntimeEnvironment, storage.KindClusterConfiguration:
+		return true
+	}
+	return false
+}
+
 // ClusterOperationHandler defines a service to manage resources based on cluster operations
 type ClusterOperationHandler interface {
 	// RemoveResource removes the specified resource
@@ -510,6 +568,8 @@ func Validate(resource storage.UnknownResource) (err error) {
 		_, err = storage.UnmarshalAlertTarget(resource.Raw)
 	case storage.KindAuthGateway:
 		_, err = storage.UnmarshalAuthGateway(resource.Raw)
+	case storage.KindBackupSchedule:
+		_, err = storage.UnmarshalBackupSchedule(resource.Raw)
 	case storage.KindRuntimeEnvironment:
 		_, err = storage.UnmarshalEnvironmentVariables(resource.Raw)
 	case storage.KindClusterConfiguration:
@@ -83,6 +83,15 @@ func (s *GravityResourcesSuite) TestGithubConnectorResource(c *check.C) {
 	compare.DeepCompare(c, collection, &githubCollection{[]teleservices.GithubConnector{}})
 }
 
+func (s *GravityResourcesSuite) TestRejectsDryRunForRegularResources(c *check.C) {
+	err := s.r.Create(resources.CreateRequest{Resource: toUnknown(c, githubConnector), DryRun: true})
+	c.Assert(trace.IsBadParameter(err), check.Equals, true, check.Commentf("expected BadParameter, got %v", err))
+
+	collection, err := s.r.GetCollection(resources.ListRequest{Kind: teleservices.KindGithubConnector})
+	c.Assert(err, check.IsNil)
+	compare.DeepCompare(c, collection, &githubCollection{[]teleservices.GithubConnector{}})
+}
+
 func (s *GravityResourcesSuite) TestUser(c *check.C) {
 	err := s.r.Create(resources.CreateRequest{Resource: toUnknown(c, user)})
 	c.Assert(err, check.IsNil)
@@ -87,6 +87,10 @@ type CreateRequest struct {
 	// Confirmed defines whether the operation has been explicitly approved.
 	// This attribute is operation-specific
 	Confirmed bool
+	// DryRun defines whether to only display the plan of the operation
+	// that would update the resource without creating it.
+	// This attribute is operation-specific
+	DryRun bool
 }
 
 // Check validates the request
@@ -0,0 +1,160 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pack
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/gravitational/gravity/lib/loc"
+	"github.com/gravitational/gravity/lib/pack/delta"
+
+	"github.com/gravitational/trace"
+	log "github.com/sirupsen/logrus"
+)
+
+// DeltaPackageService is implemented by package services
+// that can transfer packages as deltas against a base package
+// the other party already has
+type DeltaPackageService interface {
+	// ReadPackageSignature returns the signature of the specified package
+	// to compute deltas against
+	ReadPackageSignature(loc loc.Locator) (*delta.Signature, error)
+
+	// ReadPackageDelta returns the envelope of the specified package
+	// and its delta against the base package with the provided signature
+	ReadPackageDelta(loc loc.Locator, base delta.Signature) (*PackageEnvelope, io.ReadCloser, error)
+
+	// CreatePackageFromDelta creates or updates (if upsert is true) the specified
+	// package from the delta against the existing base package
+	CreatePackageFromDelta(loc loc.Locator, base loc.Locator, data io.Reader, upsert bool, options ...PackageOption) (*PackageEnvelope, error)
+}
+
+// ReadPackageSignature computes the signature of the specified package
+// from the provided package service
+func ReadPackageSignature(packages PackageService, loc loc.Locator) (*delta.Signature, error) {
+	_, reader, err := packages.ReadPackage(loc)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	defer reader.Close()
+	sig, err := delta.NewSignature(loc.String(), reader)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return sig, nil
+}
+
+// ReadPackageDelta computes the delta of the specified package from the provided
+// package service against the base package with the given signature
+func ReadPackageDelta(packages PackageService, loc loc.Locator, base delta.Signature) (*PackageEnvelope, io.ReadCloser, error) {
+	envelope, reader, err := packages.ReadPackage(loc)
+	if err != nil {
+		return nil, nil, trace.Wrap(err)
+	}
+	pr, pw := io.Pipe()
+	go func() {
+		defer reader.Close()
+		stats, err := delta.Diff(base, reader, pw)
+		if err == nil {
+			log.WithField("package", loc).Debugf("Computed delta against %v: %v bytes copied, %v bytes sent.",
+				base.Base, stats.Copied, stats.Literal)
+		}
+		pw.CloseWithError(err)
+	}()
+	return envelope, pr, nil
+}
+
+// CreatePackageFromDelta creates or updates (if upsert is true) the specified package
+// in the provided package service by applying the delta read from data to the base package
+func CreatePackageFromDelta(packages PackageService, loc loc.Locator, base loc.Locator, data io.Reader, upsert bool, options ...PackageOption) (*PackageEnvelope, error) {
+	_, reader, err := packages.ReadPackage(base)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	defer reader.Close()
+	baseData, ok := reader.(io.ReaderAt)
+	if !ok {
+		// the delta refers to the base package by offset
+		file, err := spoolToTempFile(reader)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		defer func() {
+			file.Close()
+			os.Remove(file.Name())
+		}()
+		baseData = file
+	}
+	pr, pw := io.Pipe()
+	go func() {
+		_, err := delta.Patch(baseData, data, pw)
+		// the package is not created if the reconstructed data
+		// does not match as the error is returned to the reader
+		pw.CloseWithError(err)
+	}()
+	// unblock the patch if the package service stops reading early
+	defer pr.Close()
+	var envelope *PackageEnvelope
+	if upsert {
+		envelope, err = packages.UpsertPackage(loc, pr, options...)
+	} else {
+		envelope, err = packages.CreatePackage(loc, pr, options...)
+	}
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return envelope, nil
+}
+
+// FindDeltaBase returns the package to use as a base for the delta of the
+// specified package: the latest version of the same package older than loc
+func FindDeltaBase(packages PackageService, loc loc.Locator) (*loc.Locator, error) {
+	version, err := loc.SemVer()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	base, err := FindLatestPackageCustom(FindLatestPackageRequest{
+		Packages:   packages,
+		Repository: loc.Repository,
+		Match: func(e PackageEnvelope) bool {
+			if e.Locator.Name != loc.Name {
+				return false
+			}
+			other, err := e.Locator.SemVer()
+			return err == nil && other.LessThan(*version)
+		},
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return base, nil
+}
+
+func spoolToTempFile(r io.Reader) (*os.File, error) {
+	file, err := ioutil.TempFile("", "delta")
+	if err != nil {
+		return nil, trace.ConvertSystemError(err)
+	}
+	if _, err := io.Copy(file, r); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return nil, trace.ConvertSystemError(err)
+	}
+	return file, nil
+}
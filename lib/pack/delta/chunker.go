@@ -0,0 +1,113 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package delta
+
+import (
+	"io"
+
+	"github.com/gravitational/trace"
+)
+
+// Split splits the data read from r into content-defined chunks
+// and invokes fn for each chunk in order.
+// The chunk data is only valid for the duration of the call
+func Split(r io.Reader, fn func(chunk []byte) error) error {
+	chunker := newChunker(r)
+	for {
+		chunk, err := chunker.next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		if err := fn(chunk); err != nil {
+			return trace.Wrap(err)
+		}
+	}
+}
+
+// chunker splits a stream into content-defined chunks using a gear hash
+type chunker struct {
+	r   io.Reader
+	buf []byte
+	// start is the offset of the unconsumed data in buf
+	start int
+	// end is the offset of the end of the data in buf
+	end int
+	eof bool
+}
+
+func newChunker(r io.Reader) *chunker {
+	return &chunker{
+		r:   r,
+		buf: make([]byte, maxChunkSize),
+	}
+}
+
+// next returns the next chunk. The chunk is only valid until the next call
+func (c *chunker) next() ([]byte, error) {
+	if c.start > 0 {
+		c.end = copy(c.buf, c.buf[c.start:c.end])
+		c.start = 0
+	}
+	for c.end < len(c.buf) && !c.eof {
+		n, err := c.r.Read(c.buf[c.end:])
+		c.end += n
+		if err == io.EOF {
+			c.eof = true
+		} else if err != nil {
+			return nil, err
+		}
+	}
+	if c.end == 0 {
+		return nil, io.EOF
+	}
+	c.start = cutPoint(c.buf[:c.end])
+	return c.buf[:c.start], nil
+}
+
+// cutPoint returns the size of the chunk at the start of data
+func cutPoint(data []byte) int {
+	if len(data) <= minChunkSize {
+		return len(data)
+	}
+	var hash uint64
+	for i := minChunkSize; i < len(data); i++ {
+		hash = (hash << 1) + gear[data[i]]
+		if hash&chunkMask == 0 {
+			return i + 1
+		}
+	}
+	return len(data)
+}
+
+// gear is the table of random values for the gear hash.
+// It is generated deterministically so that all parties
+// split the same data into the same chunks
+var gear = func() (table [256]uint64) {
+	// splitmix64
+	seed := uint64(0x6772617669747921)
+	for i := range table {
+		seed += 0x9e3779b97f4a7c15
+		z := seed
+		z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+		z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+		table[i] = z ^ (z >> 31)
+	}
+	return table
+}()
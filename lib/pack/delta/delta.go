@@ -0,0 +1,301 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package delta implements binary deltas between package blobs.
+//
+// Blobs are split into content-defined chunks with a rolling hash, so that
+// an insertion or removal only affects the chunks around it. A signature
+// lists the chunks of a base blob. A delta against a signature describes
+// a target blob as a sequence of chunks copied from the base blob and
+// literal data for the chunks the base blob does not have.
+package delta
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"io"
+
+	"github.com/gravitational/trace"
+)
+
+const (
+	// minChunkSize is the minimum size of a chunk
+	minChunkSize = 16 * 1024
+	// maxChunkSize is the maximum size of a chunk
+	maxChunkSize = 256 * 1024
+	// chunkMask selects the bits of the rolling hash that must be zero at
+	// a chunk boundary. 16 bits yield chunks of about 64KiB on average
+	chunkMask = uint64(0xffff) << 48
+)
+
+// Signature lists the chunks of a base blob
+type Signature struct {
+	// Base identifies the base blob, e.g. the package locator
+	Base string `json:"base"`
+	// Size is the size of the base blob
+	Size int64 `json:"size"`
+	// Chunks lists the chunks of the base blob in order
+	Chunks []Chunk `json:"chunks"`
+}
+
+// Chunk describes a chunk of a base blob
+type Chunk struct {
+	// Hash is the SHA256 hash of the chunk data
+	Hash []byte `json:"hash"`
+	// Size is the size of the chunk
+	Size int64 `json:"size"`
+}
+
+// Stats describes a delta
+type Stats struct {
+	// Size is the size of the target blob
+	Size int64
+	// Copied is the number of target bytes copied from the base blob
+	Copied int64
+	// Literal is the number of target bytes included in the delta
+	Literal int64
+}
+
+// NewSignature computes the signature of the base blob read from r
+func NewSignature(base string, r io.Reader) (*Signature, error) {
+	sig := &Signature{Base: base}
+	err := Split(r, func(chunk []byte) error {
+		hash := sha256.Sum256(chunk)
+		sig.Chunks = append(sig.Chunks, Chunk{Hash: hash[:], Size: int64(len(chunk))})
+		sig.Size += int64(len(chunk))
+		return nil
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return sig, nil
+}
+
+// Diff writes the delta of the target blob read from r
+// against the base blob with the specified signature to w
+func Diff(base Signature, r io.Reader, w io.Writer) (*Stats, error) {
+	index := newIndex(base)
+	out := &writer{w: bufio.NewWriter(w)}
+	out.write([]byte(magic))
+	var stats Stats
+	var pending *copyOp
+	hasher := sha512.New()
+	chunker := newChunker(io.TeeReader(r, hasher))
+	for {
+		chunk, err := chunker.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		size := int64(len(chunk))
+		stats.Size += size
+		offset, ok := index[sha256.Sum256(chunk)]
+		if !ok {
+			if pending != nil {
+				out.copy(*pending)
+				pending = nil
+			}
+			out.literal(chunk)
+			stats.Literal += size
+			continue
+		}
+		stats.Copied += size
+		if pending != nil && pending.offset+pending.size == offset {
+			// coalesce copies of consecutive base chunks
+			pending.size += size
+			continue
+		}
+		if pending != nil {
+			out.copy(*pending)
+		}
+		pending = &copyOp{offset: offset, size: size}
+	}
+	if pending != nil {
+		out.copy(*pending)
+	}
+	out.end(stats.Size, hasher.Sum(nil)[:hashSize])
+	if err := out.flush(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &stats, nil
+}
+
+// Patch reconstructs the target blob from the base blob
+// and the delta read from r and writes it to w.
+// The reconstructed blob is verified against the size
+// and hash of the target blob recorded in the delta
+func Patch(base io.ReaderAt, r io.Reader, w io.Writer) (*Stats, error) {
+	in := bufio.NewReader(r)
+	header := make([]byte, len(magic))
+	if _, err := io.ReadFull(in, header); err != nil {
+		return nil, trace.Wrap(err, "failed to read delta header")
+	}
+	if string(header) != magic {
+		return nil, trace.BadParameter("not a package delta")
+	}
+	var stats Stats
+	hasher := sha512.New()
+	out := io.MultiWriter(w, hasher)
+	for {
+		op, err := in.ReadByte()
+		if err != nil {
+			return nil, trace.Wrap(unexpectedEOF(err))
+		}
+		switch op {
+		case opCopy:
+			offset, size, err := readPair(in)
+			if err != nil {
+				return nil, trace.Wrap(err)
+			}
+			n, err := io.Copy(out, io.NewSectionReader(base, int64(offset), int64(size)))
+			if err != nil {
+				return nil, trace.Wrap(err)
+			}
+			if n != int64(size) {
+				return nil, trace.BadParameter("delta refers past the end of the base blob")
+			}
+			stats.Copied += n
+		case opLiteral:
+			size, err := binary.ReadUvarint(in)
+			if err != nil {
+				return nil, trace.Wrap(unexpectedEOF(err))
+			}
+			if size > maxChunkSize {
+				return nil, trace.BadParameter("invalid literal size %v", size)
+			}
+			if _, err := io.CopyN(out, in, int64(size)); err != nil {
+				return nil, trace.Wrap(unexpectedEOF(err))
+			}
+			stats.Literal += int64(size)
+		case opEnd:
+			size, err := binary.ReadUvarint(in)
+			if err != nil {
+				return nil, trace.Wrap(unexpectedEOF(err))
+			}
+			hash := make([]byte, hashSize)
+			if _, err := io.ReadFull(in, hash); err != nil {
+				return nil, trace.Wrap(unexpectedEOF(err))
+			}
+			stats.Size = stats.Copied + stats.Literal
+			if stats.Size != int64(size) || !bytes.Equal(hash, hasher.Sum(nil)[:hashSize]) {
+				return nil, trace.BadParameter("reconstructed blob does not match the target blob, " +
+					"the base blob differs from the one the delta was computed against")
+			}
+			return &stats, nil
+		default:
+			return nil, trace.BadParameter("invalid delta operation %v", op)
+		}
+	}
+}
+
+const (
+	// magic identifies the delta format
+	magic = "GDELTA01"
+	// hashSize is the size of the target blob hash: SHA512
+	// truncated to its first half as used for package blobs
+	hashSize = sha512.Size / 2
+
+	opEnd     = byte(0)
+	opCopy    = byte(1)
+	opLiteral = byte(2)
+)
+
+type copyOp struct {
+	offset int64
+	size   int64
+}
+
+// newIndex maps the chunks of the base blob to their offsets
+func newIndex(sig Signature) map[[sha256.Size]byte]int64 {
+	index := make(map[[sha256.Size]byte]int64, len(sig.Chunks))
+	var offset int64
+	for _, chunk := range sig.Chunks {
+		var hash [sha256.Size]byte
+		copy(hash[:], chunk.Hash)
+		if _, ok := index[hash]; !ok {
+			index[hash] = offset
+		}
+		offset += chunk.Size
+	}
+	return index
+}
+
+// writer encodes delta operations and keeps the first write error
+type writer struct {
+	w   *bufio.Writer
+	buf [binary.MaxVarintLen64]byte
+	err error
+}
+
+func (w *writer) write(data []byte) {
+	if w.err == nil {
+		_, w.err = w.w.Write(data)
+	}
+}
+
+func (w *writer) uvarint(v uint64) {
+	n := binary.PutUvarint(w.buf[:], v)
+	w.write(w.buf[:n])
+}
+
+func (w *writer) copy(op copyOp) {
+	w.write([]byte{opCopy})
+	w.uvarint(uint64(op.offset))
+	w.uvarint(uint64(op.size))
+}
+
+func (w *writer) literal(data []byte) {
+	w.write([]byte{opLiteral})
+	w.uvarint(uint64(len(data)))
+	w.write(data)
+}
+
+func (w *writer) end(size int64, hash []byte) {
+	w.write([]byte{opEnd})
+	w.uvarint(uint64(size))
+	w.write(hash)
+}
+
+func (w *writer) flush() error {
+	if w.err != nil {
+		return w.err
+	}
+	return w.w.Flush()
+}
+
+func readPair(r io.ByteReader) (first, second uint64, err error) {
+	if first, err = binary.ReadUvarint(r); err != nil {
+		return 0, 0, unexpectedEOF(err)
+	}
+	if second, err = binary.ReadUvarint(r); err != nil {
+		return 0, 0, unexpectedEOF(err)
+	}
+	return first, second, nil
+}
+
+// unexpectedEOF converts the end of the delta before
+// the end operation into an error
+func unexpectedEOF(err error) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return trace.BadParameter("unexpected end of package delta")
+	}
+	return err
+}
@@ -0,0 +1,162 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package delta
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+	"testing"
+
+	"github.com/gravitational/trace"
+	. "gopkg.in/check.v1"
+)
+
+func TestDelta(t *testing.T) { TestingT(t) }
+
+type DeltaSuite struct{}
+
+var _ = Suite(&DeltaSuite{})
+
+func (s *DeltaSuite) TestRoundtrip(c *C) {
+	base := randomData(c, 1, 4*1024*1024)
+	var tcs = []struct {
+		comment string
+		target  []byte
+		// maxLiteral is the upper bound on the literal data in the delta
+		maxLiteral int64
+	}{
+		{
+			comment:    "identical data",
+			target:     base,
+			maxLiteral: 0,
+		},
+		{
+			comment:    "insertion",
+			target:     splice(base, 1024*1024, 0, []byte("inserted data")),
+			maxLiteral: 2 * maxChunkSize,
+		},
+		{
+			comment:    "removal",
+			target:     splice(base, 2*1024*1024, 4096, nil),
+			maxLiteral: 2 * maxChunkSize,
+		},
+		{
+			comment:    "appended data",
+			target:     append(append([]byte{}, base...), randomData(c, 2, 100*1024)...),
+			maxLiteral: 100*1024 + maxChunkSize,
+		},
+		{
+			comment:    "unrelated data",
+			target:     randomData(c, 3, 1024*1024),
+			maxLiteral: 1024 * 1024,
+		},
+		{
+			comment:    "empty target",
+			target:     nil,
+			maxLiteral: 0,
+		},
+	}
+	sig, err := NewSignature("base", bytes.NewReader(base))
+	c.Assert(err, IsNil)
+	c.Assert(sig.Size, Equals, int64(len(base)))
+	for _, tc := range tcs {
+		comment := Commentf(tc.comment)
+		var delta bytes.Buffer
+		stats, err := Diff(*sig, bytes.NewReader(tc.target), &delta)
+		c.Assert(err, IsNil, comment)
+		c.Assert(stats.Size, Equals, int64(len(tc.target)), comment)
+		c.Assert(stats.Literal <= tc.maxLiteral, Equals, true,
+			Commentf("%v: %v literal bytes", tc.comment, stats.Literal))
+
+		var target bytes.Buffer
+		patchStats, err := Patch(bytes.NewReader(base), &delta, &target)
+		c.Assert(err, IsNil, comment)
+		c.Assert(bytes.Equal(target.Bytes(), tc.target), Equals, true, comment)
+		c.Assert(*patchStats, DeepEquals, *stats, comment)
+	}
+}
+
+func (s *DeltaSuite) TestDetectsMismatchedBase(c *C) {
+	base := randomData(c, 1, 1024*1024)
+	target := splice(base, 512*1024, 0, []byte("inserted data"))
+	sig, err := NewSignature("base", bytes.NewReader(base))
+	c.Assert(err, IsNil)
+	var delta bytes.Buffer
+	_, err = Diff(*sig, bytes.NewReader(target), &delta)
+	c.Assert(err, IsNil)
+
+	other := append([]byte{}, base...)
+	other[0] ^= 0xff
+	_, err = Patch(bytes.NewReader(other), bytes.NewReader(delta.Bytes()), &bytes.Buffer{})
+	c.Assert(trace.IsBadParameter(err), Equals, true, Commentf("%v", err))
+
+	truncated := delta.Bytes()[:delta.Len()-1]
+	_, err = Patch(bytes.NewReader(base), bytes.NewReader(truncated), &bytes.Buffer{})
+	c.Assert(trace.IsBadParameter(err), Equals, true, Commentf("%v", err))
+
+	_, err = Patch(bytes.NewReader(base), bytes.NewReader([]byte("not a delta")), &bytes.Buffer{})
+	c.Assert(trace.IsBadParameter(err), Equals, true, Commentf("%v", err))
+}
+
+func (s *DeltaSuite) TestChunkBoundaries(c *C) {
+	data := randomData(c, 1, 2*1024*1024)
+	sig, err := NewSignature("data", bytes.NewReader(data))
+	c.Assert(err, IsNil)
+	c.Assert(len(sig.Chunks) > 1, Equals, true)
+	for i, chunk := range sig.Chunks {
+		c.Assert(chunk.Size <= maxChunkSize, Equals, true)
+		if i < len(sig.Chunks)-1 {
+			c.Assert(chunk.Size >= minChunkSize, Equals, true)
+		}
+	}
+	// boundaries do not depend on how the data is read
+	other, err := NewSignature("data", &oneByteReader{data: data})
+	c.Assert(err, IsNil)
+	c.Assert(other, DeepEquals, sig)
+}
+
+func randomData(c *C, seed int64, size int) []byte {
+	data := make([]byte, size)
+	_, err := rand.New(rand.NewSource(seed)).Read(data)
+	c.Assert(err, IsNil)
+	return data
+}
+
+// splice returns a copy of data with n bytes at offset replaced with insert
+func splice(data []byte, offset, n int, insert []byte) []byte {
+	var out []byte
+	out = append(out, data[:offset]...)
+	out = append(out, insert...)
+	return append(out, data[offset+n:]...)
+}
+
+type oneByteReader struct {
+	data []byte
+}
+
+func (r *oneByteReader) Read(p []byte) (int, error) {
+	if len(r.data) == 0 {
+		return 0, io.EOF
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+	p[0] = r.data[0]
+	r.data = r.data[1:]
+	return 1, nil
+}
@@ -17,6 +17,7 @@ limitations under the License.
 package localpack
 
 import (
+	"context"
 	"io"
 	"os"
 	"strings"
@@ -462,6 +463,16 @@ func (p *PackageServer) tryDeleteBlob(toDelete storage.Package) error {
 	return trace.Wrap(p.cfg.Objects.DeleteBLOB(toDelete.SHA512))
 }
 
+// PruneBLOBs removes the package data no longer referenced by any package
+// if the BLOB storage shares data between packages
+func (p *PackageServer) PruneBLOBs(ctx context.Context, dryRun bool) (*blob.PruneResult, error) {
+	pruner, ok := p.cfg.Objects.(blob.Pruner)
+	if !ok {
+		return nil, trace.NotImplemented("BLOB storage does not share data between packages")
+	}
+	return pruner.PruneBLOBs(ctx, dryRun)
+}
+
 func newEnvelope(loc loc.Locator, p *storage.Package) *pack.PackageEnvelope {
 	return &pack.PackageEnvelope{
 		Locator:       loc,
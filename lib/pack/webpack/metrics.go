@@ -0,0 +1,89 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webpack
+
+import (
+	"io"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	transferBytes = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "gravity_package_transfer_bytes_total",
+			Help: "Number of bytes of package data uploaded to and downloaded from the package service",
+		},
+		[]string{"direction"},
+	)
+	transferLatencies = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name: "gravity_package_transfer_duration_seconds",
+			Help: "Latency of package uploads and downloads",
+			// lowest bucket start of upper bound 0.01 sec (10 ms) with factor 2
+			// highest bucket start of 0.01 sec * 2^15 == 327.68 sec
+			Buckets: prometheus.ExponentialBuckets(0.01, 2, 16),
+		},
+		[]string{"direction", "result"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(transferBytes)
+	prometheus.MustRegister(transferLatencies)
+}
+
+const (
+	directionUpload   = "upload"
+	directionDownload = "download"
+)
+
+// observeTransfer records the latency of the package transfer
+// in the specified direction
+func observeTransfer(direction string, started time.Time, err error) {
+	result := "success"
+	if err != nil {
+		result = "failure"
+	}
+	transferLatencies.WithLabelValues(direction, result).Observe(time.Since(started).Seconds())
+}
+
+// countingReader counts the bytes read from the package data
+type countingReader struct {
+	io.Reader
+	direction string
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	transferBytes.WithLabelValues(r.direction).Add(float64(n))
+	return n, err
+}
+
+// countingReadSeeker counts the bytes read from the package data
+// served with support for range requests
+type countingReadSeeker struct {
+	io.ReadSeeker
+	direction string
+}
+
+func (r *countingReadSeeker) Read(p []byte) (int, error) {
+	n, err := r.ReadSeeker.Read(p)
+	transferBytes.WithLabelValues(r.direction).Add(float64(n))
+	return n, err
+}
@@ -17,15 +17,18 @@ limitations under the License.
 package webpack
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net/http"
 	"net/url"
 	"time"
 
 	"github.com/gravitational/gravity/lib/loc"
 	"github.com/gravitational/gravity/lib/pack"
+	"github.com/gravitational/gravity/lib/pack/delta"
 	"github.com/gravitational/gravity/lib/storage"
 
 	"github.com/gravitational/roundtrip"
@@ -138,7 +141,71 @@ func (c *Client) UpsertPackage(loc loc.Locator, data io.Reader, options ...pack.
 	return c.createOrUpsertPackage(loc, data, true, options...)
 }
 
+// CreatePackageFromDelta creates or updates (if upsert is true) the specified
+// package from the delta against the base package the server already has
+func (c *Client) CreatePackageFromDelta(loc loc.Locator, base loc.Locator, data io.Reader, upsert bool, options ...pack.PackageOption) (*pack.PackageEnvelope, error) {
+	return c.uploadPackage(c.Endpoint("repositories", loc.Repository, "deltas"),
+		loc, data, url.Values{"base": []string{base.String()}}, upsert, options...)
+}
+
+// ReadPackageSignature returns the signature of the specified package
+// to compute the delta of a newer version of the package against
+func (c *Client) ReadPackageSignature(loc loc.Locator) (*delta.Signature, error) {
+	out, err := c.Get(
+		c.Endpoint("repositories", loc.Repository,
+			"packages", loc.Name, loc.Version, "signature"), url.Values{})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var sig delta.Signature
+	if err := json.Unmarshal(out.Bytes(), &sig); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &sig, nil
+}
+
+// ReadPackageDelta returns the envelope of the specified package and
+// its delta against the base package with the provided signature
+func (c *Client) ReadPackageDelta(loc loc.Locator, base delta.Signature) (*pack.PackageEnvelope, io.ReadCloser, error) {
+	envelope, err := c.ReadPackageEnvelope(loc)
+	if err != nil {
+		return nil, nil, trace.Wrap(err)
+	}
+	data, err := json.Marshal(base)
+	if err != nil {
+		return nil, nil, trace.Wrap(err)
+	}
+	endpoint := c.Endpoint("repositories", loc.Repository, "packages", loc.Name, loc.Version, "delta")
+	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, trace.Wrap(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	c.SetAuthHeader(req.Header)
+	// the response is streamed as the delta can be large
+	resp, err := c.HTTPClient().Do(req)
+	if err != nil {
+		_, err = telehttplib.ConvertResponse(nil, err)
+		return nil, nil, trace.Wrap(err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		body, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return nil, nil, trace.Wrap(err)
+		}
+		return nil, nil, trace.ReadError(resp.StatusCode, body)
+	}
+	return envelope, resp.Body, nil
+}
+
 func (c *Client) createOrUpsertPackage(loc loc.Locator, data io.Reader, upsert bool, options ...pack.PackageOption) (*pack.PackageEnvelope, error) {
+	return c.uploadPackage(c.Endpoint("repositories", loc.Repository, "packages"),
+		loc, data, url.Values{}, upsert, options...)
+}
+
+// uploadPackage uploads the package data to the specified endpoint
+func (c *Client) uploadPackage(endpoint string, loc loc.Locator, data io.Reader, values url.Values, upsert bool, options ...pack.PackageOption) (*pack.PackageEnvelope, error) {
 	file := roundtrip.File{
 		Name:     "package",
 		Filename: loc.String(),
@@ -156,18 +223,16 @@ func (c *Client) createOrUpsertPackage(loc loc.Locator, data io.Reader, upsert b
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
-	values := url.Values{
-		"labels": []string{string(labelsJSON)},
-		"hidden": []string{fmt.Sprintf("%t", pkg.Hidden)},
-		"upsert": []string{fmt.Sprintf("%t", upsert)},
-	}
+	values.Set("labels", string(labelsJSON))
+	values.Set("hidden", fmt.Sprintf("%t", pkg.Hidden))
+	values.Set("upsert", fmt.Sprintf("%t", upsert))
 	if pkg.Type != "" {
 		values["type"] = []string{pkg.Type}
 	}
 	if len(pkg.Manifest) > 0 {
 		values["manifest"] = []string{string(pkg.Manifest)}
 	}
-	out, err := c.PostForm(c.Endpoint("repositories", loc.Repository, "packages"), values, file)
+	out, err := c.PostForm(endpoint, values, file)
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
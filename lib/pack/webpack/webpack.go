@@ -28,6 +28,7 @@ import (
 	"github.com/gravitational/gravity/lib/httplib"
 	"github.com/gravitational/gravity/lib/loc"
 	"github.com/gravitational/gravity/lib/pack"
+	"github.com/gravitational/gravity/lib/pack/delta"
 	"github.com/gravitational/gravity/lib/storage"
 	"github.com/gravitational/gravity/lib/users"
 
@@ -67,10 +68,13 @@ func NewHandler(cfg Config) (*Server, error) {
 	h.GET("/pack/v1/repositories", h.needsAuth(h.getRepositories))
 	h.GET("/pack/v1/repositories/:repository", h.needsAuth(h.getRepository))
 	h.POST("/pack/v1/repositories/:repository/packages", h.needsAuth(h.createPackage))
+	h.POST("/pack/v1/repositories/:repository/deltas", h.needsAuth(h.createPackageFromDelta))
 	h.GET("/pack/v1/repositories/:repository/packages", h.needsAuth(h.getPackages))
 	h.GET("/pack/v1/repositories/:repository/packages/:package_name/:package_version/file", h.needsAuth(h.getPackageFile))
 	h.HEAD("/pack/v1/repositories/:repository/packages/:package_name/:package_version/file", h.needsAuth(h.getPackageFile))
 	h.GET("/pack/v1/repositories/:repository/packages/:package_name/:package_version/envelope", h.needsAuth(h.getPackageEnvelope))
+	h.GET("/pack/v1/repositories/:repository/packages/:package_name/:package_version/signature", h.needsAuth(h.getPackageSignature))
+	h.POST("/pack/v1/repositories/:repository/packages/:package_name/:package_version/delta", h.needsAuth(h.getPackageDelta))
 	h.POST("/pack/v1/repositories/:repository/packages/:package_name/:package_version", h.needsAuth(h.updatePackageLabels))
 	h.DELETE("/pack/v1/repositories/:repository/packages/:package_name/:package_version", h.needsAuth(h.deletePackage))
 
@@ -163,7 +167,11 @@ func (s *Server) getPackageEnvelope(w http.ResponseWriter, r *http.Request, p ht
 	return nil
 }
 
-func (s *Server) getPackageFile(w http.ResponseWriter, r *http.Request, p httprouter.Params, service pack.PackageService) error {
+func (s *Server) getPackageFile(w http.ResponseWriter, r *http.Request, p httprouter.Params, service pack.PackageService) (err error) {
+	started := time.Now()
+	defer func() {
+		observeTransfer(directionDownload, started, err)
+	}()
 	loc, err := loc.NewLocator(p.ByName("repository"), p.ByName("package_name"), p.ByName("package_version"))
 	if err != nil {
 		return trace.BadParameter(err.Error())
@@ -180,30 +188,104 @@ func (s *Server) getPackageFile(w http.ResponseWriter, r *http.Request, p httpro
 		return trace.BadParameter("expected read seeker object")
 	}
 	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename=%v`, loc.String()))
-	http.ServeContent(w, r, loc.String(), time.Now(), readSeeker)
+	http.ServeContent(w, r, loc.String(), time.Now(), &countingReadSeeker{
+		ReadSeeker: readSeeker,
+		direction:  directionDownload,
+	})
+	return nil
+}
+
+// getPackageSignature returns the signature of the package
+// the client computes the delta of the package to upload against
+func (s *Server) getPackageSignature(w http.ResponseWriter, r *http.Request, p httprouter.Params, service pack.PackageService) error {
+	loc, err := loc.NewLocator(p.ByName("repository"), p.ByName("package_name"), p.ByName("package_version"))
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	sig, err := pack.ReadPackageSignature(service, *loc)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	roundtrip.ReplyJSON(w, http.StatusOK, sig)
+	return nil
+}
+
+// getPackageDelta streams the delta of the package against
+// the base package with the signature provided by the client
+func (s *Server) getPackageDelta(w http.ResponseWriter, r *http.Request, p httprouter.Params, service pack.PackageService) (err error) {
+	started := time.Now()
+	defer func() {
+		observeTransfer(directionDownload, started, err)
+	}()
+	loc, err := loc.NewLocator(p.ByName("repository"), p.ByName("package_name"), p.ByName("package_version"))
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	var sig delta.Signature
+	if err := json.NewDecoder(r.Body).Decode(&sig); err != nil {
+		return trace.BadParameter("invalid signature: %v", err)
+	}
+	_, reader, err := pack.ReadPackageDelta(service, *loc, sig)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	defer reader.Close()
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.WriteHeader(http.StatusOK)
+	// the client fails to apply a truncated delta
+	_, err = io.Copy(w, &countingReader{Reader: reader, direction: directionDownload})
+	if err != nil {
+		log.Warnf("Failed to send delta of %v: %v.", loc, trace.DebugReport(err))
+	}
 	return nil
 }
 
 func (s *Server) createPackage(w http.ResponseWriter, r *http.Request, p httprouter.Params, service pack.PackageService) error {
+	return s.receivePackage(w, r, service, false)
+}
+
+// createPackageFromDelta creates the package from the delta
+// against the base package uploaded by the client
+func (s *Server) createPackageFromDelta(w http.ResponseWriter, r *http.Request, p httprouter.Params, service pack.PackageService) error {
+	return s.receivePackage(w, r, service, true)
+}
+
+// receivePackage creates the package uploaded by the client.
+// If fromDelta is true, the uploaded data is the delta against the base package
+func (s *Server) receivePackage(w http.ResponseWriter, r *http.Request, service pack.PackageService, fromDelta bool) (err error) {
+	started := time.Now()
+	defer func() {
+		observeTransfer(directionUpload, started, err)
+	}()
 	var files form.Files
 	var labelsMap string
 	var upsertS string
 	var hiddenS string
 	var packageType string
 	var manifest string
+	var baseS string
 
-	err := form.Parse(r,
+	err = form.Parse(r,
 		form.FileSlice("package", &files),
 		form.String("labels", &labelsMap),
 		form.String("upsert", &upsertS),
 		form.String("hidden", &hiddenS),
 		form.String("type", &packageType),
 		form.String("manifest", &manifest),
+		form.String("base", &baseS),
 	)
 	if err != nil {
 		return trace.Wrap(err)
 	}
 
+	var base *loc.Locator
+	if fromDelta {
+		base, err = loc.ParseLocator(baseS)
+		if err != nil {
+			return trace.BadParameter("invalid base package %q: %v", baseS, err)
+		}
+	}
+
 	if len(files) != 1 {
 		return trace.BadParameter("expected a single file parameter but got %d", len(files))
 	}
@@ -247,11 +329,14 @@ func (s *Server) createPackage(w http.ResponseWriter, r *http.Request, p httprou
 		opts = append(opts, pack.WithManifest(packageType, []byte(manifest)))
 	}
 
+	data := &countingReader{Reader: files[0], direction: directionUpload}
 	var envelope *pack.PackageEnvelope
-	if upsert {
-		envelope, err = service.UpsertPackage(*loc, files[0], opts...)
+	if fromDelta {
+		envelope, err = pack.CreatePackageFromDelta(service, *loc, *base, data, upsert, opts...)
+	} else if upsert {
+		envelope, err = service.UpsertPackage(*loc, data, opts...)
 	} else {
-		envelope, err = service.CreatePackage(*loc, files[0], opts...)
+		envelope, err = service.CreatePackage(*loc, data, opts...)
 	}
 
 	if err != nil {
@@ -19,6 +19,8 @@ package webpack
 import (
 	"bytes"
 	"crypto/tls"
+	"io/ioutil"
+	"math/rand"
 	"net/http"
 	"net/http/httptest"
 	"os"
@@ -30,6 +32,7 @@ import (
 	"github.com/gravitational/gravity/lib/blob/fs"
 	"github.com/gravitational/gravity/lib/defaults"
 	"github.com/gravitational/gravity/lib/loc"
+	"github.com/gravitational/gravity/lib/pack"
 	"github.com/gravitational/gravity/lib/pack/localpack"
 	"github.com/gravitational/gravity/lib/pack/suite"
 	"github.com/gravitational/gravity/lib/storage"
@@ -293,3 +296,79 @@ func (s *WebpackSuite) TestUpsertPackages(c *C) {
 func (s *WebpackSuite) TestDeleteRepository(c *C) {
 	s.suite.DeleteRepository(c)
 }
+
+func (s *WebpackSuite) TestPackageDeltas(c *C) {
+	client := s.suite.S.(*Client)
+	local := s.newLocalPackages(c)
+	base := loc.MustParseLocator("example.com/package:0.0.1")
+	update := loc.MustParseLocator("example.com/package:0.0.2")
+	baseData := randomData(1024 * 1024)
+	updateData := append(append([]byte{}, baseData[:512*1024]...), "update"...)
+	updateData = append(updateData, baseData[512*1024:]...)
+	for _, packages := range []pack.PackageService{client, local} {
+		c.Assert(packages.UpsertRepository("example.com", time.Time{}), IsNil)
+		_, err := packages.CreatePackage(base, bytes.NewReader(baseData))
+		c.Assert(err, IsNil)
+	}
+
+	// upload the update as delta against the signature from the server
+	sig, err := client.ReadPackageSignature(base)
+	c.Assert(err, IsNil)
+	_, err = local.CreatePackage(update, bytes.NewReader(updateData))
+	c.Assert(err, IsNil)
+	_, reader, err := pack.ReadPackageDelta(local, update, *sig)
+	c.Assert(err, IsNil)
+	env, err := client.CreatePackageFromDelta(update, base, reader, false,
+		pack.WithLabels(map[string]string{"purpose": "test"}))
+	reader.Close()
+	c.Assert(err, IsNil)
+	c.Assert(env.SizeBytes, Equals, int64(len(updateData)))
+	c.Assert(env.RuntimeLabels, DeepEquals, map[string]string{"purpose": "test"})
+	assertPackageData(c, client, update, updateData)
+
+	// download the update as delta against the local base package
+	c.Assert(local.DeletePackage(update), IsNil)
+	sig, err = pack.ReadPackageSignature(local, base)
+	c.Assert(err, IsNil)
+	env, reader, err = client.ReadPackageDelta(update, *sig)
+	c.Assert(err, IsNil)
+	c.Assert(env.Locator, Equals, update)
+	_, err = pack.CreatePackageFromDelta(local, update, base, reader, false)
+	reader.Close()
+	c.Assert(err, IsNil)
+	assertPackageData(c, local, update, updateData)
+
+	_, err = client.ReadPackageSignature(loc.MustParseLocator("example.com/missing:0.0.1"))
+	c.Assert(trace.IsNotFound(err), Equals, true, Commentf("%v", err))
+}
+
+func (s *WebpackSuite) newLocalPackages(c *C) pack.PackageService {
+	dir := c.MkDir()
+	backend, err := keyval.NewBolt(keyval.BoltConfig{Path: filepath.Join(dir, "bolt.db")})
+	c.Assert(err, IsNil)
+	objects, err := fs.New(dir)
+	c.Assert(err, IsNil)
+	packages, err := localpack.New(localpack.Config{
+		Backend:     backend,
+		UnpackedDir: filepath.Join(dir, defaults.UnpackedDir),
+		Clock:       s.clock,
+		Objects:     objects,
+	})
+	c.Assert(err, IsNil)
+	return packages
+}
+
+func assertPackageData(c *C, packages pack.PackageService, loc loc.Locator, expected []byte) {
+	_, reader, err := packages.ReadPackage(loc)
+	c.Assert(err, IsNil)
+	defer reader.Close()
+	data, err := ioutil.ReadAll(reader)
+	c.Assert(err, IsNil)
+	c.Assert(bytes.Equal(data, expected), Equals, true)
+}
+
+func randomData(size int) []byte {
+	data := make([]byte, size)
+	rand.New(rand.NewSource(1)).Read(data)
+	return data
+}
@@ -0,0 +1,46 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package process
+
+import (
+	"github.com/gravitational/gravity/lib/audit"
+	"github.com/gravitational/gravity/lib/constants"
+	"github.com/gravitational/gravity/lib/ops/opsservice"
+
+	"github.com/gravitational/trace"
+)
+
+// newAuditLog returns the audit log that records changes made through
+// the cluster APIs. Changes are not audited in the installer mode
+func (p *Process) newAuditLog(forwarders opsservice.LogForwardersControl) (audit.Emitter, error) {
+	if p.mode == constants.ComponentInstaller {
+		return nil, nil
+	}
+	config := audit.Config{
+		Backend:   p.backend,
+		Retention: p.cfg.Audit.Retention,
+		Hostname:  p.cfg.Hostname,
+	}
+	if p.cfg.Audit.Forward && forwarders != nil {
+		config.Forwarders = forwarders.Get
+	}
+	auditLog, err := audit.New(config)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return auditLog, nil
+}
@@ -0,0 +1,112 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package process
+
+import (
+	"context"
+	"path/filepath"
+
+	"github.com/gravitational/gravity/lib/blob"
+	blobchunked "github.com/gravitational/gravity/lib/blob/chunked"
+	blobs3 "github.com/gravitational/gravity/lib/blob/s3"
+	"github.com/gravitational/gravity/lib/constants"
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/processconfig"
+
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/gravitational/rigging"
+	"github.com/gravitational/trace"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// MigrateBLOBs copies the packages stored in the specified data directory
+// to the S3 bucket of the given package storage configuration
+func MigrateBLOBs(ctx context.Context, client kubernetes.Interface, dataDir string, config processconfig.BlobConfig) (*blobs3.MigrateResult, error) {
+	if err := config.CheckAndSetDefaults(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if config.Backend != blob.BackendS3 {
+		return nil, trace.BadParameter("package storage backend is %q, set it to %q to migrate packages",
+			config.Backend, blob.BackendS3)
+	}
+	local, err := blobchunked.Open(filepath.Join(dataDir, defaults.PackagesDir))
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	defer local.Close()
+	bucket, err := newS3Objects(client, dataDir, config.S3)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	defer bucket.Close()
+	return blobs3.Migrate(ctx, local, bucket)
+}
+
+// newS3Objects returns the package storage in the configured S3 bucket.
+// The client is only used to read the credentials secret
+func newS3Objects(client kubernetes.Interface, dataDir string, config processconfig.S3BlobConfig) (blob.Objects, error) {
+	creds, err := getS3Credentials(client, config.CredentialsSecret)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return blobs3.New(blobs3.Config{
+		Bucket:               config.Bucket,
+		Prefix:               config.Prefix,
+		Region:               config.Region,
+		Endpoint:             config.Endpoint,
+		Credentials:          creds,
+		ServerSideEncryption: config.ServerSideEncryption,
+		KMSKeyID:             config.KMSKeyID,
+		TempDir:              filepath.Join(dataDir, defaults.PackagesDir, "tmp"),
+	})
+}
+
+// getS3Credentials returns the S3 credentials from the specified secret
+// or nil to use the default AWS credentials chain if no secret is specified
+func getS3Credentials(client kubernetes.Interface, secretName string) (*credentials.Credentials, error) {
+	if secretName == "" {
+		return nil, nil
+	}
+	secret, err := client.CoreV1().Secrets(defaults.KubeSystemNamespace).Get(secretName, metav1.GetOptions{})
+	if err != nil {
+		return nil, trace.Wrap(rigging.ConvertError(err))
+	}
+	accessKeyID := string(secret.Data[constants.S3AccessKeyIDKey])
+	secretAccessKey := string(secret.Data[constants.S3SecretAccessKeyKey])
+	if accessKeyID == "" || secretAccessKey == "" {
+		return nil, trace.BadParameter("secret %v must have both %v and %v keys",
+			secretName, constants.S3AccessKeyIDKey, constants.S3SecretAccessKeyKey)
+	}
+	return credentials.NewStaticCredentials(accessKeyID, secretAccessKey, ""), nil
+}
+
+// checkBLOBsMigrated refuses to switch to the bucket storage
+// while packages stored on this node are missing from the bucket
+// as they would become unavailable
+func checkBLOBsMigrated(local, bucket blob.Objects) error {
+	missing, err := blobs3.Missing(local, bucket)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if len(missing) != 0 {
+		return trace.BadParameter("%v packages stored on this node are missing from the S3 bucket, "+
+			"copy them with 'gravity system blob migrate' or switch the package storage back to %q",
+			len(missing), blob.BackendFS)
+	}
+	return nil
+}
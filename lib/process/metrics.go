@@ -0,0 +1,70 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package process
+
+import (
+	"github.com/gravitational/gravity/lib/ops/opsservice"
+
+	"github.com/gravitational/trace"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	leaderChanges = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "gravity_leader_changes_total",
+			Help: "Number of times the active gravity-site leader has changed",
+		},
+	)
+	isLeader = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "gravity_is_leader",
+			Help: "Whether this gravity-site process is the active leader (1) or not (0)",
+		},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(leaderChanges)
+	prometheus.MustRegister(isLeader)
+}
+
+// observeLeader updates leader election metrics after the leader
+// has changed from oldLeaderID to leaderID
+func (p *Process) observeLeader(oldLeaderID, leaderID string) {
+	if oldLeaderID != "" && oldLeaderID != leaderID {
+		leaderChanges.Inc()
+	}
+	if leaderID == p.id {
+		isLeader.Set(1)
+	} else {
+		isLeader.Set(0)
+	}
+}
+
+// registerMetrics registers the collectors that compute metrics from
+// the cluster state when scraped
+func (p *Process) registerMetrics() error {
+	err := prometheus.Register(opsservice.NewMetricsCollector(p.backend))
+	if err != nil {
+		if _, ok := err.(prometheus.AlreadyRegisteredError); ok {
+			return nil
+		}
+		return trace.Wrap(err)
+	}
+	return nil
+}
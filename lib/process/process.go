@@ -35,8 +35,14 @@ import (
 	"github.com/gravitational/gravity/lib/app"
 	apphandler "github.com/gravitational/gravity/lib/app/handler"
 	appservice "github.com/gravitational/gravity/lib/app/service"
+	"github.com/gravitational/gravity/lib/autorepair"
+	"github.com/gravitational/gravity/lib/autoscale"
 	"github.com/gravitational/gravity/lib/autoscale/aws"
+	"github.com/gravitational/gravity/lib/autoscale/gce"
+	"github.com/gravitational/gravity/lib/autoscale/webhook"
+	"github.com/gravitational/gravity/lib/backup"
 	"github.com/gravitational/gravity/lib/blob"
+	blobchunked "github.com/gravitational/gravity/lib/blob/chunked"
 	blobclient "github.com/gravitational/gravity/lib/blob/client"
 	blobcluster "github.com/gravitational/gravity/lib/blob/cluster"
 	blobfs "github.com/gravitational/gravity/lib/blob/fs"
@@ -90,6 +96,7 @@ import (
 	"github.com/gravitational/teleport"
 	"github.com/gravitational/trace"
 	"github.com/julienschmidt/httprouter"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/sirupsen/logrus"
 	"k8s.io/apimachinery/pkg/version"
 	"k8s.io/client-go/kubernetes"
@@ -157,6 +164,8 @@ type Handlers struct {
 	BLOB *blobhandler.Server
 	// Registry is the Docker registry handler.
 	Registry http.Handler
+	// Autoscale is the webhook autoscaler handler, set only if enabled
+	Autoscale *webhook.Autoscaler
 }
 
 // rpcCredentials holds generated RPC agents credentials
@@ -209,7 +218,14 @@ func New(ctx context.Context, cfg processconfig.Config, tcfg telecfg.FileConfig)
 		return nil, trace.Wrap(err)
 	}
 
-	objects, err := blobfs.New(filepath.Join(cfg.DataDir, defaults.PackagesDir))
+	packagesDir := filepath.Join(cfg.DataDir, defaults.PackagesDir)
+	var objects blob.Objects
+	if cfg.Blob.Dedup {
+		objects, err = blobchunked.New(blobchunked.Config{Path: packagesDir})
+	} else {
+		// keep serving the chunks if deduplication has been turned off
+		objects, err = blobchunked.Open(packagesDir)
+	}
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
@@ -232,14 +248,32 @@ func New(ctx context.Context, cfg processconfig.Config, tcfg telecfg.FileConfig)
 		return nil, trace.Wrap(err)
 	}
 
-	clusterObjects, err := blobcluster.New(blobcluster.Config{
-		Local:         objects,
-		Backend:       backend,
-		GetPeer:       peerPool.GetPeer,
-		ID:            processID,
-		AdvertiseAddr: fmt.Sprintf("https://%v", peerAddr.Addr),
-		// TODO: set WriteFactor to the number of controller instances
-	})
+	var clusterObjects blob.Objects
+	switch cfg.Blob.Backend {
+	case blob.BackendS3:
+		// the bucket is shared by all controller instances
+		// so the objects need not be replicated between peers
+		var client *kubernetes.Clientset
+		if cfg.Blob.S3.CredentialsSecret != "" {
+			client, err = tryGetPrivilegedKubeClient()
+			if err != nil {
+				return nil, trace.Wrap(err)
+			}
+		}
+		clusterObjects, err = newS3Objects(client, cfg.DataDir, cfg.Blob.S3)
+		if err == nil {
+			err = checkBLOBsMigrated(objects, clusterObjects)
+		}
+	default:
+		clusterObjects, err = blobcluster.New(blobcluster.Config{
+			Local:         objects,
+			Backend:       backend,
+			GetPeer:       peerPool.GetPeer,
+			ID:            processID,
+			AdvertiseAddr: fmt.Sprintf("https://%v", peerAddr.Addr),
+			// TODO: set WriteFactor to the number of controller instances
+		})
+	}
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
@@ -475,39 +509,80 @@ func (p *Process) leaderStatus() (string, bool) {
 	return p.leaderID, p.leaderID == p.id
 }
 
-func (p *Process) startAutoscale(ctx context.Context) error {
-	_, err := cloudaws.NewLocalInstance()
-	if err != nil {
-		p.Info("Not on AWS, skip autoscaler start.")
-		return nil
+// startAutoRepair returns a cluster service that repairs failed nodes
+func (p *Process) startAutoRepair(client *kubernetes.Clientset) func(context.Context) error {
+	return func(ctx context.Context) error {
+		controller, err := autorepair.New(autorepair.Config{
+			Operator:    p.operator,
+			Client:      client,
+			GracePeriod: p.cfg.AutoRepair.GracePeriod,
+			MaxNodes:    p.cfg.AutoRepair.MaxNodes,
+			Provisioner: p.cfg.AutoRepair.Provisioner,
+		})
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		return trace.Wrap(controller.Run(ctx))
 	}
-	p.Info("Starting AWS autoscaler.")
-	site, err := p.operator.GetLocalSite()
-	if err != nil {
-		return trace.Wrap(err)
+}
+
+// initAutoscale creates the cluster autoscaler for the configured provider.
+// Returns nil autoscaler if autoscaling is not enabled
+func (p *Process) initAutoscale(client *kubernetes.Clientset) (autoscale.Autoscaler, error) {
+	provider := p.cfg.Autoscale.Provider
+	if provider == "" {
+		if _, err := cloudaws.NewLocalInstance(); err != nil {
+			p.Info("Not on AWS, skip autoscaler start.")
+			return nil, nil
+		}
+		provider = autoscale.ProviderAWS
 	}
-	client, err := tryGetPrivilegedKubeClient()
+	site, err := p.operator.GetLocalSite()
 	if err != nil {
-		return trace.Wrap(err)
+		return nil, trace.Wrap(err)
 	}
-	autoscaler, err := aws.New(aws.Config{
-		ClusterName: site.Domain,
-		Client:      client,
-	})
-	if err != nil {
-		return trace.Wrap(err)
+	p.Infof("Starting %v autoscaler.", provider)
+	switch provider {
+	case autoscale.ProviderAWS:
+		autoscaler, err := aws.New(aws.Config{
+			ClusterName: site.Domain,
+			Client:      client,
+		})
+		return autoscaler, trace.Wrap(err)
+	case autoscale.ProviderGCE:
+		autoscaler, err := gce.New(gce.Config{
+			ClusterName:   site.Domain,
+			Project:       p.cfg.Autoscale.GCE.Project,
+			Zone:          p.cfg.Autoscale.GCE.Zone,
+			InstanceGroup: p.cfg.Autoscale.GCE.InstanceGroup,
+			Client:        client,
+		})
+		return autoscaler, trace.Wrap(err)
+	case autoscale.ProviderWebhook:
+		autoscaler, err := webhook.New(webhook.Config{
+			Users:  p.identity,
+			Client: client,
+		})
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		p.handlers.Autoscale = autoscaler
+		return autoscaler, nil
 	}
-	queueURL, err := autoscaler.GetQueueURL(ctx)
-	if err != nil {
-		p.Warningf("Failed to get Autoscale Queue URL: %v. Cluster will continue without autoscaling support. Fix the problem and restart the process.", trace.DebugReport(err))
+	return nil, trace.BadParameter("unsupported autoscale provider %q", provider)
+}
+
+// startAutoscale returns a cluster service that runs the autoscaler
+func (p *Process) startAutoscale(autoscaler autoscale.Autoscaler) func(context.Context) error {
+	return func(ctx context.Context) error {
+		go func() {
+			err := autoscaler.Run(ctx, p.operator)
+			if err != nil && trace.Unwrap(err) != context.Canceled {
+				p.Warningf("Autoscaler failed: %v. Cluster will continue without autoscaling support. Fix the problem and restart the process.", trace.DebugReport(err))
+			}
+		}()
 		return nil
 	}
-
-	// receive and process events from SQS notification service
-	go autoscaler.ProcessEvents(ctx, queueURL, p.operator)
-	// publish discovery information about this cluster
-	go autoscaler.PublishDiscovery(ctx, p.operator)
-	return nil
 }
 
 // startApplicationsSynchronizer starts a service that periodically exports
@@ -616,6 +691,63 @@ func (p *Process) startSiteStatusChecker(ctx context.Context) error {
 	}
 }
 
+// startBackupScheduler runs the scheduled cluster backups
+func (p *Process) startBackupScheduler(ctx context.Context) error {
+	scheduler, err := backup.NewScheduler(backup.Config{
+		Backend:     p.backend,
+		Operator:    p.operator,
+		Apps:        p.applications,
+		AdvertiseIP: os.Getenv(constants.EnvPodIP),
+	})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return trace.Wrap(scheduler.Run(ctx))
+}
+
+// startAlertRelay returns a cluster service that configures Kapacitor
+// to relay alerts to the webhook, Slack and PagerDuty alert targets
+func (p *Process) startAlertRelay(tokens monitoring.NotifyTokens) func(context.Context) error {
+	return func(ctx context.Context) error {
+		cluster, err := p.operator.GetLocalSite()
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		relay, err := monitoring.NewRelay(monitoring.RelayConfig{
+			NotifyURL: fmt.Sprintf("%v/portal/v1/accounts/%v/sites/%v/monitoring/alert-targets/notify",
+				defaults.GravityServiceURL, cluster.AccountID, cluster.Domain),
+			Tokens:      tokens,
+			FieldLogger: p.WithField(trace.Component, "alert-relay"),
+		})
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		return trace.Wrap(relay.Run(ctx))
+	}
+}
+
+// startBLOBConversion converts the packages stored as whole files
+// into deduplicated chunks in background
+func (p *Process) startBLOBConversion(ctx context.Context) {
+	p.RegisterFunc("gravity.blobs.dedup", func() error {
+		p.Info("Converting package BLOBs into chunks.")
+		result, err := blobchunked.Convert(ctx, blobchunked.Config{
+			Path:        filepath.Join(p.cfg.DataDir, defaults.PackagesDir),
+			FieldLogger: p.WithField(trace.Component, "blob:chunked"),
+		})
+		if err != nil {
+			p.Warnf("Failed to convert package BLOBs into chunks: %v.", trace.DebugReport(err))
+			return nil
+		}
+		p.WithFields(logrus.Fields{
+			"converted":    result.Converted,
+			"size-bytes":   result.Usage.SizeBytes,
+			"stored-bytes": result.Usage.StoredBytes,
+		}).Info("Converted package BLOBs into chunks.")
+		return nil
+	})
+}
+
 // startElection starts leader election process and watches the changes
 func (p *Process) startElection() error {
 	// elect gravity site leader - all other sites will remain
@@ -633,6 +765,7 @@ func (p *Process) startElection() error {
 		p.Infof("Start watching gravity leaders.")
 		for leaderID := range gravityLeadersC {
 			oldLeaderID := p.setLeader(leaderID)
+			p.observeLeader(oldLeaderID, leaderID)
 			p.onSiteLeader(oldLeaderID)
 		}
 		return nil
@@ -1178,6 +1311,8 @@ func (p *Process) initService(ctx context.Context) (err error) {
 	}
 
 	// start operator service and HTTP API
+	// only the replicated package storage supports integrity checks
+	checker, _ := p.clusterObjects.(blob.Checker)
 	operator, err := opsservice.New(opsservice.Config{
 		Devmode:         p.cfg.Devmode,
 		StateDir:        p.cfg.DataDir,
@@ -1199,6 +1334,7 @@ func (p *Process) initService(ctx context.Context) (err error) {
 		ProcessID:       p.id,
 		InstallLogFiles: p.cfg.InstallLogFiles,
 		LogForwarders:   logs,
+		BLOBs:           checker,
 	})
 	if err != nil {
 		return trace.Wrap(err)
@@ -1225,6 +1361,16 @@ func (p *Process) initService(ctx context.Context) (err error) {
 		p.operator = operator
 	}
 
+	auditLog, err := p.newAuditLog(logs)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	var alertTokens monitoring.NotifyTokens
+	if p.inKubernetes() {
+		alertTokens = monitoring.NewNotifyTokens(client.Core().Secrets(defaults.KubeSystemNamespace))
+	}
+
 	p.handlers.Operator, err = opshandler.NewWebHandler(opshandler.WebHandlerConfig{
 		Users:               p.identity,
 		Operator:            p.operator,
@@ -1233,6 +1379,8 @@ func (p *Process) initService(ctx context.Context) (err error) {
 		Authenticator:       p.handlers.WebProxy.GetHandler().AuthenticateRequest,
 		Backend:             p.backend,
 		PublicAdvertiseAddr: p.cfg.Pack.GetPublicAddr(),
+		AuditLog:            auditLog,
+		AlertNotifyTokens:   alertTokens,
 	})
 	if err != nil {
 		return trace.Wrap(err)
@@ -1241,6 +1389,11 @@ func (p *Process) initService(ctx context.Context) (err error) {
 	// site status checker executes status hook periodically
 	p.RegisterClusterService(p.startSiteStatusChecker)
 
+	// each process converts its own copy of the package BLOBs
+	if p.cfg.Blob.Dedup {
+		p.startBLOBConversion(p.context)
+	}
+
 	// a few services that are running only when gravity is started in
 	// local site mode
 	if p.inKubernetes() {
@@ -1249,6 +1402,23 @@ func (p *Process) initService(ctx context.Context) (err error) {
 				"cluster requires backend with election capability")
 		}
 
+		// backup scheduler runs scheduled cluster backups
+		p.RegisterClusterService(p.startBackupScheduler)
+
+		// alert relay makes Kapacitor deliver alerts to the alert targets
+		p.RegisterClusterService(p.startAlertRelay(alertTokens))
+
+		if p.cfg.AutoRepair.Enabled {
+			p.RegisterClusterService(p.startAutoRepair(client))
+		}
+
+		autoscaler, err := p.initAutoscale(client)
+		if err != nil {
+			p.Warningf("Failed to initialize autoscaler: %v. Cluster will continue without autoscaling support. Fix the problem and restart the process.", trace.DebugReport(err))
+		} else if autoscaler != nil {
+			p.RegisterClusterService(p.startAutoscale(autoscaler))
+		}
+
 		p.Info("Running inside Kubernetes: starting leader election.")
 		// gravity site leader election
 		if err := p.startElection(); err != nil {
@@ -1279,10 +1449,6 @@ func (p *Process) initService(ctx context.Context) (err error) {
 			return trace.Wrap(err)
 		}
 
-		if err := p.startAutoscale(p.context); err != nil {
-			return trace.Wrap(err)
-		}
-
 	} else {
 		p.Debug("Not running inside Kubernetes.")
 	}
@@ -1366,6 +1532,7 @@ func (p *Process) initService(ctx context.Context) (err error) {
 		Mode:             p.mode,
 		ProxyHost:        sshProxyHost,
 		ServiceUser:      *p.cfg.ServiceUser,
+		AuditLog:         auditLog,
 	})
 
 	if err != nil {
@@ -1426,6 +1593,10 @@ func (p *Process) ServeHealth() error {
 	healthMux := &httprouter.Router{}
 	healthMux.HandlerFunc("GET", "/readyz", p.ReportReadiness)
 	healthMux.HandlerFunc("GET", "/healthz", p.ReportHealth)
+	if err := p.registerMetrics(); err != nil {
+		return trace.Wrap(err)
+	}
+	healthMux.Handler("GET", "/metrics", prometheus.Handler())
 	p.RegisterFunc("gravity.healthz", func() error {
 		p.Infof("Start healthcheck server on %v.", p.cfg.HealthAddr)
 		return trace.Wrap(http.ListenAndServe(p.cfg.HealthAddr.Addr, healthMux))
@@ -1504,6 +1675,9 @@ func (p *Process) initMux(ctx context.Context) error {
 		mux.Handler(method, "/charts/*rest", p.handlers.Apps)
 		mux.Handler(method, "/objects/*rest", p.handlers.BLOB)
 		mux.Handler(method, "/v2/*rest", p.handlers.Registry)
+		if p.handlers.Autoscale != nil {
+			mux.Handler(method, "/autoscale/*rest", p.handlers.Autoscale)
+		}
 		mux.HandlerFunc(method, "/readyz", p.ReportReadiness)
 		mux.HandlerFunc(method, "/healthz", p.ReportHealth)
 	}
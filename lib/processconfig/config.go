@@ -23,13 +23,17 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
+	"github.com/gravitational/gravity/lib/autoscale"
+	"github.com/gravitational/gravity/lib/blob"
 	"github.com/gravitational/gravity/lib/constants"
 	"github.com/gravitational/gravity/lib/defaults"
 	"github.com/gravitational/gravity/lib/helm"
 	"github.com/gravitational/gravity/lib/loc"
 	"github.com/gravitational/gravity/lib/modules"
 	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/schema"
 	"github.com/gravitational/gravity/lib/storage"
 	"github.com/gravitational/gravity/lib/storage/keyval"
 	"github.com/gravitational/gravity/lib/systeminfo"
@@ -150,6 +154,18 @@ type Config struct {
 	// Charts is Helm chart repository configuration.
 	Charts ChartsConfig `yaml:"charts"`
 
+	// Blob configures the storage for package BLOBs
+	Blob BlobConfig `yaml:"blob"`
+
+	// Audit provides settings for the audit log
+	Audit AuditConfig `yaml:"audit"`
+
+	// Autoscale configures the cluster autoscaler
+	Autoscale AutoscaleConfig `yaml:"autoscale"`
+
+	// AutoRepair configures automatic replacement of failed nodes
+	AutoRepair AutoRepairConfig `yaml:"auto_repair"`
+
 	// Users list allows to add registered users to the application
 	// e.g. application admins, what is handy for development purposes
 	Users Users `yaml:"users"`
@@ -220,6 +236,18 @@ func (cfg *Config) CheckAndSetDefaults() error {
 		return trace.Wrap(err)
 	}
 
+	if err := cfg.Blob.CheckAndSetDefaults(); err != nil {
+		return trace.Wrap(err)
+	}
+
+	if err := cfg.Autoscale.Check(); err != nil {
+		return trace.Wrap(err)
+	}
+
+	if err := cfg.AutoRepair.Check(); err != nil {
+		return trace.Wrap(err)
+	}
+
 	return nil
 }
 
@@ -365,6 +393,133 @@ func (c *ChartsConfig) CheckAndSetDefaults() error {
 	return nil
 }
 
+// BlobConfig defines package BLOB storage configuration
+type BlobConfig struct {
+	// Backend selects the BLOB storage: fs or s3.
+	// Defaults to fs which keeps BLOBs on the master nodes
+	Backend string `yaml:"backend"`
+	// S3 configures the S3-compatible BLOB storage
+	S3 S3BlobConfig `yaml:"s3"`
+	// Dedup enables storing package data on the local filesystem
+	// as content-addressed chunks shared between packages
+	Dedup bool `yaml:"dedup"`
+}
+
+// CheckAndSetDefaults validates BLOB storage configuration
+func (c *BlobConfig) CheckAndSetDefaults() error {
+	switch c.Backend {
+	case blob.BackendFS:
+	case "":
+		c.Backend = blob.BackendFS
+	case blob.BackendS3:
+		if c.S3.Bucket == "" {
+			return trace.BadParameter("missing S3 bucket for BLOB storage")
+		}
+	default:
+		return trace.BadParameter("unsupported BLOB storage backend %q, supported are: %v",
+			c.Backend, []string{blob.BackendFS, blob.BackendS3})
+	}
+	return nil
+}
+
+// S3BlobConfig defines the S3-compatible BLOB storage configuration
+type S3BlobConfig struct {
+	// Endpoint is the optional endpoint of an S3-compatible store, such as MinIO.
+	// AWS S3 is used if not set
+	Endpoint string `yaml:"endpoint"`
+	// Bucket is the name of the bucket to store BLOBs in
+	Bucket string `yaml:"bucket"`
+	// Prefix is the optional key prefix for BLOBs in the bucket
+	Prefix string `yaml:"prefix"`
+	// Region is the bucket region
+	Region string `yaml:"region"`
+	// CredentialsSecret is the name of the secret in the kube-system namespace
+	// with the access key ID and the secret access key.
+	// If unspecified, the default AWS credentials chain is used
+	CredentialsSecret string `yaml:"credentials_secret"`
+	// ServerSideEncryption is the optional server-side
+	// encryption algorithm: AES256 or aws:kms
+	ServerSideEncryption string `yaml:"server_side_encryption"`
+	// KMSKeyID is the optional ID of the KMS key used with aws:kms encryption
+	KMSKeyID string `yaml:"kms_key_id"`
+}
+
+// AuditConfig defines audit log configuration
+type AuditConfig struct {
+	// Retention is how long audit events are kept.
+	// Defaults to defaults.AuditEventRetention
+	Retention time.Duration `yaml:"retention"`
+	// Forward enables sending audit events to the configured log forwarders
+	Forward bool `yaml:"forward"`
+}
+
+// AutoscaleConfig defines cluster autoscaler configuration
+type AutoscaleConfig struct {
+	// Provider selects the autoscaler driver: aws, gce or webhook.
+	// If unspecified, AWS autoscaler is started when running on AWS
+	Provider string `yaml:"provider"`
+	// GCE configures the Google Compute Engine autoscaler
+	GCE GCEAutoscaleConfig `yaml:"gce"`
+}
+
+// Check validates autoscaler configuration
+func (c AutoscaleConfig) Check() error {
+	switch c.Provider {
+	case "", autoscale.ProviderAWS, autoscale.ProviderWebhook:
+	case autoscale.ProviderGCE:
+		if c.GCE.InstanceGroup == "" {
+			return trace.BadParameter("missing GCE managed instance group name")
+		}
+	default:
+		return trace.BadParameter("unsupported autoscale provider %q, supported are: %v",
+			c.Provider, []string{autoscale.ProviderAWS, autoscale.ProviderGCE, autoscale.ProviderWebhook})
+	}
+	return nil
+}
+
+// GCEAutoscaleConfig defines the Google Compute Engine autoscaler configuration
+type GCEAutoscaleConfig struct {
+	// Project is the GCE project ID, defaults to the project of the instance
+	Project string `yaml:"project"`
+	// Zone is the zone of the managed instance group, defaults to the zone of the instance
+	Zone string `yaml:"zone"`
+	// InstanceGroup is the name of the managed instance group with cluster nodes
+	InstanceGroup string `yaml:"instance_group"`
+}
+
+// AutoRepairConfig defines the node auto-repair configuration
+type AutoRepairConfig struct {
+	// Enabled turns on removal of nodes that have failed
+	// for longer than the grace period
+	Enabled bool `yaml:"enabled"`
+	// GracePeriod is how long a node has to stay failed before it is removed.
+	// Defaults to defaults.AutoRepairGracePeriod
+	GracePeriod time.Duration `yaml:"grace_period"`
+	// MaxNodes is the maximum number of nodes repaired at once.
+	// Defaults to defaults.AutoRepairMaxNodes
+	MaxNodes int `yaml:"max_nodes"`
+	// Provisioner is the provisioner used to replace removed nodes.
+	// If unspecified, removed nodes are not replaced
+	Provisioner string `yaml:"provisioner"`
+}
+
+// Check validates node auto-repair configuration
+func (c AutoRepairConfig) Check() error {
+	if c.GracePeriod < 0 {
+		return trace.BadParameter("auto-repair grace period can't be negative")
+	}
+	if c.MaxNodes < 0 {
+		return trace.BadParameter("auto-repair max nodes can't be negative")
+	}
+	switch c.Provisioner {
+	case "", schema.ProvisionerAWSTerraform:
+	default:
+		return trace.BadParameter("unsupported auto-repair provisioner %q, only %q is currently supported",
+			c.Provisioner, schema.ProvisionerAWSTerraform)
+	}
+	return nil
+}
+
 // OpsCenterConfig provides settings for access and installation portal
 type OpsCenterConfig struct {
 	// SeedConfig defines optional configuration to apply on OpsCenter start
@@ -0,0 +1,439 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package report
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sync"
+
+	"github.com/gravitational/trace"
+)
+
+// RedactionRule describes a class of sensitive data to remove from the report
+type RedactionRule struct {
+	// Name identifies the rule in the redaction manifest
+	Name string
+	// Pattern matches the sensitive data. If the pattern has a group
+	// named "secret", only the group is replaced, otherwise the whole match
+	Pattern *regexp.Regexp
+}
+
+// DefaultRedactionRules returns the built-in redaction rules.
+// Private keys are redacted separately as they span multiple lines
+func DefaultRedactionRules() []RedactionRule {
+	return []RedactionRule{
+		{
+			Name:    RuleURLCredentials,
+			Pattern: regexp.MustCompile(`[a-zA-Z][a-zA-Z0-9+.-]*://[^/\s:@]+:(?P<secret>[^/\s@]+)@`),
+		},
+		{
+			Name:    RuleAWSAccessKey,
+			Pattern: regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`),
+		},
+		{
+			Name:    RuleAWSSecretKey,
+			Pattern: regexp.MustCompile(`(?i)aws.{0,20}?(?:secret|key).{0,20}?[:=]\s*["']?(?P<secret>[A-Za-z0-9/+=]{40})\b`),
+		},
+		{
+			Name:    RuleBearerToken,
+			Pattern: regexp.MustCompile(`(?i)\bbearer\s+(?P<secret>[A-Za-z0-9\-._~+/]+=*)`),
+		},
+		{
+			Name:    RuleJWT,
+			Pattern: regexp.MustCompile(`\beyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+`),
+		},
+		{
+			Name: RulePassword,
+			Pattern: regexp.MustCompile(
+				`(?i)(?:password|passwd|pwd|secret|token|api[_-]?key)["']?\s*[:=]\s*["']?(?P<secret>[^\s"',;]+)`),
+		},
+		{
+			Name:    RuleSecretData,
+			Pattern: regexp.MustCompile(`"(?:data|stringData)":\{(?P<secret>[^{}]+)\}`),
+		},
+	}
+}
+
+// NewRedactor returns a new redactor with the built-in rules and the
+// additional rules specified as regular expressions
+func NewRedactor(patterns []string) (*Redactor, error) {
+	rules := DefaultRedactionRules()
+	for i, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, trace.BadParameter("invalid redaction pattern %q: %v", pattern, err)
+		}
+		rules = append(rules, RedactionRule{
+			Name:    fmt.Sprintf("%v-%v", RuleCustom, i+1),
+			Pattern: re,
+		})
+	}
+	return &Redactor{
+		rules: rules,
+		files: make(map[string]map[string]int),
+	}, nil
+}
+
+// Redactor removes sensitive data from the report.
+// It is safe for concurrent use
+type Redactor struct {
+	rules []RedactionRule
+	mu    sync.Mutex
+	// files maps the file name to the number of redactions per rule
+	files map[string]map[string]int
+}
+
+// RedactionManifest lists the redactions made in the report.
+// It does not contain any of the redacted data
+type RedactionManifest struct {
+	// Rules lists the names of all applied rules
+	Rules []string `json:"rules"`
+	// Files maps the report file name to the number of redactions per rule.
+	// Files inside archives are named as <archive>:<path>
+	Files map[string]map[string]int `json:"files"`
+}
+
+// Writer returns a report writer that redacts all data before
+// passing it to the specified writer
+func (r *Redactor) Writer(w Writer) Writer {
+	return func(name string) (io.WriteCloser, error) {
+		out, err := w(name)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		reader, writer := io.Pipe()
+		redacting := &redactingWriter{
+			PipeWriter: writer,
+			out:        out,
+			errCh:      make(chan error, 1),
+		}
+		go func() {
+			err := r.Redact(out, reader, name)
+			// Unblock the writer if redaction has failed
+			reader.CloseWithError(err)
+			redacting.errCh <- err
+		}()
+		return redacting, nil
+	}
+}
+
+// Redact copies data from src to dst removing sensitive data.
+// Gzip-compressed data and tar archives are redacted recursively.
+// name identifies the data in the redaction manifest
+func (r *Redactor) Redact(dst io.Writer, src io.Reader, name string) error {
+	in := bufio.NewReaderSize(src, redactBufferSize)
+	header, err := in.Peek(tarMagicOffset + len(tarMagic))
+	if err != nil && err != io.EOF {
+		return trace.Wrap(err)
+	}
+	switch {
+	case bytes.HasPrefix(header, gzipMagic):
+		return trace.Wrap(r.redactGzip(dst, in, name))
+	case len(header) > tarMagicOffset && bytes.HasPrefix(header[tarMagicOffset:], tarMagic):
+		return trace.Wrap(r.redactTar(dst, in, name))
+	}
+	return trace.Wrap(r.redactText(dst, in, name))
+}
+
+// Manifest returns the manifest of redactions made so far
+func (r *Redactor) Manifest() RedactionManifest {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	manifest := RedactionManifest{
+		Rules: []string{RulePrivateKey},
+		Files: make(map[string]map[string]int, len(r.files)),
+	}
+	for _, rule := range r.rules {
+		manifest.Rules = append(manifest.Rules, rule.Name)
+	}
+	for name, counts := range r.files {
+		manifest.Files[name] = make(map[string]int, len(counts))
+		for rule, count := range counts {
+			manifest.Files[name][rule] = count
+		}
+	}
+	return manifest
+}
+
+// WriteManifest writes the redaction manifest into the report
+func (r *Redactor) WriteManifest(reportWriter Writer) error {
+	w, err := reportWriter(RedactionManifestFile)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	defer w.Close()
+	bytes, err := json.MarshalIndent(r.Manifest(), "", "  ")
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	_, err = w.Write(bytes)
+	return trace.Wrap(err)
+}
+
+func (r *Redactor) redactGzip(dst io.Writer, src io.Reader, name string) error {
+	gzReader, err := gzip.NewReader(src)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	defer gzReader.Close()
+	gzWriter := gzip.NewWriter(dst)
+	if err := r.Redact(gzWriter, gzReader, name); err != nil {
+		return trace.Wrap(err)
+	}
+	return trace.Wrap(gzWriter.Close())
+}
+
+// redactTar redacts the contents of all regular files in the tar archive.
+// Files are buffered in memory as the size of the redacted file needs to be known
+// before it is written
+func (r *Redactor) redactTar(dst io.Writer, src io.Reader, name string) error {
+	tarReader := tar.NewReader(src)
+	tarWriter := tar.NewWriter(dst)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		if header.Typeflag != tar.TypeReg && header.Typeflag != tar.TypeRegA {
+			if err := tarWriter.WriteHeader(header); err != nil {
+				return trace.Wrap(err)
+			}
+			continue
+		}
+		var buf bytes.Buffer
+		err = r.Redact(&buf, tarReader, fmt.Sprintf("%v:%v", name, header.Name))
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		header.Typeflag = tar.TypeReg
+		header.Size = int64(buf.Len())
+		if err := tarWriter.WriteHeader(header); err != nil {
+			return trace.Wrap(err)
+		}
+		if _, err := io.Copy(tarWriter, &buf); err != nil {
+			return trace.Wrap(err)
+		}
+	}
+	return trace.Wrap(tarWriter.Close())
+}
+
+func (r *Redactor) redactText(dst io.Writer, src *bufio.Reader, name string) error {
+	redactor := &lineRedactor{
+		rules:  r.rules,
+		counts: make(map[string]int),
+	}
+	w := bufio.NewWriter(dst)
+	for {
+		line, err := src.ReadBytes('\n')
+		if len(line) != 0 {
+			if _, err := w.Write(redactor.redact(line)); err != nil {
+				return trace.Wrap(err)
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return trace.Wrap(err)
+		}
+	}
+	r.record(name, redactor.counts)
+	return trace.Wrap(w.Flush())
+}
+
+func (r *Redactor) record(name string, counts map[string]int) {
+	if len(counts) == 0 {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.files[name] = counts
+}
+
+// lineRedactor redacts text line by line. Private keys and values of YAML
+// data mappings span multiple lines so the redactor keeps track of
+// whether it is inside of either
+type lineRedactor struct {
+	rules  []RedactionRule
+	counts map[string]int
+	// inPrivateKey is true while inside of a PEM-encoded private key block
+	inPrivateKey bool
+	// inData is true while inside of a YAML data mapping
+	inData bool
+	// dataIndent is the indentation of the YAML data mapping key
+	dataIndent int
+}
+
+func (r *lineRedactor) redact(line []byte) []byte {
+	if r.inPrivateKey {
+		loc := privateKeyEnd.FindIndex(line)
+		if loc == nil {
+			return nil
+		}
+		r.inPrivateKey = false
+		line = line[loc[1]:]
+		if len(bytes.TrimSpace(line)) == 0 {
+			// The placeholder has already been terminated with a newline
+			return nil
+		}
+	}
+	if loc := privateKeyBegin.FindIndex(line); loc != nil {
+		r.counts[RulePrivateKey]++
+		result := append(append([]byte{}, line[:loc[0]]...), placeholder(RulePrivateKey)...)
+		end := privateKeyEnd.FindIndex(line[loc[1]:])
+		if end == nil {
+			r.inPrivateKey = true
+			return append(result, '\n')
+		}
+		return append(result, r.redact(line[loc[1]+end[1]:])...)
+	}
+	if r.inData {
+		indent := len(line) - len(bytes.TrimLeft(line, " "))
+		if indent > r.dataIndent && len(bytes.TrimSpace(line)) != 0 {
+			return r.redactDataValue(line)
+		}
+		r.inData = false
+	}
+	if match := yamlDataKey.FindSubmatch(line); match != nil {
+		r.inData = true
+		r.dataIndent = len(match[1])
+		return line
+	}
+	for _, rule := range r.rules {
+		line = r.apply(rule, line)
+	}
+	return line
+}
+
+// redactDataValue replaces the value of a YAML data mapping entry
+func (r *lineRedactor) redactDataValue(line []byte) []byte {
+	loc := yamlValue.FindSubmatchIndex(line)
+	if loc == nil {
+		return line
+	}
+	r.counts[RuleSecretData]++
+	result := append(append([]byte{}, line[:loc[2]]...), placeholder(RuleSecretData)...)
+	return append(result, line[loc[3]:]...)
+}
+
+// apply replaces all matches of the rule in the line
+func (r *lineRedactor) apply(rule RedactionRule, line []byte) []byte {
+	matches := rule.Pattern.FindAllSubmatchIndex(line, -1)
+	if matches == nil {
+		return line
+	}
+	group := subexpIndex(rule.Pattern, secretGroup)
+	var result []byte
+	last := 0
+	for _, match := range matches {
+		start, end := match[0], match[1]
+		if group > 0 {
+			start, end = match[2*group], match[2*group+1]
+		}
+		if start < 0 || bytes.HasPrefix(line[start:end], redactedPrefix) {
+			continue
+		}
+		r.counts[rule.Name]++
+		result = append(result, line[last:start]...)
+		result = append(result, placeholder(rule.Name)...)
+		last = end
+	}
+	if result == nil {
+		return line
+	}
+	return append(result, line[last:]...)
+}
+
+// Close waits for the redaction to complete and closes the underlying writer
+func (r *redactingWriter) Close() error {
+	r.PipeWriter.Close()
+	err := <-r.errCh
+	closeErr := r.out.Close()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return trace.Wrap(closeErr)
+}
+
+type redactingWriter struct {
+	*io.PipeWriter
+	out   io.WriteCloser
+	errCh chan error
+}
+
+func subexpIndex(re *regexp.Regexp, name string) int {
+	for i, subexp := range re.SubexpNames() {
+		if subexp == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func placeholder(rule string) []byte {
+	return []byte(fmt.Sprintf("%v%v]", string(redactedPrefix), rule))
+}
+
+const (
+	// RedactionManifestFile is the name of the report file with the redaction manifest
+	RedactionManifestFile = "redaction-manifest.json"
+
+	// RulePrivateKey redacts PEM-encoded private keys
+	RulePrivateKey = "private-key"
+	// RuleURLCredentials redacts passwords in URLs
+	RuleURLCredentials = "url-credentials"
+	// RuleAWSAccessKey redacts AWS access key IDs
+	RuleAWSAccessKey = "aws-access-key"
+	// RuleAWSSecretKey redacts AWS secret access keys
+	RuleAWSSecretKey = "aws-secret-key"
+	// RuleBearerToken redacts HTTP bearer tokens
+	RuleBearerToken = "bearer-token"
+	// RuleJWT redacts JSON web tokens
+	RuleJWT = "jwt"
+	// RulePassword redacts values of password, secret, token and API key settings
+	RulePassword = "password"
+	// RuleSecretData redacts values of Kubernetes Secret data
+	RuleSecretData = "kubernetes-secret-data"
+	// RuleCustom is the name prefix of user-supplied rules
+	RuleCustom = "custom"
+
+	secretGroup      = "secret"
+	redactBufferSize = 64 * 1024
+	tarMagicOffset   = 257
+)
+
+var (
+	redactedPrefix  = []byte("[REDACTED:")
+	gzipMagic       = []byte{0x1f, 0x8b}
+	tarMagic        = []byte("ustar")
+	privateKeyBegin = regexp.MustCompile(`-----BEGIN [A-Z0-9 ]*PRIVATE KEY-----`)
+	privateKeyEnd   = regexp.MustCompile(`-----END [A-Z0-9 ]*PRIVATE KEY-----`)
+	// yamlDataKey matches the key of a YAML Secret data mapping
+	yamlDataKey = regexp.MustCompile(`^(\s*)(?:data|stringData):\s*$`)
+	// yamlValue matches the value of a YAML mapping entry
+	yamlValue = regexp.MustCompile(`^\s*[^:\s]+:\s+(\S.*?)\s*$`)
+)
@@ -42,6 +42,7 @@ func (srv *agentServer) Command(req *pb.CommandArgs, stream pb.Agent_CommandServ
 		"args":    req.Args})
 	log.Debug("request received")
 
+	args := req.Args
 	if req.SelfCommand {
 		gravityPath, err := os.Executable()
 		if err != nil {
@@ -51,7 +52,9 @@ func (srv *agentServer) Command(req *pb.CommandArgs, stream pb.Agent_CommandServ
 		req.Args = append([]string{gravityPath}, req.Args...)
 	}
 
-	return trace.Wrap(srv.command(*req, stream, log))
+	err := srv.command(*req, stream, log)
+	countCommand(args, req.SelfCommand, err)
+	return trace.Wrap(err)
 }
 
 // PeerJoin accepts a new peer
@@ -0,0 +1,48 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"path/filepath"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var agentCommands = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "gravity_rpc_agent_commands_total",
+		Help: "Number of commands executed by the RPC agent",
+	},
+	[]string{"command", "result"},
+)
+
+func init() {
+	prometheus.MustRegister(agentCommands)
+}
+
+// countCommand records the execution of the command with the specified arguments
+func countCommand(args []string, selfCommand bool, err error) {
+	command := filepath.Base(args[0])
+	if selfCommand {
+		command = "gravity " + args[0]
+	}
+	result := "success"
+	if err != nil {
+		result = "failure"
+	}
+	agentCommands.WithLabelValues(command, result).Inc()
+}
@@ -31,6 +31,7 @@ import (
 	"github.com/gravitational/gravity/lib/utils"
 
 	"github.com/gravitational/trace"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/sirupsen/logrus"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
@@ -73,6 +74,11 @@ func New(config Config, log logrus.FieldLogger) (*agentServer, error) {
 		ctx:         ctx,
 		cancel:      cancel,
 	}
+	if config.MetricsAddr != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", prometheus.Handler())
+		srv.metricsServer = &http.Server{Addr: config.MetricsAddr, Handler: mux}
+	}
 	pb.RegisterAgentServer(grpcServer, &srv)
 	pb.RegisterDiscoveryServer(grpcServer, &srv)
 	healthpb.RegisterHealthServer(grpcServer, healthServer)
@@ -83,6 +89,9 @@ func New(config Config, log logrus.FieldLogger) (*agentServer, error) {
 
 // Serve starts the server loop accepting connections
 func (srv *agentServer) Serve() error {
+	if srv.metricsServer != nil {
+		go srv.serveMetrics()
+	}
 	srv.WithField("addr", srv.Listener.Addr().String()).Info("Listening.")
 	return trace.Wrap(srv.serve(srv.Listener))
 }
@@ -100,6 +109,9 @@ func (srv *agentServer) Stop(_ context.Context) error {
 	default:
 		srv.cancel()
 	}
+	if srv.metricsServer != nil {
+		srv.metricsServer.Close()
+	}
 	srv.grpcServer.GracefulStop()
 	return nil
 }
@@ -114,6 +126,15 @@ func (srv *agentServer) Done() <-chan struct{} {
 	return srv.ctx.Done()
 }
 
+// serveMetrics serves Prometheus metrics until the server is stopped
+func (srv *agentServer) serveMetrics() {
+	srv.WithField("addr", srv.MetricsAddr).Info("Serving metrics.")
+	err := srv.metricsServer.ListenAndServe()
+	if err != nil && err != http.ErrServerClosed {
+		srv.WithError(err).Warn("Failed to serve metrics.")
+	}
+}
+
 func (srv *agentServer) serve(listener net.Listener) error {
 	err := srv.grpcServer.Serve(listener)
 	if err != nil && utils.IsClosedConnectionError(err) {
@@ -144,6 +165,8 @@ type Config struct {
 	// ReconnectTimeout specifies the maximum timeout used to reconnect to a peer.
 	// Defaults to defaults.RPCAgentBackoffThreshold
 	ReconnectTimeout time.Duration
+	// MetricsAddr is an optional address to serve Prometheus metrics on
+	MetricsAddr string
 	// systemInfo queries system information
 	systemInfo
 	// commandExecutor is a system command executor.
@@ -199,6 +222,8 @@ type agentServer struct {
 	Config
 	logrus.FieldLogger
 	grpcServer *grpc.Server
+	// metricsServer serves Prometheus metrics if configured
+	metricsServer *http.Server
 	// listener is the server's listener
 	listener net.Listener
 	ctx      context.Context
@@ -1153,6 +1153,36 @@ func (in *Runtime) DeepCopy() *Runtime {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SystemDependencies) DeepCopyInto(out *SystemDependencies) {
+	*out = *in
+	if in.Runtime != nil {
+		in, out := &in.Runtime, &out.Runtime
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(Dependency)
+			**out = **in
+		}
+	}
+	if in.IntermediateRuntimes != nil {
+		in, out := &in.IntermediateRuntimes, &out.IntermediateRuntimes
+		*out = make([]Dependency, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SystemDependencies.
+func (in *SystemDependencies) DeepCopy() *SystemDependencies {
+	if in == nil {
+		return nil
+	}
+	out := new(SystemDependencies)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SystemOptions) DeepCopyInto(out *SystemOptions) {
 	*out = *in
@@ -1192,6 +1222,7 @@ func (in *SystemOptions) DeepCopyInto(out *SystemOptions) {
 			(*in).DeepCopyInto(*out)
 		}
 	}
+	in.Dependencies.DeepCopyInto(&out.Dependencies)
 	return
 }
 
@@ -341,6 +341,17 @@ func (m Manifest) DefaultRuntimePackage() (*loc.Locator, error) {
 	return &m.SystemOptions.Dependencies.Runtime.Locator, nil
 }
 
+// IntermediateRuntimePackages returns the list of intermediate runtime packages
+func (m Manifest) IntermediateRuntimePackages() (packages []loc.Locator) {
+	if m.SystemOptions == nil {
+		return nil
+	}
+	for _, dependency := range m.SystemOptions.Dependencies.IntermediateRuntimes {
+		packages = append(packages, dependency.Locator)
+	}
+	return packages
+}
+
 // RuntimeImages returns the list of all runtime images.
 func (m Manifest) RuntimeImages() (images []string) {
 	if m.SystemOptions != nil && m.SystemOptions.BaseImage != "" {
@@ -359,6 +370,7 @@ func (m Manifest) AllPackageDependencies() (deps []loc.Locator) {
 	if m.SystemOptions != nil && m.SystemOptions.Dependencies.Runtime != nil {
 		deps = append(deps, m.SystemOptions.Dependencies.Runtime.Locator)
 	}
+	deps = append(deps, m.IntermediateRuntimePackages()...)
 	deps = append(deps, m.NodeProfiles.RuntimePackages()...)
 	return loc.Deduplicate(append(m.Dependencies.GetPackages(), deps...))
 }
@@ -1021,6 +1033,10 @@ func (r *Runtime) UnmarshalJSON(data []byte) error {
 type SystemDependencies struct {
 	// Runtime describes the runtime package
 	Runtime *Dependency `json:"runtimePackage,omitempty"`
+	// IntermediateRuntimes lists runtime packages the cluster is updated
+	// through on the way to the runtime package when the update spans
+	// several Kubernetes versions
+	IntermediateRuntimes []Dependency `json:"intermediateRuntimePackages,omitempty"`
 }
 
 // Docker describes docker options
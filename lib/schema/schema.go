@@ -684,7 +684,11 @@ const manifestSchema = `
         "dependencies": {
           "type": "object",
           "properties": {
-            "runtimePackage": {"type": "string"}
+            "runtimePackage": {"type": "string"},
+            "intermediateRuntimePackages": {
+              "type": "array",
+              "items": {"type": "string"}
+            }
           }
         }
       }
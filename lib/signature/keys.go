@@ -0,0 +1,145 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package signature
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/hex"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+
+	"github.com/gravitational/gravity/lib/utils"
+
+	"github.com/gravitational/trace"
+)
+
+// ReadSigningKey reads a PEM-encoded RSA or ECDSA private key from the specified file
+func ReadSigningKey(path string) (crypto.Signer, error) {
+	data, err := utils.ReadPath(path)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	key, err := ParseSigningKey(data)
+	if err != nil {
+		return nil, trace.Wrap(err, "failed to parse signing key %v", path)
+	}
+	return key, nil
+}
+
+// ParseSigningKey parses a PEM-encoded RSA or ECDSA private key
+func ParseSigningKey(data []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, trace.BadParameter("expected PEM-encoded private key")
+	}
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		return key, nil
+	case "EC PRIVATE KEY":
+		key, err := x509.ParseECPrivateKey(block.Bytes)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		return key, nil
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		signer, ok := key.(crypto.Signer)
+		if !ok {
+			return nil, trace.BadParameter("unsupported private key type %T", key)
+		}
+		return signer, nil
+	}
+	return nil, trace.BadParameter("unsupported PEM block %q", block.Type)
+}
+
+// ParsePublicKey parses a PEM-encoded PKIX public key
+func ParsePublicKey(data []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "PUBLIC KEY" {
+		return nil, trace.BadParameter("expected PEM-encoded public key")
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return key, nil
+}
+
+// LoadTrustedKeys returns the public keys found in the specified directory
+// along with the keys from the specified files.
+// A missing directory is not an error
+func LoadTrustedKeys(dir string, paths ...string) (keys []crypto.PublicKey, err error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, trace.ConvertSystemError(err)
+	}
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+		paths = append(paths, filepath.Join(dir, file.Name()))
+	}
+	for _, path := range paths {
+		data, err := utils.ReadPath(path)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		key, err := ParsePublicKey(data)
+		if err != nil {
+			return nil, trace.Wrap(err, "failed to parse trusted key %v", path)
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// KeyID returns the identifier of the specified public key which
+// is the SHA-256 checksum of its PKIX encoding
+func KeyID(key crypto.PublicKey) (string, error) {
+	data, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		return "", trace.Wrap(err)
+	}
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:]), nil
+}
+
+// verifyECDSA verifies the ASN.1-encoded ECDSA signature of the digest
+func verifyECDSA(key *ecdsa.PublicKey, digest, signature []byte) bool {
+	var sig struct {
+		R, S *big.Int
+	}
+	rest, err := asn1.Unmarshal(signature, &sig)
+	if err != nil || len(rest) != 0 {
+		return false
+	}
+	return ecdsa.Verify(key, digest, sig.R, sig.S)
+}
@@ -0,0 +1,268 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package signature implements signing and verification of installers.
+//
+// An installer signature is a detached signature over the installer manifest
+// which lists every package of the installer along with the checksums of
+// the package data and the package manifest. The signature is stored in
+// the root of the installer tarball next to the packages it covers.
+package signature
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/loc"
+	"github.com/gravitational/gravity/lib/pack"
+
+	"github.com/gravitational/trace"
+)
+
+// Manifest describes the contents of a signed installer
+type Manifest struct {
+	// Application is the application the installer is for
+	Application loc.Locator `json:"application"`
+	// Packages lists all installer packages
+	Packages []PackageDigest `json:"packages"`
+}
+
+// PackageDigest describes a single package of a signed installer
+type PackageDigest struct {
+	// Locator identifies the package
+	Locator loc.Locator `json:"locator"`
+	// SHA512 is the checksum of the package data
+	SHA512 string `json:"sha512"`
+	// ManifestSHA512 is the checksum of the application manifest
+	// attached to the package, empty for regular packages
+	ManifestSHA512 string `json:"manifest_sha512,omitempty"`
+}
+
+// Signature is a detached signature over an installer manifest
+type Signature struct {
+	// Manifest is the serialized installer manifest the signature was computed for
+	Manifest []byte `json:"manifest"`
+	// KeyID identifies the public key that verifies the signature
+	KeyID string `json:"key_id"`
+	// Signature is the signature of the manifest SHA-256 digest
+	Signature []byte `json:"signature"`
+}
+
+// Sign computes the manifest of all packages in the specified package service
+// and returns its signature created with the specified key
+func Sign(packages pack.PackageService, app loc.Locator, key crypto.Signer) (*Signature, error) {
+	manifest, err := NewManifest(packages, app)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	keyID, err := KeyID(key.Public())
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	digest := sha256.Sum256(data)
+	signature, err := key.Sign(rand.Reader, digest[:], crypto.SHA256)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &Signature{
+		Manifest:  data,
+		KeyID:     keyID,
+		Signature: signature,
+	}, nil
+}
+
+// Verify verifies that the signature was created by one of the trusted keys
+// and that the packages in the specified package service match the signed
+// manifest exactly. Returns the verified manifest
+func Verify(packages pack.PackageService, sig Signature, trustedKeys []crypto.PublicKey) (*Manifest, error) {
+	key, err := findKey(sig.KeyID, trustedKeys)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	digest := sha256.Sum256(sig.Manifest)
+	if err := verifyDigest(key, digest[:], sig.Signature); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var signed Manifest
+	if err := json.Unmarshal(sig.Manifest, &signed); err != nil {
+		return nil, trace.BadParameter("invalid signed manifest: %v", err)
+	}
+	actual, err := NewManifest(packages, signed.Application)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if err := compare(signed, *actual); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &signed, nil
+}
+
+// NewManifest computes the manifest of all packages in the specified package service
+func NewManifest(packages pack.PackageService, app loc.Locator) (*Manifest, error) {
+	manifest := Manifest{Application: app}
+	err := pack.ForeachPackage(packages, func(env pack.PackageEnvelope) error {
+		digest, err := newDigest(packages, env.Locator)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		manifest.Packages = append(manifest.Packages, *digest)
+		return nil
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	sort.Slice(manifest.Packages, func(i, j int) bool {
+		return manifest.Packages[i].Locator.String() < manifest.Packages[j].Locator.String()
+	})
+	return &manifest, nil
+}
+
+// Write saves the signature into the installer directory
+func Write(dir string, sig Signature) error {
+	data, err := json.MarshalIndent(sig, "", "  ")
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	err = ioutil.WriteFile(filepath.Join(dir, defaults.InstallerSignatureFile), data, defaults.SharedReadMask)
+	return trace.ConvertSystemError(err)
+}
+
+// Read reads the signature from the installer directory.
+// Returns NotFound if the installer is not signed
+func Read(dir string) (*Signature, error) {
+	data, err := ioutil.ReadFile(filepath.Join(dir, defaults.InstallerSignatureFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, trace.NotFound("installer in %v is not signed", dir)
+		}
+		return nil, trace.ConvertSystemError(err)
+	}
+	var sig Signature
+	if err := json.Unmarshal(data, &sig); err != nil {
+		return nil, trace.BadParameter("invalid installer signature: %v", err)
+	}
+	return &sig, nil
+}
+
+// VerifyDir verifies the signature of the installer in the specified
+// directory against the trusted keys.
+// If no trusted keys have been configured, verification is skipped
+// and the method returns NotFound
+func VerifyDir(dir string, packages pack.PackageService, trustedKeys []crypto.PublicKey) (*Manifest, error) {
+	if len(trustedKeys) == 0 {
+		return nil, trace.NotFound("no trusted keys configured")
+	}
+	sig, err := Read(dir)
+	if err != nil {
+		if trace.IsNotFound(err) {
+			return nil, trace.AccessDenied("refusing to use unsigned installer in %v", dir)
+		}
+		return nil, trace.Wrap(err)
+	}
+	manifest, err := Verify(packages, *sig, trustedKeys)
+	if err != nil {
+		return nil, trace.Wrap(err, "failed to verify installer in %v", dir)
+	}
+	return manifest, nil
+}
+
+func newDigest(packages pack.PackageService, locator loc.Locator) (*PackageDigest, error) {
+	env, rc, err := packages.ReadPackage(locator)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	defer rc.Close()
+	hash := sha512.New()
+	if _, err := io.Copy(hash, rc); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	digest := PackageDigest{
+		Locator: locator,
+		SHA512:  hex.EncodeToString(hash.Sum(nil)),
+	}
+	if len(env.Manifest) != 0 {
+		manifestHash := sha512.Sum512(env.Manifest)
+		digest.ManifestSHA512 = hex.EncodeToString(manifestHash[:])
+	}
+	return &digest, nil
+}
+
+// compare makes sure the actual set of packages is exactly the signed one
+func compare(signed, actual Manifest) error {
+	digests := make(map[string]PackageDigest, len(actual.Packages))
+	for _, digest := range actual.Packages {
+		digests[digest.Locator.String()] = digest
+	}
+	for _, expected := range signed.Packages {
+		digest, ok := digests[expected.Locator.String()]
+		if !ok {
+			return trace.AccessDenied("signed package %v is missing", expected.Locator)
+		}
+		if digest.SHA512 != expected.SHA512 || digest.ManifestSHA512 != expected.ManifestSHA512 {
+			return trace.AccessDenied("package %v has been tampered with", expected.Locator)
+		}
+		delete(digests, expected.Locator.String())
+	}
+	for name := range digests {
+		return trace.AccessDenied("package %v is not signed", name)
+	}
+	return nil
+}
+
+func findKey(keyID string, trustedKeys []crypto.PublicKey) (crypto.PublicKey, error) {
+	for _, key := range trustedKeys {
+		id, err := KeyID(key)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		if id == keyID {
+			return key, nil
+		}
+	}
+	return nil, trace.AccessDenied("installer is signed with untrusted key %v", keyID)
+}
+
+func verifyDigest(key crypto.PublicKey, digest, signature []byte) error {
+	switch key := key.(type) {
+	case *rsa.PublicKey:
+		if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest, signature); err != nil {
+			return trace.AccessDenied("invalid installer signature")
+		}
+	case *ecdsa.PublicKey:
+		if !verifyECDSA(key, digest, signature) {
+			return trace.AccessDenied("invalid installer signature")
+		}
+	default:
+		return trace.BadParameter("unsupported key type %T", key)
+	}
+	return nil
+}
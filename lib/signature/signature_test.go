@@ -0,0 +1,193 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package signature
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gravitational/gravity/lib/blob/fs"
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/loc"
+	"github.com/gravitational/gravity/lib/pack"
+	"github.com/gravitational/gravity/lib/pack/localpack"
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/storage/keyval"
+
+	"github.com/gravitational/trace"
+	check "gopkg.in/check.v1"
+)
+
+func TestSignature(t *testing.T) { check.TestingT(t) }
+
+type SignatureSuite struct {
+	dir      string
+	backend  storage.Backend
+	packages pack.PackageService
+	key      crypto.Signer
+	app      loc.Locator
+}
+
+var _ = check.Suite(&SignatureSuite{})
+
+func (s *SignatureSuite) SetUpTest(c *check.C) {
+	s.dir = c.MkDir()
+	var err error
+	s.backend, err = keyval.NewBolt(keyval.BoltConfig{Path: filepath.Join(s.dir, "gravity.db")})
+	c.Assert(err, check.IsNil)
+	objects, err := fs.New(filepath.Join(s.dir, defaults.PackagesDir))
+	c.Assert(err, check.IsNil)
+	s.packages, err = localpack.New(localpack.Config{
+		Backend:     s.backend,
+		UnpackedDir: filepath.Join(s.dir, defaults.PackagesDir, defaults.UnpackedDir),
+		Objects:     objects,
+	})
+	c.Assert(err, check.IsNil)
+
+	s.app = loc.MustParseLocator("example.com/app:1.0.0")
+	c.Assert(s.packages.UpsertRepository(s.app.Repository, time.Time{}), check.IsNil)
+	s.createPackage(c, s.app, "application data", pack.WithManifest(string(storage.AppUser), []byte("kind: Application")))
+	s.createPackage(c, loc.MustParseLocator("example.com/dependency:1.0.0"), "dependency data")
+
+	s.key, err = rsa.GenerateKey(rand.Reader, 2048)
+	c.Assert(err, check.IsNil)
+}
+
+func (s *SignatureSuite) TearDownTest(c *check.C) {
+	if s.backend != nil {
+		s.backend.Close()
+	}
+}
+
+func (s *SignatureSuite) TestVerifiesSignedPackages(c *check.C) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	c.Assert(err, check.IsNil)
+	for _, key := range []crypto.Signer{s.key, ecKey} {
+		sig, err := Sign(s.packages, s.app, key)
+		c.Assert(err, check.IsNil)
+		c.Assert(Write(s.dir, *sig), check.IsNil)
+
+		manifest, err := VerifyDir(s.dir, s.packages, []crypto.PublicKey{key.Public()})
+		c.Assert(err, check.IsNil)
+		c.Assert(manifest.Application, check.DeepEquals, s.app)
+		c.Assert(manifest.Packages, check.HasLen, 2)
+	}
+}
+
+func (s *SignatureSuite) TestRejectsTamperedPackages(c *check.C) {
+	sig, err := Sign(s.packages, s.app, s.key)
+	c.Assert(err, check.IsNil)
+	trusted := []crypto.PublicKey{s.key.Public()}
+
+	s.createPackage(c, loc.MustParseLocator("example.com/dependency:1.0.0"), "tampered data")
+	_, err = Verify(s.packages, *sig, trusted)
+	c.Assert(trace.IsAccessDenied(err), check.Equals, true, check.Commentf("%v", err))
+	c.Assert(err, check.ErrorMatches, ".*has been tampered with.*")
+}
+
+func (s *SignatureSuite) TestRejectsUnsignedPackages(c *check.C) {
+	sig, err := Sign(s.packages, s.app, s.key)
+	c.Assert(err, check.IsNil)
+	trusted := []crypto.PublicKey{s.key.Public()}
+
+	s.createPackage(c, loc.MustParseLocator("example.com/extra:1.0.0"), "extra data")
+	_, err = Verify(s.packages, *sig, trusted)
+	c.Assert(trace.IsAccessDenied(err), check.Equals, true, check.Commentf("%v", err))
+	c.Assert(err, check.ErrorMatches, ".*is not signed.*")
+
+	c.Assert(s.packages.DeletePackage(loc.MustParseLocator("example.com/extra:1.0.0")), check.IsNil)
+	c.Assert(s.packages.DeletePackage(loc.MustParseLocator("example.com/dependency:1.0.0")), check.IsNil)
+	_, err = Verify(s.packages, *sig, trusted)
+	c.Assert(trace.IsAccessDenied(err), check.Equals, true, check.Commentf("%v", err))
+	c.Assert(err, check.ErrorMatches, ".*is missing.*")
+}
+
+func (s *SignatureSuite) TestRejectsModifiedManifest(c *check.C) {
+	sig, err := Sign(s.packages, s.app, s.key)
+	c.Assert(err, check.IsNil)
+	sig.Manifest = bytes.Replace(sig.Manifest, []byte("dependency"), []byte("dependencx"), -1)
+	_, err = Verify(s.packages, *sig, []crypto.PublicKey{s.key.Public()})
+	c.Assert(trace.IsAccessDenied(err), check.Equals, true, check.Commentf("%v", err))
+	c.Assert(err, check.ErrorMatches, ".*invalid installer signature.*")
+}
+
+func (s *SignatureSuite) TestRejectsUntrustedOrMissingSignature(c *check.C) {
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	c.Assert(err, check.IsNil)
+	trusted := []crypto.PublicKey{otherKey.Public()}
+
+	_, err = VerifyDir(s.dir, s.packages, trusted)
+	c.Assert(trace.IsAccessDenied(err), check.Equals, true, check.Commentf("%v", err))
+	c.Assert(err, check.ErrorMatches, ".*unsigned installer.*")
+
+	sig, err := Sign(s.packages, s.app, s.key)
+	c.Assert(err, check.IsNil)
+	c.Assert(Write(s.dir, *sig), check.IsNil)
+	_, err = VerifyDir(s.dir, s.packages, trusted)
+	c.Assert(trace.IsAccessDenied(err), check.Equals, true, check.Commentf("%v", err))
+	c.Assert(err, check.ErrorMatches, ".*untrusted key.*")
+
+	_, err = VerifyDir(s.dir, s.packages, nil)
+	c.Assert(trace.IsNotFound(err), check.Equals, true, check.Commentf("%v", err))
+}
+
+func (s *SignatureSuite) TestLoadsKeys(c *check.C) {
+	privateKey := pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(s.key.(*rsa.PrivateKey)),
+	})
+	keyPath := filepath.Join(s.dir, "signing.pem")
+	c.Assert(ioutil.WriteFile(keyPath, privateKey, defaults.SharedReadMask), check.IsNil)
+	signingKey, err := ReadSigningKey(keyPath)
+	c.Assert(err, check.IsNil)
+
+	publicKeyBytes, err := x509.MarshalPKIXPublicKey(s.key.Public())
+	c.Assert(err, check.IsNil)
+	keysDir := filepath.Join(s.dir, "trusted-keys")
+	c.Assert(ioutil.WriteFile(filepath.Join(s.dir, "public.pem"), pem.EncodeToMemory(&pem.Block{
+		Type:  "PUBLIC KEY",
+		Bytes: publicKeyBytes,
+	}), defaults.SharedReadMask), check.IsNil)
+
+	keys, err := LoadTrustedKeys(keysDir)
+	c.Assert(err, check.IsNil)
+	c.Assert(keys, check.HasLen, 0)
+
+	keys, err = LoadTrustedKeys(keysDir, filepath.Join(s.dir, "public.pem"))
+	c.Assert(err, check.IsNil)
+	c.Assert(keys, check.HasLen, 1)
+	expectedID, err := KeyID(signingKey.Public())
+	c.Assert(err, check.IsNil)
+	keyID, err := KeyID(keys[0])
+	c.Assert(err, check.IsNil)
+	c.Assert(keyID, check.Equals, expectedID)
+}
+
+func (s *SignatureSuite) createPackage(c *check.C, locator loc.Locator, data string, options ...pack.PackageOption) {
+	_, err := s.packages.UpsertPackage(locator, bytes.NewBufferString(data), options...)
+	c.Assert(err, check.IsNil)
+}
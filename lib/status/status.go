@@ -62,6 +62,31 @@ func FromCluster(ctx context.Context, operator ops.Operator, cluster ops.Site, o
 		status.Token = *token
 	}
 
+	schedules, err := operator.GetBackupSchedules(cluster.Key(), false)
+	if err != nil {
+		logrus.WithError(err).Warn("Failed to fetch backup schedules.")
+	}
+	for _, schedule := range schedules {
+		status.Backups = append(status.Backups, BackupSchedule{
+			Name:                 schedule.GetName(),
+			Schedule:             schedule.GetSchedule(),
+			Destination:          schedule.GetDestination().String(),
+			BackupScheduleStatus: schedule.GetStatus(),
+		})
+	}
+
+	blobs, err := operator.GetBLOBStatus(cluster.Key())
+	if err != nil {
+		logrus.WithError(err).Warn("Failed to fetch package storage status.")
+	}
+	if blobs != nil {
+		for _, peer := range blobs.Peers {
+			if peer.Integrity != nil {
+				status.BLOBs = append(status.BLOBs, peer)
+			}
+		}
+	}
+
 	// Collect application endpoints.
 	endpoints, err := operator.GetApplicationEndpoints(cluster.Key())
 	if err != nil {
@@ -186,10 +211,27 @@ type Cluster struct {
 	ActiveOperations []*ClusterOperation `json:"active_operations,omitempty"`
 	// Endpoints contains cluster and application endpoints.
 	Endpoints Endpoints `json:"endpoints"`
+	// Backups describes the state of scheduled backups
+	Backups []BackupSchedule `json:"backups,omitempty"`
+	// BLOBs lists the package storage peers with the outcome
+	// of their last integrity check
+	BLOBs []storage.Peer `json:"blobs,omitempty"`
 	// Extension is a cluster status extension
 	Extension `json:",inline,omitempty"`
 }
 
+// BackupSchedule describes the state of a scheduled backup
+type BackupSchedule struct {
+	// Name is the backup schedule name
+	Name string `json:"name"`
+	// Schedule is the cron schedule of the backup
+	Schedule string `json:"schedule"`
+	// Destination describes where backups are shipped to
+	Destination string `json:"destination"`
+	// BackupScheduleStatus describes the outcome of recent backups
+	storage.BackupScheduleStatus `json:",inline"`
+}
+
 // Endpoints contains information about cluster and application endpoints.
 type Endpoints struct {
 	// Applications contains endpoints for installed applications.
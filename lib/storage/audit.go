@@ -0,0 +1,123 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gravitational/trace"
+)
+
+// AuditEvents stores the audit trail of changes made to the cluster
+type AuditEvents interface {
+	// CreateAuditEvent records a new audit event
+	CreateAuditEvent(AuditEvent) (*AuditEvent, error)
+	// GetAuditEvents returns audit events that match the filter
+	// sorted by time, oldest first
+	GetAuditEvents(AuditEventFilter) ([]AuditEvent, error)
+}
+
+// AuditEvent describes a single change made to the cluster
+type AuditEvent struct {
+	// ID is the unique event ID
+	ID string `json:"id"`
+	// Time is when the change was made
+	Time time.Time `json:"time"`
+	// User is the name of the user that made the change
+	User string `json:"user"`
+	// Verb is the kind of change, e.g. create, update or delete
+	Verb string `json:"verb"`
+	// Kind is the kind of the changed resource
+	Kind string `json:"kind"`
+	// Name is the name of the changed resource
+	Name string `json:"name,omitempty"`
+	// ClusterName is the name of the cluster the change was made in
+	ClusterName string `json:"cluster_name,omitempty"`
+	// Diff is the difference between the resource state before
+	// and after the change, with secrets redacted
+	Diff string `json:"diff,omitempty"`
+	// Result is the outcome of the change, either success or failure
+	Result string `json:"result"`
+	// Error is the error message if the change has failed
+	Error string `json:"error,omitempty"`
+	// Expires is when the event is removed from the audit log
+	Expires time.Time `json:"expires"`
+}
+
+// Check validates the audit event
+func (e AuditEvent) Check() error {
+	if e.Time.IsZero() {
+		return trace.BadParameter("missing parameter Time")
+	}
+	if e.Verb == "" {
+		return trace.BadParameter("missing parameter Verb")
+	}
+	if e.Kind == "" {
+		return trace.BadParameter("missing parameter Kind")
+	}
+	switch e.Result {
+	case AuditResultSuccess, AuditResultFailure:
+	default:
+		return trace.BadParameter("unsupported audit event result %q", e.Result)
+	}
+	return nil
+}
+
+// String returns a text representation of this event
+func (e AuditEvent) String() string {
+	var resource string
+	if e.Name != "" {
+		resource = fmt.Sprintf("%v %v", e.Kind, e.Name)
+	} else {
+		resource = e.Kind
+	}
+	return fmt.Sprintf("AuditEvent(user=%v, %v %v, result=%v)",
+		e.User, e.Verb, resource, e.Result)
+}
+
+// AuditEventFilter selects audit events
+type AuditEventFilter struct {
+	// Since selects events recorded after the specified time
+	Since time.Time
+	// User selects events of the specified user
+	User string
+	// Kind selects events for the specified resource kind
+	Kind string
+}
+
+// Match returns true if the event matches this filter
+func (f AuditEventFilter) Match(e AuditEvent) bool {
+	if !f.Since.IsZero() && e.Time.Before(f.Since) {
+		return false
+	}
+	if f.User != "" && f.User != e.User {
+		return false
+	}
+	if f.Kind != "" && !strings.EqualFold(CanonicalKind(f.Kind), e.Kind) {
+		return false
+	}
+	return true
+}
+
+const (
+	// AuditResultSuccess is the result of a change that has succeeded
+	AuditResultSuccess = "success"
+	// AuditResultFailure is the result of a change that has failed
+	AuditResultFailure = "failure"
+)
@@ -0,0 +1,452 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gravitational/gravity/lib/cron"
+	"github.com/gravitational/gravity/lib/defaults"
+
+	teleservices "github.com/gravitational/teleport/lib/services"
+	teleutils "github.com/gravitational/teleport/lib/utils"
+	"github.com/gravitational/trace"
+	"github.com/jonboulle/clockwork"
+)
+
+// BackupSchedule describes a scheduled cluster backup
+type BackupSchedule interface {
+	// Resource provides common resource methods
+	teleservices.Resource
+	// CheckAndSetDefaults validates the backup schedule
+	CheckAndSetDefaults() error
+	// GetSchedule returns the cron expression that specifies when backups run
+	GetSchedule() string
+	// GetTimeout returns the backup hook timeout
+	GetTimeout() time.Duration
+	// GetDestination returns the location backups are shipped to
+	GetDestination() BackupDestination
+	// GetRetention returns the backup retention policy
+	GetRetention() BackupRetention
+	// GetStatus returns the status of the most recent backups
+	GetStatus() BackupScheduleStatus
+	// SetStatus sets the status of the most recent backups
+	SetStatus(BackupScheduleStatus)
+	// WithoutSecrets returns a copy of the backup schedule without
+	// the S3 secret access key and the cluster API token
+	WithoutSecrets() BackupSchedule
+}
+
+// NewBackupSchedule creates a new backup schedule with the specified name and spec
+func NewBackupSchedule(name string, spec BackupScheduleSpecV2) BackupSchedule {
+	return &BackupScheduleV2{
+		Kind:    KindBackupSchedule,
+		Version: teleservices.V2,
+		Metadata: teleservices.Metadata{
+			Name:      name,
+			Namespace: defaults.Namespace,
+		},
+		Spec: spec,
+	}
+}
+
+// BackupScheduleV2 defines a scheduled cluster backup
+type BackupScheduleV2 struct {
+	// Kind is the resource kind, "backupschedule"
+	Kind string `json:"kind"`
+	// Version is the resource version, "v2"
+	Version string `json:"version"`
+	// Metadata is resource metadata
+	Metadata teleservices.Metadata `json:"metadata"`
+	// Spec defines the backup schedule
+	Spec BackupScheduleSpecV2 `json:"spec"`
+	// Status describes the most recent backups
+	Status BackupScheduleStatus `json:"status"`
+}
+
+// GetName returns the backup schedule name
+func (r *BackupScheduleV2) GetName() string {
+	return r.Metadata.Name
+}
+
+// SetName sets the backup schedule name
+func (r *BackupScheduleV2) SetName(name string) {
+	r.Metadata.Name = name
+}
+
+// GetMetadata returns the backup schedule metadata
+func (r *BackupScheduleV2) GetMetadata() teleservices.Metadata {
+	return r.Metadata
+}
+
+// SetExpiry sets the backup schedule expiration time
+func (r *BackupScheduleV2) SetExpiry(expires time.Time) {
+	r.Metadata.SetExpiry(expires)
+}
+
+// Expiry returns the backup schedule expiration time
+func (r *BackupScheduleV2) Expiry() time.Time {
+	return r.Metadata.Expiry()
+}
+
+// SetTTL sets the backup schedule TTL
+func (r *BackupScheduleV2) SetTTL(clock clockwork.Clock, ttl time.Duration) {
+	r.Metadata.SetTTL(clock, ttl)
+}
+
+// GetSchedule returns the cron expression that specifies when backups run
+func (r *BackupScheduleV2) GetSchedule() string {
+	return r.Spec.Schedule
+}
+
+// GetTimeout returns the backup hook timeout
+func (r *BackupScheduleV2) GetTimeout() time.Duration {
+	return r.Spec.Timeout.Duration
+}
+
+// GetDestination returns the location backups are shipped to
+func (r *BackupScheduleV2) GetDestination() BackupDestination {
+	return r.Spec.Destination
+}
+
+// GetRetention returns the backup retention policy
+func (r *BackupScheduleV2) GetRetention() BackupRetention {
+	return r.Spec.Retention
+}
+
+// GetStatus returns the status of the most recent backups
+func (r *BackupScheduleV2) GetStatus() BackupScheduleStatus {
+	return r.Status
+}
+
+// SetStatus sets the status of the most recent backups
+func (r *BackupScheduleV2) SetStatus(status BackupScheduleStatus) {
+	r.Status = status
+}
+
+// WithoutSecrets returns a copy of the backup schedule without
+// the S3 secret access key and the cluster API token
+func (r *BackupScheduleV2) WithoutSecrets() BackupSchedule {
+	copy := *r
+	if r.Spec.Destination.S3 != nil {
+		s3 := *r.Spec.Destination.S3
+		s3.SecretAccessKey = ""
+		copy.Spec.Destination.S3 = &s3
+	}
+	if r.Spec.Destination.Cluster != nil {
+		cluster := *r.Spec.Destination.Cluster
+		cluster.Token = ""
+		copy.Spec.Destination.Cluster = &cluster
+	}
+	return &copy
+}
+
+// CheckAndSetDefaults validates the backup schedule and sets defaults
+func (r *BackupScheduleV2) CheckAndSetDefaults() error {
+	if r.Metadata.Name == "" {
+		return trace.BadParameter("missing parameter Name")
+	}
+	if _, err := cron.Parse(r.Spec.Schedule); err != nil {
+		return trace.Wrap(err)
+	}
+	if r.Spec.Timeout.Duration < 0 {
+		return trace.BadParameter("timeout cannot be negative")
+	}
+	if err := r.Spec.Destination.Check(); err != nil {
+		return trace.Wrap(err)
+	}
+	if r.Spec.Retention.Count < 0 || r.Spec.Retention.MaxAge.Duration < 0 {
+		return trace.BadParameter("retention count and age cannot be negative")
+	}
+	if r.Spec.Retention.Count == 0 && r.Spec.Retention.MaxAge.Duration == 0 {
+		r.Spec.Retention.Count = defaults.BackupRetentionCount
+	}
+	return nil
+}
+
+// BackupScheduleSpecV2 defines a scheduled cluster backup
+type BackupScheduleSpecV2 struct {
+	// Schedule is the cron expression that specifies when backups run, in UTC
+	Schedule string `json:"schedule"`
+	// Timeout is the backup hook timeout, the hook default is used if not set
+	Timeout teleservices.Duration `json:"timeout,omitempty"`
+	// Destination is the location backups are shipped to
+	Destination BackupDestination `json:"destination"`
+	// Retention defines how many backups are kept
+	Retention BackupRetention `json:"retention,omitempty"`
+}
+
+// BackupDestination defines the location backups are shipped to.
+// Exactly one destination should be set
+type BackupDestination struct {
+	// S3 ships backups to an S3-compatible bucket
+	S3 *BackupS3Destination `json:"s3,omitempty"`
+	// NFS ships backups to a directory on master nodes, such as an NFS mount
+	NFS *BackupNFSDestination `json:"nfs,omitempty"`
+	// Cluster ships backups to the package service of another cluster
+	Cluster *BackupClusterDestination `json:"cluster,omitempty"`
+}
+
+// Check makes sure exactly one valid destination is set
+func (d BackupDestination) Check() error {
+	var count int
+	if d.S3 != nil {
+		count++
+		if d.S3.Bucket == "" {
+			return trace.BadParameter("missing S3 bucket")
+		}
+		if (d.S3.AccessKeyID == "") != (d.S3.SecretAccessKey == "") {
+			return trace.BadParameter("S3 access key ID and secret access key should be specified together")
+		}
+		if d.S3.Endpoint != "" {
+			if _, err := url.Parse(d.S3.Endpoint); err != nil {
+				return trace.BadParameter("invalid S3 endpoint %q: %v", d.S3.Endpoint, err)
+			}
+		}
+	}
+	if d.NFS != nil {
+		count++
+		if !filepath.IsAbs(d.NFS.Path) {
+			return trace.BadParameter("NFS path should be absolute, got %q", d.NFS.Path)
+		}
+		if rel, err := filepath.Rel(defaults.ClusterBackupsDir, d.NFS.Path); err != nil || strings.HasPrefix(rel, "..") {
+			return trace.BadParameter("NFS path should be inside %v, got %q",
+				defaults.ClusterBackupsDir, d.NFS.Path)
+		}
+	}
+	if d.Cluster != nil {
+		count++
+		if d.Cluster.Address == "" {
+			return trace.BadParameter("missing cluster address")
+		}
+		if d.Cluster.Token == "" {
+			return trace.BadParameter("missing cluster token")
+		}
+	}
+	if count != 1 {
+		return trace.BadParameter("exactly one of s3, nfs or cluster destinations should be specified")
+	}
+	return nil
+}
+
+// String returns a textual representation of the destination
+func (d BackupDestination) String() string {
+	switch {
+	case d.S3 != nil:
+		return fmt.Sprintf("s3://%v", filepath.Join(d.S3.Bucket, d.S3.Prefix))
+	case d.NFS != nil:
+		return d.NFS.Path
+	case d.Cluster != nil:
+		return d.Cluster.Address
+	}
+	return ""
+}
+
+// BackupS3Destination defines an S3-compatible bucket to ship backups to
+type BackupS3Destination struct {
+	// Bucket is the bucket name
+	Bucket string `json:"bucket"`
+	// Prefix is the key prefix backups are stored under
+	Prefix string `json:"prefix,omitempty"`
+	// Region is the bucket region
+	Region string `json:"region,omitempty"`
+	// Endpoint is the address of an S3-compatible store, such as MinIO.
+	// AWS S3 is used if not set
+	Endpoint string `json:"endpoint,omitempty"`
+	// AccessKeyID is the access key ID.
+	// Credentials of the instance are used if not set
+	AccessKeyID string `json:"access_key_id,omitempty"`
+	// SecretAccessKey is the secret access key
+	SecretAccessKey string `json:"secret_access_key,omitempty"`
+}
+
+// BackupNFSDestination defines a directory to ship backups to
+type BackupNFSDestination struct {
+	// Path is the directory on master nodes where backups are stored.
+	// The directory should be inside defaults.ClusterBackupsDir
+	Path string `json:"path"`
+}
+
+// BackupClusterDestination defines another cluster to ship backups to
+type BackupClusterDestination struct {
+	// Address is the address of the cluster package service,
+	// for example https://opscenter.example.com:3009
+	Address string `json:"address"`
+	// Token is the API token used to authenticate with the cluster
+	Token string `json:"token"`
+	// Repository is the package repository backups are stored in.
+	// Defaults to the name of the local cluster
+	Repository string `json:"repository,omitempty"`
+}
+
+// BackupRetention defines how many backups are kept.
+// Backups that exceed either limit are removed after a successful backup
+type BackupRetention struct {
+	// Count is the maximum number of backups to keep
+	Count int `json:"count,omitempty"`
+	// MaxAge is the maximum age of backups to keep
+	MaxAge teleservices.Duration `json:"max_age,omitempty"`
+}
+
+// BackupScheduleStatus describes the most recent scheduled backups
+type BackupScheduleStatus struct {
+	// LastRun is the time the most recent backup started
+	LastRun time.Time `json:"last_run,omitempty"`
+	// LastSuccess is the time the most recent successful backup completed
+	LastSuccess time.Time `json:"last_success,omitempty"`
+	// LastFailure is the time the most recent backup failed
+	LastFailure time.Time `json:"last_failure,omitempty"`
+	// LastError is the error of the most recent failed backup
+	LastError string `json:"last_error,omitempty"`
+	// LastBackup is the name of the most recent successful backup
+	LastBackup string `json:"last_backup,omitempty"`
+}
+
+// IsFailed returns true if the most recent backup has failed
+func (s BackupScheduleStatus) IsFailed() bool {
+	return !s.LastFailure.IsZero() && s.LastFailure.After(s.LastSuccess)
+}
+
+// UnmarshalBackupSchedule unmarshals backup schedule from JSON or YAML
+func UnmarshalBackupSchedule(data []byte) (BackupSchedule, error) {
+	if len(data) == 0 {
+		return nil, trace.BadParameter("empty input")
+	}
+	jsonData, err := teleutils.ToJSON(data)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var header teleservices.ResourceHeader
+	if err := json.Unmarshal(jsonData, &header); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	switch header.Version {
+	case teleservices.V2:
+		var schedule BackupScheduleV2
+		err := teleutils.UnmarshalWithSchema(GetBackupScheduleSchema(), &schedule, jsonData)
+		if err != nil {
+			return nil, trace.BadParameter("%v", err)
+		}
+		schedule.Metadata.CheckAndSetDefaults()
+		if err := schedule.CheckAndSetDefaults(); err != nil {
+			return nil, trace.Wrap(err)
+		}
+		return &schedule, nil
+	}
+	return nil, trace.BadParameter(
+		"%v resource version %q is not supported", KindBackupSchedule, header.Version)
+}
+
+// MarshalBackupSchedule marshals backup schedule into JSON
+func MarshalBackupSchedule(schedule BackupSchedule, opts ...teleservices.MarshalOption) ([]byte, error) {
+	return json.Marshal(schedule)
+}
+
+// GetBackupScheduleSchema returns backup schedule schema for version V2
+func GetBackupScheduleSchema() string {
+	return fmt.Sprintf(BackupScheduleV2SchemaTemplate, teleservices.MetadataSchema,
+		BackupScheduleSpecV2Schema, BackupScheduleStatusSchema)
+}
+
+// BackupScheduleV2SchemaTemplate is JSON schema template for the backup
+// schedule resource which, unlike most resources, has a status
+const BackupScheduleV2SchemaTemplate = `{
+  "type": "object",
+  "additionalProperties": false,
+  "required": ["kind", "spec", "metadata", "version"],
+  "properties": {
+    "kind": {"type": "string"},
+    "version": {"type": "string", "default": "v2"},
+    "metadata": %v,
+    "spec": %v,
+    "status": %v
+  }
+}`
+
+// BackupScheduleSpecV2Schema is JSON schema for backup schedule spec
+const BackupScheduleSpecV2Schema = `{
+  "type": "object",
+  "additionalProperties": false,
+  "required": ["schedule", "destination"],
+  "properties": {
+    "schedule": {"type": "string"},
+    "timeout": {"type": "string"},
+    "destination": {
+      "type": "object",
+      "additionalProperties": false,
+      "properties": {
+        "s3": {
+          "type": "object",
+          "additionalProperties": false,
+          "required": ["bucket"],
+          "properties": {
+            "bucket": {"type": "string"},
+            "prefix": {"type": "string"},
+            "region": {"type": "string"},
+            "endpoint": {"type": "string"},
+            "access_key_id": {"type": "string"},
+            "secret_access_key": {"type": "string"}
+          }
+        },
+        "nfs": {
+          "type": "object",
+          "additionalProperties": false,
+          "required": ["path"],
+          "properties": {
+            "path": {"type": "string"}
+          }
+        },
+        "cluster": {
+          "type": "object",
+          "additionalProperties": false,
+          "required": ["address", "token"],
+          "properties": {
+            "address": {"type": "string"},
+            "token": {"type": "string"},
+            "repository": {"type": "string"}
+          }
+        }
+      }
+    },
+    "retention": {
+      "type": "object",
+      "additionalProperties": false,
+      "properties": {
+        "count": {"type": "integer"},
+        "max_age": {"type": "string"}
+      }
+    }
+  }
+}`
+
+// BackupScheduleStatusSchema is JSON schema for backup schedule status
+const BackupScheduleStatusSchema = `{
+  "type": "object",
+  "additionalProperties": false,
+  "properties": {
+    "last_run": {"type": "string"},
+    "last_success": {"type": "string"},
+    "last_failure": {"type": "string"},
+    "last_error": {"type": "string"},
+    "last_backup": {"type": "string"}
+  }
+}`
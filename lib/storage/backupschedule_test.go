@@ -0,0 +1,186 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"time"
+
+	"github.com/gravitational/gravity/lib/compare"
+	"github.com/gravitational/gravity/lib/defaults"
+
+	teleservices "github.com/gravitational/teleport/lib/services"
+	. "gopkg.in/check.v1"
+)
+
+type BackupScheduleSuite struct{}
+
+var _ = Suite(&BackupScheduleSuite{})
+
+func (s *BackupScheduleSuite) TestParsesBackupSchedule(c *C) {
+	schedule, err := UnmarshalBackupSchedule([]byte(`kind: backupschedule
+version: v2
+metadata:
+  name: nightly
+spec:
+  schedule: "0 2 * * *"
+  timeout: 30m
+  destination:
+    s3:
+      bucket: backups
+      prefix: cluster
+      endpoint: https://minio.example.com:9000
+      access_key_id: key
+      secret_access_key: secret
+  retention:
+    max_age: 720h`))
+	c.Assert(err, IsNil)
+	compare.DeepCompare(c, schedule, NewBackupSchedule("nightly", BackupScheduleSpecV2{
+		Schedule: "0 2 * * *",
+		Timeout:  teleservices.NewDuration(30 * time.Minute),
+		Destination: BackupDestination{
+			S3: &BackupS3Destination{
+				Bucket:          "backups",
+				Prefix:          "cluster",
+				Endpoint:        "https://minio.example.com:9000",
+				AccessKeyID:     "key",
+				SecretAccessKey: "secret",
+			},
+		},
+		Retention: BackupRetention{
+			MaxAge: teleservices.NewDuration(720 * time.Hour),
+		},
+	}))
+
+	status := BackupScheduleStatus{
+		LastRun:     time.Date(2018, time.June, 15, 2, 0, 0, 0, time.UTC),
+		LastSuccess: time.Date(2018, time.June, 15, 2, 5, 0, 0, time.UTC),
+		LastBackup:  "nightly-20180615020000.tar.gz",
+	}
+	schedule.SetStatus(status)
+	data, err := MarshalBackupSchedule(schedule)
+	c.Assert(err, IsNil)
+	schedule, err = UnmarshalBackupSchedule(data)
+	c.Assert(err, IsNil)
+	c.Assert(schedule.GetStatus(), DeepEquals, status)
+}
+
+func (s *BackupScheduleSuite) TestValidatesBackupSchedule(c *C) {
+	testCases := []struct {
+		spec    BackupScheduleSpecV2
+		error   string
+		comment string
+	}{
+		{
+			spec: BackupScheduleSpecV2{
+				Schedule: "@daily",
+				Destination: BackupDestination{
+					NFS: &BackupNFSDestination{Path: defaults.ClusterBackupsDir + "/cluster"},
+				},
+			},
+			comment: "NFS destination",
+		},
+		{
+			spec: BackupScheduleSpecV2{
+				Schedule: "0 2 * *",
+				Destination: BackupDestination{
+					S3: &BackupS3Destination{Bucket: "backups"},
+				},
+			},
+			error:   `expected 5 fields in schedule .*`,
+			comment: "invalid schedule",
+		},
+		{
+			spec:    BackupScheduleSpecV2{Schedule: "@daily"},
+			error:   "exactly one of s3, nfs or cluster destinations should be specified",
+			comment: "missing destination",
+		},
+		{
+			spec: BackupScheduleSpecV2{
+				Schedule: "@daily",
+				Destination: BackupDestination{
+					S3:  &BackupS3Destination{Bucket: "backups"},
+					NFS: &BackupNFSDestination{Path: defaults.ClusterBackupsDir},
+				},
+			},
+			error:   "exactly one of s3, nfs or cluster destinations should be specified",
+			comment: "multiple destinations",
+		},
+		{
+			spec: BackupScheduleSpecV2{
+				Schedule: "@daily",
+				Destination: BackupDestination{
+					NFS: &BackupNFSDestination{Path: "/etc"},
+				},
+			},
+			error:   `NFS path should be inside .*`,
+			comment: "NFS path outside of backups directory",
+		},
+		{
+			spec: BackupScheduleSpecV2{
+				Schedule: "@daily",
+				Destination: BackupDestination{
+					Cluster: &BackupClusterDestination{Address: "https://opscenter.example.com:3009"},
+				},
+			},
+			error:   "missing cluster token",
+			comment: "cluster without token",
+		},
+	}
+	for _, tc := range testCases {
+		comment := Commentf(tc.comment)
+		schedule := NewBackupSchedule("backup", tc.spec)
+		err := schedule.CheckAndSetDefaults()
+		if tc.error != "" {
+			c.Assert(err, ErrorMatches, tc.error, comment)
+			continue
+		}
+		c.Assert(err, IsNil, comment)
+		c.Assert(schedule.GetRetention().Count, Equals, defaults.BackupRetentionCount, comment)
+	}
+}
+
+func (s *BackupScheduleSuite) TestRemovesSecrets(c *C) {
+	schedule := NewBackupSchedule("nightly", BackupScheduleSpecV2{
+		Schedule: "@daily",
+		Destination: BackupDestination{
+			S3: &BackupS3Destination{
+				Bucket:          "backups",
+				AccessKeyID:     "key",
+				SecretAccessKey: "secret",
+			},
+		},
+	})
+	redacted := schedule.WithoutSecrets()
+	c.Assert(redacted.GetDestination().S3, DeepEquals, &BackupS3Destination{
+		Bucket:      "backups",
+		AccessKeyID: "key",
+	})
+	// The original schedule is not modified
+	c.Assert(schedule.GetDestination().S3.SecretAccessKey, Equals, "secret")
+
+	schedule = NewBackupSchedule("remote", BackupScheduleSpecV2{
+		Schedule: "@daily",
+		Destination: BackupDestination{
+			Cluster: &BackupClusterDestination{
+				Address: "https://opscenter.example.com:3009",
+				Token:   "token",
+			},
+		},
+	})
+	c.Assert(schedule.WithoutSecrets().GetDestination().Cluster.Token, Equals, "")
+	c.Assert(schedule.GetDestination().Cluster.Token, Equals, "token")
+}
@@ -0,0 +1,69 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package keyval
+
+import (
+	"sort"
+
+	"github.com/gravitational/gravity/lib/storage"
+
+	"github.com/gravitational/trace"
+	"github.com/pborman/uuid"
+)
+
+func (b *backend) CreateAuditEvent(e storage.AuditEvent) (*storage.AuditEvent, error) {
+	if err := e.Check(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if e.ID == "" {
+		e.ID = uuid.New()
+	}
+	err := b.createVal(b.key(auditP, e.ID), e, b.ttl(e.Expires))
+	if err != nil {
+		if trace.IsAlreadyExists(err) {
+			return nil, trace.Wrap(err, "audit event %v already exists", e.ID)
+		}
+		return nil, trace.Wrap(err)
+	}
+	return &e, nil
+}
+
+func (b *backend) GetAuditEvents(filter storage.AuditEventFilter) ([]storage.AuditEvent, error) {
+	ids, err := b.getKeys(b.key(auditP))
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var events []storage.AuditEvent
+	for _, id := range ids {
+		var e storage.AuditEvent
+		err := b.getVal(b.key(auditP, id), &e)
+		if err != nil {
+			if trace.IsNotFound(err) {
+				// the event has expired
+				continue
+			}
+			return nil, trace.Wrap(err)
+		}
+		if filter.Match(e) {
+			events = append(events, e)
+		}
+	}
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].Time.Before(events[j].Time)
+	})
+	return events, nil
+}
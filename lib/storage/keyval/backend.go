@@ -117,3 +117,45 @@ func (*v1codec) DecodeFromBytes(data []byte, in interface{}) error {
 	}
 	return nil
 }
+
+// v3codec is codec designed for etcd 3.x series that support binary
+// data, so values are stored as JSON without additional encoding
+type v3codec struct {
+}
+
+func (*v3codec) EncodeBytesToString(data []byte) (string, error) {
+	return string(data), nil
+}
+
+func (*v3codec) EncodeToString(val interface{}) (string, error) {
+	data, err := json.Marshal(val)
+	if err != nil {
+		return "", trace.Wrap(err, "failed to encode object")
+	}
+	return string(data), nil
+}
+
+func (*v3codec) EncodeToBytes(val interface{}) ([]byte, error) {
+	data, err := json.Marshal(val)
+	if err != nil {
+		return nil, trace.Wrap(err, "failed to encode object")
+	}
+	return data, nil
+}
+
+func (*v3codec) DecodeBytesFromString(val string) ([]byte, error) {
+	return []byte(val), nil
+}
+
+func (*v3codec) DecodeFromString(val string, in interface{}) error {
+	return trace.Wrap((&v3codec{}).DecodeFromBytes([]byte(val), in))
+}
+
+func (*v3codec) DecodeFromBytes(data []byte, in interface{}) error {
+	err := json.Unmarshal(data, &in)
+	if err != nil {
+		log.Errorf("failed to decode: %s", data)
+		return trace.Wrap(err)
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package keyval
+
+import (
+	"github.com/gravitational/gravity/lib/storage"
+
+	"github.com/gravitational/trace"
+)
+
+func (b *backend) UpsertBackupSchedule(schedule storage.BackupSchedule) error {
+	if err := schedule.CheckAndSetDefaults(); err != nil {
+		return trace.Wrap(err)
+	}
+	bytes, err := storage.MarshalBackupSchedule(schedule)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	err = b.upsertValBytes(b.key(backupSchedulesP, schedule.GetName()),
+		bytes, b.ttl(schedule.Expiry()))
+	return trace.Wrap(err)
+}
+
+func (b *backend) GetBackupSchedule(name string) (storage.BackupSchedule, error) {
+	bytes, err := b.getValBytes(b.key(backupSchedulesP, name))
+	if err != nil {
+		if trace.IsNotFound(err) {
+			return nil, trace.NotFound("backup schedule %q not found", name)
+		}
+		return nil, trace.Wrap(err)
+	}
+	schedule, err := storage.UnmarshalBackupSchedule(bytes)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return schedule, nil
+}
+
+func (b *backend) GetBackupSchedules() ([]storage.BackupSchedule, error) {
+	names, err := b.getKeys(b.key(backupSchedulesP))
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var schedules []storage.BackupSchedule
+	for _, name := range names {
+		schedule, err := b.GetBackupSchedule(name)
+		if err != nil {
+			if trace.IsNotFound(err) {
+				continue
+			}
+			return nil, trace.Wrap(err)
+		}
+		schedules = append(schedules, schedule)
+	}
+	return schedules, nil
+}
+
+func (b *backend) DeleteBackupSchedule(name string) error {
+	err := b.deleteKey(b.key(backupSchedulesP, name))
+	if err != nil {
+		if trace.IsNotFound(err) {
+			return trace.NotFound("backup schedule %q not found", name)
+		}
+		return trace.Wrap(err)
+	}
+	return nil
+}
@@ -25,6 +25,7 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/gravitational/gravity/lib/constants"
 	"github.com/gravitational/gravity/lib/defaults"
 	"github.com/gravitational/gravity/lib/storage"
 	"github.com/gravitational/gravity/lib/utils"
@@ -56,7 +57,7 @@ func NewBolt(cfg BoltConfig) (storage.Backend, error) {
 	}
 	return &backend{
 		Clock:    clock,
-		kvengine: engine,
+		kvengine: withMetrics(engine, constants.BoltBackend),
 	}, nil
 }
 
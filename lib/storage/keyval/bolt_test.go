@@ -240,3 +240,11 @@ func (s *BSuite) TestClusterLogin(c *C) {
 func (s *BSuite) TestIndexFile(c *C) {
 	s.suite.IndexFile(c)
 }
+
+func (s *BSuite) TestBackupSchedulesCRUD(c *C) {
+	s.suite.BackupSchedulesCRUD(c)
+}
+
+func (s *BSuite) TestAuditEvents(c *C) {
+	s.suite.AuditEvents(c)
+}
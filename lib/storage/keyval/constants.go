@@ -71,6 +71,8 @@ const (
 	localClusterP               = "localcluster"
 	planP                       = "plan"
 	trustedClustersP            = "trustedclusters"
+	backupSchedulesP            = "backupschedules"
+	auditP                      = "audit"
 	tunnelConnectionsP          = "tunnelconnections"
 	remoteClustersP             = "remoteclusters"
 	systemP                     = "system"
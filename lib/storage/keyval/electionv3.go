@@ -0,0 +1,302 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package keyval
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/coreos/etcd/clientv3"
+	"github.com/coreos/etcd/mvcc/mvccpb"
+	"github.com/gravitational/trace"
+	log "github.com/sirupsen/logrus"
+)
+
+// newV3Leader returns a new leader election client on top of etcd v3 API
+func newV3Leader(client *clientv3.Client) *v3Leader {
+	return &v3Leader{
+		client: client,
+		pauseC: make(chan bool),
+		closeC: make(chan struct{}),
+	}
+}
+
+// v3Leader implements leader election with etcd v3 leases.
+//
+// The elected voter holds the election key attached to the lease of
+// its session with the duration of the term and the session keeps
+// the lease alive. Once the lease is lost or revoked, the key is removed
+// and another voter can be elected.
+// The voter that has lost its session or the election key
+// gives up the leadership before campaigning again
+type v3Leader struct {
+	client *clientv3.Client
+	pauseC chan bool
+	closeC chan struct{}
+	once   sync.Once
+}
+
+// AddWatch starts watching the key for changes and sending them
+// to the valuesC
+func (l *v3Leader) AddWatch(key string, retry time.Duration, valuesC chan string) {
+	go func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go func() {
+			<-l.closeC
+			cancel()
+		}()
+		var sent *string
+		send := func(value string) bool {
+			if value == "" || (sent != nil && *sent == value) {
+				return true
+			}
+			select {
+			case valuesC <- value:
+				sent = &value
+				return true
+			case <-l.closeC:
+				return false
+			}
+		}
+		for {
+			resp, err := l.client.Get(ctx, key)
+			if err == nil {
+				if len(resp.Kvs) != 0 && !send(string(resp.Kvs[0].Value)) {
+					return
+				}
+				watchC := l.client.Watch(clientv3.WithRequireLeader(ctx), key,
+					clientv3.WithRev(resp.Header.Revision+1))
+				for watchResp := range watchC {
+					if watchResp.Err() != nil {
+						log.Debugf("Watch on %v failed: %v.", key, watchResp.Err())
+						break
+					}
+					for _, event := range watchResp.Events {
+						if event.Type == mvccpb.PUT && !send(string(event.Kv.Value)) {
+							return
+						}
+					}
+				}
+			} else {
+				log.Debugf("Failed to get value of %v: %v.", key, err)
+			}
+			select {
+			case <-time.After(retry):
+			case <-l.closeC:
+				return
+			}
+		}
+	}()
+}
+
+// AddVoter starts a goroutine that attempts to set the specified key to
+// the given value with the session lease for the duration of the term.
+// Once elected, the voter keeps the session alive until asked to step down
+func (l *v3Leader) AddVoter(ctx context.Context, key, value string, term time.Duration) error {
+	if value == "" {
+		return trace.BadParameter("voter value for key cannot be empty")
+	}
+	if term < time.Second {
+		return trace.BadParameter("term cannot be < 1s")
+	}
+	go func() {
+		voter := &v3Voter{client: l.client, key: key, value: value, term: term}
+		ticker := time.NewTicker(term / 5)
+		defer ticker.Stop()
+		defer voter.resign()
+		for {
+			if err := voter.campaign(ctx); err != nil {
+				log.Debugf("Voter error: %v.", err)
+			}
+			select {
+			case <-l.pauseC:
+				log.Debug("Was asked to step down, pausing heartbeat.")
+				voter.resign()
+				select {
+				case <-time.After(term * 2):
+				case <-l.closeC:
+					return
+				case <-ctx.Done():
+					log.Debugf("Removing voter for %v.", value)
+					return
+				}
+			case <-voter.lost():
+				log.Debugf("Lost lease for %v.", key)
+			case <-ticker.C:
+			case <-l.closeC:
+				return
+			case <-ctx.Done():
+				log.Debugf("Removing voter for %v.", value)
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+// StepDown makes the voter pause its attempts to re-elect itself
+// thus giving up its leadership
+func (l *v3Leader) StepDown() {
+	l.pauseC <- true
+}
+
+// Close stops all watches and voters
+func (l *v3Leader) Close() error {
+	l.once.Do(func() {
+		close(l.closeC)
+	})
+	return nil
+}
+
+// v3Voter is a single participant of the election
+type v3Voter struct {
+	client *clientv3.Client
+	key    string
+	value  string
+	term   time.Duration
+	// session holds the lease of the election key if this voter is elected
+	session *v3Session
+}
+
+// campaign attempts to become the leader unless this voter
+// has already been elected and still holds the election key
+func (v *v3Voter) campaign(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, v.term)
+	defer cancel()
+	resp, err := v.client.Get(ctx, v.key)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if v.session != nil {
+		if v.session.isAlive() && len(resp.Kvs) != 0 &&
+			clientv3.LeaseID(resp.Kvs[0].Lease) == v.session.leaseID {
+			return nil
+		}
+		log.Debugf("Lost leadership for %v.", v.key)
+		v.resign()
+	}
+	var cmp clientv3.Cmp
+	switch {
+	case len(resp.Kvs) == 0:
+		cmp = clientv3.Compare(clientv3.CreateRevision(v.key), "=", 0)
+	case string(resp.Kvs[0].Value) == v.value:
+		// the key has been set by this voter before restart,
+		// take over the key with a new session
+		cmp = clientv3.Compare(clientv3.ModRevision(v.key), "=", resp.Kvs[0].ModRevision)
+	default:
+		return nil
+	}
+	session, err := newV3Session(ctx, v.client, v.term)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	txnResp, err := v.client.Txn(ctx).
+		If(cmp).
+		Then(clientv3.OpPut(v.key, v.value, clientv3.WithLease(session.leaseID))).
+		Commit()
+	if err == nil && txnResp.Succeeded {
+		log.Debugf("Candidate(key=%v, value=%v, term=%v) successfully elected.", v.key, v.value, v.term)
+		v.session = session
+		return nil
+	}
+	session.close()
+	return trace.Wrap(err)
+}
+
+// lost returns the channel that is closed once the elected voter loses its session
+func (v *v3Voter) lost() <-chan struct{} {
+	if v.session == nil {
+		return nil
+	}
+	return v.session.doneC
+}
+
+// resign gives up the leadership by revoking the session lease
+// which removes the election key
+func (v *v3Voter) resign() {
+	if v.session == nil {
+		return
+	}
+	v.session.close()
+	v.session = nil
+}
+
+// newV3Session grants a lease with the specified TTL and keeps it alive
+// until the session is closed or the lease is lost
+func newV3Session(ctx context.Context, client *clientv3.Client, ttl time.Duration) (*v3Session, error) {
+	lease, err := client.Grant(ctx, leaseTTL(ttl))
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	keepAliveCtx, cancel := context.WithCancel(context.Background())
+	keepAliveC, err := client.KeepAlive(keepAliveCtx, lease.ID)
+	if err != nil {
+		cancel()
+		revokeLease(client, lease.ID, ttl)
+		return nil, trace.Wrap(err)
+	}
+	session := &v3Session{
+		client:  client,
+		leaseID: lease.ID,
+		ttl:     ttl,
+		cancel:  cancel,
+		doneC:   make(chan struct{}),
+	}
+	go func() {
+		defer close(session.doneC)
+		// the channel is closed once the lease cannot be kept alive
+		for range keepAliveC {
+		}
+	}()
+	return session, nil
+}
+
+// v3Session is a lease kept alive in background
+type v3Session struct {
+	client  *clientv3.Client
+	leaseID clientv3.LeaseID
+	ttl     time.Duration
+	cancel  context.CancelFunc
+	// doneC is closed once the lease is no longer kept alive
+	doneC chan struct{}
+}
+
+// isAlive returns true if the session lease is still being kept alive
+func (s *v3Session) isAlive() bool {
+	select {
+	case <-s.doneC:
+		return false
+	default:
+		return true
+	}
+}
+
+// close stops keeping the lease alive and revokes it
+func (s *v3Session) close() {
+	s.cancel()
+	revokeLease(s.client, s.leaseID, s.ttl)
+}
+
+func revokeLease(client *clientv3.Client, leaseID clientv3.LeaseID, timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if _, err := client.Revoke(ctx, leaseID); err != nil {
+		log.Debugf("Failed to revoke lease %v: %v.", leaseID, err)
+	}
+}
@@ -24,6 +24,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/gravitational/gravity/lib/constants"
 	"github.com/gravitational/gravity/lib/defaults"
 	"github.com/gravitational/gravity/lib/state"
 	"github.com/gravitational/gravity/lib/utils"
@@ -38,22 +39,37 @@ import (
 	"golang.org/x/net/context"
 )
 
-// NewETCD returns new ETCD-backed engine
+// NewETCD returns new ETCD-backed engine.
+// The etcd API version is selected with the APIVersion configuration field:
+// in automatic mode the backend uses the API the data has been migrated to
+// and follows the migration started with MigrateToV3
 func NewETCD(cfg ETCDConfig) (*electingBackend, error) {
 	if err := cfg.Check(); err != nil {
 		return nil, trace.Wrap(err)
 	}
 
-	engine, err := newEngine(cfg, &v1codec{})
-	if err != nil {
-		return nil, trace.Wrap(err)
-	}
-
 	clock := cfg.Clock
 	if clock == nil {
 		clock = clockwork.NewRealClock()
 	}
 
+	switch cfg.APIVersion {
+	case APIVersionV2:
+		return newETCDv2(cfg, clock)
+	case APIVersionV3:
+		return newETCDv3(cfg, clock)
+	default:
+		return newETCDAuto(cfg, clock)
+	}
+}
+
+// newETCDv2 returns a new backend that stores data using the etcd v2 API
+func newETCDv2(cfg ETCDConfig, clock clockwork.Clock) (*electingBackend, error) {
+	engine, err := newEngine(cfg, &v1codec{})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+
 	leader, err := leader.NewClient(leader.Config{Client: engine.client, Clock: clock})
 	if err != nil {
 		return nil, trace.Wrap(err)
@@ -62,7 +78,7 @@ func NewETCD(cfg ETCDConfig) (*electingBackend, error) {
 	return &electingBackend{
 		Backend: &backend{
 			Clock:    clock,
-			kvengine: engine,
+			kvengine: withMetrics(engine, constants.ETCDBackend),
 		},
 		Leader: leader,
 		client: engine.client,
@@ -78,8 +94,19 @@ type ETCDConfig struct {
 	TLSCertFile   string          `json:"tls_cert_file" yaml:"tls_cert_file"`
 	TLSCAFile     string          `json:"tls_ca_file" yaml:"tls_ca_file"`
 	RetryInterval time.Duration   `json:"retry_interval" yaml:"retry_interval"`
+	// APIVersion selects the etcd API version, empty for automatic mode
+	APIVersion string `json:"api_version,omitempty" yaml:"api_version,omitempty"`
 }
 
+const (
+	// APIVersionAuto selects the etcd API version the backend data has been migrated to
+	APIVersionAuto = ""
+	// APIVersionV2 selects the etcd v2 API
+	APIVersionV2 = "v2"
+	// APIVersionV3 selects the etcd v3 API
+	APIVersionV3 = "v3"
+)
+
 // LocalEtcdConfig returns config for local etcd
 func LocalEtcdConfig(retryTimeout time.Duration) (*ETCDConfig, error) {
 	stateDir, err := state.GetStateDir()
@@ -115,6 +142,11 @@ func (cfg *ETCDConfig) Check() error {
 	if cfg.TLSCertFile == "" {
 		return trace.BadParameter(`TLSCertFile: please supply a path to TLS certificate file`)
 	}
+	switch cfg.APIVersion {
+	case APIVersionAuto, APIVersionV2, APIVersionV3:
+	default:
+		return trace.BadParameter(`APIVersion: unsupported etcd API version %q, expected "v2" or "v3"`, cfg.APIVersion)
+	}
 	return nil
 }
 
@@ -149,8 +181,13 @@ type engine struct {
 }
 
 func (e *engine) key(prefix string, keys ...string) key {
-	key := make([]string, 0, len(e.etcdKey)+len(keys)+1)
-	key = append(key, e.etcdKey...)
+	return makeKey(e.etcdKey, prefix, keys...)
+}
+
+// makeKey returns the key for the specified path under the root key
+func makeKey(root []string, prefix string, keys ...string) key {
+	key := make([]string, 0, len(root)+len(keys)+1)
+	key = append(key, root...)
 	key = append(key, prefix)
 	key = append(key, keys...)
 	for i := range key {
@@ -21,7 +21,9 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/gravitational/gravity/lib/defaults"
 	"github.com/gravitational/gravity/lib/storage"
@@ -31,6 +33,7 @@ import (
 	"github.com/gravitational/trace"
 
 	"github.com/coreos/etcd/client"
+	"github.com/coreos/etcd/clientv3"
 	"github.com/jonboulle/clockwork"
 	log "github.com/sirupsen/logrus"
 	"golang.org/x/net/context"
@@ -40,35 +43,54 @@ import (
 func TestETCD(t *testing.T) { TestingT(t) }
 
 type ESuite struct {
-	backend *tempBackend
-	suite   suite.StorageSuite
+	apiVersion string
+	backend    *tempBackend
+	suite      suite.StorageSuite
 }
 
-var _ = Suite(&ESuite{})
+var _ = Suite(&ESuite{apiVersion: APIVersionV2})
+var _ = Suite(&ESuite{apiVersion: APIVersionV3})
+
+// The backend in automatic mode is tested in dual mode:
+// it writes with the v2 API and mirrors the writes into the v3 keyspace
+var _ = Suite(&ESuite{apiVersion: APIVersionAuto})
 
 // tempBackend helps to create and destroy ad-hock
 // databases in Etcd
 type tempBackend struct {
 	api     client.KeysAPI
+	v3      *clientv3.Client
 	prefix  string
 	clock   clockwork.FakeClock
 	backend storage.Backend
 }
 
 func (t *tempBackend) Delete() error {
-	var err error
+	if t.v3 != nil {
+		_, err := t.v3.Delete(context.Background(), t.prefix+"/", clientv3.WithPrefix())
+		if err != nil {
+			return trace.Wrap(err)
+		}
+	}
 	if t.api != nil {
-		_, err = t.api.Delete(context.Background(), t.prefix, &client.DeleteOptions{Recursive: true, Dir: true})
+		_, err := t.api.Delete(context.Background(), t.prefix, &client.DeleteOptions{Recursive: true, Dir: true})
 		err = convertErr(err)
 		if err != nil && !trace.IsNotFound(err) {
 			return trace.Wrap(err)
 		}
-		return nil
 	}
 	return nil
 }
 
-func newBackend(configJSON string) (*tempBackend, error) {
+// engineOf returns the engine of the specified backend
+func engineOf(b storage.Backend) kvengine {
+	if electing, ok := b.(*electingBackend); ok {
+		b = electing.Backend
+	}
+	return b.(*backend).kvengine.(*meteredEngine).kvengine
+}
+
+func newBackend(configJSON, apiVersion string) (*tempBackend, error) {
 	if configJSON == "" {
 		return nil, trace.BadParameter("missing ETCD configuration")
 	}
@@ -84,15 +106,73 @@ func newBackend(configJSON string) (*tempBackend, error) {
 	}
 
 	cfg.Key = fmt.Sprintf("%v/%v", cfg.Key, token)
+	cfg.APIVersion = apiVersion
+
+	if apiVersion == APIVersionAuto {
+		if err := setDualMode(cfg); err != nil {
+			return nil, trace.Wrap(err)
+		}
+	}
 
 	b, err := NewETCD(cfg)
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
 
+	switch engine := engineOf(b).(type) {
+	case *v3engine:
+		return &tempBackend{prefix: cfg.Key, v3: engine.client, clock: fakeClock, backend: b}, nil
+	case *switchingEngine:
+		return &tempBackend{prefix: cfg.Key, api: b.api(), v3: engine.v3.client, clock: fakeClock, backend: b}, nil
+	}
 	return &tempBackend{prefix: cfg.Key, api: b.api(), clock: fakeClock, backend: b}, nil
 }
 
+// setDualMode switches the storage with the specified configuration
+// to dual mode
+func setDualMode(cfg ETCDConfig) error {
+	v3, err := newV3Engine(cfg, &v3codec{})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	defer v3.Close()
+	return trace.Wrap(setStorageMode(context.TODO(), v3, storageDual))
+}
+
+// checkMirrored verifies that the v3 keyspace of the backend
+// in dual mode matches its v2 tree
+func checkMirrored(c *C, engine *switchingEngine) {
+	root := strings.Join(engine.v3.etcdKey, "/")
+	expected := make(map[string]*client.Node)
+	resp, err := engine.v2.Get(context.TODO(), root, &client.GetOptions{Recursive: true})
+	err = convertErr(err)
+	if !trace.IsNotFound(err) {
+		c.Assert(err, IsNil)
+		flattenTree(resp.Node, expected)
+	}
+	delete(expected, root+"/")
+	actual, err := engine.v3.client.Get(context.TODO(), root+"/", clientv3.WithPrefix())
+	c.Assert(err, IsNil)
+	versionKey := storageVersionKeyFor(engine.v3)
+	for _, kv := range actual.Kvs {
+		key := string(kv.Key)
+		if strings.HasPrefix(key, versionKey) {
+			continue
+		}
+		node, ok := expected[key]
+		c.Assert(ok, Equals, true, Commentf("%v is not in v2 tree", key))
+		c.Assert(string(kv.Value), Equals, nodeValue(node), Commentf("%v", key))
+		delete(expected, key)
+	}
+	for key, node := range expected {
+		// the directories created implicitly with the v2 API
+		// are not mirrored
+		if !node.Dir {
+			c.Errorf("%v has not been mirrored", key)
+		}
+	}
+}
+
 func (s *ESuite) SetUpTest(c *C) {
 	log.SetOutput(os.Stderr)
 
@@ -104,7 +184,7 @@ func (s *ESuite) SetUpTest(c *C) {
 	}
 
 	var err error
-	s.backend, err = newBackend(os.Getenv(defaults.TestETCDConfig))
+	s.backend, err = newBackend(os.Getenv(defaults.TestETCDConfig), s.apiVersion)
 	c.Assert(err, IsNil)
 
 	s.suite.Backend = s.backend.backend
@@ -113,6 +193,9 @@ func (s *ESuite) SetUpTest(c *C) {
 
 func (s *ESuite) TearDownTest(c *C) {
 	if s.backend != nil {
+		if engine, ok := engineOf(s.backend.backend).(*switchingEngine); ok {
+			checkMirrored(c, engine)
+		}
 		err := s.backend.Delete()
 		if err != nil {
 			log.Error(trace.DebugReport(err))
@@ -264,3 +347,73 @@ func (s *ESuite) TestClusterLogin(c *C) {
 func (s *ESuite) TestIndexFile(c *C) {
 	s.suite.IndexFile(c)
 }
+
+func (s *ESuite) TestBackupSchedulesCRUD(c *C) {
+	s.suite.BackupSchedulesCRUD(c)
+}
+
+func (s *ESuite) TestAuditEvents(c *C) {
+	s.suite.AuditEvents(c)
+}
+
+func (s *ESuite) TestDirectoryExpires(c *C) {
+	engine := engineOf(s.backend.backend)
+	dir := engine.key("expiring")
+	child := engine.key("expiring", "child")
+	c.Assert(engine.createDir(dir, 2*time.Second), IsNil)
+	c.Assert(engine.upsertValBytes(child, []byte("value"), forever), IsNil)
+	keys, err := engine.getKeys(dir)
+	c.Assert(err, IsNil)
+	c.Assert(keys, DeepEquals, []string{"child"})
+
+	waitExpired(c, engine, child)
+	keys, err = engine.getKeys(dir)
+	if !trace.IsNotFound(err) {
+		c.Assert(err, IsNil)
+		c.Assert(keys, HasLen, 0)
+	}
+	// the directory is gone as well
+	c.Assert(engine.createDir(dir, forever), IsNil)
+}
+
+func (s *ESuite) TestDirectoryKeepsKeysOnTTLUpdate(c *C) {
+	engine := engineOf(s.backend.backend)
+	dir := engine.key("expiring")
+	child := engine.key("expiring", "child")
+	c.Assert(engine.upsertDir(dir, 2*time.Second), IsNil)
+	c.Assert(engine.upsertValBytes(child, []byte("value"), forever), IsNil)
+
+	// the directory is refreshed with the same TTL and then made permanent
+	c.Assert(engine.upsertDir(dir, 2*time.Second), IsNil)
+	c.Assert(engine.upsertDir(dir, forever), IsNil)
+	time.Sleep(4 * time.Second)
+	data, err := engine.getValBytes(child)
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, "value")
+
+	// the child expires with the directory once it has a TTL again
+	c.Assert(engine.updateTTL(dir, 2*time.Second), IsNil)
+	waitExpired(c, engine, child)
+}
+
+// waitExpired waits until the specified key has expired
+func waitExpired(c *C, engine kvengine, key key) {
+	if switching, ok := engine.(*switchingEngine); ok {
+		// the mirrored key expires with its own lease
+		waitExpired(c, switching.v2, key)
+		waitExpired(c, switching.v3, key)
+		return
+	}
+	deadline := time.Now().Add(15 * time.Second)
+	for {
+		_, err := engine.getValBytes(key)
+		if trace.IsNotFound(err) {
+			return
+		}
+		c.Assert(err, IsNil)
+		if time.Now().After(deadline) {
+			c.Fatalf("%v has not expired", ekey(key))
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+}
@@ -0,0 +1,985 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package keyval
+
+import (
+	"context"
+	"encoding/base64"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gravitational/gravity/lib/constants"
+	"github.com/gravitational/gravity/lib/storage"
+
+	"github.com/coreos/etcd/client"
+	"github.com/coreos/etcd/clientv3"
+	"github.com/coreos/etcd/mvcc/mvccpb"
+	"github.com/gravitational/coordinate/leader"
+	"github.com/gravitational/trace"
+	"github.com/jonboulle/clockwork"
+	"github.com/pborman/uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// storageVersionKey is the name of the v3 key under the backend root key
+	// that records which etcd API version the backend data lives in
+	storageVersionKey = ".storage_version"
+	// storageV2 means that the data is stored using the v2 API
+	storageV2 = "v2"
+	// storageDual means that the data is being migrated: it is read
+	// from the v2 keyspace and all writes are mirrored into the v3 keyspace
+	storageDual = "dual"
+	// storageV3 means that the data has been migrated to the v3 API
+	storageV3 = "v3"
+	// storageModeTimeout limits the time to query the storage mode
+	storageModeTimeout = 5 * time.Second
+	// storageAckKey is the name of the v3 directory under the backend root key
+	// where the running backends acknowledge the storage mode they use
+	storageAckKey = ".storage_version_acks"
+	// storageAckTTL is the TTL of the session that holds the acknowledgement
+	storageAckTTL = 30 * time.Second
+	// mirrorAttempts limits the number of attempts to mirror the key
+	// that is being modified concurrently
+	mirrorAttempts = 5
+)
+
+// MigrateToV3 copies the contents of the backend from the etcd v2 keyspace
+// into the v3 keyspace and switches all backends running in automatic mode
+// to the v3 API without downtime.
+//
+// The migration first switches the backends into dual mode in which they
+// keep using the v2 data but mirror all writes into the v3 keyspace, then
+// copies the v2 tree and finally switches the backends to the v3 API.
+// Each running backend acknowledges the mode it has switched to and the
+// migration does not proceed to the next step until all backends have
+// acknowledged the current one. The grace period limits the time to wait
+// for the acknowledgements. The v2 data is left intact.
+func MigrateToV3(ctx context.Context, cfg ETCDConfig, gracePeriod time.Duration) error {
+	if err := cfg.Check(); err != nil {
+		return trace.Wrap(err)
+	}
+	v2, err := newEngine(cfg, &v1codec{})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	v3, err := newV3Engine(cfg, &v3codec{})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	defer v3.Close()
+	mode, err := getStorageMode(v3)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if mode == storageV3 {
+		log.Info("Backend has already been migrated to etcd v3 API.")
+		return nil
+	}
+	log.Info("Switching backend to dual mode.")
+	if err := setStorageMode(ctx, v3, storageDual); err != nil {
+		return trace.Wrap(err)
+	}
+	// No backend writes to the v2 keyspace without mirroring the write
+	// once all of them have acknowledged the dual mode
+	if err := waitForAcks(ctx, v3, storageDual, gracePeriod); err != nil {
+		return trace.Wrap(err)
+	}
+	log.Info("Copying backend data to etcd v3 keyspace.")
+	if err := syncTree(ctx, v2, v3); err != nil {
+		return trace.Wrap(err)
+	}
+	log.Info("Switching backend to etcd v3 API.")
+	if err := setStorageMode(ctx, v3, storageV3); err != nil {
+		return trace.Wrap(err)
+	}
+	// The backends that have not yet switched fail to mirror their writes
+	// from now on so they cannot overwrite the v3 data
+	return trace.Wrap(waitForAcks(ctx, v3, storageV3, gracePeriod))
+}
+
+// newETCDAuto returns a new backend that uses the API version recorded
+// in the storage version key and follows the migration as it progresses
+func newETCDAuto(cfg ETCDConfig, clock clockwork.Clock) (*electingBackend, error) {
+	v3, err := newV3Engine(cfg, &v3codec{})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	// The backend cannot assume the v2 API if the storage version is unknown:
+	// the data might have already been migrated
+	mode, err := getStorageMode(v3)
+	if err != nil {
+		v3.Close()
+		return nil, trace.Wrap(err, "failed to query storage version")
+	}
+	if mode == storageV3 {
+		return &electingBackend{
+			Backend: &backend{
+				Clock:    clock,
+				kvengine: withMetrics(v3, constants.ETCDBackend),
+			},
+			Leader: newV3Leader(v3.client),
+		}, nil
+	}
+	v2, err := newEngine(cfg, &v1codec{})
+	if err != nil {
+		v3.Close()
+		return nil, trace.Wrap(err)
+	}
+	v2Leader, err := leader.NewClient(leader.Config{Client: v2.client, Clock: clock})
+	if err != nil {
+		v3.Close()
+		return nil, trace.Wrap(err)
+	}
+	switching := &switchingLeader{
+		v2:     v2Leader,
+		v2keys: v2,
+		v3:     newV3Leader(v3.client),
+	}
+	engine := &switchingEngine{
+		v2:       v2,
+		v3:       v3,
+		mode:     mode,
+		ack:      &modeAck{v3: v3, key: storageAckKeyFor(v3, uuid.New())},
+		onV3:     switching.switchToV3,
+		closeC:   make(chan struct{}),
+		watching: make(chan struct{}),
+	}
+	// The engine does not write until it has acknowledged the storage mode
+	// so the migration cannot miss its writes
+	if _, err := engine.register(); err != nil {
+		v3.Close()
+		return nil, trace.Wrap(err, "failed to acknowledge storage version")
+	}
+	go engine.watchMode()
+	return &electingBackend{
+		Backend: &backend{
+			Clock:    clock,
+			kvengine: withMetrics(engine, constants.ETCDBackend),
+		},
+		Leader: switching,
+		client: v2.client,
+	}, nil
+}
+
+// switchingEngine is the engine used while the data may still be migrated.
+// It delegates to the v2 or v3 engine depending on the storage mode and
+// mirrors writes into the v3 keyspace in dual mode.
+//
+// Writes hold the read lock for their duration so the mode is only
+// changed (and acknowledged) once the writes started in the previous
+// mode have completed
+type switchingEngine struct {
+	sync.RWMutex
+	v2   *engine
+	v3   *v3engine
+	mode string
+	// ack records the storage mode observed by this engine
+	ack *modeAck
+	// onV3 is invoked once the engine has switched to the v3 API
+	onV3     func()
+	closeC   chan struct{}
+	watching chan struct{}
+	once     sync.Once
+}
+
+func (s *switchingEngine) key(prefix string, keys ...string) key {
+	return s.v2.key(prefix, keys...)
+}
+
+func (s *switchingEngine) Close() error {
+	s.once.Do(func() {
+		close(s.closeC)
+	})
+	<-s.watching
+	return trace.Wrap(s.v3.Close())
+}
+
+func (s *switchingEngine) createVal(key key, val interface{}, ttl time.Duration) error {
+	return s.write(key, func(e kvengine) error { return e.createVal(key, val, ttl) })
+}
+
+func (s *switchingEngine) createValBytes(key key, data []byte, ttl time.Duration) error {
+	return s.write(key, func(e kvengine) error { return e.createValBytes(key, data, ttl) })
+}
+
+func (s *switchingEngine) upsertVal(key key, val interface{}, ttl time.Duration) error {
+	return s.write(key, func(e kvengine) error { return e.upsertVal(key, val, ttl) })
+}
+
+func (s *switchingEngine) upsertValBytes(key key, data []byte, ttl time.Duration) error {
+	return s.write(key, func(e kvengine) error { return e.upsertValBytes(key, data, ttl) })
+}
+
+func (s *switchingEngine) updateVal(key key, val interface{}, ttl time.Duration) error {
+	return s.write(key, func(e kvengine) error { return e.updateVal(key, val, ttl) })
+}
+
+func (s *switchingEngine) updateValBytes(key key, data []byte, ttl time.Duration) error {
+	return s.write(key, func(e kvengine) error { return e.updateValBytes(key, data, ttl) })
+}
+
+func (s *switchingEngine) updateTTL(key key, ttl time.Duration) error {
+	return s.write(key, func(e kvengine) error { return e.updateTTL(key, ttl) })
+}
+
+func (s *switchingEngine) compareAndSwap(key key, val, prevVal, outVal interface{}, ttl time.Duration) error {
+	return s.write(key, func(e kvengine) error { return e.compareAndSwap(key, val, prevVal, outVal, ttl) })
+}
+
+func (s *switchingEngine) compareAndSwapBytes(key key, val, prevVal []byte, outVal *[]byte, ttl time.Duration) error {
+	return s.write(key, func(e kvengine) error { return e.compareAndSwapBytes(key, val, prevVal, outVal, ttl) })
+}
+
+func (s *switchingEngine) getVal(key key, val interface{}) error {
+	return s.current().getVal(key, val)
+}
+
+func (s *switchingEngine) getValBytes(key key) ([]byte, error) {
+	return s.current().getValBytes(key)
+}
+
+func (s *switchingEngine) deleteKey(key key) error {
+	return s.write(key, func(e kvengine) error { return e.deleteKey(key) })
+}
+
+func (s *switchingEngine) compareAndDelete(key key, prevVal interface{}) error {
+	return s.write(key, func(e kvengine) error { return e.compareAndDelete(key, prevVal) })
+}
+
+func (s *switchingEngine) createDir(key key, ttl time.Duration) error {
+	return s.write(key, func(e kvengine) error { return e.createDir(key, ttl) })
+}
+
+func (s *switchingEngine) upsertDir(key key, ttl time.Duration) error {
+	return s.write(key, func(e kvengine) error { return e.upsertDir(key, ttl) })
+}
+
+func (s *switchingEngine) deleteDir(key key) error {
+	return s.write(key, func(e kvengine) error { return e.deleteDir(key) })
+}
+
+// acquireLock acquires the lock with the current API.
+// In dual mode, the lock is acquired with both APIs to exclude
+// the backends that have already switched to the v3 API
+func (s *switchingEngine) acquireLock(key key, ttl time.Duration) error {
+	if s.getMode() != storageDual {
+		return s.current().acquireLock(key, ttl)
+	}
+	if err := s.v2.acquireLock(key, ttl); err != nil {
+		return trace.Wrap(err)
+	}
+	if err := s.v3.acquireLock(key, ttl); err != nil {
+		s.v2.releaseLock(key)
+		return trace.Wrap(err)
+	}
+	return nil
+}
+
+func (s *switchingEngine) tryAcquireLock(key key, ttl time.Duration) error {
+	if s.getMode() != storageDual {
+		return s.current().tryAcquireLock(key, ttl)
+	}
+	if err := s.v2.tryAcquireLock(key, ttl); err != nil {
+		return trace.Wrap(err)
+	}
+	if err := s.v3.tryAcquireLock(key, ttl); err != nil {
+		s.v2.releaseLock(key)
+		return trace.Wrap(err)
+	}
+	return nil
+}
+
+// releaseLock releases the lock with both APIs since the mode
+// might have changed since the lock has been acquired
+func (s *switchingEngine) releaseLock(key key) error {
+	errV3 := s.v3.releaseLock(key)
+	if s.getMode() == storageV3 {
+		return trace.Wrap(errV3)
+	}
+	return trace.Wrap(s.v2.releaseLock(key))
+}
+
+func (s *switchingEngine) getKeys(key key) ([]string, error) {
+	return s.current().getKeys(key)
+}
+
+// write executes the update with the current API and mirrors
+// the result into the v3 keyspace in dual mode.
+// The write fails if it cannot be mirrored, for example, because
+// the storage has already been switched to the v3 API
+func (s *switchingEngine) write(key key, fn func(kvengine) error) error {
+	s.RLock()
+	defer s.RUnlock()
+	if s.mode == storageV3 {
+		return fn(s.v3)
+	}
+	err := fn(s.v2)
+	if s.mode != storageDual {
+		return err
+	}
+	if errMirror := mirror(context.TODO(), s.v2, s.v3, ekey(key)); errMirror != nil {
+		return trace.Wrap(errMirror, "failed to mirror %v", ekey(key))
+	}
+	return err
+}
+
+func (s *switchingEngine) current() kvengine {
+	if s.getMode() == storageV3 {
+		return s.v3
+	}
+	return s.v2
+}
+
+func (s *switchingEngine) getMode() string {
+	s.RLock()
+	defer s.RUnlock()
+	return s.mode
+}
+
+func (s *switchingEngine) setMode(mode string) {
+	s.Lock()
+	prev := s.mode
+	s.mode = mode
+	s.Unlock()
+	if prev == mode {
+		return
+	}
+	log.Infof("Storage mode changed from %v to %v.", prev, mode)
+	if mode == storageV3 && s.onV3 != nil {
+		s.onV3()
+	}
+}
+
+// register reads the current storage mode, switches the engine to it
+// and acknowledges it.
+// It returns the revision to watch the storage version key from
+func (s *switchingEngine) register() (revision int64, err error) {
+	key := storageVersionKeyFor(s.v3)
+	for {
+		var resp *clientv3.GetResponse
+		err := s.v3.retry(func(ctx context.Context) (err error) {
+			resp, err = s.v3.client.Get(ctx, key)
+			return err
+		})
+		if err != nil {
+			return 0, trace.Wrap(err)
+		}
+		mode := storageModeFrom(resp.Kvs)
+		s.setMode(mode)
+		if mode == storageV3 {
+			return resp.Header.Revision, nil
+		}
+		var modRevision int64
+		if len(resp.Kvs) != 0 {
+			modRevision = resp.Kvs[0].ModRevision
+		}
+		err = s.ack.set(mode, modRevision)
+		if trace.IsCompareFailed(err) {
+			// The mode has changed since it has been read
+			continue
+		}
+		if err != nil {
+			return 0, trace.Wrap(err)
+		}
+		return resp.Header.Revision, nil
+	}
+}
+
+// watchMode watches the storage version key and switches the engine
+// to the new mode until the engine is closed or switched to the v3 API
+func (s *switchingEngine) watchMode() {
+	defer close(s.watching)
+	// Removes the acknowledgement once the engine has switched
+	// to the v3 API or has been closed
+	defer s.ack.close()
+	for {
+		if err := s.followMode(); err != nil {
+			log.Debugf("Failed to watch storage mode: %v.", err)
+		}
+		if s.getMode() == storageV3 {
+			return
+		}
+		select {
+		case <-time.After(delayBetweenLockAttempts):
+		case <-s.closeC:
+			return
+		}
+	}
+}
+
+// followMode registers the engine, then switches it to the new modes
+// and acknowledges them until the watch fails, the acknowledgement is lost, the engine is switched
+// to the v3 API or closed
+func (s *switchingEngine) followMode() error {
+	revision, err := s.register()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if s.getMode() == storageV3 {
+		return nil
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	watchC := s.v3.client.Watch(clientv3.WithRequireLeader(ctx), storageVersionKeyFor(s.v3),
+		clientv3.WithRev(revision+1))
+	for {
+		select {
+		case watchResp, ok := <-watchC:
+			if !ok {
+				return trace.ConnectionProblem(nil, "storage mode watch closed")
+			}
+			if err := watchResp.Err(); err != nil {
+				return trace.Wrap(err)
+			}
+			for _, event := range watchResp.Events {
+				mode := storageModeFrom([]*mvccpb.KeyValue{event.Kv})
+				s.setMode(mode)
+				if mode == storageV3 {
+					return nil
+				}
+				if err := s.ack.set(mode, event.Kv.ModRevision); err != nil {
+					return trace.Wrap(err)
+				}
+			}
+		case <-s.ack.lost():
+			return trace.ConnectionProblem(nil, "lost storage mode acknowledgement")
+		case <-s.closeC:
+			return nil
+		}
+	}
+}
+
+// modeAck is the record of the storage mode observed by a running backend.
+//
+// The record is attached to the lease of the backend session, so it
+// disappears once the backend is stopped or loses the connection to etcd
+type modeAck struct {
+	v3      *v3engine
+	key     string
+	session *v3Session
+}
+
+// set acknowledges the storage mode provided the storage version key
+// still has the specified modification revision (zero if the key
+// does not exist).
+// It returns CompareFailed if the storage mode has changed
+func (a *modeAck) set(mode string, modRevision int64) error {
+	if a.session == nil || !a.session.isAlive() {
+		a.close()
+		err := a.v3.retry(func(ctx context.Context) (err error) {
+			a.session, err = newV3Session(ctx, a.v3.client, storageAckTTL)
+			return err
+		})
+		if err != nil {
+			return trace.Wrap(err)
+		}
+	}
+	versionKey := storageVersionKeyFor(a.v3)
+	var resp *clientv3.TxnResponse
+	err := a.v3.retry(func(ctx context.Context) (err error) {
+		resp, err = a.v3.client.Txn(ctx).
+			If(clientv3.Compare(clientv3.ModRevision(versionKey), "=", modRevision)).
+			Then(clientv3.OpPut(a.key, mode, clientv3.WithLease(a.session.leaseID))).
+			Commit()
+		return err
+	})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if !resp.Succeeded {
+		return trace.CompareFailed("storage version has changed")
+	}
+	return nil
+}
+
+// lost returns the channel that is closed once the acknowledgement is lost
+func (a *modeAck) lost() <-chan struct{} {
+	if a.session == nil {
+		return nil
+	}
+	return a.session.doneC
+}
+
+// close removes the acknowledgement
+func (a *modeAck) close() {
+	if a.session == nil {
+		return
+	}
+	a.session.close()
+	a.session = nil
+}
+
+// waitForAcks waits until all running backends have acknowledged
+// the specified storage mode.
+// The backends acknowledging the v3 mode remove their records instead
+func waitForAcks(ctx context.Context, v3 *v3engine, mode string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	prefix := storageAckKeyFor(v3, "")
+	for {
+		var pending []string
+		var resp *clientv3.GetResponse
+		err := v3.retry(func(ctx context.Context) (err error) {
+			resp, err = v3.client.Get(ctx, prefix, clientv3.WithPrefix())
+			return err
+		})
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		for _, kv := range resp.Kvs {
+			if string(kv.Value) != mode {
+				pending = append(pending, strings.TrimPrefix(string(kv.Key), prefix))
+			}
+		}
+		if len(pending) == 0 {
+			return nil
+		}
+		log.Infof("Waiting for %v backends to switch to %v mode.", len(pending), mode)
+		select {
+		case <-time.After(delayBetweenLockAttempts):
+		case <-ctx.Done():
+			return trace.LimitExceeded("backends %v have not switched to %v mode in %v",
+				pending, mode, timeout)
+		}
+	}
+}
+
+// switchingLeader runs the election with the v2 API until
+// the backend switches to the v3 API at which point all voters
+// and watches are moved to the v3 leader
+type switchingLeader struct {
+	sync.Mutex
+	v2 *leader.Client
+	// v2keys reads the v2 election keys during the handover
+	v2keys   client.KeysAPI
+	v3       *v3Leader
+	switched bool
+	voters   []voter
+	watches  []watch
+}
+
+type voter struct {
+	ctx   context.Context
+	key   string
+	value string
+	term  time.Duration
+}
+
+type watch struct {
+	key     string
+	retry   time.Duration
+	valuesC chan string
+}
+
+func (l *switchingLeader) AddWatch(key string, retry time.Duration, valuesC chan string) {
+	l.Lock()
+	defer l.Unlock()
+	if l.switched {
+		l.v3.AddWatch(key, retry, valuesC)
+		return
+	}
+	l.watches = append(l.watches, watch{key: key, retry: retry, valuesC: valuesC})
+	l.v2.AddWatch(key, retry, valuesC)
+}
+
+func (l *switchingLeader) AddVoter(ctx context.Context, key, value string, term time.Duration) error {
+	l.Lock()
+	defer l.Unlock()
+	if l.switched {
+		return trace.Wrap(l.v3.AddVoter(ctx, key, value, term))
+	}
+	if err := l.v2.AddVoter(ctx, key, value, term); err != nil {
+		return trace.Wrap(err)
+	}
+	l.voters = append(l.voters, voter{ctx: ctx, key: key, value: value, term: term})
+	return nil
+}
+
+func (l *switchingLeader) StepDown() {
+	l.Lock()
+	switched := l.switched
+	l.Unlock()
+	if switched {
+		l.v3.StepDown()
+		return
+	}
+	l.v2.StepDown()
+}
+
+func (l *switchingLeader) Close() error {
+	l.v2.Close()
+	return l.v3.Close()
+}
+
+// switchToV3 stops the v2 election and re-registers
+// all voters and watches with the v3 leader
+func (l *switchingLeader) switchToV3() {
+	l.Lock()
+	defer l.Unlock()
+	if l.switched {
+		return
+	}
+	l.switched = true
+	l.v2.Close()
+	for _, w := range l.watches {
+		l.v3.AddWatch(w.key, w.retry, w.valuesC)
+	}
+	for _, v := range l.voters {
+		go l.handOver(v)
+	}
+	l.voters, l.watches = nil, nil
+}
+
+// handOver registers the voter with the v3 leader once this does not
+// risk electing a second leader: the v2 leader registers right away and
+// takes over the v3 election key while other voters wait until
+// the v2 leader has been elected with the v3 API or its v2 term has expired
+func (l *switchingLeader) handOver(v voter) {
+	ticker := time.NewTicker(v.term / 5)
+	defer ticker.Stop()
+	for {
+		ready, err := l.canHandOver(v)
+		if err != nil {
+			log.Debugf("Failed to check leadership of %v: %v.", v.key, err)
+		}
+		if ready {
+			if err := l.v3.AddVoter(v.ctx, v.key, v.value, v.term); err != nil {
+				log.Warnf("Failed to add voter for %v: %v.", v.key, err)
+			}
+			return
+		}
+		select {
+		case <-ticker.C:
+		case <-l.v3.closeC:
+			return
+		case <-v.ctx.Done():
+			return
+		}
+	}
+}
+
+// canHandOver returns true if the voter can join the v3 election
+func (l *switchingLeader) canHandOver(v voter) (bool, error) {
+	ctx, cancel := context.WithTimeout(v.ctx, v.term)
+	defer cancel()
+	resp, err := l.v3.client.Get(ctx, v.key)
+	if err != nil {
+		return false, trace.Wrap(err)
+	}
+	if len(resp.Kvs) != 0 {
+		return true, nil
+	}
+	v2resp, err := l.v2keys.Get(ctx, v.key, nil)
+	err = convertErr(err)
+	if trace.IsNotFound(err) {
+		return true, nil
+	}
+	if err != nil {
+		return false, trace.Wrap(err)
+	}
+	return v2resp.Node.Value == v.value, nil
+}
+
+// mirror copies the current state of the specified key
+// from the v2 keyspace into the v3 keyspace.
+//
+// The copy is only written while the storage is in dual mode, so
+// a writer that has not observed the switch to the v3 API cannot overwrite
+// the v3 data. The copy is repeated if the v2 key has been modified
+// while it was being copied since the concurrent writer might have
+// mirrored its change before this copy has been written
+func mirror(ctx context.Context, v2 *engine, v3 *v3engine, key string) error {
+	for i := 0; i < mirrorAttempts; i++ {
+		node, err := getV2Node(ctx, v2, key)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		if node == nil {
+			_, err = dualTxn(v3, &v3lease{}, nil,
+				clientv3.OpDelete(key),
+				clientv3.OpDelete(key+"/", clientv3.WithPrefix()))
+		} else if node.Dir {
+			_, err = copyNode(v3, key+"/", node)
+		} else {
+			_, err = copyNode(v3, key, node)
+		}
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		latest, err := getV2Node(ctx, v2, key)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		if modifiedIndex(latest) == modifiedIndex(node) {
+			return nil
+		}
+	}
+	return trace.CompareFailed("%v is being modified concurrently", key)
+}
+
+// syncTree makes the v3 keyspace under the backend root key match
+// the v2 tree, with the v2 data being the source of truth.
+//
+// The v3 keyspace is read before the v2 tree and each key is only written
+// if it has not been modified since, so the keys mirrored by the running
+// backends in the meantime are not overwritten with the older v2 data
+func syncTree(ctx context.Context, v2 *engine, v3 *v3engine) error {
+	root := strings.Join(v3.etcdKey, "/")
+	var actual *clientv3.GetResponse
+	err := v3.retry(func(ctx context.Context) (err error) {
+		actual, err = v3.client.Get(ctx, root+"/", clientv3.WithPrefix())
+		return err
+	})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	expected := make(map[string]*client.Node)
+	resp, err := v2.Get(ctx, root, &client.GetOptions{Recursive: true})
+	err = convertErr(err)
+	if err != nil && !trace.IsNotFound(err) {
+		return trace.Wrap(err)
+	}
+	if err == nil {
+		flattenTree(resp.Node, expected)
+	}
+	// The prefix also matches the acknowledgements
+	versionKey := storageVersionKeyFor(v3)
+	var count, skipped int
+	for _, kv := range actual.Kvs {
+		key := string(kv.Key)
+		if strings.HasPrefix(key, versionKey) {
+			continue
+		}
+		unmodified := clientv3.Compare(clientv3.ModRevision(key), "=", kv.ModRevision)
+		node, ok := expected[key]
+		if ok {
+			delete(expected, key)
+			if string(kv.Value) == nodeValue(node) {
+				continue
+			}
+		}
+		var applied bool
+		if ok {
+			applied, err = copyNode(v3, key, node, unmodified)
+		} else {
+			applied, err = dualTxn(v3, &v3lease{}, []clientv3.Cmp{unmodified}, clientv3.OpDelete(key))
+		}
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		if !applied {
+			skipped++
+		}
+		if applied && ok {
+			count++
+		}
+	}
+	// The directories are copied before their contents so the keys
+	// in the directories with a TTL are attached to the directory lease
+	keys := make([]string, 0, len(expected))
+	for key := range expected {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		node := expected[key]
+		applied, err := copyNode(v3, key, node, clientv3.Compare(clientv3.CreateRevision(key), "=", 0))
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		if applied {
+			count++
+		} else {
+			skipped++
+		}
+	}
+	log.Infof("Copied %v keys to etcd v3 keyspace, %v keys have been mirrored concurrently.", count, skipped)
+	return nil
+}
+
+// copyNode writes the v2 node into the v3 keyspace under the specified key
+// provided the storage is in dual mode and the conditions hold
+func copyNode(v3 *v3engine, key string, node *client.Node, cmps ...clientv3.Cmp) (applied bool, err error) {
+	lease, err := v3.leaseFor(key, nodeTTL(node))
+	if err != nil {
+		return false, trace.Wrap(err)
+	}
+	if !node.Dir {
+		return dualTxn(v3, lease, cmps,
+			clientv3.OpPut(key, nodeValue(node), append(lease.opts, clientv3.WithPrevKV())...))
+	}
+	// The keys that expire with the directory are moved to the new lease
+	// of the directory before its previous lease is revoked
+	prev, err := v3.getKV(key)
+	if err != nil && !trace.IsNotFound(err) {
+		lease.revoke(v3)
+		return false, trace.Wrap(err)
+	}
+	applied, err = dualTxn(v3, lease, cmps, clientv3.OpPut(key, nodeValue(node), lease.opts...))
+	if err != nil || !applied || prev == nil {
+		return applied, trace.Wrap(err)
+	}
+	prevLeaseID := clientv3.LeaseID(prev.Lease)
+	if prevLeaseID == lease.id {
+		return true, nil
+	}
+	if err := v3.moveKeys(key, prevLeaseID, lease); err != nil {
+		return true, trace.Wrap(err)
+	}
+	if prevLeaseID != 0 && prevLeaseID != lease.parent {
+		v3.revokeUnused(prevLeaseID)
+	}
+	return true, nil
+}
+
+// dualTxn applies the operations to the v3 keyspace only while the storage
+// is in dual mode and the specified conditions hold.
+// Once the storage has been switched to the v3 API, the v3 keyspace is
+// the source of truth and must not be overwritten with the v2 data.
+//
+// It returns CompareFailed if the storage is no longer in dual mode
+// and false if the conditions do not hold
+func dualTxn(v3 *v3engine, lease *v3lease, cmps []clientv3.Cmp, ops ...clientv3.Op) (applied bool, err error) {
+	versionKey := storageVersionKeyFor(v3)
+	cmps = append([]clientv3.Cmp{clientv3.Compare(clientv3.Value(versionKey), "=", storageDual)}, cmps...)
+	var resp *clientv3.TxnResponse
+	err = v3.retry(func(ctx context.Context) (err error) {
+		resp, err = v3.client.Txn(ctx).
+			If(cmps...).
+			Then(ops...).
+			Else(clientv3.OpGet(versionKey)).
+			Commit()
+		return err
+	})
+	v3.release(lease, resp, err)
+	if err != nil {
+		return false, trace.Wrap(err)
+	}
+	if resp.Succeeded {
+		return true, nil
+	}
+	if get := resp.Responses[0].GetResponseRange(); get != nil && storageModeFrom(get.Kvs) != storageDual {
+		return false, trace.CompareFailed("storage is no longer in dual mode")
+	}
+	return false, nil
+}
+
+// getV2Node returns the v2 node or nil if it does not exist
+func getV2Node(ctx context.Context, v2 *engine, key string) (*client.Node, error) {
+	resp, err := v2.Get(ctx, key, nil)
+	err = convertErr(err)
+	if trace.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return resp.Node, nil
+}
+
+func modifiedIndex(node *client.Node) uint64 {
+	if node == nil {
+		return 0
+	}
+	return node.ModifiedIndex
+}
+
+// flattenTree indexes the v2 tree by the v3 keys
+func flattenTree(node *client.Node, nodes map[string]*client.Node) {
+	if !node.Dir {
+		nodes[node.Key] = node
+		return
+	}
+	nodes[node.Key+"/"] = node
+	for _, child := range node.Nodes {
+		flattenTree(child, nodes)
+	}
+}
+
+// nodeValue returns the v3 value for the specified v2 node
+func nodeValue(node *client.Node) string {
+	if node.Dir {
+		return ""
+	}
+	return convertV2Value(node.Value)
+}
+
+// nodeTTL returns the remaining TTL of the specified v2 node
+func nodeTTL(node *client.Node) time.Duration {
+	if node.Expiration == nil {
+		return forever
+	}
+	ttl := time.Until(*node.Expiration)
+	if ttl < time.Second {
+		return time.Second
+	}
+	return ttl
+}
+
+// convertV2Value converts the value encoded with v1codec into
+// the value encoded with v3codec. Values not written by the codec,
+// like locks, are copied as-is
+func convertV2Value(value string) string {
+	data, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return value
+	}
+	return string(data)
+}
+
+// getStorageMode returns the storage mode retrying on transient errors
+func getStorageMode(v3 *v3engine) (mode string, err error) {
+	var resp *clientv3.GetResponse
+	err = v3.retry(func(ctx context.Context) (err error) {
+		ctx, cancel := context.WithTimeout(ctx, storageModeTimeout)
+		defer cancel()
+		resp, err = v3.client.Get(ctx, storageVersionKeyFor(v3))
+		return err
+	})
+	if err != nil {
+		return "", trace.Wrap(err)
+	}
+	return storageModeFrom(resp.Kvs), nil
+}
+
+func setStorageMode(ctx context.Context, v3 *v3engine, mode string) error {
+	return trace.Wrap(v3.put(storageVersionKeyFor(v3), mode, forever))
+}
+
+func storageModeFrom(kvs []*mvccpb.KeyValue) string {
+	if len(kvs) == 0 {
+		return storageV2
+	}
+	switch mode := string(kvs[0].Value); mode {
+	case storageDual, storageV3:
+		return mode
+	}
+	return storageV2
+}
+
+func storageVersionKeyFor(v3 *v3engine) string {
+	return strings.Join(append(append([]string{}, v3.etcdKey...), storageVersionKey), "/")
+}
+
+func storageAckKeyFor(v3 *v3engine, id string) string {
+	return strings.Join(append(append([]string{}, v3.etcdKey...), storageAckKey, id), "/")
+}
+
+var _ storage.Leader = (*switchingLeader)(nil)
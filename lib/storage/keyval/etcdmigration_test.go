@@ -0,0 +1,158 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package keyval
+
+import (
+	"context"
+	"encoding/base64"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/storage"
+
+	"github.com/coreos/etcd/client"
+	"github.com/gravitational/trace"
+	. "gopkg.in/check.v1"
+)
+
+type MigrationSuite struct{}
+
+var _ = Suite(&MigrationSuite{})
+
+func (s *MigrationSuite) TestChildKeys(c *C) {
+	keys := childKeys("/root/dir/", []string{
+		"/root/dir/",
+		"/root/dir/b",
+		"/root/dir/a/",
+		"/root/dir/a/val",
+		"/root/dir/c/nested/val",
+	})
+	c.Assert(keys, DeepEquals, []string{"a", "b", "c"})
+	c.Assert(childKeys("/root/dir/", []string{"/root/dir/"}), IsNil)
+}
+
+func (s *MigrationSuite) TestParentDirs(c *C) {
+	e := &v3engine{etcdKey: []string{"", "root"}}
+	c.Assert(e.parentDirs("/root/dir/nested/val"), DeepEquals, []string{"/root/dir/nested/", "/root/dir/"})
+	c.Assert(e.parentDirs("/root/dir/nested/"), DeepEquals, []string{"/root/dir/"})
+	c.Assert(e.parentDirs("/root/val"), IsNil)
+}
+
+func (s *MigrationSuite) TestLeaseTTL(c *C) {
+	c.Assert(leaseTTL(100*time.Millisecond), Equals, int64(1))
+	c.Assert(leaseTTL(time.Minute), Equals, int64(60))
+	c.Assert(leaseTTL(1500*time.Millisecond), Equals, int64(2))
+}
+
+func (s *MigrationSuite) TestConvertsValues(c *C) {
+	encoded, err := (&v1codec{}).EncodeToString(map[string]string{"name": "value"})
+	c.Assert(err, IsNil)
+	c.Assert(convertV2Value(encoded), Equals, `{"name":"value"}`)
+	c.Assert(convertV2Value("locked"), Equals, "locked")
+	c.Assert(convertV2Value(base64.StdEncoding.EncodeToString([]byte{0, 1})), Equals, string([]byte{0, 1}))
+
+	expires := time.Now().Add(time.Hour)
+	nodes := make(map[string]*client.Node)
+	flattenTree(&client.Node{
+		Key: "/root",
+		Dir: true,
+		Nodes: client.Nodes{
+			{Key: "/root/val", Value: encoded},
+			{Key: "/root/dir", Dir: true, Expiration: &expires, Nodes: client.Nodes{
+				{Key: "/root/dir/lock", Value: "locked", Expiration: &expires},
+			}},
+		},
+	}, nodes)
+	c.Assert(nodes, HasLen, 4)
+	c.Assert(nodeValue(nodes["/root/val"]), Equals, `{"name":"value"}`)
+	c.Assert(nodeValue(nodes["/root/dir/"]), Equals, "")
+	c.Assert(nodeTTL(nodes["/root/val"]), Equals, time.Duration(forever))
+	c.Assert(nodeTTL(nodes["/root/dir/lock"]) > 59*time.Minute, Equals, true)
+}
+
+func (s *MigrationSuite) TestMigratesToV3(c *C) {
+	if ok, _ := strconv.ParseBool(os.Getenv(defaults.TestETCD)); !ok {
+		c.Skip("Skipping test suite for ETCD")
+	}
+	v2, err := newBackend(os.Getenv(defaults.TestETCDConfig), APIVersionV2)
+	c.Assert(err, IsNil)
+	defer v2.Delete()
+
+	// the backend in automatic mode shares the root key with the v2 backend
+	cfg := engineOf(v2.backend).(*engine).cfg
+	cfg.APIVersion = APIVersionAuto
+	auto, err := NewETCD(cfg)
+	c.Assert(err, IsNil)
+	defer auto.Close()
+
+	_, err = v2.backend.CreateRepository(storage.NewRepository("example.com"))
+	c.Assert(err, IsNil)
+
+	err = MigrateToV3(context.TODO(), cfg, time.Second)
+	c.Assert(err, IsNil)
+
+	cfg.APIVersion = APIVersionV3
+	v3, err := NewETCD(cfg)
+	c.Assert(err, IsNil)
+	defer v3.Close()
+	defer (&tempBackend{prefix: cfg.Key, v3: engineOf(v3).(*v3engine).client}).Delete()
+	repo, err := v3.GetRepository("example.com")
+	c.Assert(err, IsNil)
+	c.Assert(repo.GetName(), Equals, "example.com")
+
+	// the backend in automatic mode has switched to v3 API
+	_, err = auto.CreateRepository(storage.NewRepository("example2.com"))
+	c.Assert(err, IsNil)
+	_, err = v3.GetRepository("example2.com")
+	c.Assert(err, IsNil)
+}
+
+func (s *MigrationSuite) TestMirrorFailsAfterSwitch(c *C) {
+	if ok, _ := strconv.ParseBool(os.Getenv(defaults.TestETCD)); !ok {
+		c.Skip("Skipping test suite for ETCD")
+	}
+	v2, err := newBackend(os.Getenv(defaults.TestETCDConfig), APIVersionV2)
+	c.Assert(err, IsNil)
+	defer v2.Delete()
+
+	cfg := engineOf(v2.backend).(*engine).cfg
+	v3, err := newV3Engine(cfg, &v3codec{})
+	c.Assert(err, IsNil)
+	defer v3.Close()
+	defer (&tempBackend{prefix: cfg.Key, v3: v3.client}).Delete()
+
+	v2engine := engineOf(v2.backend).(*engine)
+	key := v2engine.key("values", "key")
+	c.Assert(v2engine.upsertValBytes(key, []byte("v2"), forever), IsNil)
+
+	c.Assert(setStorageMode(context.TODO(), v3, storageDual), IsNil)
+	c.Assert(mirror(context.TODO(), v2engine, v3, ekey(key)), IsNil)
+	kv, err := v3.getKV(ekey(key))
+	c.Assert(err, IsNil)
+	c.Assert(string(kv.Value), Equals, "v2")
+
+	// once switched to v3, the v3 data is not overwritten
+	c.Assert(setStorageMode(context.TODO(), v3, storageV3), IsNil)
+	c.Assert(v3.put(ekey(key), "v3", forever), IsNil)
+	err = mirror(context.TODO(), v2engine, v3, ekey(key))
+	c.Assert(trace.IsCompareFailed(err), Equals, true, Commentf("%v", err))
+	kv, err = v3.getKV(ekey(key))
+	c.Assert(err, IsNil)
+	c.Assert(string(kv.Value), Equals, "v3")
+}
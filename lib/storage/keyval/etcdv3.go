@@ -0,0 +1,955 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package keyval
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gravitational/gravity/lib/constants"
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/utils"
+
+	"github.com/cenkalti/backoff"
+	"github.com/coreos/etcd/clientv3"
+	"github.com/coreos/etcd/etcdserver/api/v3rpc/rpctypes"
+	"github.com/coreos/etcd/mvcc/mvccpb"
+	"github.com/coreos/etcd/pkg/transport"
+	"github.com/gravitational/trace"
+	"github.com/jonboulle/clockwork"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// newETCDv3 returns a new backend that stores data using the etcd v3 API
+func newETCDv3(cfg ETCDConfig, clock clockwork.Clock) (*electingBackend, error) {
+	engine, err := newV3Engine(cfg, &v3codec{})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &electingBackend{
+		Backend: &backend{
+			Clock:    clock,
+			kvengine: withMetrics(engine, constants.ETCDBackend),
+		},
+		Leader: newV3Leader(engine.client),
+	}, nil
+}
+
+// newV3Client returns a new etcd v3 API client for the specified configuration
+func newV3Client(cfg ETCDConfig) (*clientv3.Client, error) {
+	info := transport.TLSInfo{
+		CAFile:   cfg.TLSCAFile,
+		CertFile: cfg.TLSCertFile,
+		KeyFile:  cfg.TLSKeyFile,
+	}
+	tlsConfig, err := info.ClientConfig()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	// the client connects in background so that the backend
+	// can be created while the etcd cluster is unavailable
+	client, err := clientv3.New(clientv3.Config{
+		Endpoints: cfg.Nodes,
+		TLS:       tlsConfig,
+	})
+	if err != nil {
+		return nil, trace.ConnectionProblem(err, "failed to connect to the etcd cluster")
+	}
+	return client, nil
+}
+
+// newV3Engine returns a new etcd v3 API engine
+func newV3Engine(cfg ETCDConfig, codec Codec) (*v3engine, error) {
+	if err := cfg.Check(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	client, err := newV3Client(cfg)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &v3engine{
+		cfg:     cfg,
+		codec:   codec,
+		etcdKey: strings.Split(cfg.Key, "/"),
+		client:  client,
+	}, nil
+}
+
+// v3engine implements kvengine on top of the etcd v3 API.
+//
+// There are no directories in the v3 keyspace: a directory is a marker key
+// with a trailing slash and its contents are the keys sharing its prefix.
+// TTLs are implemented with leases, conditional updates with transactions.
+// The keys in a directory with a TTL are attached to the lease of the directory
+// so they expire together with the directory.
+type v3engine struct {
+	cfg     ETCDConfig
+	codec   Codec
+	etcdKey []string
+	client  *clientv3.Client
+}
+
+func (e *v3engine) key(prefix string, keys ...string) key {
+	return makeKey(e.etcdKey, prefix, keys...)
+}
+
+func (e *v3engine) Close() error {
+	return e.client.Close()
+}
+
+func (e *v3engine) createVal(key key, val interface{}, ttl time.Duration) error {
+	encoded, err := e.codec.EncodeToString(val)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return trace.Wrap(e.create(ekey(key), encoded, ttl))
+}
+
+func (e *v3engine) createValBytes(key key, data []byte, ttl time.Duration) error {
+	encoded, err := e.codec.EncodeBytesToString(data)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return trace.Wrap(e.create(ekey(key), encoded, ttl))
+}
+
+func (e *v3engine) upsertVal(key key, val interface{}, ttl time.Duration) error {
+	encoded, err := e.codec.EncodeToString(val)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return trace.Wrap(e.put(ekey(key), encoded, ttl))
+}
+
+func (e *v3engine) upsertValBytes(key key, data []byte, ttl time.Duration) error {
+	encoded, err := e.codec.EncodeBytesToString(data)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return trace.Wrap(e.put(ekey(key), encoded, ttl))
+}
+
+func (e *v3engine) updateVal(key key, val interface{}, ttl time.Duration) error {
+	encoded, err := e.codec.EncodeToString(val)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return trace.Wrap(e.update(ekey(key), encoded, ttl))
+}
+
+func (e *v3engine) updateValBytes(key key, data []byte, ttl time.Duration) error {
+	encoded, err := e.codec.EncodeBytesToString(data)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return trace.Wrap(e.update(ekey(key), encoded, ttl))
+}
+
+// updateTTL resets the TTL of the specified value or directory.
+//
+// The lease of the key is kept alive if it has been granted with the same TTL,
+// otherwise the key is attached to a new lease and the old lease is revoked.
+// The lease of the directory the key is in is never renewed or revoked
+func (e *v3engine) updateTTL(key key, ttl time.Duration) error {
+	kv, err := e.getKV(ekey(key))
+	if trace.IsNotFound(err) {
+		kv, err = e.getKV(dirKey(key))
+		if err == nil {
+			return trace.Wrap(e.updateDirTTL(string(kv.Key), clientv3.LeaseID(kv.Lease), ttl))
+		}
+	}
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	parent, err := e.parentLease(string(kv.Key))
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	prevLeaseID := clientv3.LeaseID(kv.Lease)
+	if prevLeaseID != 0 && prevLeaseID != parent && ttl != forever {
+		renewed, err := e.renew(prevLeaseID, ttl)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		if renewed {
+			return nil
+		}
+	}
+	lease, err := e.leaseIn(parent, ttl)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if lease.id == prevLeaseID {
+		return nil
+	}
+	var resp *clientv3.TxnResponse
+	err = e.retry(func(ctx context.Context) (err error) {
+		resp, err = e.client.Txn(ctx).
+			If(clientv3.Compare(clientv3.ModRevision(string(kv.Key)), "=", kv.ModRevision)).
+			Then(clientv3.OpPut(string(kv.Key), string(kv.Value), append(lease.opts, clientv3.WithPrevKV())...)).
+			Commit()
+		return err
+	})
+	e.release(lease, resp, err)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if !resp.Succeeded {
+		return trace.CompareFailed("%v has been modified concurrently", string(kv.Key))
+	}
+	return nil
+}
+
+// updateDirTTL resets the TTL of the directory with the specified marker.
+//
+// The lease of the directory is kept alive if it has been granted with the same TTL,
+// otherwise the directory and the keys that expire with it are attached to a new lease
+// before the old lease is revoked, so the keys do not expire with the old lease
+func (e *v3engine) updateDirTTL(dir string, prevLeaseID clientv3.LeaseID, ttl time.Duration) error {
+	parent, err := e.parentLease(dir)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if prevLeaseID != 0 && prevLeaseID != parent && ttl != forever {
+		renewed, err := e.renew(prevLeaseID, ttl)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		if renewed {
+			return nil
+		}
+	}
+	lease, err := e.leaseIn(parent, ttl)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	err = e.retry(func(ctx context.Context) error {
+		_, err := e.client.Put(ctx, dir, "", lease.opts...)
+		return err
+	})
+	if err != nil {
+		lease.revoke(e)
+		return trace.Wrap(err)
+	}
+	if lease.id == prevLeaseID {
+		return nil
+	}
+	if err := e.moveKeys(dir, prevLeaseID, lease); err != nil {
+		return trace.Wrap(err)
+	}
+	if prevLeaseID != 0 && prevLeaseID != parent {
+		e.revokeUnused(prevLeaseID)
+	}
+	return nil
+}
+
+// moveKeys attaches the keys in the specified directory that are attached
+// to the previous lease to the new lease.
+// The keys modified concurrently are left with the lease chosen by their writer
+func (e *v3engine) moveKeys(dir string, prevLeaseID clientv3.LeaseID, lease *v3lease) error {
+	var resp *clientv3.GetResponse
+	err := e.retry(func(ctx context.Context) (err error) {
+		resp, err = e.client.Get(ctx, dir, clientv3.WithPrefix())
+		return err
+	})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	for _, kv := range resp.Kvs {
+		key := string(kv.Key)
+		if key == dir || clientv3.LeaseID(kv.Lease) != prevLeaseID {
+			continue
+		}
+		err := e.retry(func(ctx context.Context) error {
+			_, err := e.client.Txn(ctx).
+				If(clientv3.Compare(clientv3.ModRevision(key), "=", kv.ModRevision)).
+				Then(clientv3.OpPut(key, string(kv.Value), lease.opts...)).
+				Commit()
+			return err
+		})
+		if err != nil {
+			return trace.Wrap(err)
+		}
+	}
+	return nil
+}
+
+// renew resets the remaining time of the specified lease to its full TTL
+// if the lease has been granted with the specified TTL.
+// Returns false if the lease has a different TTL or has already expired
+func (e *v3engine) renew(leaseID clientv3.LeaseID, ttl time.Duration) (renewed bool, err error) {
+	var resp *clientv3.LeaseTimeToLiveResponse
+	err = e.retry(func(ctx context.Context) (err error) {
+		resp, err = e.client.TimeToLive(ctx, leaseID)
+		return err
+	})
+	if err != nil {
+		return false, trace.Wrap(err)
+	}
+	if resp.TTL <= 0 || resp.GrantedTTL != leaseTTL(ttl) {
+		return false, nil
+	}
+	err = e.retry(func(ctx context.Context) error {
+		_, err := e.client.KeepAliveOnce(ctx, leaseID)
+		return err
+	})
+	if trace.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, trace.Wrap(err)
+	}
+	return true, nil
+}
+
+func (e *v3engine) compareAndSwap(key key, val, prevVal, outVal interface{}, ttl time.Duration) error {
+	encoded, err := e.codec.EncodeToString(val)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if prevVal == nil {
+		return trace.Wrap(e.create(ekey(key), encoded, ttl))
+	}
+	encodedPrev, err := e.codec.EncodeToString(prevVal)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	err = e.swap(ekey(key), encoded, encodedPrev, ttl)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return trace.Wrap(e.codec.DecodeFromString(encodedPrev, outVal))
+}
+
+func (e *v3engine) compareAndSwapBytes(key key, val, prevVal []byte, outVal *[]byte, ttl time.Duration) error {
+	encoded, err := e.codec.EncodeBytesToString(val)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if prevVal == nil {
+		return trace.Wrap(e.create(ekey(key), encoded, ttl))
+	}
+	encodedPrev, err := e.codec.EncodeBytesToString(prevVal)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	err = e.swap(ekey(key), encoded, encodedPrev, ttl)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	*outVal, err = e.codec.DecodeBytesFromString(encodedPrev)
+	return trace.Wrap(err)
+}
+
+func (e *v3engine) getVal(key key, val interface{}) error {
+	kv, err := e.getKV(ekey(key))
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return trace.Wrap(e.codec.DecodeFromString(string(kv.Value), val))
+}
+
+func (e *v3engine) getValBytes(key key) ([]byte, error) {
+	kv, err := e.getKV(ekey(key))
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return e.codec.DecodeBytesFromString(string(kv.Value))
+}
+
+func (e *v3engine) deleteKey(key key) error {
+	var resp *clientv3.DeleteResponse
+	err := e.retry(func(ctx context.Context) (err error) {
+		resp, err = e.client.Delete(ctx, ekey(key))
+		return err
+	})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if resp.Deleted == 0 {
+		return trace.NotFound("%v is not found", ekey(key))
+	}
+	return nil
+}
+
+func (e *v3engine) compareAndDelete(key key, prevVal interface{}) error {
+	encodedPrev, err := e.codec.EncodeToString(prevVal)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	k := ekey(key)
+	var resp *clientv3.TxnResponse
+	err = e.retry(func(ctx context.Context) (err error) {
+		resp, err = e.client.Txn(ctx).
+			If(clientv3.Compare(clientv3.Value(k), "=", encodedPrev)).
+			Then(clientv3.OpDelete(k)).
+			Else(clientv3.OpGet(k, clientv3.WithKeysOnly())).
+			Commit()
+		return err
+	})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return trace.Wrap(compareFailed(k, resp))
+}
+
+func (e *v3engine) createDir(key key, ttl time.Duration) error {
+	dir := dirKey(key)
+	lease, err := e.leaseFor(dir, ttl)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	var resp *clientv3.TxnResponse
+	err = e.retry(func(ctx context.Context) (err error) {
+		// the directory exists if there are any keys with its prefix
+		resp, err = e.client.Txn(ctx).
+			If(clientv3.Compare(clientv3.CreateRevision(dir), "=", 0).WithPrefix()).
+			Then(clientv3.OpPut(dir, "", lease.opts...)).
+			Commit()
+		return err
+	})
+	e.release(lease, resp, err)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if !resp.Succeeded {
+		return trace.AlreadyExists("%v already exists", dir)
+	}
+	return nil
+}
+
+// upsertDir creates the directory or resets its TTL
+func (e *v3engine) upsertDir(key key, ttl time.Duration) error {
+	dir := dirKey(key)
+	kv, err := e.getKV(dir)
+	if err != nil && !trace.IsNotFound(err) {
+		return trace.Wrap(err)
+	}
+	var prevLeaseID clientv3.LeaseID
+	if kv != nil {
+		prevLeaseID = clientv3.LeaseID(kv.Lease)
+	}
+	return trace.Wrap(e.updateDirTTL(dir, prevLeaseID, ttl))
+}
+
+func (e *v3engine) deleteDir(key key) error {
+	var resp *clientv3.DeleteResponse
+	err := e.retry(func(ctx context.Context) (err error) {
+		resp, err = e.client.Delete(ctx, dirKey(key), clientv3.WithPrefix())
+		return err
+	})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if resp.Deleted == 0 {
+		return trace.NotFound("%v is not found", dirKey(key))
+	}
+	return nil
+}
+
+// acquireLock blocks until the lock is acquired. Instead of polling,
+// it waits for the current lock holder to release or lose the lock
+func (e *v3engine) acquireLock(key key, ttl time.Duration) error {
+	for {
+		revision, err := e.tryLock(key, ttl)
+		if err == nil {
+			return nil
+		}
+		if !trace.IsAlreadyExists(err) {
+			return trace.Wrap(err)
+		}
+		e.waitDeleted(ekey(key), revision)
+	}
+}
+
+func (e *v3engine) tryAcquireLock(key key, ttl time.Duration) error {
+	_, err := e.tryLock(key, ttl)
+	return trace.Wrap(err)
+}
+
+// releaseLock releases the lock by revoking its lease
+func (e *v3engine) releaseLock(key key) error {
+	kv, err := e.getKV(ekey(key))
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if kv.Lease == 0 {
+		return trace.Wrap(e.deleteKey(key))
+	}
+	return trace.Wrap(e.revoke(clientv3.LeaseID(kv.Lease)))
+}
+
+func (e *v3engine) getKeys(key key) ([]string, error) {
+	dir := dirKey(key)
+	var resp *clientv3.GetResponse
+	err := e.retry(func(ctx context.Context) (err error) {
+		resp, err = e.client.Get(ctx, dir, clientv3.WithPrefix(), clientv3.WithKeysOnly())
+		return err
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	keys := make([]string, 0, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		keys = append(keys, string(kv.Key))
+	}
+	return childKeys(dir, keys), nil
+}
+
+// tryLock makes a single attempt to acquire the lock.
+// If the lock is held, returns AlreadyExists and the revision
+// to wait for the release of the lock from
+func (e *v3engine) tryLock(key key, ttl time.Duration) (revision int64, err error) {
+	k := ekey(key)
+	var leaseID clientv3.LeaseID
+	var opts []clientv3.OpOption
+	if ttl != forever {
+		leaseID, err = e.grant(ttl)
+		if err != nil {
+			return 0, trace.Wrap(err)
+		}
+		opts = append(opts, clientv3.WithLease(leaseID))
+	}
+	var resp *clientv3.TxnResponse
+	err = e.retry(func(ctx context.Context) (err error) {
+		resp, err = e.client.Txn(ctx).
+			If(clientv3.Compare(clientv3.CreateRevision(k), "=", 0)).
+			Then(clientv3.OpPut(k, "locked", opts...)).
+			Commit()
+		return err
+	})
+	if err == nil && resp.Succeeded {
+		return 0, nil
+	}
+	if leaseID != 0 {
+		if errRevoke := e.revoke(leaseID); errRevoke != nil {
+			log.Warnf("Failed to revoke lease for %v: %v.", k, errRevoke)
+		}
+	}
+	if err != nil {
+		return 0, trace.Wrap(err)
+	}
+	return resp.Header.Revision, trace.AlreadyExists("%v is locked", k)
+}
+
+// lockWaitTimeout limits the time to wait for the lock release
+// notification before making another attempt to acquire the lock
+const lockWaitTimeout = 10 * time.Second
+
+// waitDeleted waits until the specified key is deleted after the given revision.
+// The wait is bounded so that lost watch events do not block the caller forever
+func (e *v3engine) waitDeleted(key string, revision int64) {
+	ctx, cancel := context.WithTimeout(context.Background(), lockWaitTimeout)
+	defer cancel()
+	watchC := e.client.Watch(ctx, key, clientv3.WithRev(revision+1), clientv3.WithFilterPut())
+	for resp := range watchC {
+		if resp.Err() != nil {
+			time.Sleep(delayBetweenLockAttempts)
+			return
+		}
+		if len(resp.Events) != 0 {
+			return
+		}
+	}
+}
+
+func (e *v3engine) create(key, value string, ttl time.Duration) error {
+	lease, err := e.leaseFor(key, ttl)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	var resp *clientv3.TxnResponse
+	err = e.retry(func(ctx context.Context) (err error) {
+		resp, err = e.client.Txn(ctx).
+			If(clientv3.Compare(clientv3.CreateRevision(key), "=", 0)).
+			Then(clientv3.OpPut(key, value, lease.opts...)).
+			Commit()
+		return err
+	})
+	e.release(lease, resp, err)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if !resp.Succeeded {
+		return trace.AlreadyExists("%v already exists", key)
+	}
+	return nil
+}
+
+func (e *v3engine) update(key, value string, ttl time.Duration) error {
+	lease, err := e.leaseFor(key, ttl)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	var resp *clientv3.TxnResponse
+	err = e.retry(func(ctx context.Context) (err error) {
+		resp, err = e.client.Txn(ctx).
+			If(clientv3.Compare(clientv3.CreateRevision(key), ">", 0)).
+			Then(clientv3.OpPut(key, value, append(lease.opts, clientv3.WithPrevKV())...)).
+			Commit()
+		return err
+	})
+	e.release(lease, resp, err)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if !resp.Succeeded {
+		return trace.NotFound("%v is not found", key)
+	}
+	return nil
+}
+
+func (e *v3engine) swap(key, value, prevValue string, ttl time.Duration) error {
+	lease, err := e.leaseFor(key, ttl)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	var resp *clientv3.TxnResponse
+	err = e.retry(func(ctx context.Context) (err error) {
+		resp, err = e.client.Txn(ctx).
+			If(clientv3.Compare(clientv3.Value(key), "=", prevValue)).
+			Then(clientv3.OpPut(key, value, append(lease.opts, clientv3.WithPrevKV())...)).
+			Else(clientv3.OpGet(key, clientv3.WithKeysOnly())).
+			Commit()
+		return err
+	})
+	e.release(lease, resp, err)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return trace.Wrap(compareFailed(key, resp))
+}
+
+func (e *v3engine) put(key, value string, ttl time.Duration) error {
+	lease, err := e.leaseFor(key, ttl)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	var resp *clientv3.PutResponse
+	err = e.retry(func(ctx context.Context) (err error) {
+		resp, err = e.client.Put(ctx, key, value, append(lease.opts, clientv3.WithPrevKV())...)
+		return err
+	})
+	if err != nil {
+		lease.revoke(e)
+		return trace.Wrap(err)
+	}
+	e.releasePrev(lease, resp.PrevKv)
+	return nil
+}
+
+func (e *v3engine) getKV(key string) (*mvccpb.KeyValue, error) {
+	var resp *clientv3.GetResponse
+	err := e.retry(func(ctx context.Context) (err error) {
+		resp, err = e.client.Get(ctx, key)
+		return err
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if len(resp.Kvs) == 0 {
+		return nil, trace.NotFound("%v is not found", key)
+	}
+	return resp.Kvs[0], nil
+}
+
+// leaseFor returns the lease to attach the specified key with the given TTL to
+func (e *v3engine) leaseFor(key string, ttl time.Duration) (*v3lease, error) {
+	parent, err := e.parentLease(key)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return e.leaseIn(parent, ttl)
+}
+
+// leaseIn returns the lease for a key with the specified TTL in the directory
+// attached to the parent lease.
+//
+// A key expires with its directory: it shares the lease of the directory
+// unless its own TTL runs out earlier, since a key can only be attached
+// to a single lease
+func (e *v3engine) leaseIn(parent clientv3.LeaseID, ttl time.Duration) (*v3lease, error) {
+	if parent != 0 {
+		shared := ttl == forever
+		if !shared {
+			remaining, err := e.remaining(parent)
+			if err != nil {
+				return nil, trace.Wrap(err)
+			}
+			shared = remaining != 0 && remaining <= ttl
+		}
+		if shared {
+			return &v3lease{
+				id:     parent,
+				parent: parent,
+				opts:   []clientv3.OpOption{clientv3.WithLease(parent)},
+			}, nil
+		}
+	}
+	lease, err := e.lease(ttl)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	lease.parent = parent
+	return lease, nil
+}
+
+// parentLease returns the lease of the nearest directory with a TTL
+// the specified key is in or zero if none of its directories expires
+func (e *v3engine) parentLease(key string) (clientv3.LeaseID, error) {
+	dirs := e.parentDirs(key)
+	if len(dirs) == 0 {
+		return 0, nil
+	}
+	ops := make([]clientv3.Op, 0, len(dirs))
+	for _, dir := range dirs {
+		ops = append(ops, clientv3.OpGet(dir, clientv3.WithKeysOnly()))
+	}
+	var resp *clientv3.TxnResponse
+	err := e.retry(func(ctx context.Context) (err error) {
+		resp, err = e.client.Txn(ctx).Then(ops...).Commit()
+		return err
+	})
+	if err != nil {
+		return 0, trace.Wrap(err)
+	}
+	for _, op := range resp.Responses {
+		get := op.GetResponseRange()
+		if get != nil && len(get.Kvs) != 0 && get.Kvs[0].Lease != 0 {
+			return clientv3.LeaseID(get.Kvs[0].Lease), nil
+		}
+	}
+	return 0, nil
+}
+
+// parentDirs returns the markers of the directories the specified key is in,
+// nearest first, up to the root key of the backend
+func (e *v3engine) parentDirs(key string) (dirs []string) {
+	root := strings.Join(e.etcdKey, "/") + "/"
+	key = strings.TrimSuffix(key, "/")
+	for i := strings.LastIndex(key, "/"); i >= len(root); i = strings.LastIndex(key, "/") {
+		key = key[:i]
+		dirs = append(dirs, key+"/")
+	}
+	return dirs
+}
+
+// remaining returns the remaining time of the specified lease
+// or zero if the lease has expired
+func (e *v3engine) remaining(leaseID clientv3.LeaseID) (time.Duration, error) {
+	var resp *clientv3.LeaseTimeToLiveResponse
+	err := e.retry(func(ctx context.Context) (err error) {
+		resp, err = e.client.TimeToLive(ctx, leaseID)
+		return err
+	})
+	if err != nil {
+		return 0, trace.Wrap(err)
+	}
+	if resp.TTL <= 0 {
+		return 0, nil
+	}
+	return time.Duration(resp.TTL) * time.Second, nil
+}
+
+// lease grants a new lease with the specified TTL to attach to a key
+func (e *v3engine) lease(ttl time.Duration) (*v3lease, error) {
+	if ttl == forever {
+		return &v3lease{}, nil
+	}
+	leaseID, err := e.grant(ttl)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &v3lease{
+		id:   leaseID,
+		opts: []clientv3.OpOption{clientv3.WithLease(leaseID)},
+	}, nil
+}
+
+// v3lease is the lease granted for a single write
+type v3lease struct {
+	// id is the lease ID or zero if the key does not expire
+	id clientv3.LeaseID
+	// opts are the put options to attach the lease
+	opts []clientv3.OpOption
+	// parent is the lease of the directory the key is in.
+	// It is shared with the other keys in the directory and is never revoked
+	// by the write
+	parent clientv3.LeaseID
+}
+
+// revoke revokes the lease that has not been attached to the key
+func (r *v3lease) revoke(e *v3engine) {
+	if r.id != 0 && r.id != r.parent {
+		e.revokeUnused(r.id)
+	}
+}
+
+// release revokes the leases left unused by the conditional write:
+// the new lease if the write has not been applied or the lease
+// the key has been attached to before the write otherwise.
+// Each written key gets its own lease unless it shares the lease of
+// its directory, so the leases are not kept around until they expire
+func (e *v3engine) release(lease *v3lease, resp *clientv3.TxnResponse, err error) {
+	if err != nil || !resp.Succeeded {
+		lease.revoke(e)
+		return
+	}
+	if len(resp.Responses) == 0 {
+		return
+	}
+	if put := resp.Responses[0].GetResponsePut(); put != nil {
+		e.releasePrev(lease, put.PrevKv)
+	}
+}
+
+// releasePrev revokes the lease the key has been attached to before the write
+func (e *v3engine) releasePrev(lease *v3lease, prev *mvccpb.KeyValue) {
+	if prev == nil || prev.Lease == 0 || clientv3.LeaseID(prev.Lease) == lease.id ||
+		clientv3.LeaseID(prev.Lease) == lease.parent {
+		return
+	}
+	e.revokeUnused(clientv3.LeaseID(prev.Lease))
+}
+
+func (e *v3engine) grant(ttl time.Duration) (leaseID clientv3.LeaseID, err error) {
+	err = e.retry(func(ctx context.Context) error {
+		resp, err := e.client.Grant(ctx, leaseTTL(ttl))
+		if err != nil {
+			return err
+		}
+		leaseID = resp.ID
+		return nil
+	})
+	return leaseID, trace.Wrap(err)
+}
+
+// revokeUnused revokes the lease that is no longer attached to any key
+func (e *v3engine) revokeUnused(leaseID clientv3.LeaseID) {
+	if err := e.revoke(leaseID); err != nil && !trace.IsNotFound(err) {
+		log.Warnf("Failed to revoke lease %v: %v.", leaseID, err)
+	}
+}
+
+func (e *v3engine) revoke(leaseID clientv3.LeaseID) error {
+	return e.retry(func(ctx context.Context) error {
+		_, err := e.client.Revoke(ctx, leaseID)
+		return err
+	})
+}
+
+// retry retries the specified call on transient errors
+func (e *v3engine) retry(fn func(context.Context) error) error {
+	interval := backoff.NewExponentialBackOff()
+	interval.MaxElapsedTime = defaults.RetrySmallerMaxInterval
+	if e.cfg.RetryInterval != 0 {
+		interval.MaxElapsedTime = e.cfg.RetryInterval
+	}
+	err := backoff.Retry(func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), defaults.ReadHeadersTimeout)
+		defer cancel()
+		err := convertV3Err(fn(ctx))
+		if utils.IsTransientClusterError(err) {
+			log.Debugf("Retrying on transient etcd error: %v.", err)
+			return trace.Wrap(err)
+		}
+		if err != nil {
+			return &backoff.PermanentError{Err: err}
+		}
+		return nil
+	}, interval)
+	if perr, ok := err.(*backoff.PermanentError); ok {
+		return perr.Err
+	}
+	return err
+}
+
+// compareFailed interprets the result of a failed conditional transaction
+// whose else branch reads the key
+func compareFailed(key string, resp *clientv3.TxnResponse) error {
+	if resp.Succeeded {
+		return nil
+	}
+	if len(resp.Responses) != 0 {
+		if get := resp.Responses[0].GetResponseRange(); get != nil && len(get.Kvs) == 0 {
+			return trace.NotFound("%v is not found", key)
+		}
+	}
+	return trace.CompareFailed("%v does not have the expected value", key)
+}
+
+func convertV3Err(err error) error {
+	if err == nil {
+		return nil
+	}
+	if err == rpctypes.ErrLeaseNotFound || err == rpctypes.ErrGRPCLeaseNotFound {
+		return trace.NotFound("%v", err)
+	}
+	if err == context.DeadlineExceeded {
+		return trace.ConnectionProblem(err, "timed out talking to the etcd cluster")
+	}
+	if s, ok := status.FromError(err); ok {
+		switch s.Code() {
+		case codes.Unavailable, codes.DeadlineExceeded:
+			return trace.ConnectionProblem(err, "failed to connect to the etcd cluster")
+		case codes.NotFound:
+			return trace.NotFound("%v", s.Message())
+		case codes.AlreadyExists:
+			return trace.AlreadyExists("%v", s.Message())
+		case codes.FailedPrecondition:
+			return trace.CompareFailed("%v", s.Message())
+		}
+	}
+	return err
+}
+
+// dirKey returns the key of the marker of the specified directory
+// which is also the prefix of all keys in the directory
+func dirKey(key key) string {
+	return ekey(key) + "/"
+}
+
+// childKeys returns the sorted unique names of immediate children
+// of the directory with the specified prefix
+func childKeys(dir string, keys []string) []string {
+	seen := make(map[string]struct{})
+	var out []string
+	for _, key := range keys {
+		child := strings.SplitN(strings.TrimPrefix(key, dir), "/", 2)[0]
+		if child == "" {
+			// directory marker
+			continue
+		}
+		if _, ok := seen[child]; ok {
+			continue
+		}
+		seen[child] = struct{}{}
+		out = append(out, child)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// leaseTTL converts the TTL to lease duration in seconds
+func leaseTTL(ttl time.Duration) int64 {
+	return int64(math.Max(1, math.Ceil(ttl.Seconds())))
+}
@@ -18,6 +18,7 @@ package keyval
 
 import (
 	"context"
+	"io"
 	"time"
 
 	etcd "github.com/coreos/etcd/client"
@@ -48,6 +49,14 @@ func (b *electingBackend) StepDown() {
 	b.Leader.StepDown()
 }
 
+// Close stops the election and closes the backend
+func (b *electingBackend) Close() error {
+	if closer, ok := b.Leader.(io.Closer); ok {
+		closer.Close()
+	}
+	return b.Backend.Close()
+}
+
 // api returns etcd API client used by tests
 func (b *electingBackend) api() etcd.KeysAPI {
 	return etcd.NewKeysAPI(b.client)
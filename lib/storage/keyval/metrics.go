@@ -0,0 +1,179 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package keyval
+
+import (
+	"time"
+
+	"github.com/gravitational/trace"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	backendLatencies = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name: "gravity_backend_request_duration_seconds",
+			Help: "Latency of keyval backend requests",
+			// lowest bucket start of upper bound 0.0005 sec (0.5 ms) with factor 2
+			// highest bucket start of 0.0005 sec * 2^15 == 16.384 sec
+			Buckets: prometheus.ExponentialBuckets(0.0005, 2, 16),
+		},
+		[]string{"backend", "request"},
+	)
+	backendErrors = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "gravity_backend_errors_total",
+			Help: "Number of failed keyval backend requests",
+		},
+		[]string{"backend", "request"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(backendLatencies)
+	prometheus.MustRegister(backendErrors)
+}
+
+// meteredEngine is a kvengine that records the latency
+// and errors of the requests to the underlying engine
+type meteredEngine struct {
+	kvengine
+	// name is the backend name the metrics are labeled with
+	name string
+}
+
+// withMetrics returns the engine that records metrics of the requests
+// to the specified engine
+func withMetrics(engine kvengine, name string) *meteredEngine {
+	return &meteredEngine{kvengine: engine, name: name}
+}
+
+func (m *meteredEngine) createVal(key key, val interface{}, ttl time.Duration) error {
+	defer m.observe("create", time.Now())
+	return m.check("create", m.kvengine.createVal(key, val, ttl))
+}
+
+func (m *meteredEngine) createValBytes(key key, data []byte, ttl time.Duration) error {
+	defer m.observe("create", time.Now())
+	return m.check("create", m.kvengine.createValBytes(key, data, ttl))
+}
+
+func (m *meteredEngine) upsertVal(key key, val interface{}, ttl time.Duration) error {
+	defer m.observe("upsert", time.Now())
+	return m.check("upsert", m.kvengine.upsertVal(key, val, ttl))
+}
+
+func (m *meteredEngine) upsertValBytes(key key, data []byte, ttl time.Duration) error {
+	defer m.observe("upsert", time.Now())
+	return m.check("upsert", m.kvengine.upsertValBytes(key, data, ttl))
+}
+
+func (m *meteredEngine) updateVal(key key, val interface{}, ttl time.Duration) error {
+	defer m.observe("update", time.Now())
+	return m.check("update", m.kvengine.updateVal(key, val, ttl))
+}
+
+func (m *meteredEngine) updateValBytes(key key, data []byte, ttl time.Duration) error {
+	defer m.observe("update", time.Now())
+	return m.check("update", m.kvengine.updateValBytes(key, data, ttl))
+}
+
+func (m *meteredEngine) updateTTL(key key, ttl time.Duration) error {
+	defer m.observe("update_ttl", time.Now())
+	return m.check("update_ttl", m.kvengine.updateTTL(key, ttl))
+}
+
+func (m *meteredEngine) compareAndSwap(key key, val, prevVal, outVal interface{}, ttl time.Duration) error {
+	defer m.observe("compare_and_swap", time.Now())
+	return m.check("compare_and_swap", m.kvengine.compareAndSwap(key, val, prevVal, outVal, ttl))
+}
+
+func (m *meteredEngine) compareAndSwapBytes(key key, val, prevVal []byte, outVal *[]byte, ttl time.Duration) error {
+	defer m.observe("compare_and_swap", time.Now())
+	return m.check("compare_and_swap", m.kvengine.compareAndSwapBytes(key, val, prevVal, outVal, ttl))
+}
+
+func (m *meteredEngine) getVal(key key, val interface{}) error {
+	defer m.observe("get", time.Now())
+	return m.check("get", m.kvengine.getVal(key, val))
+}
+
+func (m *meteredEngine) getValBytes(key key) ([]byte, error) {
+	defer m.observe("get", time.Now())
+	data, err := m.kvengine.getValBytes(key)
+	return data, m.check("get", err)
+}
+
+func (m *meteredEngine) deleteKey(key key) error {
+	defer m.observe("delete", time.Now())
+	return m.check("delete", m.kvengine.deleteKey(key))
+}
+
+func (m *meteredEngine) compareAndDelete(key key, prevVal interface{}) error {
+	defer m.observe("compare_and_delete", time.Now())
+	return m.check("compare_and_delete", m.kvengine.compareAndDelete(key, prevVal))
+}
+
+func (m *meteredEngine) createDir(key key, ttl time.Duration) error {
+	defer m.observe("create_dir", time.Now())
+	return m.check("create_dir", m.kvengine.createDir(key, ttl))
+}
+
+func (m *meteredEngine) upsertDir(key key, ttl time.Duration) error {
+	defer m.observe("upsert_dir", time.Now())
+	return m.check("upsert_dir", m.kvengine.upsertDir(key, ttl))
+}
+
+func (m *meteredEngine) deleteDir(key key) error {
+	defer m.observe("delete_dir", time.Now())
+	return m.check("delete_dir", m.kvengine.deleteDir(key))
+}
+
+func (m *meteredEngine) acquireLock(key key, ttl time.Duration) error {
+	defer m.observe("acquire_lock", time.Now())
+	return m.check("acquire_lock", m.kvengine.acquireLock(key, ttl))
+}
+
+func (m *meteredEngine) tryAcquireLock(key key, ttl time.Duration) error {
+	defer m.observe("try_acquire_lock", time.Now())
+	return m.check("try_acquire_lock", m.kvengine.tryAcquireLock(key, ttl))
+}
+
+func (m *meteredEngine) releaseLock(key key) error {
+	defer m.observe("release_lock", time.Now())
+	return m.check("release_lock", m.kvengine.releaseLock(key))
+}
+
+func (m *meteredEngine) getKeys(key key) ([]string, error) {
+	defer m.observe("get_keys", time.Now())
+	keys, err := m.kvengine.getKeys(key)
+	return keys, m.check("get_keys", err)
+}
+
+func (m *meteredEngine) observe(request string, started time.Time) {
+	backendLatencies.WithLabelValues(m.name, request).Observe(time.Since(started).Seconds())
+}
+
+// check counts the error unless it is part of the regular
+// control flow like a missing key or a failed comparison
+func (m *meteredEngine) check(request string, err error) error {
+	if err == nil || trace.IsNotFound(err) || trace.IsAlreadyExists(err) || trace.IsCompareFailed(err) {
+		return err
+	}
+	backendErrors.WithLabelValues(m.name, request).Inc()
+	return err
+}
@@ -17,13 +17,17 @@ limitations under the License.
 package storage
 
 import (
+	"crypto/tls"
 	"encoding/json"
 	"fmt"
+	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/utils"
 
+	cfsslhelpers "github.com/cloudflare/cfssl/helpers"
 	teleservices "github.com/gravitational/teleport/lib/services"
 	teleutils "github.com/gravitational/teleport/lib/utils"
 	"github.com/gravitational/trace"
@@ -37,6 +41,15 @@ type LogForwarder interface {
 	GetAddress() string
 	// GetProtocol returns log forwarder protocol
 	GetProtocol() string
+	// GetTLS returns log forwarder TLS configuration, nil if TLS is not used
+	GetTLS() *LogForwarderTLS
+	// GetToken returns the token the log forwarder authenticates with
+	GetToken() string
+	// GetSelector returns the selector of the logs to forward
+	GetSelector() *LogForwarderSelector
+	// WithoutSecrets returns a copy of the log forwarder without
+	// the client key and the authentication token
+	WithoutSecrets() LogForwarder
 	// CheckAndSetDefaults validates log forwarder configuration
 	CheckAndSetDefaults() error
 }
@@ -69,6 +82,19 @@ func NewLogForwarder(name, address, protocol string) LogForwarder {
 	}
 }
 
+// NewLogForwarderWithSpec creates a new log forwarder with the specified spec
+func NewLogForwarderWithSpec(name string, spec LogForwarderSpecV2) LogForwarder {
+	return &LogForwarderV2{
+		Kind:    KindLogForwarder,
+		Version: teleservices.V2,
+		Metadata: teleservices.Metadata{
+			Name:      name,
+			Namespace: defaults.Namespace,
+		},
+		Spec: spec,
+	}
+}
+
 // NewLogForwarderFromV1 creates a new log forwarder from legacy format
 func NewLogForwarderFromV1(l LogForwarderV1) LogForwarder {
 	return NewLogForwarder(
@@ -115,6 +141,34 @@ func (l *LogForwarderV2) GetProtocol() string {
 	return l.Spec.Protocol
 }
 
+// GetTLS returns log forwarder TLS configuration, nil if TLS is not used
+func (l *LogForwarderV2) GetTLS() *LogForwarderTLS {
+	return l.Spec.TLS
+}
+
+// GetToken returns the token the log forwarder authenticates with
+func (l *LogForwarderV2) GetToken() string {
+	return l.Spec.Token
+}
+
+// GetSelector returns the selector of the logs to forward
+func (l *LogForwarderV2) GetSelector() *LogForwarderSelector {
+	return l.Spec.Selector
+}
+
+// WithoutSecrets returns a copy of the log forwarder without
+// the client key and the authentication token
+func (l *LogForwarderV2) WithoutSecrets() LogForwarder {
+	copy := *l
+	if l.Spec.TLS != nil {
+		tls := *l.Spec.TLS
+		tls.ClientKey = ""
+		copy.Spec.TLS = &tls
+	}
+	copy.Spec.Token = ""
+	return &copy
+}
+
 // CheckAndSetDefaults validates log forwarder configuration
 func (l *LogForwarderV2) CheckAndSetDefaults() error {
 	if l.Metadata.Name == "" {
@@ -131,6 +185,22 @@ func (l *LogForwarderV2) CheckAndSetDefaults() error {
 	} else {
 		l.Spec.Protocol = "tcp"
 	}
+	if l.Spec.TLS != nil {
+		if l.Spec.Protocol != "tcp" {
+			return trace.BadParameter("TLS is only supported with tcp protocol")
+		}
+		if err := l.Spec.TLS.Check(); err != nil {
+			return trace.Wrap(err)
+		}
+	}
+	if l.Spec.Token != "" && l.Spec.TLS == nil {
+		return trace.BadParameter("token authentication requires TLS")
+	}
+	if l.Spec.Selector != nil {
+		if err := l.Spec.Selector.Check(); err != nil {
+			return trace.Wrap(err)
+		}
+	}
 	return nil
 }
 
@@ -140,6 +210,80 @@ type LogForwarderSpecV2 struct {
 	Address string `json:"address"`
 	// Protocol is log forwarder protocol
 	Protocol string `json:"protocol,omitempty"`
+	// TLS specifies TLS settings for the connection to the remote log server.
+	// Logs are forwarded as plaintext if not set
+	TLS *LogForwarderTLS `json:"tls,omitempty"`
+	// Token is an optional token the log forwarder authenticates with.
+	// The token is sent as a structured data element of each message
+	Token string `json:"token,omitempty"`
+	// Selector limits the logs that are forwarded. All logs are forwarded if not set
+	Selector *LogForwarderSelector `json:"selector,omitempty"`
+}
+
+// LogForwarderTLS defines TLS settings of a log forwarder
+type LogForwarderTLS struct {
+	// CACert is the PEM-encoded certificate authority used to verify
+	// the remote server. System roots are used if not set
+	CACert string `json:"ca_cert,omitempty"`
+	// ClientCert is the PEM-encoded client certificate for mutual TLS
+	ClientCert string `json:"client_cert,omitempty"`
+	// ClientKey is the PEM-encoded private key of the client certificate
+	ClientKey string `json:"client_key,omitempty"`
+	// ServerName overrides the server name used to verify the remote server
+	// certificate. Defaults to the host part of the forwarder address
+	ServerName string `json:"server_name,omitempty"`
+}
+
+// Check validates the TLS settings
+func (t LogForwarderTLS) Check() error {
+	if t.CACert != "" {
+		if _, err := cfsslhelpers.ParseCertificatesPEM([]byte(t.CACert)); err != nil {
+			return trace.Wrap(err, "failed to parse CA certificate, expected PEM formatted block")
+		}
+	}
+	if (t.ClientCert == "") != (t.ClientKey == "") {
+		return trace.BadParameter("client certificate and key should be specified together")
+	}
+	if t.ClientCert != "" {
+		if _, err := tls.X509KeyPair([]byte(t.ClientCert), []byte(t.ClientKey)); err != nil {
+			return trace.BadParameter("invalid client certificate or key: %v", err)
+		}
+	}
+	return nil
+}
+
+// LogForwarderSelector selects logs to forward.
+// Each non-empty list narrows the selection, a log entry is forwarded
+// only if it matches all of them
+type LogForwarderSelector struct {
+	// Namespaces lists Kubernetes namespaces to forward logs from
+	Namespaces []string `json:"namespaces,omitempty"`
+	// Pods lists glob patterns of pod names to forward logs from
+	Pods []string `json:"pods,omitempty"`
+	// Containers lists glob patterns of container names to forward logs from
+	Containers []string `json:"containers,omitempty"`
+	// Severity is the minimum syslog severity of forwarded logs,
+	// for example "warning" forwards warning, err, crit, alert and emerg messages
+	Severity string `json:"severity,omitempty"`
+}
+
+// Check validates the selector
+func (s LogForwarderSelector) Check() error {
+	for _, pattern := range append(append([]string{}, s.Pods...), s.Containers...) {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return trace.BadParameter("invalid pattern %q: %v", pattern, err)
+		}
+	}
+	if s.Severity != "" && !utils.StringInSlice(LogSeverities, s.Severity) {
+		return trace.BadParameter("unsupported severity %q, must be one of: %v",
+			s.Severity, strings.Join(LogSeverities, ", "))
+	}
+	return nil
+}
+
+// LogSeverities lists syslog severities from the most to the least severe
+var LogSeverities = []string{
+	"emerg", "alert", "crit", "err", "warning", "notice", "info", "debug",
 }
 
 // LogForwarderV2Scheme is the log forwarder JSON schema
@@ -149,7 +293,28 @@ const LogForwarderV2Schema = `{
   "required": ["address"],
   "properties": {
     "address": {"type": "string"},
-    "protocol": {"type": "string"}
+    "protocol": {"type": "string"},
+    "tls": {
+      "type": "object",
+      "additionalProperties": false,
+      "properties": {
+        "ca_cert": {"type": "string"},
+        "client_cert": {"type": "string"},
+        "client_key": {"type": "string"},
+        "server_name": {"type": "string"}
+      }
+    },
+    "token": {"type": "string"},
+    "selector": {
+      "type": "object",
+      "additionalProperties": false,
+      "properties": {
+        "namespaces": {"type": "array", "items": {"type": "string"}},
+        "pods": {"type": "array", "items": {"type": "string"}},
+        "containers": {"type": "array", "items": {"type": "string"}},
+        "severity": {"type": "string"}
+      }
+    }
   }
 }`
 
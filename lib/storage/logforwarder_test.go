@@ -0,0 +1,117 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	teleutils "github.com/gravitational/teleport/lib/utils"
+	. "gopkg.in/check.v1"
+)
+
+type LogForwarderSuite struct{}
+
+var _ = Suite(&LogForwarderSuite{})
+
+func (s *LogForwarderSuite) TestValidatesLogForwarder(c *C) {
+	creds, err := teleutils.GenerateSelfSignedCert([]string{"localhost"})
+	c.Assert(err, IsNil)
+
+	testCases := []struct {
+		spec    LogForwarderSpecV2
+		error   string
+		comment string
+	}{
+		{
+			spec:    LogForwarderSpecV2{Address: "127.0.0.1:514"},
+			comment: "plaintext forwarder",
+		},
+		{
+			spec: LogForwarderSpecV2{
+				Address: "127.0.0.1:6514",
+				TLS: &LogForwarderTLS{
+					CACert:     string(creds.Cert),
+					ClientCert: string(creds.Cert),
+					ClientKey:  string(creds.PrivateKey),
+				},
+				Token: "token",
+				Selector: &LogForwarderSelector{
+					Namespaces: []string{"default"},
+					Pods:       []string{"api-*"},
+					Severity:   "warning",
+				},
+			},
+			comment: "TLS forwarder with token and selector",
+		},
+		{
+			spec: LogForwarderSpecV2{
+				Address:  "127.0.0.1:6514",
+				Protocol: "udp",
+				TLS:      &LogForwarderTLS{},
+			},
+			error:   "TLS is only supported with tcp protocol",
+			comment: "TLS over UDP",
+		},
+		{
+			spec: LogForwarderSpecV2{
+				Address: "127.0.0.1:6514",
+				TLS:     &LogForwarderTLS{ClientCert: string(creds.Cert)},
+			},
+			error:   "client certificate and key should be specified together",
+			comment: "client certificate without key",
+		},
+		{
+			spec:    LogForwarderSpecV2{Address: "127.0.0.1:514", Token: "token"},
+			error:   "token authentication requires TLS",
+			comment: "token without TLS",
+		},
+		{
+			spec: LogForwarderSpecV2{
+				Address:  "127.0.0.1:514",
+				Selector: &LogForwarderSelector{Severity: "fatal"},
+			},
+			error:   `unsupported severity "fatal".*`,
+			comment: "unknown severity",
+		},
+	}
+	for _, tc := range testCases {
+		comment := Commentf(tc.comment)
+		err := NewLogForwarderWithSpec("forwarder", tc.spec).CheckAndSetDefaults()
+		if tc.error != "" {
+			c.Assert(err, ErrorMatches, tc.error, comment)
+			continue
+		}
+		c.Assert(err, IsNil, comment)
+	}
+}
+
+func (s *LogForwarderSuite) TestRemovesSecrets(c *C) {
+	forwarder := NewLogForwarderWithSpec("siem", LogForwarderSpecV2{
+		Address: "siem.example.com:6514",
+		TLS: &LogForwarderTLS{
+			ClientCert: "cert",
+			ClientKey:  "key",
+		},
+		Token: "token",
+	})
+
+	redacted := forwarder.WithoutSecrets()
+	c.Assert(redacted.GetToken(), Equals, "")
+	c.Assert(redacted.GetTLS(), DeepEquals, &LogForwarderTLS{ClientCert: "cert"})
+	c.Assert(redacted.GetAddress(), Equals, "siem.example.com:6514")
+	c.Assert(forwarder.GetToken(), Equals, "token")
+	c.Assert(forwarder.GetTLS().ClientKey, Equals, "key",
+		Commentf("original forwarder should not be modified"))
+}
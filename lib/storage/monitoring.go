@@ -19,6 +19,10 @@ package storage
 import (
 	"encoding/json"
 	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/gravitational/gravity/lib/defaults"
 
 	teleservices "github.com/gravitational/teleport/lib/services"
 	teleutils "github.com/gravitational/teleport/lib/utils"
@@ -35,6 +39,19 @@ type Alert interface {
 	GetFormula() string
 }
 
+// NewAlert returns a new monitoring alert with the specified name and spec
+func NewAlert(name string, spec AlertSpecV2) Alert {
+	return &AlertV2{
+		Kind:    KindAlert,
+		Version: teleservices.V2,
+		Metadata: teleservices.Metadata{
+			Name:      name,
+			Namespace: defaults.Namespace,
+		},
+		Spec: spec,
+	}
+}
+
 // AlertV2 defines a monitoring alert
 type AlertV2 struct {
 	// Metadata is resource metadata
@@ -129,8 +146,32 @@ type AlertTarget interface {
 	teleservices.Resource
 	// CheckAndSetDefaults that the object is valid
 	CheckAndSetDefaults() error
+	// GetType returns the type of this alert target
+	GetType() string
 	// GetEmail returns the recipient's email
 	GetEmail() string
+	// GetWebhook returns the generic HTTP webhook configuration
+	GetWebhook() *WebhookAlertTarget
+	// GetSlack returns the Slack incoming webhook configuration
+	GetSlack() *SlackAlertTarget
+	// GetPagerDuty returns the PagerDuty Events API v2 configuration
+	GetPagerDuty() *PagerDutyAlertTarget
+	// WithoutSecrets returns a copy of the alert target without the
+	// credentials required to deliver alerts
+	WithoutSecrets() AlertTarget
+}
+
+// NewAlertTarget returns a new alert target with the specified name and spec
+func NewAlertTarget(name string, spec AlertTargetSpecV2) *AlertTargetV2 {
+	return &AlertTargetV2{
+		Kind:    KindAlertTarget,
+		Version: teleservices.V2,
+		Metadata: teleservices.Metadata{
+			Name:      name,
+			Namespace: defaults.Namespace,
+		},
+		Spec: spec,
+	}
 }
 
 // AlertTargetV2 defines a monitoring alert target
@@ -145,17 +186,111 @@ type AlertTargetV2 struct {
 	Spec AlertTargetSpecV2 `json:"spec"`
 }
 
+// GetType returns the type of this alert target
+func (r *AlertTargetV2) GetType() string {
+	switch {
+	case r.Spec.Webhook != nil:
+		return AlertTargetWebhook
+	case r.Spec.Slack != nil:
+		return AlertTargetSlack
+	case r.Spec.PagerDuty != nil:
+		return AlertTargetPagerDuty
+	default:
+		return AlertTargetEmail
+	}
+}
+
 // GetEmail returns recipient's email
 func (r *AlertTargetV2) GetEmail() string {
 	return r.Spec.Email
 }
 
+// GetWebhook returns the generic HTTP webhook configuration
+func (r *AlertTargetV2) GetWebhook() *WebhookAlertTarget {
+	return r.Spec.Webhook
+}
+
+// GetSlack returns the Slack incoming webhook configuration
+func (r *AlertTargetV2) GetSlack() *SlackAlertTarget {
+	return r.Spec.Slack
+}
+
+// GetPagerDuty returns the PagerDuty Events API v2 configuration
+func (r *AlertTargetV2) GetPagerDuty() *PagerDutyAlertTarget {
+	return r.Spec.PagerDuty
+}
+
+// WithoutSecrets returns a copy of the alert target without the
+// credentials required to deliver alerts: webhook header values and
+// signing secret, Slack webhook URL and PagerDuty routing key
+func (r *AlertTargetV2) WithoutSecrets() AlertTarget {
+	copy := *r
+	if r.Spec.Webhook != nil {
+		webhook := *r.Spec.Webhook
+		if len(webhook.Headers) != 0 {
+			webhook.Headers = make(map[string]string, len(r.Spec.Webhook.Headers))
+			for name := range r.Spec.Webhook.Headers {
+				webhook.Headers[name] = ""
+			}
+		}
+		webhook.HMACSecret = ""
+		copy.Spec.Webhook = &webhook
+	}
+	if r.Spec.Slack != nil {
+		slack := *r.Spec.Slack
+		slack.URL = ""
+		copy.Spec.Slack = &slack
+	}
+	if r.Spec.PagerDuty != nil {
+		pagerDuty := *r.Spec.PagerDuty
+		pagerDuty.RoutingKey = ""
+		copy.Spec.PagerDuty = &pagerDuty
+	}
+	return &copy
+}
+
 // CheckAndSetDefaults checks validity of all parameters and sets defaults
 func (r *AlertTargetV2) CheckAndSetDefaults() error {
-	if r.Spec.Email == "" {
-		return trace.BadParameter("missing parameter Email")
+	var targets []string
+	if r.Spec.Email != "" {
+		targets = append(targets, AlertTargetEmail)
+	}
+	if r.Spec.Webhook != nil {
+		targets = append(targets, AlertTargetWebhook)
+		if err := checkURL(r.Spec.Webhook.URL); err != nil {
+			return trace.Wrap(err, "invalid webhook URL")
+		}
+	}
+	if r.Spec.Slack != nil {
+		targets = append(targets, AlertTargetSlack)
+		if err := checkURL(r.Spec.Slack.URL); err != nil {
+			return trace.Wrap(err, "invalid Slack webhook URL")
+		}
+	}
+	if r.Spec.PagerDuty != nil {
+		targets = append(targets, AlertTargetPagerDuty)
+		if r.Spec.PagerDuty.RoutingKey == "" {
+			return trace.BadParameter("missing PagerDuty routing key")
+		}
+		if r.Spec.PagerDuty.URL == "" {
+			r.Spec.PagerDuty.URL = defaults.PagerDutyEventsURL
+		}
+		if err := checkURL(r.Spec.PagerDuty.URL); err != nil {
+			return trace.Wrap(err, "invalid PagerDuty URL")
+		}
+	}
+	switch len(targets) {
+	case 0:
+		return trace.BadParameter("alert target should specify one of: %v",
+			strings.Join(AllAlertTargets, ", "))
+	case 1:
+	default:
+		return trace.BadParameter("alert target should specify only one of: %v",
+			strings.Join(targets, ", "))
+	}
+	if r.GetType() != AlertTargetEmail && r.Metadata.Name == "" {
+		return trace.BadParameter("missing parameter Name")
 	}
-
 	return nil
 }
 
@@ -195,19 +330,89 @@ func MarshalAlertTarget(target AlertTarget, opts ...teleservices.MarshalOption)
 	return json.Marshal(target)
 }
 
-// AlertTargetSpecV2 defines a monitoring alert target
+// AlertTargetSpecV2 defines a monitoring alert target.
+// Exactly one of the target types should be specified
 type AlertTargetSpecV2 struct {
 	// Email specifies recipient's email
-	Email string `json:"email"`
+	Email string `json:"email,omitempty"`
+	// Webhook specifies a generic HTTP webhook target
+	Webhook *WebhookAlertTarget `json:"webhook,omitempty"`
+	// Slack specifies a Slack incoming webhook target
+	Slack *SlackAlertTarget `json:"slack,omitempty"`
+	// PagerDuty specifies a PagerDuty Events API v2 target
+	PagerDuty *PagerDutyAlertTarget `json:"pagerduty,omitempty"`
+}
+
+// WebhookAlertTarget defines a generic HTTP webhook alert target
+type WebhookAlertTarget struct {
+	// URL is the webhook URL alerts are posted to
+	URL string `json:"url"`
+	// Headers specifies additional HTTP headers to send with each request
+	Headers map[string]string `json:"headers,omitempty"`
+	// HMACSecret is an optional secret used to sign request payloads.
+	// If set, each request carries the hex-encoded HMAC-SHA256 signature
+	// of the payload in the X-Gravity-Signature header
+	HMACSecret string `json:"hmac_secret,omitempty"`
+}
+
+// SlackAlertTarget defines a Slack incoming webhook alert target
+type SlackAlertTarget struct {
+	// URL is the Slack incoming webhook URL
+	URL string `json:"url"`
+	// Channel optionally overrides the default channel of the webhook
+	Channel string `json:"channel,omitempty"`
+	// Username optionally overrides the default username of the webhook
+	Username string `json:"username,omitempty"`
+}
+
+// PagerDutyAlertTarget defines a PagerDuty Events API v2 alert target
+type PagerDutyAlertTarget struct {
+	// RoutingKey is the integration key of the PagerDuty service
+	RoutingKey string `json:"routing_key"`
+	// URL optionally overrides the PagerDuty Events API v2 endpoint
+	URL string `json:"url,omitempty"`
 }
 
 // AlertTargetSpecV2Schema is JSON schema for a monitoring alert target
 const AlertTargetSpecV2Schema = `{
   "type": "object",
   "additionalProperties": false,
-  "required": ["email"],
   "properties": {
-    "email": {"type": "string"}
+    "email": {"type": "string"},
+    "webhook": {
+      "type": "object",
+      "additionalProperties": false,
+      "required": ["url"],
+      "properties": {
+        "url": {"type": "string"},
+        "headers": {
+          "type": "object",
+          "patternProperties": {
+             "^.+$": {"type": "string"}
+          }
+        },
+        "hmac_secret": {"type": "string"}
+      }
+    },
+    "slack": {
+      "type": "object",
+      "additionalProperties": false,
+      "required": ["url"],
+      "properties": {
+        "url": {"type": "string"},
+        "channel": {"type": "string"},
+        "username": {"type": "string"}
+      }
+    },
+    "pagerduty": {
+      "type": "object",
+      "additionalProperties": false,
+      "required": ["routing_key"],
+      "properties": {
+        "routing_key": {"type": "string"},
+        "url": {"type": "string"}
+      }
+    }
   }
 }`
 
@@ -216,3 +421,40 @@ func GetAlertTargetSchema() string {
 	return fmt.Sprintf(teleservices.V2SchemaTemplate, teleservices.MetadataSchema,
 		AlertTargetSpecV2Schema, "")
 }
+
+// checkURL makes sure the specified value is a valid HTTP(S) URL
+func checkURL(value string) error {
+	if value == "" {
+		return trace.BadParameter("URL cannot be empty")
+	}
+	u, err := url.Parse(value)
+	if err != nil {
+		return trace.BadParameter("failed to parse URL %q: %v", value, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return trace.BadParameter("URL %q should use either http or https scheme", value)
+	}
+	if u.Host == "" {
+		return trace.BadParameter("URL %q is missing host", value)
+	}
+	return nil
+}
+
+const (
+	// AlertTargetEmail is the email alert target delivered by Kapacitor over SMTP
+	AlertTargetEmail = "email"
+	// AlertTargetWebhook is the generic HTTP webhook alert target
+	AlertTargetWebhook = "webhook"
+	// AlertTargetSlack is the Slack incoming webhook alert target
+	AlertTargetSlack = "slack"
+	// AlertTargetPagerDuty is the PagerDuty Events API v2 alert target
+	AlertTargetPagerDuty = "pagerduty"
+)
+
+// AllAlertTargets lists all supported alert target types
+var AllAlertTargets = []string{
+	AlertTargetEmail,
+	AlertTargetWebhook,
+	AlertTargetSlack,
+	AlertTargetPagerDuty,
+}
@@ -0,0 +1,135 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"github.com/gravitational/gravity/lib/defaults"
+
+	. "gopkg.in/check.v1"
+)
+
+type MonitoringSuite struct{}
+
+var _ = Suite(&MonitoringSuite{})
+
+func (s *MonitoringSuite) TestParsesAlertTargets(c *C) {
+	testCases := []struct {
+		in      string
+		target  AlertTargetSpecV2
+		error   string
+		comment string
+	}{
+		{
+			in: `kind: alerttarget
+version: v2
+metadata:
+  name: email
+spec:
+  email: admin@example.com`,
+			target:  AlertTargetSpecV2{Email: "admin@example.com"},
+			comment: "email target",
+		},
+		{
+			in: `kind: alerttarget
+version: v2
+metadata:
+  name: pagerduty
+spec:
+  pagerduty:
+    routing_key: key`,
+			target: AlertTargetSpecV2{PagerDuty: &PagerDutyAlertTarget{
+				RoutingKey: "key",
+				URL:        defaults.PagerDutyEventsURL,
+			}},
+			comment: "PagerDuty target with default URL",
+		},
+		{
+			in: `kind: alerttarget
+version: v2
+metadata:
+  name: hook
+spec:
+  webhook:
+    url: ftp://example.com`,
+			error:   ".*should use either http or https scheme.*",
+			comment: "webhook with invalid URL",
+		},
+		{
+			in: `kind: alerttarget
+version: v2
+metadata:
+  name: both
+spec:
+  email: admin@example.com
+  slack:
+    url: https://hooks.slack.com/services/T/B/X`,
+			error:   ".*should specify only one of: email, slack.*",
+			comment: "multiple target types",
+		},
+		{
+			in: `kind: alerttarget
+version: v2
+metadata:
+  name: empty
+spec: {}`,
+			error:   ".*should specify one of.*",
+			comment: "no target type",
+		},
+	}
+	for _, tc := range testCases {
+		comment := Commentf(tc.comment)
+		target, err := UnmarshalAlertTarget([]byte(tc.in))
+		c.Assert(err, IsNil, comment)
+		err = target.CheckAndSetDefaults()
+		if tc.error != "" {
+			c.Assert(err, ErrorMatches, tc.error, comment)
+			continue
+		}
+		c.Assert(err, IsNil, comment)
+		c.Assert(target.Spec, DeepEquals, tc.target, comment)
+	}
+}
+
+func (s *MonitoringSuite) TestAlertTargetWithoutSecrets(c *C) {
+	target := NewAlertTarget("hook", AlertTargetSpecV2{
+		Webhook: &WebhookAlertTarget{
+			URL:        "https://example.com/alerts",
+			Headers:    map[string]string{"Authorization": "Bearer token"},
+			HMACSecret: "secret",
+		},
+	})
+
+	redacted := target.WithoutSecrets()
+	c.Assert(redacted.GetWebhook(), DeepEquals, &WebhookAlertTarget{
+		URL:     "https://example.com/alerts",
+		Headers: map[string]string{"Authorization": ""},
+	})
+	// The original target is not modified
+	c.Assert(target.GetWebhook().HMACSecret, Equals, "secret")
+	c.Assert(target.GetWebhook().Headers["Authorization"], Equals, "Bearer token")
+
+	// Redacted target can still be marshaled and unmarshaled
+	data, err := MarshalAlertTarget(redacted)
+	c.Assert(err, IsNil)
+	_, err = UnmarshalAlertTarget(data)
+	c.Assert(err, IsNil)
+
+	target = NewAlertTarget("pd", AlertTargetSpecV2{
+		PagerDuty: &PagerDutyAlertTarget{RoutingKey: "key"},
+	})
+	c.Assert(target.WithoutSecrets().GetPagerDuty().RoutingKey, Equals, "")
+}
@@ -148,6 +148,9 @@ type UpdateOperationData struct {
 	// Servers lists the subset of cluster servers to use for the step in case
 	// the operation needs to operate not on the whole cluster
 	Servers []Server `json:"servers,omitempty" yaml:"servers,omitempty"`
+	// ChangesetID optionally specifies the ID of the package changeset
+	// to record the system update with. Defaults to the operation ID
+	ChangesetID string `json:"changeset_id,omitempty" yaml:"changeset_id,omitempty"`
 }
 
 // InstallOperationData describes configuration for the install operation
@@ -69,6 +69,14 @@ const (
 	KindRuntimeEnvironment = "runtimeenvironment"
 	// KindClusterConfiguration defines the resource that manages cluster configuration
 	KindClusterConfiguration = "clusterconfiguration"
+	// KindBackupSchedule defines the scheduled cluster backup resource type
+	KindBackupSchedule = "backupschedule"
+	// KindOperation defines the cluster operation resource type
+	KindOperation = "operation"
+	// KindAuditEvent defines the audit log event resource type
+	KindAuditEvent = "auditevent"
+	// KindUserInvite defines the user invite resource type
+	KindUserInvite = "userinvite"
 )
 
 // CanonicalKind translates the specified kind to canonical form.
@@ -101,6 +109,14 @@ func CanonicalKind(kind string) string {
 		return KindClusterConfiguration
 	case KindAuthGateway, "gw":
 		return KindAuthGateway
+	case KindBackupSchedule, "backupschedules", "backup", "backups":
+		return KindBackupSchedule
+	case KindOperation, "operations", "op", "ops":
+		return KindOperation
+	case KindAuditEvent, "auditevents", "audit":
+		return KindAuditEvent
+	case KindUserInvite, "userinvites", "invite", "invites":
+		return KindUserInvite
 	}
 	return kind
 }
@@ -167,6 +183,7 @@ var SupportedGravityResources = []string{
 	KindAuthGateway,
 	KindRuntimeEnvironment,
 	KindClusterConfiguration,
+	KindBackupSchedule,
 }
 
 // SupportedGravityResourcesToRemove is a list of resources supported by
@@ -182,6 +199,7 @@ var SupportedGravityResourcesToRemove = []string{
 	KindTLSKeyPair,
 	KindRuntimeEnvironment,
 	KindClusterConfiguration,
+	KindBackupSchedule,
 }
 
 // MetadataSchema is a copy of teleport/lib/services.MetadataSchema but with
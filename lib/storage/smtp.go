@@ -41,6 +41,21 @@ type SMTPConfig interface {
 	GetUsername() string
 	// GetPassword returns SMTP password
 	GetPassword() string
+	// WithoutSecrets returns a copy of the configuration without the password
+	WithoutSecrets() SMTPConfig
+}
+
+// NewSMTPConfig returns a new SMTP configuration with the specified spec
+func NewSMTPConfig(spec SMTPConfigSpecV2) SMTPConfig {
+	return &SMTPConfigV2{
+		Kind:    KindSMTPConfig,
+		Version: teleservices.V2,
+		Metadata: teleservices.Metadata{
+			Name:      KindSMTPConfig,
+			Namespace: defaults.Namespace,
+		},
+		Spec: spec,
+	}
 }
 
 // SMTPConfigV2 defines SMTP configuration
@@ -75,6 +90,13 @@ func (r *SMTPConfigV2) GetPassword() string {
 	return r.Spec.Password
 }
 
+// WithoutSecrets returns a copy of the configuration without the password
+func (r *SMTPConfigV2) WithoutSecrets() SMTPConfig {
+	copy := *r
+	copy.Spec.Password = ""
+	return &copy
+}
+
 // CheckAndSetDefaults checks validity of all parameters and sets defaults
 func (r *SMTPConfigV2) CheckAndSetDefaults() error {
 	if r.Spec.Host == "" {
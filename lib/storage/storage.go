@@ -846,6 +846,9 @@ type ProgressEntries interface {
 	CreateProgressEntry(p ProgressEntry) (*ProgressEntry, error)
 	// GetLastProgressEntry gets a progress entry for this site
 	GetLastProgressEntry(siteDomain, operationID string) (*ProgressEntry, error)
+	// GetProgressEntries returns all progress entries of the operation
+	// ordered by creation time
+	GetProgressEntries(siteDomain, operationID string) ([]ProgressEntry, error)
 }
 
 // Package is any named and versioned blob with an optional manifest
@@ -1298,6 +1301,38 @@ type Peer struct {
 	ID            string    `json:"id"`
 	AdvertiseAddr string    `json:"advertise_addr"`
 	LastHeartbeat time.Time `json:"last_heartbeat"`
+	// Integrity is the outcome of the last integrity check
+	// of the objects stored on this peer
+	Integrity *BLOBIntegrity `json:"integrity,omitempty"`
+}
+
+// BLOBIntegrity describes the outcome of the integrity check
+// of the objects stored on a peer
+type BLOBIntegrity struct {
+	// Checked is the time of the check
+	Checked time.Time `json:"checked"`
+	// Objects is the number of verified objects
+	Objects int `json:"objects"`
+	// Corrupted lists the objects that did not match their hash
+	Corrupted []string `json:"corrupted,omitempty"`
+	// Repaired lists the corrupted objects that have been
+	// fetched again from other peers
+	Repaired []string `json:"repaired,omitempty"`
+	// Replicated lists the objects that have been
+	// replicated to other peers
+	Replicated []string `json:"replicated,omitempty"`
+	// UnderReplicated lists the objects that are stored on fewer
+	// active peers than required by the write factor
+	UnderReplicated []string `json:"under_replicated,omitempty"`
+	// Error is the error that interrupted the check
+	Error string `json:"error,omitempty"`
+}
+
+// IsHealthy returns true if no problems were found during the check
+// or all of them have been repaired
+func (r BLOBIntegrity) IsHealthy() bool {
+	return r.Error == "" && len(r.UnderReplicated) == 0 &&
+		len(r.Corrupted) == len(r.Repaired)
 }
 
 func (p Peer) String() string {
@@ -1472,6 +1507,8 @@ type Backend interface {
 	LegacyRoles
 	SystemMetadata
 	Charts
+	BackupSchedules
+	AuditEvents
 }
 
 const (
@@ -1992,6 +2029,65 @@ type UpdateOperationState struct {
 	ServerUpdates []ServerUpdate `json:"server_updates,omitempty"`
 	// Manual specifies whether this update operation was created in manual mode
 	Manual bool `json:"manual"`
+	// Strategy optionally specifies how the nodes are rolled during the update
+	Strategy *UpdateStrategy `json:"strategy,omitempty"`
+	// AutoRollback specifies whether the update is rolled back automatically
+	// if it fails or the application status check fails after the update
+	AutoRollback bool `json:"auto_rollback,omitempty"`
+	// Parallelism specifies the maximum number of plan phases to execute concurrently
+	Parallelism int `json:"parallelism,omitempty"`
+}
+
+// UpdateStrategy describes how the update operation rolls out
+// system software across the cluster nodes
+type UpdateStrategy struct {
+	// BatchSize specifies the number of regular nodes to update at once
+	BatchSize int `json:"batch_size,omitempty"`
+	// MaxUnavailable specifies the maximum number of regular nodes that
+	// can be unavailable during the update at any given time.
+	// Master nodes are always updated one at a time
+	MaxUnavailable int `json:"max_unavailable,omitempty"`
+	// Canary specifies whether a single node is updated and verified
+	// before the rest of the nodes
+	Canary bool `json:"canary,omitempty"`
+	// CanaryPause specifies how long to wait after the canary node has been
+	// verified before updating the remaining nodes.
+	// If unspecified, the update waits for the explicit approval
+	CanaryPause time.Duration `json:"canary_pause,omitempty"`
+}
+
+// Check validates this strategy
+func (r UpdateStrategy) Check() error {
+	if r.BatchSize < 0 {
+		return trace.BadParameter("batch size cannot be negative")
+	}
+	if r.MaxUnavailable < 0 {
+		return trace.BadParameter("max unavailable cannot be negative")
+	}
+	if r.CanaryPause < 0 {
+		return trace.BadParameter("canary pause cannot be negative")
+	}
+	if r.CanaryPause != 0 && !r.Canary {
+		return trace.BadParameter("canary pause requires canary update")
+	}
+	return nil
+}
+
+// NodeBatchSize returns the number of nodes to update at once.
+// The batch size defaults to max unavailable (or a single node if
+// unspecified) and never exceeds max unavailable
+func (r UpdateStrategy) NodeBatchSize() int {
+	size := r.BatchSize
+	if size == 0 {
+		size = r.MaxUnavailable
+	}
+	if r.MaxUnavailable != 0 && size > r.MaxUnavailable {
+		size = r.MaxUnavailable
+	}
+	if size == 0 {
+		size = 1
+	}
+	return size
 }
 
 // UpdateEnvarsOperationState describes the state of the operation to update cluster environment variables.
@@ -2093,3 +2189,15 @@ type Charts interface {
 	// UpsertIndexFile creates or replaces chart repository index file.
 	UpsertIndexFile(repo.IndexFile) error
 }
+
+// BackupSchedules manages scheduled cluster backups
+type BackupSchedules interface {
+	// UpsertBackupSchedule creates or updates the backup schedule
+	UpsertBackupSchedule(BackupSchedule) error
+	// GetBackupSchedule returns the backup schedule by name
+	GetBackupSchedule(name string) (BackupSchedule, error)
+	// GetBackupSchedules returns all backup schedules
+	GetBackupSchedules() ([]BackupSchedule, error)
+	// DeleteBackupSchedule deletes the backup schedule by name
+	DeleteBackupSchedule(name string) error
+}
@@ -894,6 +894,10 @@ func (s *StorageSuite) ProgressEntriesCRUD(c *C) {
 	c.Assert(err, IsNil)
 	c.Assert(*ope2, DeepEquals, pe2)
 
+	entries, err := s.Backend.GetProgressEntries(sa.Domain, op.ID)
+	c.Assert(err, IsNil)
+	c.Assert(entries, DeepEquals, []storage.ProgressEntry{pe1, pe2})
+
 	// Create for non existent site should fail
 	_, err = s.Backend.CreateProgressEntry(storage.ProgressEntry{
 		SiteDomain:  "nothere.com",
@@ -1954,6 +1958,90 @@ func (s *StorageSuite) IndexFile(c *C) {
 	compare.DeepCompare(c, retrievedFile, updatedIndex2)
 }
 
+func (s *StorageSuite) BackupSchedulesCRUD(c *C) {
+	schedule := storage.NewBackupSchedule("nightly", storage.BackupScheduleSpecV2{
+		Schedule: "0 2 * * *",
+		Destination: storage.BackupDestination{
+			S3: &storage.BackupS3Destination{Bucket: "backups"},
+		},
+	})
+
+	_, err := s.Backend.GetBackupSchedule(schedule.GetName())
+	c.Assert(trace.IsNotFound(err), Equals, true, Commentf("%v", err))
+
+	c.Assert(s.Backend.UpsertBackupSchedule(schedule), IsNil)
+
+	out, err := s.Backend.GetBackupSchedule(schedule.GetName())
+	c.Assert(err, IsNil)
+	compare.DeepCompare(c, out, schedule)
+
+	schedule.SetStatus(storage.BackupScheduleStatus{
+		LastRun:     now,
+		LastFailure: now,
+		LastError:   "hook failed",
+	})
+	c.Assert(s.Backend.UpsertBackupSchedule(schedule), IsNil)
+
+	schedules, err := s.Backend.GetBackupSchedules()
+	c.Assert(err, IsNil)
+	compare.DeepCompare(c, schedules, []storage.BackupSchedule{schedule})
+
+	c.Assert(s.Backend.DeleteBackupSchedule(schedule.GetName()), IsNil)
+	err = s.Backend.DeleteBackupSchedule(schedule.GetName())
+	c.Assert(trace.IsNotFound(err), Equals, true, Commentf("%v", err))
+
+	schedules, err = s.Backend.GetBackupSchedules()
+	c.Assert(err, IsNil)
+	c.Assert(schedules, HasLen, 0)
+}
+
+func (s *StorageSuite) AuditEvents(c *C) {
+	events := []storage.AuditEvent{
+		{
+			Time:   now.Add(-time.Hour),
+			User:   "alice@example.com",
+			Verb:   teleservices.VerbCreate,
+			Kind:   teleservices.KindUser,
+			Name:   "bob@example.com",
+			Result: storage.AuditResultSuccess,
+		},
+		{
+			Time:   now,
+			User:   "bob@example.com",
+			Verb:   teleservices.VerbUpdate,
+			Kind:   storage.KindSMTPConfig,
+			Diff:   "-host: smtp.example.com\n+host: mail.example.com",
+			Result: storage.AuditResultFailure,
+			Error:  "access denied",
+		},
+	}
+	for i := range events {
+		out, err := s.Backend.CreateAuditEvent(events[i])
+		c.Assert(err, IsNil)
+		c.Assert(out.ID, Not(Equals), "")
+		events[i].ID = out.ID
+	}
+
+	out, err := s.Backend.GetAuditEvents(storage.AuditEventFilter{})
+	c.Assert(err, IsNil)
+	compare.DeepCompare(c, out, events)
+
+	out, err = s.Backend.GetAuditEvents(storage.AuditEventFilter{Since: now.Add(-time.Minute)})
+	c.Assert(err, IsNil)
+	compare.DeepCompare(c, out, events[1:])
+
+	out, err = s.Backend.GetAuditEvents(storage.AuditEventFilter{User: "alice@example.com"})
+	c.Assert(err, IsNil)
+	compare.DeepCompare(c, out, events[:1])
+
+	out, err = s.Backend.GetAuditEvents(storage.AuditEventFilter{Kind: "smtps"})
+	c.Assert(err, IsNil)
+	compare.DeepCompare(c, out, events[1:])
+
+	_, err = s.Backend.CreateAuditEvent(storage.AuditEvent{Time: now, Verb: teleservices.VerbDelete})
+	c.Assert(trace.IsBadParameter(err), Equals, true, Commentf("%v", err))
+}
+
 func newIndex() *repo.IndexFile {
 	return &repo.IndexFile{
 		APIVersion: repo.APIVersionV1,
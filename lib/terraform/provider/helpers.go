@@ -1,6 +1,11 @@
 package provider
 
 import (
+	"encoding/json"
+	"reflect"
+	"time"
+
+	"github.com/gravitational/trace"
 	"github.com/hashicorp/terraform/helper/schema"
 )
 
@@ -29,3 +34,43 @@ func ExpandStringMap(v map[string]interface{}) map[string]string {
 	}
 	return m
 }
+
+// resourceExists interprets the result of reading a resource for use in
+// the resource Exists callback: a missing resource is reported as absent
+// so terraform can detect out-of-band deletions and plan re-creation
+func resourceExists(readErr error) (bool, error) {
+	if readErr != nil {
+		if trace.IsNotFound(readErr) {
+			return false, nil
+		}
+		return false, trace.Wrap(readErr)
+	}
+	return true, nil
+}
+
+// suppressEquivalentDuration suppresses the diff between two duration
+// strings that describe the same duration, e.g. 30m and 30m0s
+func suppressEquivalentDuration(k, old, new string, d *schema.ResourceData) bool {
+	oldDuration, err := time.ParseDuration(old)
+	if err != nil {
+		return false
+	}
+	newDuration, err := time.ParseDuration(new)
+	if err != nil {
+		return false
+	}
+	return oldDuration == newDuration
+}
+
+// suppressEquivalentJSON suppresses the diff between two JSON documents
+// that only differ in formatting or the order of object keys
+func suppressEquivalentJSON(k, old, new string, d *schema.ResourceData) bool {
+	var oldValue, newValue interface{}
+	if err := json.Unmarshal([]byte(old), &oldValue); err != nil {
+		return false
+	}
+	if err := json.Unmarshal([]byte(new), &newValue); err != nil {
+		return false
+	}
+	return reflect.DeepEqual(oldValue, newValue)
+}
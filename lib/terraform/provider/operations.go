@@ -0,0 +1,77 @@
+package provider
+
+import (
+	"log"
+
+	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/ops/opsclient"
+	"github.com/gravitational/trace"
+
+	"github.com/hashicorp/terraform/helper/schema"
+)
+
+// operationInitializer creates a cluster operation and its operation plan
+type operationInitializer struct {
+	// newOperation creates the operation for the specified cluster
+	newOperation func(ops.Operator, ops.Site) (*ops.SiteOperationKey, error)
+	// newOperationPlan creates the plan for the specified operation
+	newOperationPlan func(ops.Operator, ops.SiteOperation, ops.Site) error
+}
+
+// startOperation creates a cluster operation along with its operation plan
+// through the cluster operator.
+// The operation is only prepared here - it is executed from one of the master
+// nodes the same way as an operation started in manual mode.
+// If the plan cannot be created, the operation is marked failed
+func startOperation(client *opsclient.Client, init operationInitializer) (key *ops.SiteOperationKey, err error) {
+	cluster, err := client.GetLocalSite()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	key, err = init.newOperation(client, *cluster)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	defer func() {
+		if err == nil {
+			return
+		}
+		if errReset := ops.FailOperationAndResetCluster(*key, client, err.Error()); errReset != nil {
+			log.Printf("[WARN] Failed to mark operation %v as failed: %v", key.OperationID, errReset)
+		}
+	}()
+	operation, err := client.GetSiteOperation(*key)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	err = init.newOperationPlan(client, *operation, *cluster)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	log.Printf("[INFO] Operation %v created, resume it with 'gravity plan resume' on a master node",
+		key.OperationID)
+	return key, nil
+}
+
+// operationPending returns true if the operation that last updated the resource
+// has not completed yet.
+// The cluster only reflects the change once the operation completes so until
+// then the configured state is kept
+func operationPending(d *schema.ResourceData, client *opsclient.Client, clusterKey ops.SiteKey) (bool, error) {
+	operationID := d.Get("operation_id").(string)
+	if operationID == "" {
+		return false, nil
+	}
+	operation, err := client.GetSiteOperation(ops.SiteOperationKey{
+		AccountID:   clusterKey.AccountID,
+		SiteDomain:  clusterKey.SiteDomain,
+		OperationID: operationID,
+	})
+	if err != nil {
+		if trace.IsNotFound(err) {
+			return false, nil
+		}
+		return false, trace.Wrap(err)
+	}
+	return !operation.IsFinished(), nil
+}
@@ -38,6 +38,16 @@ func Provider() terraform.ResourceProvider {
 			"gravity_log_forwarder":           resourceGravityLogForwarder(),
 			"gravity_tlskeypair":              resourceGravityTLSKeyPair(),
 			"gravity_cluster_auth_preference": resourceGravityClusterAuthPreference(),
+			"gravity_alert":                   resourceGravityAlert(),
+			"gravity_alert_target":            resourceGravityAlertTarget(),
+			"gravity_smtp":                    resourceGravitySMTP(),
+			"gravity_auth_gateway":            resourceGravityAuthGateway(),
+			"gravity_oidc":                    resourceGravityOIDC(),
+			"gravity_saml":                    resourceGravitySAML(),
+			"gravity_role":                    resourceGravityRole(),
+			"gravity_trusted_cluster":         resourceGravityTrustedCluster(),
+			"gravity_runtime_environment":     resourceGravityRuntimeEnvironment(),
+			"gravity_cluster_configuration":   resourceGravityClusterConfiguration(),
 		},
 		ConfigureFunc: providerConfigure,
 	}
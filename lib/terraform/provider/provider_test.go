@@ -0,0 +1,90 @@
+package provider
+
+import (
+	"testing"
+
+	libclusterconfig "github.com/gravitational/gravity/lib/storage/clusterconfig"
+	"github.com/gravitational/teleport/lib/services"
+
+	"github.com/hashicorp/terraform/helper/schema"
+	"gopkg.in/check.v1"
+)
+
+func TestProvider(t *testing.T) { check.TestingT(t) }
+
+type ProviderSuite struct{}
+
+var _ = check.Suite(&ProviderSuite{})
+
+func (s *ProviderSuite) TestValidatesSchema(c *check.C) {
+	c.Assert(Provider().(*schema.Provider).InternalValidate(), check.IsNil)
+}
+
+func (s *ProviderSuite) TestSuppressesEquivalentDurations(c *check.C) {
+	c.Assert(suppressEquivalentDuration("", "30m0s", "30m", nil), check.Equals, true)
+	c.Assert(suppressEquivalentDuration("", "30m0s", "1h", nil), check.Equals, false)
+	c.Assert(suppressEquivalentDuration("", "", "1h", nil), check.Equals, false)
+}
+
+func (s *ProviderSuite) TestSuppressesEquivalentJSON(c *check.C) {
+	c.Assert(suppressEquivalentJSON("", `{"a":1,"b":[2]}`, `{ "b": [2], "a": 1 }`, nil), check.Equals, true)
+	c.Assert(suppressEquivalentJSON("", `{"a":1}`, `{"a":2}`, nil), check.Equals, false)
+	c.Assert(suppressEquivalentJSON("", "", `{"a":1}`, nil), check.Equals, false)
+}
+
+func (s *ProviderSuite) TestExpandsClusterConfiguration(c *check.C) {
+	d := resourceGravityClusterConfiguration().TestResourceData()
+	c.Assert(d.Set("global", []interface{}{
+		map[string]interface{}{
+			"cloud_provider": "aws",
+			"pod_cidr":       "10.244.0.0/16",
+			"feature_gates":  map[string]interface{}{"PodPriority": true},
+		},
+	}), check.IsNil)
+	c.Assert(d.Set("kubelet", []interface{}{
+		map[string]interface{}{
+			"extra_args": []interface{}{"--v=4"},
+			"config":     `{"kind":"KubeletConfiguration"}`,
+		},
+	}), check.IsNil)
+
+	config, err := expandClusterConfiguration(d)
+	c.Assert(err, check.IsNil)
+	c.Assert(config.GetGlobalConfig(), check.DeepEquals, &libclusterconfig.Global{
+		CloudProvider: "aws",
+		PodCIDR:       "10.244.0.0/16",
+		FeatureGates:  map[string]bool{"PodPriority": true},
+	})
+	c.Assert(config.GetKubeletConfig().ExtraArgs, check.DeepEquals, []string{"--v=4"})
+	c.Assert(string(config.GetKubeletConfig().Config), check.Equals, `{"kind":"KubeletConfiguration"}`)
+}
+
+func (s *ProviderSuite) TestFlattensRoleConditions(c *check.C) {
+	role, err := services.NewRole("auditor", services.RoleSpecV3{
+		Allow: expandRoleConditions([]interface{}{
+			map[string]interface{}{
+				"logins":            []interface{}{"root"},
+				"kubernetes_groups": []interface{}{"admin"},
+				"node_labels":       map[string]interface{}{"env": "prod,staging"},
+				"rule": []interface{}{
+					map[string]interface{}{
+						"resources": []interface{}{"cluster"},
+						"verbs":     []interface{}{"read"},
+						"where":     "",
+						"actions":   []interface{}{},
+					},
+				},
+			},
+		}),
+	})
+	c.Assert(err, check.IsNil)
+	c.Assert(role.GetNodeLabels(services.Allow), check.DeepEquals, services.Labels{
+		"env": []string{"prod", "staging"},
+	})
+
+	allow := flattenRoleConditions(role, services.Allow)
+	c.Assert(allow, check.HasLen, 1)
+	c.Assert(allow[0].(map[string]interface{})["node_labels"], check.DeepEquals,
+		map[string]interface{}{"env": "prod,staging"})
+	c.Assert(flattenRoleConditions(role, services.Deny), check.IsNil)
+}
@@ -0,0 +1,104 @@
+package provider
+
+import (
+	"time"
+
+	"github.com/gravitational/gravity/lib/ops/opsclient"
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/trace"
+
+	"github.com/hashicorp/terraform/helper/schema"
+)
+
+func resourceGravityAlert() *schema.Resource {
+	return &schema.Resource{
+		Create: resourceGravityAlertUpsert,
+		Read:   resourceGravityAlertRead,
+		Update: resourceGravityAlertUpsert,
+		Delete: resourceGravityAlertDelete,
+		Exists: resourceGravityAlertExists,
+		Importer: &schema.ResourceImporter{
+			State: schema.ImportStatePassthrough,
+		},
+
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(1 * time.Minute),
+			Delete: schema.DefaultTimeout(1 * time.Minute),
+		},
+
+		Schema: map[string]*schema.Schema{
+			"name": {
+				Type:     schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+			"formula": {
+				Type:        schema.TypeString,
+				Required:    true,
+				Description: "The Kapacitor TICKscript formula of the alert",
+			},
+		},
+	}
+}
+
+func resourceGravityAlertUpsert(d *schema.ResourceData, m interface{}) error {
+	client := m.(*opsclient.Client)
+	clusterKey, err := client.LocalClusterKey()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	name := d.Get("name").(string)
+	alert := storage.NewAlert(name, storage.AlertSpecV2{
+		Formula: d.Get("formula").(string),
+	})
+	if err := alert.CheckAndSetDefaults(); err != nil {
+		return trace.Wrap(err)
+	}
+
+	err = client.UpdateAlert(clusterKey, alert)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	d.SetId(name)
+	return nil
+}
+
+func resourceGravityAlertRead(d *schema.ResourceData, m interface{}) error {
+	client := m.(*opsclient.Client)
+	clusterKey, err := client.LocalClusterKey()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	alerts, err := client.GetAlerts(clusterKey)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	for _, alert := range alerts {
+		if alert.GetName() == d.Id() {
+			d.Set("name", alert.GetName())
+			d.Set("formula", alert.GetFormula())
+			return nil
+		}
+	}
+
+	return trace.NotFound("alert %v not found", d.Id())
+}
+
+func resourceGravityAlertDelete(d *schema.ResourceData, m interface{}) error {
+	client := m.(*opsclient.Client)
+	clusterKey, err := client.LocalClusterKey()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	err = client.DeleteAlert(clusterKey, d.Id())
+	return trace.Wrap(err)
+}
+
+func resourceGravityAlertExists(d *schema.ResourceData, m interface{}) (bool, error) {
+	return resourceExists(resourceGravityAlertRead(d, m))
+}
@@ -0,0 +1,227 @@
+package provider
+
+import (
+	"time"
+
+	"github.com/gravitational/gravity/lib/ops/opsclient"
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/trace"
+
+	"github.com/hashicorp/terraform/helper/schema"
+)
+
+func resourceGravityAlertTarget() *schema.Resource {
+	return &schema.Resource{
+		Create: resourceGravityAlertTargetUpsert,
+		Read:   resourceGravityAlertTargetRead,
+		Update: resourceGravityAlertTargetUpsert,
+		Delete: resourceGravityAlertTargetDelete,
+		Exists: resourceGravityAlertTargetExists,
+		Importer: &schema.ResourceImporter{
+			State: schema.ImportStatePassthrough,
+		},
+
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(1 * time.Minute),
+			Delete: schema.DefaultTimeout(1 * time.Minute),
+		},
+
+		Schema: map[string]*schema.Schema{
+			"name": {
+				Type:     schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+			"email": {
+				Type:          schema.TypeString,
+				Optional:      true,
+				Description:   "The email address to deliver alerts to over SMTP",
+				ConflictsWith: []string{"webhook", "slack", "pagerduty"},
+			},
+			"webhook": {
+				Type:          schema.TypeList,
+				Optional:      true,
+				MaxItems:      1,
+				ConflictsWith: []string{"email", "slack", "pagerduty"},
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"url": {
+							Type:     schema.TypeString,
+							Required: true,
+						},
+						"headers": {
+							Type:     schema.TypeMap,
+							Optional: true,
+							Elem: &schema.Schema{
+								Type: schema.TypeString,
+							},
+						},
+						"hmac_secret": {
+							Type:      schema.TypeString,
+							Optional:  true,
+							Sensitive: true,
+						},
+					},
+				},
+			},
+			"slack": {
+				Type:          schema.TypeList,
+				Optional:      true,
+				MaxItems:      1,
+				ConflictsWith: []string{"email", "webhook", "pagerduty"},
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"url": {
+							Type:      schema.TypeString,
+							Required:  true,
+							Sensitive: true,
+						},
+						"channel": {
+							Type:     schema.TypeString,
+							Optional: true,
+						},
+						"username": {
+							Type:     schema.TypeString,
+							Optional: true,
+						},
+					},
+				},
+			},
+			"pagerduty": {
+				Type:          schema.TypeList,
+				Optional:      true,
+				MaxItems:      1,
+				ConflictsWith: []string{"email", "webhook", "slack"},
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"routing_key": {
+							Type:      schema.TypeString,
+							Required:  true,
+							Sensitive: true,
+						},
+						"url": {
+							Type:     schema.TypeString,
+							Optional: true,
+							Computed: true,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func expandAlertTargetSpec(d *schema.ResourceData) storage.AlertTargetSpecV2 {
+	spec := storage.AlertTargetSpecV2{
+		Email: d.Get("email").(string),
+	}
+	if v := d.Get("webhook").([]interface{}); len(v) > 0 && v[0] != nil {
+		m := v[0].(map[string]interface{})
+		spec.Webhook = &storage.WebhookAlertTarget{
+			URL:        m["url"].(string),
+			Headers:    ExpandStringMap(m["headers"].(map[string]interface{})),
+			HMACSecret: m["hmac_secret"].(string),
+		}
+	}
+	if v := d.Get("slack").([]interface{}); len(v) > 0 && v[0] != nil {
+		m := v[0].(map[string]interface{})
+		spec.Slack = &storage.SlackAlertTarget{
+			URL:      m["url"].(string),
+			Channel:  m["channel"].(string),
+			Username: m["username"].(string),
+		}
+	}
+	if v := d.Get("pagerduty").([]interface{}); len(v) > 0 && v[0] != nil {
+		m := v[0].(map[string]interface{})
+		spec.PagerDuty = &storage.PagerDutyAlertTarget{
+			RoutingKey: m["routing_key"].(string),
+			URL:        m["url"].(string),
+		}
+	}
+	return spec
+}
+
+func resourceGravityAlertTargetUpsert(d *schema.ResourceData, m interface{}) error {
+	client := m.(*opsclient.Client)
+	clusterKey, err := client.LocalClusterKey()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	name := d.Get("name").(string)
+	target := storage.NewAlertTarget(name, expandAlertTargetSpec(d))
+	if err := target.CheckAndSetDefaults(); err != nil {
+		return trace.Wrap(err)
+	}
+
+	err = client.UpdateAlertTarget(clusterKey, target)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	d.SetId(name)
+	return nil
+}
+
+func resourceGravityAlertTargetRead(d *schema.ResourceData, m interface{}) error {
+	client := m.(*opsclient.Client)
+	clusterKey, err := client.LocalClusterKey()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	targets, err := client.GetAlertTargets(clusterKey, true)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	for _, target := range targets {
+		if target.GetName() != d.Id() {
+			continue
+		}
+		d.Set("name", target.GetName())
+		d.Set("email", target.GetEmail())
+		var webhook, slack, pagerduty []interface{}
+		if v := target.GetWebhook(); v != nil {
+			webhook = append(webhook, map[string]interface{}{
+				"url":         v.URL,
+				"headers":     v.Headers,
+				"hmac_secret": v.HMACSecret,
+			})
+		}
+		if v := target.GetSlack(); v != nil {
+			slack = append(slack, map[string]interface{}{
+				"url":      v.URL,
+				"channel":  v.Channel,
+				"username": v.Username,
+			})
+		}
+		if v := target.GetPagerDuty(); v != nil {
+			pagerduty = append(pagerduty, map[string]interface{}{
+				"routing_key": v.RoutingKey,
+				"url":         v.URL,
+			})
+		}
+		d.Set("webhook", webhook)
+		d.Set("slack", slack)
+		d.Set("pagerduty", pagerduty)
+		return nil
+	}
+
+	return trace.NotFound("alert target %v not found", d.Id())
+}
+
+func resourceGravityAlertTargetDelete(d *schema.ResourceData, m interface{}) error {
+	client := m.(*opsclient.Client)
+	clusterKey, err := client.LocalClusterKey()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	err = client.DeleteAlertTarget(clusterKey, d.Id())
+	return trace.Wrap(err)
+}
+
+func resourceGravityAlertTargetExists(d *schema.ResourceData, m interface{}) (bool, error) {
+	return resourceExists(resourceGravityAlertTargetRead(d, m))
+}
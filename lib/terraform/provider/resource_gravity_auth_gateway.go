@@ -0,0 +1,194 @@
+package provider
+
+import (
+	"time"
+
+	"github.com/gravitational/gravity/lib/ops/opsclient"
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/teleport/lib/services"
+	"github.com/gravitational/trace"
+
+	"github.com/hashicorp/terraform/helper/schema"
+)
+
+func resourceGravityAuthGateway() *schema.Resource {
+	return &schema.Resource{
+		Create: resourceGravityAuthGatewayUpsert,
+		Read:   resourceGravityAuthGatewayRead,
+		Update: resourceGravityAuthGatewayUpsert,
+		Delete: resourceGravityAuthGatewayDelete,
+		Exists: resourceGravityAuthGatewayExists,
+		Importer: &schema.ResourceImporter{
+			State: schema.ImportStatePassthrough,
+		},
+
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(5 * time.Minute),
+			Delete: schema.DefaultTimeout(1 * time.Minute),
+		},
+
+		// The cluster merges the provided settings into the existing
+		// configuration so all attributes are optional and computed
+		Schema: map[string]*schema.Schema{
+			"max_connections": {
+				Type:     schema.TypeInt,
+				Optional: true,
+				Computed: true,
+			},
+			"max_users": {
+				Type:     schema.TypeInt,
+				Optional: true,
+				Computed: true,
+			},
+			"client_idle_timeout": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Computed:    true,
+				Description: "The idle SSH session timeout, for example 30m",
+
+				DiffSuppressFunc: suppressEquivalentDuration,
+			},
+			"disconnect_expired_cert": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Computed: true,
+			},
+			"public_addr": {
+				Type:     schema.TypeList,
+				Optional: true,
+				Computed: true,
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+			},
+			"ssh_public_addr": {
+				Type:     schema.TypeList,
+				Optional: true,
+				Computed: true,
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+			},
+			"kubernetes_public_addr": {
+				Type:     schema.TypeList,
+				Optional: true,
+				Computed: true,
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+			},
+			"web_public_addr": {
+				Type:     schema.TypeList,
+				Optional: true,
+				Computed: true,
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+			},
+		},
+	}
+}
+
+func expandAuthGatewaySpec(d *schema.ResourceData) (*storage.AuthGatewaySpecV1, error) {
+	var spec storage.AuthGatewaySpecV1
+	if v, ok := d.GetOkExists("max_connections"); ok {
+		maxConnections := int64(v.(int))
+		if spec.ConnectionLimits == nil {
+			spec.ConnectionLimits = &storage.ConnectionLimits{}
+		}
+		spec.ConnectionLimits.MaxConnections = &maxConnections
+	}
+	if v, ok := d.GetOkExists("max_users"); ok {
+		maxUsers := v.(int)
+		if spec.ConnectionLimits == nil {
+			spec.ConnectionLimits = &storage.ConnectionLimits{}
+		}
+		spec.ConnectionLimits.MaxUsers = &maxUsers
+	}
+	if v, ok := d.GetOk("client_idle_timeout"); ok {
+		timeout, err := time.ParseDuration(v.(string))
+		if err != nil {
+			return nil, trace.BadParameter("invalid client_idle_timeout %q: %v", v, err)
+		}
+		duration := services.NewDuration(timeout)
+		spec.ClientIdleTimeout = &duration
+	}
+	if v, ok := d.GetOkExists("disconnect_expired_cert"); ok {
+		spec.DisconnectExpiredCert = services.NewBoolOption(v.(bool))
+	}
+	for key, field := range map[string]**[]string{
+		"public_addr":            &spec.PublicAddr,
+		"ssh_public_addr":        &spec.SSHPublicAddr,
+		"kubernetes_public_addr": &spec.KubernetesPublicAddr,
+		"web_public_addr":        &spec.WebPublicAddr,
+	} {
+		if v, ok := d.GetOk(key); ok {
+			addrs := ExpandStringList(v.([]interface{}))
+			*field = &addrs
+		}
+	}
+	return &spec, nil
+}
+
+func resourceGravityAuthGatewayUpsert(d *schema.ResourceData, m interface{}) error {
+	client := m.(*opsclient.Client)
+	clusterKey, err := client.LocalClusterKey()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	spec, err := expandAuthGatewaySpec(d)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	gw := storage.NewAuthGateway(*spec)
+	if err := gw.CheckAndSetDefaults(); err != nil {
+		return trace.Wrap(err)
+	}
+
+	err = client.UpsertAuthGateway(clusterKey, gw)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	// The cluster has a single auth gateway configuration so a static ID is used
+	d.SetId(storage.KindAuthGateway)
+	return resourceGravityAuthGatewayRead(d, m)
+}
+
+func resourceGravityAuthGatewayRead(d *schema.ResourceData, m interface{}) error {
+	client := m.(*opsclient.Client)
+	clusterKey, err := client.LocalClusterKey()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	gw, err := client.GetAuthGateway(clusterKey)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	d.Set("max_connections", gw.GetMaxConnections())
+	d.Set("max_users", gw.GetMaxUsers())
+	if v := gw.GetClientIdleTimeout(); v != nil {
+		d.Set("client_idle_timeout", v.Value().String())
+	}
+	if v := gw.GetDisconnectExpiredCert(); v != nil {
+		d.Set("disconnect_expired_cert", v.Value())
+	}
+	d.Set("public_addr", gw.GetPublicAddrs())
+	d.Set("ssh_public_addr", gw.GetSSHPublicAddrs())
+	d.Set("kubernetes_public_addr", gw.GetKubernetesPublicAddrs())
+	d.Set("web_public_addr", gw.GetWebPublicAddrs())
+	return nil
+}
+
+func resourceGravityAuthGatewayDelete(d *schema.ResourceData, m interface{}) error {
+	// The auth gateway configuration cannot be deleted, so removing the resource
+	// from the configuration leaves the current settings in place.
+	return nil
+}
+
+func resourceGravityAuthGatewayExists(d *schema.ResourceData, m interface{}) (bool, error) {
+	return resourceExists(resourceGravityAuthGatewayRead(d, m))
+}
@@ -17,6 +17,9 @@ func resourceGravityClusterAuthPreference() *schema.Resource {
 		Update: resourceGravityClusterAuthPreferenceCreate,
 		Delete: resourceGravityClusterAuthPreferenceDelete,
 		Exists: resourceGravityClusterAuthPreferenceExists,
+		Importer: &schema.ResourceImporter{
+			State: schema.ImportStatePassthrough,
+		},
 
 		Timeouts: &schema.ResourceTimeout{
 			Create: schema.DefaultTimeout(1 * time.Minute),
@@ -74,6 +77,9 @@ func resourceGravityClusterAuthPreferenceCreate(d *schema.ResourceData, m interf
 			Facets: ExpandStringList(u2fFacets),
 		},
 	})
+	if err != nil {
+		return trace.Wrap(err)
+	}
 
 	err = client.UpsertClusterAuthPreference(clusterKey, authPreference)
 	if err != nil {
@@ -123,9 +129,5 @@ func resourceGravityClusterAuthPreferenceDelete(d *schema.ResourceData, m interf
 }
 
 func resourceGravityClusterAuthPreferenceExists(d *schema.ResourceData, m interface{}) (bool, error) {
-	err := resourceGravityClusterAuthPreferenceRead(d, m)
-	if err != nil {
-		return false, trace.Wrap(err)
-	}
-	return true, nil
+	return resourceExists(resourceGravityClusterAuthPreferenceRead(d, m))
 }
@@ -0,0 +1,248 @@
+package provider
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/ops/opsclient"
+	"github.com/gravitational/gravity/lib/storage"
+	libclusterconfig "github.com/gravitational/gravity/lib/storage/clusterconfig"
+	"github.com/gravitational/gravity/lib/update/clusterconfig"
+	"github.com/gravitational/trace"
+
+	"github.com/hashicorp/terraform/helper/schema"
+)
+
+func resourceGravityClusterConfiguration() *schema.Resource {
+	return &schema.Resource{
+		Create: resourceGravityClusterConfigurationUpsert,
+		Read:   resourceGravityClusterConfigurationRead,
+		Update: resourceGravityClusterConfigurationUpsert,
+		Delete: resourceGravityClusterConfigurationDelete,
+		Exists: resourceGravityClusterConfigurationExists,
+		Importer: &schema.ResourceImporter{
+			State: schema.ImportStatePassthrough,
+		},
+
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(1 * time.Minute),
+			Delete: schema.DefaultTimeout(1 * time.Minute),
+		},
+
+		// The cluster fills in some of the global settings during installation
+		// so the global attributes are optional and computed
+		Schema: map[string]*schema.Schema{
+			"global": {
+				Type:     schema.TypeList,
+				Optional: true,
+				Computed: true,
+				MaxItems: 1,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"cloud_provider": {
+							Type:     schema.TypeString,
+							Optional: true,
+							Computed: true,
+						},
+						"cloud_config": {
+							Type:     schema.TypeString,
+							Optional: true,
+							Computed: true,
+						},
+						"service_cidr": {
+							Type:     schema.TypeString,
+							Optional: true,
+							Computed: true,
+						},
+						"service_node_port_range": {
+							Type:     schema.TypeString,
+							Optional: true,
+							Computed: true,
+						},
+						"pod_cidr": {
+							Type:     schema.TypeString,
+							Optional: true,
+							Computed: true,
+						},
+						"proxy_port_range": {
+							Type:     schema.TypeString,
+							Optional: true,
+							Computed: true,
+						},
+						"feature_gates": {
+							Type:     schema.TypeMap,
+							Optional: true,
+							Elem: &schema.Schema{
+								Type: schema.TypeBool,
+							},
+						},
+					},
+				},
+			},
+			"kubelet": {
+				Type:     schema.TypeList,
+				Optional: true,
+				MaxItems: 1,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"extra_args": {
+							Type:     schema.TypeList,
+							Optional: true,
+							Elem: &schema.Schema{
+								Type: schema.TypeString,
+							},
+						},
+						"config": {
+							Type:        schema.TypeString,
+							Optional:    true,
+							Description: "The kubelet configuration as a JSON document",
+
+							DiffSuppressFunc: suppressEquivalentJSON,
+						},
+					},
+				},
+			},
+			"operation_id": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "The ID of the operation that applies the last change",
+			},
+		},
+	}
+}
+
+func expandClusterConfiguration(d *schema.ResourceData) (*libclusterconfig.Resource, error) {
+	config := libclusterconfig.New()
+	if v := d.Get("global").([]interface{}); len(v) > 0 && v[0] != nil {
+		global := v[0].(map[string]interface{})
+		config.Spec.Global = &libclusterconfig.Global{
+			CloudProvider:        global["cloud_provider"].(string),
+			CloudConfig:          global["cloud_config"].(string),
+			ServiceCIDR:          global["service_cidr"].(string),
+			ServiceNodePortRange: global["service_node_port_range"].(string),
+			PodCIDR:              global["pod_cidr"].(string),
+			ProxyPortRange:       global["proxy_port_range"].(string),
+		}
+		if gates := global["feature_gates"].(map[string]interface{}); len(gates) > 0 {
+			config.Spec.Global.FeatureGates = make(map[string]bool, len(gates))
+			for name, enabled := range gates {
+				config.Spec.Global.FeatureGates[name] = enabled.(bool)
+			}
+		}
+	}
+	if v := d.Get("kubelet").([]interface{}); len(v) > 0 && v[0] != nil {
+		kubelet := v[0].(map[string]interface{})
+		config.Spec.Kubelet = &libclusterconfig.Kubelet{
+			ExtraArgs: ExpandStringList(kubelet["extra_args"].([]interface{})),
+		}
+		if data := kubelet["config"].(string); data != "" {
+			if !json.Valid([]byte(data)) {
+				return nil, trace.BadParameter("kubelet config is not valid JSON")
+			}
+			config.Spec.Kubelet.Config = json.RawMessage(data)
+		}
+	}
+	return config, nil
+}
+
+func resourceGravityClusterConfigurationUpsert(d *schema.ResourceData, m interface{}) error {
+	client := m.(*opsclient.Client)
+
+	config, err := expandClusterConfiguration(d)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	key, err := updateClusterConfiguration(client, config)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	// The cluster has a single configuration so a static ID is used
+	d.SetId(storage.KindClusterConfiguration)
+	d.Set("operation_id", key.OperationID)
+	return resourceGravityClusterConfigurationRead(d, m)
+}
+
+func resourceGravityClusterConfigurationRead(d *schema.ResourceData, m interface{}) error {
+	client := m.(*opsclient.Client)
+	clusterKey, err := client.LocalClusterKey()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	pending, err := operationPending(d, client, clusterKey)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if pending {
+		return nil
+	}
+
+	config, err := client.GetClusterConfiguration(clusterKey)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	var global []interface{}
+	if v := config.GetGlobalConfig(); v != nil {
+		global = append(global, map[string]interface{}{
+			"cloud_provider":          v.CloudProvider,
+			"cloud_config":            v.CloudConfig,
+			"service_cidr":            v.ServiceCIDR,
+			"service_node_port_range": v.ServiceNodePortRange,
+			"pod_cidr":                v.PodCIDR,
+			"proxy_port_range":        v.ProxyPortRange,
+			"feature_gates":           v.FeatureGates,
+		})
+	}
+	d.Set("global", global)
+
+	var kubelet []interface{}
+	if v := config.GetKubeletConfig(); v != nil {
+		kubelet = append(kubelet, map[string]interface{}{
+			"extra_args": v.ExtraArgs,
+			"config":     string(v.Config),
+		})
+	}
+	d.Set("kubelet", kubelet)
+
+	return nil
+}
+
+func resourceGravityClusterConfigurationDelete(d *schema.ResourceData, m interface{}) error {
+	client := m.(*opsclient.Client)
+
+	// Removing the resource resets the cluster configuration to defaults
+	_, err := updateClusterConfiguration(client, libclusterconfig.New())
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	return nil
+}
+
+func resourceGravityClusterConfigurationExists(d *schema.ResourceData, m interface{}) (bool, error) {
+	return resourceExists(resourceGravityClusterConfigurationRead(d, m))
+}
+
+// updateClusterConfiguration starts the operation to replace the cluster
+// configuration with the specified one
+func updateClusterConfiguration(client *opsclient.Client, config libclusterconfig.Interface) (*ops.SiteOperationKey, error) {
+	configBytes, err := libclusterconfig.Marshal(config)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return startOperation(client, operationInitializer{
+		newOperation: func(operator ops.Operator, cluster ops.Site) (*ops.SiteOperationKey, error) {
+			return operator.CreateUpdateConfigOperation(ops.CreateUpdateConfigOperationRequest{
+				ClusterKey: cluster.Key(),
+				Config:     configBytes,
+			})
+		},
+		newOperationPlan: func(operator ops.Operator, operation ops.SiteOperation, cluster ops.Site) error {
+			_, err := clusterconfig.NewOperationPlan(operator, operation, config, cluster.ClusterState.Servers)
+			return trace.Wrap(err)
+		},
+	})
+}
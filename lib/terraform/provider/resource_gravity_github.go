@@ -20,6 +20,9 @@ func resourceGravityGithub() *schema.Resource {
 		Update: resourceGravityGithubCreateOrUpdate,
 		Delete: resourceGravityGithubDelete,
 		Exists: resourceGravityGithubExists,
+		Importer: &schema.ResourceImporter{
+			State: schema.ImportStatePassthrough,
+		},
 
 		Timeouts: &schema.ResourceTimeout{
 			Create: schema.DefaultTimeout(1 * time.Minute),
@@ -32,6 +35,7 @@ func resourceGravityGithub() *schema.Resource {
 				Required:     true,
 				Description:  "The name of the resource",
 				InputDefault: "github",
+				ForceNew:     true,
 			},
 			"client_id": {
 				Type:     schema.TypeString,
@@ -136,7 +140,7 @@ func resourceGravityGithubCreateOrUpdate(d *schema.ResourceData, m interface{})
 
 func resourceGravityGithubRead(d *schema.ResourceData, m interface{}) error {
 	client := m.(*opsclient.Client)
-	name := d.Get("name").(string)
+	name := d.Id()
 
 	cluster, err := client.GetLocalSite()
 	if err != nil {
@@ -184,9 +188,7 @@ func resourceGravityGithubDelete(d *schema.ResourceData, m interface{}) error {
 		SiteDomain: cluster.Domain,
 	}
 
-	name := d.Get("token").(string)
-
-	err = client.DeleteGithubConnector(clusterKey, name)
+	err = client.DeleteGithubConnector(clusterKey, d.Id())
 	if err != nil {
 		return trace.Wrap(err)
 	}
@@ -195,12 +197,5 @@ func resourceGravityGithubDelete(d *schema.ResourceData, m interface{}) error {
 }
 
 func resourceGravityGithubExists(d *schema.ResourceData, m interface{}) (bool, error) {
-	err := resourceGravityGithubRead(d, m)
-	if err != nil && trace.IsNotFound(err) {
-		return false, nil
-	}
-	if err != nil {
-		return false, trace.Wrap(err)
-	}
-	return true, nil
+	return resourceExists(resourceGravityGithubRead(d, m))
 }
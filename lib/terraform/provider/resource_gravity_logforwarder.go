@@ -17,6 +17,9 @@ func resourceGravityLogForwarder() *schema.Resource {
 		Update: resourceGravityLogForwarderUpdate,
 		Delete: resourceGravityLogForwarderDelete,
 		Exists: resourceGravityLogForwarderExists,
+		Importer: &schema.ResourceImporter{
+			State: schema.ImportStatePassthrough,
+		},
 
 		Timeouts: &schema.ResourceTimeout{
 			Create: schema.DefaultTimeout(1 * time.Minute),
@@ -27,6 +30,7 @@ func resourceGravityLogForwarder() *schema.Resource {
 			"name": {
 				Type:     schema.TypeString,
 				Required: true,
+				ForceNew: true,
 			},
 			"address": {
 				Type:     schema.TypeString,
@@ -36,10 +40,106 @@ func resourceGravityLogForwarder() *schema.Resource {
 				Type:     schema.TypeString,
 				Required: true,
 			},
+			"tls": {
+				Type:     schema.TypeList,
+				Optional: true,
+				MaxItems: 1,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"ca_cert": {
+							Type:     schema.TypeString,
+							Optional: true,
+						},
+						"client_cert": {
+							Type:     schema.TypeString,
+							Optional: true,
+						},
+						"client_key": {
+							Type:      schema.TypeString,
+							Optional:  true,
+							Sensitive: true,
+						},
+						"server_name": {
+							Type:     schema.TypeString,
+							Optional: true,
+						},
+					},
+				},
+			},
+			"token": {
+				Type:      schema.TypeString,
+				Optional:  true,
+				Sensitive: true,
+			},
+			"selector": {
+				Type:     schema.TypeList,
+				Optional: true,
+				MaxItems: 1,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"namespaces": {
+							Type:     schema.TypeList,
+							Optional: true,
+							Elem: &schema.Schema{
+								Type: schema.TypeString,
+							},
+						},
+						"pods": {
+							Type:     schema.TypeList,
+							Optional: true,
+							Elem: &schema.Schema{
+								Type: schema.TypeString,
+							},
+						},
+						"containers": {
+							Type:     schema.TypeList,
+							Optional: true,
+							Elem: &schema.Schema{
+								Type: schema.TypeString,
+							},
+						},
+						"severity": {
+							Type:     schema.TypeString,
+							Optional: true,
+						},
+					},
+				},
+			},
 		},
 	}
 }
 
+func expandLogForwarder(d *schema.ResourceData) (storage.LogForwarder, error) {
+	spec := storage.LogForwarderSpecV2{
+		Address:  d.Get("address").(string),
+		Protocol: d.Get("protocol").(string),
+		Token:    d.Get("token").(string),
+	}
+	if v := d.Get("tls").([]interface{}); len(v) > 0 {
+		spec.TLS = &storage.LogForwarderTLS{}
+		if m, ok := v[0].(map[string]interface{}); ok {
+			spec.TLS.CACert = m["ca_cert"].(string)
+			spec.TLS.ClientCert = m["client_cert"].(string)
+			spec.TLS.ClientKey = m["client_key"].(string)
+			spec.TLS.ServerName = m["server_name"].(string)
+		}
+	}
+	if v := d.Get("selector").([]interface{}); len(v) > 0 && v[0] != nil {
+		m := v[0].(map[string]interface{})
+		spec.Selector = &storage.LogForwarderSelector{
+			Namespaces: ExpandStringList(m["namespaces"].([]interface{})),
+			Pods:       ExpandStringList(m["pods"].([]interface{})),
+			Containers: ExpandStringList(m["containers"].([]interface{})),
+			Severity:   m["severity"].(string),
+		}
+	}
+	forwarder := storage.NewLogForwarderWithSpec(d.Get("name").(string), spec)
+	if err := forwarder.CheckAndSetDefaults(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return forwarder, nil
+}
+
 func resourceGravityLogForwarderCreate(d *schema.ResourceData, m interface{}) error {
 	client := m.(*opsclient.Client)
 	clusterKey, err := client.LocalClusterKey()
@@ -47,18 +147,17 @@ func resourceGravityLogForwarderCreate(d *schema.ResourceData, m interface{}) er
 		return trace.Wrap(err)
 	}
 
-	name := d.Get("name").(string)
-	address := d.Get("address").(string)
-	protocol := d.Get("protocol").(string)
-
-	forwarder := storage.NewLogForwarder(name, address, protocol)
+	forwarder, err := expandLogForwarder(d)
+	if err != nil {
+		return trace.Wrap(err)
+	}
 
 	err = client.CreateLogForwarder(clusterKey, forwarder)
 	if err != nil {
 		return trace.Wrap(err)
 	}
 
-	d.SetId(name)
+	d.SetId(forwarder.GetName())
 	return nil
 }
 
@@ -69,22 +168,42 @@ func resourceGravityLogForwarderRead(d *schema.ResourceData, m interface{}) erro
 		return trace.Wrap(err)
 	}
 
-	name := d.Get("name").(string)
-
-	forwarders, err := client.GetLogForwarders(clusterKey)
+	forwarders, err := client.GetLogForwarders(clusterKey, true)
 	if err != nil {
 		return trace.Wrap(err)
 	}
 
 	for _, forwarder := range forwarders {
-		if forwarder.GetName() == name {
-			d.Set("address", forwarder.GetAddress())
-			d.Set("protocol", forwarder.GetProtocol())
-			return nil
+		if forwarder.GetName() != d.Id() {
+			continue
 		}
+		d.Set("name", forwarder.GetName())
+		d.Set("address", forwarder.GetAddress())
+		d.Set("protocol", forwarder.GetProtocol())
+		d.Set("token", forwarder.GetToken())
+		var tls, selector []interface{}
+		if v := forwarder.GetTLS(); v != nil {
+			tls = append(tls, map[string]interface{}{
+				"ca_cert":     v.CACert,
+				"client_cert": v.ClientCert,
+				"client_key":  v.ClientKey,
+				"server_name": v.ServerName,
+			})
+		}
+		if v := forwarder.GetSelector(); v != nil {
+			selector = append(selector, map[string]interface{}{
+				"namespaces": v.Namespaces,
+				"pods":       v.Pods,
+				"containers": v.Containers,
+				"severity":   v.Severity,
+			})
+		}
+		d.Set("tls", tls)
+		d.Set("selector", selector)
+		return nil
 	}
 
-	return trace.NotFound("log forwarder %v not found", name)
+	return trace.NotFound("log forwarder %v not found", d.Id())
 }
 
 func resourceGravityLogForwarderUpdate(d *schema.ResourceData, m interface{}) error {
@@ -94,11 +213,10 @@ func resourceGravityLogForwarderUpdate(d *schema.ResourceData, m interface{}) er
 		return trace.Wrap(err)
 	}
 
-	name := d.Get("name").(string)
-	address := d.Get("address").(string)
-	protocol := d.Get("protocol").(string)
-
-	forwarder := storage.NewLogForwarder(name, address, protocol)
+	forwarder, err := expandLogForwarder(d)
+	if err != nil {
+		return trace.Wrap(err)
+	}
 
 	err = client.UpdateLogForwarder(clusterKey, forwarder)
 	return trace.Wrap(err)
@@ -111,16 +229,10 @@ func resourceGravityLogForwarderDelete(d *schema.ResourceData, m interface{}) er
 		return trace.Wrap(err)
 	}
 
-	name := d.Get("name").(string)
-
-	err = client.DeleteLogForwarder(clusterKey, name)
+	err = client.DeleteLogForwarder(clusterKey, d.Id())
 	return trace.Wrap(err)
 }
 
 func resourceGravityLogForwarderExists(d *schema.ResourceData, m interface{}) (bool, error) {
-	err := resourceGravityLogForwarderRead(d, m)
-	if err != nil {
-		return false, trace.Wrap(err)
-	}
-	return true, nil
+	return resourceExists(resourceGravityLogForwarderRead(d, m))
 }
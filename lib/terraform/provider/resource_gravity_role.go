@@ -0,0 +1,271 @@
+package provider
+
+import (
+	"log"
+	"strings"
+	"time"
+
+	"github.com/gravitational/gravity/lib/ops/opsclient"
+	"github.com/gravitational/teleport/lib/services"
+	"github.com/gravitational/trace"
+
+	"github.com/hashicorp/terraform/helper/schema"
+)
+
+func resourceGravityRole() *schema.Resource {
+	return &schema.Resource{
+		Create: resourceGravityRoleCreateOrUpdate,
+		Read:   resourceGravityRoleRead,
+		Update: resourceGravityRoleCreateOrUpdate,
+		Delete: resourceGravityRoleDelete,
+		Exists: resourceGravityRoleExists,
+		Importer: &schema.ResourceImporter{
+			State: schema.ImportStatePassthrough,
+		},
+
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(1 * time.Minute),
+			Delete: schema.DefaultTimeout(1 * time.Minute),
+		},
+
+		Schema: map[string]*schema.Schema{
+			"name": {
+				Type:        schema.TypeString,
+				Required:    true,
+				Description: "The name of the resource",
+				ForceNew:    true,
+			},
+			"max_session_ttl": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Computed:    true,
+				Description: "The maximum duration of a session, for example 30h",
+
+				DiffSuppressFunc: suppressEquivalentDuration,
+			},
+			"forward_agent": {
+				Type:     schema.TypeBool,
+				Optional: true,
+			},
+			// The cluster allows access to all nodes unless node labels are
+			// specified, so the allow conditions are computed
+			"allow": {
+				Type:     schema.TypeList,
+				Optional: true,
+				Computed: true,
+				MaxItems: 1,
+				Elem:     roleConditionsSchema(),
+			},
+			"deny": {
+				Type:     schema.TypeList,
+				Optional: true,
+				MaxItems: 1,
+				Elem:     roleConditionsSchema(),
+			},
+		},
+	}
+}
+
+func roleConditionsSchema() *schema.Resource {
+	return &schema.Resource{
+		Schema: map[string]*schema.Schema{
+			"logins": {
+				Type:     schema.TypeList,
+				Optional: true,
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+			},
+			"kubernetes_groups": {
+				Type:     schema.TypeList,
+				Optional: true,
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+			},
+			"node_labels": {
+				Type:        schema.TypeMap,
+				Optional:    true,
+				Computed:    true,
+				Description: "The node labels to match, multiple values of a label are separated with commas",
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+			},
+			"rule": {
+				Type:     schema.TypeList,
+				Optional: true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"resources": {
+							Type:     schema.TypeList,
+							Required: true,
+							MinItems: 1,
+							Elem: &schema.Schema{
+								Type: schema.TypeString,
+							},
+						},
+						"verbs": {
+							Type:     schema.TypeList,
+							Required: true,
+							MinItems: 1,
+							Elem: &schema.Schema{
+								Type: schema.TypeString,
+							},
+						},
+						"where": {
+							Type:     schema.TypeString,
+							Optional: true,
+						},
+						"actions": {
+							Type:     schema.TypeList,
+							Optional: true,
+							Elem: &schema.Schema{
+								Type: schema.TypeString,
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func expandRoleConditions(v []interface{}) services.RoleConditions {
+	var conditions services.RoleConditions
+	if len(v) == 0 || v[0] == nil {
+		return conditions
+	}
+	m := v[0].(map[string]interface{})
+	conditions.Logins = ExpandStringList(m["logins"].([]interface{}))
+	conditions.KubeGroups = ExpandStringList(m["kubernetes_groups"].([]interface{}))
+	if labels := m["node_labels"].(map[string]interface{}); len(labels) > 0 {
+		conditions.NodeLabels = make(services.Labels, len(labels))
+		for name, value := range labels {
+			conditions.NodeLabels[name] = strings.Split(value.(string), ",")
+		}
+	}
+	for _, v := range m["rule"].([]interface{}) {
+		rule := v.(map[string]interface{})
+		conditions.Rules = append(conditions.Rules, services.Rule{
+			Resources: ExpandStringList(rule["resources"].([]interface{})),
+			Verbs:     ExpandStringList(rule["verbs"].([]interface{})),
+			Where:     rule["where"].(string),
+			Actions:   ExpandStringList(rule["actions"].([]interface{})),
+		})
+	}
+	return conditions
+}
+
+func flattenRoleConditions(role services.Role, condition services.RoleConditionType) []interface{} {
+	logins := role.GetLogins(condition)
+	kubeGroups := role.GetKubeGroups(condition)
+	nodeLabels := role.GetNodeLabels(condition)
+	rules := role.GetRules(condition)
+	if len(logins) == 0 && len(kubeGroups) == 0 && len(nodeLabels) == 0 && len(rules) == 0 {
+		return nil
+	}
+
+	labels := make(map[string]interface{}, len(nodeLabels))
+	for name, values := range nodeLabels {
+		labels[name] = strings.Join(values, ",")
+	}
+	var ruleList []interface{}
+	for _, rule := range rules {
+		ruleList = append(ruleList, map[string]interface{}{
+			"resources": rule.Resources,
+			"verbs":     rule.Verbs,
+			"where":     rule.Where,
+			"actions":   rule.Actions,
+		})
+	}
+	return []interface{}{
+		map[string]interface{}{
+			"logins":            logins,
+			"kubernetes_groups": kubeGroups,
+			"node_labels":       labels,
+			"rule":              ruleList,
+		},
+	}
+}
+
+func resourceGravityRoleCreateOrUpdate(d *schema.ResourceData, m interface{}) error {
+	client := m.(*opsclient.Client)
+	clusterKey, err := client.LocalClusterKey()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	name := d.Get("name").(string)
+
+	spec := services.RoleSpecV3{
+		Options: services.RoleOptions{
+			ForwardAgent: services.NewBool(d.Get("forward_agent").(bool)),
+		},
+		Allow: expandRoleConditions(d.Get("allow").([]interface{})),
+		Deny:  expandRoleConditions(d.Get("deny").([]interface{})),
+	}
+	if v, ok := d.GetOk("max_session_ttl"); ok {
+		ttl, err := time.ParseDuration(v.(string))
+		if err != nil {
+			return trace.BadParameter("invalid max_session_ttl %q: %v", v, err)
+		}
+		spec.Options.MaxSessionTTL = services.NewDuration(ttl)
+	}
+
+	role, err := services.NewRole(name, spec)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	err = client.UpsertRole(clusterKey, role)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	log.Printf("[INFO] Role %s created", name)
+	d.SetId(name)
+
+	return resourceGravityRoleRead(d, m)
+}
+
+func resourceGravityRoleRead(d *schema.ResourceData, m interface{}) error {
+	client := m.(*opsclient.Client)
+	clusterKey, err := client.LocalClusterKey()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	role, err := client.GetRole(clusterKey, d.Id())
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	options := role.GetOptions()
+	d.Set("name", role.GetName())
+	d.Set("max_session_ttl", options.MaxSessionTTL.Value().String())
+	d.Set("forward_agent", options.ForwardAgent.Value())
+	d.Set("allow", flattenRoleConditions(role, services.Allow))
+	d.Set("deny", flattenRoleConditions(role, services.Deny))
+
+	return nil
+}
+
+func resourceGravityRoleDelete(d *schema.ResourceData, m interface{}) error {
+	client := m.(*opsclient.Client)
+	clusterKey, err := client.LocalClusterKey()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	err = client.DeleteRole(clusterKey, d.Id())
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	return nil
+}
+
+func resourceGravityRoleExists(d *schema.ResourceData, m interface{}) (bool, error) {
+	return resourceExists(resourceGravityRoleRead(d, m))
+}
@@ -0,0 +1,119 @@
+package provider
+
+import (
+	"time"
+
+	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/ops/opsclient"
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/update/environ"
+	"github.com/gravitational/trace"
+
+	"github.com/hashicorp/terraform/helper/schema"
+)
+
+func resourceGravityRuntimeEnvironment() *schema.Resource {
+	return &schema.Resource{
+		Create: resourceGravityRuntimeEnvironmentUpsert,
+		Read:   resourceGravityRuntimeEnvironmentRead,
+		Update: resourceGravityRuntimeEnvironmentUpsert,
+		Delete: resourceGravityRuntimeEnvironmentDelete,
+		Exists: resourceGravityRuntimeEnvironmentExists,
+		Importer: &schema.ResourceImporter{
+			State: schema.ImportStatePassthrough,
+		},
+
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(1 * time.Minute),
+			Delete: schema.DefaultTimeout(1 * time.Minute),
+		},
+
+		Schema: map[string]*schema.Schema{
+			"env": {
+				Type:        schema.TypeMap,
+				Required:    true,
+				Description: "The environment variables to set for cluster services",
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+			},
+			"operation_id": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "The ID of the operation that applies the last change",
+			},
+		},
+	}
+}
+
+func resourceGravityRuntimeEnvironmentUpsert(d *schema.ResourceData, m interface{}) error {
+	client := m.(*opsclient.Client)
+
+	env := ExpandStringMap(d.Get("env").(map[string]interface{}))
+	key, err := updateRuntimeEnvironment(client, env)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	// The cluster has a single runtime environment so a static ID is used
+	d.SetId(storage.KindRuntimeEnvironment)
+	d.Set("operation_id", key.OperationID)
+	return resourceGravityRuntimeEnvironmentRead(d, m)
+}
+
+func resourceGravityRuntimeEnvironmentRead(d *schema.ResourceData, m interface{}) error {
+	client := m.(*opsclient.Client)
+	clusterKey, err := client.LocalClusterKey()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	pending, err := operationPending(d, client, clusterKey)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if pending {
+		return nil
+	}
+
+	env, err := client.GetClusterEnvironmentVariables(clusterKey)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	d.Set("env", env.GetKeyValues())
+	return nil
+}
+
+func resourceGravityRuntimeEnvironmentDelete(d *schema.ResourceData, m interface{}) error {
+	client := m.(*opsclient.Client)
+
+	// Removing the resource clears the cluster runtime environment
+	_, err := updateRuntimeEnvironment(client, nil)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	return nil
+}
+
+func resourceGravityRuntimeEnvironmentExists(d *schema.ResourceData, m interface{}) (bool, error) {
+	return resourceExists(resourceGravityRuntimeEnvironmentRead(d, m))
+}
+
+// updateRuntimeEnvironment starts the operation to replace the cluster
+// runtime environment with the specified variables
+func updateRuntimeEnvironment(client *opsclient.Client, env map[string]string) (*ops.SiteOperationKey, error) {
+	return startOperation(client, operationInitializer{
+		newOperation: func(operator ops.Operator, cluster ops.Site) (*ops.SiteOperationKey, error) {
+			return operator.CreateUpdateEnvarsOperation(ops.CreateUpdateEnvarsOperationRequest{
+				ClusterKey: cluster.Key(),
+				Env:        env,
+			})
+		},
+		newOperationPlan: func(operator ops.Operator, operation ops.SiteOperation, cluster ops.Site) error {
+			_, err := environ.NewOperationPlan(operator, operation, cluster.ClusterState.Servers)
+			return trace.Wrap(err)
+		},
+	})
+}
@@ -0,0 +1,262 @@
+package provider
+
+import (
+	"log"
+	"time"
+
+	"github.com/gravitational/gravity/lib/ops/opsclient"
+	"github.com/gravitational/teleport/lib/services"
+	"github.com/gravitational/trace"
+
+	"github.com/hashicorp/terraform/helper/schema"
+)
+
+func resourceGravitySAML() *schema.Resource {
+	return &schema.Resource{
+		Create: resourceGravitySAMLCreateOrUpdate,
+		Read:   resourceGravitySAMLRead,
+		Update: resourceGravitySAMLCreateOrUpdate,
+		Delete: resourceGravitySAMLDelete,
+		Exists: resourceGravitySAMLExists,
+		Importer: &schema.ResourceImporter{
+			State: schema.ImportStatePassthrough,
+		},
+
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(1 * time.Minute),
+			Delete: schema.DefaultTimeout(1 * time.Minute),
+		},
+
+		// The cluster fills in the identity provider settings from the entity
+		// descriptor and generates the signing key pair if it is not provided,
+		// so these attributes are optional and computed
+		Schema: map[string]*schema.Schema{
+			"name": {
+				Type:        schema.TypeString,
+				Required:    true,
+				Description: "The name of the resource",
+				ForceNew:    true,
+			},
+			"acs": {
+				Type:        schema.TypeString,
+				Required:    true,
+				Description: "The URL of the assertion consumer service",
+			},
+			"issuer": {
+				Type:     schema.TypeString,
+				Optional: true,
+				Computed: true,
+			},
+			"sso": {
+				Type:     schema.TypeString,
+				Optional: true,
+				Computed: true,
+			},
+			"cert": {
+				Type:     schema.TypeString,
+				Optional: true,
+				Computed: true,
+			},
+			"audience": {
+				Type:     schema.TypeString,
+				Optional: true,
+				Computed: true,
+			},
+			"service_provider_issuer": {
+				Type:     schema.TypeString,
+				Optional: true,
+				Computed: true,
+			},
+			"entity_descriptor": {
+				Type:     schema.TypeString,
+				Optional: true,
+				Computed: true,
+			},
+			"entity_descriptor_url": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"display": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"identity_provider": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"attributes_to_roles": {
+				Type:     schema.TypeSet,
+				Required: true,
+				MinItems: 1,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"name": {
+							Type:     schema.TypeString,
+							Required: true,
+						},
+						"value": {
+							Type:     schema.TypeString,
+							Required: true,
+						},
+						"roles": {
+							Type:     schema.TypeList,
+							Required: true,
+							MinItems: 1,
+							Elem: &schema.Schema{
+								Type: schema.TypeString,
+							},
+						},
+					},
+				},
+			},
+			"signing_key_pair": {
+				Type:     schema.TypeList,
+				Optional: true,
+				Computed: true,
+				MaxItems: 1,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"private_key": {
+							Type:     schema.TypeString,
+							Required: true,
+
+							Sensitive: true,
+						},
+						"cert": {
+							Type:     schema.TypeString,
+							Required: true,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func parseAttributeMapping(m map[string]interface{}) services.AttributeMapping {
+	return services.AttributeMapping{
+		Name:  m["name"].(string),
+		Value: m["value"].(string),
+		Roles: ExpandStringList(m["roles"].([]interface{})),
+	}
+}
+
+func resourceGravitySAMLCreateOrUpdate(d *schema.ResourceData, m interface{}) error {
+	client := m.(*opsclient.Client)
+	clusterKey, err := client.LocalClusterKey()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	name := d.Get("name").(string)
+
+	var mappings []services.AttributeMapping
+	if v := d.Get("attributes_to_roles").(*schema.Set); v.Len() > 0 {
+		mappings = make([]services.AttributeMapping, 0, v.Len())
+		for _, v := range v.List() {
+			mappings = append(mappings, parseAttributeMapping(v.(map[string]interface{})))
+		}
+	}
+
+	var keyPair *services.SigningKeyPair
+	if v := d.Get("signing_key_pair").([]interface{}); len(v) > 0 && v[0] != nil {
+		pair := v[0].(map[string]interface{})
+		keyPair = &services.SigningKeyPair{
+			PrivateKey: pair["private_key"].(string),
+			Cert:       pair["cert"].(string),
+		}
+	}
+
+	connector := services.NewSAMLConnector(
+		name,
+		services.SAMLConnectorSpecV2{
+			AssertionConsumerService: d.Get("acs").(string),
+			Issuer:                   d.Get("issuer").(string),
+			SSO:                      d.Get("sso").(string),
+			Cert:                     d.Get("cert").(string),
+			Audience:                 d.Get("audience").(string),
+			ServiceProviderIssuer:    d.Get("service_provider_issuer").(string),
+			EntityDescriptor:         d.Get("entity_descriptor").(string),
+			EntityDescriptorURL:      d.Get("entity_descriptor_url").(string),
+			Display:                  d.Get("display").(string),
+			Provider:                 d.Get("identity_provider").(string),
+			AttributesToRoles:        mappings,
+			SigningKeyPair:           keyPair,
+		},
+	)
+
+	err = client.UpsertSAMLConnector(clusterKey, connector)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	log.Printf("[INFO] SAML connector %s created", name)
+	d.SetId(name)
+
+	return resourceGravitySAMLRead(d, m)
+}
+
+func resourceGravitySAMLRead(d *schema.ResourceData, m interface{}) error {
+	client := m.(*opsclient.Client)
+	clusterKey, err := client.LocalClusterKey()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	connector, err := client.GetSAMLConnector(clusterKey, d.Id(), true)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	d.Set("name", connector.GetName())
+	d.Set("acs", connector.GetAssertionConsumerService())
+	d.Set("issuer", connector.GetIssuer())
+	d.Set("sso", connector.GetSSO())
+	d.Set("cert", connector.GetCert())
+	d.Set("audience", connector.GetAudience())
+	d.Set("service_provider_issuer", connector.GetServiceProviderIssuer())
+	d.Set("entity_descriptor", connector.GetEntityDescriptor())
+	d.Set("entity_descriptor_url", connector.GetEntityDescriptorURL())
+	d.Set("display", connector.GetDisplay())
+	d.Set("identity_provider", connector.GetProvider())
+
+	var attributesToRoles []interface{}
+	for _, mapping := range connector.GetAttributesToRoles() {
+		attributesToRoles = append(attributesToRoles, map[string]interface{}{
+			"name":  mapping.Name,
+			"value": mapping.Value,
+			"roles": mapping.Roles,
+		})
+	}
+	d.Set("attributes_to_roles", attributesToRoles)
+
+	var keyPair []interface{}
+	if pair := connector.GetSigningKeyPair(); pair != nil {
+		keyPair = append(keyPair, map[string]interface{}{
+			"private_key": pair.PrivateKey,
+			"cert":        pair.Cert,
+		})
+	}
+	d.Set("signing_key_pair", keyPair)
+
+	return nil
+}
+
+func resourceGravitySAMLDelete(d *schema.ResourceData, m interface{}) error {
+	client := m.(*opsclient.Client)
+	clusterKey, err := client.LocalClusterKey()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	err = client.DeleteSAMLConnector(clusterKey, d.Id())
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	return nil
+}
+
+func resourceGravitySAMLExists(d *schema.ResourceData, m interface{}) (bool, error) {
+	return resourceExists(resourceGravitySAMLRead(d, m))
+}
@@ -0,0 +1,113 @@
+package provider
+
+import (
+	"time"
+
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/ops/opsclient"
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/trace"
+
+	"github.com/hashicorp/terraform/helper/schema"
+)
+
+func resourceGravitySMTP() *schema.Resource {
+	return &schema.Resource{
+		Create: resourceGravitySMTPUpsert,
+		Read:   resourceGravitySMTPRead,
+		Update: resourceGravitySMTPUpsert,
+		Delete: resourceGravitySMTPDelete,
+		Exists: resourceGravitySMTPExists,
+		Importer: &schema.ResourceImporter{
+			State: schema.ImportStatePassthrough,
+		},
+
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(1 * time.Minute),
+			Delete: schema.DefaultTimeout(1 * time.Minute),
+		},
+
+		Schema: map[string]*schema.Schema{
+			"host": {
+				Type:     schema.TypeString,
+				Required: true,
+			},
+			"port": {
+				Type:     schema.TypeInt,
+				Optional: true,
+				Default:  defaults.SMTPPort,
+			},
+			"username": {
+				Type:     schema.TypeString,
+				Required: true,
+			},
+			"password": {
+				Type:     schema.TypeString,
+				Required: true,
+
+				Sensitive: true,
+			},
+		},
+	}
+}
+
+func resourceGravitySMTPUpsert(d *schema.ResourceData, m interface{}) error {
+	client := m.(*opsclient.Client)
+	clusterKey, err := client.LocalClusterKey()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	config := storage.NewSMTPConfig(storage.SMTPConfigSpecV2{
+		Host:     d.Get("host").(string),
+		Port:     d.Get("port").(int),
+		Username: d.Get("username").(string),
+		Password: d.Get("password").(string),
+	})
+	if err := config.CheckAndSetDefaults(); err != nil {
+		return trace.Wrap(err)
+	}
+
+	err = client.UpdateSMTPConfig(clusterKey, config)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	// The cluster has a single SMTP configuration so a static ID is used
+	d.SetId(storage.KindSMTPConfig)
+	return nil
+}
+
+func resourceGravitySMTPRead(d *schema.ResourceData, m interface{}) error {
+	client := m.(*opsclient.Client)
+	clusterKey, err := client.LocalClusterKey()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	config, err := client.GetSMTPConfig(clusterKey, true)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	d.Set("host", config.GetHost())
+	d.Set("port", config.GetPort())
+	d.Set("username", config.GetUsername())
+	d.Set("password", config.GetPassword())
+	return nil
+}
+
+func resourceGravitySMTPDelete(d *schema.ResourceData, m interface{}) error {
+	client := m.(*opsclient.Client)
+	clusterKey, err := client.LocalClusterKey()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	err = client.DeleteSMTPConfig(clusterKey)
+	return trace.Wrap(err)
+}
+
+func resourceGravitySMTPExists(d *schema.ResourceData, m interface{}) (bool, error) {
+	return resourceExists(resourceGravitySMTPRead(d, m))
+}
@@ -17,6 +17,9 @@ func resourceGravityTLSKeyPair() *schema.Resource {
 		Update: resourceGravityTLSKeyPairCreate,
 		Delete: resourceGravityTLSKeyPairDelete,
 		Exists: resourceGravityTLSKeyPairExists,
+		Importer: &schema.ResourceImporter{
+			State: schema.ImportStatePassthrough,
+		},
 
 		Timeouts: &schema.ResourceTimeout{
 			Create: schema.DefaultTimeout(1 * time.Minute),
@@ -46,7 +49,7 @@ func resourceGravityTLSKeyPairCreate(d *schema.ResourceData, m interface{}) erro
 	}
 
 	privateKey := d.Get("private_key").(string)
-	cert := d.Get("certificate").(string)
+	cert := d.Get("cert").(string)
 
 	_, err = client.UpdateClusterCertificate(ops.UpdateCertificateRequest{
 		AccountID:   clusterKey.AccountID,
@@ -76,7 +79,7 @@ func resourceGravityTLSKeyPairRead(d *schema.ResourceData, m interface{}) error
 	}
 
 	d.Set("private_key", string(cert.PrivateKey))
-	d.Set("certificate", string(cert.Certificate))
+	d.Set("cert", string(cert.Certificate))
 	return nil
 }
 
@@ -92,9 +95,5 @@ func resourceGravityTLSKeyPairDelete(d *schema.ResourceData, m interface{}) erro
 }
 
 func resourceGravityTLSKeyPairExists(d *schema.ResourceData, m interface{}) (bool, error) {
-	err := resourceGravityTLSKeyPairRead(d, m)
-	if err != nil {
-		return false, trace.Wrap(err)
-	}
-	return true, nil
+	return resourceExists(resourceGravityTLSKeyPairRead(d, m))
 }
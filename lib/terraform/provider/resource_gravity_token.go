@@ -1,6 +1,7 @@
 package provider
 
 import (
+	"strings"
 	"time"
 
 	"github.com/gravitational/gravity/lib/ops"
@@ -17,6 +18,9 @@ func resourceGravityToken() *schema.Resource {
 		Update: resourceGravityTokenUpdate,
 		Delete: resourceGravityTokenDelete,
 		Exists: resourceGravityTokenExists,
+		Importer: &schema.ResourceImporter{
+			State: resourceGravityTokenImport,
+		},
 
 		Timeouts: &schema.ResourceTimeout{
 			Create: schema.DefaultTimeout(1 * time.Minute),
@@ -109,9 +113,18 @@ func resourceGravityTokenDelete(d *schema.ResourceData, m interface{}) error {
 }
 
 func resourceGravityTokenExists(d *schema.ResourceData, m interface{}) (bool, error) {
-	err := resourceGravityTokenRead(d, m)
-	if err != nil {
-		return false, trace.Wrap(err)
+	return resourceExists(resourceGravityTokenRead(d, m))
+}
+
+// resourceGravityTokenImport imports an existing token specified as <user>:<token>
+// since tokens can only be looked up by the owning user
+func resourceGravityTokenImport(d *schema.ResourceData, m interface{}) ([]*schema.ResourceData, error) {
+	parts := strings.SplitN(d.Id(), ":", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return nil, trace.BadParameter("expected token ID in the format <user>:<token>, got %q", d.Id())
 	}
-	return true, nil
+	d.Set("user", parts[0])
+	d.Set("token", parts[1])
+	d.SetId(parts[1])
+	return []*schema.ResourceData{d}, nil
 }
@@ -0,0 +1,154 @@
+package provider
+
+import (
+	"log"
+	"time"
+
+	"github.com/gravitational/gravity/lib/ops/opsclient"
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/trace"
+
+	"github.com/hashicorp/terraform/helper/schema"
+)
+
+func resourceGravityTrustedCluster() *schema.Resource {
+	return &schema.Resource{
+		Create: resourceGravityTrustedClusterCreateOrUpdate,
+		Read:   resourceGravityTrustedClusterRead,
+		Update: resourceGravityTrustedClusterCreateOrUpdate,
+		Delete: resourceGravityTrustedClusterDelete,
+		Exists: resourceGravityTrustedClusterExists,
+		Importer: &schema.ResourceImporter{
+			State: schema.ImportStatePassthrough,
+		},
+
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(5 * time.Minute),
+			Delete: schema.DefaultTimeout(1 * time.Minute),
+		},
+
+		Schema: map[string]*schema.Schema{
+			"name": {
+				Type:        schema.TypeString,
+				Required:    true,
+				Description: "The name of the Ops Center cluster",
+				ForceNew:    true,
+			},
+			"enabled": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  true,
+			},
+			"token": {
+				Type:     schema.TypeString,
+				Required: true,
+
+				Sensitive: true,
+			},
+			"web_proxy_addr": {
+				Type:     schema.TypeString,
+				Required: true,
+			},
+			"tunnel_addr": {
+				Type:     schema.TypeString,
+				Required: true,
+			},
+			"sni_host": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"roles": {
+				Type:     schema.TypeList,
+				Optional: true,
+				Computed: true,
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+			},
+			"pull_updates": {
+				Type:     schema.TypeBool,
+				Optional: true,
+			},
+		},
+	}
+}
+
+func resourceGravityTrustedClusterCreateOrUpdate(d *schema.ResourceData, m interface{}) error {
+	client := m.(*opsclient.Client)
+	clusterKey, err := client.LocalClusterKey()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	name := d.Get("name").(string)
+
+	cluster := storage.NewTrustedCluster(name, storage.TrustedClusterSpecV2{
+		Enabled:              d.Get("enabled").(bool),
+		Token:                d.Get("token").(string),
+		ProxyAddress:         d.Get("web_proxy_addr").(string),
+		ReverseTunnelAddress: d.Get("tunnel_addr").(string),
+		SNIHost:              d.Get("sni_host").(string),
+		Roles:                ExpandStringList(d.Get("roles").([]interface{})),
+		PullUpdates:          d.Get("pull_updates").(bool),
+	})
+	if err := cluster.CheckAndSetDefaults(); err != nil {
+		return trace.Wrap(err)
+	}
+
+	err = client.UpsertTrustedCluster(clusterKey, cluster)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	log.Printf("[INFO] Trusted cluster %s created", name)
+	d.SetId(name)
+
+	return resourceGravityTrustedClusterRead(d, m)
+}
+
+func resourceGravityTrustedClusterRead(d *schema.ResourceData, m interface{}) error {
+	client := m.(*opsclient.Client)
+	clusterKey, err := client.LocalClusterKey()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	tc, err := client.GetTrustedCluster(clusterKey, d.Id())
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	cluster, ok := tc.(storage.TrustedCluster)
+	if !ok {
+		return trace.BadParameter("unexpected trusted cluster type %T", tc)
+	}
+
+	d.Set("name", cluster.GetName())
+	d.Set("enabled", cluster.GetEnabled())
+	d.Set("token", cluster.GetToken())
+	d.Set("web_proxy_addr", cluster.GetProxyAddress())
+	d.Set("tunnel_addr", cluster.GetReverseTunnelAddress())
+	d.Set("sni_host", cluster.GetSNIHost())
+	d.Set("roles", cluster.GetRoles())
+	d.Set("pull_updates", cluster.GetPullUpdates())
+
+	return nil
+}
+
+func resourceGravityTrustedClusterDelete(d *schema.ResourceData, m interface{}) error {
+	client := m.(*opsclient.Client)
+	clusterKey, err := client.LocalClusterKey()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	err = client.DeleteTrustedCluster(clusterKey, d.Id())
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	return nil
+}
+
+func resourceGravityTrustedClusterExists(d *schema.ResourceData, m interface{}) (bool, error) {
+	return resourceExists(resourceGravityTrustedClusterRead(d, m))
+}
@@ -16,6 +16,9 @@ func resourceGravityUser() *schema.Resource {
 		Update: resourceGravityUserUpsert,
 		Delete: resourceGravityUserDelete,
 		Exists: resourceGravityUserExists,
+		Importer: &schema.ResourceImporter{
+			State: schema.ImportStatePassthrough,
+		},
 
 		Timeouts: &schema.ResourceTimeout{
 			Create: schema.DefaultTimeout(1 * time.Minute),
@@ -26,6 +29,7 @@ func resourceGravityUser() *schema.Resource {
 			"name": {
 				Type:     schema.TypeString,
 				Required: true,
+				ForceNew: true,
 			},
 			"full_name": {
 				Type:     schema.TypeString,
@@ -77,7 +81,7 @@ func resourceGravityUserUpsert(d *schema.ResourceData, m interface{}) error {
 
 	err = client.UpsertUser(clusterKey, user)
 	if err != nil {
-		trace.Wrap(err)
+		return trace.Wrap(err)
 	}
 
 	d.SetId(name)
@@ -91,14 +95,13 @@ func resourceGravityUserRead(d *schema.ResourceData, m interface{}) error {
 		return trace.Wrap(err)
 	}
 
-	name := d.Get("name").(string)
-
-	u, err := client.GetUser(clusterKey, name)
+	u, err := client.GetUser(clusterKey, d.Id())
 	if err != nil {
 		return trace.Wrap(err)
 	}
 	user := u.(storage.User)
 
+	d.Set("name", user.GetName())
 	d.Set("full_name", user.GetFullName())
 	// skip password, because the server will change to bcrypt, which will conflict with the tf state
 	d.Set("type", user.GetType())
@@ -114,17 +117,10 @@ func resourceGravityUserDelete(d *schema.ResourceData, m interface{}) error {
 		return trace.Wrap(err)
 	}
 
-	name := d.Get("name").(string)
-
-	err = client.DeleteUser(clusterKey, name)
+	err = client.DeleteUser(clusterKey, d.Id())
 	return trace.Wrap(err)
 }
 
 func resourceGravityUserExists(d *schema.ResourceData, m interface{}) (bool, error) {
-	err := resourceGravityUserRead(d, m)
-	if err != nil {
-		return false, trace.Wrap(err)
-	}
-
-	return true, nil
+	return resourceExists(resourceGravityUserRead(d, m))
 }
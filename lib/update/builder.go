@@ -78,6 +78,30 @@ func (p *Phase) RequireLiteral(ids ...string) *Phase {
 	return p
 }
 
+// Nest returns a copy of this phase with absolute IDs of the phase,
+// its sub-phases and their requirements relocated under the specified parent.
+// Relative IDs are left intact as they are resolved against the parent phase
+func (p Phase) Nest(parent string) Phase {
+	result := p
+	result.ID = nestID(parent, p.ID)
+	result.Requires = nil
+	for _, req := range p.Requires {
+		result.Requires = append(result.Requires, nestID(parent, req))
+	}
+	result.Phases = nil
+	for _, sub := range p.Phases {
+		result.Phases = append(result.Phases, storage.OperationPhase(Phase(sub).Nest(parent)))
+	}
+	return result
+}
+
+func nestID(parent, id string) string {
+	if !path.IsAbs(id) {
+		return id
+	}
+	return path.Join(parent, id)
+}
+
 // GetID returns this phase's ID.
 // implements PhaseDependency
 func (p Phase) GetID() string {
@@ -21,6 +21,7 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gravitational/gravity/lib/constants"
 	"github.com/gravitational/gravity/lib/defaults"
@@ -289,21 +290,97 @@ func (r phaseBuilder) masters(leadMaster runtimeServer, otherMasters runtimeServ
 	return &root
 }
 
-func (r phaseBuilder) nodes(leadMaster storage.Server, nodes []runtimeServer, supportsTaints bool) *update.Phase {
+// nodes returns a new phase for upgrading regular nodes.
+// If strategy is specified, the nodes are optionally preceded by a canary node
+// and are updated in batches as configured by the strategy
+func (r phaseBuilder) nodes(leadMaster storage.Server, installedApp loc.Locator, nodes []runtimeServer,
+	supportsTaints bool, strategy *storage.UpdateStrategy) *update.Phase {
 	root := update.RootPhase(update.Phase{
 		ID:          "nodes",
 		Description: "Update regular nodes",
 	})
 
-	for _, server := range nodes {
-		node := r.node(server.Server, &root, "Update system software on node %q")
-		node.AddSequential(r.commonNode(server.Server, server.runtime, leadMaster, supportsTaints,
+	if strategy == nil {
+		for _, server := range nodes {
+			node := r.node(server.Server, &root, "Update system software on node %q")
+			node.AddSequential(r.commonNode(server.Server, server.runtime, leadMaster, supportsTaints,
+				waitsForEndpoints(true))...)
+			root.AddParallel(node)
+		}
+		return &root
+	}
+
+	if strategy.Canary && len(nodes) != 0 {
+		canary := update.Phase{
+			ID:          root.ChildLiteral("canary"),
+			Description: fmt.Sprintf("Update and verify canary node %q", nodes[0].Hostname),
+		}
+		node := r.node(nodes[0].Server, &canary, "Update system software on node %q")
+		node.AddSequential(r.commonNode(nodes[0].Server, nodes[0].runtime, leadMaster, supportsTaints,
 			waitsForEndpoints(true))...)
-		root.AddParallel(node)
+		canary.AddSequential(node, r.canaryVerify(nodes[0].Server, leadMaster, installedApp))
+		nodes = nodes[1:]
+		if len(nodes) != 0 {
+			canary.AddSequential(r.canaryApprove(strategy.CanaryPause))
+		}
+		root.AddSequential(canary)
+	}
+
+	batchSize := strategy.NodeBatchSize()
+	for i := 0; i < len(nodes); i += batchSize {
+		end := i + batchSize
+		if end > len(nodes) {
+			end = len(nodes)
+		}
+		batch := update.Phase{
+			ID:          root.ChildLiteral(fmt.Sprintf("batch-%v", i/batchSize+1)),
+			Description: fmt.Sprintf("Update nodes %v", strings.Join(runtimeServers(nodes[i:end]).hostnames(), ", ")),
+			Parallel:    true,
+		}
+		for _, server := range nodes[i:end] {
+			node := r.node(server.Server, &batch, "Update system software on node %q")
+			node.AddSequential(r.commonNode(server.Server, server.runtime, leadMaster, supportsTaints,
+				waitsForEndpoints(true))...)
+			batch.AddParallel(node)
+		}
+		root.AddSequential(batch)
 	}
 	return &root
 }
 
+// canaryVerify returns a phase that verifies the health of the canary node
+// and the application after the node has been updated
+func (r phaseBuilder) canaryVerify(server, leadMaster storage.Server, installedApp loc.Locator) update.Phase {
+	return update.Phase{
+		ID:          "verify",
+		Executor:    canaryVerify,
+		Description: fmt.Sprintf("Verify cluster health after updating node %q", server.Hostname),
+		Data: &storage.OperationPhaseData{
+			Server:     &server,
+			ExecServer: &leadMaster,
+			Package:    &installedApp,
+		},
+	}
+}
+
+// canaryApprove returns a phase that blocks the update of the remaining nodes
+// until either the specified pause elapses or the update is explicitly approved
+// if no pause has been specified
+func (r phaseBuilder) canaryApprove(pause time.Duration) update.Phase {
+	phase := update.Phase{
+		ID:          "approve",
+		Executor:    canaryApprove,
+		Description: "Wait for approval to update the remaining nodes",
+	}
+	if pause != 0 {
+		phase.Description = fmt.Sprintf("Wait %v before updating the remaining nodes", pause)
+		phase.Data = &storage.OperationPhaseData{
+			Data: pause.String(),
+		}
+	}
+	return phase
+}
+
 func (r phaseBuilder) etcdPlan(
 	leadMaster storage.Server,
 	otherMasters []storage.Server,
@@ -385,6 +462,7 @@ func (r phaseBuilder) etcdPlan(
 		Executor:    updateEtcdRestore,
 		Data: &storage.OperationPhaseData{
 			Server: &leadMaster,
+			Update: r.updateData(),
 		},
 	}
 	root.AddSequential(restoreData)
@@ -429,6 +507,7 @@ func (r phaseBuilder) etcdBackupNode(server storage.Server, parent update.Phase)
 		Executor:    updateEtcdBackup,
 		Data: &storage.OperationPhaseData{
 			Server: &server,
+			Update: r.updateData(),
 		},
 	}
 }
@@ -493,6 +572,7 @@ func (r phaseBuilder) commonNode(server storage.Server, runtimePackage loc.Locat
 			Data: &storage.OperationPhaseData{
 				Server:         &server,
 				RuntimePackage: &runtimePackage,
+				Update:         r.updateData(),
 			}},
 	}
 	if supportsTaints {
@@ -536,6 +616,21 @@ func (r phaseBuilder) commonNode(server storage.Server, runtimePackage loc.Locat
 	return phases
 }
 
+// verify returns a phase that verifies the health of the cluster
+// and the updated application
+func (r phaseBuilder) verify(leadMaster storage.Server, appPackage loc.Locator) *update.Phase {
+	phase := update.RootPhase(update.Phase{
+		ID:          "verify",
+		Executor:    verifyUpdate,
+		Description: "Verify cluster health after the update",
+		Data: &storage.OperationPhaseData{
+			ExecServer: &leadMaster,
+			Package:    &appPackage,
+		},
+	})
+	return &phase
+}
+
 func (r phaseBuilder) cleanup(nodes []storage.Server) *update.Phase {
 	root := update.RootPhase(update.Phase{
 		ID:          "gc",
@@ -553,7 +648,21 @@ func (r phaseBuilder) cleanup(nodes []storage.Server) *update.Phase {
 	return &root
 }
 
-type phaseBuilder struct{}
+type phaseBuilder struct {
+	// changesetID optionally specifies the ID of the package changeset
+	// to record system updates with.
+	// It also names the etcd backup of the step
+	changesetID string
+}
+
+// updateData returns the update data with the changeset ID of this builder.
+// Returns nil if the builder uses the default changeset
+func (r phaseBuilder) updateData() *storage.UpdateOperationData {
+	if r.changesetID == "" {
+		return nil
+	}
+	return &storage.UpdateOperationData{ChangesetID: r.changesetID}
+}
 
 func shouldUpdateCoreDNS(client *kubernetes.Clientset) (bool, error) {
 	_, err := client.RbacV1().ClusterRoles().Get(libphase.CoreDNSResourceName, metav1.GetOptions{})
@@ -598,18 +707,14 @@ func supportsTaints(gravityPackage loc.Locator) (supports bool, err error) {
 
 func shouldUpdateEtcd(p planConfig) (updateEtcd bool, installedEtcdVersion string, updateEtcdVersion string, err error) {
 	// TODO: should somehow maintain etcd version invariant across runtime packages
-	runtimePackage, err := p.installedRuntime.Manifest.DefaultRuntimePackage()
-	if err != nil && !trace.IsNotFound(err) {
-		return false, "", "", trace.Wrap(err)
-	}
+	runtimePackage, err := installedRuntimePackage(p.installedRuntime)
 	if err != nil {
-		runtimePackage, err = p.installedRuntime.Manifest.Dependencies.ByName(loc.LegacyPlanetMaster.Name)
-		if err != nil {
+		if trace.IsNotFound(err) {
 			log.Warnf("Failed to fetch the runtime package: %v.", err)
-			return false, "", "", trace.NotFound("runtime package not found")
 		}
+		return false, "", "", trace.Wrap(err)
 	}
-	installedVersion, err := getEtcdVersion("version-etcd", *runtimePackage, p.packageService)
+	installedVersion, err := getVersionLabel(etcdVersionLabel, *runtimePackage, p.packageService)
 	if err != nil {
 		if !trace.IsNotFound(err) {
 			return false, "", "", trace.Wrap(err)
@@ -621,7 +726,7 @@ func shouldUpdateEtcd(p planConfig) (updateEtcd bool, installedEtcdVersion strin
 	if err != nil {
 		return false, "", "", trace.Wrap(err)
 	}
-	updateVersion, err := getEtcdVersion("version-etcd", *runtimePackage, p.packageService)
+	updateVersion, err := getVersionLabel(etcdVersionLabel, *runtimePackage, p.packageService)
 	if err != nil {
 		return false, "", "", trace.Wrap(err)
 	}
@@ -635,7 +740,8 @@ func shouldUpdateEtcd(p planConfig) (updateEtcd bool, installedEtcdVersion strin
 	return updateEtcd, installedEtcdVersion, updateEtcdVersion, nil
 }
 
-func getEtcdVersion(searchLabel string, locator loc.Locator, packageService pack.PackageService) (*semver.Version, error) {
+// getVersionLabel returns the version stored in the specified label of the package manifest
+func getVersionLabel(searchLabel string, locator loc.Locator, packageService pack.PackageService) (*semver.Version, error) {
 	manifest, err := pack.GetPackageManifest(packageService, locator)
 	if err != nil {
 		return nil, trace.Wrap(err)
@@ -687,6 +793,14 @@ func (r runtimeServers) asServers() (result []storage.Server) {
 	return result
 }
 
+func (r runtimeServers) hostnames() (result []string) {
+	result = make([]string, 0, len(r))
+	for _, server := range r {
+		result = append(result, server.Hostname)
+	}
+	return result
+}
+
 type runtimeServers []runtimeServer
 
 type runtimeServer struct {
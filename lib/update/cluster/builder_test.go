@@ -17,11 +17,14 @@ limitations under the License.
 package cluster
 
 import (
+	"fmt"
+
 	"github.com/gravitational/gravity/lib/app"
 	apptest "github.com/gravitational/gravity/lib/app/service/test"
 	"github.com/gravitational/gravity/lib/archive"
 	"github.com/gravitational/gravity/lib/compare"
 	"github.com/gravitational/gravity/lib/constants"
+	"github.com/gravitational/gravity/lib/fsm"
 	"github.com/gravitational/gravity/lib/loc"
 	"github.com/gravitational/gravity/lib/ops"
 	"github.com/gravitational/gravity/lib/ops/opsservice"
@@ -30,6 +33,7 @@ import (
 	"github.com/gravitational/gravity/lib/storage"
 	"github.com/gravitational/gravity/lib/update"
 
+	"github.com/coreos/go-semver/semver"
 	teleservices "github.com/gravitational/teleport/lib/services"
 	"gopkg.in/check.v1"
 )
@@ -80,7 +84,7 @@ func (s *PlanSuite) TestPlanWithRuntimeUpdate(c *check.C) {
 	leadMaster := runtimeServer{params.servers[0], runtimeLoc}
 	coreDNS := *builder.corednsPhase(leadMaster.Server)
 	masters := *builder.masters(leadMaster, servers[1:2], false).Require(checks, bootstrap, preUpdate, coreDNS)
-	nodes := *builder.nodes(leadMaster.Server, servers[2:], false).Require(masters)
+	nodes := *builder.nodes(leadMaster.Server, appLoc1, servers[2:], false, nil).Require(masters)
 	etcd := *builder.etcdPlan(leadMaster.Server, params.servers[1:2], params.servers[2:], "1.0.0", "2.0.0")
 	migration := builder.migration(leadMaster.Server, params).Require(etcd)
 	c.Assert(migration, check.NotNil)
@@ -164,6 +168,208 @@ func (s *PlanSuite) TestPlanWithoutRuntimeUpdate(c *check.C) {
 	c.Assert(*obtainedPlan, compare.DeepEquals, plan)
 }
 
+func (s *PlanSuite) TestPlanWithAutoRollback(c *check.C) {
+	runtimeLoc1 := loc.MustParseLocator("gravitational.io/runtime:1.0.0")
+	appLoc1 := loc.MustParseLocator("gravitational.io/app:1.0.0")
+	appLoc2 := loc.MustParseLocator("gravitational.io/app:2.0.0")
+
+	_, params := newTestPlan(c, params{
+		installedRuntime:         runtimeLoc1,
+		installedApp:             appLoc1,
+		updateRuntime:            runtimeLoc1,
+		updateApp:                appLoc2,
+		installedRuntimeManifest: installedRuntimeManifest,
+		installedAppManifest:     installedAppManifest,
+		updateRuntimeManifest:    installedRuntimeManifest,
+		updateAppManifest:        updateAppManifest,
+	})
+	params.operation.Update = &storage.UpdateOperationState{
+		UpdatePackage: appLoc2.String(),
+		AutoRollback:  true,
+	}
+
+	plan, err := newOperationPlan(params)
+	c.Assert(err, check.IsNil)
+
+	var ids []string
+	for _, phase := range plan.Phases {
+		ids = append(ids, phase.ID)
+	}
+	c.Assert(ids, check.DeepEquals, []string{"/init", "/checks", "/pre-update", "/app", "/verify", "/gc"})
+	verify := plan.Phases[4]
+	c.Assert(verify.Executor, check.Equals, verifyUpdate)
+	c.Assert(verify.Data.Package, check.DeepEquals, &appLoc2)
+	c.Assert(verify.Requires, check.DeepEquals, []string{"/app"})
+	c.Assert(plan.Phases[5].Requires, check.DeepEquals, []string{"/verify"})
+}
+
+func (s *PlanSuite) TestPlanWithIntermediateRuntimes(c *check.C) {
+	runtimeLoc1 := loc.MustParseLocator("gravitational.io/runtime:1.0.0")
+	appLoc1 := loc.MustParseLocator("gravitational.io/app:1.0.0")
+	runtimeLoc2 := loc.MustParseLocator("gravitational.io/runtime:2.0.0")
+	appLoc2 := loc.MustParseLocator("gravitational.io/app:2.0.0")
+	intermediateLoc := loc.MustParseLocator("gravitational.io/planet:1.5.0")
+
+	_, params := newTestPlan(c, params{
+		installedRuntime:         runtimeLoc1,
+		installedApp:             appLoc1,
+		updateRuntime:            runtimeLoc2,
+		updateApp:                appLoc2,
+		installedRuntimeManifest: installedRuntimeManifest,
+		installedAppManifest:     installedAppManifest,
+		updateRuntimeManifest:    updateRuntimeManifest,
+		updateAppManifest:        updateAppManifest,
+	})
+	params.intermediateRuntimes = []intermediateRuntime{
+		{runtime: intermediateLoc, etcdVersion: "1.5.0"},
+	}
+
+	plan, err := newOperationPlan(params)
+	c.Assert(err, check.IsNil)
+
+	var ids []string
+	for _, phase := range plan.Phases {
+		ids = append(ids, phase.ID)
+	}
+	c.Assert(ids, check.DeepEquals, []string{"/init", "/checks", "/pre-update", "/bootstrap",
+		"/intermediate-1.5.0", "/masters", "/nodes", "/etcd", "/migration", "/config", "/runtime", "/app", "/gc"})
+
+	step := plan.Phases[4]
+	c.Assert(step.Requires, check.DeepEquals, []string{"/checks", "/bootstrap", "/pre-update"})
+	c.Assert(plan.Phases[5].Requires, check.DeepEquals, []string{"/intermediate-1.5.0"})
+
+	ids = nil
+	for _, phase := range step.Phases {
+		ids = append(ids, phase.ID)
+	}
+	c.Assert(ids, check.DeepEquals, []string{"/intermediate-1.5.0/masters",
+		"/intermediate-1.5.0/nodes", "/intermediate-1.5.0/etcd"})
+	c.Assert(step.Phases[1].Requires, check.DeepEquals, []string{"/intermediate-1.5.0/masters"})
+
+	upgrade := step.Phases[0].Phases[0].Phases[3]
+	c.Assert(upgrade.ID, check.Equals, "/intermediate-1.5.0/masters/node-1/system-upgrade")
+	c.Assert(upgrade.Data.RuntimePackage, check.DeepEquals, &intermediateLoc)
+	c.Assert(upgrade.Data.Update, check.DeepEquals, &storage.UpdateOperationData{ChangesetID: "123-1.5.0"})
+
+	c.Assert(step.Phases[2].Description, check.Equals, "Upgrade etcd 1.0.0 to 1.5.0")
+	c.Assert(plan.Phases[7].Description, check.Equals, "Upgrade etcd 1.5.0 to 2.0.0")
+}
+
+// TestRollsBackIntermediateEtcdSteps verifies that every etcd update step keeps
+// its backup under the changeset of the step and that the steps are rolled back
+// in reverse order
+func (s *PlanSuite) TestRollsBackIntermediateEtcdSteps(c *check.C) {
+	runtimeLoc1 := loc.MustParseLocator("gravitational.io/runtime:1.0.0")
+	appLoc1 := loc.MustParseLocator("gravitational.io/app:1.0.0")
+	runtimeLoc2 := loc.MustParseLocator("gravitational.io/runtime:2.0.0")
+	appLoc2 := loc.MustParseLocator("gravitational.io/app:2.0.0")
+
+	_, params := newTestPlan(c, params{
+		installedRuntime:         runtimeLoc1,
+		installedApp:             appLoc1,
+		updateRuntime:            runtimeLoc2,
+		updateApp:                appLoc2,
+		installedRuntimeManifest: installedRuntimeManifest,
+		installedAppManifest:     installedAppManifest,
+		updateRuntimeManifest:    updateRuntimeManifest,
+		updateAppManifest:        updateAppManifest,
+	})
+	params.intermediateRuntimes = []intermediateRuntime{
+		{runtime: loc.MustParseLocator("gravitational.io/planet:1.3.0"), etcdVersion: "1.3.0"},
+		{runtime: loc.MustParseLocator("gravitational.io/planet:1.5.0"), etcdVersion: "1.5.0"},
+	}
+
+	plan, err := newOperationPlan(params)
+	c.Assert(err, check.IsNil)
+	graph, err := fsm.NewPhaseGraph(*plan)
+	c.Assert(err, check.IsNil)
+
+	changesetID := func(phase storage.OperationPhase) string {
+		if phase.Data.Update == nil {
+			return ""
+		}
+		return phase.Data.Update.ChangesetID
+	}
+	var restores, changesets []string
+	backups := make(map[string][]string)
+	phases := graph.Order()
+	for i := len(phases) - 1; i >= 0; i-- {
+		switch phases[i].Executor {
+		case updateEtcdRestore:
+			restores = append(restores, phases[i].ID)
+			changesets = append(changesets, changesetID(phases[i]))
+		case updateEtcdBackup:
+			backups[changesetID(phases[i])] = append(backups[changesetID(phases[i])], phases[i].ID)
+		}
+	}
+	c.Assert(restores, check.DeepEquals, []string{
+		"/etcd/restore",
+		"/intermediate-1.5.0/etcd/restore",
+		"/intermediate-1.3.0/etcd/restore",
+	})
+	c.Assert(changesets, check.DeepEquals, []string{"", "123-1.5.0", "123-1.3.0"})
+	c.Assert(backups, check.DeepEquals, map[string][]string{
+		"":          {"/etcd/backup/node-2", "/etcd/backup/node-1"},
+		"123-1.5.0": {"/intermediate-1.5.0/etcd/backup/node-2", "/intermediate-1.5.0/etcd/backup/node-1"},
+		"123-1.3.0": {"/intermediate-1.3.0/etcd/backup/node-2", "/intermediate-1.3.0/etcd/backup/node-1"},
+	})
+}
+
+func (s *PlanSuite) TestChecksUpgradePathVersions(c *check.C) {
+	c.Assert(skipsMinorVersion(*semver.New("1.13.5"), *semver.New("1.14.1")), check.Equals, false)
+	c.Assert(skipsMinorVersion(*semver.New("1.13.5"), *semver.New("1.13.7")), check.Equals, false)
+	c.Assert(skipsMinorVersion(*semver.New("1.13.5"), *semver.New("1.15.0")), check.Equals, true)
+	c.Assert(skipsMinorVersion(*semver.New("1.15.0"), *semver.New("2.0.0")), check.Equals, true)
+	c.Assert(etcdNeedsUpdate("", "3.3.3"), check.Equals, true)
+	c.Assert(etcdNeedsUpdate("3.3.3", ""), check.Equals, false)
+	c.Assert(etcdNeedsUpdate("3.3.2", "3.3.3"), check.Equals, true)
+	c.Assert(etcdNeedsUpdate("3.3.3", "3.3.3"), check.Equals, false)
+}
+
+func (s *PlanSuite) TestNodesWithUpdateStrategy(c *check.C) {
+	runtimeLoc := loc.MustParseLocator("gravitational.io/planet:2.0.0")
+	appLoc := loc.MustParseLocator("gravitational.io/app:1.0.0")
+	var servers runtimeServers
+	for i := 1; i <= 4; i++ {
+		servers = append(servers, runtimeServer{storage.Server{
+			AdvertiseIP: fmt.Sprintf("192.168.0.%v", i),
+			Hostname:    fmt.Sprintf("node-%v", i),
+		}, runtimeLoc})
+	}
+	leadMaster := storage.Server{AdvertiseIP: "192.168.0.10", Hostname: "master"}
+
+	builder := phaseBuilder{}
+	nodes := builder.nodes(leadMaster, appLoc, servers, false, &storage.UpdateStrategy{
+		BatchSize:      3,
+		MaxUnavailable: 2,
+		Canary:         true,
+	})
+	c.Assert(nodes.Phases, check.HasLen, 3)
+
+	canary := nodes.Phases[0]
+	c.Assert(canary.ID, check.Equals, "/nodes/canary")
+	c.Assert(canary.Phases, check.HasLen, 3)
+	c.Assert(canary.Phases[0].ID, check.Equals, "/nodes/canary/node-1")
+	c.Assert(canary.Phases[1].Executor, check.Equals, canaryVerify)
+	c.Assert(canary.Phases[1].Data.Package, check.DeepEquals, &appLoc)
+	c.Assert(canary.Phases[2].Executor, check.Equals, canaryApprove)
+	c.Assert(canary.Phases[2].Data, check.IsNil)
+
+	first := nodes.Phases[1]
+	c.Assert(first.ID, check.Equals, "/nodes/batch-1")
+	c.Assert(first.Parallel, check.Equals, true)
+	c.Assert(first.Requires, check.DeepEquals, []string{"/nodes/canary"})
+	c.Assert(first.Phases, check.HasLen, 2)
+	c.Assert(first.Phases[0].ID, check.Equals, "/nodes/batch-1/node-2")
+	c.Assert(first.Phases[1].ID, check.Equals, "/nodes/batch-1/node-3")
+
+	second := nodes.Phases[2]
+	c.Assert(second.ID, check.Equals, "/nodes/batch-2")
+	c.Assert(second.Requires, check.DeepEquals, []string{"/nodes/batch-1"})
+	c.Assert(second.Phases, check.HasLen, 1)
+	c.Assert(second.Phases[0].ID, check.Equals, "/nodes/batch-2/node-4")
+}
+
 func (s *PlanSuite) TestUpdatesEtcdFromManifestWithoutLabels(c *check.C) {
 	services := opsservice.SetupTestServices(c)
 	files := []*archive.Item{
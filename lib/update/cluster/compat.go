@@ -0,0 +1,551 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/gravitational/gravity/lib/app"
+	"github.com/gravitational/gravity/lib/loc"
+	"github.com/gravitational/gravity/lib/pack"
+	"github.com/gravitational/gravity/lib/schema"
+	"github.com/gravitational/gravity/lib/storage"
+
+	"github.com/coreos/go-semver/semver"
+	"github.com/dustin/go-humanize"
+	"github.com/gravitational/trace"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/kubernetes"
+)
+
+// CompatibilityConfig describes the installed and the target cluster images
+// to compare
+type CompatibilityConfig struct {
+	// InstalledPackage specifies the installed cluster application package
+	InstalledPackage loc.Locator
+	// UpdatePackage specifies the cluster application package to update to
+	UpdatePackage loc.Locator
+	// Apps is the cluster application service
+	Apps app.Applications
+	// Packages is the cluster package service
+	Packages pack.PackageService
+	// Servers lists the cluster servers
+	Servers []storage.Server
+	// Docker specifies the current cluster docker configuration
+	Docker storage.DockerConfig
+	// Client is the optional Kubernetes client used to inspect live objects.
+	// If unspecified, the removed API versions are not checked
+	Client kubernetes.Interface
+	// FreeDiskSpace optionally returns the free disk space (in bytes)
+	// available in the state directory of the specified server.
+	// If unspecified, the disk space is not checked
+	FreeDiskSpace func(context.Context, storage.Server) (uint64, error)
+}
+
+func (r *CompatibilityConfig) checkAndSetDefaults() error {
+	if r.InstalledPackage.IsEmpty() {
+		return trace.BadParameter("installed application package is required")
+	}
+	if r.UpdatePackage.IsEmpty() {
+		return trace.BadParameter("update application package is required")
+	}
+	if r.Apps == nil {
+		return trace.BadParameter("application service is required")
+	}
+	if r.Packages == nil {
+		return trace.BadParameter("package service is required")
+	}
+	return nil
+}
+
+// CompatibilitySeverity defines the severity of a compatibility finding
+type CompatibilitySeverity string
+
+const (
+	// SeverityBlocker marks a finding that prevents the upgrade
+	SeverityBlocker CompatibilitySeverity = "blocker"
+	// SeverityWarning marks a finding that requires attention but
+	// does not prevent the upgrade
+	SeverityWarning CompatibilitySeverity = "warning"
+)
+
+// CompatibilityFinding describes a single result of the compatibility check
+type CompatibilityFinding struct {
+	// Severity specifies the severity of the finding
+	Severity CompatibilitySeverity `json:"severity"`
+	// Check names the check that produced the finding
+	Check string `json:"check"`
+	// Message is the human-readable description of the finding
+	Message string `json:"message"`
+}
+
+// String formats this finding as text
+func (r CompatibilityFinding) String() string {
+	return fmt.Sprintf("[%v] %v: %v", r.Severity, r.Check, r.Message)
+}
+
+// CompatibilityReport is the result of comparing the installed cluster
+// image with the target cluster image
+type CompatibilityReport struct {
+	// InstalledPackage specifies the installed cluster application package
+	InstalledPackage loc.Locator `json:"installed_package"`
+	// UpdatePackage specifies the cluster application package to update to
+	UpdatePackage loc.Locator `json:"update_package"`
+	// Findings lists all blockers and warnings
+	Findings []CompatibilityFinding `json:"findings,omitempty"`
+}
+
+// Blockers returns the findings that prevent the upgrade
+func (r CompatibilityReport) Blockers() []CompatibilityFinding {
+	return r.withSeverity(SeverityBlocker)
+}
+
+// Warnings returns the findings that do not prevent the upgrade
+func (r CompatibilityReport) Warnings() []CompatibilityFinding {
+	return r.withSeverity(SeverityWarning)
+}
+
+func (r CompatibilityReport) withSeverity(severity CompatibilitySeverity) (result []CompatibilityFinding) {
+	for _, finding := range r.Findings {
+		if finding.Severity == severity {
+			result = append(result, finding)
+		}
+	}
+	return result
+}
+
+// CheckCompatibility compares the installed cluster image with the update image
+// and returns the report of blockers and warnings found.
+// It does not modify the cluster state
+func CheckCompatibility(ctx context.Context, config CompatibilityConfig) (*CompatibilityReport, error) {
+	if err := config.checkAndSetDefaults(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	installedApp, installedRuntime, err := getAppWithRuntime(config.Apps, config.InstalledPackage)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	updateApp, updateRuntime, err := getAppWithRuntime(config.Apps, config.UpdatePackage)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	report := CompatibilityReport{
+		InstalledPackage: config.InstalledPackage,
+		UpdatePackage:    config.UpdatePackage,
+	}
+	report.Findings = append(report.Findings,
+		checkRuntimeVersion(installedRuntime.Package, updateRuntime.Package,
+			len(updateApp.Manifest.IntermediateRuntimePackages()) != 0)...)
+	report.Findings = append(report.Findings,
+		checkDeprecatedManifestFields(updateApp.Manifest)...)
+	report.Findings = append(report.Findings,
+		checkNodeProfiles(installedApp.Manifest, updateApp.Manifest, config.Servers)...)
+	report.Findings = append(report.Findings,
+		checkDockerStorageDriver(config.Docker, updateApp.Manifest)...)
+	if config.FreeDiskSpace != nil {
+		required, err := newPackagesSize(config.Packages,
+			[]schema.Manifest{installedApp.Manifest, installedRuntime.Manifest},
+			[]schema.Manifest{updateApp.Manifest, updateRuntime.Manifest})
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		report.Findings = append(report.Findings,
+			checkDiskSpace(ctx, required, config.Servers, config.FreeDiskSpace)...)
+	}
+	if config.Client != nil {
+		version, err := kubernetesVersion(*updateRuntime, config.Packages)
+		if err != nil && !trace.IsNotFound(err) {
+			return nil, trace.Wrap(err)
+		}
+		if err != nil {
+			report.Findings = append(report.Findings, CompatibilityFinding{
+				Severity: SeverityWarning,
+				Check:    checkRemovedAPIsName,
+				Message:  "failed to determine the Kubernetes version of the update, skipping the check",
+			})
+		} else {
+			findings, err := checkRemovedAPIs(*version, restObjectLister(config.Client))
+			if err != nil {
+				return nil, trace.Wrap(err)
+			}
+			report.Findings = append(report.Findings, findings...)
+		}
+	}
+	return &report, nil
+}
+
+func getAppWithRuntime(apps app.Applications, locator loc.Locator) (application, runtime *app.Application, err error) {
+	application, err = apps.GetApp(locator)
+	if err != nil {
+		return nil, nil, trace.Wrap(err)
+	}
+	base := application.Manifest.Base()
+	if base == nil {
+		return nil, nil, trace.BadParameter("application %v does not have a runtime", locator)
+	}
+	runtime, err = apps.GetApp(*base)
+	if err != nil {
+		return nil, nil, trace.Wrap(err)
+	}
+	return application, runtime, nil
+}
+
+// checkRuntimeVersion flags runtime downgrades and upgrades
+// that skip intermediate releases unless the update bundles intermediate runtimes
+func checkRuntimeVersion(installed, update loc.Locator, hasIntermediateRuntimes bool) []CompatibilityFinding {
+	installedVersion, err := installed.SemVer()
+	if err != nil {
+		return []CompatibilityFinding{runtimeVersionFinding(SeverityWarning,
+			"failed to parse installed runtime version %q: %v", installed.Version, err)}
+	}
+	updateVersion, err := update.SemVer()
+	if err != nil {
+		return []CompatibilityFinding{runtimeVersionFinding(SeverityWarning,
+			"failed to parse update runtime version %q: %v", update.Version, err)}
+	}
+	switch {
+	case updateVersion.LessThan(*installedVersion):
+		return []CompatibilityFinding{runtimeVersionFinding(SeverityBlocker,
+			"runtime cannot be downgraded from %v to %v", installedVersion, updateVersion)}
+	case updateVersion.Major != installedVersion.Major:
+		return []CompatibilityFinding{runtimeVersionFinding(SeverityWarning,
+			"runtime is updated across major versions from %v to %v, "+
+				"make sure the upgrade path is supported", installedVersion, updateVersion)}
+	case updateVersion.Minor > installedVersion.Minor+1 && !hasIntermediateRuntimes:
+		return []CompatibilityFinding{runtimeVersionFinding(SeverityWarning,
+			"runtime update from %v to %v skips intermediate releases, "+
+				"make sure the upgrade path is supported", installedVersion, updateVersion)}
+	}
+	return nil
+}
+
+func runtimeVersionFinding(severity CompatibilitySeverity, format string, args ...interface{}) CompatibilityFinding {
+	return CompatibilityFinding{
+		Severity: severity,
+		Check:    "runtime-version",
+		Message:  fmt.Sprintf(format, args...),
+	}
+}
+
+// checkDeprecatedManifestFields flags deprecated fields in the update manifest
+func checkDeprecatedManifestFields(manifest schema.Manifest) (findings []CompatibilityFinding) {
+	warn := func(format string, args ...interface{}) {
+		findings = append(findings, CompatibilityFinding{
+			Severity: SeverityWarning,
+			Check:    "deprecated-manifest-fields",
+			Message:  fmt.Sprintf(format, args...),
+		})
+	}
+	if manifest.Kind == schema.KindBundle {
+		warn("manifest kind %q is deprecated, use %q", schema.KindBundle, schema.KindCluster)
+	}
+	if manifest.APIVersion == schema.APIVersionLegacyV2 {
+		warn("manifest API version %q is deprecated, use %q",
+			manifest.APIVersion, schema.APIVersionV2Cluster)
+	}
+	for _, dependency := range manifest.AllPackageDependencies() {
+		if loc.IsLegacyRuntimePackage(dependency) {
+			warn("legacy runtime package %v is deprecated, use the runtime "+
+				"package from systemOptions.dependencies", dependency)
+		}
+	}
+	return findings
+}
+
+// checkNodeProfiles compares the node profiles used by the cluster servers
+// between the installed and the update manifests
+func checkNodeProfiles(installed, update schema.Manifest, servers []storage.Server) (findings []CompatibilityFinding) {
+	add := func(severity CompatibilitySeverity, format string, args ...interface{}) {
+		findings = append(findings, CompatibilityFinding{
+			Severity: severity,
+			Check:    "node-profiles",
+			Message:  fmt.Sprintf(format, args...),
+		})
+	}
+	for _, profile := range usedProfiles(servers) {
+		hosts := strings.Join(profile.hostnames, ", ")
+		updateProfile, err := update.NodeProfiles.ByName(profile.name)
+		if err != nil {
+			add(SeverityBlocker, "profile %q used by nodes %v is removed", profile.name, hosts)
+			continue
+		}
+		installedProfile, err := installed.NodeProfiles.ByName(profile.name)
+		if err != nil {
+			continue
+		}
+		if installedProfile.ServiceRole != updateProfile.ServiceRole {
+			add(SeverityBlocker, "service role of profile %q used by nodes %v changes from %q to %q",
+				profile.name, hosts, installedProfile.ServiceRole, updateProfile.ServiceRole)
+		}
+		installedReqs, updateReqs := installedProfile.Requirements, updateProfile.Requirements
+		if updateReqs.CPU.Min > installedReqs.CPU.Min {
+			add(SeverityWarning, "profile %q requires at least %v CPUs (was %v), check nodes %v",
+				profile.name, updateReqs.CPU.Min, installedReqs.CPU.Min, hosts)
+		}
+		if updateReqs.RAM.Min > installedReqs.RAM.Min {
+			add(SeverityWarning, "profile %q requires at least %v of RAM (was %v), check nodes %v",
+				profile.name, updateReqs.RAM.Min, installedReqs.RAM.Min, hosts)
+		}
+		for _, volume := range updateReqs.Volumes {
+			if !hasVolume(installedReqs.Volumes, volume.Path) {
+				add(SeverityWarning, "profile %q requires new volume %v, check nodes %v",
+					profile.name, volume.Path, hosts)
+			}
+		}
+		for _, os := range installedReqs.OS {
+			if len(updateReqs.OS) != 0 && !hasOS(updateReqs.OS, os.Name) {
+				add(SeverityWarning, "profile %q no longer supports OS %q, check nodes %v",
+					profile.name, os.Name, hosts)
+			}
+		}
+	}
+	return findings
+}
+
+type profileUsage struct {
+	name      string
+	hostnames []string
+}
+
+func usedProfiles(servers []storage.Server) (result []profileUsage) {
+	usage := make(map[string][]string)
+	for _, server := range servers {
+		usage[server.Role] = append(usage[server.Role], server.Hostname)
+	}
+	for name, hostnames := range usage {
+		result = append(result, profileUsage{name: name, hostnames: hostnames})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].name < result[j].name
+	})
+	return result
+}
+
+func hasVolume(volumes []schema.Volume, path string) bool {
+	for _, volume := range volumes {
+		if volume.Path == path {
+			return true
+		}
+	}
+	return false
+}
+
+func hasOS(distributions []schema.OS, name string) bool {
+	for _, os := range distributions {
+		if os.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// checkDockerStorageDriver flags the change of the docker storage driver
+func checkDockerStorageDriver(docker storage.DockerConfig, update schema.Manifest) []CompatibilityFinding {
+	driver := update.SystemDocker().StorageDriver
+	if driver == "" || docker.StorageDriver == "" || driver == docker.StorageDriver {
+		return nil
+	}
+	return []CompatibilityFinding{{
+		Severity: SeverityWarning,
+		Check:    "docker-storage-driver",
+		Message: fmt.Sprintf("docker storage driver changes from %v to %v, "+
+			"existing images and containers will be recreated on every node",
+			docker.StorageDriver, driver),
+	}}
+}
+
+// newPackagesSize estimates the disk space required on a node for the packages
+// of the update that are not installed.
+// The size of each package is doubled to account for its unpacked contents
+func newPackagesSize(packages pack.PackageService, installed, update []schema.Manifest) (uint64, error) {
+	existing := make(map[string]struct{})
+	for _, manifest := range installed {
+		for _, dependency := range manifest.AllPackageDependencies() {
+			existing[dependency.String()] = struct{}{}
+		}
+	}
+	var deps []loc.Locator
+	for _, manifest := range update {
+		deps = append(deps, manifest.AllPackageDependencies()...)
+	}
+	var size uint64
+	for _, dependency := range loc.Deduplicate(deps) {
+		if _, ok := existing[dependency.String()]; ok {
+			continue
+		}
+		envelope, err := packages.ReadPackageEnvelope(dependency)
+		if err != nil {
+			return 0, trace.Wrap(err)
+		}
+		size += 2 * uint64(envelope.SizeBytes)
+	}
+	return size, nil
+}
+
+// checkDiskSpace verifies that every server has the specified amount
+// of disk space available
+func checkDiskSpace(ctx context.Context, required uint64, servers []storage.Server,
+	freeDiskSpace func(context.Context, storage.Server) (uint64, error)) (findings []CompatibilityFinding) {
+	for _, server := range servers {
+		available, err := freeDiskSpace(ctx, server)
+		if err != nil {
+			findings = append(findings, CompatibilityFinding{
+				Severity: SeverityWarning,
+				Check:    "disk-space",
+				Message: fmt.Sprintf("failed to determine free disk space on node %v: %v",
+					server.Hostname, trace.UserMessage(err)),
+			})
+			continue
+		}
+		if available < required {
+			findings = append(findings, CompatibilityFinding{
+				Severity: SeverityBlocker,
+				Check:    "disk-space",
+				Message: fmt.Sprintf("node %v has %v available in %v but the update requires %v",
+					server.Hostname, humanize.Bytes(available), server.StateDir(),
+					humanize.Bytes(required)),
+			})
+		}
+	}
+	return findings
+}
+
+// kubernetesVersion returns the version of Kubernetes shipped with
+// the specified runtime application
+func kubernetesVersion(runtime app.Application, packages pack.PackageService) (*semver.Version, error) {
+	runtimePackage, err := runtime.Manifest.DefaultRuntimePackage()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return getVersionLabel(kubernetesVersionLabel, *runtimePackage, packages)
+}
+
+// removedAPI describes a Kubernetes API group version that is no longer
+// served for the specified resource starting with a given Kubernetes version
+type removedAPI struct {
+	// groupVersion is the removed API group version
+	groupVersion string
+	// resource is the resource name
+	resource string
+	// replacement is the API group version to use instead
+	replacement string
+	// removedIn is the Kubernetes version the API is no longer served in
+	removedIn semver.Version
+}
+
+var removedAPIs = []removedAPI{
+	{"extensions/v1beta1", "deployments", "apps/v1", *semver.New("1.16.0")},
+	{"extensions/v1beta1", "daemonsets", "apps/v1", *semver.New("1.16.0")},
+	{"extensions/v1beta1", "replicasets", "apps/v1", *semver.New("1.16.0")},
+	{"extensions/v1beta1", "networkpolicies", "networking.k8s.io/v1", *semver.New("1.16.0")},
+	{"extensions/v1beta1", "podsecuritypolicies", "policy/v1beta1", *semver.New("1.16.0")},
+	{"apps/v1beta1", "deployments", "apps/v1", *semver.New("1.16.0")},
+	{"apps/v1beta1", "statefulsets", "apps/v1", *semver.New("1.16.0")},
+	{"apps/v1beta2", "deployments", "apps/v1", *semver.New("1.16.0")},
+	{"apps/v1beta2", "statefulsets", "apps/v1", *semver.New("1.16.0")},
+	{"apps/v1beta2", "daemonsets", "apps/v1", *semver.New("1.16.0")},
+	{"apps/v1beta2", "replicasets", "apps/v1", *semver.New("1.16.0")},
+	{"extensions/v1beta1", "ingresses", "networking.k8s.io/v1", *semver.New("1.22.0")},
+	{"networking.k8s.io/v1beta1", "ingresses", "networking.k8s.io/v1", *semver.New("1.22.0")},
+	{"apiextensions.k8s.io/v1beta1", "customresourcedefinitions", "apiextensions.k8s.io/v1", *semver.New("1.22.0")},
+	{"rbac.authorization.k8s.io/v1beta1", "roles", "rbac.authorization.k8s.io/v1", *semver.New("1.22.0")},
+	{"rbac.authorization.k8s.io/v1beta1", "rolebindings", "rbac.authorization.k8s.io/v1", *semver.New("1.22.0")},
+	{"rbac.authorization.k8s.io/v1beta1", "clusterroles", "rbac.authorization.k8s.io/v1", *semver.New("1.22.0")},
+	{"rbac.authorization.k8s.io/v1beta1", "clusterrolebindings", "rbac.authorization.k8s.io/v1", *semver.New("1.22.0")},
+}
+
+// liveObject describes a Kubernetes object as returned by the API server
+type liveObject struct {
+	Metadata struct {
+		Name      string `json:"name"`
+		Namespace string `json:"namespace"`
+	} `json:"metadata"`
+}
+
+func (r liveObject) String() string {
+	if r.Metadata.Namespace == "" {
+		return r.Metadata.Name
+	}
+	return fmt.Sprintf("%v/%v", r.Metadata.Namespace, r.Metadata.Name)
+}
+
+// objectLister returns the objects of the specified resource
+// using the given API group version.
+// Returns a NotFound error if the API is not served
+type objectLister func(groupVersion, resource string) ([]liveObject, error)
+
+// checkRemovedAPIs flags live objects served by the API group versions
+// removed in the specified Kubernetes version.
+//
+// Objects are queried through each removed group version rather than
+// inferred from the kubectl last-applied annotation which is absent
+// from objects created by other clients
+func checkRemovedAPIs(version semver.Version, list objectLister) (findings []CompatibilityFinding, err error) {
+	for _, api := range removedAPIs {
+		if version.LessThan(api.removedIn) {
+			continue
+		}
+		objects, err := list(api.groupVersion, api.resource)
+		if err != nil && !trace.IsNotFound(err) {
+			return nil, trace.Wrap(err)
+		}
+		if len(objects) == 0 {
+			continue
+		}
+		names := make([]string, 0, len(objects))
+		for _, object := range objects {
+			names = append(names, object.String())
+		}
+		findings = append(findings, CompatibilityFinding{
+			Severity: SeverityBlocker,
+			Check:    checkRemovedAPIsName,
+			Message: fmt.Sprintf("%v %v are served by %v which is removed in Kubernetes %v, "+
+				"make sure their manifests and clients use %v",
+				api.resource, strings.Join(names, ", "), api.groupVersion, version, api.replacement),
+		})
+	}
+	return findings, nil
+}
+
+// restObjectLister returns an objectLister that queries the API server
+func restObjectLister(client kubernetes.Interface) objectLister {
+	return func(groupVersion, resource string) ([]liveObject, error) {
+		data, err := client.Discovery().RESTClient().Get().
+			AbsPath("/apis", groupVersion, resource).
+			Do().Raw()
+		if err != nil {
+			if errors.IsNotFound(err) {
+				return nil, trace.NotFound("%v is not served", groupVersion)
+			}
+			return nil, trace.Wrap(err)
+		}
+		var list struct {
+			Items []liveObject `json:"items"`
+		}
+		if err := json.Unmarshal(data, &list); err != nil {
+			return nil, trace.Wrap(err)
+		}
+		return list.Items, nil
+	}
+}
+
+// checkRemovedAPIsName names the check for removed Kubernetes APIs
+const checkRemovedAPIsName = "removed-kubernetes-apis"
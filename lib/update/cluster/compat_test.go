@@ -0,0 +1,188 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"context"
+
+	"github.com/gravitational/gravity/lib/loc"
+	"github.com/gravitational/gravity/lib/schema"
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/utils"
+
+	"github.com/coreos/go-semver/semver"
+	"github.com/dustin/go-humanize"
+	"github.com/gravitational/trace"
+	"gopkg.in/check.v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type CompatSuite struct{}
+
+var _ = check.Suite(&CompatSuite{})
+
+func (s *CompatSuite) TestRuntimeVersion(c *check.C) {
+	var testCases = []struct {
+		installed, update string
+		intermediate      bool
+		severity          CompatibilitySeverity
+		comment           string
+	}{
+		{"5.5.0", "5.5.1", false, "", "patch update"},
+		{"5.4.0", "5.5.0", false, "", "minor update"},
+		{"5.2.0", "5.5.0", false, SeverityWarning, "skips releases"},
+		{"5.2.0", "5.5.0", true, "", "skips releases with intermediate runtimes"},
+		{"5.5.0", "6.0.0", false, SeverityWarning, "major update"},
+		{"5.5.1", "5.5.0", false, SeverityBlocker, "downgrade"},
+	}
+	for _, tc := range testCases {
+		findings := checkRuntimeVersion(
+			loc.MustParseLocator("gravitational.io/kubernetes:"+tc.installed),
+			loc.MustParseLocator("gravitational.io/kubernetes:"+tc.update),
+			tc.intermediate)
+		if tc.severity == "" {
+			c.Assert(findings, check.HasLen, 0, check.Commentf(tc.comment))
+			continue
+		}
+		c.Assert(findings, check.HasLen, 1, check.Commentf(tc.comment))
+		c.Assert(findings[0].Severity, check.Equals, tc.severity, check.Commentf(tc.comment))
+	}
+}
+
+func (s *CompatSuite) TestDeprecatedManifestFields(c *check.C) {
+	manifest := schema.Manifest{
+		Header: schema.Header{TypeMeta: metav1.TypeMeta{Kind: schema.KindBundle, APIVersion: schema.APIVersionLegacyV2}},
+	}
+	c.Assert(checkDeprecatedManifestFields(manifest), check.HasLen, 2)
+
+	manifest.Header.TypeMeta = metav1.TypeMeta{Kind: schema.KindCluster, APIVersion: schema.APIVersionV2Cluster}
+	c.Assert(checkDeprecatedManifestFields(manifest), check.HasLen, 0)
+}
+
+func (s *CompatSuite) TestNodeProfiles(c *check.C) {
+	installed := schema.Manifest{
+		NodeProfiles: schema.NodeProfiles{
+			{
+				Name:        "master",
+				ServiceRole: schema.ServiceRoleMaster,
+				Requirements: schema.Requirements{
+					CPU: schema.CPU{Min: 2},
+					RAM: schema.RAM{Min: utils.Capacity(2 * humanize.GiByte)},
+					OS:  []schema.OS{{Name: "centos"}, {Name: "ubuntu"}},
+				},
+			},
+			{Name: "worker"},
+		},
+	}
+	update := schema.Manifest{
+		NodeProfiles: schema.NodeProfiles{
+			{
+				Name:        "master",
+				ServiceRole: schema.ServiceRoleMaster,
+				Requirements: schema.Requirements{
+					CPU:     schema.CPU{Min: 4},
+					RAM:     schema.RAM{Min: utils.Capacity(2 * humanize.GiByte)},
+					OS:      []schema.OS{{Name: "centos"}},
+					Volumes: []schema.Volume{{Path: "/var/lib/data"}},
+				},
+			},
+		},
+	}
+	servers := []storage.Server{
+		{Hostname: "node-1", Role: "master"},
+		{Hostname: "node-2", Role: "worker"},
+	}
+	findings := checkNodeProfiles(installed, update, servers)
+	c.Assert(findings, check.HasLen, 4)
+	c.Assert(severities(findings), check.DeepEquals, []CompatibilitySeverity{
+		SeverityWarning, // cpu
+		SeverityWarning, // volume
+		SeverityWarning, // os
+		SeverityBlocker, // removed worker profile
+	})
+}
+
+func (s *CompatSuite) TestDockerStorageDriver(c *check.C) {
+	update := schema.Manifest{
+		SystemOptions: &schema.SystemOptions{
+			Docker: &schema.Docker{StorageDriver: "overlay2"},
+		},
+	}
+	c.Assert(checkDockerStorageDriver(storage.DockerConfig{StorageDriver: "overlay2"}, update), check.HasLen, 0)
+	c.Assert(checkDockerStorageDriver(storage.DockerConfig{StorageDriver: "devicemapper"}, update), check.HasLen, 1)
+}
+
+func (s *CompatSuite) TestDiskSpace(c *check.C) {
+	servers := []storage.Server{
+		{Hostname: "node-1"},
+		{Hostname: "node-2"},
+		{Hostname: "node-3"},
+	}
+	freeDiskSpace := func(ctx context.Context, server storage.Server) (uint64, error) {
+		switch server.Hostname {
+		case "node-1":
+			return 2000, nil
+		case "node-2":
+			return 500, nil
+		default:
+			return 0, trace.ConnectionProblem(nil, "node is unreachable")
+		}
+	}
+	findings := checkDiskSpace(context.TODO(), 1000, servers, freeDiskSpace)
+	c.Assert(severities(findings), check.DeepEquals, []CompatibilitySeverity{
+		SeverityBlocker, // node-2
+		SeverityWarning, // node-3
+	})
+}
+
+func (s *CompatSuite) TestRemovedAPIs(c *check.C) {
+	list := func(groupVersion, resource string) ([]liveObject, error) {
+		if groupVersion == "extensions/v1beta1" && resource == "deployments" {
+			return []liveObject{
+				newLiveObject("default", "app"),
+				newLiveObject("kube-system", "unmanaged"),
+			}, nil
+		}
+		if groupVersion == "apps/v1beta2" && resource == "deployments" {
+			return nil, nil
+		}
+		return nil, trace.NotFound("%v is not served", groupVersion)
+	}
+	findings, err := checkRemovedAPIs(*semver.New("1.15.0"), list)
+	c.Assert(err, check.IsNil)
+	c.Assert(findings, check.HasLen, 0)
+
+	findings, err = checkRemovedAPIs(*semver.New("1.16.0"), list)
+	c.Assert(err, check.IsNil)
+	c.Assert(findings, check.HasLen, 1)
+	c.Assert(findings[0].Severity, check.Equals, SeverityBlocker)
+	c.Assert(findings[0].Message, check.Matches,
+		"deployments default/app, kube-system/unmanaged are served by extensions/v1beta1.*")
+}
+
+func newLiveObject(namespace, name string) (object liveObject) {
+	object.Metadata.Namespace = namespace
+	object.Metadata.Name = name
+	return object
+}
+
+func severities(findings []CompatibilityFinding) (result []CompatibilitySeverity) {
+	for _, finding := range findings {
+		result = append(result, finding.Severity)
+	}
+	return result
+}
@@ -40,6 +40,12 @@ import (
 
 // New returns new updater for the specified configuration
 func New(ctx context.Context, config Config) (*update.Updater, error) {
+	if config.Operation != nil && config.Operation.Update != nil {
+		config.AutoRollback = config.Operation.Update.AutoRollback
+		if config.Parallelism == 0 {
+			config.Parallelism = config.Operation.Update.Parallelism
+		}
+	}
 	machine, err := newMachine(ctx, config)
 	if err != nil {
 		return nil, trace.Wrap(err)
@@ -51,6 +57,48 @@ func New(ctx context.Context, config Config) (*update.Updater, error) {
 	return updater, nil
 }
 
+// ApproveCanary approves the update of the remaining nodes once the canary
+// node has been updated and verified and moves the operation awaiting
+// the approval back in progress.
+// The operation continues after it has been resumed
+func ApproveCanary(ctx context.Context, updater *update.Updater) error {
+	plan, err := updater.GetPlan()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	var approve, verify *storage.OperationPhase
+	for _, phase := range fsm.FlattenPlan(plan) {
+		switch phase.Executor {
+		case canaryApprove:
+			approve = phase
+		case canaryVerify:
+			verify = phase
+		}
+	}
+	if approve == nil || verify == nil {
+		return trace.NotFound("operation does not use a canary update")
+	}
+	if !approve.IsCompleted() {
+		if !verify.IsCompleted() {
+			return trace.CompareFailed("canary node has not been verified yet")
+		}
+		err = updater.ChangePhaseState(ctx, fsm.StateChange{
+			Phase: approve.ID,
+			State: storage.OperationPhaseStateCompleted,
+		})
+		if err != nil {
+			return trace.Wrap(err)
+		}
+	}
+	if updater.Operation.State != ops.OperationStateUpdateAwaitingApproval {
+		return nil
+	}
+	stateSetter := fsm.OperationStateSetter(updater.Operation.Key(), updater.Operator, updater.LocalBackend)
+	return trace.Wrap(stateSetter.SetOperationState(updater.Operation.Key(), ops.SetOperationStateRequest{
+		State: ops.OperationStateUpdateInProgress,
+	}))
+}
+
 // checkAndSetDefaults validates FSM config and sets defaults
 func (c *Config) checkAndSetDefaults() error {
 	if err := c.Config.CheckAndSetDefaults(); err != nil {
@@ -100,9 +148,10 @@ func newMachine(ctx context.Context, c Config) (*fsm.FSM, error) {
 		return nil, trace.Wrap(err)
 	}
 	fsm, err := fsm.New(fsm.Config{
-		Engine: engine,
-		Logger: logger,
-		Runner: c.Runner,
+		Engine:      engine,
+		Logger:      logger,
+		Runner:      c.Runner,
+		Parallelism: c.Parallelism,
 	})
 	if err != nil {
 		return nil, trace.Wrap(err)
@@ -159,7 +208,11 @@ func (f *engine) GetExecutor(p fsm.ExecutorParams, remote fsm.Remote) (fsm.Phase
 // RunCommand executes the phase specified by params on the specified server
 // using the provided runner
 func (f *engine) RunCommand(ctx context.Context, runner fsm.RemoteRunner, server storage.Server, p fsm.Params) error {
-	args := []string{"plan", "execute",
+	command := "execute"
+	if p.Rollback {
+		command = "rollback"
+	}
+	args := []string{"plan", command,
 		"--phase", p.PhaseID,
 		"--operation-id", f.plan.OperationID,
 	}
@@ -185,7 +238,8 @@ func (f *engine) PreRollback(ctx context.Context, p fsm.Params) error {
 }
 
 // Complete marks the provided update operation as completed or failed
-// and moves the cluster into active state
+// and moves the cluster into active state if the plan has either been
+// completed or rolled back
 func (f *engine) Complete(fsmErr error) error {
 	plan, err := f.GetPlan()
 	if err != nil {
@@ -200,6 +254,13 @@ func (f *engine) Complete(fsmErr error) error {
 
 	stateSetter := fsm.OperationStateSetter(opKey, f.Operator, f.LocalBackend)
 	completed := fsm.IsCompleted(plan)
+	if !completed && fsm.IsPaused(fsmErr) {
+		// The operation is resumed once the update has been approved
+		err = stateSetter.SetOperationState(opKey, ops.SetOperationStateRequest{
+			State: ops.OperationStateUpdateAwaitingApproval,
+		})
+		return trace.Wrap(err)
+	}
 	if completed {
 		err = ops.CompleteOperation(opKey, stateSetter)
 	} else {
@@ -209,7 +270,8 @@ func (f *engine) Complete(fsmErr error) error {
 		return trace.Wrap(err)
 	}
 
-	if !completed {
+	rolledBack := fsm.IsRolledBack(plan)
+	if !completed && !rolledBack {
 		return nil
 	}
 
@@ -218,6 +280,11 @@ func (f *engine) Complete(fsmErr error) error {
 		return trace.Wrap(err)
 	}
 
+	if rolledBack {
+		// The cluster is back to its state before the update
+		return trace.Wrap(f.activateCluster(*cluster))
+	}
+
 	err = f.commitClusterChanges(cluster, *op)
 	if err != nil {
 		return trace.Wrap(err)
@@ -22,9 +22,11 @@ import (
 	"testing"
 
 	"github.com/gravitational/gravity/lib/fsm"
+	"github.com/gravitational/gravity/lib/ops"
 	"github.com/gravitational/gravity/lib/ops/opsservice"
 	"github.com/gravitational/gravity/lib/storage"
 	"github.com/gravitational/gravity/lib/update"
+	libphase "github.com/gravitational/gravity/lib/update/cluster/phases"
 
 	"github.com/gravitational/trace"
 	"github.com/sirupsen/logrus"
@@ -167,6 +169,40 @@ func (s *FSMSuite) TestFSMExecutePhaseWithSubphases(c *check.C) {
 	})
 }
 
+func (s *FSMSuite) TestCanaryApprovalDoesNotTriggerRollback(c *check.C) {
+	plan := storage.OperationPlan{
+		OperationID:   operationID,
+		OperationType: "test_operation",
+		ClusterName:   clusterName,
+		Phases: []storage.OperationPhase{
+			{ID: "/phase1"},
+			{ID: "/canary", Executor: canaryApprove},
+			{ID: "/phase2"},
+		},
+	}
+	s.engine.plan = plan
+	s.engine.reconciler = &changelogReconciler{backend: s.engine.LocalBackend}
+
+	engine := &completeRecorder{engine: s.engine}
+	machine, err := fsm.New(fsm.Config{Engine: engine})
+	c.Assert(err, check.IsNil)
+	config := s.engine.Config.Config
+	config.Operation = &ops.SiteOperation{ID: operationID, SiteDomain: clusterName}
+	config.Backend = config.LocalBackend
+	config.AutoRollback = true
+	updater, err := update.NewUpdater(context.TODO(), config, machine)
+	c.Assert(err, check.IsNil)
+
+	err = updater.Run(context.TODO(), false)
+	c.Assert(fsm.IsPaused(err), check.Equals, true, check.Commentf("expected pause, got %v", err))
+	c.Assert(fsm.IsPaused(engine.completeErr), check.Equals, true)
+	checkStates(c, s.resolvePlan(c, plan), map[string]string{
+		"/phase1": storage.OperationPhaseStateCompleted,
+		"/canary": storage.OperationPhaseStateUnstarted,
+		"/phase2": storage.OperationPhaseStateUnstarted,
+	})
+}
+
 func (s *FSMSuite) resolvePlan(c *check.C, plan storage.OperationPlan) *storage.OperationPlan {
 	changelog, err := s.engine.LocalBackend.GetOperationPlanChangelog(plan.ClusterName, plan.OperationID)
 	c.Assert(err, check.IsNil)
@@ -183,6 +219,9 @@ func checkStates(c *check.C, plan *storage.OperationPlan, states map[string]stri
 
 func getTestExecutor() fsm.FSMSpecFunc {
 	return func(p fsm.ExecutorParams, remote fsm.Remote) (fsm.PhaseExecutor, error) {
+		if p.Phase.Executor == canaryApprove {
+			return libphase.NewPhaseCanaryApprove(p, logrus.NewEntry(logrus.New()))
+		}
 		if strings.HasPrefix(p.Phase.ID, "/phase1") {
 			return &testPhase1{
 				FieldLogger: logrus.NewEntry(logrus.New()),
@@ -231,8 +270,33 @@ func (p *testPhase2) Rollback(context.Context) error {
 	return nil
 }
 
+// completeRecorder is the update engine that records the error
+// the operation has been completed with
+type completeRecorder struct {
+	*engine
+	completeErr error
+}
+
+func (r *completeRecorder) Complete(fsmErr error) error {
+	r.completeErr = fsmErr
+	return nil
+}
+
 func (r *testReconciler) ReconcilePlan(ctx context.Context, plan storage.OperationPlan) (*storage.OperationPlan, error) {
 	return &plan, nil
 }
 
 type testReconciler struct{}
+
+// changelogReconciler resolves the plan using the changelog from the backend
+type changelogReconciler struct {
+	backend storage.Backend
+}
+
+func (r *changelogReconciler) ReconcilePlan(ctx context.Context, plan storage.OperationPlan) (*storage.OperationPlan, error) {
+	changelog, err := r.backend.GetOperationPlanChangelog(plan.ClusterName, plan.OperationID)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return fsm.ResolvePlan(plan, changelog), nil
+}
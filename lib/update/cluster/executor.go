@@ -77,6 +77,12 @@ const (
 	updateEtcdRestartGravity = "etcd_restart_gravity"
 	// cleanupNode is the phase to clean up a node after the upgrade
 	cleanupNode = "cleanup_node"
+	// canaryVerify is the phase that verifies the cluster after the canary node has been updated
+	canaryVerify = "canary_verify"
+	// canaryApprove is the phase that waits for approval to update the remaining nodes
+	canaryApprove = "canary_approve"
+	// verifyUpdate is the phase that verifies the cluster after the update
+	verifyUpdate = "verify_update"
 )
 
 // fsmSpec returns the function that returns an appropriate phase executor
@@ -144,7 +150,7 @@ func fsmSpec(c Config) fsm.FSMSpecFunc {
 		case migrateRoles:
 			return libphase.NewPhaseMigrateRoles(p.Plan, c.Backend, logger)
 		case updateEtcdBackup:
-			return libphase.NewPhaseUpgradeEtcdBackup(logger)
+			return libphase.NewPhaseUpgradeEtcdBackup(p.Phase, logger)
 		case updateEtcdShutdown:
 			return libphase.NewPhaseUpgradeEtcdShutdown(p.Phase, c.Client, logger)
 		case updateEtcdMaster:
@@ -157,6 +163,10 @@ func fsmSpec(c Config) fsm.FSMSpecFunc {
 			return libphase.NewPhaseUpgradeGravitySiteRestart(p.Phase, c.Client, logger)
 		case cleanupNode:
 			return libphase.NewGarbageCollectPhase(p, remote, logger)
+		case canaryVerify, verifyUpdate:
+			return libphase.NewPhaseVerify(p, c.Operator, c.Apps, c.Client, logger)
+		case canaryApprove:
+			return libphase.NewPhaseCanaryApprove(p, logger)
 		default:
 			return nil, trace.BadParameter(
 				"phase %q requires executor %q (potential mismatch between upgrade versions)",
@@ -0,0 +1,239 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/gravitational/gravity/lib/app"
+	"github.com/gravitational/gravity/lib/loc"
+	"github.com/gravitational/gravity/lib/pack"
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/update"
+
+	"github.com/coreos/go-semver/semver"
+	"github.com/gravitational/trace"
+)
+
+// intermediateRuntime describes a runtime package the cluster is updated
+// through on the way to the runtime package of the update
+type intermediateRuntime struct {
+	// runtime is the intermediate runtime package
+	runtime loc.Locator
+	// etcdVersion is the version of etcd shipped with the runtime package.
+	// Empty if unknown
+	etcdVersion string
+}
+
+// getIntermediateRuntimes returns the intermediate runtime packages bundled with
+// the update application that are newer than the installed runtime package,
+// ordered by version.
+// Returns an error if the resulting upgrade path skips a Kubernetes minor version
+func getIntermediateRuntimes(
+	installedRuntime, updateApp, updateRuntime app.Application,
+	packages pack.PackageService,
+) ([]intermediateRuntime, error) {
+	installedPackage, err := installedRuntimePackage(installedRuntime)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	updatePackage, err := updateRuntime.Manifest.DefaultRuntimePackage()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	installedVersion, err := installedPackage.SemVer()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	updateVersion, err := updatePackage.SemVer()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	type versionedPackage struct {
+		loc.Locator
+		version semver.Version
+	}
+	var intermediate []versionedPackage
+	for _, runtimePackage := range updateApp.Manifest.IntermediateRuntimePackages() {
+		version, err := runtimePackage.SemVer()
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		if installedVersion.LessThan(*version) && version.LessThan(*updateVersion) {
+			intermediate = append(intermediate, versionedPackage{Locator: runtimePackage, version: *version})
+		}
+	}
+	sort.Slice(intermediate, func(i, j int) bool {
+		return intermediate[i].version.LessThan(intermediate[j].version)
+	})
+	path := []loc.Locator{*installedPackage}
+	for _, runtimePackage := range intermediate {
+		path = append(path, runtimePackage.Locator)
+	}
+	path = append(path, *updatePackage)
+	if err := checkUpgradePath(path, packages); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	result := make([]intermediateRuntime, 0, len(intermediate))
+	for _, runtimePackage := range intermediate {
+		etcdVersion, err := getVersionLabel(etcdVersionLabel, runtimePackage.Locator, packages)
+		if err != nil && !trace.IsNotFound(err) {
+			return nil, trace.Wrap(err)
+		}
+		step := intermediateRuntime{runtime: runtimePackage.Locator}
+		if etcdVersion != nil {
+			step.etcdVersion = etcdVersion.String()
+		}
+		result = append(result, step)
+	}
+	return result, nil
+}
+
+// checkUpgradePath verifies that every consecutive pair of runtime packages
+// in the specified path is at most one Kubernetes minor version apart.
+// Runtime packages that do not specify the Kubernetes version are not checked
+func checkUpgradePath(path []loc.Locator, packages pack.PackageService) error {
+	var prevPackage loc.Locator
+	var prevVersion *semver.Version
+	for _, runtimePackage := range path {
+		version, err := getVersionLabel(kubernetesVersionLabel, runtimePackage, packages)
+		if err != nil && !trace.IsNotFound(err) {
+			return trace.Wrap(err)
+		}
+		if prevVersion != nil && version != nil && skipsMinorVersion(*prevVersion, *version) {
+			return trace.BadParameter("cannot update Kubernetes %v (%v) to %v (%v) directly, "+
+				"add the intermediate runtime packages to "+
+				"systemOptions.dependencies.intermediateRuntimePackages of the cluster image",
+				prevVersion, prevPackage, version, runtimePackage)
+		}
+		prevPackage, prevVersion = runtimePackage, version
+	}
+	return nil
+}
+
+// skipsMinorVersion returns true if the update from the specified version
+// to the specified version spans more than a single minor version
+func skipsMinorVersion(from, to semver.Version) bool {
+	return from.Major != to.Major || to.Minor > from.Minor+1
+}
+
+// intermediateSteps returns the phases that update the system software on all nodes
+// through the intermediate runtime packages of the plan in order.
+// etcdVersion specifies the installed version of etcd.
+// Returns the version of etcd after the last step
+func intermediateSteps(
+	p planConfig,
+	leadMaster runtimeServer,
+	otherMasters, nodes runtimeServers,
+	supportsTaints bool,
+	etcdVersion string,
+) (steps []update.Phase, lastEtcdVersion string) {
+	for _, intermediate := range p.intermediateRuntimes {
+		builder := phaseBuilder{
+			// Each step is recorded as a separate changeset so
+			// it can be rolled back individually
+			changesetID: fmt.Sprintf("%v-%v", p.operation.ID, intermediate.runtime.Version),
+		}
+		root := update.RootPhase(update.Phase{
+			ID:          fmt.Sprintf("intermediate-%v", intermediate.runtime.Version),
+			Description: fmt.Sprintf("Update system software to intermediate runtime %v", intermediate.runtime),
+		})
+		root.AddSequential(builder.masters(leadMaster.withRuntime(intermediate.runtime),
+			otherMasters.withRuntime(intermediate.runtime), supportsTaints).Nest(root.ID))
+		nodesPhase := builder.nodes(leadMaster.Server, p.installedApp.Package,
+			nodes.withRuntime(intermediate.runtime), supportsTaints,
+			intermediateStrategy(p.updateStrategy())).Nest(root.ID)
+		if len(nodesPhase.Phases) != 0 {
+			root.AddSequential(nodesPhase)
+		}
+		if etcdNeedsUpdate(etcdVersion, intermediate.etcdVersion) {
+			root.AddSequential(builder.etcdPlan(leadMaster.Server, otherMasters.asServers(), nodes.asServers(),
+				etcdVersion, intermediate.etcdVersion).Nest(root.ID))
+			etcdVersion = intermediate.etcdVersion
+		}
+		steps = append(steps, root)
+	}
+	return steps, etcdVersion
+}
+
+// intermediateStrategy returns the update strategy for the intermediate steps.
+// Only the final step updates a canary node
+func intermediateStrategy(strategy *storage.UpdateStrategy) *storage.UpdateStrategy {
+	if strategy == nil {
+		return nil
+	}
+	result := *strategy
+	result.Canary = false
+	return &result
+}
+
+// etcdNeedsUpdate returns true if etcd needs to be updated from the installed
+// to the update version. An unknown installed version always needs the update,
+// while an unknown update version never does
+func etcdNeedsUpdate(installedVersion, updateVersion string) bool {
+	if updateVersion == "" {
+		return false
+	}
+	if installedVersion == "" {
+		return true
+	}
+	installed, err := semver.NewVersion(installedVersion)
+	if err != nil {
+		return true
+	}
+	update, err := semver.NewVersion(updateVersion)
+	if err != nil {
+		return false
+	}
+	return installed.LessThan(*update)
+}
+
+// installedRuntimePackage returns the runtime package of the installed runtime application
+func installedRuntimePackage(installedRuntime app.Application) (*loc.Locator, error) {
+	runtimePackage, err := installedRuntime.Manifest.DefaultRuntimePackage()
+	if err != nil && !trace.IsNotFound(err) {
+		return nil, trace.Wrap(err)
+	}
+	if err != nil {
+		runtimePackage, err = installedRuntime.Manifest.Dependencies.ByName(loc.LegacyPlanetMaster.Name)
+		if err != nil {
+			return nil, trace.NotFound("runtime package not found")
+		}
+	}
+	return runtimePackage, nil
+}
+
+func (r runtimeServer) withRuntime(runtimePackage loc.Locator) runtimeServer {
+	r.runtime = runtimePackage
+	return r
+}
+
+func (r runtimeServers) withRuntime(runtimePackage loc.Locator) (result runtimeServers) {
+	result = make(runtimeServers, 0, len(r))
+	for _, server := range r {
+		result = append(result, server.withRuntime(runtimePackage))
+	}
+	return result
+}
+
+const (
+	// kubernetesVersionLabel is the runtime package label with the version of Kubernetes
+	kubernetesVersionLabel = "version-k8s"
+	// etcdVersionLabel is the runtime package label with the version of etcd
+	etcdVersionLabel = "version-etcd"
+)
@@ -180,6 +180,10 @@ func (p *updatePhaseBootstrap) Execute(ctx context.Context) error {
 	if err != nil {
 		return trace.Wrap(err)
 	}
+	err = p.pullIntermediateRuntimes()
+	if err != nil {
+		return trace.Wrap(err)
+	}
 	err = p.addUpdateRuntimePackageLabel()
 	if err != nil {
 		return trace.Wrap(err)
@@ -280,6 +284,42 @@ func (p *updatePhaseBootstrap) pullSystemUpdates() error {
 	return nil
 }
 
+// pullIntermediateRuntimes pulls the intermediate runtime packages this node
+// is updated through before the runtime package of the update
+func (p *updatePhaseBootstrap) pullIntermediateRuntimes() error {
+	for _, runtimePackage := range intermediateRuntimes(p.Plan, p.Server, p.runtimePackage) {
+		p.Infof("Pulling intermediate runtime package: %v.", runtimePackage)
+		_, err := appservice.PullPackage(appservice.PackagePullRequest{
+			SrcPack: p.Packages,
+			DstPack: p.LocalPackages,
+			Package: runtimePackage,
+			Labels:  pack.RuntimePackageLabels,
+			Upsert:  true,
+		})
+		if err != nil {
+			return trace.Wrap(err)
+		}
+	}
+	return nil
+}
+
+// intermediateRuntimes returns the runtime packages the system software on the specified
+// server is updated to by the operation plan other than the specified runtime package
+// of the update
+func intermediateRuntimes(plan storage.OperationPlan, server storage.Server, runtimePackage loc.Locator) (packages []loc.Locator) {
+	for _, phase := range fsm.FlattenPlan(&plan) {
+		if phase.Data == nil || phase.Data.Server == nil || phase.Data.RuntimePackage == nil {
+			continue
+		}
+		if phase.Data.Server.AdvertiseIP != server.AdvertiseIP ||
+			phase.Data.RuntimePackage.IsEqualTo(runtimePackage) {
+			continue
+		}
+		packages = append(packages, *phase.Data.RuntimePackage)
+	}
+	return loc.Deduplicate(packages)
+}
+
 func (p *updatePhaseBootstrap) collectTeleportUpdates() (updates []loc.Locator, err error) {
 	teleportNodeConfigUpdate, err := pack.FindLatestPackageWithLabels(
 		p.Packages, p.Operation.SiteDomain, map[string]string{
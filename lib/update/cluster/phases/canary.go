@@ -0,0 +1,87 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package phases
+
+import (
+	"context"
+	"time"
+
+	"github.com/gravitational/gravity/lib/fsm"
+
+	"github.com/gravitational/trace"
+	log "github.com/sirupsen/logrus"
+)
+
+// phaseCanaryApprove blocks the update of the remaining nodes after
+// the canary node has been verified
+type phaseCanaryApprove struct {
+	log.FieldLogger
+	// Pause is how long to wait before continuing the update.
+	// If unspecified, the update requires explicit approval
+	Pause time.Duration
+}
+
+// NewPhaseCanaryApprove returns a new executor that waits for the
+// canary update to be approved
+func NewPhaseCanaryApprove(p fsm.ExecutorParams, logger log.FieldLogger) (*phaseCanaryApprove, error) {
+	phase := &phaseCanaryApprove{
+		FieldLogger: logger,
+	}
+	if p.Phase.Data != nil && p.Phase.Data.Data != "" {
+		pause, err := time.ParseDuration(p.Phase.Data.Data)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		phase.Pause = pause
+	}
+	return phase, nil
+}
+
+// Execute waits for the configured pause to elapse
+func (p *phaseCanaryApprove) Execute(ctx context.Context) error {
+	if p.Pause == 0 {
+		return nil
+	}
+	p.Infof("Wait %v before updating the remaining nodes.", p.Pause)
+	select {
+	case <-time.After(p.Pause):
+		return nil
+	case <-ctx.Done():
+		return trace.Wrap(ctx.Err())
+	}
+}
+
+// Rollback is a no-op for this phase
+func (p *phaseCanaryApprove) Rollback(context.Context) error {
+	return nil
+}
+
+// PreCheck pauses the operation until the update has been approved which
+// marks this phase completed, unless the phase is configured with a pause.
+// The phase is left unstarted rather than failed while awaiting the approval
+func (p *phaseCanaryApprove) PreCheck(context.Context) error {
+	if p.Pause == 0 {
+		return fsm.Pause("canary node has been updated, " +
+			"verify the application and run 'gravity upgrade --approve' to update the remaining nodes")
+	}
+	return nil
+}
+
+// PostCheck is no-op for this phase
+func (p *phaseCanaryApprove) PostCheck(context.Context) error {
+	return nil
+}
@@ -18,6 +18,7 @@ package phases
 
 import (
 	"context"
+	"fmt"
 	"path/filepath"
 
 	"github.com/gravitational/gravity/lib/constants"
@@ -65,29 +66,42 @@ import (
 // PhaseUpgradeEtcdBackup backs up etcd data on all servers
 type PhaseUpgradeEtcdBackup struct {
 	log.FieldLogger
+	// backupFile is the path to the etcd backup
+	backupFile string
 }
 
-func NewPhaseUpgradeEtcdBackup(logger log.FieldLogger) (fsm.PhaseExecutor, error) {
+func NewPhaseUpgradeEtcdBackup(phase storage.OperationPhase, logger log.FieldLogger) (fsm.PhaseExecutor, error) {
+	backupFile, err := backupFile(phase)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
 	return &PhaseUpgradeEtcdBackup{
 		FieldLogger: logger,
+		backupFile:  backupFile,
 	}, nil
 }
 
-func backupFile() (string, error) {
+// backupFile returns the path to the etcd backup for the specified phase.
+// Each intermediate step of the update keeps its backup in a separate file
+// named after the changeset of the step so it is not overwritten by the next step
+func backupFile(phase storage.OperationPhase) (string, error) {
 	stateDir, err := state.GetStateDir()
 	if err != nil {
 		return "", trace.Wrap(err)
 	}
-	return filepath.Join(state.GravityUpdateDir(stateDir), defaults.EtcdUpgradeBackupFile), nil
+	return filepath.Join(state.GravityUpdateDir(stateDir), backupFileName(phase)), nil
 }
 
-func (p *PhaseUpgradeEtcdBackup) Execute(ctx context.Context) error {
-	p.Info("Backup etcd.")
-	backupFile, err := backupFile()
-	if err != nil {
-		return trace.Wrap(err)
+func backupFileName(phase storage.OperationPhase) string {
+	if phase.Data == nil || phase.Data.Update == nil || phase.Data.Update.ChangesetID == "" {
+		return defaults.EtcdUpgradeBackupFile
 	}
-	_, err = utils.RunPlanetCommand(ctx, p.FieldLogger, "etcd", "backup", backupFile)
+	return fmt.Sprintf("%v.%v", phase.Data.Update.ChangesetID, defaults.EtcdUpgradeBackupFile)
+}
+
+func (p *PhaseUpgradeEtcdBackup) Execute(ctx context.Context) error {
+	p.Infof("Backup etcd to %v.", p.backupFile)
+	_, err := utils.RunPlanetCommand(ctx, p.FieldLogger, "etcd", "backup", p.backupFile)
 	if err != nil {
 		return trace.Wrap(err, "failed to backup etcd")
 	}
@@ -221,12 +235,19 @@ func (*PhaseUpgradeEtcd) PostCheck(context.Context) error {
 type PhaseUpgradeEtcdRestore struct {
 	log.FieldLogger
 	Server storage.Server
+	// backupFile is the path to the etcd backup
+	backupFile string
 }
 
 func NewPhaseUpgradeEtcdRestore(phase storage.OperationPhase, logger log.FieldLogger) (fsm.PhaseExecutor, error) {
+	backupFile, err := backupFile(phase)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
 	return &PhaseUpgradeEtcdRestore{
 		FieldLogger: logger,
 		Server:      *phase.Data.Server,
+		backupFile:  backupFile,
 	}, nil
 }
 
@@ -234,12 +255,8 @@ func NewPhaseUpgradeEtcdRestore(phase storage.OperationPhase, logger log.FieldLo
 // 7. Restore the /registry (kubernetes) data to etcd, including automatic migration to v3 datastore for kubernetes
 // 10. Restart etcd on the correct ports on first node // API outage ends
 func (p *PhaseUpgradeEtcdRestore) Execute(ctx context.Context) error {
-	p.Info("Restore etcd data from backup.")
-	backupFile, err := backupFile()
-	if err != nil {
-		return trace.Wrap(err)
-	}
-	_, err = utils.RunPlanetCommand(ctx, p.FieldLogger, "etcd", "restore", backupFile)
+	p.Infof("Restore etcd data from backup %v.", p.backupFile)
+	_, err := utils.RunPlanetCommand(ctx, p.FieldLogger, "etcd", "restore", p.backupFile)
 	if err != nil {
 		return trace.Wrap(err)
 	}
@@ -70,6 +70,8 @@ type updatePhaseInit struct {
 	app app.Application
 	// installedApp references the installed application instance
 	installedApp app.Application
+	// plan is the operation plan
+	plan storage.OperationPlan
 	// existingDocker describes the existing Docker configuration
 	existingDocker        storage.DockerConfig
 	existingDNS           storage.DNSConfig
@@ -141,6 +143,7 @@ func NewUpdatePhaseInit(
 		FieldLogger:           logger,
 		app:                   *app,
 		installedApp:          *installedApp,
+		plan:                  p.Plan,
 		existingDocker:        existingDocker,
 		existingDNS:           p.Plan.DNSConfig,
 		existingClusterConfig: configBytes,
@@ -191,7 +194,7 @@ func (p *updatePhaseInit) Execute(context.Context) error {
 			return trace.Wrap(err)
 		}
 		if updatePlanet {
-			if err := p.rotatePlanetConfig(server); err != nil {
+			if err := p.rotatePlanetConfigs(server); err != nil {
 				return trace.Wrap(err, "failed to rotate planet configuration for %v", server)
 			}
 		}
@@ -357,17 +360,30 @@ func (p *updatePhaseInit) rotateSecrets(server storage.Server) error {
 	return nil
 }
 
-func (p *updatePhaseInit) rotatePlanetConfig(server storage.Server) error {
-	p.Infof("Generate new runtime configuration package for %v.", server)
+// rotatePlanetConfigs generates the runtime configuration packages for the specified server.
+// Besides the runtime package of the update, the configuration is generated for each
+// intermediate runtime package the server is updated through, so every step
+// runs the runtime with the configuration of its version
+func (p *updatePhaseInit) rotatePlanetConfigs(server storage.Server) error {
 	runtimePackage, err := p.app.Manifest.RuntimePackageForProfile(server.Role)
 	if err != nil {
 		return trace.Wrap(err)
 	}
+	for _, intermediate := range intermediateRuntimes(p.plan, server, *runtimePackage) {
+		if err := p.rotatePlanetConfig(server, intermediate); err != nil {
+			return trace.Wrap(err)
+		}
+	}
+	return trace.Wrap(p.rotatePlanetConfig(server, *runtimePackage))
+}
+
+func (p *updatePhaseInit) rotatePlanetConfig(server storage.Server, runtimePackage loc.Locator) error {
+	p.Infof("Generate new runtime configuration package for %v and %v.", server, runtimePackage)
 	resp, err := p.Operator.RotatePlanetConfig(ops.RotatePlanetConfigRequest{
 		Key:      p.Operation.Key(),
 		Server:   server,
 		Manifest: p.app.Manifest,
-		Package:  *runtimePackage,
+		Package:  runtimePackage,
 		Config:   p.existingClusterConfig,
 		Env:      p.existingEnviron,
 	})
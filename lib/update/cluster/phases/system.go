@@ -49,6 +49,8 @@ type updatePhaseSystem struct {
 	remote fsm.Remote
 	// runtimePackage specifies the runtime package to update to
 	runtimePackage loc.Locator
+	// changesetID specifies the ID of the package changeset to record the update with
+	changesetID string
 }
 
 // NewUpdatePhaseNode returns a new node update phase executor
@@ -63,6 +65,10 @@ func NewUpdatePhaseSystem(p fsm.ExecutorParams, remote fsm.Remote, logger log.Fi
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
+	changesetID := p.Plan.OperationID
+	if p.Phase.Data.Update != nil && p.Phase.Data.Update.ChangesetID != "" {
+		changesetID = p.Phase.Data.Update.ChangesetID
+	}
 	return &updatePhaseSystem{
 		OperationID:    p.Plan.OperationID,
 		Server:         *p.Phase.Data.Server,
@@ -70,6 +76,7 @@ func NewUpdatePhaseSystem(p fsm.ExecutorParams, remote fsm.Remote, logger log.Fi
 		FieldLogger:    logger,
 		remote:         remote,
 		runtimePackage: *p.Phase.Data.RuntimePackage,
+		changesetID:    changesetID,
 	}, nil
 }
 
@@ -87,7 +94,7 @@ func (p *updatePhaseSystem) PostCheck(context.Context) error {
 func (p *updatePhaseSystem) Execute(context.Context) error {
 	out, err := fsm.RunCommand([]string{p.GravityPath,
 		"--insecure", "--debug", "system", "update",
-		"--changeset-id", p.OperationID,
+		"--changeset-id", p.changesetID,
 		"--runtime-package", p.runtimePackage.String(),
 		"--with-status",
 	})
@@ -108,7 +115,7 @@ func (p *updatePhaseSystem) Execute(context.Context) error {
 // Rollback runs rolls back the system upgrade on the node
 func (p *updatePhaseSystem) Rollback(context.Context) error {
 	out, err := fsm.RunCommand([]string{p.GravityPath, "--insecure", "system", "rollback",
-		"--changeset-id", p.OperationID, "--with-status"})
+		"--changeset-id", p.changesetID, "--with-status"})
 	if err != nil {
 		p.Warnf("Failed to rollback system: %s (%v).", out, err)
 		return trace.Wrap(err, "failed to rollback system: %s", out)
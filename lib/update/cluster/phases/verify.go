@@ -0,0 +1,113 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package phases
+
+import (
+	"context"
+
+	"github.com/gravitational/gravity/lib/app"
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/fsm"
+	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/schema"
+	"github.com/gravitational/gravity/lib/status"
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/utils"
+
+	pb "github.com/gravitational/satellite/agent/proto/agentpb"
+	"github.com/gravitational/trace"
+	log "github.com/sirupsen/logrus"
+	"k8s.io/client-go/kubernetes"
+)
+
+// phaseVerify verifies the health of the cluster and the application
+// during or after the update
+type phaseVerify struct {
+	phaseApp
+	// Server is the optional node that has been updated
+	Server *storage.Server
+}
+
+// NewPhaseVerify returns a new executor that verifies the health of the cluster,
+// the optionally specified node and the application
+func NewPhaseVerify(
+	p fsm.ExecutorParams,
+	operator ops.Operator,
+	apps app.Applications,
+	client *kubernetes.Clientset,
+	logger log.FieldLogger,
+) (*phaseVerify, error) {
+	if p.Phase.Data == nil || p.Phase.Data.Package == nil {
+		return nil, trace.NotFound("no package specified for phase %q", p.Phase.ID)
+	}
+	cluster, err := operator.GetLocalSite()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &phaseVerify{
+		phaseApp: phaseApp{
+			FieldLogger:    logger,
+			Apps:           apps,
+			Client:         client,
+			GravityPackage: p.Plan.GravityPackage,
+			Package:        *p.Phase.Data.Package,
+			Servers:        p.Plan.Servers,
+			ServiceUser:    cluster.ServiceUser,
+		},
+		Server: p.Phase.Data.Server,
+	}, nil
+}
+
+// Execute waits for the cluster and the node to become healthy
+// and runs the application status hook
+func (p *phaseVerify) Execute(ctx context.Context) error {
+	p.Infof("Verify cluster health.")
+	b := utils.NewExponentialBackOff(defaults.VerifyUpdateTimeout)
+	err := utils.RetryWithInterval(ctx, b, func() error {
+		return trace.Wrap(p.checkHealth(ctx))
+	})
+	if err != nil {
+		return trace.Wrap(err, "cluster is not healthy")
+	}
+	err = p.runHooks(ctx, schema.HookStatus)
+	if err != nil {
+		return trace.Wrap(err, "application status check failed")
+	}
+	return nil
+}
+
+// Rollback is a no-op for this phase
+func (p *phaseVerify) Rollback(context.Context) error {
+	return nil
+}
+
+func (p *phaseVerify) checkHealth(ctx context.Context) error {
+	agent, err := status.FromPlanetAgent(ctx, p.Servers)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	for _, node := range agent.Nodes {
+		if p.Server != nil && node.AdvertiseIP == p.Server.AdvertiseIP && node.Status != status.NodeHealthy {
+			return trace.BadParameter("node %v is %v: %v", node.Hostname,
+				node.Status, node.FailedProbes)
+		}
+	}
+	if agent.GetSystemStatus() != pb.SystemStatus_Running {
+		return trace.BadParameter("cluster is degraded")
+	}
+	return nil
+}
@@ -72,6 +72,34 @@ func InitOperationPlan(
 		return nil, trace.AlreadyExists("plan is already initialized")
 	}
 
+	plan, err = BuildOperationPlan(localEnv, clusterEnv, *operation)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+
+	_, err = clusterEnv.Backend.CreateOperationPlan(*plan)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+
+	// all plan creation was done on the cluster, so sync it to the local backend, which will be authoritative
+	// from now on
+	err = SyncOperationPlan(clusterEnv.Backend, updateEnv.Backend)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+
+	return plan, nil
+}
+
+// BuildOperationPlan returns the update plan for the specified operation
+// without storing it in the cluster.
+// The operation is not required to exist in the cluster backend
+func BuildOperationPlan(
+	localEnv *localenv.LocalEnvironment,
+	clusterEnv *localenv.ClusterEnvironment,
+	operation storage.SiteOperation,
+) (*storage.OperationPlan, error) {
 	cluster, err := clusterEnv.Operator.GetLocalSite()
 	if err != nil {
 		return nil, trace.Wrap(err)
@@ -87,30 +115,17 @@ func InitOperationPlan(
 		dnsConfig = *existingDNS
 	}
 
-	plan, err = NewOperationPlan(PlanConfig{
+	plan, err := NewOperationPlan(PlanConfig{
 		Backend:   clusterEnv.Backend,
 		Apps:      clusterEnv.Apps,
 		Packages:  clusterEnv.ClusterPackages,
 		Client:    clusterEnv.Client,
 		DNSConfig: dnsConfig,
-		Operation: operation,
+		Operation: &operation,
 	})
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
-
-	_, err = clusterEnv.Backend.CreateOperationPlan(*plan)
-	if err != nil {
-		return nil, trace.Wrap(err)
-	}
-
-	// all plan creation was done on the cluster, so sync it to the local backend, which will be authoritative
-	// from now on
-	err = SyncOperationPlan(clusterEnv.Backend, updateEnv.Backend)
-	if err != nil {
-		return nil, trace.Wrap(err)
-	}
-
 	return plan, nil
 }
 
@@ -201,6 +216,11 @@ func NewOperationPlan(config PlanConfig) (*storage.OperationPlan, error) {
 		return nil, trace.Wrap(err)
 	}
 
+	intermediateRuntimes, err := getIntermediateRuntimes(*installedRuntime, *updateApp, *updateRuntime, config.Packages)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+
 	links, err := config.Backend.GetOpsCenterLinks(config.Operation.SiteDomain)
 	if err != nil {
 		return nil, trace.Wrap(err)
@@ -216,20 +236,21 @@ func NewOperationPlan(config PlanConfig) (*storage.OperationPlan, error) {
 	}
 
 	plan, err := newOperationPlan(planConfig{
-		operation:         *config.Operation,
-		servers:           servers,
-		installedRuntime:  *installedRuntime,
-		installedApp:      *installedApp,
-		updateRuntime:     *updateRuntime,
-		updateApp:         *updateApp,
-		links:             links,
-		trustedClusters:   trustedClusters,
-		packageService:    config.Packages,
-		shouldUpdateEtcd:  shouldUpdateEtcd,
-		updateCoreDNS:     updateCoreDNS,
-		dnsConfig:         config.DNSConfig,
-		updateDNSAppEarly: updateDNSAppEarly,
-		roles:             roles,
+		operation:            *config.Operation,
+		servers:              servers,
+		installedRuntime:     *installedRuntime,
+		installedApp:         *installedApp,
+		updateRuntime:        *updateRuntime,
+		updateApp:            *updateApp,
+		intermediateRuntimes: intermediateRuntimes,
+		links:                links,
+		trustedClusters:      trustedClusters,
+		packageService:       config.Packages,
+		shouldUpdateEtcd:     shouldUpdateEtcd,
+		updateCoreDNS:        updateCoreDNS,
+		dnsConfig:            config.DNSConfig,
+		updateDNSAppEarly:    updateDNSAppEarly,
+		roles:                roles,
 	})
 	if err != nil {
 		return nil, trace.Wrap(err)
@@ -281,6 +302,9 @@ type planConfig struct {
 	updateRuntime app.Application
 	// updateApp is the update app
 	updateApp app.Application
+	// intermediateRuntimes lists the runtime packages to update the nodes
+	// through before the runtime package of the update app
+	intermediateRuntimes []intermediateRuntime
 	// links is a list of configured remote Ops Center links
 	links []storage.OpsCenterLink
 	// trustedClusters is a list of configured trusted clusters
@@ -300,6 +324,19 @@ type planConfig struct {
 	roles []teleservices.Role
 }
 
+// updateStrategy returns the optional update strategy of the operation
+func (r planConfig) updateStrategy() *storage.UpdateStrategy {
+	if r.operation.Update == nil {
+		return nil
+	}
+	return r.operation.Update.Strategy
+}
+
+// autoRollback returns whether the operation is rolled back automatically on failure
+func (r planConfig) autoRollback() bool {
+	return r.operation.Update != nil && r.operation.Update.AutoRollback
+}
+
 func newOperationPlan(p planConfig) (*storage.OperationPlan, error) {
 	gravityPackage, err := p.updateRuntime.Manifest.Dependencies.ByName(constants.GravityPackage)
 	if err != nil {
@@ -360,8 +397,8 @@ func newOperationPlan(p planConfig) (*storage.OperationPlan, error) {
 
 	mastersPhase := *builder.masters(leadMaster, masters[1:], supportsTaints).
 		Require(checksPhase, bootstrapPhase, preUpdatePhase)
-	nodesPhase := *builder.nodes(leadMaster.Server, nodes, supportsTaints).
-		Require(mastersPhase)
+	nodesPhase := *builder.nodes(leadMaster.Server, p.installedApp.Package, nodes, supportsTaints,
+		p.updateStrategy()).Require(mastersPhase)
 
 	allRuntimeUpdates, err := app.GetUpdatedDependencies(p.installedRuntime, p.updateRuntime)
 	if err != nil && !trace.IsNotFound(err) {
@@ -406,7 +443,20 @@ func newOperationPlan(p planConfig) (*storage.OperationPlan, error) {
 			}
 		}
 
-		root.Add(bootstrapPhase, mastersPhase)
+		root.Add(bootstrapPhase)
+		steps, etcdVersion := intermediateSteps(p, leadMaster, masters[1:], nodes, supportsTaints, currentVersion)
+		if len(steps) != 0 {
+			// Step through the intermediate runtimes before updating to the final one
+			steps[0].RequireLiteral(mastersPhase.Requires...)
+			for i := 1; i < len(steps); i++ {
+				steps[i].Require(steps[i-1])
+			}
+			mastersPhase.Requires = []string{steps[len(steps)-1].ID}
+			root.Add(steps...)
+			updateEtcd = etcdNeedsUpdate(etcdVersion, desiredVersion)
+			currentVersion = etcdVersion
+		}
+		root.Add(mastersPhase)
 		if len(nodesPhase.Phases) > 0 {
 			root.Add(nodesPhase)
 		}
@@ -434,7 +484,13 @@ func newOperationPlan(p planConfig) (*storage.OperationPlan, error) {
 		root.Add(configPhase, runtimePhase)
 	}
 
-	root.AddSequential(*builder.app(appUpdates), *builder.cleanup(p.servers))
+	root.AddSequential(*builder.app(appUpdates))
+	if p.autoRollback() {
+		// Verify the cluster once updated so the failed status check
+		// rolls back the update
+		root.AddSequential(*builder.verify(leadMaster.Server, p.updateApp.Package))
+	}
+	root.AddSequential(*builder.cleanup(p.servers))
 	plan.Phases = root.Phases
 	update.ResolvePlan(&plan)
 
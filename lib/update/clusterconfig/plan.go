@@ -35,11 +35,7 @@ func NewOperationPlan(
 	clusterConfig clusterconfig.Interface,
 	servers []storage.Server,
 ) (plan *storage.OperationPlan, err error) {
-	cluster, err := operator.GetLocalSite()
-	if err != nil {
-		return nil, trace.Wrap(err)
-	}
-	plan, err = newOperationPlan(cluster.App.Package, cluster.DNSConfig, operation, clusterConfig, servers)
+	plan, err = BuildOperationPlan(operator, operation, clusterConfig, servers)
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
@@ -55,6 +51,25 @@ func NewOperationPlan(
 	return plan, nil
 }
 
+// BuildOperationPlan returns a new plan for the specified operation
+// without storing it in the cluster
+func BuildOperationPlan(
+	operator ops.Operator,
+	operation ops.SiteOperation,
+	clusterConfig clusterconfig.Interface,
+	servers []storage.Server,
+) (*storage.OperationPlan, error) {
+	cluster, err := operator.GetLocalSite()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	plan, err := newOperationPlan(cluster.App.Package, cluster.DNSConfig, operation, clusterConfig, servers)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return plan, nil
+}
+
 // newOperationPlan returns a new plan for the specified operation
 // and the given set of servers
 func newOperationPlan(
@@ -39,8 +39,9 @@ func NewMachine(ctx context.Context, config Config, engine *Engine) (*fsm.FSM, e
 		return nil, trace.Wrap(err)
 	}
 	machine, err := fsm.New(fsm.Config{
-		Engine: engine,
-		Runner: config.Runner,
+		Engine:      engine,
+		Runner:      config.Runner,
+		Parallelism: config.Parallelism,
 	})
 	if err != nil {
 		return nil, trace.Wrap(err)
@@ -163,7 +164,11 @@ func (r *Engine) ChangePhaseState(ctx context.Context, change fsm.StateChange) e
 // RunCommand executes the phase specified by params on the specified server
 // using the provided runner
 func (r *Engine) RunCommand(ctx context.Context, runner fsm.RemoteRunner, server storage.Server, params fsm.Params) error {
-	args := []string{"plan", "execute",
+	command := "execute"
+	if params.Rollback {
+		command = "rollback"
+	}
+	args := []string{"plan", command,
 		"--phase", params.PhaseID,
 		"--operation-id", r.Operation.ID,
 	}
@@ -29,11 +29,7 @@ import (
 
 // NewOperationPlan creates a new operation plan for the specified operation
 func NewOperationPlan(operator ops.Operator, operation ops.SiteOperation, servers []storage.Server) (plan *storage.OperationPlan, err error) {
-	cluster, err := operator.GetLocalSite()
-	if err != nil {
-		return nil, trace.Wrap(err)
-	}
-	plan, err = newOperationPlan(cluster.App.Package, cluster.DNSConfig, operation, servers)
+	plan, err = BuildOperationPlan(operator, operation, servers)
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
@@ -49,6 +45,20 @@ func NewOperationPlan(operator ops.Operator, operation ops.SiteOperation, server
 	return plan, nil
 }
 
+// BuildOperationPlan returns a new plan for the specified operation
+// without storing it in the cluster
+func BuildOperationPlan(operator ops.Operator, operation ops.SiteOperation, servers []storage.Server) (*storage.OperationPlan, error) {
+	cluster, err := operator.GetLocalSite()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	plan, err := newOperationPlan(cluster.App.Package, cluster.DNSConfig, operation, servers)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return plan, nil
+}
+
 // newOperationPlan returns a new plan for the specified operation
 // and the given set of servers
 func newOperationPlan(app loc.Locator, dnsConfig storage.DNSConfig, operation ops.SiteOperation, servers []storage.Server) (*storage.OperationPlan, error) {
@@ -23,6 +23,7 @@ import (
 	"time"
 
 	"github.com/gravitational/gravity/lib/constants"
+	"github.com/gravitational/gravity/lib/defaults"
 	"github.com/gravitational/gravity/lib/fsm"
 	"github.com/gravitational/gravity/lib/localenv"
 	"github.com/gravitational/gravity/lib/ops"
@@ -128,6 +129,11 @@ func (r *Updater) Complete(fsmErr error) error {
 	return trace.Wrap(err)
 }
 
+// ChangePhaseState changes the state of the specified phase
+func (r *Updater) ChangePhaseState(ctx context.Context, change fsm.StateChange) error {
+	return trace.Wrap(r.machine.ChangePhaseState(ctx, change))
+}
+
 // GetPlan returns the up-to-date operation plan
 func (r *Updater) GetPlan() (*storage.OperationPlan, error) {
 	return r.machine.GetPlan()
@@ -145,6 +151,10 @@ func (r *Updater) executePlan(ctx context.Context, force bool) error {
 	planErr := r.machine.ExecutePlan(ctx, progress, force)
 	if planErr != nil {
 		r.Warnf("Failed to execute plan: %v.", trace.DebugReport(planErr))
+		// The paused operation is resumed once approved
+		if r.AutoRollback && !fsm.IsPaused(planErr) {
+			r.rollbackPlan(progress)
+		}
 	}
 
 	err := r.machine.Complete(planErr)
@@ -167,6 +177,30 @@ func (r *Updater) executePlan(ctx context.Context, force bool) error {
 	return nil
 }
 
+// rollbackPlan rolls back all started phases of the failed plan
+// and reports the phases that have been rolled back.
+//
+// The rollback uses its own context as the plan might have failed
+// because the operation context has been cancelled
+func (r *Updater) rollbackPlan(progress utils.Progress) {
+	r.Silent.Println("Update has failed, rolling back.")
+	ctx, cancel := context.WithTimeout(context.Background(), defaults.RollbackTimeout)
+	defer cancel()
+	rolledBack, err := r.machine.RollbackPlan(ctx, progress, false)
+	for _, phase := range rolledBack {
+		r.Silent.Printf("\tRolled back phase %v\n", phase)
+	}
+	if err != nil {
+		r.Warnf("Failed to roll back plan: %v.", trace.DebugReport(err))
+		r.Silent.Printf("Failed to roll back the update: %v.\n"+
+			"Use 'gravity plan' to review and roll back the remaining phases manually.\n",
+			trace.UserMessage(err))
+		return
+	}
+	r.Silent.Printf("Rolled back %v phases, the cluster has been restored to its state before the update.\n",
+		len(rolledBack))
+}
+
 func (r *Updater) updateProgress(lastProgress *ops.ProgressEntry) *ops.ProgressEntry {
 	progress, err := r.Operator.GetSiteOperationProgress(r.Operation.Key())
 	if err != nil {
@@ -215,6 +249,11 @@ type Config struct {
 	LocalBackend storage.Backend
 	// Runner specifies the runner for remote commands
 	Runner fsm.AgentRepository
+	// Parallelism specifies the maximum number of phases to execute concurrently
+	Parallelism int
+	// AutoRollback specifies whether the started phases of the plan
+	// are rolled back automatically if the plan fails
+	AutoRollback bool
 	// FieldLogger is the logger to use
 	log.FieldLogger
 	// Silent controls whether the process outputs messages to stdout
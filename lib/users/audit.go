@@ -0,0 +1,303 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package users
+
+import (
+	"time"
+
+	"github.com/gravitational/gravity/lib/audit"
+	"github.com/gravitational/gravity/lib/storage"
+
+	teleservices "github.com/gravitational/teleport/lib/services"
+	"github.com/gravitational/trace"
+)
+
+// IdentityWithAudit returns a new instance of the Identity interface
+// that records every change of users, roles, auth connectors and tokens
+// made on behalf of the specified user in the audit log
+func IdentityWithAudit(identity Identity, emitter audit.Emitter, user storage.User) Identity {
+	return &identityAudit{
+		Identity: identity,
+		emitter:  emitter,
+		username: user.GetName(),
+	}
+}
+
+// identityAudit is a wrapper around any Identity service that audits
+// changes of users, roles, auth connectors, trusted clusters and tokens.
+//
+// Read-only calls and changes of internal state, like web sessions, login
+// attempts and certificate authorities, are passed through to the underlying service
+type identityAudit struct {
+	// Identity is the audited identity service
+	Identity
+	emitter  audit.Emitter
+	username string
+}
+
+// record emits the audit event for the change with the specified result
+// and the resource state after the change
+func (i *identityAudit) record(c *audit.Change, after interface{}, err error) {
+	i.emitter.Emit(c.Event(i.username, after, err))
+}
+
+func (i *identityAudit) CreateUser(user teleservices.User) error {
+	c := audit.NewChange(teleservices.VerbCreate, teleservices.KindUser, user.GetName(), "")
+	err := i.Identity.CreateUser(user)
+	i.record(c, user, err)
+	return trace.Wrap(err)
+}
+
+func (i *identityAudit) UpsertUser(user teleservices.User) error {
+	c := audit.NewChange(audit.VerbUpsert, teleservices.KindUser, user.GetName(), "")
+	c.CaptureBefore(func() (interface{}, error) {
+		return i.Identity.GetUser(user.GetName())
+	})
+	err := i.Identity.UpsertUser(user)
+	i.record(c, user, err)
+	return trace.Wrap(err)
+}
+
+func (i *identityAudit) UpdateUser(username string, req storage.UpdateUserReq) error {
+	c := audit.NewChange(teleservices.VerbUpdate, teleservices.KindUser, username, "")
+	c.CaptureBefore(func() (interface{}, error) {
+		return i.Identity.GetUser(username)
+	})
+	err := i.Identity.UpdateUser(username, req)
+	i.record(c, i.getUser(username), err)
+	return trace.Wrap(err)
+}
+
+func (i *identityAudit) DeleteUser(username string) error {
+	c := audit.NewChange(teleservices.VerbDelete, teleservices.KindUser, username, "")
+	c.CaptureBefore(func() (interface{}, error) {
+		return i.Identity.GetUser(username)
+	})
+	err := i.Identity.DeleteUser(username)
+	i.record(c, nil, err)
+	return trace.Wrap(err)
+}
+
+func (i *identityAudit) UpdatePassword(username string, oldPassword, newPassword Password) error {
+	c := audit.NewChange(teleservices.VerbUpdate, teleservices.KindUser, username, "")
+	err := i.Identity.UpdatePassword(username, oldPassword, newPassword)
+	i.record(c, nil, err)
+	return trace.Wrap(err)
+}
+
+func (i *identityAudit) ResetPassword(username string) (string, error) {
+	c := audit.NewChange(teleservices.VerbUpdate, teleservices.KindUser, username, "")
+	password, err := i.Identity.ResetPassword(username)
+	i.record(c, nil, err)
+	return password, trace.Wrap(err)
+}
+
+func (i *identityAudit) CreateResetToken(advertiseURL string, email string, ttl time.Duration) (*storage.UserToken, error) {
+	c := audit.NewChange(teleservices.VerbUpdate, teleservices.KindUser, email, "")
+	token, err := i.Identity.CreateResetToken(advertiseURL, email, ttl)
+	i.record(c, nil, err)
+	return token, trace.Wrap(err)
+}
+
+func (i *identityAudit) CreateInviteToken(advertiseURL string, invite storage.UserInvite) (*storage.UserToken, error) {
+	c := audit.NewChange(teleservices.VerbCreate, storage.KindUserInvite, invite.Name, "")
+	token, err := i.Identity.CreateInviteToken(advertiseURL, invite)
+	i.record(c, invite, err)
+	return token, trace.Wrap(err)
+}
+
+func (i *identityAudit) DeleteUserInvite(accountID, email string) error {
+	c := audit.NewChange(teleservices.VerbDelete, storage.KindUserInvite, email, "")
+	err := i.Identity.DeleteUserInvite(accountID, email)
+	i.record(c, nil, err)
+	return trace.Wrap(err)
+}
+
+func (i *identityAudit) UpsertRole(role teleservices.Role, ttl time.Duration) error {
+	c := audit.NewChange(audit.VerbUpsert, teleservices.KindRole, role.GetName(), "")
+	c.CaptureBefore(func() (interface{}, error) {
+		return i.Identity.GetRole(role.GetName())
+	})
+	err := i.Identity.UpsertRole(role, ttl)
+	i.record(c, role, err)
+	return trace.Wrap(err)
+}
+
+func (i *identityAudit) CreateRole(role teleservices.Role, ttl time.Duration) error {
+	c := audit.NewChange(teleservices.VerbCreate, teleservices.KindRole, role.GetName(), "")
+	err := i.Identity.CreateRole(role, ttl)
+	i.record(c, role, err)
+	return trace.Wrap(err)
+}
+
+func (i *identityAudit) DeleteRole(name string) error {
+	c := audit.NewChange(teleservices.VerbDelete, teleservices.KindRole, name, "")
+	c.CaptureBefore(func() (interface{}, error) {
+		return i.Identity.GetRole(name)
+	})
+	err := i.Identity.DeleteRole(name)
+	i.record(c, nil, err)
+	return trace.Wrap(err)
+}
+
+func (i *identityAudit) UpsertOIDCConnector(connector teleservices.OIDCConnector) error {
+	c := audit.NewChange(audit.VerbUpsert, teleservices.KindOIDCConnector, connector.GetName(), "")
+	c.CaptureBefore(func() (interface{}, error) {
+		return i.Identity.GetOIDCConnector(connector.GetName(), false)
+	})
+	err := i.Identity.UpsertOIDCConnector(connector)
+	i.record(c, connector, err)
+	return trace.Wrap(err)
+}
+
+func (i *identityAudit) DeleteOIDCConnector(name string) error {
+	c := audit.NewChange(teleservices.VerbDelete, teleservices.KindOIDCConnector, name, "")
+	c.CaptureBefore(func() (interface{}, error) {
+		return i.Identity.GetOIDCConnector(name, false)
+	})
+	err := i.Identity.DeleteOIDCConnector(name)
+	i.record(c, nil, err)
+	return trace.Wrap(err)
+}
+
+func (i *identityAudit) CreateSAMLConnector(connector teleservices.SAMLConnector) error {
+	c := audit.NewChange(teleservices.VerbCreate, teleservices.KindSAMLConnector, connector.GetName(), "")
+	err := i.Identity.CreateSAMLConnector(connector)
+	i.record(c, connector, err)
+	return trace.Wrap(err)
+}
+
+func (i *identityAudit) UpsertSAMLConnector(connector teleservices.SAMLConnector) error {
+	c := audit.NewChange(audit.VerbUpsert, teleservices.KindSAMLConnector, connector.GetName(), "")
+	c.CaptureBefore(func() (interface{}, error) {
+		return i.Identity.GetSAMLConnector(connector.GetName(), false)
+	})
+	err := i.Identity.UpsertSAMLConnector(connector)
+	i.record(c, connector, err)
+	return trace.Wrap(err)
+}
+
+func (i *identityAudit) DeleteSAMLConnector(name string) error {
+	c := audit.NewChange(teleservices.VerbDelete, teleservices.KindSAMLConnector, name, "")
+	c.CaptureBefore(func() (interface{}, error) {
+		return i.Identity.GetSAMLConnector(name, false)
+	})
+	err := i.Identity.DeleteSAMLConnector(name)
+	i.record(c, nil, err)
+	return trace.Wrap(err)
+}
+
+func (i *identityAudit) CreateGithubConnector(connector teleservices.GithubConnector) error {
+	c := audit.NewChange(teleservices.VerbCreate, teleservices.KindGithubConnector, connector.GetName(), "")
+	err := i.Identity.CreateGithubConnector(connector)
+	i.record(c, connector, err)
+	return trace.Wrap(err)
+}
+
+func (i *identityAudit) UpsertGithubConnector(connector teleservices.GithubConnector) error {
+	c := audit.NewChange(audit.VerbUpsert, teleservices.KindGithubConnector, connector.GetName(), "")
+	c.CaptureBefore(func() (interface{}, error) {
+		return i.Identity.GetGithubConnector(connector.GetName(), false)
+	})
+	err := i.Identity.UpsertGithubConnector(connector)
+	i.record(c, connector, err)
+	return trace.Wrap(err)
+}
+
+func (i *identityAudit) DeleteGithubConnector(name string) error {
+	c := audit.NewChange(teleservices.VerbDelete, teleservices.KindGithubConnector, name, "")
+	c.CaptureBefore(func() (interface{}, error) {
+		return i.Identity.GetGithubConnector(name, false)
+	})
+	err := i.Identity.DeleteGithubConnector(name)
+	i.record(c, nil, err)
+	return trace.Wrap(err)
+}
+
+func (i *identityAudit) SetAuthPreference(auth teleservices.AuthPreference) error {
+	c := audit.NewChange(audit.VerbUpsert, teleservices.KindClusterAuthPreference, "", "")
+	c.CaptureBefore(func() (interface{}, error) {
+		return i.Identity.GetAuthPreference()
+	})
+	err := i.Identity.SetAuthPreference(auth)
+	i.record(c, auth, err)
+	return trace.Wrap(err)
+}
+
+func (i *identityAudit) UpsertTrustedCluster(cluster teleservices.TrustedCluster) (teleservices.TrustedCluster, error) {
+	c := audit.NewChange(audit.VerbUpsert, teleservices.KindTrustedCluster, cluster.GetName(), "")
+	c.CaptureBefore(func() (interface{}, error) {
+		return i.Identity.GetTrustedCluster(cluster.GetName())
+	})
+	upserted, err := i.Identity.UpsertTrustedCluster(cluster)
+	i.record(c, cluster, err)
+	return upserted, trace.Wrap(err)
+}
+
+func (i *identityAudit) DeleteTrustedCluster(name string) error {
+	c := audit.NewChange(teleservices.VerbDelete, teleservices.KindTrustedCluster, name, "")
+	c.CaptureBefore(func() (interface{}, error) {
+		return i.Identity.GetTrustedCluster(name)
+	})
+	err := i.Identity.DeleteTrustedCluster(name)
+	i.record(c, nil, err)
+	return trace.Wrap(err)
+}
+
+func (i *identityAudit) CreateAPIKey(key storage.APIKey, upsert bool) (*storage.APIKey, error) {
+	c := audit.NewChange(teleservices.VerbCreate, storage.KindToken, key.UserEmail, "")
+	created, err := i.Identity.CreateAPIKey(key, upsert)
+	i.record(c, nil, err)
+	return created, trace.Wrap(err)
+}
+
+func (i *identityAudit) DeleteAPIKey(username, token string) error {
+	c := audit.NewChange(teleservices.VerbDelete, storage.KindToken, username, "")
+	err := i.Identity.DeleteAPIKey(username, token)
+	i.record(c, nil, err)
+	return trace.Wrap(err)
+}
+
+func (i *identityAudit) CreateProvisioningToken(token storage.ProvisioningToken) (*storage.ProvisioningToken, error) {
+	c := audit.NewChange(teleservices.VerbCreate, storage.KindToken, string(token.Type), token.SiteDomain)
+	created, err := i.Identity.CreateProvisioningToken(token)
+	i.record(c, nil, err)
+	return created, trace.Wrap(err)
+}
+
+func (i *identityAudit) CreateInstallToken(token storage.InstallToken) (*storage.InstallToken, error) {
+	c := audit.NewChange(teleservices.VerbCreate, storage.KindToken, token.UserEmail, token.SiteDomain)
+	created, err := i.Identity.CreateInstallToken(token)
+	i.record(c, nil, err)
+	return created, trace.Wrap(err)
+}
+
+func (i *identityAudit) UpdateInstallToken(req InstallTokenUpdateRequest) (*storage.InstallToken, teleservices.Role, error) {
+	c := audit.NewChange(teleservices.VerbUpdate, storage.KindToken, "", req.SiteDomain)
+	token, role, err := i.Identity.UpdateInstallToken(req)
+	i.record(c, nil, err)
+	return token, role, trace.Wrap(err)
+}
+
+// getUser returns the current state of the user with the specified name
+func (i *identityAudit) getUser(username string) interface{} {
+	user, err := i.Identity.GetUser(username)
+	if err != nil {
+		return nil
+	}
+	return user
+}
@@ -0,0 +1,125 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package users
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gravitational/gravity/lib/storage"
+
+	teleservices "github.com/gravitational/teleport/lib/services"
+	"github.com/gravitational/trace"
+	check "gopkg.in/check.v1"
+)
+
+func TestUsers(t *testing.T) { check.TestingT(t) }
+
+type AuditSuite struct{}
+
+var _ = check.Suite(&AuditSuite{})
+
+func (s *AuditSuite) TestRecordsChanges(c *check.C) {
+	identity := &roleIdentity{roles: make(map[string]teleservices.Role)}
+	emitter := &recordingEmitter{}
+	audited := IdentityWithAudit(identity, emitter, storage.NewUser("alice@example.com", storage.UserSpecV2{}))
+
+	role, err := teleservices.NewRole("auditor", teleservices.RoleSpecV3{
+		Allow: teleservices.RoleConditions{Logins: []string{"root"}},
+	})
+	c.Assert(err, check.IsNil)
+	c.Assert(audited.UpsertRole(role, 0), check.IsNil)
+
+	role.SetLogins(teleservices.Allow, []string{"admin"})
+	c.Assert(audited.UpsertRole(role, 0), check.IsNil)
+	c.Assert(audited.DeleteRole("auditor"), check.IsNil)
+
+	_, err = audited.CreateInviteToken("https://example.com", storage.UserInvite{
+		Name:  "bob@example.com",
+		Roles: []string{"auditor"},
+	})
+	c.Assert(trace.IsAccessDenied(err), check.Equals, true)
+
+	c.Assert(emitter.events, check.HasLen, 4)
+	created, updated, deleted, invited := emitter.events[0], emitter.events[1],
+		emitter.events[2], emitter.events[3]
+
+	c.Assert(created.User, check.Equals, "alice@example.com")
+	c.Assert(created.Verb, check.Equals, teleservices.VerbCreate)
+	c.Assert(created.Kind, check.Equals, teleservices.KindRole)
+	c.Assert(created.Name, check.Equals, "auditor")
+	c.Assert(created.Result, check.Equals, storage.AuditResultSuccess)
+
+	c.Assert(updated.Verb, check.Equals, teleservices.VerbUpdate)
+	c.Assert(strings.Contains(updated.Diff, "-    - root"), check.Equals, true, check.Commentf(updated.Diff))
+	c.Assert(strings.Contains(updated.Diff, "+    - admin"), check.Equals, true, check.Commentf(updated.Diff))
+
+	c.Assert(deleted.Verb, check.Equals, teleservices.VerbDelete)
+	c.Assert(strings.Contains(deleted.Diff, "-kind: role"), check.Equals, true, check.Commentf(deleted.Diff))
+
+	c.Assert(invited.Verb, check.Equals, teleservices.VerbCreate)
+	c.Assert(invited.Kind, check.Equals, storage.KindUserInvite)
+	c.Assert(invited.Name, check.Equals, "bob@example.com")
+	c.Assert(invited.Result, check.Equals, storage.AuditResultFailure)
+	c.Assert(invited.Error, check.Equals, "access denied")
+}
+
+// roleIdentity is an identity service that only manages roles
+type roleIdentity struct {
+	Identity
+	roles map[string]teleservices.Role
+}
+
+func (i *roleIdentity) GetRole(name string) (teleservices.Role, error) {
+	role, ok := i.roles[name]
+	if !ok {
+		return nil, trace.NotFound("role %q not found", name)
+	}
+	return role, nil
+}
+
+func (i *roleIdentity) UpsertRole(role teleservices.Role, ttl time.Duration) error {
+	// Store a copy so the recorded state is not changed by the caller
+	var logins []string
+	logins = append(logins, role.GetLogins(teleservices.Allow)...)
+	stored, err := teleservices.NewRole(role.GetName(), teleservices.RoleSpecV3{
+		Allow: teleservices.RoleConditions{Logins: logins},
+	})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	i.roles[role.GetName()] = stored
+	return nil
+}
+
+func (i *roleIdentity) DeleteRole(name string) error {
+	delete(i.roles, name)
+	return nil
+}
+
+func (i *roleIdentity) CreateInviteToken(string, storage.UserInvite) (*storage.UserToken, error) {
+	return nil, trace.AccessDenied("access denied")
+}
+
+type recordingEmitter struct {
+	events []storage.AuditEvent
+}
+
+func (r *recordingEmitter) Emit(event storage.AuditEvent) {
+	r.events = append(r.events, event)
+}
@@ -249,10 +249,28 @@ func ConvertS3Error(err error) error {
 	switch awsErr.Code() {
 	case s3.ErrCodeNoSuchKey, s3.ErrCodeNoSuchBucket:
 		return trace.NotFound(awsErr.Message())
+	case s3ErrCodeNotFound:
+		// HEAD requests carry no response body so the error
+		// only has the generic code derived from the status
+		return trace.NotFound("object not found")
+	case s3ErrCodePreconditionFailed, s3ErrCodeConditionalRequestConflict:
+		return trace.CompareFailed("%v", awsErr.Message())
 	}
 	return err
 }
 
+const (
+	// s3ErrCodeNotFound is the error code S3 returns for HEAD requests
+	// on missing objects
+	s3ErrCodeNotFound = "NotFound"
+	// s3ErrCodePreconditionFailed is the error code S3 returns when
+	// the condition of a conditional write does not hold
+	s3ErrCodePreconditionFailed = "PreconditionFailed"
+	// s3ErrCodeConditionalRequestConflict is the error code S3 returns when
+	// a conditional write conflicts with a concurrent write of the same object
+	s3ErrCodeConditionalRequestConflict = "ConditionalRequestConflict"
+)
+
 // UnsupportedFilesystemError represents a condition when an action is being
 // performed on an unsupported filesystem, for example an attempt to create
 // a bolt database file on filesystem that does not support mmap
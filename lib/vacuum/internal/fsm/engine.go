@@ -200,6 +200,9 @@ func (r *engine) GetExecutor(params libfsm.ExecutorParams, remote libfsm.Remote)
 // RunCommand executes the phase specified by params on the specified server
 // using the provided runner
 func (r *engine) RunCommand(ctx context.Context, runner libfsm.RemoteRunner, server storage.Server, params libfsm.Params) error {
+	if params.Rollback {
+		return trace.NotImplemented("remote rollback is not supported for garbage collection operation")
+	}
 	args := []string{"plan", "execute", "--phase", params.PhaseID}
 	if params.Force {
 		args = append(args, "--force")
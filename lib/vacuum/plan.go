@@ -17,12 +17,23 @@ limitations under the License.
 package vacuum
 
 import (
+	"github.com/gravitational/gravity/lib/ops"
 	"github.com/gravitational/gravity/lib/storage"
 	"github.com/gravitational/gravity/lib/vacuum/internal/fsm"
 
 	"github.com/gravitational/trace"
 )
 
+// BuildOperationPlan returns the garbage collection plan for the specified
+// operation without storing it in the cluster
+func BuildOperationPlan(operation ops.SiteOperation, servers []storage.Server, remoteApps []storage.Application) (*storage.OperationPlan, error) {
+	plan, err := fsm.NewOperationPlan(operation, servers, remoteApps)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return plan, nil
+}
+
 func (r *Collector) getOrCreateOperationPlan() (plan *storage.OperationPlan, err error) {
 	plan, err = r.Operator.GetOperationPlan(r.Operation.Key())
 	if err != nil && !trace.IsNotFound(err) {
@@ -30,7 +41,7 @@ func (r *Collector) getOrCreateOperationPlan() (plan *storage.OperationPlan, err
 	}
 
 	if trace.IsNotFound(err) {
-		plan, err = fsm.NewOperationPlan(*r.Operation, r.Servers, r.RemoteApps)
+		plan, err = BuildOperationPlan(*r.Operation, r.Servers, r.RemoteApps)
 		if err != nil {
 			return nil, trace.Wrap(err)
 		}
@@ -21,6 +21,7 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/gravitational/gravity/lib/blob"
 	"github.com/gravitational/gravity/lib/defaults"
 	"github.com/gravitational/gravity/lib/loc"
 	"github.com/gravitational/gravity/lib/pack"
@@ -28,6 +29,7 @@ import (
 	"github.com/gravitational/gravity/lib/vacuum/prune"
 
 	"github.com/coreos/go-semver/semver"
+	"github.com/dustin/go-humanize"
 	"github.com/gravitational/trace"
 	log "github.com/sirupsen/logrus"
 )
@@ -94,7 +96,7 @@ type packageService interface {
 // that are still required, and sweeps the rest.
 // It will not remove packages from repositories other than the defaults.SystemAccountOrg
 // unless it can tell if a package is safe to remove.
-func (r *cleanup) Prune(context.Context) error {
+func (r *cleanup) Prune(ctx context.Context) error {
 	required, err := r.mark()
 	if err != nil {
 		return trace.Wrap(err)
@@ -118,6 +120,27 @@ func (r *cleanup) Prune(context.Context) error {
 		}
 	}
 
+	return trace.Wrap(r.pruneBLOBs(ctx))
+}
+
+// pruneBLOBs removes the package data no longer referenced by any package
+// if the package service shares data between packages
+func (r *cleanup) pruneBLOBs(ctx context.Context) error {
+	pruner, ok := r.Packages.(blob.Pruner)
+	if !ok {
+		return nil
+	}
+	result, err := pruner.PruneBLOBs(ctx, r.DryRun)
+	if err != nil {
+		if trace.IsNotImplemented(err) {
+			return nil
+		}
+		return trace.Wrap(err)
+	}
+	if result.Removed != 0 {
+		r.PrintStep("Reclaimed %v from %v unreferenced package data chunks.",
+			humanize.Bytes(uint64(result.ReclaimedBytes)), result.Removed)
+	}
 	return nil
 }
 
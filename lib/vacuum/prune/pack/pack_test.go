@@ -22,6 +22,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/gravitational/gravity/lib/blob"
 	"github.com/gravitational/gravity/lib/compare"
 	"github.com/gravitational/gravity/lib/defaults"
 	"github.com/gravitational/gravity/lib/loc"
@@ -171,6 +172,33 @@ func (*S) TestNoopIfDryRun(c *C) {
 	c.Assert(byLocator(allPackages), compare.SortedSliceEquals, byLocator(expected))
 }
 
+func (*S) TestPrunesUnreferencedBLOBs(c *C) {
+	// setup
+	runtimePackage := newPackage("gravitational.io/planet:0.0.1", pack.PurposeLabel, pack.PurposeRuntime)
+	app := newAppPackage("gravitational.io/app:0.0.2", storage.AppUser)
+	runtimeApp := newAppPackage("gravitational.io/runtime:0.0.1", storage.AppRuntime)
+	oldApp := newAppPackage("gravitational.io/app:0.0.1", storage.AppUser)
+
+	a, dependencies := newApp(app, runtimeApp, runtimePackage)
+	packages := &blobPackages{testPackages: append(dependencies, oldApp)}
+
+	// exercise
+	p, err := New(Config{
+		App:      a,
+		Packages: packages,
+	})
+	c.Assert(err, IsNil)
+
+	err = p.Prune(context.TODO())
+	c.Assert(err, IsNil)
+
+	// verify
+	c.Assert(byLocator(packages.testPackages), compare.SortedSliceEquals, byLocator(dependencies))
+	c.Assert(packages.pruned, Equals, 1)
+	c.Assert(packages.remainingOnPrune, Equals, len(dependencies),
+		Commentf("BLOBs should be pruned after packages have been deleted."))
+}
+
 func (*S) TestPrunesOldPlanetConfiguration(c *C) {
 	// setup
 	runtimePackage := newPackage("gravitational.io/planet:0.0.3", pack.PurposeLabel, pack.PurposeRuntime)
@@ -546,6 +574,21 @@ func labelsTo(result map[string]string, labels ...string) {
 
 type testPackages []packageEnvelope
 
+// blobPackages is a package service that shares data between packages
+type blobPackages struct {
+	testPackages
+	// pruned counts the calls to prune the data
+	pruned int
+	// remainingOnPrune is the number of packages when the data is pruned
+	remainingOnPrune int
+}
+
+func (r *blobPackages) PruneBLOBs(ctx context.Context, dryRun bool) (*blob.PruneResult, error) {
+	r.pruned++
+	r.remainingOnPrune = len(r.testPackages)
+	return &blob.PruneResult{Removed: 1, ReclaimedBytes: 1024}, nil
+}
+
 func (r packageEnvelope) String() string {
 	return r.Locator.String()
 }
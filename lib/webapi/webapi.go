@@ -31,6 +31,7 @@ import (
 
 	"github.com/gravitational/gravity/lib/app"
 	appsapi "github.com/gravitational/gravity/lib/app"
+	"github.com/gravitational/gravity/lib/audit"
 	"github.com/gravitational/gravity/lib/clients"
 	"github.com/gravitational/gravity/lib/cloudprovider/aws"
 	awsservice "github.com/gravitational/gravity/lib/cloudprovider/aws/service"
@@ -39,6 +40,7 @@ import (
 	"github.com/gravitational/gravity/lib/httplib"
 	"github.com/gravitational/gravity/lib/loc"
 	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/ops/opshandler"
 	"github.com/gravitational/gravity/lib/ops/resources"
 	"github.com/gravitational/gravity/lib/pack"
 	"github.com/gravitational/gravity/lib/schema"
@@ -102,6 +104,8 @@ type Config struct {
 	// ServiceUser specifies the service user to use to
 	// create a cluster with for wizard-based installation
 	ServiceUser systeminfo.User
+	// AuditLog records changes made through the API, optional
+	AuditLog audit.Emitter
 }
 
 // Check validates the config
@@ -234,6 +238,7 @@ func NewAPI(cfg Config) (*Handler, error) {
 	h.GET("/domains/:domain_name", h.needsAuth(h.validateDomainName))
 
 	h.GET("/sites/:domain/operations/:operation_id/progress", h.needsAuth(h.getSiteOperationProgress))
+	h.GET("/sites/:domain/operations/:operation_id/events", h.needsAuth(h.getOperationEvents))
 
 	// Operations
 	h.GET("/sites/:domain/operations/:operation_id/agent", h.needsAuth(h.agentReport))
@@ -623,7 +628,7 @@ type AuthContext struct {
 	// Checkers is access checker
 	Checker teleservices.AccessChecker
 	// Operator is the interface to operations service
-	Operator *ops.OperatorACL
+	Operator ops.Operator
 	// Applications is the interface to application management service
 	Applications appsapi.Applications
 	// Packages is the interface to package management service
@@ -656,12 +661,18 @@ func (m *Handler) GetHandlerContext(w http.ResponseWriter, r *http.Request) (*Au
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
+	var operator ops.Operator = ops.OperatorWithACL(m.cfg.Operator, m.cfg.Identity, user, checker)
+	identity := users.IdentityWithACL(m.cfg.Backend, m.cfg.Identity, user, checker)
+	if m.cfg.AuditLog != nil {
+		operator = ops.OperatorWithAudit(operator, m.cfg.AuditLog, user)
+		identity = users.IdentityWithAudit(identity, m.cfg.AuditLog, user)
+	}
 	return &AuthContext{
 		User:           user,
-		Operator:       ops.OperatorWithACL(m.cfg.Operator, m.cfg.Identity, user, checker),
+		Operator:       operator,
 		Applications:   app.ApplicationsWithACL(m.cfg.Applications, m.cfg.Identity, user, checker),
 		Packages:       pack.PackagesWithACL(m.cfg.Packages, m.cfg.Identity, user, checker),
-		Identity:       users.IdentityWithACL(m.cfg.Backend, m.cfg.Identity, user, checker),
+		Identity:       identity,
 		Checker:        checker,
 		SessionContext: session,
 	}, nil
@@ -697,7 +708,9 @@ func (m *Handler) resetUserCompleteHandle(w http.ResponseWriter, r *http.Request
 		return nil, trace.Wrap(err)
 	}
 
+	change, username := m.userTokenChange(teleservices.VerbUpdate, req.TokenID)
 	websession, err := m.cfg.Identity.ResetUserWithToken(req)
+	m.record(change, username, err)
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
@@ -720,7 +733,9 @@ func (m *Handler) inviteUserCompleteHandle(w http.ResponseWriter, r *http.Reques
 		return nil, trace.Wrap(err)
 	}
 
+	change, username := m.userTokenChange(teleservices.VerbCreate, req.TokenID)
 	websession, err := m.cfg.Identity.CreateUserWithToken(req)
+	m.record(change, username, err)
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
@@ -731,6 +746,24 @@ func (m *Handler) inviteUserCompleteHandle(w http.ResponseWriter, r *http.Reques
 	return httplib.OK(), nil
 }
 
+// userTokenChange returns the audited change of the user the specified
+// user token was issued for. The token is removed once used, so the user
+// is looked up before the action is completed
+func (m *Handler) userTokenChange(verb, tokenID string) (change *audit.Change, username string) {
+	token, err := m.cfg.Identity.GetUserToken(tokenID)
+	if err == nil {
+		username = token.User
+	}
+	return audit.NewChange(verb, teleservices.KindUser, username, ""), username
+}
+
+// record emits the audit event for the change made by the specified user
+func (m *Handler) record(change *audit.Change, username string, err error) {
+	if m.cfg.AuditLog != nil {
+		m.cfg.AuditLog.Emit(change.Event(username, nil, err))
+	}
+}
+
 // validateDomainName ensures that the specified domain name is unique
 //
 // GET /portalapi/v1/sites/:domain_name
@@ -862,6 +895,50 @@ func (m *Handler) getSiteOperationProgress(w http.ResponseWriter, r *http.Reques
 	return progressEntry, nil
 }
 
+// getOperationEvents streams progress entries, phase state changes and log lines
+// of the operation until the operation completes
+//
+// GET /sites/:domain/portalapi/v1/operations/:operation_id/events?offset=<offset>
+//
+// The events are sent as JSON messages over a web socket connection or as
+// Server-Sent Events if the request is not a web socket upgrade.
+// The stream resumes after the offset taken from the offset query parameter
+// or the Last-Event-ID header.
+//
+// Output:
+//
+// id: <offset>
+// event: progress
+// data: {"type": "progress", "offset": {...}, "progress": {...}}
+//
+func (m *Handler) getOperationEvents(w http.ResponseWriter, r *http.Request, p httprouter.Params, context *AuthContext) (interface{}, error) {
+	siteDomain, operationID := p[0].Value, p[1].Value
+	site, err := context.Operator.GetSiteByDomain(siteDomain)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+
+	offset, err := opshandler.OperationEventOffset(r)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+
+	opKey := ops.SiteOperationKey{
+		AccountID:   site.AccountID,
+		SiteDomain:  site.Domain,
+		OperationID: operationID,
+	}
+
+	stream, err := context.Operator.GetOperationEvents(r.Context(), opKey, *offset)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	defer stream.Close()
+
+	opshandler.ServeOperationEvents(w, r, stream)
+	return nil, nil
+}
+
 // agentReport provides update on the specified active operation
 //
 // GET /sites/:domain/portalapi/v1/operations/:operation_id/agent
@@ -1696,7 +1773,7 @@ func (m *Handler) getSiteEndpoints(w http.ResponseWriter, r *http.Request, p htt
 //
 //   report.tar
 func (m *Handler) getSiteReport(w http.ResponseWriter, r *http.Request, p httprouter.Params, context *AuthContext) (interface{}, error) {
-	reader, err := context.Operator.GetSiteReport(ops.SiteKey{
+	reader, err := context.Operator.GetSiteReport(ops.GetSiteReportRequest{
 		AccountID:  context.User.GetAccountID(),
 		SiteDomain: p.ByName("domain"),
 	})
@@ -0,0 +1,102 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/gravitational/gravity/lib/constants"
+	"github.com/gravitational/gravity/lib/localenv"
+	"github.com/gravitational/gravity/lib/storage"
+
+	"github.com/ghodss/yaml"
+	"github.com/gravitational/trace"
+)
+
+// listAuditEvents displays the cluster audit events recorded within
+// the specified duration, optionally filtered by user and resource kind
+func listAuditEvents(env *localenv.LocalEnvironment, since time.Duration, user, kind string, format constants.Format) error {
+	operator, err := env.SiteOperator()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	cluster, err := operator.GetLocalSite()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	filter := storage.AuditEventFilter{
+		User: user,
+		Kind: storage.CanonicalKind(kind),
+	}
+	if since != 0 {
+		filter.Since = time.Now().Add(-since).UTC()
+	}
+	events, err := operator.GetAuditEvents(cluster.Key(), filter)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return trace.Wrap(outputAuditEvents(events, format))
+}
+
+func outputAuditEvents(events []storage.AuditEvent, format constants.Format) error {
+	switch format {
+	case constants.EncodingText:
+		w := new(tabwriter.Writer)
+		w.Init(os.Stdout, 0, 8, 1, '\t', 0)
+		fmt.Fprintf(w, "Time\tUser\tVerb\tKind\tName\tResult\n")
+		fmt.Fprintf(w, "----\t----\t----\t----\t----\t------\n")
+		for _, event := range events {
+			result := event.Result
+			if event.Error != "" {
+				result = fmt.Sprintf("%v: %v", event.Result, event.Error)
+			}
+			fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\t%v\n",
+				event.Time.UTC().Format(constants.HumanDateFormatSeconds),
+				event.User, event.Verb, event.Kind, dashIfEmpty(event.Name), result)
+		}
+		return trace.Wrap(w.Flush())
+	case constants.EncodingJSON:
+		if events == nil {
+			events = []storage.AuditEvent{}
+		}
+		data, err := json.MarshalIndent(events, "", "    ")
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		fmt.Println(string(data))
+	case constants.EncodingYAML:
+		data, err := yaml.Marshal(events)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		fmt.Print(string(data))
+	default:
+		return trace.BadParameter("unknown output format %q", format)
+	}
+	return nil
+}
+
+func dashIfEmpty(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
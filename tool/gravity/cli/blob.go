@@ -0,0 +1,170 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/gravitational/gravity/lib/blob/chunked"
+	"github.com/gravitational/gravity/lib/constants"
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/helm"
+	"github.com/gravitational/gravity/lib/localenv"
+	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/process"
+	"github.com/gravitational/gravity/lib/processconfig"
+	"github.com/gravitational/gravity/lib/storage"
+
+	"github.com/dustin/go-humanize"
+	"github.com/fatih/color"
+	"github.com/gravitational/configure"
+	"github.com/gravitational/rigging"
+	"github.com/gravitational/trace"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// checkBLOBs verifies the integrity of the packages stored on the cluster
+// controller serving the request and displays the outcome of the last
+// check on the other controllers
+func checkBLOBs(env *localenv.LocalEnvironment, repair bool) error {
+	operator, err := env.SiteOperator()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	cluster, err := operator.GetLocalSite()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	env.Println("Verifying package storage, this may take a while...")
+	integrity, err := operator.CheckBLOBs(ops.CheckBLOBsRequest{
+		ClusterKey: cluster.Key(),
+		Repair:     repair,
+	})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	status, err := operator.GetBLOBStatus(cluster.Key())
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	printBLOBStatus(*status, *integrity)
+	if !integrity.IsHealthy() || !status.IsHealthy() {
+		if !repair {
+			return trace.BadParameter("package storage has problems, re-run with --repair to fix them")
+		}
+		return trace.BadParameter("package storage has problems that could not be repaired")
+	}
+	return nil
+}
+
+// dedupBLOBs converts the packages stored on this node as whole files
+// into deduplicated chunks
+func dedupBLOBs(env *localenv.LocalEnvironment) error {
+	env.Println("Converting packages into chunks, this may take a while...")
+	result, err := chunked.Convert(context.TODO(), chunked.Config{
+		Path: filepath.Join(env.StateDir, defaults.PackagesDir),
+	})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	env.Printf("Converted %v packages. Package data takes %v on disk (%v before deduplication).\n",
+		result.Converted, humanize.Bytes(uint64(result.Usage.StoredBytes)),
+		humanize.Bytes(uint64(result.Usage.SizeBytes)))
+	return nil
+}
+
+// migrateBLOBs copies the Ops Center packages stored on this node to the
+// S3 bucket configured in the Ops Center configuration.
+//
+// The configuration is read from the cluster rather than from the cluster
+// controller since the controller refuses to start with the S3 storage
+// until its packages have been copied
+func migrateBLOBs(env *localenv.LocalEnvironment) error {
+	client, _, err := helm.GetKubeClient(env.DNS.Addr())
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	configMap, err := client.CoreV1().ConfigMaps(defaults.KubeSystemNamespace).Get(
+		constants.OpsCenterConfigMap, metav1.GetOptions{})
+	if err != nil {
+		return trace.Wrap(rigging.ConvertError(err))
+	}
+	var config processconfig.Config
+	err = configure.ParseYAML([]byte(configMap.Data[defaults.GravityYAMLFile]), &config,
+		configure.EnableTemplating())
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	env.Println("Copying packages to the S3 bucket, this may take a while...")
+	result, err := process.MigrateBLOBs(context.TODO(), client, defaults.SiteGravityDir, config.Blob)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	env.Printf("Copied %v packages (%v).\n", result.Migrated, humanize.Bytes(uint64(result.SizeBytes)))
+	return nil
+}
+
+// printBLOBStatus outputs the outcome of the last integrity check on
+// each peer. The results of the current check are matched to the peer
+// by the check time since the serving peer is not known in advance
+func printBLOBStatus(status ops.BLOBStatus, current storage.BLOBIntegrity) {
+	w := new(tabwriter.Writer)
+	w.Init(os.Stdout, 0, 8, 1, '\t', 0)
+	fmt.Fprintf(w, "Peer\tAddress\tChecked\tObjects\tCorrupted\tRepaired\tReplicated\tUnder-replicated\tStatus\n")
+	fmt.Fprintf(w, "----\t-------\t-------\t-------\t---------\t--------\t----------\t----------------\t------\n")
+	for _, peer := range status.Peers {
+		integrity := peer.Integrity
+		if integrity == nil {
+			fmt.Fprintf(w, "%v\t%v\t-\t-\t-\t-\t-\t-\t%v\n", peer.ID, peer.AdvertiseAddr, "not checked yet")
+			continue
+		}
+		checked := integrity.Checked.Format(constants.HumanDateFormatSeconds)
+		if integrity.Checked.Equal(current.Checked) {
+			checked += " (now)"
+		}
+		fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\n",
+			peer.ID, peer.AdvertiseAddr, checked, integrity.Objects,
+			len(integrity.Corrupted), len(integrity.Repaired), len(integrity.Replicated),
+			len(integrity.UnderReplicated), integrityState(*integrity))
+	}
+	w.Flush()
+	printObjects("Corrupted objects", current.Corrupted)
+	printObjects("Under-replicated objects", current.UnderReplicated)
+}
+
+func integrityState(integrity storage.BLOBIntegrity) string {
+	switch {
+	case integrity.Error != "":
+		return color.RedString("error: %v", integrity.Error)
+	case !integrity.IsHealthy():
+		return color.RedString("degraded")
+	default:
+		return color.GreenString("healthy")
+	}
+}
+
+func printObjects(title string, hashes []string) {
+	if len(hashes) == 0 {
+		return
+	}
+	fmt.Printf("\n%v:\n    %v\n", title, strings.Join(hashes, "\n    "))
+}
@@ -23,6 +23,7 @@ import (
 	libfsm "github.com/gravitational/gravity/lib/fsm"
 	"github.com/gravitational/gravity/lib/localenv"
 	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/storage"
 	libclusterconfig "github.com/gravitational/gravity/lib/storage/clusterconfig"
 	"github.com/gravitational/gravity/lib/update"
 	"github.com/gravitational/gravity/lib/update/clusterconfig"
@@ -177,6 +178,24 @@ func (r configInitializer) newOperationPlan(
 	return trace.Wrap(err)
 }
 
+func (r configInitializer) newDryRunPlan(
+	ctx context.Context,
+	operator ops.Operator,
+	cluster ops.Site,
+	localEnv *localenv.LocalEnvironment,
+	clusterEnv *localenv.ClusterEnvironment,
+) (*storage.OperationPlan, error) {
+	operation := newDryRunOperation(cluster, ops.OperationUpdateConfig)
+	operation.UpdateConfig = &storage.UpdateConfigOperationState{
+		Config: r.resource,
+	}
+	plan, err := clusterconfig.BuildOperationPlan(operator, operation, r.config, cluster.ClusterState.Servers)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return plan, nil
+}
+
 func (configInitializer) newUpdater(
 	ctx context.Context,
 	operator ops.Operator,
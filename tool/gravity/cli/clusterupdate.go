@@ -30,6 +30,7 @@ import (
 	"github.com/gravitational/gravity/lib/ops"
 	"github.com/gravitational/gravity/lib/pack"
 	"github.com/gravitational/gravity/lib/schema"
+	"github.com/gravitational/gravity/lib/storage"
 	"github.com/gravitational/gravity/lib/update"
 	clusterupdate "github.com/gravitational/gravity/lib/update/cluster"
 	"github.com/gravitational/version"
@@ -57,10 +58,26 @@ func updateTrigger(
 	localEnv *localenv.LocalEnvironment,
 	updateEnv *localenv.LocalEnvironment,
 	updatePackage string,
-	manual, block, noValidateVersion bool,
+	manual, block, noValidateVersion, dryRun bool,
+	parallelism int,
+	strategy *storage.UpdateStrategy,
+	autoRollback bool,
 ) error {
 	ctx := context.TODO()
-	updater, err := newClusterUpdater(ctx, localEnv, updateEnv, updatePackage, manual, block, noValidateVersion)
+	if strategy != nil {
+		if err := strategy.Check(); err != nil {
+			return trace.Wrap(err)
+		}
+	}
+	if dryRun {
+		init := &clusterInitializer{
+			updatePackage: updatePackage,
+			strategy:      strategy,
+			autoRollback:  autoRollback,
+		}
+		return trace.Wrap(previewUpdate(ctx, localEnv, init))
+	}
+	updater, err := newClusterUpdater(ctx, localEnv, updateEnv, updatePackage, manual, block, noValidateVersion, parallelism, strategy, autoRollback)
 	if err != nil {
 		return trace.Wrap(err)
 	}
@@ -82,11 +99,17 @@ func newClusterUpdater(
 	localEnv, updateEnv *localenv.LocalEnvironment,
 	updatePackage string,
 	manual, block, noValidateVersion bool,
+	parallelism int,
+	strategy *storage.UpdateStrategy,
+	autoRollback bool,
 ) (updater, error) {
 	unattended := !manual && !block
 	init := &clusterInitializer{
 		updatePackage: updatePackage,
 		unattended:    unattended,
+		parallelism:   parallelism,
+		strategy:      strategy,
+		autoRollback:  autoRollback,
 	}
 	updater, err := newUpdater(ctx, localEnv, updateEnv, init)
 	if err != nil {
@@ -121,6 +144,24 @@ func rollbackUpdatePhase(env, updateEnv *localenv.LocalEnvironment, params Phase
 	return trace.Wrap(err)
 }
 
+// approveUpdate approves the update of the remaining nodes after
+// the canary node of the active update operation has been verified
+func approveUpdate(env, updateEnv, joinEnv *localenv.LocalEnvironment) error {
+	operation, err := getActiveOperation(env, updateEnv, joinEnv, "")
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if operation.Type != ops.OperationUpdate {
+		return trace.BadParameter("active operation %v is not an update", operation)
+	}
+	updater, err := getClusterUpdater(env, updateEnv, *operation, true)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	defer updater.Close()
+	return trace.Wrap(clusterupdate.ApproveCanary(context.TODO(), updater))
+}
+
 func completeUpdatePlan(env, updateEnv *localenv.LocalEnvironment, operation ops.SiteOperation) error {
 	updater, err := getClusterUpdater(env, updateEnv, operation, true)
 	if err != nil {
@@ -187,9 +228,12 @@ func (r *clusterInitializer) validatePreconditions(localEnv *localenv.LocalEnvir
 
 func (r clusterInitializer) newOperation(operator ops.Operator, cluster ops.Site) (*ops.SiteOperationKey, error) {
 	return operator.CreateSiteAppUpdateOperation(ops.CreateSiteAppUpdateOperationRequest{
-		AccountID:  cluster.AccountID,
-		SiteDomain: cluster.Domain,
-		App:        r.updateLoc.String(),
+		AccountID:    cluster.AccountID,
+		SiteDomain:   cluster.Domain,
+		App:          r.updateLoc.String(),
+		Strategy:     r.strategy,
+		AutoRollback: r.autoRollback,
+		Parallelism:  r.parallelism,
 	})
 }
 
@@ -207,7 +251,28 @@ func (r clusterInitializer) newOperationPlan(
 	return trace.Wrap(err)
 }
 
-func (clusterInitializer) newUpdater(
+func (r clusterInitializer) newDryRunPlan(
+	ctx context.Context,
+	operator ops.Operator,
+	cluster ops.Site,
+	localEnv *localenv.LocalEnvironment,
+	clusterEnv *localenv.ClusterEnvironment,
+) (*storage.OperationPlan, error) {
+	operation := newDryRunOperation(cluster, ops.OperationUpdate)
+	operation.Update = &storage.UpdateOperationState{
+		UpdatePackage: r.updateLoc.String(),
+		Strategy:      r.strategy,
+		AutoRollback:  r.autoRollback,
+		Parallelism:   r.parallelism,
+	}
+	plan, err := clusterupdate.BuildOperationPlan(localEnv, clusterEnv, (storage.SiteOperation)(operation))
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return plan, nil
+}
+
+func (r clusterInitializer) newUpdater(
 	ctx context.Context,
 	operator ops.Operator,
 	operation ops.SiteOperation,
@@ -222,6 +287,7 @@ func (clusterInitializer) newUpdater(
 			Backend:      clusterEnv.Backend,
 			LocalBackend: updateEnv.Backend,
 			Runner:       runner,
+			Parallelism:  r.parallelism,
 		},
 		HostLocalBackend:  localEnv.Backend,
 		HostLocalPackages: localEnv.Packages,
@@ -245,6 +311,9 @@ type clusterInitializer struct {
 	updateLoc     loc.Locator
 	updatePackage string
 	unattended    bool
+	parallelism   int
+	strategy      *storage.UpdateStrategy
+	autoRollback  bool
 }
 
 const (
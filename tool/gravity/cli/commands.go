@@ -54,6 +54,8 @@ type Application struct {
 	UserLogFile *string
 	// SystemLogFile is the path to the system log file
 	SystemLogFile *string
+	// TrustedKeys is a list of public keys to verify installer signatures with
+	TrustedKeys *[]string
 	// VersionCmd output the binary version
 	VersionCmd VersionCmd
 	// InstallCmd launches cluster installation
@@ -102,6 +104,10 @@ type Application struct {
 	BackupCmd BackupCmd
 	// RestoreCmd launches app restore hook
 	RestoreCmd RestoreCmd
+	// AuditCmd combines audit log subcommands
+	AuditCmd AuditCmd
+	// AuditListCmd displays cluster audit events
+	AuditListCmd AuditListCmd
 	// CheckCmd checks that the host satisfies app manifest requirements
 	CheckCmd CheckCmd
 	// AppCmd combines subcommands for app service
@@ -118,6 +124,10 @@ type Application struct {
 	AppUninstallCmd AppUninstallCmd
 	// AppHistoryCmd displays revision history for a release
 	AppHistoryCmd AppHistoryCmd
+	// AppMigrateReleasesCmd converts Tiller releases for the tillerless release driver
+	AppMigrateReleasesCmd AppMigrateReleasesCmd
+	// AppSetDriverCmd selects the release driver for the cluster
+	AppSetDriverCmd AppSetDriverCmd
 	// AppSyncCmd synchronizes an application image with a cluster
 	AppSyncCmd AppSyncCmd
 	// AppSearchCmd searches for applications.
@@ -250,6 +260,16 @@ type Application struct {
 	SystemHistoryCmd SystemHistoryCmd
 	// SystemStepDownCmd asks active gravity master to step down
 	SystemStepDownCmd SystemStepDownCmd
+	// SystemMigrateStorageCmd migrates cluster state to etcd v3 API
+	SystemMigrateStorageCmd SystemMigrateStorageCmd
+	// SystemBLOBCmd combines subcommands for cluster package storage
+	SystemBLOBCmd SystemBLOBCmd
+	// SystemBLOBFsckCmd verifies the integrity of cluster package storage
+	SystemBLOBFsckCmd SystemBLOBFsckCmd
+	// SystemBLOBDedupCmd converts local package storage into deduplicated chunks
+	SystemBLOBDedupCmd SystemBLOBDedupCmd
+	// SystemBLOBMigrateCmd copies local package storage to the S3 package storage
+	SystemBLOBMigrateCmd SystemBLOBMigrateCmd
 	// SystemRollbackCmd rolls back last system update
 	SystemRollbackCmd SystemRollbackCmd
 	// SystemServiceCmd combines subcommands for systems services
@@ -384,6 +404,8 @@ type InstallCmd struct {
 	Resume *bool
 	// Manual puts install operation in manual mode
 	Manual *bool
+	// Parallelism is the maximum number of plan phases to execute concurrently
+	Parallelism *int
 	// ServiceUID is system user ID
 	ServiceUID *string
 	// ServiceGID is system user group ID
@@ -566,6 +588,10 @@ type UpdateTriggerCmd struct {
 	Block *bool
 	// SkipVersionCheck suppresses version mismatch errors
 	SkipVersionCheck *bool
+	// Parallelism is the maximum number of plan phases to execute concurrently
+	Parallelism *int
+	// DryRun displays the operation plan without creating the operation
+	DryRun *bool
 }
 
 // UpdateUploadCmd uploads new app version to local cluster
@@ -620,6 +646,37 @@ type UpgradeCmd struct {
 	Resume *bool
 	// SkipVersionCheck suppresses version mismatch errors
 	SkipVersionCheck *bool
+	// Parallelism is the maximum number of plan phases to execute concurrently
+	Parallelism *int
+	// DryRun displays the operation plan without creating the operation
+	DryRun *bool
+	// BatchSize is the number of regular nodes to update at once
+	BatchSize *int
+	// MaxUnavailable is the maximum number of regular nodes unavailable at once
+	MaxUnavailable *int
+	// Canary updates and verifies a single regular node before the rest
+	Canary *bool
+	// CanaryPause is how long to wait after the canary node before continuing
+	CanaryPause *time.Duration
+	// Approve approves the update of the remaining nodes after the canary node
+	Approve *bool
+	// AutoRollback rolls back the update automatically if it fails
+	AutoRollback *bool
+	// Check displays the compatibility report without starting the operation
+	Check *bool
+}
+
+// updateStrategy returns the update strategy if any of the strategy flags have been specified
+func (r UpgradeCmd) updateStrategy() *storage.UpdateStrategy {
+	if *r.BatchSize == 0 && *r.MaxUnavailable == 0 && !*r.Canary && *r.CanaryPause == 0 {
+		return nil
+	}
+	return &storage.UpdateStrategy{
+		BatchSize:      *r.BatchSize,
+		MaxUnavailable: *r.MaxUnavailable,
+		Canary:         *r.Canary,
+		CanaryPause:    *r.CanaryPause,
+	}
 }
 
 // StatusCmd displays cluster status
@@ -653,6 +710,24 @@ type BackupCmd struct {
 	Follow *bool
 }
 
+// AuditCmd combines audit log subcommands
+type AuditCmd struct {
+	*kingpin.CmdClause
+}
+
+// AuditListCmd displays cluster audit events
+type AuditListCmd struct {
+	*kingpin.CmdClause
+	// Since displays events within the specified duration
+	Since *time.Duration
+	// User displays events of the specified user
+	User *string
+	// Kind displays events for the specified resource kind
+	Kind *string
+	// Output is the output format
+	Output *constants.Format
+}
+
 // RestoreCmd launches app restore hook
 type RestoreCmd struct {
 	*kingpin.CmdClause
@@ -754,6 +829,23 @@ type AppHistoryCmd struct {
 	Release *string
 }
 
+// AppMigrateReleasesCmd converts releases kept by Tiller into Secrets
+// and switches the cluster to the tillerless release driver.
+type AppMigrateReleasesCmd struct {
+	*kingpin.CmdClause
+	// Cleanup removes Tiller releases once they have been converted.
+	Cleanup *bool
+	// DryRun only displays the releases that would be converted.
+	DryRun *bool
+}
+
+// AppSetDriverCmd selects the release driver for the cluster.
+type AppSetDriverCmd struct {
+	*kingpin.CmdClause
+	// Driver is the release driver to select.
+	Driver *string
+}
+
 // AppSyncCmd synchronizes an application image with a cluster.
 type AppSyncCmd struct {
 	*kingpin.CmdClause
@@ -1205,6 +1297,10 @@ type ReportCmd struct {
 	*kingpin.CmdClause
 	// FilePath is the report tarball path
 	FilePath *string
+	// Redact removes sensitive data from the report
+	Redact *bool
+	// RedactPatterns lists additional regular expressions of data to redact
+	RedactPatterns *[]string
 }
 
 // SiteCmd combines cluster related subcommands
@@ -1299,6 +1395,8 @@ type RPCAgentRunCmd struct {
 	*kingpin.CmdClause
 	// Args is additional arguments to the agent
 	Args *[]string
+	// MetricsAddr is an optional address to serve Prometheus metrics on
+	MetricsAddr *string
 }
 
 // SystemCmd combines system subcommands
@@ -1376,6 +1474,36 @@ type SystemStepDownCmd struct {
 	*kingpin.CmdClause
 }
 
+// SystemMigrateStorageCmd migrates cluster state to etcd v3 API
+type SystemMigrateStorageCmd struct {
+	*kingpin.CmdClause
+	// GracePeriod is the maximum time to wait for running processes to acknowledge each migration step
+	GracePeriod *time.Duration
+}
+
+// SystemBLOBCmd combines subcommands for cluster package storage
+type SystemBLOBCmd struct {
+	*kingpin.CmdClause
+}
+
+// SystemBLOBFsckCmd verifies the integrity of cluster package storage
+type SystemBLOBFsckCmd struct {
+	*kingpin.CmdClause
+	// Repair sets aside and fetches again the corrupted packages
+	// and replicates the packages stored on too few peers
+	Repair *bool
+}
+
+// SystemBLOBDedupCmd converts local package storage into deduplicated chunks
+type SystemBLOBDedupCmd struct {
+	*kingpin.CmdClause
+}
+
+// SystemBLOBMigrateCmd copies local package storage to the S3 package storage
+type SystemBLOBMigrateCmd struct {
+	*kingpin.CmdClause
+}
+
 // SystemRollbackCmd rolls back last system update
 type SystemRollbackCmd struct {
 	*kingpin.CmdClause
@@ -1451,6 +1579,10 @@ type SystemReportCmd struct {
 	Filter *[]string
 	// Compressed allows to gzip the tarball
 	Compressed *bool
+	// Redact removes sensitive data from the report
+	Redact *bool
+	// RedactPatterns lists additional regular expressions of data to redact
+	RedactPatterns *[]string
 }
 
 // SystemStateDirCmd shows local state directory
@@ -1547,6 +1679,8 @@ type GarbageCollectCmd struct {
 	// Confirmed is whether the user has confirmed the removal of custom docker
 	// images
 	Confirmed *bool
+	// DryRun displays the operation plan without creating the operation
+	DryRun *bool
 }
 
 // GarbageCollectPlanCmd displays the plan of the garbage collection operation
@@ -1616,6 +1750,9 @@ type ResourceCreateCmd struct {
 	Manual *bool
 	// Confirmed suppresses confirmation prompt
 	Confirmed *bool
+	// DryRun displays the plan of the operation that would update
+	// the resource without creating the operation
+	DryRun *bool
 }
 
 // ResourceRemoveCmd removes specified resource
@@ -94,6 +94,8 @@ type InstallConfig struct {
 	Docker storage.DockerConfig
 	// Manual allows to execute install plan phases manually
 	Manual bool
+	// Parallelism is the maximum number of plan phases to execute concurrently
+	Parallelism int
 	// AppPackage is the application package to install
 	AppPackage string
 	// ServiceUser is the service user configuration
@@ -110,6 +112,9 @@ type InstallConfig struct {
 	NodeTags []string
 	// NewProcess is used to launch gravity API server process
 	NewProcess process.NewGravityProcess
+	// TrustedKeys is a list of additional public keys to verify
+	// the installer signature with
+	TrustedKeys []string
 }
 
 // NewInstallConfig creates install config from the passed CLI args and flags
@@ -147,11 +152,13 @@ func NewInstallConfig(g *Application) InstallConfig {
 			StorageDriver: g.InstallCmd.DockerStorageDriver.value,
 			Args:          *g.InstallCmd.DockerArgs,
 		},
-		DNSConfig:  g.InstallCmd.DNSConfig(),
-		Manual:     *g.InstallCmd.Manual,
-		ServiceUID: *g.InstallCmd.ServiceUID,
-		ServiceGID: *g.InstallCmd.ServiceGID,
-		NodeTags:   *g.InstallCmd.GCENodeTags,
+		DNSConfig:   g.InstallCmd.DNSConfig(),
+		Manual:      *g.InstallCmd.Manual,
+		Parallelism: *g.InstallCmd.Parallelism,
+		ServiceUID:  *g.InstallCmd.ServiceUID,
+		ServiceGID:  *g.InstallCmd.ServiceGID,
+		NodeTags:    *g.InstallCmd.GCENodeTags,
+		TrustedKeys: *g.TrustedKeys,
 	}
 }
 
@@ -345,6 +352,7 @@ func (i *InstallConfig) ToInstallerConfig(env *localenv.LocalEnvironment) (*inst
 		Docker:             i.Docker,
 		Insecure:           i.Insecure,
 		Manual:             i.Manual,
+		Parallelism:        i.Parallelism,
 		ServiceUser:        i.ServiceUser,
 		GCENodeTags:        i.NodeTags,
 		NewProcess:         i.NewProcess,
@@ -190,6 +190,24 @@ func (environInitializer) newOperationPlan(
 	return trace.Wrap(err)
 }
 
+func (r environInitializer) newDryRunPlan(
+	ctx context.Context,
+	operator ops.Operator,
+	cluster ops.Site,
+	localEnv *localenv.LocalEnvironment,
+	clusterEnv *localenv.ClusterEnvironment,
+) (*storage.OperationPlan, error) {
+	operation := newDryRunOperation(cluster, ops.OperationUpdateRuntimeEnviron)
+	operation.UpdateEnviron = &storage.UpdateEnvarsOperationState{
+		Env: r.environ.GetKeyValues(),
+	}
+	plan, err := environ.BuildOperationPlan(operator, operation, cluster.ClusterState.Servers)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return plan, nil
+}
+
 func (environInitializer) newUpdater(
 	ctx context.Context,
 	operator ops.Operator,
@@ -37,7 +37,10 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
-func garbageCollect(env *localenv.LocalEnvironment, manual, confirmed bool) error {
+func garbageCollect(env *localenv.LocalEnvironment, manual, confirmed, dryRun bool) error {
+	if dryRun {
+		return trace.Wrap(previewGarbageCollect(env))
+	}
 	if !confirmed {
 		env.Println("This operation will also remove docker images that " +
 			"you manually pushed to the docker registry. Are you sure?")
@@ -84,6 +87,29 @@ $ gravity plan resume`)
 	return nil
 }
 
+// previewGarbageCollect displays the plan of the garbage collection operation
+// without creating the operation
+func previewGarbageCollect(env *localenv.LocalEnvironment) error {
+	operator, err := env.SiteOperator()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	cluster, err := operator.GetLocalSite()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	remoteApps, err := collectRemoteApplications(operator, cluster.Key())
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	operation := newDryRunOperation(*cluster, ops.OperationGarbageCollect)
+	plan, err := vacuum.BuildOperationPlan(operation, cluster.ClusterState.Servers, remoteApps)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return trace.Wrap(outputDryRunPlan(env, *plan))
+}
+
 func newCollector(env *localenv.LocalEnvironment) (*vacuum.Collector, error) {
 	clusterPackages, err := env.ClusterPackages()
 	if err != nil {
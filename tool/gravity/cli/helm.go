@@ -51,6 +51,9 @@ type releaseInstallConfig struct {
 	valuesConfig
 	// registryConfig is registry configuration.
 	registryConfig
+	// TrustedKeys is a list of additional public keys to verify
+	// the image signature with.
+	TrustedKeys []string
 }
 
 func (c *releaseInstallConfig) setDefaults(env *localenv.LocalEnvironment) error {
@@ -70,6 +73,9 @@ type releaseUpgradeConfig struct {
 	valuesConfig
 	// registryConfig is registry configuration.
 	registryConfig
+	// TrustedKeys is a list of additional public keys to verify
+	// the image signature with.
+	TrustedKeys []string
 }
 
 func (c *releaseUpgradeConfig) setDefaults(env *localenv.LocalEnvironment) error {
@@ -147,6 +153,7 @@ func releaseInstall(env *localenv.LocalEnvironment, conf releaseInstallConfig) e
 	err = appSyncEnv(env, imageEnv, appSyncConfig{
 		Image:          conf.Image,
 		registryConfig: conf.registryConfig,
+		TrustedKeys:    conf.TrustedKeys,
 	})
 	if err != nil {
 		return trace.Wrap(err)
@@ -250,6 +257,7 @@ func releaseUpgrade(env *localenv.LocalEnvironment, conf releaseUpgradeConfig) e
 	err = appSyncEnv(env, imageEnv, appSyncConfig{
 		Image:          conf.Image,
 		registryConfig: conf.registryConfig,
+		TrustedKeys:    conf.TrustedKeys,
 	})
 	if err != nil {
 		return trace.Wrap(err)
@@ -343,6 +351,52 @@ func releaseHistory(env *localenv.LocalEnvironment, conf releaseHistoryConfig) e
 	return nil
 }
 
+// releaseMigrate converts releases kept by Tiller into Secrets in the
+// release namespace and switches the cluster to the tillerless driver
+func releaseMigrate(env *localenv.LocalEnvironment, cleanup, dryRun bool) error {
+	client, _, err := helm.GetKubeClient(env.DNS.Addr())
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	result, err := helm.MigrateReleases(helm.MigrateConfig{
+		Client:  client,
+		Cleanup: cleanup,
+		DryRun:  dryRun,
+	})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	w := new(tabwriter.Writer)
+	w.Init(os.Stdout, 0, 8, 1, '\t', 0)
+	fmt.Fprintf(w, "Release\tStatus\tChart\tRevision\tNamespace\n")
+	fmt.Fprintf(w, "-------\t------\t-----\t--------\t---------\n")
+	for _, r := range result.Releases {
+		fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\n",
+			r.Name, r.Status, r.Chart, r.Revision, r.Namespace)
+	}
+	w.Flush()
+	if dryRun {
+		env.PrintStep("%v revisions would be converted", result.Converted)
+		return nil
+	}
+	env.PrintStep("Converted %v revisions, %v had already been converted", result.Converted, result.Skipped)
+	env.PrintStep("Releases are now managed without Tiller")
+	return nil
+}
+
+// releaseSetDriver selects the release driver for the cluster
+func releaseSetDriver(env *localenv.LocalEnvironment, driver string) error {
+	client, _, err := helm.GetKubeClient(env.DNS.Addr())
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if err := helm.SetDriver(client, driver); err != nil {
+		return trace.Wrap(err)
+	}
+	env.PrintStep("Releases are now managed by the %v driver", driver)
+	return nil
+}
+
 func appSearch(env *localenv.LocalEnvironment, pattern string, remoteOnly, all bool) error {
 	result, err := catalog.Search(catalog.SearchRequest{
 		Pattern: pattern,
@@ -412,8 +466,7 @@ func appIndex(env *localenv.LocalEnvironment, mergeInto string) error {
 		if err != nil {
 			return trace.ConvertSystemError(err)
 		}
-		mergeIndexFile.Merge(indexFile)
-		mergeIndexFile.SortEntries()
+		helm.MergeIndexFile(mergeIndexFile, indexFile, false)
 		indexFile = mergeIndexFile
 	}
 	bytes, err := yaml.Marshal(indexFile)
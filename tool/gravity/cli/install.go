@@ -23,7 +23,9 @@ import (
 	"strings"
 	"time"
 
+	"github.com/gravitational/gravity/lib/autoscale"
 	autoscaleaws "github.com/gravitational/gravity/lib/autoscale/aws"
+	autoscalegce "github.com/gravitational/gravity/lib/autoscale/gce"
 	cloudaws "github.com/gravitational/gravity/lib/cloudprovider/aws"
 	"github.com/gravitational/gravity/lib/defaults"
 	"github.com/gravitational/gravity/lib/expand"
@@ -41,6 +43,7 @@ import (
 	"github.com/gravitational/gravity/lib/systemservice"
 	"github.com/gravitational/gravity/lib/utils"
 
+	"cloud.google.com/go/compute/metadata"
 	"github.com/gravitational/configure"
 	"github.com/gravitational/trace"
 	"github.com/sirupsen/logrus"
@@ -59,6 +62,11 @@ func startInstall(env *localenv.LocalEnvironment, i InstallConfig) error {
 		return trace.Wrap(err)
 	}
 
+	err = verifyInstallerDir(env, i.ReadStateDir, i.TrustedKeys)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
 	if i.ResourcesPath != "" {
 		err = i.ValidateResources(resources.ValidateFunc(gravity.Validate))
 		if err != nil {
@@ -284,36 +292,19 @@ func autojoin(env, joinEnv *localenv.LocalEnvironment, d autojoinConfig) error {
 		return trace.Wrap(err)
 	}
 
-	instance, err := cloudaws.NewLocalInstance()
-	if err != nil {
-		return trace.BadParameter("autojoin only supports AWS")
-	}
-
-	autoscaler, err := autoscaleaws.New(autoscaleaws.Config{
-		ClusterName: d.clusterName,
-	})
-	if err != nil {
-		return trace.Wrap(err)
-	}
-
-	joinToken, err := autoscaler.GetJoinToken(context.TODO())
-	if err != nil {
-		return trace.Wrap(err)
-	}
-
-	serviceURL, err := autoscaler.GetServiceURL(context.TODO())
+	advertiseAddr, discovery, err := getAutojoinDiscovery(d.clusterName)
 	if err != nil {
 		return trace.Wrap(err)
 	}
 
-	fmt.Printf("auto joining to cluster %q via %v\n", d.clusterName, serviceURL)
+	fmt.Printf("auto joining to cluster %q via %v\n", d.clusterName, discovery.ServiceURL)
 
 	return Join(env, joinEnv, JoinConfig{
 		SystemLogFile: d.systemLogFile,
 		UserLogFile:   d.userLogFile,
-		AdvertiseAddr: instance.PrivateIP,
-		PeerAddrs:     serviceURL,
-		Token:         joinToken,
+		AdvertiseAddr: advertiseAddr,
+		PeerAddrs:     discovery.ServiceURL,
+		Token:         discovery.Token,
 		Role:          d.role,
 		SystemDevice:  d.systemDevice,
 		DockerDevice:  d.dockerDevice,
@@ -321,6 +312,44 @@ func autojoin(env, joinEnv *localenv.LocalEnvironment, d autojoinConfig) error {
 	})
 }
 
+// getAutojoinDiscovery returns the advertise address of this node and
+// the discovery information of the cluster published by its autoscaler
+func getAutojoinDiscovery(clusterName string) (advertiseAddr string, discovery *autoscale.Discovery, err error) {
+	instance, err := cloudaws.NewLocalInstance()
+	if err == nil {
+		autoscaler, err := autoscaleaws.New(autoscaleaws.Config{
+			ClusterName: clusterName,
+		})
+		if err != nil {
+			return "", nil, trace.Wrap(err)
+		}
+		joinToken, err := autoscaler.GetJoinToken(context.TODO())
+		if err != nil {
+			return "", nil, trace.Wrap(err)
+		}
+		serviceURL, err := autoscaler.GetServiceURL(context.TODO())
+		if err != nil {
+			return "", nil, trace.Wrap(err)
+		}
+		return instance.PrivateIP, &autoscale.Discovery{
+			ServiceURL: serviceURL,
+			Token:      joinToken,
+		}, nil
+	}
+	if !metadata.OnGCE() {
+		return "", nil, trace.BadParameter("autojoin only supports AWS and GCE")
+	}
+	advertiseAddr, err = metadata.InternalIP()
+	if err != nil {
+		return "", nil, trace.Wrap(err)
+	}
+	discovery, err = autoscalegce.GetDiscovery(context.TODO(), clusterName)
+	if err != nil {
+		return "", nil, trace.Wrap(err)
+	}
+	return advertiseAddr, discovery, nil
+}
+
 func (r *agentConfig) checkAndSetDefaults() (err error) {
 	if r.serviceUID == "" {
 		return trace.BadParameter("service user ID is required")
@@ -86,7 +86,7 @@ func appPackage(env *localenv.LocalEnvironment) error {
 	return nil
 }
 
-func uploadUpdate(env *localenv.LocalEnvironment, opsURL string) error {
+func uploadUpdate(env *localenv.LocalEnvironment, opsURL string, trustedKeys []string) error {
 	// create local environment with gravity state dir because the environment
 	// provided above has upgrade tarball as a state dir
 	localStateDir, err := localenv.LocalGravityDir()
@@ -118,6 +118,11 @@ func uploadUpdate(env *localenv.LocalEnvironment, opsURL string) error {
 			"attempting again.")
 	}
 
+	err = verifySignature(env, env.StateDir, env.Packages, trustedKeys)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
 	var tarballPackages pack.PackageService = env.Packages
 	if cluster.License != nil {
 		parsed, err := license.ParseLicense(cluster.License.Raw)
@@ -149,6 +149,13 @@ func displayExpandOperationPlan(joinEnv *localenv.LocalEnvironment, opKey ops.Si
 	return outputPlan(*plan, format)
 }
 
+// outputDryRunPlan displays the plan of an operation that has not been created
+func outputDryRunPlan(env *localenv.LocalEnvironment, plan storage.OperationPlan) error {
+	env.Printf("Operation plan (dry run, no changes have been made to the cluster):\n\n")
+	fsm.FormatOperationPlanText(os.Stdout, plan)
+	return nil
+}
+
 func outputPlan(plan storage.OperationPlan, format constants.Format) (err error) {
 	switch format {
 	case constants.EncodingYAML:
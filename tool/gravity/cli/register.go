@@ -24,6 +24,7 @@ import (
 
 	"github.com/gravitational/gravity/lib/constants"
 	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/helm"
 	"github.com/gravitational/gravity/lib/loc"
 	"github.com/gravitational/gravity/lib/modules"
 	"github.com/gravitational/gravity/lib/schema"
@@ -50,6 +51,7 @@ func RegisterCommands(app *kingpin.Application) *Application {
 	g.ProfileTo = g.Flag("profile-dir", "store periodic state snapshots in the specified directory").Default("").Hidden().String()
 	g.UserLogFile = g.Flag("log-file", "log file with diagnostic information").Default(defaults.GravityUserLog).String()
 	g.SystemLogFile = g.Flag("system-log-file", "log file with system level logs").Default(defaults.GravitySystemLog).Hidden().String()
+	g.TrustedKeys = g.Flag("trusted-key", fmt.Sprintf("Path to a PEM-encoded public key to verify installer signatures with, in addition to the keys in %v. Can be repeated", defaults.TrustedKeysDir)).Strings()
 
 	g.VersionCmd.CmdClause = g.Command("version", "Print gravity version")
 	g.VersionCmd.Output = common.Format(g.VersionCmd.Flag("output", "Output format, text or json").Short('o').Default(string(constants.EncodingText)))
@@ -84,6 +86,7 @@ func RegisterCommands(app *kingpin.Application) *Application {
 	g.InstallCmd.Force = g.InstallCmd.Flag("force", "Force phase execution").Bool()
 	g.InstallCmd.Resume = g.InstallCmd.Flag("resume", "Resume installation from last failed step").Bool()
 	g.InstallCmd.Manual = g.InstallCmd.Flag("manual", "Manually execute install operation phases").Bool()
+	g.InstallCmd.Parallelism = g.InstallCmd.Flag("parallel", "Maximum number of independent install operation phases to execute concurrently. Phases are executed sequentially by default").Int()
 	g.InstallCmd.ServiceUID = g.InstallCmd.Flag("service-uid",
 		fmt.Sprintf("Service user ID for planet. %q user will created and used if none specified", defaults.ServiceUser)).
 		Default(defaults.ServiceUserID).
@@ -168,6 +171,8 @@ func RegisterCommands(app *kingpin.Application) *Application {
 		Default("true").
 		Bool()
 	g.UpdateTriggerCmd.SkipVersionCheck = g.UpdateTriggerCmd.Flag("skip-version-check", "Bypass version compatibility check").Hidden().Bool()
+	g.UpdateTriggerCmd.Parallelism = g.UpdateTriggerCmd.Flag("parallel", "Maximum number of independent update operation phases to execute concurrently. Phases are executed sequentially by default").Int()
+	g.UpdateTriggerCmd.DryRun = g.UpdateTriggerCmd.Flag("dry-run", "Display the operation plan without starting the operation").Bool()
 
 	g.UpdatePlanInitCmd.CmdClause = g.UpdateCmd.Command("init-plan", "Initialize operation plan").Hidden()
 
@@ -184,6 +189,15 @@ func RegisterCommands(app *kingpin.Application) *Application {
 	g.UpgradeCmd.Force = g.UpgradeCmd.Flag("force", "Force phase execution even if pre-conditions are not satisfied").Bool()
 	g.UpgradeCmd.Resume = g.UpgradeCmd.Flag("resume", "Resume upgrade from the last failed step").Bool()
 	g.UpgradeCmd.SkipVersionCheck = g.UpgradeCmd.Flag("skip-version-check", "Bypass version compatibility check").Hidden().Bool()
+	g.UpgradeCmd.Parallelism = g.UpgradeCmd.Flag("parallel", "Maximum number of independent update operation phases to execute concurrently. Phases are executed sequentially by default").Int()
+	g.UpgradeCmd.DryRun = g.UpgradeCmd.Flag("dry-run", "Display the operation plan without starting the operation").Bool()
+	g.UpgradeCmd.BatchSize = g.UpgradeCmd.Flag("batch-size", "Number of regular nodes to update at once").Int()
+	g.UpgradeCmd.MaxUnavailable = g.UpgradeCmd.Flag("max-unavailable", "Maximum number of regular nodes that can be unavailable during the update at once").Int()
+	g.UpgradeCmd.Canary = g.UpgradeCmd.Flag("canary", "Update and verify a single regular node before updating the rest").Bool()
+	g.UpgradeCmd.CanaryPause = g.UpgradeCmd.Flag("canary-pause", "Continue the update automatically after the specified duration once the canary node has been verified. Requires approval with --approve if unspecified").Duration()
+	g.UpgradeCmd.AutoRollback = g.UpgradeCmd.Flag("auto-rollback", "Automatically roll back the update if it fails or the application status check fails after the update").Bool()
+	g.UpgradeCmd.Approve = g.UpgradeCmd.Flag("approve", "Approve the update of the remaining nodes after the canary node and resume the operation").Bool()
+	g.UpgradeCmd.Check = g.UpgradeCmd.Flag("check", "Check the compatibility of the update with the cluster and display the blockers and warnings without starting the operation").Bool()
 
 	g.UpdateUploadCmd.CmdClause = g.UpdateCmd.Command("upload", "Upload update package to locally running site").Hidden()
 	g.UpdateUploadCmd.OpsCenterURL = g.UpdateUploadCmd.Flag("ops-url", "Optional OpsCenter URL to upload new packages to (defaults to local gravity site)").Default(defaults.GravityServiceURL).String()
@@ -224,6 +238,14 @@ func RegisterCommands(app *kingpin.Application) *Application {
 	g.RestoreCmd.CmdClause = g.Command("restore", "Restore state of the local application from a previously taken backup")
 	g.RestoreCmd.Tarball = g.RestoreCmd.Arg("from", "Tarball with backup data to restore from").Required().String()
 	g.RestoreCmd.Follow = g.RestoreCmd.Flag("follow", "Output restore job logs to the stdout").Bool()
+
+	// audit log
+	g.AuditCmd.CmdClause = g.Command("audit", "Operations with the cluster audit log")
+	g.AuditListCmd.CmdClause = g.AuditCmd.Command("ls", "Display changes made to the cluster").Alias("list")
+	g.AuditListCmd.Since = g.AuditListCmd.Flag("since", "Display events within the specified duration, e.g. 1h or 72h").Default(defaults.AuditEventsSince.String()).Duration()
+	g.AuditListCmd.User = g.AuditListCmd.Flag("user", "Display events of the specified user").String()
+	g.AuditListCmd.Kind = g.AuditListCmd.Flag("kind", "Display events for the specified resource kind, e.g. user or smtp").String()
+	g.AuditListCmd.Output = common.Format(g.AuditListCmd.Flag("output", "Output format: text, json or yaml").Short('o').Default(string(constants.EncodingText)))
 	g.RestoreCmd.Timeout = g.RestoreCmd.Flag("timeout", fmt.Sprintf("Maximum time a restore job is active. Defaults to the value from the manifest or %v if unspecified", defaults.HookJobDeadline)).Duration()
 
 	// operations on gravity applications
@@ -264,6 +286,13 @@ func RegisterCommands(app *kingpin.Application) *Application {
 	g.AppHistoryCmd.CmdClause = g.AppCmd.Command("history", "Display revision history for a release.")
 	g.AppHistoryCmd.Release = g.AppHistoryCmd.Arg("release", "Release name to display revisions for.").Required().String()
 
+	g.AppMigrateReleasesCmd.CmdClause = g.AppCmd.Command("migrate-releases", "Convert releases kept by Tiller and switch the cluster to managing releases without Tiller.")
+	g.AppMigrateReleasesCmd.Cleanup = g.AppMigrateReleasesCmd.Flag("cleanup", "Remove Tiller releases once they have been converted.").Bool()
+	g.AppMigrateReleasesCmd.DryRun = g.AppMigrateReleasesCmd.Flag("dry-run", "Only display the releases that would be converted.").Bool()
+
+	g.AppSetDriverCmd.CmdClause = g.AppCmd.Command("set-driver", "Select the release driver for the cluster.")
+	g.AppSetDriverCmd.Driver = g.AppSetDriverCmd.Arg("driver", fmt.Sprintf("Release driver, one of %v.", helm.Drivers)).Required().Enum(helm.Drivers...)
+
 	g.AppSyncCmd.CmdClause = g.AppCmd.Command("sync", "Synchronize an application image with a cluster.")
 	g.AppSyncCmd.Image = g.AppSyncCmd.Arg("image", "Specifies application image to install. Can be an image tarball, an unpacked image tarball, or an image name in the form of <name>:<version>.").Required().String()
 	g.AppSyncCmd.Registry = g.AppSyncCmd.Flag("registry", "Address of Docker registry to push application images to.").String()
@@ -509,6 +538,8 @@ func RegisterCommands(app *kingpin.Application) *Application {
 	// get cluster diagnostics report
 	g.ReportCmd.CmdClause = g.Command("report", "Generate cluster diagnostics report")
 	g.ReportCmd.FilePath = g.ReportCmd.Flag("file", "target report file name").Default("report.tar.gz").String()
+	g.ReportCmd.Redact = g.ReportCmd.Flag("redact", "remove tokens, private keys, passwords and other sensitive data from the report").Bool()
+	g.ReportCmd.RedactPatterns = g.ReportCmd.Flag("redact-pattern", "additional regular expression of data to remove from the report, can be repeated, implies --redact").Strings()
 
 	// operations on sites
 	g.SiteCmd.CmdClause = g.Command("site", "operations on gravity sites")
@@ -558,6 +589,7 @@ func RegisterCommands(app *kingpin.Application) *Application {
 
 	g.RPCAgentRunCmd.CmdClause = g.RPCAgentCmd.Command("run", "run RPC agent").Hidden()
 	g.RPCAgentRunCmd.Args = g.RPCAgentRunCmd.Arg("arg", "additional arguments").Strings()
+	g.RPCAgentRunCmd.MetricsAddr = g.RPCAgentRunCmd.Flag("metrics-addr", "Optional address to serve Prometheus metrics on").String()
 
 	g.SystemCmd.CmdClause = g.Command("system", "operations on system components")
 
@@ -593,6 +625,17 @@ func RegisterCommands(app *kingpin.Application) *Application {
 	// ask the current active master to step down
 	g.SystemStepDownCmd.CmdClause = g.SystemCmd.Command("step-down", "Ask the active master to step down").Hidden()
 
+	// migrate cluster state to etcd v3 API
+	g.SystemMigrateStorageCmd.CmdClause = g.SystemCmd.Command("migrate-storage", "Migrate cluster state to etcd v3 API without downtime").Hidden()
+	g.SystemMigrateStorageCmd.GracePeriod = g.SystemMigrateStorageCmd.Flag("grace-period", "Maximum time to wait for running processes to acknowledge each migration step").Default(defaults.StorageMigrationGracePeriod.String()).Duration()
+
+	// cluster package storage
+	g.SystemBLOBCmd.CmdClause = g.SystemCmd.Command("blob", "Operations on cluster package storage")
+	g.SystemBLOBFsckCmd.CmdClause = g.SystemBLOBCmd.Command("fsck", "Verify the integrity of packages stored on a cluster controller")
+	g.SystemBLOBFsckCmd.Repair = g.SystemBLOBFsckCmd.Flag("repair", "Fetch corrupted packages from other controllers and replicate under-replicated packages").Bool()
+	g.SystemBLOBDedupCmd.CmdClause = g.SystemBLOBCmd.Command("dedup", "Convert packages stored on this node into deduplicated chunks")
+	g.SystemBLOBMigrateCmd.CmdClause = g.SystemBLOBCmd.Command("migrate", "Copy packages stored on this node to the S3 package storage of the Ops Center")
+
 	g.SystemRollbackCmd.CmdClause = g.SystemCmd.Command("rollback", "starts rollback").Hidden()
 	g.SystemRollbackCmd.ChangesetID = g.SystemRollbackCmd.Flag("changeset-id", "optionally select changeset id to rollback to").String()
 	g.SystemRollbackCmd.ServiceName = g.SystemRollbackCmd.Flag("service-name", "setting service name starts upgrade as a system service instead of foreground process").String()
@@ -632,6 +675,8 @@ func RegisterCommands(app *kingpin.Application) *Application {
 	g.SystemReportCmd.CmdClause = g.SystemCmd.Command("report", "collect system diagnostics and output as gzipped tarball to terminal").Hidden()
 	g.SystemReportCmd.Filter = g.SystemReportCmd.Flag("filter", "collect only specific diagnostics ('system', 'kubernetes'). Collect everything if unspecified").Strings()
 	g.SystemReportCmd.Compressed = g.SystemReportCmd.Flag("compressed", "whether to compress the tarball").Default("true").Bool()
+	g.SystemReportCmd.Redact = g.SystemReportCmd.Flag("redact", "remove sensitive data from the report").Bool()
+	g.SystemReportCmd.RedactPatterns = g.SystemReportCmd.Flag("redact-pattern", "additional regular expression of data to remove from the report, implies --redact").Strings()
 
 	g.SystemStateDirCmd.CmdClause = g.SystemCmd.Command("state-dir", "show where all gravity data is stored on the node").Hidden()
 
@@ -658,6 +703,7 @@ func RegisterCommands(app *kingpin.Application) *Application {
 	g.GarbageCollectCmd.CmdClause = g.Command("gc", "Prune cluster resources")
 	g.GarbageCollectCmd.Manual = g.GarbageCollectCmd.Flag("manual", "Do not start the operation automatically").Short('m').Bool()
 	g.GarbageCollectCmd.Confirmed = g.GarbageCollectCmd.Flag("confirm", "Confirm to remove unrelated docker images").Short('c').Bool()
+	g.GarbageCollectCmd.DryRun = g.GarbageCollectCmd.Flag("dry-run", "Display the operation plan without starting the operation").Bool()
 
 	// system clean up tasks
 	systemGCCmd := g.SystemCmd.Command("gc", "Run system clean up tasks")
@@ -708,6 +754,7 @@ func RegisterCommands(app *kingpin.Application) *Application {
 	g.ResourceCreateCmd.User = g.ResourceCreateCmd.Flag("user", "user to create resource for, defaults to currently logged in user").String()
 	g.ResourceCreateCmd.Manual = g.ResourceCreateCmd.Flag("manual", "manually execute operation phases").Short('m').Bool()
 	g.ResourceCreateCmd.Confirmed = g.ResourceCreateCmd.Flag("confirm", "do not ask for confirmation").Bool()
+	g.ResourceCreateCmd.DryRun = g.ResourceCreateCmd.Flag("dry-run", "display the plan of the operation that would update the resource without starting it. Only supported for resources managed with cluster operations").Bool()
 
 	// remove one or many resources
 	g.ResourceRemoveCmd.CmdClause = g.ResourceCmd.Command("rm", fmt.Sprintf("Remove a configuration resource, e.g. gravity resource rm oidc google. Supported resources are: %v", modules.Get().SupportedResourcesToRemove()))
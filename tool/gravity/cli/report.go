@@ -43,7 +43,18 @@ import (
 // to the stdout.
 // filters define the specific diagnostics to collect ('system', 'kubernetes'),
 // if empty all diagnostics are collected.
-func systemReport(env *localenv.LocalEnvironment, filters []string, compressed bool) error {
+// If redact is set, sensitive data is removed from the report using
+// the built-in rules and the specified redactPatterns.
+func systemReport(env *localenv.LocalEnvironment, filters []string, compressed, redact bool, redactPatterns []string) error {
+	var redactor *report.Redactor
+	if redact {
+		var err error
+		redactor, err = report.NewRedactor(redactPatterns)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+	}
+
 	runner := utils.NewRunner(nil)
 	var collectors report.Collectors
 	for _, filter := range teleutils.Deduplicate(filters) {
@@ -68,10 +79,19 @@ func systemReport(env *localenv.LocalEnvironment, filters []string, compressed b
 	defer os.RemoveAll(dir)
 
 	rw := report.NewFileWriter(dir)
+	if redactor != nil {
+		rw = redactor.Writer(rw)
+	}
 	err = collectors.Collect(rw, runner)
 	if err != nil {
 		log.Errorf("failed to collect some diagnostics: %v", trace.DebugReport(err))
 	}
+	if redactor != nil {
+		err = redactor.WriteManifest(report.NewFileWriter(dir))
+		if err != nil {
+			return trace.Wrap(err)
+		}
+	}
 
 	reader, writer := io.Pipe()
 
@@ -46,8 +46,10 @@ type LocalEnvironmentFactory interface {
 // upsert controls whether the resource is expected to exist.
 // manual controls whether the operation is created in manual mode if resource creation is implemented
 // as a cluster operation.
-// confirmed specifies if the user has explicitly approved the operation
-func createResource(env *localenv.LocalEnvironment, factory LocalEnvironmentFactory, filename string, upsert bool, user string, manual, confirmed bool) error {
+// confirmed specifies if the user has explicitly approved the operation.
+// dryRun controls whether only the plan of the operation is displayed without
+// creating the operation
+func createResource(env *localenv.LocalEnvironment, factory LocalEnvironmentFactory, filename string, upsert bool, user string, manual, confirmed, dryRun bool) error {
 	operator, err := env.SiteOperator()
 	if err != nil {
 		return trace.Wrap(err)
@@ -74,6 +76,7 @@ func createResource(env *localenv.LocalEnvironment, factory LocalEnvironmentFact
 			User:      user,
 			Manual:    manual,
 			Confirmed: confirmed,
+			DryRun:    dryRun,
 		}
 		return trace.Wrap(control.Create(bytes.NewReader(resource.Raw), req))
 	})
@@ -182,6 +185,9 @@ func (r clusterOperationHandler) UpdateResource(req resources.CreateRequest) err
 		return trace.Wrap(err)
 	}
 	defer localEnv.Close()
+	if req.DryRun {
+		return trace.Wrap(previewResourceUpdate(localEnv, req))
+	}
 	updateEnv, err := r.NewUpdateEnv()
 	if err != nil {
 		return trace.Wrap(err)
@@ -207,6 +213,29 @@ func (r clusterOperationHandler) UpdateResource(req resources.CreateRequest) err
 	return trace.BadParameter("unknown resource kind %q", req.Resource.Kind)
 }
 
+// previewResourceUpdate displays the plan of the operation that would
+// update the resource specified with req
+func previewResourceUpdate(localEnv *localenv.LocalEnvironment, req resources.CreateRequest) error {
+	var init updateInitializer
+	switch req.Resource.Kind {
+	case storage.KindRuntimeEnvironment:
+		env, err := storage.UnmarshalEnvironmentVariables(req.Resource.Raw)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		init = environInitializer{environ: env}
+	case storage.KindClusterConfiguration:
+		config, err := clusterconfig.Unmarshal(req.Resource.Raw)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		init = configInitializer{resource: req.Resource.Raw, config: config}
+	default:
+		return trace.BadParameter("dry run is not supported for resource %q", req.Resource.Kind)
+	}
+	return trace.Wrap(previewUpdate(context.TODO(), localEnv, init))
+}
+
 type clusterOperationHandler struct {
 	LocalEnvironmentFactory
 }
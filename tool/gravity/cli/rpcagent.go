@@ -60,8 +60,8 @@ func rpcAgentInstall(env *localenv.LocalEnvironment, args []string) error {
 }
 
 // rpcAgentRun runs a local agent executing the function specified with optional args
-func rpcAgentRun(localEnv, upgradeEnv *localenv.LocalEnvironment, args []string) error {
-	server, err := startAgent()
+func rpcAgentRun(localEnv, upgradeEnv *localenv.LocalEnvironment, args []string, metricsAddr string) error {
+	server, err := startAgent(metricsAddr)
 	if err != nil {
 		return trace.Wrap(err)
 	}
@@ -89,7 +89,7 @@ func rpcAgentRun(localEnv, upgradeEnv *localenv.LocalEnvironment, args []string)
 	return trace.Wrap(server.Serve())
 }
 
-func startAgent() (rpcserver.Server, error) {
+func startAgent(metricsAddr string) (rpcserver.Server, error) {
 	secretsDir, err := fsm.AgentSecretsDir()
 	if err != nil {
 		return nil, trace.Wrap(err)
@@ -111,7 +111,8 @@ func startAgent() (rpcserver.Server, error) {
 			Server: serverCreds,
 			Client: clientCreds,
 		},
-		Listener: listener,
+		Listener:    listener,
+		MetricsAddr: metricsAddr,
 	}
 	server, err := rpcserver.New(config, logrus.StandardLogger())
 	if err != nil {
@@ -249,7 +249,9 @@ func Execute(g *Application, cmd string, extraArgs []string) error {
 		g.AppUpgradeCmd.FullCommand(),
 		g.AppRollbackCmd.FullCommand(),
 		g.AppUninstallCmd.FullCommand(),
-		g.AppHistoryCmd.FullCommand():
+		g.AppHistoryCmd.FullCommand(),
+		g.AppMigrateReleasesCmd.FullCommand(),
+		g.AppSetDriverCmd.FullCommand():
 		if err := httplib.InGravity(localEnv.DNS.Addr()); err != nil {
 			if !httplib.InKubernetes() {
 				return trace.BadParameter("this command must be executed " +
@@ -301,6 +303,7 @@ func Execute(g *Application, cmd string, extraArgs []string) error {
 			SystemLogFile: *g.SystemLogFile,
 			ServiceUID:    *g.WizardCmd.ServiceUID,
 			ServiceGID:    *g.WizardCmd.ServiceGID,
+			TrustedKeys:   *g.TrustedKeys,
 		})
 	case g.InstallCmd.FullCommand():
 		if *g.InstallCmd.Resume {
@@ -346,10 +349,23 @@ func Execute(g *Application, cmd string, extraArgs []string) error {
 			*g.UpdateTriggerCmd.Manual,
 			*g.UpdateTriggerCmd.Block,
 			*g.UpdateTriggerCmd.SkipVersionCheck,
+			*g.UpdateTriggerCmd.DryRun,
+			*g.UpdateTriggerCmd.Parallelism,
+			nil,
+			false,
 		)
 	case g.UpdatePlanInitCmd.FullCommand():
 		return initUpdateOperationPlan(localEnv, updateEnv)
 	case g.UpgradeCmd.FullCommand():
+		if *g.UpgradeCmd.Check {
+			return checkUpdateCompatibility(context.TODO(), localEnv, *g.UpgradeCmd.App)
+		}
+		if *g.UpgradeCmd.Approve {
+			if err := approveUpdate(localEnv, updateEnv, joinEnv); err != nil {
+				return trace.Wrap(err)
+			}
+			*g.UpgradeCmd.Resume = true
+		}
 		if *g.UpgradeCmd.Resume {
 			*g.UpgradeCmd.Phase = fsm.RootPhase
 		}
@@ -368,6 +384,10 @@ func Execute(g *Application, cmd string, extraArgs []string) error {
 			*g.UpgradeCmd.Manual,
 			*g.UpgradeCmd.Block,
 			*g.UpgradeCmd.SkipVersionCheck,
+			*g.UpgradeCmd.DryRun,
+			*g.UpgradeCmd.Parallelism,
+			g.UpgradeCmd.updateStrategy(),
+			*g.UpgradeCmd.AutoRollback,
 		)
 	case g.PlanExecuteCmd.FullCommand():
 		return executePhase(localEnv, updateEnv, joinEnv,
@@ -428,7 +448,7 @@ func Execute(g *Application, cmd string, extraArgs []string) error {
 			return status(localEnv, printOptions)
 		}
 	case g.UpdateUploadCmd.FullCommand():
-		return uploadUpdate(localEnv, *g.UpdateUploadCmd.OpsCenterURL)
+		return uploadUpdate(localEnv, *g.UpdateUploadCmd.OpsCenterURL, *g.TrustedKeys)
 	case g.AppPackageCmd.FullCommand():
 		return appPackage(localEnv)
 		// app commands
@@ -447,6 +467,7 @@ func Execute(g *Application, cmd string, extraArgs []string) error {
 				CertPath: *g.AppInstallCmd.RegistryCert,
 				KeyPath:  *g.AppInstallCmd.RegistryKey,
 			},
+			TrustedKeys: *g.TrustedKeys,
 		})
 	case g.AppListCmd.FullCommand():
 		return releaseList(localEnv,
@@ -465,6 +486,7 @@ func Execute(g *Application, cmd string, extraArgs []string) error {
 				CertPath: *g.AppUpgradeCmd.RegistryCert,
 				KeyPath:  *g.AppUpgradeCmd.RegistryKey,
 			},
+			TrustedKeys: *g.TrustedKeys,
 		})
 	case g.AppRollbackCmd.FullCommand():
 		return releaseRollback(localEnv, releaseRollbackConfig{
@@ -479,6 +501,12 @@ func Execute(g *Application, cmd string, extraArgs []string) error {
 		return releaseHistory(localEnv, releaseHistoryConfig{
 			Release: *g.AppHistoryCmd.Release,
 		})
+	case g.AppMigrateReleasesCmd.FullCommand():
+		return releaseMigrate(localEnv,
+			*g.AppMigrateReleasesCmd.Cleanup,
+			*g.AppMigrateReleasesCmd.DryRun)
+	case g.AppSetDriverCmd.FullCommand():
+		return releaseSetDriver(localEnv, *g.AppSetDriverCmd.Driver)
 	case g.AppSyncCmd.FullCommand():
 		return appSync(localEnv, appSyncConfig{
 			Image: *g.AppSyncCmd.Image,
@@ -488,6 +516,7 @@ func Execute(g *Application, cmd string, extraArgs []string) error {
 				CertPath: *g.AppSyncCmd.RegistryCert,
 				KeyPath:  *g.AppSyncCmd.RegistryKey,
 			},
+			TrustedKeys: *g.TrustedKeys,
 		})
 	case g.AppSearchCmd.FullCommand():
 		return appSearch(localEnv,
@@ -672,7 +701,9 @@ func Execute(g *Application, cmd string, extraArgs []string) error {
 			*g.APIKeyDeleteCmd.Email,
 			*g.APIKeyDeleteCmd.Token)
 	case g.ReportCmd.FullCommand():
-		return getClusterReport(localEnv, *g.ReportCmd.FilePath)
+		return getClusterReport(localEnv, *g.ReportCmd.FilePath,
+			*g.ReportCmd.Redact || len(*g.ReportCmd.RedactPatterns) != 0,
+			*g.ReportCmd.RedactPatterns)
 	// cluster commands
 	case g.SiteListCmd.FullCommand():
 		return listSites(localEnv, *g.SiteListCmd.OpsCenterURL)
@@ -729,12 +760,26 @@ func Execute(g *Application, cmd string, extraArgs []string) error {
 			*g.SystemRollbackCmd.WithStatus)
 	case g.SystemStepDownCmd.FullCommand():
 		return stepDown(localEnv)
+	case g.SystemMigrateStorageCmd.FullCommand():
+		return migrateStorage(localEnv, *g.EtcdRetryTimeout, *g.SystemMigrateStorageCmd.GracePeriod)
+	case g.SystemBLOBFsckCmd.FullCommand():
+		return checkBLOBs(localEnv, *g.SystemBLOBFsckCmd.Repair)
+	case g.SystemBLOBDedupCmd.FullCommand():
+		return dedupBLOBs(localEnv)
+	case g.SystemBLOBMigrateCmd.FullCommand():
+		return migrateBLOBs(localEnv)
 	case g.BackupCmd.FullCommand():
 		return backup(localEnv,
 			*g.BackupCmd.Tarball,
 			*g.BackupCmd.Timeout,
 			*g.BackupCmd.Follow,
 			*g.Silent)
+	case g.AuditListCmd.FullCommand():
+		return listAuditEvents(localEnv,
+			*g.AuditListCmd.Since,
+			*g.AuditListCmd.User,
+			*g.AuditListCmd.Kind,
+			*g.AuditListCmd.Output)
 	case g.RestoreCmd.FullCommand():
 		return restore(localEnv,
 			*g.RestoreCmd.Tarball,
@@ -774,7 +819,9 @@ func Execute(g *Application, cmd string, extraArgs []string) error {
 	case g.SystemReportCmd.FullCommand():
 		return systemReport(localEnv,
 			*g.SystemReportCmd.Filter,
-			*g.SystemReportCmd.Compressed)
+			*g.SystemReportCmd.Compressed,
+			*g.SystemReportCmd.Redact || len(*g.SystemReportCmd.RedactPatterns) != 0,
+			*g.SystemReportCmd.RedactPatterns)
 	case g.SystemStateDirCmd.FullCommand():
 		return printStateDir()
 	case g.SystemEnablePromiscModeCmd.FullCommand():
@@ -786,7 +833,10 @@ func Execute(g *Application, cmd string, extraArgs []string) error {
 	case g.SystemStreamRuntimeJournalCmd.FullCommand():
 		return streamRuntimeJournal(localEnv)
 	case g.GarbageCollectCmd.FullCommand():
-		return garbageCollect(localEnv, *g.GarbageCollectCmd.Manual, *g.GarbageCollectCmd.Confirmed)
+		return garbageCollect(localEnv,
+			*g.GarbageCollectCmd.Manual,
+			*g.GarbageCollectCmd.Confirmed,
+			*g.GarbageCollectCmd.DryRun)
 	case g.SystemGCJournalCmd.FullCommand():
 		return removeUnusedJournalFiles(localEnv,
 			*g.SystemGCJournalCmd.MachineIDFile,
@@ -832,7 +882,8 @@ func Execute(g *Application, cmd string, extraArgs []string) error {
 			*g.ResourceCreateCmd.Upsert,
 			*g.ResourceCreateCmd.User,
 			*g.ResourceCreateCmd.Manual,
-			*g.ResourceCreateCmd.Confirmed)
+			*g.ResourceCreateCmd.Confirmed,
+			*g.ResourceCreateCmd.DryRun)
 	case g.ResourceRemoveCmd.FullCommand():
 		return removeResource(localEnv, g,
 			*g.ResourceRemoveCmd.Kind,
@@ -856,7 +907,8 @@ func Execute(g *Application, cmd string, extraArgs []string) error {
 		return rpcAgentInstall(localEnv, *g.RPCAgentInstallCmd.Args)
 	case g.RPCAgentRunCmd.FullCommand():
 		return rpcAgentRun(localEnv, updateEnv,
-			*g.RPCAgentRunCmd.Args)
+			*g.RPCAgentRunCmd.Args,
+			*g.RPCAgentRunCmd.MetricsAddr)
 	case g.RPCAgentShutdownCmd.FullCommand():
 		return rpcAgentShutdown(localEnv)
 	case g.CheckCmd.FullCommand():
@@ -0,0 +1,64 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/localenv"
+	"github.com/gravitational/gravity/lib/pack"
+	"github.com/gravitational/gravity/lib/signature"
+
+	"github.com/gravitational/trace"
+)
+
+// verifySignature verifies the signature of the installer unpacked into
+// the specified directory against the trusted keys.
+//
+// Trusted keys are read from the trusted keys directory and the specified
+// files. If no trusted keys have been configured, verification is skipped,
+// otherwise unsigned installers and installers with packages that do not
+// match the signature are rejected.
+func verifySignature(env *localenv.LocalEnvironment, dir string, packages pack.PackageService, trustedKeys []string) error {
+	keys, err := signature.LoadTrustedKeys(defaults.TrustedKeysDir, trustedKeys...)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if len(keys) == 0 {
+		log.Infof("No trusted keys configured in %v, skipping signature verification.",
+			defaults.TrustedKeysDir)
+		return nil
+	}
+	env.PrintStep("Verifying installer signature")
+	manifest, err := signature.VerifyDir(dir, packages, keys)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	env.PrintStep("Verified signature of %v packages of %v",
+		len(manifest.Packages), manifest.Application)
+	return nil
+}
+
+// verifyInstallerDir verifies the signature of the installer unpacked
+// into the specified directory
+func verifyInstallerDir(env *localenv.LocalEnvironment, dir string, trustedKeys []string) error {
+	installerEnv, err := localenv.New(dir)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	defer installerEnv.Close()
+	return trace.Wrap(verifySignature(env, dir, installerEnv.Packages, trustedKeys))
+}
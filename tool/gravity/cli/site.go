@@ -122,7 +122,7 @@ func listSites(env *localenv.LocalEnvironment, opsCenterURL string) error {
 	return nil
 }
 
-func getClusterReport(env *localenv.LocalEnvironment, targetFile string) error {
+func getClusterReport(env *localenv.LocalEnvironment, targetFile string, redact bool, redactPatterns []string) error {
 	f, err := os.Create(targetFile)
 	if err != nil {
 		return trace.Wrap(err)
@@ -139,9 +139,11 @@ func getClusterReport(env *localenv.LocalEnvironment, targetFile string) error {
 		return trace.Wrap(err)
 	}
 
-	report, err := operator.GetSiteReport(ops.SiteKey{
-		AccountID:  site.AccountID,
-		SiteDomain: site.Domain,
+	report, err := operator.GetSiteReport(ops.GetSiteReportRequest{
+		AccountID:      site.AccountID,
+		SiteDomain:     site.Domain,
+		Redact:         redact,
+		RedactPatterns: redactPatterns,
 	})
 	if err != nil {
 		return trace.Wrap(err)
@@ -32,6 +32,8 @@ import (
 	"github.com/gravitational/gravity/lib/ops"
 	"github.com/gravitational/gravity/lib/schema"
 	statusapi "github.com/gravitational/gravity/lib/status"
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/utils"
 
 	"github.com/dustin/go-humanize"
 	"github.com/fatih/color"
@@ -203,45 +205,67 @@ func printStatusWithOptions(status clusterStatus, printOptions printOptions) err
 	return nil
 }
 
-// tailOperationLogs follows the logs of the currently ongoing operation until the operation completes
+// tailOperationLogs follows the logs of the currently ongoing operation until the operation completes.
+//
+// The logs are received from the operation event stream which is resumed
+// from the last received event if the connection is interrupted
 func tailOperationLogs(operator ops.Operator, operationKey ops.SiteOperationKey) error {
-	reader, err := operator.GetSiteOperationLogs(operationKey)
-	if err != nil {
-		return trace.Wrap(err)
-	}
-	defer reader.Close()
-
-	// tail operation logs and spit them out into console
-	errCh := make(chan error, 1)
-	go func() {
-		_, err := io.Copy(os.Stdout, reader)
-		errCh <- trace.Wrap(err)
-	}()
-
-	// watch operation progress so we can exit when the operation completes
-	ticker := time.NewTicker(time.Second)
-	defer ticker.Stop()
+	var offset ops.EventOffset
+	var attempts int
 	for {
-		select {
-		case <-ticker.C:
-			progress, err := operator.GetSiteOperationProgress(operationKey)
-			if err != nil && !trace.IsNotFound(err) {
-				return trace.Wrap(err)
-			}
-			// this can happen if an operation has been cancelled before it's been started
-			if progress == nil {
-				return trace.NotFound("the operation has been cancelled")
-			}
-			if !progress.IsCompleted() {
-				continue
-			}
+		received := offset
+		progress, err := followOperationEvents(context.TODO(), operator, operationKey, &offset)
+		if err == nil {
 			if progress.State == ops.ProgressStateFailed {
-				return trace.Errorf(progress.Message)
+				return trace.Errorf("%v", progress.Message)
 			}
 			return nil
-		case err = <-errCh:
+		}
+		if !utils.IsTransientClusterError(err) && !utils.IsNetworkError(err) {
+			return trace.Wrap(err)
+		}
+		if offset != received {
+			attempts = 0
+		}
+		attempts++
+		if attempts > defaults.OperationEventsReconnectAttempts {
 			return trace.Wrap(err)
 		}
+		log.Debugf("Operation event stream interrupted, resuming from %v: %v.", offset, err)
+		time.Sleep(defaults.OperationEventsPollInterval)
+	}
+}
+
+// followOperationEvents prints the logs from the operation event stream
+// starting after the specified offset until the operation completes.
+// It returns the final progress entry of the operation.
+//
+// The offset is updated with each received event
+func followOperationEvents(ctx context.Context, operator ops.Operator, operationKey ops.SiteOperationKey, offset *ops.EventOffset) (*ops.ProgressEntry, error) {
+	stream, err := operator.GetOperationEvents(ctx, operationKey, *offset)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	defer stream.Close()
+	var progress *ops.ProgressEntry
+	for {
+		event, err := stream.Recv()
+		if err == io.EOF {
+			if progress == nil {
+				return nil, trace.ConnectionProblem(nil, "operation event stream ended before the operation completed")
+			}
+			return progress, nil
+		}
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		*offset = event.Offset
+		switch event.Type {
+		case ops.OperationEventLog:
+			fmt.Println(event.Log)
+		case ops.OperationEventProgress:
+			progress = event.Progress
+		}
 	}
 }
 
@@ -307,9 +331,58 @@ func printClusterStatus(cluster statusapi.Cluster, w io.Writer) {
 		fmt.Fprintf(w, "Last completed operation:\n")
 		printOperation(cluster.Operation, w)
 	}
+	if len(cluster.Backups) != 0 {
+		fmt.Fprintf(w, "Scheduled backups:\n")
+		for _, backup := range cluster.Backups {
+			printBackupSchedule(backup, w)
+		}
+	}
+	if len(cluster.BLOBs) != 0 {
+		fmt.Fprintf(w, "Package storage:\n")
+		for _, peer := range cluster.BLOBs {
+			printBLOBIntegrity(peer, w)
+		}
+	}
 	cluster.Endpoints.Cluster.WriteTo(w)
 }
 
+func printBLOBIntegrity(peer storage.Peer, w io.Writer) {
+	integrity := peer.Integrity
+	state := color.GreenString("healthy")
+	if !integrity.IsHealthy() {
+		state = color.RedString("degraded")
+	}
+	fmt.Fprintf(w, "    * %v (%v)\t%v, checked %v\n", peer.ID, peer.AdvertiseAddr, state,
+		humanize.RelTime(integrity.Checked, time.Now(), "ago", ""))
+	if integrity.Error != "" {
+		fmt.Fprintf(w, "      %v\t%v\n", color.RedString("error:"), integrity.Error)
+	}
+	if unrepaired := len(integrity.Corrupted) - len(integrity.Repaired); unrepaired != 0 {
+		fmt.Fprintf(w, "      %v\t%v\n", color.RedString("corrupted objects:"), unrepaired)
+	}
+	if len(integrity.UnderReplicated) != 0 {
+		fmt.Fprintf(w, "      %v\t%v\n", color.RedString("under-replicated objects:"), len(integrity.UnderReplicated))
+	}
+}
+
+func printBackupSchedule(backup statusapi.BackupSchedule, w io.Writer) {
+	fmt.Fprintf(w, "    * %v (%v to %v)\n", backup.Name, backup.Schedule, backup.Destination)
+	if !backup.LastSuccess.IsZero() {
+		fmt.Fprintf(w, "      last success:\t%v (%v)\n",
+			backup.LastSuccess.Format(constants.HumanDateFormat),
+			humanize.RelTime(backup.LastSuccess, time.Now(), "ago", ""))
+	}
+	if backup.IsFailed() {
+		fmt.Fprintf(w, "      %v\t%v (%v): %v\n", color.RedString("last failure:"),
+			backup.LastFailure.Format(constants.HumanDateFormat),
+			humanize.RelTime(backup.LastFailure, time.Now(), "ago", ""),
+			backup.LastError)
+	}
+	if backup.LastRun.IsZero() {
+		fmt.Fprintf(w, "      has not run yet\n")
+	}
+}
+
 func printOperation(operation *statusapi.ClusterOperation, w io.Writer) {
 	fmt.Fprintf(w, "    * %v (%v)\n", operation.Type, operation.ID)
 	fmt.Fprintf(w, "      started:\t%v (%v)\n",
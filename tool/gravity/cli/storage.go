@@ -0,0 +1,46 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"context"
+	"time"
+
+	"github.com/gravitational/gravity/lib/localenv"
+	"github.com/gravitational/gravity/lib/storage/keyval"
+
+	"github.com/gravitational/trace"
+)
+
+// migrateStorage migrates the cluster state stored in the local etcd
+// from the etcd v2 API to the v3 API.
+//
+// Gravity processes running with the default configuration follow
+// the migration and switch to the v3 API once it completes
+func migrateStorage(env *localenv.LocalEnvironment, etcdTimeout, gracePeriod time.Duration) error {
+	config, err := keyval.LocalEtcdConfig(etcdTimeout)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	env.PrintStep("Migrating cluster state to etcd v3 API")
+	err = keyval.MigrateToV3(context.TODO(), *config, gracePeriod)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	env.PrintStep("Cluster state has been migrated to etcd v3 API")
+	return nil
+}
@@ -32,6 +32,9 @@ type appSyncConfig struct {
 	Image string
 	// registryConfig is configuration of a registry to push images to.
 	registryConfig
+	// TrustedKeys is a list of additional public keys to verify
+	// the image signature with.
+	TrustedKeys []string
 }
 
 // registryConfig describes Docker registry configuration.
@@ -65,6 +68,10 @@ func appSync(env *localenv.LocalEnvironment, conf appSyncConfig) error {
 }
 
 func appSyncEnv(env *localenv.LocalEnvironment, imageEnv *localenv.ImageEnvironment, conf appSyncConfig) error {
+	err := verifySignature(env, imageEnv.StateDir, imageEnv.Packages, conf.TrustedKeys)
+	if err != nil {
+		return trace.Wrap(err)
+	}
 	if err := httplib.InGravity(env.DNS.Addr()); err == nil {
 		// If we're running inside Gravity cluster, sync application images
 		// to all cluster registries and push the application package to
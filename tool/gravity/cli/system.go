@@ -447,6 +447,7 @@ func findPackages(packages pack.PackageService, runtimePackageUpdate loc.Locator
 				updateFilter:     runtimeConfigUpdate,
 				labels:           runtimeConfigLabels,
 				less:             configPackageLess,
+				configFor:        &runtimePackageUpdate,
 			},
 		},
 		packageRequest{
@@ -1151,6 +1152,20 @@ func findPackageUpdateHelper(packages pack.PackageService, req packageRequest) (
 		req.less = pack.Less
 	}
 	latestPackage := req.updatePackage
+	if latestPackage == nil && req.configFor != nil {
+		// Prefer the configuration generated for the version of the package
+		// being installed, as the update might step through several versions
+		filter := req.updateSearchFilter()
+		latestPackage, err = pack.FindLatestPackageCustom(pack.FindLatestPackageRequest{
+			Packages:   packages,
+			Repository: filter.Repository,
+			Match:      req.matchConfigFor,
+			Less:       req.less,
+		})
+		if err != nil && !trace.IsNotFound(err) {
+			return nil, trace.Wrap(err)
+		}
+	}
 	if latestPackage == nil {
 		filter := req.updateSearchFilter()
 		latestPackage, err = pack.FindLatestPackageCustom(pack.FindLatestPackageRequest{
@@ -1284,6 +1299,27 @@ func (r packageRequest) match(env pack.PackageEnvelope) bool {
 	return matched
 }
 
+// matchConfigFor returns true if the specified configuration package matches
+// this request and has been generated for the version of the package in configFor.
+// Configuration package versions are formatted as <package-version>+<timestamp>
+func (r packageRequest) matchConfigFor(env pack.PackageEnvelope) bool {
+	if !r.match(env) {
+		return false
+	}
+	configVersion, err := env.Locator.SemVer()
+	if err != nil {
+		return false
+	}
+	version, err := r.configFor.SemVer()
+	if err != nil {
+		return false
+	}
+	return configVersion.Major == version.Major &&
+		configVersion.Minor == version.Minor &&
+		configVersion.Patch == version.Patch &&
+		configVersion.PreRelease == version.PreRelease
+}
+
 func (r packageRequest) updateSearchFilter() loc.Locator {
 	if r.updateFilter != nil {
 		return *r.updateFilter
@@ -1302,7 +1338,11 @@ type packageRequest struct {
 	labels map[string]string
 	// less specifies optional version comparator to use when searching
 	// for an update
-	less          pack.LessFunc
+	less pack.LessFunc
+	// configFor optionally specifies the package this configuration package
+	// is for. If set, the configuration generated for the version of this
+	// package is preferred over the latest one
+	configFor     *loc.Locator
 	configPackage *packageRequest
 }
 
@@ -28,9 +28,11 @@ import (
 	libfsm "github.com/gravitational/gravity/lib/fsm"
 	"github.com/gravitational/gravity/lib/localenv"
 	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/storage"
 	"github.com/gravitational/gravity/lib/update"
 
 	"github.com/gravitational/trace"
+	"github.com/pborman/uuid"
 	"github.com/sirupsen/logrus"
 )
 
@@ -121,9 +123,58 @@ func newUpdater(ctx context.Context, localEnv, updateEnv *localenv.LocalEnvironm
 	return updater, nil
 }
 
+// previewUpdate builds and displays the plan of the operation that
+// the specified initializer would create without creating the operation
+func previewUpdate(ctx context.Context, localEnv *localenv.LocalEnvironment, init updateInitializer) error {
+	clusterEnv, err := localEnv.NewClusterEnvironment()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if clusterEnv.Client == nil {
+		return trace.BadParameter("this operation can only be executed on one of the master nodes")
+	}
+	operator := clusterEnv.Operator
+	cluster, err := operator.GetLocalSite()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	err = init.validatePreconditions(localEnv, operator, *cluster)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	plan, err := init.newDryRunPlan(ctx, operator, *cluster, localEnv, clusterEnv)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return trace.Wrap(outputDryRunPlan(localEnv, *plan))
+}
+
+// newDryRunOperation returns a new operation of the specified type
+// for the given cluster. The operation is only used to build the
+// operation plan for preview and is never stored
+func newDryRunOperation(cluster ops.Site, operationType string) ops.SiteOperation {
+	now := time.Now().UTC()
+	return ops.SiteOperation{
+		ID:         uuid.New(),
+		AccountID:  cluster.AccountID,
+		SiteDomain: cluster.Domain,
+		Type:       operationType,
+		Created:    now,
+		Updated:    now,
+		State:      ops.OperationStateReady,
+	}
+}
+
 type updateInitializer interface {
 	validatePreconditions(localEnv *localenv.LocalEnvironment, operator ops.Operator, cluster ops.Site) error
 	newOperation(ops.Operator, ops.Site) (*ops.SiteOperationKey, error)
+	// newDryRunPlan builds the plan of the operation without creating
+	// the operation or storing the plan
+	newDryRunPlan(ctx context.Context,
+		operator ops.Operator,
+		cluster ops.Site,
+		localEnv *localenv.LocalEnvironment,
+		clusterEnv *localenv.ClusterEnvironment) (*storage.OperationPlan, error)
 	newOperationPlan(ctx context.Context,
 		operator ops.Operator,
 		cluster ops.Site,
@@ -0,0 +1,133 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"bytes"
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/gravitational/gravity/lib/constants"
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/localenv"
+	"github.com/gravitational/gravity/lib/rpc"
+	"github.com/gravitational/gravity/lib/storage"
+	clusterupdate "github.com/gravitational/gravity/lib/update/cluster"
+	"github.com/gravitational/gravity/lib/utils"
+
+	teleclient "github.com/gravitational/teleport/lib/client"
+	"github.com/gravitational/trace"
+)
+
+// checkUpdateCompatibility compares the installed cluster image with the
+// specified update and prints the report of blockers and warnings.
+// It does not start the update operation.
+// Returns an error if the report has any blockers
+func checkUpdateCompatibility(ctx context.Context, localEnv *localenv.LocalEnvironment, updatePackage string) error {
+	clusterEnv, err := localEnv.NewClusterEnvironment()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if clusterEnv.Client == nil {
+		return trace.BadParameter("this operation can only be executed on one of the master nodes")
+	}
+	cluster, err := clusterEnv.Operator.GetLocalSite()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	updateApp, err := checkForUpdate(localEnv, clusterEnv.Operator, cluster.App.Package, updatePackage)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	config := clusterupdate.CompatibilityConfig{
+		InstalledPackage: cluster.App.Package,
+		UpdatePackage:    updateApp.Package,
+		Apps:             clusterEnv.Apps,
+		Packages:         clusterEnv.Packages,
+		Servers:          cluster.ClusterState.Servers,
+		Docker:           cluster.ClusterState.Docker,
+		Client:           clusterEnv.Client,
+	}
+	proxy, err := connectToProxy(ctx, localEnv)
+	if err != nil {
+		localEnv.Printf("Failed to connect to the cluster nodes, skipping the disk space check: %v\n",
+			trace.UserMessage(err))
+	} else {
+		config.FreeDiskSpace = freeDiskSpace(proxy)
+	}
+	report, err := clusterupdate.CheckCompatibility(ctx, config)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	printCompatibilityReport(localEnv, *report)
+	if blockers := report.Blockers(); len(blockers) != 0 {
+		return trace.BadParameter("found %v blocker(s), the cluster cannot be updated to %v",
+			len(blockers), report.UpdatePackage)
+	}
+	return nil
+}
+
+func printCompatibilityReport(localEnv *localenv.LocalEnvironment, report clusterupdate.CompatibilityReport) {
+	localEnv.Printf("Compatibility of %v with the installed %v:\n",
+		report.UpdatePackage, report.InstalledPackage)
+	if len(report.Findings) == 0 {
+		localEnv.Println("No blockers or warnings found.")
+		return
+	}
+	for _, finding := range append(report.Blockers(), report.Warnings()...) {
+		localEnv.Printf("  %v\n", finding)
+	}
+}
+
+func connectToProxy(ctx context.Context, localEnv *localenv.LocalEnvironment) (*teleclient.ProxyClient, error) {
+	teleportClient, err := localEnv.TeleportClient(constants.Localhost)
+	if err != nil {
+		return nil, trace.Wrap(err, "failed to create a teleport client")
+	}
+	proxy, err := teleportClient.ConnectToProxy(ctx)
+	if err != nil {
+		return nil, trace.Wrap(err, "failed to connect to teleport proxy")
+	}
+	return proxy, nil
+}
+
+// freeDiskSpace returns a function that determines the free disk space
+// in the state directory of a server using the teleport proxy
+func freeDiskSpace(proxy *teleclient.ProxyClient) func(context.Context, storage.Server) (uint64, error) {
+	return func(ctx context.Context, server storage.Server) (uint64, error) {
+		client, err := proxy.ConnectToNode(ctx, rpc.NewDeployServer(server).NodeAddr,
+			defaults.SSHUser, false)
+		if err != nil {
+			return 0, trace.Wrap(err)
+		}
+		defer client.Close()
+		var out bytes.Buffer
+		err = utils.NewSSHCommands(client.Client).
+			C("df --output=avail -B1 %v | tail -1", server.StateDir()).
+			WithOutput(&out).
+			Run(ctx)
+		if err != nil {
+			return 0, trace.Wrap(err)
+		}
+		available, err := strconv.ParseUint(strings.TrimSpace(out.String()), 10, 64)
+		if err != nil {
+			return 0, trace.Wrap(err, "unexpected df output: %q", out.String())
+		}
+		return available, nil
+	}
+}
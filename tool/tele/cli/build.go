@@ -18,9 +18,11 @@ package cli
 
 import (
 	"context"
+	"crypto"
 
 	"github.com/gravitational/gravity/lib/app/service"
 	"github.com/gravitational/gravity/lib/builder"
+	"github.com/gravitational/gravity/lib/signature"
 	"github.com/gravitational/gravity/lib/utils"
 
 	"github.com/gravitational/trace"
@@ -44,10 +46,21 @@ type BuildParameters struct {
 	Silent bool
 	// Insecure turns on insecure verify mode
 	Insecure bool
+	// Hub is the optional URL of the hub to download runtimes from
+	Hub string
+	// SignKeyPath is the optional path to the private key to sign the installer with
+	SignKeyPath string
 }
 
 // build builds an installer tarball according to the provided parameters
 func build(ctx context.Context, params BuildParameters, req service.VendorRequest) (err error) {
+	var signingKey crypto.Signer
+	if params.SignKeyPath != "" {
+		signingKey, err = signature.ReadSigningKey(params.SignKeyPath)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+	}
 	installerBuilder, err := builder.New(builder.Config{
 		Context:          ctx,
 		StateDir:         params.StateDir,
@@ -56,7 +69,9 @@ func build(ctx context.Context, params BuildParameters, req service.VendorReques
 		OutPath:          params.OutPath,
 		Overwrite:        params.Overwrite,
 		Repository:       params.Repository,
+		Hub:              params.Hub,
 		SkipVersionCheck: params.SkipVersionCheck,
+		SigningKey:       signingKey,
 		VendorReq:        req,
 		Progress:         utils.NewProgress(ctx, "Build", 6, params.Silent),
 	})
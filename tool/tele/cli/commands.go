@@ -33,6 +33,8 @@ type Application struct {
 	Insecure *bool
 	// StateDir is the local state directory
 	StateDir *string
+	// Hub is the URL of the hub with application and cluster images
+	Hub *string
 	// VersionCmd outputs the binary version
 	VersionCmd VersionCmd
 	// BuildCmd builds app installer tarball
@@ -41,6 +43,8 @@ type Application struct {
 	ListCmd ListCmd
 	// PullCmd downloads app installer from Ops Center
 	PullCmd PullCmd
+	// PushCmd publishes app installer to the hub
+	PushCmd PushCmd
 }
 
 // VersionCmd outputs the binary version
@@ -79,6 +83,8 @@ type BuildCmd struct {
 	Parallel *int
 	// Quiet allows to suppress console output
 	Quiet *bool
+	// SignKey is the path to the private key to sign the installer with
+	SignKey *string
 }
 
 type ListCmd struct {
@@ -103,3 +109,14 @@ type PullCmd struct {
 	// Quiet allows to suppress console output
 	Quiet *bool
 }
+
+// PushCmd publishes app installer to the hub
+type PushCmd struct {
+	*kingpin.CmdClause
+	// Tarball is the path to the installer tarball
+	Tarball *string
+	// Force overwrites the already published version
+	Force *bool
+	// Quiet allows to suppress console output
+	Quiet *bool
+}
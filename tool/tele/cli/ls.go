@@ -24,8 +24,8 @@ import (
 	"github.com/gravitational/trace"
 )
 
-func list(env localenv.LocalEnvironment, all bool, format constants.Format) error {
-	lister, err := catalog.NewLister()
+func list(env localenv.LocalEnvironment, all bool, format constants.Format, hubURL string, insecure bool) error {
+	lister, err := catalog.NewLister(hubURL, insecure)
 	if err != nil {
 		return trace.Wrap(err)
 	}
@@ -30,7 +30,7 @@ import (
 	"github.com/gravitational/trace"
 )
 
-func pull(env localenv.LocalEnvironment, app, outFile string, force, quiet bool) error {
+func pull(env localenv.LocalEnvironment, app, outFile string, force, quiet bool, hubURL string, insecure bool) error {
 	locator, err := loc.MakeLocator(app)
 	if err != nil {
 		return trace.Wrap(err)
@@ -42,7 +42,7 @@ func pull(env localenv.LocalEnvironment, app, outFile string, force, quiet bool)
 		locator.Name = constants.LegacyBaseImageName
 	}
 
-	hub, err := hub.New(hub.Config{})
+	hub, err := hub.NewFromURL(hubURL, insecure)
 	if err != nil {
 		return trace.Wrap(err)
 	}
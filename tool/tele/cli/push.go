@@ -0,0 +1,111 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"archive/tar"
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/gravitational/gravity/lib/archive"
+	"github.com/gravitational/gravity/lib/constants"
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/helm"
+	"github.com/gravitational/gravity/lib/hub"
+	"github.com/gravitational/gravity/lib/localenv"
+	"github.com/gravitational/gravity/lib/schema"
+	"github.com/gravitational/gravity/lib/utils"
+
+	dockerarchive "github.com/docker/docker/pkg/archive"
+	"github.com/gravitational/trace"
+)
+
+func push(env localenv.LocalEnvironment, tarball string, force, quiet bool, hubURL string, insecure bool) error {
+	if hubURL == "" {
+		return trace.BadParameter("specify the hub to push to with --hub flag or %v environment variable",
+			constants.TeleHubEnvVar)
+	}
+
+	manifest, err := manifestFromInstaller(tarball)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	fi, err := os.Stat(tarball)
+	if err != nil {
+		return trace.ConvertSystemError(err)
+	}
+
+	h, err := hub.NewFromURL(hubURL, insecure)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	// installer, index file and up to two channels
+	progress := utils.NewProgress(context.TODO(), "Push", 4, quiet)
+	defer progress.Stop()
+
+	err = h.Push(hub.PushRequest{
+		Path:     tarball,
+		Metadata: helm.GenerateChartMetadata(*manifest, fi.Size()),
+		Force:    force,
+		Progress: progress,
+	})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	if !quiet {
+		env.PrintStep("Published %v to %v", manifest.Locator(), hubURL)
+	}
+	return nil
+}
+
+// manifestFromInstaller reads the application manifest from the root
+// of the specified installer tarball
+func manifestFromInstaller(path string) (*schema.Manifest, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, trace.ConvertSystemError(err)
+	}
+	defer f.Close()
+	decompressed, err := dockerarchive.DecompressStream(f)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	defer decompressed.Close()
+	var data []byte
+	err = archive.TarGlob(tar.NewReader(decompressed), ".", []string{defaults.ManifestFileName},
+		func(match string, file io.Reader) (err error) {
+			data, err = ioutil.ReadAll(file)
+			return trace.Wrap(err)
+		})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if data == nil {
+		return nil, trace.NotFound("no application manifest %v found in %v",
+			defaults.ManifestFileName, path)
+	}
+	manifest, err := schema.ParseManifestYAMLNoValidate(data)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return manifest, nil
+}
@@ -36,6 +36,7 @@ func RegisterCommands(app *kingpin.Application) Application {
 	tele.Debug = app.Flag("debug", "Enable debug mode").Bool()
 	tele.Insecure = app.Flag("insecure", "Skip TLS verification when making HTTP requests").Default("false").Bool()
 	tele.StateDir = app.Flag("state-dir", "Directory for temporary local state").Hidden().String()
+	tele.Hub = app.Flag("hub", "URL of the hub with application and cluster images: s3://bucket/prefix, https://host/path or a local directory. Defaults to the public hub").OverrideDefaultFromEnvar(constants.TeleHubEnvVar).String()
 
 	tele.VersionCmd.CmdClause = app.Command("version", "Print version and exit")
 	tele.VersionCmd.Output = common.Format(tele.VersionCmd.Flag("output", "Output format, text or json").Short('o').Default(string(constants.EncodingText)))
@@ -54,6 +55,7 @@ func RegisterCommands(app *kingpin.Application) Application {
 	tele.BuildCmd.SkipVersionCheck = tele.BuildCmd.Flag("skip-version-check", "Skip version compatibility check").Hidden().Bool()
 	tele.BuildCmd.Parallel = tele.BuildCmd.Flag("parallel", "Specifies the number of concurrent tasks. If < 0, the number of tasks is not restricted, if unspecified, then tasks are capped at the number of logical CPU cores").Int()
 	tele.BuildCmd.Quiet = tele.BuildCmd.Flag("quiet", "Suppress any extra output to stdout").Short('q').Bool()
+	tele.BuildCmd.SignKey = tele.BuildCmd.Flag("sign-key", "Path to a PEM-encoded RSA or ECDSA private key to sign the installer with").String()
 
 	tele.ListCmd.CmdClause = app.Command("ls", "Display a list of user applications published in remote Ops Center")
 	tele.ListCmd.Runtimes = tele.ListCmd.Flag("runtimes", "Show only runtimes").Short('r').Hidden().Bool()
@@ -66,5 +68,10 @@ func RegisterCommands(app *kingpin.Application) Application {
 	tele.PullCmd.Force = tele.PullCmd.Flag("force", "Overwrite existing tarball").Short('f').Bool()
 	tele.PullCmd.Quiet = tele.PullCmd.Flag("quiet", "Suppress any extra output to stdout").Short('q').Bool()
 
+	tele.PushCmd.CmdClause = app.Command("push", "Publish an application installer to the hub specified with --hub")
+	tele.PushCmd.Tarball = tele.PushCmd.Arg("tarball", "Path to the application installer tarball").Required().ExistingFile()
+	tele.PushCmd.Force = tele.PushCmd.Flag("force", "Overwrite the already published version").Short('f').Bool()
+	tele.PushCmd.Quiet = tele.PushCmd.Flag("quiet", "Suppress any extra output to stdout").Short('q').Bool()
+
 	return tele
 }
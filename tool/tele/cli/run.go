@@ -59,6 +59,8 @@ func Run(tele Application) error {
 			SkipVersionCheck: *tele.BuildCmd.SkipVersionCheck,
 			Silent:           *tele.BuildCmd.Quiet,
 			Insecure:         *tele.Insecure,
+			Hub:              *tele.Hub,
+			SignKeyPath:      *tele.BuildCmd.SignKey,
 		}, service.VendorRequest{
 			PackageName:            *tele.BuildCmd.Name,
 			PackageVersion:         *tele.BuildCmd.Version,
@@ -97,11 +99,22 @@ func Run(tele Application) error {
 			*tele.PullCmd.App,
 			*tele.PullCmd.OutFile,
 			*tele.PullCmd.Force,
-			*tele.PullCmd.Quiet)
+			*tele.PullCmd.Quiet,
+			*tele.Hub,
+			*tele.Insecure)
+	case tele.PushCmd.FullCommand():
+		return push(*env,
+			*tele.PushCmd.Tarball,
+			*tele.PushCmd.Force,
+			*tele.PushCmd.Quiet,
+			*tele.Hub,
+			*tele.Insecure)
 	case tele.ListCmd.FullCommand():
 		return list(*env,
 			*tele.ListCmd.All,
-			*tele.ListCmd.Format)
+			*tele.ListCmd.Format,
+			*tele.Hub,
+			*tele.Insecure)
 	}
 
 	return trace.NotFound("unknown command %v", cmd)
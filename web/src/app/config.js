@@ -217,6 +217,7 @@ let cfg = {
     operationStartPath: '/portalapi/v1/sites/:siteId/operations/:opId/start',
     operationPrecheckPath: '/portalapi/v1/sites/:siteId/operations/:opId/prechecks',
     operationLogsPath: '/portal/v1/accounts/:accountId/sites/:siteId/operations/common/:opId/logs?access_token=:token',
+    operationEventsPath: '/portal/v1/accounts/:accountId/sites/:siteId/operations/common/:opId/events?access_token=:token',
     expandSitePath: '/portalapi/v1/sites/:siteId/expand',
     shrinkSitePath: '/portalapi/v1/sites/:siteId/shrink',
 
@@ -17,9 +17,13 @@ limitations under the License.
 import reactor from 'app/reactor';
 import api from 'app/services/api';
 import cfg from 'app/config';
+import webSockets from 'app/services/webSockets';
 
 import { OP_PROGRESS_RECEIVE } from './actionTypes';
 
+const POLL_INTERVAL = 3000;
+const PROGRESS_COMPLETED = 100;
+
 export function fetchOpProgress(siteId, opId){
   let url = cfg.getOperationProgressUrl(siteId, opId);
   return api.get(url).then((data)=>{
@@ -27,3 +31,26 @@ export function fetchOpProgress(siteId, opId){
   });
 }
 
+// subscribeOpProgress receives operation progress updates from the operation
+// event stream. Browsers without Server-Sent Events support poll for the progress.
+// Returns a function that stops the updates.
+export function subscribeOpProgress(siteId, opId){
+  if(!window.EventSource){
+    fetchOpProgress(siteId, opId);
+    const timer = setInterval(() => fetchOpProgress(siteId, opId), POLL_INTERVAL);
+    return () => clearInterval(timer);
+  }
+
+  const source = webSockets.createOpEventSource(siteId, opId);
+  source.addEventListener('progress', e => {
+    const { progress } = JSON.parse(e.data);
+    reactor.dispatch(OP_PROGRESS_RECEIVE, progress);
+    // the stream ends with the completed progress entry,
+    // close it so the browser does not reconnect
+    if(progress.completion === PROGRESS_COMPLETED){
+      source.close();
+    }
+  });
+
+  return () => source.close();
+}
@@ -18,7 +18,7 @@ import React from 'react';
 import classnames from 'classnames';
 import {Success, Failure } from './items';
 import getters from './../../flux/progress/getters';
-import { fetchOpProgress} from './../../flux/progress/actions';
+import { subscribeOpProgress } from './../../flux/progress/actions';
 import LogViewer from 'app/components/logViewer';
 import connect from 'app/lib/connect';
 import cfg from 'app/config';
@@ -142,12 +142,11 @@ class Progress extends React.Component {
   }
 
   componentDidMount(){
-    fetchOpProgress();
-    this.refreshInterval = setInterval(fetchOpProgress, 3000);
+    this.unsubscribe = subscribeOpProgress();
   }
 
   componentWillUnmount() {
-    clearInterval(this.refreshInterval);
+    this.unsubscribe();
   }
 
   render() {
@@ -23,3 +23,7 @@ export function fetchOpProgress(){
   return progressActions.fetchOpProgress(siteId, opId);
 }
 
+export function subscribeOpProgress(){
+  const {siteId, opId} = reactor.evaluate(installerGetters.installer);
+  return progressActions.subscribeOpProgress(siteId, opId);
+}
@@ -20,7 +20,7 @@ import Logger from 'app/lib/logger';
 import api from 'app/services/api';
 import AjaxPoller from 'app/components/dataProviders'
 import { fetchServers } from './../flux/servers/actions';
-import { fetchOpProgress } from './../flux/currentSite/actions';
+import { subscribeOpProgress } from './../flux/currentSite/actions';
 
 const logger = Logger.create('modules/site/components/siteLogAggregatorProvider');
 const POLL_INTERVAL = 3000;
@@ -32,14 +32,16 @@ export const SiteOpProgressProvider = React.createClass({
    opId: React.PropTypes.string.isRequired
   },
   
-  fetchProgress(){
-    return fetchOpProgress(this.props.opId);
+  componentDidMount(){
+    this.unsubscribe = subscribeOpProgress(this.props.opId);
   },
-  
+
+  componentWillUnmount(){
+    this.unsubscribe();
+  },
+
   render() {
-    return (    
-      <AjaxPoller time={POLL_INTERVAL} onFetch={this.fetchProgress} />
-    )  
+    return null;
   }
 });
 
@@ -71,6 +71,11 @@ export function fetchOpProgress(opId){
   return progressActions.fetchOpProgress(siteId, opId);
 }
 
+export function subscribeOpProgress(opId){
+  const siteId = reactor.evaluate(getters.getSiteId);
+  return progressActions.subscribeOpProgress(siteId, opId);
+}
+
 export function openRemoteAccessDialog(){
   reactor.dispatch(actionTypes.SITE_OPEN_REMOTE_DIALOG);
 }
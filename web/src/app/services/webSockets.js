@@ -33,6 +33,19 @@ const webSockets = {
       });
 
     return new WebSocket(hostname + url);
+  },
+
+  createOpEventSource(siteId, opId){
+    const token = localStorage.getAccessToken();
+    const accountId = utils.getAccountId();
+    const url = formatPattern(cfg.api.operationEventsPath, {
+        siteId,
+        accountId,
+        token,
+        opId
+      });
+
+    return new EventSource(url);
   }
 }
 